@@ -1,6 +1,6 @@
 // GENERATED BY THE COMMAND ABOVE; DO NOT EDIT
 // This file was generated by swaggo/swag at
-// 2020-04-13 19:43:24.047007795 +0000 UTC m=+0.055227823
+// 2026-10-19 09:38:11.824114893 +0000 UTC m=+0.163179148
 
 package docs
 
@@ -29,6 +29,48 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/audit": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the changes requested through the API, oldest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "List audit records",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only list records since this time, in RFC 3339",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list records of changes to this object, as kind:name, like service:svc or route:svc/route",
+                        "name": "object",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    }
+                }
+            }
+        },
         "/filter": {
             "get": {
                 "security": [
@@ -80,6 +122,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
+                            "type": "object",
                             "$ref": "#/definitions/webhttp.FilterConfig"
                         }
                     }
@@ -90,11 +133,71 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/filter/:filter_name": {
+        "/filter/": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Set filter config from file\nThe config is uploaded as the Config file of a form, or sent as filter_config in json",
+                "consumes": [
+                    "multipart/form-data",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "filter"
+                ],
+                "summary": "Set filter config from file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of filter_name for which to list config",
+                        "name": "filter_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    }
+                }
+            },
             "patch": {
                 "security": [
                     {
@@ -126,6 +229,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
+                            "type": "object",
                             "$ref": "#/definitions/webhttp.FilterConfig"
                         }
                     }
@@ -136,18 +240,24 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/filter/:filter_name/config": {
-            "post": {
+        "/filter/{filter_name}": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Set filter config from file",
+                "description": "Get filters detail for provided name",
                 "consumes": [
                     "application/json"
                 ],
@@ -157,7 +267,7 @@ var doc = `{
                 "tags": [
                     "filter"
                 ],
-                "summary": "Set filter config from file",
+                "summary": "Get filters detail for provided name",
                 "parameters": [
                     {
                         "type": "string",
@@ -175,16 +285,14 @@ var doc = `{
                         }
                     }
                 }
-            }
-        },
-        "/filter/{filter_name}": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get filters detail for provided name",
+                "description": "Delete filter config\nDeleting a filter which is in use responds with its references, unless cascade is set to remove its associations with services and routes first",
                 "consumes": [
                     "application/json"
                 ],
@@ -194,7 +302,7 @@ var doc = `{
                 "tags": [
                     "filter"
                 ],
-                "summary": "Get filters detail for provided name",
+                "summary": "Delete filter config",
                 "parameters": [
                     {
                         "type": "string",
@@ -202,6 +310,12 @@ var doc = `{
                         "name": "filter_name",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Remove the associations of the filter first",
+                        "name": "cascade",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -210,16 +324,36 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/filter/{filter_name}/references": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Delete filter config",
+                "description": "Get the services and routes using a filter, and the proxies serving them",
                 "consumes": [
                     "application/json"
                 ],
@@ -229,11 +363,11 @@ var doc = `{
                 "tags": [
                     "filter"
                 ],
-                "summary": "Delete filter config",
+                "summary": "Get the objects referencing a filter",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of filter_name for which to list config",
+                        "description": "Name of filter",
                         "name": "filter_name",
                         "in": "path",
                         "required": true
@@ -243,7 +377,7 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": ""
+                            "$ref": "#/definitions/webhttp.References"
                         }
                     }
                 }
@@ -299,6 +433,18 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
@@ -371,6 +517,24 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             },
@@ -406,6 +570,18 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
@@ -417,8 +593,9 @@ var doc = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Set globalconfig from file",
+                "description": "Set globalconfig from file\nThe config is uploaded as the Config file of a form, or sent as config in json",
                 "consumes": [
+                    "multipart/form-data",
                     "application/json"
                 ],
                 "produces": [
@@ -443,6 +620,24 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
@@ -498,6 +693,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
+                            "type": "object",
                             "$ref": "#/definitions/webhttp.Proxy"
                         }
                     }
@@ -508,6 +704,18 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
@@ -647,6 +855,80 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proxy/{proxy_name}/config": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Set the configuration of a proxy, in the shape returned by GET /proxy/dump, in one transaction. Objects are matched by name, fields left out keep their values, and the associations become those listed. With dryRun set, the changes are returned without being made.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "proxy",
+                    "operational-verbs"
+                ],
+                "summary": "Apply the configuration of a proxy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of proxy",
+                        "name": "proxy_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only return the changes to be made",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
@@ -733,6 +1015,24 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             },
@@ -775,18 +1075,36 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/proxy/{proxy_name}/service": {
+        "/proxy/{proxy_name}/revision": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get all services associated with a proxy",
+                "description": "Get the revisions of the configuration of a proxy, oldest first, with the changes each made",
                 "consumes": [
                     "application/json"
                 ],
@@ -794,13 +1112,14 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "proxy"
+                    "proxy",
+                    "revision"
                 ],
-                "summary": "List services associated with proxy",
+                "summary": "List revisions of a proxy",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of proxy for which to list services",
+                        "description": "Name of proxy",
                         "name": "proxy_name",
                         "in": "path",
                         "required": true
@@ -816,14 +1135,14 @@ var doc = `{
                 }
             }
         },
-        "/proxy/{proxy_name}/service/{service_name}": {
+        "/proxy/{proxy_name}/revision/{revision_id}": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Return specified service associated with this proxy",
+                "description": "Get a revision of a proxy along with the configuration it holds",
                 "consumes": [
                     "application/json"
                 ],
@@ -831,21 +1150,22 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "proxy"
+                    "proxy",
+                    "revision"
                 ],
-                "summary": "Return specified service associated with this proxy",
+                "summary": "Get a revision of a proxy",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of proxy for which to list service",
+                        "description": "Name of proxy",
                         "name": "proxy_name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Name of service to list",
-                        "name": "service_name",
+                        "type": "integer",
+                        "description": "Id of revision",
+                        "name": "revision_id",
                         "in": "path",
                         "required": true
                     }
@@ -858,14 +1178,16 @@ var doc = `{
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/proxy/{proxy_name}/revision/{revision_id}/diff/{to_revision_id}": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Associate a service with proxy",
+                "description": "Get the changes to the configuration of a proxy from one revision to another",
                 "consumes": [
                     "application/json"
                 ],
@@ -873,21 +1195,29 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "proxy"
+                    "proxy",
+                    "revision"
                 ],
-                "summary": "Associate a service with proxy",
+                "summary": "Diff two revisions of a proxy",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of proxy for which to list service",
+                        "description": "Name of proxy",
                         "name": "proxy_name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Name of service to list",
-                        "name": "service_name",
+                        "type": "integer",
+                        "description": "Id of revision to diff from",
+                        "name": "revision_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Id of revision to diff to",
+                        "name": "to_revision_id",
                         "in": "path",
                         "required": true
                     }
@@ -900,14 +1230,16 @@ var doc = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/proxy/{proxy_name}/revision/{revision_id}/rollback": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Disassociate a service from proxy",
+                "description": "Restore the configuration of a proxy held in a revision, recording it as a new revision",
                 "consumes": [
                     "application/json"
                 ],
@@ -915,68 +1247,62 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "proxy"
+                    "proxy",
+                    "revision"
                 ],
-                "summary": "Disassociate a service from proxy",
+                "summary": "Roll back a proxy to a revision",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of proxy for which to list service",
+                        "description": "Name of proxy",
                         "name": "proxy_name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Name of service to list",
-                        "name": "service_name",
+                        "type": "integer",
+                        "description": "Id of revision to roll back to",
+                        "name": "revision_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/secret": {
+        "/proxy/{proxy_name}/service": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get a list of all secrets for all services",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "secret"
-                ],
-                "summary": "List all secrets",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": ""
-                        }
-                    }
-                }
-            },
-            "post": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
+                "description": "Get all services associated with a proxy",
                 "consumes": [
                     "application/json"
                 ],
@@ -984,18 +1310,16 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "secret"
+                    "proxy"
                 ],
-                "summary": "Create a secret",
+                "summary": "List services associated with proxy",
                 "parameters": [
                     {
-                        "description": "Secret to create",
-                        "name": "Secret",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/webhttp.Secret"
-                        }
+                        "type": "string",
+                        "description": "Name of proxy for which to list services",
+                        "name": "proxy_name",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -1008,13 +1332,14 @@ var doc = `{
                 }
             }
         },
-        "/secret/{secret_name}": {
-            "delete": {
+        "/proxy/{proxy_name}/service/{service_name}": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "Return specified service associated with this proxy",
                 "consumes": [
                     "application/json"
                 ],
@@ -1022,14 +1347,21 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "secret"
+                    "proxy"
                 ],
-                "summary": "Delete a secret",
+                "summary": "Return specified service associated with this proxy",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of secret",
-                        "name": "secret_name",
+                        "description": "Name of proxy for which to list service",
+                        "name": "proxy_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of service to list",
+                        "name": "service_name",
                         "in": "path",
                         "required": true
                     }
@@ -1042,16 +1374,14 @@ var doc = `{
                         }
                     }
                 }
-            }
-        },
-        "/secret/{secret_name}/cert": {
+            },
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Set the secret cert from file\nExample curl -X POST -F 'Secret_cert=@certificate.pem' http://localhost:1323/secret/testsecret/cert | python -m json.tool",
+                "description": "Associate a service with proxy",
                 "consumes": [
                     "application/json"
                 ],
@@ -1059,22 +1389,22 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "secret"
+                    "proxy"
                 ],
-                "summary": "Set the secret cert from file",
+                "summary": "Associate a service with proxy",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of secret",
-                        "name": "secret_name",
+                        "description": "Name of proxy for which to list service",
+                        "name": "proxy_name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "file",
-                        "description": "Location of file holding the secret cert",
-                        "name": "secret_cert",
-                        "in": "formData",
+                        "type": "string",
+                        "description": "Name of service to list",
+                        "name": "service_name",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -1084,18 +1414,34 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
-            }
-        },
-        "/secret/{secret_name}/key": {
-            "post": {
+            },
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Set the secret key from file\nExample curl -X POST -F 'Secret_key=@private_key.pem' http://localhost:1323/secret/testsecret/key | python -m json.tool",
+                "description": "Disassociate a service from proxy",
                 "consumes": [
                     "application/json"
                 ],
@@ -1103,22 +1449,22 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "secret"
+                    "proxy"
                 ],
-                "summary": "Set the secret key from file",
+                "summary": "Disassociate a service from proxy",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of secret",
-                        "name": "secret_name",
+                        "description": "Name of proxy for which to list service",
+                        "name": "proxy_name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "file",
-                        "description": "Location of file holding the secret key",
-                        "name": "secret_key",
-                        "in": "formData",
+                        "type": "string",
+                        "description": "Name of service to list",
+                        "name": "service_name",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -1128,18 +1474,36 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/service": {
+        "/secret": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get a list of all services for all proxies",
+                "description": "Get a list of all secrets for all services, with the subject, SANs and expiry of each certificate in secret_cert_info",
                 "consumes": [
                     "application/json"
                 ],
@@ -1147,9 +1511,9 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "service"
+                    "secret"
                 ],
-                "summary": "List all services",
+                "summary": "List all secrets",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -1165,7 +1529,6 @@ var doc = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Create a service",
                 "consumes": [
                     "application/json"
                 ],
@@ -1173,37 +1536,51 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "service"
+                    "secret"
                 ],
-                "summary": "Create a service",
+                "summary": "Create a secret",
                 "parameters": [
                     {
-                        "description": "Service to create",
-                        "name": "Service",
+                        "description": "Secret to create",
+                        "name": "Secret",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/webhttp.Service"
+                            "type": "object",
+                            "$ref": "#/definitions/webhttp.Secret"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/service/copy/{service_name_src}/{service_name_dst}": {
-            "post": {
+        "/secret/{secret_name}": {
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "Deleting a secret which is in use responds with its references, unless cascade is set to remove its associations with services first",
                 "consumes": [
                     "application/json"
                 ],
@@ -1211,24 +1588,22 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "service",
-                    "operational-verbs"
+                    "secret"
                 ],
-                "summary": "Copy service",
+                "summary": "Delete a secret",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of service",
-                        "name": "service_name_src",
+                        "description": "Name of secret",
+                        "name": "secret_name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Name of service",
-                        "name": "service_name_dst",
-                        "in": "path",
-                        "required": true
+                        "type": "boolean",
+                        "description": "Remove the associations of the secret first",
+                        "name": "cascade",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1237,50 +1612,60 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/service/copyroute/{service_name_src}/{service_name_dst}/route/{route_name}": {
+        "/secret/{secret_name}/cert": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Copy a route on source service to a destination service",
+                "description": "Set the secret cert from file\nExample curl -X POST -F 'Secret_cert=@certificate.pem' http://localhost:1323/secret/testsecret/cert | python -m json.tool\nThe cert may be sent as secret_cert in json as well",
                 "consumes": [
+                    "multipart/form-data",
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "service",
-                    "route",
-                    "operational-verbs"
+                    "secret"
                 ],
-                "summary": "Copy a route on source service to a destination service",
+                "summary": "Set the secret cert from file",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of service",
-                        "name": "service_name_src",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Name of service",
-                        "name": "service_name_dst",
+                        "description": "Name of secret",
+                        "name": "secret_name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Name of service",
-                        "name": "route_name",
-                        "in": "path",
+                        "type": "file",
+                        "description": "Location of file holding the secret cert",
+                        "name": "secret_cert",
+                        "in": "formData",
                         "required": true
                     }
                 ],
@@ -1290,43 +1675,60 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/service/deepcopy/{service_name_src}/{service_name_dst}": {
+        "/secret/{secret_name}/key": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Deep copy service copies service, associated routes and upstream associations. Upstream copies are not created.",
+                "description": "Set the secret key from file\nExample curl -X POST -F 'Secret_key=@private_key.pem' http://localhost:1323/secret/testsecret/key | python -m json.tool\nThe key may be sent as secret_key in json as well",
                 "consumes": [
+                    "multipart/form-data",
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "service",
-                    "route",
-                    "operational-verbs"
+                    "secret"
                 ],
-                "summary": "Deep copy service",
+                "summary": "Set the secret key from file",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of service",
-                        "name": "service_name_src",
+                        "description": "Name of secret",
+                        "name": "secret_name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Name of service",
-                        "name": "service_name_dst",
-                        "in": "path",
+                        "type": "file",
+                        "description": "Location of file holding the secret key",
+                        "name": "secret_key",
+                        "in": "formData",
                         "required": true
                     }
                 ],
@@ -1336,18 +1738,36 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/service/dump/{service_name}": {
+        "/secret/{secret_name}/references": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Fetch detail of specified service",
+                "description": "Get the services using a secret and the proxies serving them",
                 "consumes": [
                     "application/json"
                 ],
@@ -1355,15 +1775,14 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "service",
-                    "operational-verbs"
+                    "secret"
                 ],
-                "summary": "Fetch detail of specified service",
+                "summary": "Get the objects referencing a secret",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of service",
-                        "name": "service_name",
+                        "description": "Name of secret",
+                        "name": "secret_name",
                         "in": "path",
                         "required": true
                     }
@@ -1372,20 +1791,20 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": ""
+                            "$ref": "#/definitions/webhttp.References"
                         }
                     }
                 }
             }
         },
-        "/service/{service_name}": {
-            "get": {
+        "/secret/{secret_name}/tls": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Fetch some details of specified service",
+                "description": "Set the minimum and maximum TLS version, cipher suites and ECDH curves\nExample curl -X POST -d 'Secret_tls_config={\"minimum_protocol_version\":\"1.2\",\"cipher_suites\":[\"ECDHE-RSA-AES256-GCM-SHA384\"]}' http://localhost:1323/secret/testsecret/tls | python -m json.tool",
                 "consumes": [
                     "application/json"
                 ],
@@ -1393,16 +1812,26 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "service"
+                    "secret"
                 ],
-                "summary": "Fetch some details of specified service",
+                "summary": "Set the TLS parameters of the service using this secret",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of service",
-                        "name": "service_name",
+                        "description": "Name of secret",
+                        "name": "secret_name",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "TLS parameters in Secret_tls_config",
+                        "name": "Secret",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/webhttp.Secret"
+                        }
                     }
                 ],
                 "responses": {
@@ -1411,16 +1840,36 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/service": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Delete specified service",
+                "description": "Get a list of all services for all proxies",
                 "consumes": [
                     "application/json"
                 ],
@@ -1430,16 +1879,7 @@ var doc = `{
                 "tags": [
                     "service"
                 ],
-                "summary": "Delete the specified service",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Name of service",
-                        "name": "service_name",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
+                "summary": "List all services",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -1449,13 +1889,13 @@ var doc = `{
                     }
                 }
             },
-            "patch": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Update a service",
+                "description": "Create a service",
                 "consumes": [
                     "application/json"
                 ],
@@ -1465,23 +1905,17 @@ var doc = `{
                 "tags": [
                     "service"
                 ],
-                "summary": "Update a service",
+                "summary": "Create a service",
                 "parameters": [
                     {
-                        "description": "Fields of service to update",
+                        "description": "Service to create",
                         "name": "Service",
                         "in": "body",
                         "required": true,
                         "schema": {
+                            "type": "object",
                             "$ref": "#/definitions/webhttp.Service"
                         }
-                    },
-                    {
-                        "type": "string",
-                        "description": "name of service to update",
-                        "name": "service_name",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
@@ -1490,18 +1924,29 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/service/{service_name}/filter": {
-            "get": {
+        "/service/copy/{service_name_src}/{service_name_dst}": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Return filters associated with a service",
                 "consumes": [
                     "application/json"
                 ],
@@ -1509,14 +1954,22 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "filter"
+                    "service",
+                    "operational-verbs"
                 ],
-                "summary": "Return filters associated with a service",
+                "summary": "Copy service",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "Name of service",
-                        "name": "service_name",
+                        "name": "service_name_src",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of service",
+                        "name": "service_name_dst",
                         "in": "path",
                         "required": true
                     }
@@ -1527,18 +1980,36 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/service/{service_name}/filter/{filter_name}": {
-            "get": {
+        "/service/copyroute/{service_name_src}/{service_name_dst}/route/{route_name}": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Return filter associated with a service",
+                "description": "Copy a route on source service to a destination service",
                 "consumes": [
                     "application/json"
                 ],
@@ -1546,21 +2017,30 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "filter"
+                    "service",
+                    "route",
+                    "operational-verbs"
                 ],
-                "summary": "Return filter associated with a service",
+                "summary": "Copy a route on source service to a destination service",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "Name of service",
-                        "name": "service_name",
+                        "name": "service_name_src",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Name of filter",
-                        "name": "filter_name",
+                        "description": "Name of service",
+                        "name": "service_name_dst",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of service",
+                        "name": "route_name",
                         "in": "path",
                         "required": true
                     }
@@ -1571,16 +2051,36 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
-            },
+            }
+        },
+        "/service/deepcopy/{service_name_src}/{service_name_dst}": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Associate a filter with service",
+                "description": "Deep copy service copies service, associated routes and upstream associations. Upstream copies are not created.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1588,21 +2088,23 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "filter service"
+                    "service",
+                    "route",
+                    "operational-verbs"
                 ],
-                "summary": "Associate a filter with service",
+                "summary": "Deep copy service",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "Name of service",
-                        "name": "service_name",
+                        "name": "service_name_src",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Name of filter",
-                        "name": "filter_name",
+                        "description": "Name of service",
+                        "name": "service_name_dst",
                         "in": "path",
                         "required": true
                     }
@@ -1613,16 +2115,36 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/service/dump/{service_name}": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Delete filter associated with a service",
+                "description": "Fetch detail of specified service",
                 "consumes": [
                     "application/json"
                 ],
@@ -1630,9 +2152,10 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "filter"
+                    "service",
+                    "operational-verbs"
                 ],
-                "summary": "Delete filter associated with a service",
+                "summary": "Fetch detail of specified service",
                 "parameters": [
                     {
                         "type": "string",
@@ -1640,13 +2163,6 @@ var doc = `{
                         "name": "service_name",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Name of filter for route",
-                        "name": "filter_name",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
@@ -1659,14 +2175,14 @@ var doc = `{
                 }
             }
         },
-        "/service/{service_name}/proxy": {
+        "/service/{service_name}": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Fetch list of proxies on which this service is programmed",
+                "description": "Fetch some details of specified service",
                 "consumes": [
                     "application/json"
                 ],
@@ -1674,10 +2190,9 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "service",
-                    "proxy"
+                    "service"
                 ],
-                "summary": "Fetch list of proxies on which this service is programmed",
+                "summary": "Fetch some details of specified service",
                 "parameters": [
                     {
                         "type": "string",
@@ -1695,16 +2210,14 @@ var doc = `{
                         }
                     }
                 }
-            }
-        },
-        "/service/{service_name}/route": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get all routes for a service",
+                "description": "Delete specified service\nDeleting a service which is in use responds with its references, unless cascade is set to remove it from its proxies, its secrets and filters, and delete its routes, first",
                 "consumes": [
                     "application/json"
                 ],
@@ -1712,10 +2225,9 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "service",
-                    "route"
+                    "service"
                 ],
-                "summary": "Get all routes for a service",
+                "summary": "Delete the specified service",
                 "parameters": [
                     {
                         "type": "string",
@@ -1723,6 +2235,12 @@ var doc = `{
                         "name": "service_name",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Remove the associations and routes of the service first",
+                        "name": "cascade",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1731,16 +2249,34 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             },
-            "post": {
+            "patch": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Create a route associated with a service",
+                "description": "Update a service",
                 "consumes": [
                     "application/json"
                 ],
@@ -1748,46 +2284,58 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "service",
-                    "route"
+                    "service"
                 ],
-                "summary": "Create a route associated with a service",
+                "summary": "Update a service",
                 "parameters": [
+                    {
+                        "description": "Fields of service to update",
+                        "name": "Service",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/webhttp.Service"
+                        }
+                    },
                     {
                         "type": "string",
-                        "description": "Name of service",
+                        "description": "name of service to update",
                         "name": "service_name",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Route to create",
-                        "name": "Route",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/webhttp.Route"
-                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/service/{service_name}/route/{route_name}": {
-            "get": {
+        "/service/{service_name}/acme": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Delete the route associated with the service",
+                "description": "Obtain a certificate for the service fqdn from the ACME directory and store it in secret acme-{service_name}.\nThe HTTP-01 challenge is served through the gateway, the fqdn must resolve to it.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1796,9 +2344,9 @@ var doc = `{
                 ],
                 "tags": [
                     "service",
-                    "route"
+                    "secret"
                 ],
-                "summary": "Delete the route associated with the service",
+                "summary": "Issue a certificate for the service fqdn using ACME HTTP-01",
                 "parameters": [
                     {
                         "type": "string",
@@ -1806,31 +2354,44 @@ var doc = `{
                         "name": "service_name",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Name of route to delete",
-                        "name": "route_name",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
-            },
-            "patch": {
+            }
+        },
+        "/service/{service_name}/filter": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Update a route associated with a service",
+                "description": "Return filters associated with a service",
                 "consumes": [
                     "application/json"
                 ],
@@ -1838,10 +2399,9 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "service",
-                    "route"
+                    "filter"
                 ],
-                "summary": "Update a route associated with a service",
+                "summary": "Return filters associated with a service",
                 "parameters": [
                     {
                         "type": "string",
@@ -1849,22 +2409,6 @@ var doc = `{
                         "name": "service_name",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Name of service",
-                        "name": "route_name",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Route to update",
-                        "name": "Route",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/webhttp.Route"
-                        }
                     }
                 ],
                 "responses": {
@@ -1877,14 +2421,14 @@ var doc = `{
                 }
             }
         },
-        "/service/{service_name}/route/{route_name}/filter": {
+        "/service/{service_name}/filter/{filter_name}": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Return filters associated with a service route",
+                "description": "Return filter associated with a service",
                 "consumes": [
                     "application/json"
                 ],
@@ -1894,19 +2438,19 @@ var doc = `{
                 "tags": [
                     "filter"
                 ],
-                "summary": "Return filters associated with a service route",
+                "summary": "Return filter associated with a service",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of service for route",
+                        "description": "Name of service",
                         "name": "service_name",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Name of route for service",
-                        "name": "route_name",
+                        "description": "Name of filter",
+                        "name": "filter_name",
                         "in": "path",
                         "required": true
                     }
@@ -1919,16 +2463,14 @@ var doc = `{
                         }
                     }
                 }
-            }
-        },
-        "/service/{service_name}/route/{route_name}/filter/{filter_name}": {
-            "get": {
+            },
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Return filter associated with a service route",
+                "description": "Associate a filter with service",
                 "consumes": [
                     "application/json"
                 ],
@@ -1936,27 +2478,20 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "filter"
+                    "filter service"
                 ],
-                "summary": "Return filter associated with a service route",
+                "summary": "Associate a filter with service",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of service for route",
+                        "description": "Name of service",
                         "name": "service_name",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Name of route for service",
-                        "name": "route_name",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Name of filter for route",
+                        "description": "Name of filter",
                         "name": "filter_name",
                         "in": "path",
                         "required": true
@@ -1968,16 +2503,34 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             },
-            "post": {
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Associate a filter with route",
+                "description": "Delete filter associated with a service",
                 "consumes": [
                     "application/json"
                 ],
@@ -1985,9 +2538,9 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "filter route"
+                    "filter"
                 ],
-                "summary": "Associate a filter with route",
+                "summary": "Delete filter associated with a service",
                 "parameters": [
                     {
                         "type": "string",
@@ -1998,14 +2551,7 @@ var doc = `{
                     },
                     {
                         "type": "string",
-                        "description": "Name of route",
-                        "name": "route_name",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Name of filter",
+                        "description": "Name of filter for route",
                         "name": "filter_name",
                         "in": "path",
                         "required": true
@@ -2017,16 +2563,36 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/service/{service_name}/proxy": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Delete filter associated with a service route",
+                "description": "Fetch list of proxies on which this service is programmed",
                 "consumes": [
                     "application/json"
                 ],
@@ -2034,9 +2600,10 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "filter"
+                    "service",
+                    "proxy"
                 ],
-                "summary": "Delete filter associated with a service route",
+                "summary": "Fetch list of proxies on which this service is programmed",
                 "parameters": [
                     {
                         "type": "string",
@@ -2044,20 +2611,6 @@ var doc = `{
                         "name": "service_name",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Name of route for service",
-                        "name": "route_name",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Name of filter for route",
-                        "name": "filter_name",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
@@ -2070,14 +2623,14 @@ var doc = `{
                 }
             }
         },
-        "/service/{service_name}/route/{route_name}/upstream": {
+        "/service/{service_name}/references": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get all upstreams associated with a service route",
+                "description": "Get the proxies serving a service, and its routes, secrets and filters",
                 "consumes": [
                     "application/json"
                 ],
@@ -2085,11 +2638,9 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "service",
-                    "route",
-                    "upstream"
+                    "service"
                 ],
-                "summary": "Get all upstreams associated with a service route",
+                "summary": "Get the objects referencing a service",
                 "parameters": [
                     {
                         "type": "string",
@@ -2097,33 +2648,26 @@ var doc = `{
                         "name": "service_name",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Name of route",
-                        "name": "route_name",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": ""
+                            "$ref": "#/definitions/webhttp.References"
                         }
                     }
                 }
             }
         },
-        "/service/{service_name}/route/{route_name}/upstream/{upstream_name}": {
+        "/service/{service_name}/route": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Disassociate an upstream with a service route. This does not delete the upstream.",
+                "description": "Get all routes for a service",
                 "consumes": [
                     "application/json"
                 ],
@@ -2132,10 +2676,9 @@ var doc = `{
                 ],
                 "tags": [
                     "service",
-                    "route",
-                    "upstream"
+                    "route"
                 ],
-                "summary": "Disassociate an upstream with a service route",
+                "summary": "Get all routes for a service",
                 "parameters": [
                     {
                         "type": "string",
@@ -2143,20 +2686,6 @@ var doc = `{
                         "name": "service_name",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Name of route",
-                        "name": "route_name",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Name of upstream to disassociate with service route",
-                        "name": "upstream_name",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
@@ -2167,16 +2696,14 @@ var doc = `{
                         }
                     }
                 }
-            }
-        },
-        "/service/{service_name}/secret": {
-            "get": {
+            },
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "List all secrets associated with a service",
+                "description": "Create a route associated with a service",
                 "consumes": [
                     "application/json"
                 ],
@@ -2185,9 +2712,9 @@ var doc = `{
                 ],
                 "tags": [
                     "service",
-                    "secret"
+                    "route"
                 ],
-                "summary": "List all secrets associated with a service",
+                "summary": "Create a route associated with a service",
                 "parameters": [
                     {
                         "type": "string",
@@ -2195,6 +2722,16 @@ var doc = `{
                         "name": "service_name",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Route to create",
+                        "name": "Route",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/webhttp.Route"
+                        }
                     }
                 ],
                 "responses": {
@@ -2203,18 +2740,36 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/service/{service_name}/secret/{secret_name}": {
-            "post": {
+        "/service/{service_name}/route/{route_name}": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Associate a secret with a service",
+                "description": "Delete the route associated with the service",
                 "consumes": [
                     "application/json"
                 ],
@@ -2223,9 +2778,9 @@ var doc = `{
                 ],
                 "tags": [
                     "service",
-                    "secret"
+                    "route"
                 ],
-                "summary": "Associate a secret with a service",
+                "summary": "Delete the route associated with the service",
                 "parameters": [
                     {
                         "type": "string",
@@ -2236,8 +2791,1020 @@ var doc = `{
                     },
                     {
                         "type": "string",
-                        "description": "Name of secret",
-                        "name": "secret_name",
+                        "description": "Name of route to delete",
+                        "name": "route_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Update a route associated with a service",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service",
+                    "route"
+                ],
+                "summary": "Update a route associated with a service",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of service",
+                        "name": "service_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of service",
+                        "name": "route_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Route to update",
+                        "name": "Route",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/webhttp.Route"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/service/{service_name}/route/{route_name}/filter": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Return filters associated with a service route",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "filter"
+                ],
+                "summary": "Return filters associated with a service route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of service for route",
+                        "name": "service_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of route for service",
+                        "name": "route_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    }
+                }
+            }
+        },
+        "/service/{service_name}/route/{route_name}/filter/{filter_name}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Return filter associated with a service route",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "filter"
+                ],
+                "summary": "Return filter associated with a service route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of service for route",
+                        "name": "service_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of route for service",
+                        "name": "route_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of filter for route",
+                        "name": "filter_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Associate a filter with route",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "filter route"
+                ],
+                "summary": "Associate a filter with route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of service",
+                        "name": "service_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of route",
+                        "name": "route_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of filter",
+                        "name": "filter_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete filter associated with a service route",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "filter"
+                ],
+                "summary": "Delete filter associated with a service route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of service",
+                        "name": "service_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of route for service",
+                        "name": "route_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of filter for route",
+                        "name": "filter_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/service/{service_name}/route/{route_name}/upstream": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get all upstreams associated with a service route",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service",
+                    "route",
+                    "upstream"
+                ],
+                "summary": "Get all upstreams associated with a service route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of service",
+                        "name": "service_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of route",
+                        "name": "route_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    }
+                }
+            }
+        },
+        "/service/{service_name}/route/{route_name}/upstream/{upstream_name}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Disassociate an upstream with a service route. This does not delete the upstream.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service",
+                    "route",
+                    "upstream"
+                ],
+                "summary": "Disassociate an upstream with a service route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of service",
+                        "name": "service_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of route",
+                        "name": "route_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of upstream to disassociate with service route",
+                        "name": "upstream_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    }
+                }
+            }
+        },
+        "/service/{service_name}/secret": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List all secrets associated with a service",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service",
+                    "secret"
+                ],
+                "summary": "List all secrets associated with a service",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of service",
+                        "name": "service_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    }
+                }
+            }
+        },
+        "/service/{service_name}/secret/{secret_name}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Associate a secret with a service",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service",
+                    "secret"
+                ],
+                "summary": "Associate a secret with a service",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of service",
+                        "name": "service_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of secret",
+                        "name": "secret_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Disassociate a secret with a service",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service",
+                    "secret"
+                ],
+                "summary": "Disassociate a secret with a service",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of service",
+                        "name": "service_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of secret",
+                        "name": "secret_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/status/{status}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get status of HTTP service",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service"
+                ],
+                "summary": "Get status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    }
+                }
+            }
+        },
+        "/token": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List API tokens, without their value",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "token"
+                ],
+                "summary": "List API tokens",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create an API token with a role, optionally scoped to proxies and services. The token is only returned here.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "token"
+                ],
+                "summary": "Create an API token",
+                "parameters": [
+                    {
+                        "description": "Token to create",
+                        "name": "Token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/webhttp.Token"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": ""
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/token/{token_name}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get an API token, without its value",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "token"
+                ],
+                "summary": "Get an API token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of token",
+                        "name": "token_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revoke an API token, which cannot be used anymore",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "token"
+                ],
+                "summary": "Revoke an API token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of token",
+                        "name": "token_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/upstream": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get a list of all upstreams",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upstream"
+                ],
+                "summary": "List all upstreams",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upstream"
+                ],
+                "summary": "Create an upstream",
+                "parameters": [
+                    {
+                        "description": "Upstream to create",
+                        "name": "Upstream",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/webhttp.Upstream"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/upstream/copy/{upstream_name_src}/{upstream_name_dst}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Make a copy from upstream_name_src to upstream_name_dst",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upstream",
+                    "operational-verbs"
+                ],
+                "summary": "Copy an upstream",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of upstream",
+                        "name": "upstream_name_src",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of upstream",
+                        "name": "upstream_name_dst",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    }
+                }
+            }
+        },
+        "/upstream/{upstream_name}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upstream"
+                ],
+                "summary": "Get info of an upstream",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of upstream to delete",
+                        "name": "upstream_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deleting an upstream which is in use responds with its references, unless cascade is set to remove its associations with routes first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upstream"
+                ],
+                "summary": "Delete an upstream",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of upstream to delete",
+                        "name": "upstream_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Remove the associations of the upstream first",
+                        "name": "cascade",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": ""
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upstream"
+                ],
+                "summary": "Update an upstream",
+                "parameters": [
+                    {
+                        "description": "Upstream to update",
+                        "name": "Upstream",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/webhttp.Upstream"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of upstream to update",
+                        "name": "upstream_name",
                         "in": "path",
                         "required": true
                     }
@@ -2248,16 +3815,30 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/upstream/{upstream_name}/references": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Disassociate a secret with a service",
+                "description": "Get the routes using an upstream, their services and the proxies serving them",
                 "consumes": [
                     "application/json"
                 ],
@@ -2265,22 +3846,14 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "service",
-                    "secret"
+                    "upstream"
                 ],
-                "summary": "Disassociate a secret with a service",
+                "summary": "Get the objects referencing an upstream",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of service",
-                        "name": "service_name",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Name of secret",
-                        "name": "secret_name",
+                        "description": "Name of upstream",
+                        "name": "upstream_name",
                         "in": "path",
                         "required": true
                     }
@@ -2289,20 +3862,19 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": ""
+                            "$ref": "#/definitions/webhttp.References"
                         }
                     }
                 }
             }
         },
-        "/upstream": {
+        "/upstream/{upstream_name}/route": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get a list of all upstreams",
                 "consumes": [
                     "application/json"
                 ],
@@ -2310,9 +3882,19 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "upstream"
+                    "upstream",
+                    "route"
+                ],
+                "summary": "Get routes to which this upstream is associated",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of upstream",
+                        "name": "upstream_name",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "List all upstreams",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -2321,13 +3903,16 @@ var doc = `{
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/usage/preview": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "Show the anonymous usage report which would be sent, where and how often, or that none are sent.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2335,61 +3920,39 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "upstream"
-                ],
-                "summary": "Create an upstream",
-                "parameters": [
-                    {
-                        "description": "Upstream to create",
-                        "name": "Upstream",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/webhttp.Upstream"
-                        }
-                    }
+                    "usage"
                 ],
+                "summary": "Preview the usage report",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": ""
+                            "$ref": "#/definitions/webhttp.UsagePreview"
                         }
                     }
                 }
             }
         },
-        "/upstream/copy/{upstream_name_src}/{upstream_name_dst}": {
+        "/v1/watch/{proxy_name}": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Make a copy from upstream_name_src to upstream_name_dst",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Stream server-sent events with the version of the configuration of a proxy, when the stream starts and whenever it changes",
                 "produces": [
-                    "application/json"
+                    "text/event-stream"
                 ],
                 "tags": [
-                    "upstream",
-                    "operational-verbs"
+                    "proxy"
                 ],
-                "summary": "Copy an upstream",
+                "summary": "Watch the configuration of a proxy",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of upstream",
-                        "name": "upstream_name_src",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Name of upstream",
-                        "name": "upstream_name_dst",
+                        "description": "Name of proxy",
+                        "name": "proxy_name",
                         "in": "path",
                         "required": true
                     }
@@ -2404,13 +3967,14 @@ var doc = `{
                 }
             }
         },
-        "/upstream/{upstream_name}": {
+        "/webhook": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "List webhooks, without their secret",
                 "consumes": [
                     "application/json"
                 ],
@@ -2418,18 +3982,9 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "upstream"
-                ],
-                "summary": "Get info of an upstream",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Name of upstream to delete",
-                        "name": "upstream_name",
-                        "in": "path",
-                        "required": true
-                    }
+                    "webhook"
                 ],
+                "summary": "List webhooks",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -2439,12 +3994,13 @@ var doc = `{
                     }
                 }
             },
-            "delete": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "Create a webhook posted the events matching its patterns, like service.* or *.deleted, or all of them if it has none.\nEvents are signed with HMAC-SHA256 in header X-Enroute-Signature if the webhook has a secret.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2452,33 +4008,51 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "upstream"
+                    "webhook"
                 ],
-                "summary": "Delete an upstream",
+                "summary": "Create a webhook",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Name of upstream to delete",
-                        "name": "upstream_name",
-                        "in": "path",
-                        "required": true
+                        "description": "Webhook to create",
+                        "name": "Webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/webhttp.Webhook"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
-            },
-            "patch": {
+            }
+        },
+        "/webhook/{webhook_name}": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "Get a webhook, without its secret",
                 "consumes": [
                     "application/json"
                 ],
@@ -2486,23 +4060,14 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "upstream"
+                    "webhook"
                 ],
-                "summary": "Update an upstream",
+                "summary": "Get a webhook",
                 "parameters": [
-                    {
-                        "description": "Upstream to update",
-                        "name": "Upstream",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/webhttp.Upstream"
-                        }
-                    },
                     {
                         "type": "string",
-                        "description": "Name of upstream to update",
-                        "name": "upstream_name",
+                        "description": "Name of webhook",
+                        "name": "webhook_name",
                         "in": "path",
                         "required": true
                     }
@@ -2515,15 +4080,14 @@ var doc = `{
                         }
                     }
                 }
-            }
-        },
-        "/upstream/{upstream_name}/route": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "Delete a webhook, which is not posted events anymore",
                 "consumes": [
                     "application/json"
                 ],
@@ -2531,15 +4095,14 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "upstream",
-                    "route"
+                    "webhook"
                 ],
-                "summary": "Get routes to which this upstream is associated",
+                "summary": "Delete a webhook",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of upstream",
-                        "name": "upstream_name",
+                        "description": "Name of webhook",
+                        "name": "webhook_name",
                         "in": "path",
                         "required": true
                     }
@@ -2550,12 +4113,58 @@ var doc = `{
                         "schema": {
                             "type": ""
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhttp.ErrorResponse"
+                        }
                     }
                 }
             }
         }
     },
     "definitions": {
+        "webhttp.APIError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is one of invalid, bad-request, unauthorized, forbidden,\nnot-found, exists, in-use or internal, or the status text of other\nerrors, like method-not-allowed",
+                    "type": "string",
+                    "example": "invalid"
+                },
+                "field": {
+                    "description": "Field is the field of the request body the error is about",
+                    "type": "string",
+                    "example": "upstream_port"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Please provide a valid port value"
+                },
+                "object": {
+                    "description": "Object is the object the error is about, as kind:name",
+                    "type": "string",
+                    "example": "upstream:u1"
+                }
+            }
+        },
+        "webhttp.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/webhttp.APIError"
+                    }
+                },
+                "references": {
+                    "description": "References are the objects using an object which could not be\ndeleted because of them",
+                    "type": "object",
+                    "$ref": "#/definitions/webhttp.References"
+                }
+            }
+        },
         "webhttp.FilterConfig": {
             "type": "object",
             "properties": {
@@ -2563,10 +4172,12 @@ var doc = `{
                     "type": "string"
                 },
                 "filter_name": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "lua-filter"
                 },
                 "filter_type": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "http_filter_lua"
                 }
             }
         },
@@ -2574,18 +4185,61 @@ var doc = `{
             "type": "object",
             "properties": {
                 "name": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "gw"
+                }
+            }
+        },
+        "webhttp.References": {
+            "type": "object",
+            "properties": {
+                "filters": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "proxies": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "routes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secrets": {
+                    "description": "Secrets and Filters are associated with a service",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "services": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
         "webhttp.Route": {
             "type": "object",
             "properties": {
-                "route_name": {
+                "route_config": {
+                    "description": "Route_config holds configuration in json. Use this config if present. Else, fallback to individual fields above",
                     "type": "string"
                 },
+                "route_name": {
+                    "type": "string",
+                    "example": "r1"
+                },
                 "route_prefix": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "/"
                 }
             }
         },
@@ -2595,14 +4249,23 @@ var doc = `{
                 "secret_cert": {
                     "type": "string"
                 },
+                "secret_config": {
+                    "description": "Secret_config holds configuration in json. Use this config if present. Else, fallback to individual fields above",
+                    "type": "string"
+                },
                 "secret_key": {
                     "type": "string"
                 },
                 "secret_name": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "demo-secret"
                 },
                 "secret_sni": {
                     "type": "string"
+                },
+                "secret_tls_config": {
+                    "description": "Secret_tls_config holds the TLS parameters (saarasconfig.TLSConfig) in json of the service using this secret",
+                    "type": "string"
                 }
             }
         },
@@ -2610,42 +4273,81 @@ var doc = `{
             "type": "object",
             "properties": {
                 "fqdn": {
+                    "type": "string",
+                    "example": "demo.example.com"
+                },
+                "service_config": {
+                    "description": "Service_config holds configuration in json. Use this config if present. Else, fallback to individual fields above",
                     "type": "string"
                 },
                 "service_name": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "demo"
+                }
+            }
+        },
+        "webhttp.Token": {
+            "type": "object",
+            "properties": {
+                "token_name": {
+                    "type": "string",
+                    "example": "ci"
+                },
+                "token_proxies": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token_role": {
+                    "type": "string",
+                    "example": "operator"
+                },
+                "token_services": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
         "webhttp.Upstream": {
             "type": "object",
             "properties": {
-                "upstream_hc_healthythresholdcount": {
+                "upstream_config": {
+                    "description": "Upstream_config holds configuration in json. Use this config if present. Else, fallback to individual fields above",
                     "type": "string"
                 },
+                "upstream_hc_healthythresholdcount": {
+                    "type": "integer"
+                },
                 "upstream_hc_host": {
                     "type": "string"
                 },
                 "upstream_hc_intervalseconds": {
-                    "type": "string"
+                    "type": "integer"
                 },
                 "upstream_hc_path": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "/"
                 },
                 "upstream_hc_timeoutseconds": {
-                    "type": "string"
+                    "type": "integer"
                 },
                 "upstream_hc_unhealthythresholdcount": {
-                    "type": "string"
+                    "type": "integer"
                 },
                 "upstream_ip": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "10.0.0.1"
                 },
                 "upstream_name": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "u1"
                 },
                 "upstream_port": {
-                    "type": "string"
+                    "type": "integer",
+                    "example": 80
                 },
                 "upstream_protocol": {
                     "type": "string"
@@ -2660,7 +4362,59 @@ var doc = `{
                     "type": "string"
                 },
                 "upstream_weight": {
+                    "type": "integer",
+                    "example": 100
+                }
+            }
+        },
+        "webhttp.UsagePreview": {
+            "type": "object",
+            "properties": {
+                "destination": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "interval": {
+                    "type": "string"
+                },
+                "report": {
+                    "type": "object",
+                    "$ref": "#/definitions/webhttp.UsageReport"
+                }
+            }
+        },
+        "webhttp.UsageReport": {
+            "type": "object",
+            "properties": {
+                "enroutectluuid": {
+                    "type": "string"
+                },
+                "uptime": {
+                    "type": "string"
+                }
+            }
+        },
+        "webhttp.Webhook": {
+            "type": "object",
+            "properties": {
+                "webhook_events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "webhook_name": {
+                    "type": "string",
+                    "example": "ci"
+                },
+                "webhook_secret": {
                     "type": "string"
+                },
+                "webhook_url": {
+                    "type": "string",
+                    "example": "https://ci.example.com/enroute"
                 }
             }
         }
@@ -9,8 +9,9 @@ require (
 	github.com/saarasio/enroute/enroute-cp/docs v0.1.0
 	github.com/saarasio/enroute/enroute-dp v0.1.0
 	github.com/sirupsen/logrus v1.4.2
-	github.com/stretchr/testify v1.4.0
+	github.com/stretchr/testify v1.5.1
 	github.com/swaggo/echo-swagger v0.0.0-20190329130007-1219b460a043
+	golang.org/x/crypto v0.0.0-20200406173513-056763e48d71
 )
 
 replace github.com/saarasio/enroute/enroute-cp/docs => ./docs
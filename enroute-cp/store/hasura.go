@@ -0,0 +1,1615 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/saarasio/enroute/enroute-dp/saaras"
+	"github.com/sirupsen/logrus"
+)
+
+// Hasura is a Store kept in Postgres, reached through the GraphQL API of
+// the Hasura engine at URL.
+type Hasura struct {
+	URL string
+	Log logrus.FieldLogger
+}
+
+// NewHasura returns a Store for the Hasura engine at host:port.
+func NewHasura(host, port string) *Hasura {
+	return &Hasura{
+		URL: "http://" + host + ":" + port + "/v1/graphql",
+		Log: logrus.StandardLogger().WithField("context", "store"),
+	}
+}
+
+type vars map[string]interface{}
+
+type hasuraResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// run runs the query q and decodes the data of the response into out.
+// Constraint violations reported by Postgres are returned as ErrInUse
+// and ErrExists.
+func (h *Hasura) run(q string, v vars, out interface{}) error {
+	var buf bytes.Buffer
+	if err := saaras.RunDBQueryGenericVals(h.URL, q, &buf, v, h.Log); err != nil {
+		return err
+	}
+
+	var resp hasuraResponse
+	if err := json.Unmarshal(buf.Bytes(), &resp); err != nil {
+		return fmt.Errorf("decoding response: %v", err)
+	}
+	if len(resp.Errors) > 0 {
+		msg := resp.Errors[0].Message
+		switch {
+		case strings.Contains(msg, "Foreign key violation"):
+			return fmt.Errorf("%s: %w", msg, ErrInUse)
+		case strings.Contains(msg, "Uniqueness violation"):
+			return fmt.Errorf("%s: %w", msg, ErrExists)
+		}
+		return fmt.Errorf("graphql: %s", msg)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Data, out)
+}
+
+// list runs the query q and decodes the rows of table into out.
+func (h *Hasura) list(q, table string, v vars, out interface{}) error {
+	var data map[string]json.RawMessage
+	if err := h.run(q, v, &data); err != nil {
+		return err
+	}
+	rows, ok := data[table]
+	if !ok {
+		return fmt.Errorf("graphql: no %s in response", table)
+	}
+	return json.Unmarshal(rows, out)
+}
+
+// mutate runs the mutation q, returning ErrNotFound if it affects no
+// rows.
+func (h *Hasura) mutate(q, field string, v vars, kind, name string) error {
+	var data map[string]struct {
+		AffectedRows int `json:"affected_rows"`
+	}
+	if err := h.run(q, v, &data); err != nil {
+		return err
+	}
+	if data[field].AffectedRows == 0 {
+		return notFound(kind, name)
+	}
+	return nil
+}
+
+// id returns the id of the object name in table.
+func (h *Hasura) id(table, kind, name string) (int64, error) {
+	q := `query id($name: String!) { saaras_db_` + table + `(where: {` + table + `_name: {_eq: $name}}) { id: ` + table + `_id } }`
+	var rows []struct {
+		ID int64 `json:"id"`
+	}
+	if err := h.list(q, "saaras_db_"+table, vars{"name": name}, &rows); err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, notFound(kind, name)
+	}
+	return rows[0].ID, nil
+}
+
+func (h *Hasura) routeID(service, route string) (int64, error) {
+	q := `
+query route_id($service_name: String!, $route_name: String!) {
+  saaras_db_route(where: {service: {service_name: {_eq: $service_name}}, route_name: {_eq: $route_name}}) {
+    route_id
+  }
+}`
+	var rows []struct {
+		ID int64 `json:"route_id"`
+	}
+	if err := h.list(q, "saaras_db_route", vars{"service_name": service, "route_name": route}, &rows); err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		if _, err := h.id("service", "service", service); err != nil {
+			return 0, err
+		}
+		return 0, notFound("route", service+"/"+route)
+	}
+	return rows[0].ID, nil
+}
+
+// Selection sets of each object
+
+const (
+	proxyFields = `proxy_id proxy_name create_ts update_ts`
+
+	serviceFields = `service_id service_name fqdn create_ts update_ts`
+
+	routeFields = `route_id route_name route_prefix route_config config_json create_ts update_ts`
+
+	upstreamFields = `upstream_id upstream_name upstream_ip upstream_port upstream_weight
+    upstream_hc_path upstream_hc_host upstream_hc_intervalseconds upstream_hc_timeoutseconds
+    upstream_hc_unhealthythresholdcount upstream_hc_healthythresholdcount upstream_strategy
+    upstream_validation_cacertificate upstream_validation_subjectname upstream_protocol
+    create_ts update_ts`
+
+	secretFields = `secret_id secret_name secret_key secret_cert secret_sni secret_tls_config secret_acme_service create_ts update_ts`
+
+	filterFields = `filter_id filter_name filter_type filter_config config_json create_ts update_ts`
+
+	globalConfigFields = `globalconfig_id globalconfig_name globalconfig_type config config_json create_ts update_ts`
+
+	serviceDetailFields = serviceFields + `
+    routes(order_by: {route_name: asc}) {
+      ` + routeFields + `
+      route_upstreams(order_by: {upstream: {upstream_name: asc}}) { upstream { ` + upstreamFields + ` } }
+      route_filters(order_by: {filter: {filter_name: asc}}) { filter { ` + filterFields + ` } }
+    }
+    service_secrets(order_by: {secret: {secret_name: asc}}) { secret { ` + secretFields + ` } }
+    service_filters(order_by: {filter: {filter_name: asc}}) { filter { ` + filterFields + ` } }`
+
+	proxyDetailFields = proxyFields + `
+    proxy_globalconfigs(order_by: {globalconfig: {globalconfig_name: asc}}) { globalconfig { ` + globalConfigFields + ` } }
+    proxy_services(order_by: {service: {service_name: asc}}) { service { ` + serviceDetailFields + ` } }`
+)
+
+// hasuraRoute is a route along with the name of its service.
+type hasuraRoute struct {
+	Route
+	Svc struct {
+		Name string `json:"service_name"`
+	} `json:"service"`
+}
+
+func hasuraRoutes(hrs []hasuraRoute) []Route {
+	rs := make([]Route, 0, len(hrs))
+	for _, hr := range hrs {
+		r := hr.Route
+		r.Service = hr.Svc.Name
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+// setRouteServices sets the service of the routes of sd, which are
+// fetched without it.
+func setRouteServices(sd *ServiceDetail) {
+	for i := range sd.Routes {
+		sd.Routes[i].Service = sd.Name
+	}
+}
+
+// Proxies
+
+func (h *Hasura) ListProxies() ([]Proxy, error) {
+	var ps []Proxy
+	q := `query { saaras_db_proxy(order_by: {proxy_name: asc}) { ` + proxyFields + ` } }`
+	err := h.list(q, "saaras_db_proxy", nil, &ps)
+	return ps, err
+}
+
+func (h *Hasura) GetProxy(name string) (*Proxy, error) {
+	var ps []Proxy
+	q := `query get_proxy($name: String!) { saaras_db_proxy(where: {proxy_name: {_eq: $name}}) { ` + proxyFields + ` } }`
+	if err := h.list(q, "saaras_db_proxy", vars{"name": name}, &ps); err != nil {
+		return nil, err
+	}
+	if len(ps) == 0 {
+		return nil, notFound("proxy", name)
+	}
+	return &ps[0], nil
+}
+
+func (h *Hasura) CreateProxy(name string) error {
+	q := `
+mutation create_proxy($name: String!) {
+  insert_saaras_db_proxy(objects: {proxy_name: $name},
+    on_conflict: {constraint: proxy_proxy_name_key, update_columns: []}) {
+    affected_rows
+  }
+}`
+	return h.run(q, vars{"name": name}, nil)
+}
+
+func (h *Hasura) DeleteProxy(name string) error {
+	q := `
+mutation delete_proxy($name: String!) {
+  delete_saaras_db_proxy(where: {proxy_name: {_eq: $name}}) { affected_rows }
+}`
+	return h.mutate(q, "delete_saaras_db_proxy", vars{"name": name}, "proxy", name)
+}
+
+func (h *Hasura) ProxyDetail(name string) (*ProxyDetail, error) {
+	var pds []ProxyDetail
+	q := `query proxy_detail($name: String!) { saaras_db_proxy(where: {proxy_name: {_eq: $name}}) { ` + proxyDetailFields + ` } }`
+	if err := h.list(q, "saaras_db_proxy", vars{"name": name}, &pds); err != nil {
+		return nil, err
+	}
+	if len(pds) == 0 {
+		return nil, notFound("proxy", name)
+	}
+	for i := range pds[0].Services {
+		setRouteServices(&pds[0].Services[i].Service)
+	}
+	return &pds[0], nil
+}
+
+func (h *Hasura) ListProxyDetails() ([]ProxyDetail, error) {
+	var pds []ProxyDetail
+	q := `query { saaras_db_proxy(order_by: {proxy_name: asc}) { ` + proxyDetailFields + ` } }`
+	if err := h.list(q, "saaras_db_proxy", nil, &pds); err != nil {
+		return nil, err
+	}
+	for i := range pds {
+		for j := range pds[i].Services {
+			setRouteServices(&pds[i].Services[j].Service)
+		}
+	}
+	return pds, nil
+}
+
+func (h *Hasura) AssociateProxyService(proxy, service string) error {
+	pid, err := h.id("proxy", "proxy", proxy)
+	if err != nil {
+		return err
+	}
+	sid, err := h.id("service", "service", service)
+	if err != nil {
+		return err
+	}
+	q := `
+mutation associate($proxy_id: Int!, $service_id: Int!) {
+  insert_saaras_db_proxy_service(objects: {proxy_id: $proxy_id, service_id: $service_id},
+    on_conflict: {constraint: proxy_service_proxy_id_service_id_key, update_columns: []}) {
+    affected_rows
+  }
+}`
+	return h.run(q, vars{"proxy_id": pid, "service_id": sid}, nil)
+}
+
+func (h *Hasura) DisassociateProxyService(proxy, service string) error {
+	if _, err := h.id("proxy", "proxy", proxy); err != nil {
+		return err
+	}
+	q := `
+mutation disassociate($proxy_name: String!, $service_name: String!) {
+  delete_saaras_db_proxy_service(where: {
+    proxy: {proxy_name: {_eq: $proxy_name}},
+    service: {service_name: {_eq: $service_name}}
+  }) { affected_rows }
+}`
+	return h.mutate(q, "delete_saaras_db_proxy_service", vars{"proxy_name": proxy, "service_name": service},
+		"proxy service", proxy+"/"+service)
+}
+
+func (h *Hasura) ListProxyServices(proxy string) ([]Service, error) {
+	if _, err := h.id("proxy", "proxy", proxy); err != nil {
+		return nil, err
+	}
+	var ss []Service
+	q := `
+query proxy_services($proxy_name: String!) {
+  saaras_db_service(where: {proxy_services: {proxy: {proxy_name: {_eq: $proxy_name}}}}, order_by: {service_name: asc}) {
+    ` + serviceFields + `
+  }
+}`
+	err := h.list(q, "saaras_db_service", vars{"proxy_name": proxy}, &ss)
+	return ss, err
+}
+
+func (h *Hasura) ListServiceProxies(service string) ([]Proxy, error) {
+	if _, err := h.id("service", "service", service); err != nil {
+		return nil, err
+	}
+	var ps []Proxy
+	q := `
+query service_proxies($service_name: String!) {
+  saaras_db_proxy(where: {proxy_services: {service: {service_name: {_eq: $service_name}}}}, order_by: {proxy_name: asc}) {
+    ` + proxyFields + `
+  }
+}`
+	err := h.list(q, "saaras_db_proxy", vars{"service_name": service}, &ps)
+	return ps, err
+}
+
+func (h *Hasura) AssociateProxyGlobalConfig(proxy, globalconfig string) error {
+	pid, err := h.id("proxy", "proxy", proxy)
+	if err != nil {
+		return err
+	}
+	gid, err := h.id("globalconfig", "globalconfig", globalconfig)
+	if err != nil {
+		return err
+	}
+	q := `
+mutation associate($proxy_id: Int!, $globalconfig_id: Int!) {
+  insert_saaras_db_proxy_globalconfig(objects: {proxy_id: $proxy_id, globalconfig_id: $globalconfig_id},
+    on_conflict: {constraint: proxy_globalconfig_pkey, update_columns: []}) {
+    affected_rows
+  }
+}`
+	return h.run(q, vars{"proxy_id": pid, "globalconfig_id": gid}, nil)
+}
+
+func (h *Hasura) DisassociateProxyGlobalConfig(proxy, globalconfig string) error {
+	if _, err := h.id("proxy", "proxy", proxy); err != nil {
+		return err
+	}
+	q := `
+mutation disassociate($proxy_name: String!, $globalconfig_name: String!) {
+  delete_saaras_db_proxy_globalconfig(where: {
+    proxy: {proxy_name: {_eq: $proxy_name}},
+    globalconfig: {globalconfig_name: {_eq: $globalconfig_name}}
+  }) { affected_rows }
+}`
+	return h.mutate(q, "delete_saaras_db_proxy_globalconfig", vars{"proxy_name": proxy, "globalconfig_name": globalconfig},
+		"proxy globalconfig", proxy+"/"+globalconfig)
+}
+
+func (h *Hasura) ListProxyGlobalConfigs(proxy string) ([]GlobalConfig, error) {
+	if _, err := h.id("proxy", "proxy", proxy); err != nil {
+		return nil, err
+	}
+	var gcs []GlobalConfig
+	q := `
+query proxy_globalconfigs($proxy_name: String!) {
+  saaras_db_globalconfig(where: {proxy_globalconfigs: {proxy: {proxy_name: {_eq: $proxy_name}}}}, order_by: {globalconfig_name: asc}) {
+    ` + globalConfigFields + `
+  }
+}`
+	err := h.list(q, "saaras_db_globalconfig", vars{"proxy_name": proxy}, &gcs)
+	return gcs, err
+}
+
+// Services
+
+func (h *Hasura) ListServices() ([]Service, error) {
+	var ss []Service
+	q := `query { saaras_db_service(order_by: {service_name: asc}) { ` + serviceFields + ` } }`
+	err := h.list(q, "saaras_db_service", nil, &ss)
+	return ss, err
+}
+
+func (h *Hasura) GetService(name string) (*Service, error) {
+	var ss []Service
+	q := `query get_service($name: String!) { saaras_db_service(where: {service_name: {_eq: $name}}) { ` + serviceFields + ` } }`
+	if err := h.list(q, "saaras_db_service", vars{"name": name}, &ss); err != nil {
+		return nil, err
+	}
+	if len(ss) == 0 {
+		return nil, notFound("service", name)
+	}
+	return &ss[0], nil
+}
+
+func (h *Hasura) CreateService(s *Service) error {
+	q := `
+mutation create_service($name: String!, $fqdn: String!) {
+  insert_saaras_db_service(objects: {service_name: $name, fqdn: $fqdn},
+    on_conflict: {constraint: service_service_name_key, update_columns: []}) {
+    affected_rows
+  }
+}`
+	return h.run(q, vars{"name": s.Name, "fqdn": s.Fqdn}, nil)
+}
+
+func (h *Hasura) UpdateService(s *Service) error {
+	q := `
+mutation update_service($name: String!, $fqdn: String!) {
+  update_saaras_db_service(where: {service_name: {_eq: $name}}, _set: {fqdn: $fqdn}) {
+    affected_rows
+  }
+}`
+	return h.mutate(q, "update_saaras_db_service", vars{"name": s.Name, "fqdn": s.Fqdn}, "service", s.Name)
+}
+
+func (h *Hasura) DeleteService(name string) error {
+	q := `
+mutation delete_service($name: String!) {
+  delete_saaras_db_service(where: {service_name: {_eq: $name}}) { affected_rows }
+}`
+	return h.mutate(q, "delete_saaras_db_service", vars{"name": name}, "service", name)
+}
+
+func (h *Hasura) ServiceDetail(name string) (*ServiceDetail, error) {
+	var sds []ServiceDetail
+	q := `query service_detail($name: String!) { saaras_db_service(where: {service_name: {_eq: $name}}) { ` + serviceDetailFields + ` } }`
+	if err := h.list(q, "saaras_db_service", vars{"name": name}, &sds); err != nil {
+		return nil, err
+	}
+	if len(sds) == 0 {
+		return nil, notFound("service", name)
+	}
+	setRouteServices(&sds[0])
+	return &sds[0], nil
+}
+
+func (h *Hasura) AssociateServiceSecret(service, secret string) error {
+	sid, err := h.id("service", "service", service)
+	if err != nil {
+		return err
+	}
+	secid, err := h.id("secret", "secret", secret)
+	if err != nil {
+		return err
+	}
+	q := `
+mutation associate($service_id: Int!, $secret_id: Int!) {
+  insert_saaras_db_service_secret(objects: {service_id: $service_id, secret_id: $secret_id},
+    on_conflict: {constraint: service_secret_service_id_secret_id_key, update_columns: []}) {
+    affected_rows
+  }
+}`
+	return h.run(q, vars{"service_id": sid, "secret_id": secid}, nil)
+}
+
+func (h *Hasura) DisassociateServiceSecret(service, secret string) error {
+	if _, err := h.id("service", "service", service); err != nil {
+		return err
+	}
+	q := `
+mutation disassociate($service_name: String!, $secret_name: String!) {
+  delete_saaras_db_service_secret(where: {
+    service: {service_name: {_eq: $service_name}},
+    secret: {secret_name: {_eq: $secret_name}}
+  }) { affected_rows }
+}`
+	return h.mutate(q, "delete_saaras_db_service_secret", vars{"service_name": service, "secret_name": secret},
+		"service secret", service+"/"+secret)
+}
+
+func (h *Hasura) ListServiceSecrets(service string) ([]Secret, error) {
+	if _, err := h.id("service", "service", service); err != nil {
+		return nil, err
+	}
+	var ss []Secret
+	q := `
+query service_secrets($service_name: String!) {
+  saaras_db_secret(where: {service_secrets: {service: {service_name: {_eq: $service_name}}}}, order_by: {secret_name: asc}) {
+    ` + secretFields + `
+  }
+}`
+	err := h.list(q, "saaras_db_secret", vars{"service_name": service}, &ss)
+	return ss, err
+}
+
+func (h *Hasura) ListSecretServices(secret string) ([]Service, error) {
+	if _, err := h.id("secret", "secret", secret); err != nil {
+		return nil, err
+	}
+	var ss []Service
+	q := `
+query secret_services($secret_name: String!) {
+  saaras_db_service(where: {service_secrets: {secret: {secret_name: {_eq: $secret_name}}}}, order_by: {service_name: asc}) {
+    ` + serviceFields + `
+  }
+}`
+	err := h.list(q, "saaras_db_service", vars{"secret_name": secret}, &ss)
+	return ss, err
+}
+
+func (h *Hasura) AssociateServiceFilter(service, filter string) error {
+	sid, err := h.id("service", "service", service)
+	if err != nil {
+		return err
+	}
+	fid, err := h.id("filter", "filter", filter)
+	if err != nil {
+		return err
+	}
+	q := `
+mutation associate($service_id: Int!, $filter_id: Int!) {
+  insert_saaras_db_service_filter(objects: {service_id: $service_id, filter_id: $filter_id},
+    on_conflict: {constraint: service_filter_pkey, update_columns: []}) {
+    affected_rows
+  }
+}`
+	return h.run(q, vars{"service_id": sid, "filter_id": fid}, nil)
+}
+
+func (h *Hasura) DisassociateServiceFilter(service, filter string) error {
+	if _, err := h.id("service", "service", service); err != nil {
+		return err
+	}
+	q := `
+mutation disassociate($service_name: String!, $filter_name: String!) {
+  delete_saaras_db_service_filter(where: {
+    service: {service_name: {_eq: $service_name}},
+    filter: {filter_name: {_eq: $filter_name}}
+  }) { affected_rows }
+}`
+	return h.mutate(q, "delete_saaras_db_service_filter", vars{"service_name": service, "filter_name": filter},
+		"service filter", service+"/"+filter)
+}
+
+func (h *Hasura) ListServiceFilters(service string) ([]Filter, error) {
+	if _, err := h.id("service", "service", service); err != nil {
+		return nil, err
+	}
+	var fs []Filter
+	q := `
+query service_filters($service_name: String!) {
+  saaras_db_filter(where: {service_filters: {service: {service_name: {_eq: $service_name}}}}, order_by: {filter_name: asc}) {
+    ` + filterFields + `
+  }
+}`
+	err := h.list(q, "saaras_db_filter", vars{"service_name": service}, &fs)
+	return fs, err
+}
+
+func (h *Hasura) ListFilterServices(filter string) ([]Service, error) {
+	if _, err := h.id("filter", "filter", filter); err != nil {
+		return nil, err
+	}
+	var ss []Service
+	q := `
+query filter_services($filter_name: String!) {
+  saaras_db_service(where: {service_filters: {filter: {filter_name: {_eq: $filter_name}}}}, order_by: {service_name: asc}) {
+    ` + serviceFields + `
+  }
+}`
+	err := h.list(q, "saaras_db_service", vars{"filter_name": filter}, &ss)
+	return ss, err
+}
+
+// Routes
+
+func (h *Hasura) ListRoutes(service string) ([]Route, error) {
+	if _, err := h.id("service", "service", service); err != nil {
+		return nil, err
+	}
+	var hrs []hasuraRoute
+	q := `
+query service_routes($service_name: String!) {
+  saaras_db_route(where: {service: {service_name: {_eq: $service_name}}}, order_by: {route_name: asc}) {
+    ` + routeFields + ` service { service_name }
+  }
+}`
+	if err := h.list(q, "saaras_db_route", vars{"service_name": service}, &hrs); err != nil {
+		return nil, err
+	}
+	return hasuraRoutes(hrs), nil
+}
+
+func (h *Hasura) GetRoute(service, name string) (*Route, error) {
+	var hrs []hasuraRoute
+	q := `
+query get_route($service_name: String!, $route_name: String!) {
+  saaras_db_route(where: {service: {service_name: {_eq: $service_name}}, route_name: {_eq: $route_name}}) {
+    ` + routeFields + ` service { service_name }
+  }
+}`
+	if err := h.list(q, "saaras_db_route", vars{"service_name": service, "route_name": name}, &hrs); err != nil {
+		return nil, err
+	}
+	if len(hrs) == 0 {
+		if _, err := h.id("service", "service", service); err != nil {
+			return nil, err
+		}
+		return nil, notFound("route", service+"/"+name)
+	}
+	return &hasuraRoutes(hrs)[0], nil
+}
+
+// jsonb returns the value to pass for a jsonb column holding raw.
+func jsonb(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return raw
+}
+
+func (h *Hasura) CreateRoute(r *Route) error {
+	sid, err := h.id("service", "service", r.Service)
+	if err != nil {
+		return err
+	}
+	q := `
+mutation create_route($service_id: Int!, $route_name: String!, $route_prefix: String, $route_config: String, $config_json: jsonb) {
+  insert_saaras_db_route(objects: {
+    service_id: $service_id,
+    route_name: $route_name,
+    route_prefix: $route_prefix,
+    route_config: $route_config,
+    config_json: $config_json
+  }) { affected_rows }
+}`
+	return h.run(q, vars{
+		"service_id":   sid,
+		"route_name":   r.Name,
+		"route_prefix": r.Prefix,
+		"route_config": r.Config,
+		"config_json":  jsonb(r.ConfigJSON),
+	}, nil)
+}
+
+func (h *Hasura) UpdateRoute(r *Route) error {
+	id, err := h.routeID(r.Service, r.Name)
+	if err != nil {
+		return err
+	}
+	q := `
+mutation update_route($route_id: Int!, $route_prefix: String, $route_config: String, $config_json: jsonb) {
+  update_saaras_db_route(where: {route_id: {_eq: $route_id}},
+    _set: {route_prefix: $route_prefix, route_config: $route_config, config_json: $config_json}) {
+    affected_rows
+  }
+}`
+	return h.mutate(q, "update_saaras_db_route", vars{
+		"route_id":     id,
+		"route_prefix": r.Prefix,
+		"route_config": r.Config,
+		"config_json":  jsonb(r.ConfigJSON),
+	}, "route", r.Service+"/"+r.Name)
+}
+
+func (h *Hasura) DeleteRoute(service, name string) error {
+	id, err := h.routeID(service, name)
+	if err != nil {
+		return err
+	}
+	q := `
+mutation delete_route($route_id: Int!) {
+  delete_saaras_db_route(where: {route_id: {_eq: $route_id}}) { affected_rows }
+}`
+	return h.mutate(q, "delete_saaras_db_route", vars{"route_id": id}, "route", service+"/"+name)
+}
+
+func (h *Hasura) AssociateRouteUpstream(service, route, upstream string) error {
+	rid, err := h.routeID(service, route)
+	if err != nil {
+		return err
+	}
+	uid, err := h.id("upstream", "upstream", upstream)
+	if err != nil {
+		return err
+	}
+	q := `
+mutation associate($route_id: Int!, $upstream_id: Int!) {
+  insert_saaras_db_route_upstream(objects: {route_id: $route_id, upstream_id: $upstream_id},
+    on_conflict: {constraint: route_upstream_route_id_upstream_id_key, update_columns: []}) {
+    affected_rows
+  }
+}`
+	return h.run(q, vars{"route_id": rid, "upstream_id": uid}, nil)
+}
+
+func (h *Hasura) DisassociateRouteUpstream(service, route, upstream string) error {
+	rid, err := h.routeID(service, route)
+	if err != nil {
+		return err
+	}
+	q := `
+mutation disassociate($route_id: Int!, $upstream_name: String!) {
+  delete_saaras_db_route_upstream(where: {
+    route_id: {_eq: $route_id},
+    upstream: {upstream_name: {_eq: $upstream_name}}
+  }) { affected_rows }
+}`
+	return h.mutate(q, "delete_saaras_db_route_upstream", vars{"route_id": rid, "upstream_name": upstream},
+		"route upstream", service+"/"+route+"/"+upstream)
+}
+
+func (h *Hasura) ListRouteUpstreams(service, route string) ([]Upstream, error) {
+	rid, err := h.routeID(service, route)
+	if err != nil {
+		return nil, err
+	}
+	var us []Upstream
+	q := `
+query route_upstreams($route_id: Int!) {
+  saaras_db_upstream(where: {route_upstreams: {route_id: {_eq: $route_id}}}, order_by: {upstream_name: asc}) {
+    ` + upstreamFields + `
+  }
+}`
+	err = h.list(q, "saaras_db_upstream", vars{"route_id": rid}, &us)
+	return us, err
+}
+
+func (h *Hasura) ListUpstreamRoutes(upstream string) ([]Route, error) {
+	if _, err := h.id("upstream", "upstream", upstream); err != nil {
+		return nil, err
+	}
+	var hrs []hasuraRoute
+	q := `
+query upstream_routes($upstream_name: String!) {
+  saaras_db_route(where: {route_upstreams: {upstream: {upstream_name: {_eq: $upstream_name}}}},
+    order_by: [{service: {service_name: asc}}, {route_name: asc}]) {
+    ` + routeFields + ` service { service_name }
+  }
+}`
+	if err := h.list(q, "saaras_db_route", vars{"upstream_name": upstream}, &hrs); err != nil {
+		return nil, err
+	}
+	return hasuraRoutes(hrs), nil
+}
+
+func (h *Hasura) ListFilterRoutes(filter string) ([]Route, error) {
+	if _, err := h.id("filter", "filter", filter); err != nil {
+		return nil, err
+	}
+	var hrs []hasuraRoute
+	q := `
+query filter_routes($filter_name: String!) {
+  saaras_db_route(where: {route_filters: {filter: {filter_name: {_eq: $filter_name}}}},
+    order_by: [{service: {service_name: asc}}, {route_name: asc}]) {
+    ` + routeFields + ` service { service_name }
+  }
+}`
+	if err := h.list(q, "saaras_db_route", vars{"filter_name": filter}, &hrs); err != nil {
+		return nil, err
+	}
+	return hasuraRoutes(hrs), nil
+}
+
+func (h *Hasura) AssociateRouteFilter(service, route, filter string) error {
+	rid, err := h.routeID(service, route)
+	if err != nil {
+		return err
+	}
+	fid, err := h.id("filter", "filter", filter)
+	if err != nil {
+		return err
+	}
+	q := `
+mutation associate($route_id: Int!, $filter_id: Int!) {
+  insert_saaras_db_route_filter(objects: {route_id: $route_id, filter_id: $filter_id},
+    on_conflict: {constraint: route_filter_pkey, update_columns: []}) {
+    affected_rows
+  }
+}`
+	return h.run(q, vars{"route_id": rid, "filter_id": fid}, nil)
+}
+
+func (h *Hasura) DisassociateRouteFilter(service, route, filter string) error {
+	rid, err := h.routeID(service, route)
+	if err != nil {
+		return err
+	}
+	q := `
+mutation disassociate($route_id: Int!, $filter_name: String!) {
+  delete_saaras_db_route_filter(where: {
+    route_id: {_eq: $route_id},
+    filter: {filter_name: {_eq: $filter_name}}
+  }) { affected_rows }
+}`
+	return h.mutate(q, "delete_saaras_db_route_filter", vars{"route_id": rid, "filter_name": filter},
+		"route filter", service+"/"+route+"/"+filter)
+}
+
+func (h *Hasura) ListRouteFilters(service, route string) ([]Filter, error) {
+	rid, err := h.routeID(service, route)
+	if err != nil {
+		return nil, err
+	}
+	var fs []Filter
+	q := `
+query route_filters($route_id: Int!) {
+  saaras_db_filter(where: {route_filters: {route_id: {_eq: $route_id}}}, order_by: {filter_name: asc}) {
+    ` + filterFields + `
+  }
+}`
+	err = h.list(q, "saaras_db_filter", vars{"route_id": rid}, &fs)
+	return fs, err
+}
+
+// Upstreams
+
+func (h *Hasura) ListUpstreams() ([]Upstream, error) {
+	var us []Upstream
+	q := `query { saaras_db_upstream(order_by: {upstream_name: asc}) { ` + upstreamFields + ` } }`
+	err := h.list(q, "saaras_db_upstream", nil, &us)
+	return us, err
+}
+
+func (h *Hasura) GetUpstream(name string) (*Upstream, error) {
+	var us []Upstream
+	q := `query get_upstream($name: String!) { saaras_db_upstream(where: {upstream_name: {_eq: $name}}) { ` + upstreamFields + ` } }`
+	if err := h.list(q, "saaras_db_upstream", vars{"name": name}, &us); err != nil {
+		return nil, err
+	}
+	if len(us) == 0 {
+		return nil, notFound("upstream", name)
+	}
+	return &us[0], nil
+}
+
+func upstreamVars(u *Upstream) vars {
+	return vars{
+		"upstream_name":                       u.Name,
+		"upstream_ip":                         u.IP,
+		"upstream_port":                       u.Port,
+		"upstream_weight":                     u.Weight,
+		"upstream_hc_path":                    u.HCPath,
+		"upstream_hc_host":                    u.HCHost,
+		"upstream_hc_intervalseconds":         u.HCIntervalSeconds,
+		"upstream_hc_timeoutseconds":          u.HCTimeoutSeconds,
+		"upstream_hc_unhealthythresholdcount": u.HCUnhealthyThresholdCount,
+		"upstream_hc_healthythresholdcount":   u.HCHealthyThresholdCount,
+		"upstream_strategy":                   u.Strategy,
+		"upstream_validation_cacertificate":   u.ValidationCACertificate,
+		"upstream_validation_subjectname":     u.ValidationSubjectName,
+		"upstream_protocol":                   u.Protocol,
+	}
+}
+
+const upstreamVarDecls = `$upstream_name: String!, $upstream_ip: String, $upstream_port: Int, $upstream_weight: Int,
+  $upstream_hc_path: String, $upstream_hc_host: String, $upstream_hc_intervalseconds: Int, $upstream_hc_timeoutseconds: Int,
+  $upstream_hc_unhealthythresholdcount: Int, $upstream_hc_healthythresholdcount: Int, $upstream_strategy: String,
+  $upstream_validation_cacertificate: String, $upstream_validation_subjectname: String, $upstream_protocol: String`
+
+const upstreamColumns = `
+    upstream_ip: $upstream_ip,
+    upstream_port: $upstream_port,
+    upstream_weight: $upstream_weight,
+    upstream_hc_path: $upstream_hc_path,
+    upstream_hc_host: $upstream_hc_host,
+    upstream_hc_intervalseconds: $upstream_hc_intervalseconds,
+    upstream_hc_timeoutseconds: $upstream_hc_timeoutseconds,
+    upstream_hc_unhealthythresholdcount: $upstream_hc_unhealthythresholdcount,
+    upstream_hc_healthythresholdcount: $upstream_hc_healthythresholdcount,
+    upstream_strategy: $upstream_strategy,
+    upstream_validation_cacertificate: $upstream_validation_cacertificate,
+    upstream_validation_subjectname: $upstream_validation_subjectname,
+    upstream_protocol: $upstream_protocol`
+
+func (h *Hasura) CreateUpstream(u *Upstream) error {
+	q := `
+mutation create_upstream(` + upstreamVarDecls + `) {
+  insert_saaras_db_upstream(objects: {
+    upstream_name: $upstream_name,` + upstreamColumns + `
+  }) { affected_rows }
+}`
+	return h.run(q, upstreamVars(u), nil)
+}
+
+func (h *Hasura) UpdateUpstream(u *Upstream) error {
+	q := `
+mutation update_upstream(` + upstreamVarDecls + `) {
+  update_saaras_db_upstream(where: {upstream_name: {_eq: $upstream_name}}, _set: {` + upstreamColumns + `
+  }) { affected_rows }
+}`
+	return h.mutate(q, "update_saaras_db_upstream", upstreamVars(u), "upstream", u.Name)
+}
+
+func (h *Hasura) DeleteUpstream(name string) error {
+	q := `
+mutation delete_upstream($name: String!) {
+  delete_saaras_db_upstream(where: {upstream_name: {_eq: $name}}) { affected_rows }
+}`
+	return h.mutate(q, "delete_saaras_db_upstream", vars{"name": name}, "upstream", name)
+}
+
+// Secrets
+
+func (h *Hasura) ListSecrets() ([]Secret, error) {
+	var ss []Secret
+	q := `query { saaras_db_secret(order_by: {secret_name: asc}) { ` + secretFields + ` } }`
+	err := h.list(q, "saaras_db_secret", nil, &ss)
+	return ss, err
+}
+
+func (h *Hasura) GetSecret(name string) (*Secret, error) {
+	var ss []Secret
+	q := `query get_secret($name: String!) { saaras_db_secret(where: {secret_name: {_eq: $name}}) { ` + secretFields + ` } }`
+	if err := h.list(q, "saaras_db_secret", vars{"name": name}, &ss); err != nil {
+		return nil, err
+	}
+	if len(ss) == 0 {
+		return nil, notFound("secret", name)
+	}
+	return &ss[0], nil
+}
+
+// secretVars are the columns of s restoring a proxy sets, all but
+// secret_acme_service.
+func secretVars(s *Secret) vars {
+	return vars{
+		"secret_name":       s.Name,
+		"secret_key":        s.Key,
+		"secret_cert":       s.Cert,
+		"secret_sni":        s.SNI,
+		"secret_tls_config": s.TLSConfig,
+	}
+}
+
+func (h *Hasura) CreateSecret(s *Secret) error {
+	q := `
+mutation create_secret($secret_name: String!, $secret_key: String, $secret_cert: String, $secret_sni: String, $secret_tls_config: String,
+    $secret_acme_service: String) {
+  insert_saaras_db_secret(objects: {
+    secret_name: $secret_name,
+    secret_key: $secret_key,
+    secret_cert: $secret_cert,
+    secret_sni: $secret_sni,
+    secret_tls_config: $secret_tls_config,
+    secret_acme_service: $secret_acme_service
+  }) { affected_rows }
+}`
+	v := secretVars(s)
+	v["secret_acme_service"] = s.ACMEService
+	return h.run(q, v, nil)
+}
+
+func (h *Hasura) UpdateSecret(s *Secret) error {
+	q := `
+mutation update_secret($secret_name: String!, $secret_key: String, $secret_cert: String, $secret_sni: String, $secret_tls_config: String,
+    $secret_acme_service: String) {
+  update_saaras_db_secret(where: {secret_name: {_eq: $secret_name}}, _set: {
+    secret_key: $secret_key,
+    secret_cert: $secret_cert,
+    secret_sni: $secret_sni,
+    secret_tls_config: $secret_tls_config,
+    secret_acme_service: $secret_acme_service
+  }) { affected_rows }
+}`
+	v := secretVars(s)
+	v["secret_acme_service"] = s.ACMEService
+	return h.mutate(q, "update_saaras_db_secret", v, "secret", s.Name)
+}
+
+func (h *Hasura) DeleteSecret(name string) error {
+	q := `
+mutation delete_secret($name: String!) {
+  delete_saaras_db_secret(where: {secret_name: {_eq: $name}}) { affected_rows }
+}`
+	return h.mutate(q, "delete_saaras_db_secret", vars{"name": name}, "secret", name)
+}
+
+// Filters
+
+func (h *Hasura) ListFilters() ([]Filter, error) {
+	var fs []Filter
+	q := `query { saaras_db_filter(order_by: {filter_name: asc}) { ` + filterFields + ` } }`
+	err := h.list(q, "saaras_db_filter", nil, &fs)
+	return fs, err
+}
+
+func (h *Hasura) GetFilter(name string) (*Filter, error) {
+	var fs []Filter
+	q := `query get_filter($name: String!) { saaras_db_filter(where: {filter_name: {_eq: $name}}) { ` + filterFields + ` } }`
+	if err := h.list(q, "saaras_db_filter", vars{"name": name}, &fs); err != nil {
+		return nil, err
+	}
+	if len(fs) == 0 {
+		return nil, notFound("filter", name)
+	}
+	return &fs[0], nil
+}
+
+func filterVars(f *Filter) vars {
+	return vars{
+		"filter_name":   f.Name,
+		"filter_type":   f.Type,
+		"filter_config": f.Config,
+		"config_json":   jsonb(f.ConfigJSON),
+	}
+}
+
+func (h *Hasura) CreateFilter(f *Filter) error {
+	q := `
+mutation create_filter($filter_name: String!, $filter_type: String, $filter_config: String, $config_json: jsonb) {
+  insert_saaras_db_filter(objects: {
+    filter_name: $filter_name,
+    filter_type: $filter_type,
+    filter_config: $filter_config,
+    config_json: $config_json
+  }) { affected_rows }
+}`
+	return h.run(q, filterVars(f), nil)
+}
+
+func (h *Hasura) UpdateFilter(f *Filter) error {
+	q := `
+mutation update_filter($filter_name: String!, $filter_type: String, $filter_config: String, $config_json: jsonb) {
+  update_saaras_db_filter(where: {filter_name: {_eq: $filter_name}}, _set: {
+    filter_type: $filter_type,
+    filter_config: $filter_config,
+    config_json: $config_json
+  }) { affected_rows }
+}`
+	return h.mutate(q, "update_saaras_db_filter", filterVars(f), "filter", f.Name)
+}
+
+func (h *Hasura) DeleteFilter(name string) error {
+	q := `
+mutation delete_filter($name: String!) {
+  delete_saaras_db_filter(where: {filter_name: {_eq: $name}}) { affected_rows }
+}`
+	return h.mutate(q, "delete_saaras_db_filter", vars{"name": name}, "filter", name)
+}
+
+// Global configs
+
+func (h *Hasura) ListGlobalConfigs() ([]GlobalConfig, error) {
+	var gcs []GlobalConfig
+	q := `query { saaras_db_globalconfig(order_by: {globalconfig_name: asc}) { ` + globalConfigFields + ` } }`
+	err := h.list(q, "saaras_db_globalconfig", nil, &gcs)
+	return gcs, err
+}
+
+func (h *Hasura) GetGlobalConfig(name string) (*GlobalConfig, error) {
+	var gcs []GlobalConfig
+	q := `query get_globalconfig($name: String!) { saaras_db_globalconfig(where: {globalconfig_name: {_eq: $name}}) { ` + globalConfigFields + ` } }`
+	if err := h.list(q, "saaras_db_globalconfig", vars{"name": name}, &gcs); err != nil {
+		return nil, err
+	}
+	if len(gcs) == 0 {
+		return nil, notFound("globalconfig", name)
+	}
+	return &gcs[0], nil
+}
+
+func globalConfigVars(gc *GlobalConfig) vars {
+	return vars{
+		"globalconfig_name": gc.Name,
+		"globalconfig_type": gc.Type,
+		"config":            gc.Config,
+		"config_json":       jsonb(gc.ConfigJSON),
+	}
+}
+
+func (h *Hasura) CreateGlobalConfig(gc *GlobalConfig) error {
+	q := `
+mutation create_globalconfig($globalconfig_name: String!, $globalconfig_type: String, $config: String, $config_json: jsonb) {
+  insert_saaras_db_globalconfig(objects: {
+    globalconfig_name: $globalconfig_name,
+    globalconfig_type: $globalconfig_type,
+    config: $config,
+    config_json: $config_json
+  }) { affected_rows }
+}`
+	return h.run(q, globalConfigVars(gc), nil)
+}
+
+func (h *Hasura) UpdateGlobalConfig(gc *GlobalConfig) error {
+	q := `
+mutation update_globalconfig($globalconfig_name: String!, $globalconfig_type: String, $config: String, $config_json: jsonb) {
+  update_saaras_db_globalconfig(where: {globalconfig_name: {_eq: $globalconfig_name}}, _set: {
+    globalconfig_type: $globalconfig_type,
+    config: $config,
+    config_json: $config_json
+  }) { affected_rows }
+}`
+	return h.mutate(q, "update_saaras_db_globalconfig", globalConfigVars(gc), "globalconfig", gc.Name)
+}
+
+func (h *Hasura) DeleteGlobalConfig(name string) error {
+	q := `
+mutation delete_globalconfig($name: String!) {
+  delete_saaras_db_globalconfig(where: {globalconfig_name: {_eq: $name}}) { affected_rows }
+}`
+	return h.mutate(q, "delete_saaras_db_globalconfig", vars{"name": name}, "globalconfig", name)
+}
+
+// Cascades
+
+// cascadeDeletes are the deletes of the associations made with an object
+// of each kind, and of the object itself last.
+var cascadeDeletes = map[string][]string{
+	"upstream": {
+		`delete_saaras_db_route_upstream(where: {upstream: {upstream_name: {_eq: $name}}}) { affected_rows }`,
+	},
+	"filter": {
+		`delete_saaras_db_route_filter(where: {filter: {filter_name: {_eq: $name}}}) { affected_rows }`,
+		`delete_saaras_db_service_filter(where: {filter: {filter_name: {_eq: $name}}}) { affected_rows }`,
+	},
+	"secret": {
+		`delete_saaras_db_service_secret(where: {secret: {secret_name: {_eq: $name}}}) { affected_rows }`,
+	},
+	"service": {
+		`delete_saaras_db_proxy_service(where: {service: {service_name: {_eq: $name}}}) { affected_rows }`,
+		`delete_saaras_db_route_upstream(where: {route: {service: {service_name: {_eq: $name}}}}) { affected_rows }`,
+		`delete_saaras_db_route_filter(where: {route: {service: {service_name: {_eq: $name}}}}) { affected_rows }`,
+		`delete_saaras_db_service_secret(where: {service: {service_name: {_eq: $name}}}) { affected_rows }`,
+		`delete_saaras_db_service_filter(where: {service: {service_name: {_eq: $name}}}) { affected_rows }`,
+		`delete_saaras_db_route(where: {service: {service_name: {_eq: $name}}}) { affected_rows }`,
+	},
+}
+
+// DeleteCascade runs as a single mutation, which Hasura runs in one
+// transaction.
+func (h *Hasura) DeleteCascade(kind, name string) error {
+	deletes, ok := cascadeDeletes[kind]
+	if !ok {
+		return fmt.Errorf("cannot cascade the delete of a %s", kind)
+	}
+	q := "mutation delete_cascade($name: String!) {"
+	for _, d := range deletes {
+		q += "\n  " + d
+	}
+	q += fmt.Sprintf("\n  delete_saaras_db_%[1]s(where: {%[1]s_name: {_eq: $name}}) { affected_rows }\n}", kind)
+	return h.mutate(q, "delete_saaras_db_"+kind, vars{"name": name}, kind, name)
+}
+
+// Revisions
+
+const revisionFields = `revision_id proxy_name author config diff create_ts`
+
+func (h *Hasura) ListRevisions(proxy string) ([]Revision, error) {
+	var rs []Revision
+	q := `
+query list_revisions($proxy_name: String!) {
+  saaras_db_revision(where: {proxy_name: {_eq: $proxy_name}}, order_by: {revision_id: asc}) { ` + revisionFields + ` }
+}`
+	err := h.list(q, "saaras_db_revision", vars{"proxy_name": proxy}, &rs)
+	return rs, err
+}
+
+func (h *Hasura) GetRevision(proxy string, id int64) (*Revision, error) {
+	var rs []Revision
+	q := `
+query get_revision($proxy_name: String!, $revision_id: bigint!) {
+  saaras_db_revision(where: {proxy_name: {_eq: $proxy_name}, revision_id: {_eq: $revision_id}}) { ` + revisionFields + ` }
+}`
+	if err := h.list(q, "saaras_db_revision", vars{"proxy_name": proxy, "revision_id": id}, &rs); err != nil {
+		return nil, err
+	}
+	if len(rs) == 0 {
+		return nil, notFound("revision", fmt.Sprintf("%s/%d", proxy, id))
+	}
+	return &rs[0], nil
+}
+
+func (h *Hasura) LatestRevision(proxy string) (*Revision, error) {
+	var rs []Revision
+	q := `
+query latest_revision($proxy_name: String!) {
+  saaras_db_revision(where: {proxy_name: {_eq: $proxy_name}}, order_by: {revision_id: desc}, limit: 1) { ` + revisionFields + ` }
+}`
+	if err := h.list(q, "saaras_db_revision", vars{"proxy_name": proxy}, &rs); err != nil {
+		return nil, err
+	}
+	if len(rs) == 0 {
+		return nil, notFound("revision", proxy+"/latest")
+	}
+	return &rs[0], nil
+}
+
+const insertRevision = `
+  insert_saaras_db_revision(objects: {proxy_name: $proxy_name, author: $author, config: $config, diff: $diff}) {
+    returning { revision_id create_ts }
+  }`
+
+func revisionVars(r *Revision) vars {
+	return vars{
+		"proxy_name": r.Proxy,
+		"author":     r.Author,
+		"config":     jsonb(r.Config),
+		"diff":       r.Diff,
+	}
+}
+
+// setRevision sets the ID and CreateTS of r from the data of a response
+// to insertRevision.
+func setRevision(r *Revision, data map[string]json.RawMessage) error {
+	var ins struct {
+		Returning []Revision `json:"returning"`
+	}
+	if err := json.Unmarshal(data["insert_saaras_db_revision"], &ins); err != nil {
+		return err
+	}
+	if len(ins.Returning) == 0 {
+		return fmt.Errorf("graphql: no revision returned")
+	}
+	r.ID = ins.Returning[0].ID
+	r.CreateTS = ins.Returning[0].CreateTS
+	return nil
+}
+
+func (h *Hasura) CreateRevision(r *Revision) error {
+	q := `
+mutation create_revision($proxy_name: String!, $author: String, $config: jsonb, $diff: String) {` + insertRevision + `
+}`
+	var data map[string]json.RawMessage
+	if err := h.run(q, revisionVars(r), &data); err != nil {
+		return err
+	}
+	return setRevision(r, data)
+}
+
+// upsert returns the nested insert of the object data into a relationship
+// of another, which updates the columns update of the object named by
+// constraint if it exists. The update makes Hasura return the existing
+// object, which the insert needs.
+func upsert(data vars, constraint string, update ...string) vars {
+	return vars{
+		"data": data,
+		"on_conflict": vars{
+			"constraint":     constraint,
+			"update_columns": update,
+		},
+	}
+}
+
+// Upserts of each object, updating all columns but the name
+
+func upsertService(s *Service) vars {
+	return upsert(vars{"service_name": s.Name, "fqdn": s.Fqdn},
+		"service_service_name_key", "fqdn")
+}
+
+func upsertRoute(service *Service, r *Route) vars {
+	return upsert(vars{
+		"service":      upsertService(service),
+		"route_name":   r.Name,
+		"route_prefix": r.Prefix,
+		"route_config": r.Config,
+		"config_json":  jsonb(r.ConfigJSON),
+	}, "route_service_id_route_name_key", "route_prefix", "route_config", "config_json")
+}
+
+func upsertUpstream(u *Upstream) vars {
+	return upsert(upstreamVars(u), "upstream_upstream_name_key", columns(upstreamVars(u), "upstream_name")...)
+}
+
+func upsertSecret(s *Secret) vars {
+	return upsert(secretVars(s), "secret_secret_name_key", columns(secretVars(s), "secret_name")...)
+}
+
+func upsertFilter(f *Filter) vars {
+	return upsert(filterVars(f), "filter_filter_name_key", columns(filterVars(f), "filter_name")...)
+}
+
+func upsertGlobalConfig(gc *GlobalConfig) vars {
+	return upsert(globalConfigVars(gc), "globalconfig_globalconfig_name_key", columns(globalConfigVars(gc), "globalconfig_name")...)
+}
+
+// columns returns the columns set in v but name.
+func columns(v vars, name string) []string {
+	var cs []string
+	for c := range v {
+		if c != name {
+			cs = append(cs, c)
+		}
+	}
+	sort.Strings(cs)
+	return cs
+}
+
+// RestoreProxy runs as a single mutation, which Hasura runs in one
+// transaction. It first deletes the associations of the proxy and its
+// services and the routes missing from pd, then inserts pd with nested
+// upserts.
+func (h *Hasura) RestoreProxy(pd *ProxyDetail, r *Revision) error {
+	v := revisionVars(r)
+	v["proxy_name"] = pd.Name
+	decls := []string{"$proxy_name: String!", "$author: String", "$config: jsonb", "$diff: String"}
+	fields := []string{`
+  delete_proxy_services: delete_saaras_db_proxy_service(where: {proxy: {proxy_name: {_eq: $proxy_name}}}) { affected_rows }
+  delete_proxy_globalconfigs: delete_saaras_db_proxy_globalconfig(where: {proxy: {proxy_name: {_eq: $proxy_name}}}) { affected_rows }`}
+
+	proxy := upsert(vars{"proxy_name": pd.Name}, "proxy_proxy_name_key", "proxy_name")
+	var proxyServices, proxyGlobalConfigs, serviceSecrets, serviceFilters, routeUpstreams, routeFilters []vars
+
+	for _, pgc := range pd.GlobalConfigs {
+		proxyGlobalConfigs = append(proxyGlobalConfigs, vars{
+			"proxy":        proxy,
+			"globalconfig": upsertGlobalConfig(&pgc.GlobalConfig),
+		})
+	}
+
+	for i, ps := range pd.Services {
+		sd := ps.Service
+		svc := fmt.Sprintf("service_%d", i)
+		routes := []string{}
+		for _, rd := range sd.Routes {
+			routes = append(routes, rd.Name)
+		}
+		v[svc] = sd.Name
+		v[svc+"_routes"] = routes
+		decls = append(decls, "$"+svc+": String!", "$"+svc+"_routes: [String!]!")
+		fields = append(fields, fmt.Sprintf(`
+  delete_%[1]s_route_upstreams: delete_saaras_db_route_upstream(where: {route: {service: {service_name: {_eq: $%[1]s}}}}) { affected_rows }
+  delete_%[1]s_route_filters: delete_saaras_db_route_filter(where: {route: {service: {service_name: {_eq: $%[1]s}}}}) { affected_rows }
+  delete_%[1]s_secrets: delete_saaras_db_service_secret(where: {service: {service_name: {_eq: $%[1]s}}}) { affected_rows }
+  delete_%[1]s_filters: delete_saaras_db_service_filter(where: {service: {service_name: {_eq: $%[1]s}}}) { affected_rows }
+  delete_%[1]s_routes: delete_saaras_db_route(where: {service: {service_name: {_eq: $%[1]s}}, route_name: {_nin: $%[1]s_routes}}) { affected_rows }`, svc))
+
+		proxyServices = append(proxyServices, vars{
+			"proxy":   proxy,
+			"service": upsertService(&sd.Service),
+		})
+		for _, ss := range sd.Secrets {
+			serviceSecrets = append(serviceSecrets, vars{
+				"service": upsertService(&sd.Service),
+				"secret":  upsertSecret(&ss.Secret),
+			})
+		}
+		for _, sf := range sd.Filters {
+			serviceFilters = append(serviceFilters, vars{
+				"service": upsertService(&sd.Service),
+				"filter":  upsertFilter(&sf.Filter),
+			})
+		}
+		for _, rd := range sd.Routes {
+			for _, ru := range rd.Upstreams {
+				routeUpstreams = append(routeUpstreams, vars{
+					"route":    upsertRoute(&sd.Service, &rd.Route),
+					"upstream": upsertUpstream(&ru.Upstream),
+				})
+			}
+			for _, rf := range rd.Filters {
+				routeFilters = append(routeFilters, vars{
+					"route":  upsertRoute(&sd.Service, &rd.Route),
+					"filter": upsertFilter(&rf.Filter),
+				})
+			}
+		}
+	}
+
+	fields = append(fields, `
+  insert_saaras_db_proxy(objects: {proxy_name: $proxy_name},
+    on_conflict: {constraint: proxy_proxy_name_key, update_columns: []}) { affected_rows }`)
+
+	// Routes are inserted along with the associations, but those
+	// without any need their own insert
+	var routes []vars
+	for _, ps := range pd.Services {
+		for _, rd := range ps.Service.Routes {
+			route := upsertRoute(&ps.Service.Service, &rd.Route)
+			routes = append(routes, route["data"].(vars))
+		}
+	}
+	inserts := []struct {
+		table, constraint string
+		objects           []vars
+	}{
+		{"route", "route_service_id_route_name_key", routes},
+		{"proxy_service", "proxy_service_proxy_id_service_id_key", proxyServices},
+		{"proxy_globalconfig", "proxy_globalconfig_pkey", proxyGlobalConfigs},
+		{"service_secret", "service_secret_service_id_secret_id_key", serviceSecrets},
+		{"service_filter", "service_filter_pkey", serviceFilters},
+		{"route_upstream", "route_upstream_route_id_upstream_id_key", routeUpstreams},
+		{"route_filter", "route_filter_pkey", routeFilters},
+	}
+	for _, ins := range inserts {
+		if len(ins.objects) == 0 {
+			continue
+		}
+		update := "[]"
+		if ins.table == "route" {
+			update = "[route_prefix, route_config, config_json]"
+		}
+		v[ins.table] = ins.objects
+		decls = append(decls, fmt.Sprintf("$%s: [saaras_db_%s_insert_input!]!", ins.table, ins.table))
+		fields = append(fields, fmt.Sprintf(`
+  insert_saaras_db_%[1]s(objects: $%[1]s, on_conflict: {constraint: %[2]s, update_columns: %[3]s}) { affected_rows }`,
+			ins.table, ins.constraint, update))
+	}
+
+	q := "mutation restore_proxy(" + strings.Join(decls, ", ") + ") {" +
+		strings.Join(fields, "") + insertRevision + "\n}"
+
+	var data map[string]json.RawMessage
+	if err := h.run(q, v, &data); err != nil {
+		return err
+	}
+	return setRevision(r, data)
+}
+
+// Tokens
+
+const tokenFields = `token_id token_name token_hash token_role token_proxies token_services create_ts`
+
+func (h *Hasura) ListTokens() ([]Token, error) {
+	var ts []Token
+	q := `query { saaras_db_token(order_by: {token_name: asc}) { ` + tokenFields + ` } }`
+	err := h.list(q, "saaras_db_token", nil, &ts)
+	return ts, err
+}
+
+func (h *Hasura) GetToken(name string) (*Token, error) {
+	var ts []Token
+	q := `query get_token($name: String!) { saaras_db_token(where: {token_name: {_eq: $name}}) { ` + tokenFields + ` } }`
+	if err := h.list(q, "saaras_db_token", vars{"name": name}, &ts); err != nil {
+		return nil, err
+	}
+	if len(ts) == 0 {
+		return nil, notFound("token", name)
+	}
+	return &ts[0], nil
+}
+
+func (h *Hasura) TokenByHash(hash string) (*Token, error) {
+	var ts []Token
+	q := `query token_by_hash($hash: String!) { saaras_db_token(where: {token_hash: {_eq: $hash}}) { ` + tokenFields + ` } }`
+	if err := h.list(q, "saaras_db_token", vars{"hash": hash}, &ts); err != nil {
+		return nil, err
+	}
+	if len(ts) == 0 {
+		return nil, notFound("token", "with digest "+hash)
+	}
+	return &ts[0], nil
+}
+
+func (h *Hasura) CreateToken(t *Token) error {
+	q := `
+mutation create_token($token_name: String!, $token_hash: String!, $token_role: String!, $token_proxies: jsonb, $token_services: jsonb) {
+  insert_saaras_db_token(objects: {
+    token_name: $token_name,
+    token_hash: $token_hash,
+    token_role: $token_role,
+    token_proxies: $token_proxies,
+    token_services: $token_services
+  }) {
+    returning { token_id create_ts }
+  }
+}`
+	v := vars{
+		"token_name":     t.Name,
+		"token_hash":     t.Hash,
+		"token_role":     t.Role,
+		"token_proxies":  t.Proxies,
+		"token_services": t.Services,
+	}
+	var data struct {
+		Insert struct {
+			Returning []Token `json:"returning"`
+		} `json:"insert_saaras_db_token"`
+	}
+	if err := h.run(q, v, &data); err != nil {
+		return err
+	}
+	if len(data.Insert.Returning) == 0 {
+		return fmt.Errorf("graphql: no token returned")
+	}
+	t.ID = data.Insert.Returning[0].ID
+	t.CreateTS = data.Insert.Returning[0].CreateTS
+	return nil
+}
+
+func (h *Hasura) DeleteToken(name string) error {
+	q := `
+mutation delete_token($name: String!) {
+  delete_saaras_db_token(where: {token_name: {_eq: $name}}) { affected_rows }
+}`
+	return h.mutate(q, "delete_saaras_db_token", vars{"name": name}, "token", name)
+}
+
+// Webhooks
+
+const webhookFields = `webhook_id webhook_name webhook_url webhook_secret webhook_events create_ts`
+
+func (h *Hasura) ListWebhooks() ([]Webhook, error) {
+	var ws []Webhook
+	q := `query { saaras_db_webhook(order_by: {webhook_name: asc}) { ` + webhookFields + ` } }`
+	err := h.list(q, "saaras_db_webhook", nil, &ws)
+	return ws, err
+}
+
+func (h *Hasura) GetWebhook(name string) (*Webhook, error) {
+	var ws []Webhook
+	q := `query get_webhook($name: String!) { saaras_db_webhook(where: {webhook_name: {_eq: $name}}) { ` + webhookFields + ` } }`
+	if err := h.list(q, "saaras_db_webhook", vars{"name": name}, &ws); err != nil {
+		return nil, err
+	}
+	if len(ws) == 0 {
+		return nil, notFound("webhook", name)
+	}
+	return &ws[0], nil
+}
+
+func (h *Hasura) CreateWebhook(w *Webhook) error {
+	q := `
+mutation create_webhook($webhook_name: String!, $webhook_url: String!, $webhook_secret: String!, $webhook_events: jsonb) {
+  insert_saaras_db_webhook(objects: {
+    webhook_name: $webhook_name,
+    webhook_url: $webhook_url,
+    webhook_secret: $webhook_secret,
+    webhook_events: $webhook_events
+  }) {
+    returning { webhook_id create_ts }
+  }
+}`
+	v := vars{
+		"webhook_name":   w.Name,
+		"webhook_url":    w.URL,
+		"webhook_secret": w.Secret,
+		"webhook_events": w.Events,
+	}
+	var data struct {
+		Insert struct {
+			Returning []Webhook `json:"returning"`
+		} `json:"insert_saaras_db_webhook"`
+	}
+	if err := h.run(q, v, &data); err != nil {
+		return err
+	}
+	if len(data.Insert.Returning) == 0 {
+		return fmt.Errorf("graphql: no webhook returned")
+	}
+	w.ID = data.Insert.Returning[0].ID
+	w.CreateTS = data.Insert.Returning[0].CreateTS
+	return nil
+}
+
+func (h *Hasura) DeleteWebhook(name string) error {
+	q := `
+mutation delete_webhook($name: String!) {
+  delete_saaras_db_webhook(where: {webhook_name: {_eq: $name}}) { affected_rows }
+}`
+	return h.mutate(q, "delete_saaras_db_webhook", vars{"name": name}, "webhook", name)
+}
+
+// Audit
+
+const auditFields = `audit_id token_name client_ip method endpoint path objects before after code create_ts`
+
+func (h *Hasura) CreateAuditRecord(r *AuditRecord) error {
+	q := `
+mutation create_audit($token_name: String, $client_ip: String, $method: String!, $endpoint: String!, $path: String!,
+    $objects: jsonb, $before: jsonb, $after: jsonb, $code: Int!) {
+  insert_saaras_db_audit(objects: {
+    token_name: $token_name,
+    client_ip: $client_ip,
+    method: $method,
+    endpoint: $endpoint,
+    path: $path,
+    objects: $objects,
+    before: $before,
+    after: $after,
+    code: $code
+  }) {
+    returning { audit_id create_ts }
+  }
+}`
+	objects := r.Objects
+	if objects == nil {
+		objects = []string{}
+	}
+	v := vars{
+		"token_name": r.Token,
+		"client_ip":  r.IP,
+		"method":     r.Method,
+		"endpoint":   r.Endpoint,
+		"path":       r.Path,
+		"objects":    objects,
+		"before":     r.Before,
+		"after":      r.After,
+		"code":       r.Code,
+	}
+	var data struct {
+		Insert struct {
+			Returning []AuditRecord `json:"returning"`
+		} `json:"insert_saaras_db_audit"`
+	}
+	if err := h.run(q, v, &data); err != nil {
+		return err
+	}
+	if len(data.Insert.Returning) == 0 {
+		return fmt.Errorf("graphql: no audit record returned")
+	}
+	r.ID = data.Insert.Returning[0].ID
+	r.CreateTS = data.Insert.Returning[0].CreateTS
+	return nil
+}
+
+func (h *Hasura) ListAuditRecords(since time.Time, object string) ([]AuditRecord, error) {
+	var rs []AuditRecord
+	q := `
+query list_audit($since: timestamptz!, $objects: jsonb!) {
+  saaras_db_audit(where: {create_ts: {_gte: $since}, objects: {_contains: $objects}}, order_by: {audit_id: asc}) { ` + auditFields + ` }
+}`
+	objects := []string{}
+	if object != "" {
+		objects = append(objects, object)
+	}
+	err := h.list(q, "saaras_db_audit", vars{"since": since, "objects": objects}, &rs)
+	return rs, err
+}
@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package store_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/saarasio/enroute/enroute-cp/store/storetest"
+)
+
+// TestHasura runs against the Hasura GraphQL endpoint in
+// ENROUTE_TEST_HASURA_URL, e.g. http://127.0.0.1:8888/v1/graphql, which
+// must have the migrations in packaging/enroute-cp/migrations applied.
+// make test-hasura starts one with docker-compose and runs this test.
+func TestHasura(t *testing.T) {
+	url := os.Getenv("ENROUTE_TEST_HASURA_URL")
+	if url == "" {
+		t.Skip("ENROUTE_TEST_HASURA_URL not set")
+	}
+	storetest.Run(t, func(t *testing.T) store.Store {
+		h := store.NewHasura("", "")
+		h.URL = url
+		return h
+	})
+}
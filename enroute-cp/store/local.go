@@ -0,0 +1,1728 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Local is a Store kept in a single file. The whole configuration is held
+// in memory and the file is rewritten on every change, which suits the
+// size of a gateway configuration. The file is not locked, it must only be
+// opened by one process at a time.
+//
+// Revisions and audit records would otherwise grow the file without bound,
+// so only the latest are kept.
+type Local struct {
+	path string
+
+	// RevisionLimit is the number of revisions kept of each proxy, and
+	// AuditLimit the number of audit records kept. Older ones are dropped,
+	// and none are if the limit is 0.
+	RevisionLimit int
+	AuditLimit    int
+
+	mu sync.RWMutex
+	st *localState
+}
+
+type localProxy struct {
+	Proxy
+	Services      map[string]bool `json:"services"`
+	GlobalConfigs map[string]bool `json:"globalconfigs"`
+}
+
+type localService struct {
+	Service
+	Routes  map[string]*localRoute `json:"routes"`
+	Secrets map[string]bool        `json:"secrets"`
+	Filters map[string]bool        `json:"filters"`
+}
+
+type localRoute struct {
+	Route
+	Upstreams map[string]bool `json:"upstreams"`
+	Filters   map[string]bool `json:"filters"`
+}
+
+// localState is the content of the file.
+type localState struct {
+	Seq           int64                    `json:"seq"`
+	Proxies       map[string]*localProxy   `json:"proxies"`
+	Services      map[string]*localService `json:"services"`
+	Upstreams     map[string]*Upstream     `json:"upstreams"`
+	Secrets       map[string]*Secret       `json:"secrets"`
+	Filters       map[string]*Filter       `json:"filters"`
+	GlobalConfigs map[string]*GlobalConfig `json:"globalconfigs"`
+
+	RevisionSeq int64       `json:"revision_seq"`
+	Revisions   []*Revision `json:"revisions"`
+
+	Tokens map[string]*Token `json:"tokens"`
+
+	AuditSeq int64          `json:"audit_seq"`
+	Audit    []*AuditRecord `json:"audit"`
+
+	Webhooks map[string]*Webhook `json:"webhooks"`
+}
+
+// The limits a Local store is opened with
+const (
+	DefaultRevisionLimit = 100
+	DefaultAuditLimit    = 10000
+)
+
+// OpenLocal opens the store in the file at path, which is created on the
+// first change if it does not exist.
+func OpenLocal(path string) (*Local, error) {
+	l := &Local{
+		path:          path,
+		st:            new(localState),
+		RevisionLimit: DefaultRevisionLimit,
+		AuditLimit:    DefaultAuditLimit,
+	}
+
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, l.st); err != nil {
+			return nil, fmt.Errorf("reading %s: %v", path, err)
+		}
+	}
+
+	l.st.init()
+	return l, nil
+}
+
+// init allocates the maps missing from st and sets the service of
+// every route.
+func (st *localState) init() {
+	if st.Proxies == nil {
+		st.Proxies = make(map[string]*localProxy)
+	}
+	if st.Services == nil {
+		st.Services = make(map[string]*localService)
+	}
+	if st.Upstreams == nil {
+		st.Upstreams = make(map[string]*Upstream)
+	}
+	if st.Secrets == nil {
+		st.Secrets = make(map[string]*Secret)
+	}
+	if st.Filters == nil {
+		st.Filters = make(map[string]*Filter)
+	}
+	if st.GlobalConfigs == nil {
+		st.GlobalConfigs = make(map[string]*GlobalConfig)
+	}
+	if st.Tokens == nil {
+		st.Tokens = make(map[string]*Token)
+	}
+	if st.Webhooks == nil {
+		st.Webhooks = make(map[string]*Webhook)
+	}
+	for _, p := range st.Proxies {
+		if p.Services == nil {
+			p.Services = make(map[string]bool)
+		}
+		if p.GlobalConfigs == nil {
+			p.GlobalConfigs = make(map[string]bool)
+		}
+	}
+	for sname, s := range st.Services {
+		if s.Routes == nil {
+			s.Routes = make(map[string]*localRoute)
+		}
+		if s.Secrets == nil {
+			s.Secrets = make(map[string]bool)
+		}
+		if s.Filters == nil {
+			s.Filters = make(map[string]bool)
+		}
+		for _, r := range s.Routes {
+			r.Service = sname
+			if r.Upstreams == nil {
+				r.Upstreams = make(map[string]bool)
+			}
+			if r.Filters == nil {
+				r.Filters = make(map[string]bool)
+			}
+		}
+	}
+}
+
+func (st *localState) nextID() int64 {
+	st.Seq++
+	return st.Seq
+}
+
+// view runs fn with the current state, which fn must not change.
+func (l *Local) view(fn func(st *localState) error) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return fn(l.st)
+}
+
+// update runs fn on a copy of the state and, if fn succeeds, writes the
+// copy to the file and makes it the current state. A failed fn or write
+// leaves the state as it was.
+func (l *Local) update(fn func(st *localState, now time.Time) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := json.Marshal(l.st)
+	if err != nil {
+		return err
+	}
+	st := new(localState)
+	if err := json.Unmarshal(data, st); err != nil {
+		return err
+	}
+	st.init()
+
+	if err := fn(st, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	data, err = json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(l.path, data); err != nil {
+		return err
+	}
+	l.st = st
+	return nil
+}
+
+// writeFile replaces the file at path with data, so that a crash leaves
+// either the old or the new content. The file holds secret keys and is
+// only readable by its owner.
+func writeFile(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func notFound(kind, name string) error {
+	return &ObjectError{Kind: kind, Name: name, Err: ErrNotFound}
+}
+
+func exists(kind, name string) error {
+	return &ObjectError{Kind: kind, Name: name, Err: ErrExists}
+}
+
+func inUse(kind, name, by string) error {
+	return &ObjectError{Kind: kind, Name: name, By: by, Err: ErrInUse}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k, ok := range m {
+		if ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Getters returning the object in st, or ErrNotFound
+
+func (st *localState) proxy(name string) (*localProxy, error) {
+	if p, ok := st.Proxies[name]; ok {
+		return p, nil
+	}
+	return nil, notFound("proxy", name)
+}
+
+func (st *localState) service(name string) (*localService, error) {
+	if s, ok := st.Services[name]; ok {
+		return s, nil
+	}
+	return nil, notFound("service", name)
+}
+
+func (st *localState) route(service, name string) (*localRoute, error) {
+	s, err := st.service(service)
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := s.Routes[name]; ok {
+		return r, nil
+	}
+	return nil, notFound("route", service+"/"+name)
+}
+
+func (st *localState) upstream(name string) (*Upstream, error) {
+	if u, ok := st.Upstreams[name]; ok {
+		return u, nil
+	}
+	return nil, notFound("upstream", name)
+}
+
+func (st *localState) secret(name string) (*Secret, error) {
+	if s, ok := st.Secrets[name]; ok {
+		return s, nil
+	}
+	return nil, notFound("secret", name)
+}
+
+func (st *localState) filter(name string) (*Filter, error) {
+	if f, ok := st.Filters[name]; ok {
+		return f, nil
+	}
+	return nil, notFound("filter", name)
+}
+
+func (st *localState) globalConfig(name string) (*GlobalConfig, error) {
+	if gc, ok := st.GlobalConfigs[name]; ok {
+		return gc, nil
+	}
+	return nil, notFound("globalconfig", name)
+}
+
+// Proxies
+
+func (l *Local) ListProxies() ([]Proxy, error) {
+	var ps []Proxy
+	err := l.view(func(st *localState) error {
+		for _, p := range st.Proxies {
+			ps = append(ps, p.Proxy)
+		}
+		return nil
+	})
+	sort.Slice(ps, func(i, j int) bool { return ps[i].Name < ps[j].Name })
+	return ps, err
+}
+
+func (l *Local) GetProxy(name string) (*Proxy, error) {
+	var p Proxy
+	err := l.view(func(st *localState) error {
+		lp, err := st.proxy(name)
+		if err != nil {
+			return err
+		}
+		p = lp.Proxy
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (l *Local) CreateProxy(name string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		if _, ok := st.Proxies[name]; ok {
+			return nil
+		}
+		st.Proxies[name] = &localProxy{
+			Proxy:         Proxy{ID: st.nextID(), Name: name, CreateTS: now, UpdateTS: now},
+			Services:      make(map[string]bool),
+			GlobalConfigs: make(map[string]bool),
+		}
+		return nil
+	})
+}
+
+func (l *Local) DeleteProxy(name string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		p, err := st.proxy(name)
+		if err != nil {
+			return err
+		}
+		if s := sortedKeys(p.Services); len(s) > 0 {
+			return inUse("proxy", name, "service "+s[0])
+		}
+		if gc := sortedKeys(p.GlobalConfigs); len(gc) > 0 {
+			return inUse("proxy", name, "globalconfig "+gc[0])
+		}
+		delete(st.Proxies, name)
+		return nil
+	})
+}
+
+func (st *localState) proxyDetail(p *localProxy) ProxyDetail {
+	pd := ProxyDetail{
+		Proxy:         p.Proxy,
+		GlobalConfigs: []ProxyGlobalConfig{},
+		Services:      []ProxyService{},
+	}
+	for _, name := range sortedKeys(p.GlobalConfigs) {
+		pd.GlobalConfigs = append(pd.GlobalConfigs, ProxyGlobalConfig{GlobalConfig: *st.GlobalConfigs[name]})
+	}
+	for _, name := range sortedKeys(p.Services) {
+		pd.Services = append(pd.Services, ProxyService{Service: st.serviceDetail(st.Services[name])})
+	}
+	return pd
+}
+
+func (st *localState) serviceDetail(s *localService) ServiceDetail {
+	sd := ServiceDetail{
+		Service: s.Service,
+		Routes:  []RouteDetail{},
+		Secrets: []ServiceSecret{},
+		Filters: []ServiceFilter{},
+	}
+	rnames := make([]string, 0, len(s.Routes))
+	for name := range s.Routes {
+		rnames = append(rnames, name)
+	}
+	sort.Strings(rnames)
+	for _, name := range rnames {
+		r := s.Routes[name]
+		rd := RouteDetail{
+			Route:     r.Route,
+			Upstreams: []RouteUpstream{},
+			Filters:   []RouteFilter{},
+		}
+		for _, u := range sortedKeys(r.Upstreams) {
+			rd.Upstreams = append(rd.Upstreams, RouteUpstream{Upstream: *st.Upstreams[u]})
+		}
+		for _, f := range sortedKeys(r.Filters) {
+			rd.Filters = append(rd.Filters, RouteFilter{Filter: *st.Filters[f]})
+		}
+		sd.Routes = append(sd.Routes, rd)
+	}
+	for _, name := range sortedKeys(s.Secrets) {
+		sd.Secrets = append(sd.Secrets, ServiceSecret{Secret: *st.Secrets[name]})
+	}
+	for _, name := range sortedKeys(s.Filters) {
+		sd.Filters = append(sd.Filters, ServiceFilter{Filter: *st.Filters[name]})
+	}
+	return sd
+}
+
+func (l *Local) ProxyDetail(name string) (*ProxyDetail, error) {
+	var pd ProxyDetail
+	err := l.view(func(st *localState) error {
+		p, err := st.proxy(name)
+		if err != nil {
+			return err
+		}
+		pd = st.proxyDetail(p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pd, nil
+}
+
+func (l *Local) ListProxyDetails() ([]ProxyDetail, error) {
+	var pds []ProxyDetail
+	err := l.view(func(st *localState) error {
+		for _, p := range st.Proxies {
+			pds = append(pds, st.proxyDetail(p))
+		}
+		return nil
+	})
+	sort.Slice(pds, func(i, j int) bool { return pds[i].Name < pds[j].Name })
+	return pds, err
+}
+
+func (l *Local) AssociateProxyService(proxy, service string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		p, err := st.proxy(proxy)
+		if err != nil {
+			return err
+		}
+		if _, err := st.service(service); err != nil {
+			return err
+		}
+		p.Services[service] = true
+		return nil
+	})
+}
+
+func (l *Local) DisassociateProxyService(proxy, service string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		p, err := st.proxy(proxy)
+		if err != nil {
+			return err
+		}
+		if !p.Services[service] {
+			return notFound("proxy service", proxy+"/"+service)
+		}
+		delete(p.Services, service)
+		return nil
+	})
+}
+
+func (l *Local) ListProxyServices(proxy string) ([]Service, error) {
+	var ss []Service
+	err := l.view(func(st *localState) error {
+		p, err := st.proxy(proxy)
+		if err != nil {
+			return err
+		}
+		for _, name := range sortedKeys(p.Services) {
+			ss = append(ss, st.Services[name].Service)
+		}
+		return nil
+	})
+	return ss, err
+}
+
+func (l *Local) ListServiceProxies(service string) ([]Proxy, error) {
+	var ps []Proxy
+	err := l.view(func(st *localState) error {
+		if _, err := st.service(service); err != nil {
+			return err
+		}
+		for _, p := range st.Proxies {
+			if p.Services[service] {
+				ps = append(ps, p.Proxy)
+			}
+		}
+		return nil
+	})
+	sort.Slice(ps, func(i, j int) bool { return ps[i].Name < ps[j].Name })
+	return ps, err
+}
+
+func (l *Local) AssociateProxyGlobalConfig(proxy, globalconfig string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		p, err := st.proxy(proxy)
+		if err != nil {
+			return err
+		}
+		if _, err := st.globalConfig(globalconfig); err != nil {
+			return err
+		}
+		p.GlobalConfigs[globalconfig] = true
+		return nil
+	})
+}
+
+func (l *Local) DisassociateProxyGlobalConfig(proxy, globalconfig string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		p, err := st.proxy(proxy)
+		if err != nil {
+			return err
+		}
+		if !p.GlobalConfigs[globalconfig] {
+			return notFound("proxy globalconfig", proxy+"/"+globalconfig)
+		}
+		delete(p.GlobalConfigs, globalconfig)
+		return nil
+	})
+}
+
+func (l *Local) ListProxyGlobalConfigs(proxy string) ([]GlobalConfig, error) {
+	var gcs []GlobalConfig
+	err := l.view(func(st *localState) error {
+		p, err := st.proxy(proxy)
+		if err != nil {
+			return err
+		}
+		for _, name := range sortedKeys(p.GlobalConfigs) {
+			gcs = append(gcs, *st.GlobalConfigs[name])
+		}
+		return nil
+	})
+	return gcs, err
+}
+
+// Services
+
+func (l *Local) ListServices() ([]Service, error) {
+	var ss []Service
+	err := l.view(func(st *localState) error {
+		for _, s := range st.Services {
+			ss = append(ss, s.Service)
+		}
+		return nil
+	})
+	sort.Slice(ss, func(i, j int) bool { return ss[i].Name < ss[j].Name })
+	return ss, err
+}
+
+func (l *Local) GetService(name string) (*Service, error) {
+	var s Service
+	err := l.view(func(st *localState) error {
+		ls, err := st.service(name)
+		if err != nil {
+			return err
+		}
+		s = ls.Service
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (l *Local) CreateService(s *Service) error {
+	return l.update(func(st *localState, now time.Time) error {
+		if _, ok := st.Services[s.Name]; ok {
+			return nil
+		}
+		st.Services[s.Name] = &localService{
+			Service: Service{ID: st.nextID(), Name: s.Name, Fqdn: s.Fqdn, CreateTS: now, UpdateTS: now},
+			Routes:  make(map[string]*localRoute),
+			Secrets: make(map[string]bool),
+			Filters: make(map[string]bool),
+		}
+		return nil
+	})
+}
+
+func (l *Local) UpdateService(s *Service) error {
+	return l.update(func(st *localState, now time.Time) error {
+		ls, err := st.service(s.Name)
+		if err != nil {
+			return err
+		}
+		ls.Fqdn = s.Fqdn
+		ls.UpdateTS = now
+		return nil
+	})
+}
+
+func (l *Local) DeleteService(name string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		s, err := st.service(name)
+		if err != nil {
+			return err
+		}
+		for _, p := range sortedProxies(st) {
+			if p.Services[name] {
+				return inUse("service", name, "proxy "+p.Name)
+			}
+		}
+		for _, r := range s.Routes {
+			return inUse("service", name, "route "+r.Name)
+		}
+		if sec := sortedKeys(s.Secrets); len(sec) > 0 {
+			return inUse("service", name, "secret "+sec[0])
+		}
+		if f := sortedKeys(s.Filters); len(f) > 0 {
+			return inUse("service", name, "filter "+f[0])
+		}
+		delete(st.Services, name)
+		return nil
+	})
+}
+
+func sortedProxies(st *localState) []*localProxy {
+	ps := make([]*localProxy, 0, len(st.Proxies))
+	for _, p := range st.Proxies {
+		ps = append(ps, p)
+	}
+	sort.Slice(ps, func(i, j int) bool { return ps[i].Name < ps[j].Name })
+	return ps
+}
+
+func (l *Local) ServiceDetail(name string) (*ServiceDetail, error) {
+	var sd ServiceDetail
+	err := l.view(func(st *localState) error {
+		s, err := st.service(name)
+		if err != nil {
+			return err
+		}
+		sd = st.serviceDetail(s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sd, nil
+}
+
+func (l *Local) AssociateServiceSecret(service, secret string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		s, err := st.service(service)
+		if err != nil {
+			return err
+		}
+		if _, err := st.secret(secret); err != nil {
+			return err
+		}
+		s.Secrets[secret] = true
+		return nil
+	})
+}
+
+func (l *Local) DisassociateServiceSecret(service, secret string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		s, err := st.service(service)
+		if err != nil {
+			return err
+		}
+		if !s.Secrets[secret] {
+			return notFound("service secret", service+"/"+secret)
+		}
+		delete(s.Secrets, secret)
+		return nil
+	})
+}
+
+func (l *Local) ListServiceSecrets(service string) ([]Secret, error) {
+	var ss []Secret
+	err := l.view(func(st *localState) error {
+		s, err := st.service(service)
+		if err != nil {
+			return err
+		}
+		for _, name := range sortedKeys(s.Secrets) {
+			ss = append(ss, *st.Secrets[name])
+		}
+		return nil
+	})
+	return ss, err
+}
+
+func (l *Local) ListSecretServices(secret string) ([]Service, error) {
+	var ss []Service
+	err := l.view(func(st *localState) error {
+		if _, err := st.secret(secret); err != nil {
+			return err
+		}
+		for _, s := range st.Services {
+			if s.Secrets[secret] {
+				ss = append(ss, s.Service)
+			}
+		}
+		return nil
+	})
+	sort.Slice(ss, func(i, j int) bool { return ss[i].Name < ss[j].Name })
+	return ss, err
+}
+
+func (l *Local) AssociateServiceFilter(service, filter string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		s, err := st.service(service)
+		if err != nil {
+			return err
+		}
+		if _, err := st.filter(filter); err != nil {
+			return err
+		}
+		s.Filters[filter] = true
+		return nil
+	})
+}
+
+func (l *Local) DisassociateServiceFilter(service, filter string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		s, err := st.service(service)
+		if err != nil {
+			return err
+		}
+		if !s.Filters[filter] {
+			return notFound("service filter", service+"/"+filter)
+		}
+		delete(s.Filters, filter)
+		return nil
+	})
+}
+
+func (l *Local) ListServiceFilters(service string) ([]Filter, error) {
+	var fs []Filter
+	err := l.view(func(st *localState) error {
+		s, err := st.service(service)
+		if err != nil {
+			return err
+		}
+		for _, name := range sortedKeys(s.Filters) {
+			fs = append(fs, *st.Filters[name])
+		}
+		return nil
+	})
+	return fs, err
+}
+
+func (l *Local) ListFilterServices(filter string) ([]Service, error) {
+	var ss []Service
+	err := l.view(func(st *localState) error {
+		if _, err := st.filter(filter); err != nil {
+			return err
+		}
+		for _, s := range st.Services {
+			if s.Filters[filter] {
+				ss = append(ss, s.Service)
+			}
+		}
+		return nil
+	})
+	sort.Slice(ss, func(i, j int) bool { return ss[i].Name < ss[j].Name })
+	return ss, err
+}
+
+// Routes
+
+func (l *Local) ListRoutes(service string) ([]Route, error) {
+	var rs []Route
+	err := l.view(func(st *localState) error {
+		s, err := st.service(service)
+		if err != nil {
+			return err
+		}
+		for _, r := range s.Routes {
+			rs = append(rs, r.Route)
+		}
+		return nil
+	})
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Name < rs[j].Name })
+	return rs, err
+}
+
+func (l *Local) GetRoute(service, name string) (*Route, error) {
+	var r Route
+	err := l.view(func(st *localState) error {
+		lr, err := st.route(service, name)
+		if err != nil {
+			return err
+		}
+		r = lr.Route
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (l *Local) CreateRoute(r *Route) error {
+	return l.update(func(st *localState, now time.Time) error {
+		s, err := st.service(r.Service)
+		if err != nil {
+			return err
+		}
+		if _, ok := s.Routes[r.Name]; ok {
+			return exists("route", r.Service+"/"+r.Name)
+		}
+		nr := *r
+		nr.ID = st.nextID()
+		nr.CreateTS = now
+		nr.UpdateTS = now
+		s.Routes[r.Name] = &localRoute{
+			Route:     nr,
+			Upstreams: make(map[string]bool),
+			Filters:   make(map[string]bool),
+		}
+		return nil
+	})
+}
+
+func (l *Local) UpdateRoute(r *Route) error {
+	return l.update(func(st *localState, now time.Time) error {
+		lr, err := st.route(r.Service, r.Name)
+		if err != nil {
+			return err
+		}
+		lr.Prefix = r.Prefix
+		lr.Config = r.Config
+		lr.ConfigJSON = r.ConfigJSON
+		lr.UpdateTS = now
+		return nil
+	})
+}
+
+func (l *Local) DeleteRoute(service, name string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		r, err := st.route(service, name)
+		if err != nil {
+			return err
+		}
+		if u := sortedKeys(r.Upstreams); len(u) > 0 {
+			return inUse("route", service+"/"+name, "upstream "+u[0])
+		}
+		if f := sortedKeys(r.Filters); len(f) > 0 {
+			return inUse("route", service+"/"+name, "filter "+f[0])
+		}
+		delete(st.Services[service].Routes, name)
+		return nil
+	})
+}
+
+func (l *Local) AssociateRouteUpstream(service, route, upstream string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		r, err := st.route(service, route)
+		if err != nil {
+			return err
+		}
+		if _, err := st.upstream(upstream); err != nil {
+			return err
+		}
+		r.Upstreams[upstream] = true
+		return nil
+	})
+}
+
+func (l *Local) DisassociateRouteUpstream(service, route, upstream string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		r, err := st.route(service, route)
+		if err != nil {
+			return err
+		}
+		if !r.Upstreams[upstream] {
+			return notFound("route upstream", service+"/"+route+"/"+upstream)
+		}
+		delete(r.Upstreams, upstream)
+		return nil
+	})
+}
+
+func (l *Local) ListRouteUpstreams(service, route string) ([]Upstream, error) {
+	var us []Upstream
+	err := l.view(func(st *localState) error {
+		r, err := st.route(service, route)
+		if err != nil {
+			return err
+		}
+		for _, name := range sortedKeys(r.Upstreams) {
+			us = append(us, *st.Upstreams[name])
+		}
+		return nil
+	})
+	return us, err
+}
+
+func (l *Local) ListUpstreamRoutes(upstream string) ([]Route, error) {
+	var rs []Route
+	err := l.view(func(st *localState) error {
+		if _, err := st.upstream(upstream); err != nil {
+			return err
+		}
+		for _, s := range st.Services {
+			for _, r := range s.Routes {
+				if r.Upstreams[upstream] {
+					rs = append(rs, r.Route)
+				}
+			}
+		}
+		return nil
+	})
+	sortRoutes(rs)
+	return rs, err
+}
+
+// sortRoutes sorts rs by service, then name.
+func sortRoutes(rs []Route) {
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].Service != rs[j].Service {
+			return rs[i].Service < rs[j].Service
+		}
+		return rs[i].Name < rs[j].Name
+	})
+}
+
+func (l *Local) AssociateRouteFilter(service, route, filter string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		r, err := st.route(service, route)
+		if err != nil {
+			return err
+		}
+		if _, err := st.filter(filter); err != nil {
+			return err
+		}
+		r.Filters[filter] = true
+		return nil
+	})
+}
+
+func (l *Local) DisassociateRouteFilter(service, route, filter string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		r, err := st.route(service, route)
+		if err != nil {
+			return err
+		}
+		if !r.Filters[filter] {
+			return notFound("route filter", service+"/"+route+"/"+filter)
+		}
+		delete(r.Filters, filter)
+		return nil
+	})
+}
+
+func (l *Local) ListRouteFilters(service, route string) ([]Filter, error) {
+	var fs []Filter
+	err := l.view(func(st *localState) error {
+		r, err := st.route(service, route)
+		if err != nil {
+			return err
+		}
+		for _, name := range sortedKeys(r.Filters) {
+			fs = append(fs, *st.Filters[name])
+		}
+		return nil
+	})
+	return fs, err
+}
+
+func (l *Local) ListFilterRoutes(filter string) ([]Route, error) {
+	var rs []Route
+	err := l.view(func(st *localState) error {
+		if _, err := st.filter(filter); err != nil {
+			return err
+		}
+		for _, s := range st.Services {
+			for _, r := range s.Routes {
+				if r.Filters[filter] {
+					rs = append(rs, r.Route)
+				}
+			}
+		}
+		return nil
+	})
+	sortRoutes(rs)
+	return rs, err
+}
+
+// Upstreams
+
+func (l *Local) ListUpstreams() ([]Upstream, error) {
+	var us []Upstream
+	err := l.view(func(st *localState) error {
+		for _, u := range st.Upstreams {
+			us = append(us, *u)
+		}
+		return nil
+	})
+	sort.Slice(us, func(i, j int) bool { return us[i].Name < us[j].Name })
+	return us, err
+}
+
+func (l *Local) GetUpstream(name string) (*Upstream, error) {
+	var u Upstream
+	err := l.view(func(st *localState) error {
+		lu, err := st.upstream(name)
+		if err != nil {
+			return err
+		}
+		u = *lu
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (l *Local) CreateUpstream(u *Upstream) error {
+	return l.update(func(st *localState, now time.Time) error {
+		if _, ok := st.Upstreams[u.Name]; ok {
+			return exists("upstream", u.Name)
+		}
+		nu := *u
+		nu.ID = st.nextID()
+		nu.CreateTS = now
+		nu.UpdateTS = now
+		st.Upstreams[u.Name] = &nu
+		return nil
+	})
+}
+
+func (l *Local) UpdateUpstream(u *Upstream) error {
+	return l.update(func(st *localState, now time.Time) error {
+		lu, err := st.upstream(u.Name)
+		if err != nil {
+			return err
+		}
+		nu := *u
+		nu.ID = lu.ID
+		nu.CreateTS = lu.CreateTS
+		nu.UpdateTS = now
+		st.Upstreams[u.Name] = &nu
+		return nil
+	})
+}
+
+func (l *Local) DeleteUpstream(name string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		if _, err := st.upstream(name); err != nil {
+			return err
+		}
+		for _, s := range st.Services {
+			for _, r := range s.Routes {
+				if r.Upstreams[name] {
+					return inUse("upstream", name, "route "+s.Name+"/"+r.Name)
+				}
+			}
+		}
+		delete(st.Upstreams, name)
+		return nil
+	})
+}
+
+// Secrets
+
+func (l *Local) ListSecrets() ([]Secret, error) {
+	var ss []Secret
+	err := l.view(func(st *localState) error {
+		for _, s := range st.Secrets {
+			ss = append(ss, *s)
+		}
+		return nil
+	})
+	sort.Slice(ss, func(i, j int) bool { return ss[i].Name < ss[j].Name })
+	return ss, err
+}
+
+func (l *Local) GetSecret(name string) (*Secret, error) {
+	var s Secret
+	err := l.view(func(st *localState) error {
+		ls, err := st.secret(name)
+		if err != nil {
+			return err
+		}
+		s = *ls
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (l *Local) CreateSecret(s *Secret) error {
+	return l.update(func(st *localState, now time.Time) error {
+		if _, ok := st.Secrets[s.Name]; ok {
+			return exists("secret", s.Name)
+		}
+		ns := *s
+		ns.ID = st.nextID()
+		ns.CreateTS = now
+		ns.UpdateTS = now
+		st.Secrets[s.Name] = &ns
+		return nil
+	})
+}
+
+func (l *Local) UpdateSecret(s *Secret) error {
+	return l.update(func(st *localState, now time.Time) error {
+		ls, err := st.secret(s.Name)
+		if err != nil {
+			return err
+		}
+		ns := *s
+		ns.ID = ls.ID
+		ns.CreateTS = ls.CreateTS
+		ns.UpdateTS = now
+		st.Secrets[s.Name] = &ns
+		return nil
+	})
+}
+
+func (l *Local) DeleteSecret(name string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		if _, err := st.secret(name); err != nil {
+			return err
+		}
+		for _, s := range st.Services {
+			if s.Secrets[name] {
+				return inUse("secret", name, "service "+s.Name)
+			}
+		}
+		delete(st.Secrets, name)
+		return nil
+	})
+}
+
+// Filters
+
+func (l *Local) ListFilters() ([]Filter, error) {
+	var fs []Filter
+	err := l.view(func(st *localState) error {
+		for _, f := range st.Filters {
+			fs = append(fs, *f)
+		}
+		return nil
+	})
+	sort.Slice(fs, func(i, j int) bool { return fs[i].Name < fs[j].Name })
+	return fs, err
+}
+
+func (l *Local) GetFilter(name string) (*Filter, error) {
+	var f Filter
+	err := l.view(func(st *localState) error {
+		lf, err := st.filter(name)
+		if err != nil {
+			return err
+		}
+		f = *lf
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (l *Local) CreateFilter(f *Filter) error {
+	return l.update(func(st *localState, now time.Time) error {
+		if _, ok := st.Filters[f.Name]; ok {
+			return exists("filter", f.Name)
+		}
+		nf := *f
+		nf.ID = st.nextID()
+		nf.CreateTS = now
+		nf.UpdateTS = now
+		st.Filters[f.Name] = &nf
+		return nil
+	})
+}
+
+func (l *Local) UpdateFilter(f *Filter) error {
+	return l.update(func(st *localState, now time.Time) error {
+		lf, err := st.filter(f.Name)
+		if err != nil {
+			return err
+		}
+		nf := *f
+		nf.ID = lf.ID
+		nf.CreateTS = lf.CreateTS
+		nf.UpdateTS = now
+		st.Filters[f.Name] = &nf
+		return nil
+	})
+}
+
+func (l *Local) DeleteFilter(name string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		if _, err := st.filter(name); err != nil {
+			return err
+		}
+		for _, s := range st.Services {
+			if s.Filters[name] {
+				return inUse("filter", name, "service "+s.Name)
+			}
+			for _, r := range s.Routes {
+				if r.Filters[name] {
+					return inUse("filter", name, "route "+s.Name+"/"+r.Name)
+				}
+			}
+		}
+		delete(st.Filters, name)
+		return nil
+	})
+}
+
+// Global configs
+
+func (l *Local) ListGlobalConfigs() ([]GlobalConfig, error) {
+	var gcs []GlobalConfig
+	err := l.view(func(st *localState) error {
+		for _, gc := range st.GlobalConfigs {
+			gcs = append(gcs, *gc)
+		}
+		return nil
+	})
+	sort.Slice(gcs, func(i, j int) bool { return gcs[i].Name < gcs[j].Name })
+	return gcs, err
+}
+
+func (l *Local) GetGlobalConfig(name string) (*GlobalConfig, error) {
+	var gc GlobalConfig
+	err := l.view(func(st *localState) error {
+		lgc, err := st.globalConfig(name)
+		if err != nil {
+			return err
+		}
+		gc = *lgc
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &gc, nil
+}
+
+func (l *Local) CreateGlobalConfig(gc *GlobalConfig) error {
+	return l.update(func(st *localState, now time.Time) error {
+		if _, ok := st.GlobalConfigs[gc.Name]; ok {
+			return exists("globalconfig", gc.Name)
+		}
+		ngc := *gc
+		ngc.ID = st.nextID()
+		ngc.CreateTS = now
+		ngc.UpdateTS = now
+		st.GlobalConfigs[gc.Name] = &ngc
+		return nil
+	})
+}
+
+func (l *Local) UpdateGlobalConfig(gc *GlobalConfig) error {
+	return l.update(func(st *localState, now time.Time) error {
+		lgc, err := st.globalConfig(gc.Name)
+		if err != nil {
+			return err
+		}
+		ngc := *gc
+		ngc.ID = lgc.ID
+		ngc.CreateTS = lgc.CreateTS
+		ngc.UpdateTS = now
+		st.GlobalConfigs[gc.Name] = &ngc
+		return nil
+	})
+}
+
+func (l *Local) DeleteGlobalConfig(name string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		if _, err := st.globalConfig(name); err != nil {
+			return err
+		}
+		for _, p := range sortedProxies(st) {
+			if p.GlobalConfigs[name] {
+				return inUse("globalconfig", name, "proxy "+p.Name)
+			}
+		}
+		delete(st.GlobalConfigs, name)
+		return nil
+	})
+}
+
+// Cascades
+
+func (l *Local) DeleteCascade(kind, name string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		var err error
+		switch kind {
+		case "upstream":
+			if _, err = st.upstream(name); err == nil {
+				for _, s := range st.Services {
+					for _, r := range s.Routes {
+						delete(r.Upstreams, name)
+					}
+				}
+				delete(st.Upstreams, name)
+			}
+		case "filter":
+			if _, err = st.filter(name); err == nil {
+				for _, s := range st.Services {
+					delete(s.Filters, name)
+					for _, r := range s.Routes {
+						delete(r.Filters, name)
+					}
+				}
+				delete(st.Filters, name)
+			}
+		case "secret":
+			if _, err = st.secret(name); err == nil {
+				for _, s := range st.Services {
+					delete(s.Secrets, name)
+				}
+				delete(st.Secrets, name)
+			}
+		case "service":
+			if _, err = st.service(name); err == nil {
+				for _, p := range st.Proxies {
+					delete(p.Services, name)
+				}
+				delete(st.Services, name)
+			}
+		default:
+			err = fmt.Errorf("cannot cascade the delete of a %s", kind)
+		}
+		return err
+	})
+}
+
+// Revisions
+
+func (l *Local) ListRevisions(proxy string) ([]Revision, error) {
+	var rs []Revision
+	err := l.view(func(st *localState) error {
+		for _, r := range st.Revisions {
+			if r.Proxy == proxy {
+				rs = append(rs, *r)
+			}
+		}
+		return nil
+	})
+	return rs, err
+}
+
+func (l *Local) GetRevision(proxy string, id int64) (*Revision, error) {
+	var r Revision
+	err := l.view(func(st *localState) error {
+		for _, lr := range st.Revisions {
+			if lr.Proxy == proxy && lr.ID == id {
+				r = *lr
+				return nil
+			}
+		}
+		return notFound("revision", fmt.Sprintf("%s/%d", proxy, id))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (l *Local) LatestRevision(proxy string) (*Revision, error) {
+	var r Revision
+	err := l.view(func(st *localState) error {
+		for i := len(st.Revisions) - 1; i >= 0; i-- {
+			if st.Revisions[i].Proxy == proxy {
+				r = *st.Revisions[i]
+				return nil
+			}
+		}
+		return notFound("revision", proxy+"/latest")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// addRevision adds r to the revisions of its proxy, dropping the oldest
+// past limit if it is not 0.
+func (st *localState) addRevision(r *Revision, now time.Time, limit int) {
+	st.RevisionSeq++
+	r.ID = st.RevisionSeq
+	r.CreateTS = now
+	nr := *r
+	st.Revisions = append(st.Revisions, &nr)
+
+	if limit <= 0 {
+		return
+	}
+	n := 0
+	for _, lr := range st.Revisions {
+		if lr.Proxy == r.Proxy {
+			n++
+		}
+	}
+	rs := st.Revisions[:0]
+	for _, lr := range st.Revisions {
+		if lr.Proxy == r.Proxy && n > limit {
+			n--
+			continue
+		}
+		rs = append(rs, lr)
+	}
+	st.Revisions = rs
+}
+
+func (l *Local) CreateRevision(r *Revision) error {
+	return l.update(func(st *localState, now time.Time) error {
+		st.addRevision(r, now, l.RevisionLimit)
+		return nil
+	})
+}
+
+func (l *Local) RestoreProxy(pd *ProxyDetail, r *Revision) error {
+	return l.update(func(st *localState, now time.Time) error {
+		p, ok := st.Proxies[pd.Name]
+		if !ok {
+			p = &localProxy{Proxy: Proxy{ID: st.nextID(), Name: pd.Name, CreateTS: now}}
+			st.Proxies[pd.Name] = p
+		}
+		p.UpdateTS = now
+
+		p.GlobalConfigs = make(map[string]bool)
+		for _, pgc := range pd.GlobalConfigs {
+			st.putGlobalConfig(pgc.GlobalConfig, now)
+			p.GlobalConfigs[pgc.GlobalConfig.Name] = true
+		}
+
+		p.Services = make(map[string]bool)
+		for _, ps := range pd.Services {
+			st.putServiceDetail(&ps.Service, now)
+			p.Services[ps.Service.Name] = true
+		}
+
+		st.addRevision(r, now, l.RevisionLimit)
+		return nil
+	})
+}
+
+// The put methods create or update an object of st with the values of
+// the given one.
+
+func (st *localState) putServiceDetail(sd *ServiceDetail, now time.Time) {
+	s, ok := st.Services[sd.Name]
+	if !ok {
+		s = &localService{Service: Service{ID: st.nextID(), Name: sd.Name, CreateTS: now}}
+		st.Services[sd.Name] = s
+	}
+	s.Fqdn = sd.Fqdn
+	s.UpdateTS = now
+
+	s.Secrets = make(map[string]bool)
+	for _, ss := range sd.Secrets {
+		st.putSecret(ss.Secret, now)
+		s.Secrets[ss.Secret.Name] = true
+	}
+
+	s.Filters = make(map[string]bool)
+	for _, sf := range sd.Filters {
+		st.putFilter(sf.Filter, now)
+		s.Filters[sf.Filter.Name] = true
+	}
+
+	routes := make(map[string]*localRoute)
+	for _, rd := range sd.Routes {
+		lr, ok := s.Routes[rd.Name]
+		if !ok {
+			lr = &localRoute{Route: Route{ID: st.nextID(), CreateTS: now}}
+		}
+		lr.Service = sd.Name
+		lr.Name = rd.Name
+		lr.Prefix = rd.Prefix
+		lr.Config = rd.Config
+		lr.ConfigJSON = rd.ConfigJSON
+		lr.UpdateTS = now
+
+		lr.Upstreams = make(map[string]bool)
+		for _, ru := range rd.Upstreams {
+			st.putUpstream(ru.Upstream, now)
+			lr.Upstreams[ru.Upstream.Name] = true
+		}
+		lr.Filters = make(map[string]bool)
+		for _, rf := range rd.Filters {
+			st.putFilter(rf.Filter, now)
+			lr.Filters[rf.Filter.Name] = true
+		}
+		routes[rd.Name] = lr
+	}
+	s.Routes = routes
+}
+
+func (st *localState) putUpstream(u Upstream, now time.Time) {
+	if lu, ok := st.Upstreams[u.Name]; ok {
+		u.ID, u.CreateTS = lu.ID, lu.CreateTS
+	} else {
+		u.ID, u.CreateTS = st.nextID(), now
+	}
+	u.UpdateTS = now
+	st.Upstreams[u.Name] = &u
+}
+
+func (st *localState) putSecret(s Secret, now time.Time) {
+	if ls, ok := st.Secrets[s.Name]; ok {
+		s.ID, s.CreateTS = ls.ID, ls.CreateTS
+		s.ACMEService = ls.ACMEService
+	} else {
+		s.ACMEService = ""
+		s.ID, s.CreateTS = st.nextID(), now
+	}
+	s.UpdateTS = now
+	st.Secrets[s.Name] = &s
+}
+
+func (st *localState) putFilter(f Filter, now time.Time) {
+	if lf, ok := st.Filters[f.Name]; ok {
+		f.ID, f.CreateTS = lf.ID, lf.CreateTS
+	} else {
+		f.ID, f.CreateTS = st.nextID(), now
+	}
+	f.UpdateTS = now
+	st.Filters[f.Name] = &f
+}
+
+func (st *localState) putGlobalConfig(gc GlobalConfig, now time.Time) {
+	if lgc, ok := st.GlobalConfigs[gc.Name]; ok {
+		gc.ID, gc.CreateTS = lgc.ID, lgc.CreateTS
+	} else {
+		gc.ID, gc.CreateTS = st.nextID(), now
+	}
+	gc.UpdateTS = now
+	st.GlobalConfigs[gc.Name] = &gc
+}
+
+// Tokens
+
+func (l *Local) ListTokens() ([]Token, error) {
+	var ts []Token
+	err := l.view(func(st *localState) error {
+		for _, t := range st.Tokens {
+			ts = append(ts, *t)
+		}
+		return nil
+	})
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Name < ts[j].Name })
+	return ts, err
+}
+
+func (l *Local) GetToken(name string) (*Token, error) {
+	var t Token
+	err := l.view(func(st *localState) error {
+		lt, ok := st.Tokens[name]
+		if !ok {
+			return notFound("token", name)
+		}
+		t = *lt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (l *Local) TokenByHash(hash string) (*Token, error) {
+	var t Token
+	err := l.view(func(st *localState) error {
+		for _, lt := range st.Tokens {
+			if lt.Hash == hash {
+				t = *lt
+				return nil
+			}
+		}
+		return notFound("token", "with digest "+hash)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (l *Local) CreateToken(t *Token) error {
+	return l.update(func(st *localState, now time.Time) error {
+		if _, ok := st.Tokens[t.Name]; ok {
+			return exists("token", t.Name)
+		}
+		for _, lt := range st.Tokens {
+			if lt.Hash == t.Hash {
+				return exists("token", "with digest "+t.Hash)
+			}
+		}
+		t.ID = st.nextID()
+		t.CreateTS = now
+		nt := *t
+		st.Tokens[t.Name] = &nt
+		return nil
+	})
+}
+
+func (l *Local) DeleteToken(name string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		if _, ok := st.Tokens[name]; !ok {
+			return notFound("token", name)
+		}
+		delete(st.Tokens, name)
+		return nil
+	})
+}
+
+// Webhooks
+
+func (l *Local) ListWebhooks() ([]Webhook, error) {
+	var ws []Webhook
+	err := l.view(func(st *localState) error {
+		for _, w := range st.Webhooks {
+			ws = append(ws, *w)
+		}
+		return nil
+	})
+	sort.Slice(ws, func(i, j int) bool { return ws[i].Name < ws[j].Name })
+	return ws, err
+}
+
+func (l *Local) GetWebhook(name string) (*Webhook, error) {
+	var w Webhook
+	err := l.view(func(st *localState) error {
+		lw, ok := st.Webhooks[name]
+		if !ok {
+			return notFound("webhook", name)
+		}
+		w = *lw
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (l *Local) CreateWebhook(w *Webhook) error {
+	return l.update(func(st *localState, now time.Time) error {
+		if _, ok := st.Webhooks[w.Name]; ok {
+			return exists("webhook", w.Name)
+		}
+		w.ID = st.nextID()
+		w.CreateTS = now
+		nw := *w
+		st.Webhooks[w.Name] = &nw
+		return nil
+	})
+}
+
+func (l *Local) DeleteWebhook(name string) error {
+	return l.update(func(st *localState, now time.Time) error {
+		if _, ok := st.Webhooks[name]; !ok {
+			return notFound("webhook", name)
+		}
+		delete(st.Webhooks, name)
+		return nil
+	})
+}
+
+// Audit
+
+func (l *Local) CreateAuditRecord(r *AuditRecord) error {
+	return l.update(func(st *localState, now time.Time) error {
+		st.AuditSeq++
+		r.ID = st.AuditSeq
+		r.CreateTS = now
+		nr := *r
+		st.Audit = append(st.Audit, &nr)
+		if l.AuditLimit > 0 && len(st.Audit) > l.AuditLimit {
+			st.Audit = st.Audit[len(st.Audit)-l.AuditLimit:]
+		}
+		return nil
+	})
+}
+
+func (l *Local) ListAuditRecords(since time.Time, object string) ([]AuditRecord, error) {
+	var rs []AuditRecord
+	err := l.view(func(st *localState) error {
+		for _, r := range st.Audit {
+			if r.CreateTS.Before(since) {
+				continue
+			}
+			if object != "" && !containsString(r.Objects, object) {
+				continue
+			}
+			rs = append(rs, *r)
+		}
+		return nil
+	})
+	return rs, err
+}
+
+func containsString(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
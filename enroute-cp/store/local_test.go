@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package store_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/saarasio/enroute/enroute-cp/store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		l, err := store.OpenLocal(filepath.Join(t.TempDir(), "enroute.db"))
+		require.NoError(t, err)
+		return l
+	})
+}
+
+func TestLocalReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "enroute.db")
+
+	l, err := store.OpenLocal(path)
+	require.NoError(t, err)
+	require.NoError(t, l.CreateProxy("gw"))
+	require.NoError(t, l.CreateService(&store.Service{Name: "svc", Fqdn: "example.com"}))
+	require.NoError(t, l.CreateRoute(&store.Route{Service: "svc", Name: "rt", Prefix: "/"}))
+	require.NoError(t, l.CreateUpstream(&store.Upstream{Name: "up", IP: "127.0.0.1", Port: 80}))
+	require.NoError(t, l.AssociateProxyService("gw", "svc"))
+	require.NoError(t, l.AssociateRouteUpstream("svc", "rt", "up"))
+	want, err := l.ProxyDetail("gw")
+	require.NoError(t, err)
+
+	l, err = store.OpenLocal(path)
+	require.NoError(t, err)
+	got, err := l.ProxyDetail("gw")
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	// IDs are not reused after reopening
+	require.NoError(t, l.CreateProxy("gw2"))
+	p, err := l.GetProxy("gw2")
+	require.NoError(t, err)
+	assert.Greater(t, p.ID, want.Services[0].Service.Routes[0].Upstreams[0].Upstream.ID)
+}
+
+func TestLocalLimits(t *testing.T) {
+	l, err := store.OpenLocal(filepath.Join(t.TempDir(), "enroute.db"))
+	require.NoError(t, err)
+	l.RevisionLimit, l.AuditLimit = 2, 3
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, l.CreateRevision(&store.Revision{Proxy: "gw", Config: []byte(`{}`)}))
+		require.NoError(t, l.CreateAuditRecord(&store.AuditRecord{Method: "POST", Path: "/proxy"}))
+	}
+	require.NoError(t, l.CreateRevision(&store.Revision{Proxy: "other", Config: []byte(`{}`)}))
+
+	// The oldest are dropped, of each proxy
+	rs, err := l.ListRevisions("gw")
+	require.NoError(t, err)
+	require.Len(t, rs, 2)
+	assert.Equal(t, []int64{3, 4}, []int64{rs[0].ID, rs[1].ID})
+	rs, err = l.ListRevisions("other")
+	require.NoError(t, err)
+	assert.Len(t, rs, 1)
+
+	as, err := l.ListAuditRecords(time.Time{}, "")
+	require.NoError(t, err)
+	require.Len(t, as, 3)
+	assert.Equal(t, int64(2), as[0].ID)
+}
@@ -0,0 +1,496 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+// Package store holds the configuration of enroute-cp: proxies, services,
+// routes, upstreams, secrets, filters, global configs and the associations
+// between them.
+//
+// Two implementations are provided. Hasura keeps the configuration in
+// Postgres through the Hasura GraphQL engine, and Local keeps it in a single
+// file so that a standalone gateway runs without either.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when an object, or the association between
+	// two objects, does not exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrExists is returned when an object is created with the name of
+	// an existing object.
+	ErrExists = errors.New("already exists")
+
+	// ErrInUse is returned when an object is deleted while it is still
+	// associated with another object.
+	ErrInUse = errors.New("in use")
+)
+
+// ObjectError is an error about one object, wrapping ErrNotFound, ErrExists
+// or ErrInUse. Route names are qualified by their service, like svc/route.
+type ObjectError struct {
+	Kind string
+	Name string
+	// By is the object using the object, for ErrInUse
+	By  string
+	Err error
+}
+
+func (e *ObjectError) Error() string {
+	if e.By != "" {
+		return e.Kind + " " + e.Name + " is used by " + e.By + ": " + e.Err.Error()
+	}
+	return e.Kind + " " + e.Name + ": " + e.Err.Error()
+}
+
+func (e *ObjectError) Unwrap() error {
+	return e.Err
+}
+
+// Proxy is a gateway, identified by the name it is started with.
+type Proxy struct {
+	ID       int64     `json:"proxy_id"`
+	Name     string    `json:"proxy_name"`
+	CreateTS time.Time `json:"create_ts"`
+	UpdateTS time.Time `json:"update_ts"`
+}
+
+// Service is a virtual host.
+type Service struct {
+	ID       int64     `json:"service_id"`
+	Name     string    `json:"service_name"`
+	Fqdn     string    `json:"fqdn"`
+	CreateTS time.Time `json:"create_ts"`
+	UpdateTS time.Time `json:"update_ts"`
+}
+
+// Route is a route of a service.
+type Route struct {
+	ID int64 `json:"route_id"`
+
+	// Service is the name of the service the route belongs to.
+	Service string `json:"-"`
+
+	Name   string `json:"route_name"`
+	Prefix string `json:"route_prefix"`
+
+	// Config holds the match conditions of the route as given, and
+	// ConfigJSON the saarasconfig.RouteMatchConditions parsed from them.
+	Config     string          `json:"route_config"`
+	ConfigJSON json.RawMessage `json:"config_json"`
+
+	CreateTS time.Time `json:"create_ts"`
+	UpdateTS time.Time `json:"update_ts"`
+}
+
+// Upstream is an endpoint routes send traffic to.
+type Upstream struct {
+	ID                        int64     `json:"upstream_id"`
+	Name                      string    `json:"upstream_name"`
+	IP                        string    `json:"upstream_ip"`
+	Port                      int       `json:"upstream_port"`
+	Weight                    int       `json:"upstream_weight"`
+	HCPath                    string    `json:"upstream_hc_path"`
+	HCHost                    string    `json:"upstream_hc_host"`
+	HCIntervalSeconds         int       `json:"upstream_hc_intervalseconds"`
+	HCTimeoutSeconds          int       `json:"upstream_hc_timeoutseconds"`
+	HCUnhealthyThresholdCount int       `json:"upstream_hc_unhealthythresholdcount"`
+	HCHealthyThresholdCount   int       `json:"upstream_hc_healthythresholdcount"`
+	Strategy                  string    `json:"upstream_strategy"`
+	ValidationCACertificate   string    `json:"upstream_validation_cacertificate"`
+	ValidationSubjectName     string    `json:"upstream_validation_subjectname"`
+	Protocol                  string    `json:"upstream_protocol"`
+	CreateTS                  time.Time `json:"create_ts"`
+	UpdateTS                  time.Time `json:"update_ts"`
+}
+
+// Secret is a TLS key and certificate used by services.
+type Secret struct {
+	ID   int64  `json:"secret_id"`
+	Name string `json:"secret_name"`
+	Key  string `json:"secret_key"`
+	Cert string `json:"secret_cert"`
+	SNI  string `json:"secret_sni"`
+
+	// TLSConfig holds the TLS parameters (saarasconfig.TLSConfig) in json
+	// of the service using this secret.
+	TLSConfig string `json:"secret_tls_config"`
+
+	// ACMEService is the service the certificate was issued for over
+	// ACME, if it is managed by enroute-cp. Such certificates are renewed
+	// before they expire. Restoring a proxy leaves it as it is.
+	ACMEService string `json:"secret_acme_service"`
+
+	CreateTS time.Time `json:"create_ts"`
+	UpdateTS time.Time `json:"update_ts"`
+}
+
+// Filter is an HTTP filter applied to services, or a route filter
+// applied to routes.
+type Filter struct {
+	ID   int64  `json:"filter_id"`
+	Name string `json:"filter_name"`
+	Type string `json:"filter_type"`
+
+	// Config holds the configuration of the filter as given, and
+	// ConfigJSON its json form.
+	Config     string          `json:"filter_config"`
+	ConfigJSON json.RawMessage `json:"config_json"`
+
+	CreateTS time.Time `json:"create_ts"`
+	UpdateTS time.Time `json:"update_ts"`
+}
+
+// GlobalConfig is configuration applied to a proxy as a whole.
+type GlobalConfig struct {
+	ID   int64  `json:"globalconfig_id"`
+	Name string `json:"globalconfig_name"`
+	Type string `json:"globalconfig_type"`
+
+	// Config holds the configuration as given, and ConfigJSON its
+	// json form.
+	Config     string          `json:"config"`
+	ConfigJSON json.RawMessage `json:"config_json"`
+
+	CreateTS time.Time `json:"create_ts"`
+	UpdateTS time.Time `json:"update_ts"`
+}
+
+// The detail types hold an object along with the objects associated with
+// it. They nest in the same shape as the saaras_db GraphQL schema.
+
+// ProxyDetail is a proxy with its global configs and services.
+type ProxyDetail struct {
+	Proxy
+	GlobalConfigs []ProxyGlobalConfig `json:"proxy_globalconfigs"`
+	Services      []ProxyService      `json:"proxy_services"`
+}
+
+// ProxyGlobalConfig is a global config associated with a proxy.
+type ProxyGlobalConfig struct {
+	GlobalConfig GlobalConfig `json:"globalconfig"`
+}
+
+// ProxyService is a service associated with a proxy.
+type ProxyService struct {
+	Service ServiceDetail `json:"service"`
+}
+
+// ServiceDetail is a service with its routes, secrets and filters.
+type ServiceDetail struct {
+	Service
+	Routes  []RouteDetail   `json:"routes"`
+	Secrets []ServiceSecret `json:"service_secrets"`
+	Filters []ServiceFilter `json:"service_filters"`
+}
+
+// ServiceSecret is a secret associated with a service.
+type ServiceSecret struct {
+	Secret Secret `json:"secret"`
+}
+
+// ServiceFilter is a filter associated with a service.
+type ServiceFilter struct {
+	Filter Filter `json:"filter"`
+}
+
+// RouteDetail is a route with its upstreams and filters.
+type RouteDetail struct {
+	Route
+	Upstreams []RouteUpstream `json:"route_upstreams"`
+	Filters   []RouteFilter   `json:"route_filters"`
+}
+
+// RouteUpstream is an upstream associated with a route.
+type RouteUpstream struct {
+	Upstream Upstream `json:"upstream"`
+}
+
+// RouteFilter is a filter associated with a route.
+type RouteFilter struct {
+	Filter Filter `json:"filter"`
+}
+
+// Revision is the configuration of a proxy after a change. Revisions are
+// never changed once created.
+type Revision struct {
+	ID     int64  `json:"revision_id"`
+	Proxy  string `json:"proxy_name"`
+	Author string `json:"author"`
+
+	// Config holds the ProxyDetail of the proxy in json, and Diff the
+	// changes to it since the previous revision.
+	Config json.RawMessage `json:"config"`
+	Diff   string          `json:"diff"`
+
+	CreateTS time.Time `json:"create_ts"`
+}
+
+// Token is an API token. Only the digest of the token is kept, the token
+// itself is shown once when created.
+type Token struct {
+	ID   int64  `json:"token_id"`
+	Name string `json:"token_name"`
+	Hash string `json:"token_hash"`
+	Role string `json:"token_role"`
+
+	// Proxies and Services scope the token to the proxies and services
+	// named. A token with neither is not scoped.
+	Proxies  []string `json:"token_proxies"`
+	Services []string `json:"token_services"`
+
+	CreateTS time.Time `json:"create_ts"`
+}
+
+// Webhook is an endpoint notified of the changes to the configuration.
+type Webhook struct {
+	ID   int64  `json:"webhook_id"`
+	Name string `json:"webhook_name"`
+	URL  string `json:"webhook_url"`
+
+	// Secret is the key of the HMAC signing the events posted, which are
+	// not signed if it is empty.
+	Secret string `json:"webhook_secret"`
+
+	// Events are the patterns of the events posted, like service.* or
+	// *.deleted. A webhook without patterns is posted every event.
+	Events []string `json:"webhook_events"`
+
+	CreateTS time.Time `json:"create_ts"`
+}
+
+// AuditRecord is a change requested through the API.
+type AuditRecord struct {
+	ID int64 `json:"audit_id"`
+
+	// Token is the name of the token the request was made with, and IP
+	// the address it came from.
+	Token string `json:"token_name"`
+	IP    string `json:"client_ip"`
+
+	// Endpoint is the route the request matched, like
+	// /service/:service_name, and Path the path requested.
+	Method   string `json:"method"`
+	Endpoint string `json:"endpoint"`
+	Path     string `json:"path"`
+
+	// Objects names the objects changed as kind:name, like service:svc,
+	// with routes named service/route.
+	Objects []string `json:"objects"`
+
+	// Before and After hold the object changed in json, or null if it
+	// did not exist.
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+
+	Code     int       `json:"code"`
+	CreateTS time.Time `json:"create_ts"`
+}
+
+// Store holds the configuration of enroute-cp.
+//
+// Objects are identified by name, and routes by the name of their service
+// and their own name. Lists are sorted by name. Get methods and
+// the methods which change an object return ErrNotFound if it does not
+// exist. Associations are made between existing objects, and making one
+// which exists already does nothing. Deleting an object which is still
+// associated with another returns ErrInUse.
+type Store interface {
+	ProxyStore
+	ServiceStore
+	RouteStore
+	UpstreamStore
+	SecretStore
+	FilterStore
+	GlobalConfigStore
+	RevisionStore
+	TokenStore
+	AuditStore
+	WebhookStore
+
+	// DeleteCascade deletes the upstream, filter, secret or service name,
+	// as kind says, along with the associations made with it, all at once.
+	// The routes of a service are deleted with it.
+	DeleteCascade(kind, name string) error
+}
+
+// ProxyStore holds proxies and their associations with services and
+// global configs.
+type ProxyStore interface {
+	ListProxies() ([]Proxy, error)
+	GetProxy(name string) (*Proxy, error)
+
+	// CreateProxy creates a proxy. Creating a proxy which exists
+	// does nothing.
+	CreateProxy(name string) error
+	DeleteProxy(name string) error
+
+	// ProxyDetail returns the proxy with its global configs, services,
+	// and their routes, upstreams, secrets and filters.
+	ProxyDetail(name string) (*ProxyDetail, error)
+	ListProxyDetails() ([]ProxyDetail, error)
+
+	AssociateProxyService(proxy, service string) error
+	DisassociateProxyService(proxy, service string) error
+	ListProxyServices(proxy string) ([]Service, error)
+	ListServiceProxies(service string) ([]Proxy, error)
+
+	AssociateProxyGlobalConfig(proxy, globalconfig string) error
+	DisassociateProxyGlobalConfig(proxy, globalconfig string) error
+	ListProxyGlobalConfigs(proxy string) ([]GlobalConfig, error)
+}
+
+// ServiceStore holds services and their associations with secrets
+// and filters.
+type ServiceStore interface {
+	ListServices() ([]Service, error)
+	GetService(name string) (*Service, error)
+
+	// CreateService creates a service. Creating a service which exists
+	// does nothing, use UpdateService to change its fqdn.
+	CreateService(s *Service) error
+	UpdateService(s *Service) error
+	DeleteService(name string) error
+
+	// ServiceDetail returns the service with its routes, upstreams,
+	// secrets and filters.
+	ServiceDetail(name string) (*ServiceDetail, error)
+
+	AssociateServiceSecret(service, secret string) error
+	DisassociateServiceSecret(service, secret string) error
+	ListServiceSecrets(service string) ([]Secret, error)
+
+	// ListSecretServices returns the services associated with secret.
+	ListSecretServices(secret string) ([]Service, error)
+
+	AssociateServiceFilter(service, filter string) error
+	DisassociateServiceFilter(service, filter string) error
+	ListServiceFilters(service string) ([]Filter, error)
+
+	// ListFilterServices returns the services associated with filter.
+	ListFilterServices(filter string) ([]Service, error)
+}
+
+// RouteStore holds routes and their associations with upstreams
+// and filters.
+type RouteStore interface {
+	ListRoutes(service string) ([]Route, error)
+	GetRoute(service, name string) (*Route, error)
+
+	// CreateRoute creates r in the service r.Service, which must exist.
+	CreateRoute(r *Route) error
+	UpdateRoute(r *Route) error
+	DeleteRoute(service, name string) error
+
+	AssociateRouteUpstream(service, route, upstream string) error
+	DisassociateRouteUpstream(service, route, upstream string) error
+	ListRouteUpstreams(service, route string) ([]Upstream, error)
+
+	// ListUpstreamRoutes returns the routes associated with upstream.
+	ListUpstreamRoutes(upstream string) ([]Route, error)
+
+	AssociateRouteFilter(service, route, filter string) error
+	DisassociateRouteFilter(service, route, filter string) error
+	ListRouteFilters(service, route string) ([]Filter, error)
+
+	// ListFilterRoutes returns the routes associated with filter.
+	ListFilterRoutes(filter string) ([]Route, error)
+}
+
+// UpstreamStore holds upstreams.
+type UpstreamStore interface {
+	ListUpstreams() ([]Upstream, error)
+	GetUpstream(name string) (*Upstream, error)
+	CreateUpstream(u *Upstream) error
+	UpdateUpstream(u *Upstream) error
+	DeleteUpstream(name string) error
+}
+
+// SecretStore holds secrets.
+type SecretStore interface {
+	ListSecrets() ([]Secret, error)
+	GetSecret(name string) (*Secret, error)
+	CreateSecret(s *Secret) error
+	UpdateSecret(s *Secret) error
+	DeleteSecret(name string) error
+}
+
+// FilterStore holds filters.
+type FilterStore interface {
+	ListFilters() ([]Filter, error)
+	GetFilter(name string) (*Filter, error)
+	CreateFilter(f *Filter) error
+	UpdateFilter(f *Filter) error
+	DeleteFilter(name string) error
+}
+
+// GlobalConfigStore holds global configs.
+type GlobalConfigStore interface {
+	ListGlobalConfigs() ([]GlobalConfig, error)
+	GetGlobalConfig(name string) (*GlobalConfig, error)
+	CreateGlobalConfig(gc *GlobalConfig) error
+	UpdateGlobalConfig(gc *GlobalConfig) error
+	DeleteGlobalConfig(name string) error
+}
+
+// RevisionStore holds the revisions of the configuration of proxies.
+// Revisions are kept after their proxy is deleted.
+type RevisionStore interface {
+	// ListRevisions returns the revisions of proxy, oldest first.
+	ListRevisions(proxy string) ([]Revision, error)
+	GetRevision(proxy string, id int64) (*Revision, error)
+
+	// LatestRevision returns the newest revision of proxy, or
+	// ErrNotFound if it has none.
+	LatestRevision(proxy string) (*Revision, error)
+
+	// CreateRevision saves r, setting its ID and CreateTS.
+	CreateRevision(r *Revision) error
+
+	// RestoreProxy sets the configuration of the proxy pd.Name to pd
+	// and saves the revision r recording it, all at once. The proxy and
+	// the objects in pd are created if missing and updated otherwise.
+	// The global configs and services of the proxy, and the routes,
+	// secrets and filters of those services, become those in pd, so
+	// routes of the services missing from pd are deleted. Other objects
+	// are left as they are.
+	RestoreProxy(pd *ProxyDetail, r *Revision) error
+}
+
+// TokenStore holds API tokens.
+type TokenStore interface {
+	ListTokens() ([]Token, error)
+	GetToken(name string) (*Token, error)
+
+	// TokenByHash returns the token whose digest is hash.
+	TokenByHash(hash string) (*Token, error)
+
+	// CreateToken saves t, setting its ID and CreateTS.
+	CreateToken(t *Token) error
+	DeleteToken(name string) error
+}
+
+// WebhookStore holds webhooks.
+type WebhookStore interface {
+	ListWebhooks() ([]Webhook, error)
+	GetWebhook(name string) (*Webhook, error)
+
+	// CreateWebhook saves w, setting its ID and CreateTS.
+	CreateWebhook(w *Webhook) error
+	DeleteWebhook(name string) error
+}
+
+// AuditStore holds the audit records of the API.
+type AuditStore interface {
+	// CreateAuditRecord saves r, setting its ID and CreateTS.
+	CreateAuditRecord(r *AuditRecord) error
+
+	// ListAuditRecords returns the records created at or after since,
+	// oldest first. If object is not empty, only the records of changes
+	// to object (kind:name) are returned.
+	ListAuditRecords(since time.Time, object string) ([]AuditRecord, error)
+}
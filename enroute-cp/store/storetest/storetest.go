@@ -0,0 +1,835 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+// Package storetest checks that an implementation of store.Store behaves
+// as the interface documents.
+package storetest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs the conformance tests against the Store returned by open.
+// Objects are created with unique names, so the Store need not be empty.
+func Run(t *testing.T, open func(t *testing.T) store.Store) {
+	tests := map[string]func(t *testing.T, s store.Store, prefix string){
+		"proxy":         testProxy,
+		"service":       testService,
+		"route":         testRoute,
+		"upstream":      testUpstream,
+		"secret":        testSecret,
+		"filter":        testFilter,
+		"globalconfig":  testGlobalConfig,
+		"associations":  testAssociations,
+		"proxy detail":  testProxyDetail,
+		"delete in use": testDeleteInUse,
+		"cascade":       testDeleteCascade,
+		"revision":      testRevision,
+		"restore proxy": testRestoreProxy,
+		"token":         testToken,
+		"audit":         testAudit,
+		"webhook":       testWebhook,
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			prefix := fmt.Sprintf("storetest-%d-", time.Now().UnixNano())
+			test(t, open(t), prefix)
+		})
+	}
+}
+
+func assertIs(t *testing.T, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Errorf("got error %v, want %v", err, target)
+	}
+}
+
+func testProxy(t *testing.T, s store.Store, prefix string) {
+	name := prefix + "proxy"
+
+	_, err := s.GetProxy(name)
+	assertIs(t, err, store.ErrNotFound)
+
+	require.NoError(t, s.CreateProxy(name))
+	p, err := s.GetProxy(name)
+	require.NoError(t, err)
+	assert.Equal(t, name, p.Name)
+	assert.NotZero(t, p.ID)
+	assert.False(t, p.CreateTS.IsZero())
+
+	// Creating a proxy which exists does nothing
+	require.NoError(t, s.CreateProxy(name))
+	p2, err := s.GetProxy(name)
+	require.NoError(t, err)
+	assert.Equal(t, p.ID, p2.ID)
+
+	ps, err := s.ListProxies()
+	require.NoError(t, err)
+	assert.Contains(t, proxyNames(ps), name)
+
+	require.NoError(t, s.DeleteProxy(name))
+	assertIs(t, s.DeleteProxy(name), store.ErrNotFound)
+	ps, err = s.ListProxies()
+	require.NoError(t, err)
+	assert.NotContains(t, proxyNames(ps), name)
+}
+
+func testService(t *testing.T, s store.Store, prefix string) {
+	name := prefix + "service"
+
+	_, err := s.GetService(name)
+	assertIs(t, err, store.ErrNotFound)
+	assertIs(t, s.UpdateService(&store.Service{Name: name, Fqdn: "b.example.com"}), store.ErrNotFound)
+
+	require.NoError(t, s.CreateService(&store.Service{Name: name, Fqdn: "a.example.com"}))
+	svc, err := s.GetService(name)
+	require.NoError(t, err)
+	assert.Equal(t, "a.example.com", svc.Fqdn)
+
+	// Creating a service which exists does not change it
+	require.NoError(t, s.CreateService(&store.Service{Name: name, Fqdn: "b.example.com"}))
+	svc, err = s.GetService(name)
+	require.NoError(t, err)
+	assert.Equal(t, "a.example.com", svc.Fqdn)
+
+	require.NoError(t, s.UpdateService(&store.Service{Name: name, Fqdn: "b.example.com"}))
+	svc, err = s.GetService(name)
+	require.NoError(t, err)
+	assert.Equal(t, "b.example.com", svc.Fqdn)
+
+	ss, err := s.ListServices()
+	require.NoError(t, err)
+	assert.Contains(t, serviceNames(ss), name)
+
+	require.NoError(t, s.DeleteService(name))
+	assertIs(t, s.DeleteService(name), store.ErrNotFound)
+}
+
+func testRoute(t *testing.T, s store.Store, prefix string) {
+	svc := prefix + "service"
+	name := prefix + "route"
+	r := &store.Route{
+		Service:    svc,
+		Name:       name,
+		Prefix:     "/",
+		Config:     `{"Prefix":"/"}`,
+		ConfigJSON: json.RawMessage(`{"Prefix":"/","header":null}`),
+	}
+
+	assertIs(t, s.CreateRoute(r), store.ErrNotFound)
+	_, err := s.ListRoutes(svc)
+	assertIs(t, err, store.ErrNotFound)
+
+	require.NoError(t, s.CreateService(&store.Service{Name: svc, Fqdn: "example.com"}))
+	_, err = s.GetRoute(svc, name)
+	assertIs(t, err, store.ErrNotFound)
+
+	require.NoError(t, s.CreateRoute(r))
+	assertIs(t, s.CreateRoute(r), store.ErrExists)
+
+	got, err := s.GetRoute(svc, name)
+	require.NoError(t, err)
+	assert.Equal(t, svc, got.Service)
+	assert.Equal(t, name, got.Name)
+	assert.Equal(t, "/", got.Prefix)
+	assert.Equal(t, r.Config, got.Config)
+	assert.JSONEq(t, string(r.ConfigJSON), string(got.ConfigJSON))
+
+	r.Prefix = "/api"
+	require.NoError(t, s.UpdateRoute(r))
+	got, err = s.GetRoute(svc, name)
+	require.NoError(t, err)
+	assert.Equal(t, "/api", got.Prefix)
+
+	rs, err := s.ListRoutes(svc)
+	require.NoError(t, err)
+	if assert.Len(t, rs, 1) {
+		assert.Equal(t, name, rs[0].Name)
+		assert.Equal(t, svc, rs[0].Service)
+	}
+
+	// A service with routes is in use
+	assertIs(t, s.DeleteService(svc), store.ErrInUse)
+
+	require.NoError(t, s.DeleteRoute(svc, name))
+	assertIs(t, s.DeleteRoute(svc, name), store.ErrNotFound)
+	assertIs(t, s.UpdateRoute(r), store.ErrNotFound)
+	require.NoError(t, s.DeleteService(svc))
+}
+
+func testUpstream(t *testing.T, s store.Store, prefix string) {
+	u := &store.Upstream{
+		Name:                      prefix + "upstream",
+		IP:                        "127.0.0.1",
+		Port:                      8080,
+		Weight:                    100,
+		HCPath:                    "/health",
+		HCHost:                    "example.com",
+		HCIntervalSeconds:         5,
+		HCTimeoutSeconds:          2,
+		HCUnhealthyThresholdCount: 3,
+		HCHealthyThresholdCount:   1,
+		Strategy:                  "RoundRobin",
+		Protocol:                  "grpc",
+	}
+
+	_, err := s.GetUpstream(u.Name)
+	assertIs(t, err, store.ErrNotFound)
+	assertIs(t, s.UpdateUpstream(u), store.ErrNotFound)
+
+	require.NoError(t, s.CreateUpstream(u))
+	assertIs(t, s.CreateUpstream(u), store.ErrExists)
+
+	got, err := s.GetUpstream(u.Name)
+	require.NoError(t, err)
+	want := *u
+	want.ID, want.CreateTS, want.UpdateTS = got.ID, got.CreateTS, got.UpdateTS
+	assert.Equal(t, want, *got)
+
+	u.Port = 9090
+	u.Weight = 50
+	require.NoError(t, s.UpdateUpstream(u))
+	got, err = s.GetUpstream(u.Name)
+	require.NoError(t, err)
+	assert.Equal(t, 9090, got.Port)
+	assert.Equal(t, 50, got.Weight)
+	assert.Equal(t, want.ID, got.ID)
+
+	us, err := s.ListUpstreams()
+	require.NoError(t, err)
+	assert.Contains(t, upstreamNames(us), u.Name)
+
+	require.NoError(t, s.DeleteUpstream(u.Name))
+	assertIs(t, s.DeleteUpstream(u.Name), store.ErrNotFound)
+}
+
+func testSecret(t *testing.T, s store.Store, prefix string) {
+	sec := &store.Secret{
+		Name:        prefix + "secret",
+		Key:         "key",
+		Cert:        "cert",
+		SNI:         "example.com",
+		TLSConfig:   `{"minimum_protocol_version":"1.2"}`,
+		ACMEService: prefix + "service",
+	}
+
+	_, err := s.GetSecret(sec.Name)
+	assertIs(t, err, store.ErrNotFound)
+	assertIs(t, s.UpdateSecret(sec), store.ErrNotFound)
+
+	require.NoError(t, s.CreateSecret(sec))
+	assertIs(t, s.CreateSecret(sec), store.ErrExists)
+
+	got, err := s.GetSecret(sec.Name)
+	require.NoError(t, err)
+	want := *sec
+	want.ID, want.CreateTS, want.UpdateTS = got.ID, got.CreateTS, got.UpdateTS
+	assert.Equal(t, want, *got)
+
+	sec.Cert = "cert2"
+	require.NoError(t, s.UpdateSecret(sec))
+	got, err = s.GetSecret(sec.Name)
+	require.NoError(t, err)
+	assert.Equal(t, "cert2", got.Cert)
+	assert.Equal(t, "key", got.Key)
+	assert.Equal(t, prefix+"service", got.ACMEService)
+
+	ss, err := s.ListSecrets()
+	require.NoError(t, err)
+	assert.Contains(t, secretNames(ss), sec.Name)
+
+	require.NoError(t, s.DeleteSecret(sec.Name))
+	assertIs(t, s.DeleteSecret(sec.Name), store.ErrNotFound)
+}
+
+func testFilter(t *testing.T, s store.Store, prefix string) {
+	f := &store.Filter{
+		Name:       prefix + "filter",
+		Type:       "http_filter_lua",
+		Config:     "function envoy_on_request(h) end",
+		ConfigJSON: json.RawMessage(`{"config":"function envoy_on_request(h) end"}`),
+	}
+
+	_, err := s.GetFilter(f.Name)
+	assertIs(t, err, store.ErrNotFound)
+	assertIs(t, s.UpdateFilter(f), store.ErrNotFound)
+
+	require.NoError(t, s.CreateFilter(f))
+	assertIs(t, s.CreateFilter(f), store.ErrExists)
+
+	got, err := s.GetFilter(f.Name)
+	require.NoError(t, err)
+	assert.Equal(t, f.Type, got.Type)
+	assert.Equal(t, f.Config, got.Config)
+	assert.JSONEq(t, string(f.ConfigJSON), string(got.ConfigJSON))
+
+	f.Type = "route_filter_ratelimit"
+	require.NoError(t, s.UpdateFilter(f))
+	got, err = s.GetFilter(f.Name)
+	require.NoError(t, err)
+	assert.Equal(t, "route_filter_ratelimit", got.Type)
+
+	fs, err := s.ListFilters()
+	require.NoError(t, err)
+	assert.Contains(t, filterNames(fs), f.Name)
+
+	require.NoError(t, s.DeleteFilter(f.Name))
+	assertIs(t, s.DeleteFilter(f.Name), store.ErrNotFound)
+}
+
+func testGlobalConfig(t *testing.T, s store.Store, prefix string) {
+	gc := &store.GlobalConfig{
+		Name:       prefix + "globalconfig",
+		Type:       "globalconfig_ratelimit",
+		Config:     `{"domain":"enroute"}`,
+		ConfigJSON: json.RawMessage(`{"domain":"enroute"}`),
+	}
+
+	_, err := s.GetGlobalConfig(gc.Name)
+	assertIs(t, err, store.ErrNotFound)
+	assertIs(t, s.UpdateGlobalConfig(gc), store.ErrNotFound)
+
+	require.NoError(t, s.CreateGlobalConfig(gc))
+	assertIs(t, s.CreateGlobalConfig(gc), store.ErrExists)
+
+	got, err := s.GetGlobalConfig(gc.Name)
+	require.NoError(t, err)
+	assert.Equal(t, gc.Type, got.Type)
+	assert.Equal(t, gc.Config, got.Config)
+	assert.JSONEq(t, string(gc.ConfigJSON), string(got.ConfigJSON))
+
+	gc.Type = "globalconfig_tls"
+	require.NoError(t, s.UpdateGlobalConfig(gc))
+	got, err = s.GetGlobalConfig(gc.Name)
+	require.NoError(t, err)
+	assert.Equal(t, "globalconfig_tls", got.Type)
+
+	gcs, err := s.ListGlobalConfigs()
+	require.NoError(t, err)
+	assert.Contains(t, globalConfigNames(gcs), gc.Name)
+
+	require.NoError(t, s.DeleteGlobalConfig(gc.Name))
+	assertIs(t, s.DeleteGlobalConfig(gc.Name), store.ErrNotFound)
+}
+
+// fixture holds one object of each kind, not associated.
+type fixture struct {
+	proxy, service, route, upstream, secret, filter, globalconfig string
+}
+
+func createFixture(t *testing.T, s store.Store, prefix string) fixture {
+	f := fixture{
+		proxy:        prefix + "proxy",
+		service:      prefix + "service",
+		route:        prefix + "route",
+		upstream:     prefix + "upstream",
+		secret:       prefix + "secret",
+		filter:       prefix + "filter",
+		globalconfig: prefix + "globalconfig",
+	}
+	require.NoError(t, s.CreateProxy(f.proxy))
+	require.NoError(t, s.CreateService(&store.Service{Name: f.service, Fqdn: "example.com"}))
+	require.NoError(t, s.CreateRoute(&store.Route{Service: f.service, Name: f.route, Prefix: "/"}))
+	require.NoError(t, s.CreateUpstream(&store.Upstream{Name: f.upstream, IP: "127.0.0.1", Port: 80, Weight: 100}))
+	require.NoError(t, s.CreateSecret(&store.Secret{Name: f.secret, Key: "key", Cert: "cert"}))
+	require.NoError(t, s.CreateFilter(&store.Filter{Name: f.filter, Type: "http_filter_lua"}))
+	require.NoError(t, s.CreateGlobalConfig(&store.GlobalConfig{Name: f.globalconfig, Type: "globalconfig_ratelimit"}))
+	return f
+}
+
+func associateFixture(t *testing.T, s store.Store, f fixture) {
+	require.NoError(t, s.AssociateProxyService(f.proxy, f.service))
+	require.NoError(t, s.AssociateProxyGlobalConfig(f.proxy, f.globalconfig))
+	require.NoError(t, s.AssociateRouteUpstream(f.service, f.route, f.upstream))
+	require.NoError(t, s.AssociateRouteFilter(f.service, f.route, f.filter))
+	require.NoError(t, s.AssociateServiceSecret(f.service, f.secret))
+	require.NoError(t, s.AssociateServiceFilter(f.service, f.filter))
+}
+
+func deleteFixture(t *testing.T, s store.Store, f fixture) {
+	assert.NoError(t, s.DisassociateProxyService(f.proxy, f.service))
+	assert.NoError(t, s.DisassociateProxyGlobalConfig(f.proxy, f.globalconfig))
+	assert.NoError(t, s.DisassociateRouteUpstream(f.service, f.route, f.upstream))
+	assert.NoError(t, s.DisassociateRouteFilter(f.service, f.route, f.filter))
+	assert.NoError(t, s.DisassociateServiceSecret(f.service, f.secret))
+	assert.NoError(t, s.DisassociateServiceFilter(f.service, f.filter))
+
+	assert.NoError(t, s.DeleteRoute(f.service, f.route))
+	assert.NoError(t, s.DeleteService(f.service))
+	assert.NoError(t, s.DeleteProxy(f.proxy))
+	assert.NoError(t, s.DeleteUpstream(f.upstream))
+	assert.NoError(t, s.DeleteSecret(f.secret))
+	assert.NoError(t, s.DeleteFilter(f.filter))
+	assert.NoError(t, s.DeleteGlobalConfig(f.globalconfig))
+}
+
+func testAssociations(t *testing.T, s store.Store, prefix string) {
+	f := createFixture(t, s, prefix)
+	missing := prefix + "missing"
+
+	// Both ends must exist
+	assertIs(t, s.AssociateProxyService(f.proxy, missing), store.ErrNotFound)
+	assertIs(t, s.AssociateProxyService(missing, f.service), store.ErrNotFound)
+	assertIs(t, s.AssociateProxyGlobalConfig(f.proxy, missing), store.ErrNotFound)
+	assertIs(t, s.AssociateRouteUpstream(f.service, f.route, missing), store.ErrNotFound)
+	assertIs(t, s.AssociateRouteUpstream(f.service, missing, f.upstream), store.ErrNotFound)
+	assertIs(t, s.AssociateRouteFilter(f.service, f.route, missing), store.ErrNotFound)
+	assertIs(t, s.AssociateServiceSecret(f.service, missing), store.ErrNotFound)
+	assertIs(t, s.AssociateServiceFilter(missing, f.filter), store.ErrNotFound)
+
+	// Associations which do not exist cannot be removed
+	assertIs(t, s.DisassociateProxyService(f.proxy, f.service), store.ErrNotFound)
+	assertIs(t, s.DisassociateRouteUpstream(f.service, f.route, f.upstream), store.ErrNotFound)
+
+	associateFixture(t, s, f)
+	// Making an association again does nothing
+	associateFixture(t, s, f)
+
+	services, err := s.ListProxyServices(f.proxy)
+	require.NoError(t, err)
+	assert.Equal(t, []string{f.service}, serviceNames(services))
+
+	proxies, err := s.ListServiceProxies(f.service)
+	require.NoError(t, err)
+	assert.Equal(t, []string{f.proxy}, proxyNames(proxies))
+
+	gcs, err := s.ListProxyGlobalConfigs(f.proxy)
+	require.NoError(t, err)
+	assert.Equal(t, []string{f.globalconfig}, globalConfigNames(gcs))
+
+	upstreams, err := s.ListRouteUpstreams(f.service, f.route)
+	require.NoError(t, err)
+	assert.Equal(t, []string{f.upstream}, upstreamNames(upstreams))
+
+	routes, err := s.ListUpstreamRoutes(f.upstream)
+	require.NoError(t, err)
+	if assert.Len(t, routes, 1) {
+		assert.Equal(t, f.route, routes[0].Name)
+		assert.Equal(t, f.service, routes[0].Service)
+	}
+
+	filters, err := s.ListRouteFilters(f.service, f.route)
+	require.NoError(t, err)
+	assert.Equal(t, []string{f.filter}, filterNames(filters))
+
+	secrets, err := s.ListServiceSecrets(f.service)
+	require.NoError(t, err)
+	assert.Equal(t, []string{f.secret}, secretNames(secrets))
+
+	filters, err = s.ListServiceFilters(f.service)
+	require.NoError(t, err)
+	assert.Equal(t, []string{f.filter}, filterNames(filters))
+
+	services, err = s.ListSecretServices(f.secret)
+	require.NoError(t, err)
+	assert.Equal(t, []string{f.service}, serviceNames(services))
+
+	services, err = s.ListFilterServices(f.filter)
+	require.NoError(t, err)
+	assert.Equal(t, []string{f.service}, serviceNames(services))
+
+	routes, err = s.ListFilterRoutes(f.filter)
+	require.NoError(t, err)
+	if assert.Len(t, routes, 1) {
+		assert.Equal(t, f.route, routes[0].Name)
+		assert.Equal(t, f.service, routes[0].Service)
+	}
+
+	deleteFixture(t, s, f)
+
+	// Removed associations are no longer listed, and cannot be removed again
+	_, err = s.ListProxyServices(f.proxy)
+	assertIs(t, err, store.ErrNotFound)
+	assertIs(t, s.DisassociateProxyService(f.proxy, f.service), store.ErrNotFound)
+}
+
+func testProxyDetail(t *testing.T, s store.Store, prefix string) {
+	f := createFixture(t, s, prefix)
+	associateFixture(t, s, f)
+
+	pd, err := s.ProxyDetail(f.proxy)
+	require.NoError(t, err)
+	assert.Equal(t, f.proxy, pd.Name)
+	if assert.Len(t, pd.GlobalConfigs, 1) {
+		assert.Equal(t, f.globalconfig, pd.GlobalConfigs[0].GlobalConfig.Name)
+	}
+	if assert.Len(t, pd.Services, 1) {
+		sd := pd.Services[0].Service
+		assert.Equal(t, f.service, sd.Name)
+		assert.Equal(t, "example.com", sd.Fqdn)
+		if assert.Len(t, sd.Routes, 1) {
+			rd := sd.Routes[0]
+			assert.Equal(t, f.route, rd.Name)
+			assert.Equal(t, f.service, rd.Service)
+			if assert.Len(t, rd.Upstreams, 1) {
+				assert.Equal(t, f.upstream, rd.Upstreams[0].Upstream.Name)
+				assert.Equal(t, 80, rd.Upstreams[0].Upstream.Port)
+			}
+			if assert.Len(t, rd.Filters, 1) {
+				assert.Equal(t, f.filter, rd.Filters[0].Filter.Name)
+			}
+		}
+		if assert.Len(t, sd.Secrets, 1) {
+			assert.Equal(t, "cert", sd.Secrets[0].Secret.Cert)
+		}
+		if assert.Len(t, sd.Filters, 1) {
+			assert.Equal(t, f.filter, sd.Filters[0].Filter.Name)
+		}
+	}
+
+	sd, err := s.ServiceDetail(f.service)
+	require.NoError(t, err)
+	assert.Equal(t, pd.Services[0].Service, *sd)
+
+	pds, err := s.ListProxyDetails()
+	require.NoError(t, err)
+	var found bool
+	for _, d := range pds {
+		if d.Name == f.proxy {
+			found = true
+			assert.Equal(t, *pd, d)
+		}
+	}
+	assert.True(t, found, "proxy %s not in ListProxyDetails", f.proxy)
+
+	_, err = s.ProxyDetail(prefix + "missing")
+	assertIs(t, err, store.ErrNotFound)
+
+	deleteFixture(t, s, f)
+}
+
+func testDeleteInUse(t *testing.T, s store.Store, prefix string) {
+	f := createFixture(t, s, prefix)
+	associateFixture(t, s, f)
+
+	assertIs(t, s.DeleteProxy(f.proxy), store.ErrInUse)
+	assertIs(t, s.DeleteService(f.service), store.ErrInUse)
+	assertIs(t, s.DeleteRoute(f.service, f.route), store.ErrInUse)
+	assertIs(t, s.DeleteUpstream(f.upstream), store.ErrInUse)
+	assertIs(t, s.DeleteSecret(f.secret), store.ErrInUse)
+	assertIs(t, s.DeleteFilter(f.filter), store.ErrInUse)
+	assertIs(t, s.DeleteGlobalConfig(f.globalconfig), store.ErrInUse)
+
+	// Nothing was deleted
+	_, err := s.GetUpstream(f.upstream)
+	assert.NoError(t, err)
+
+	deleteFixture(t, s, f)
+}
+
+func testDeleteCascade(t *testing.T, s store.Store, prefix string) {
+	f := createFixture(t, s, prefix)
+	associateFixture(t, s, f)
+
+	require.NoError(t, s.DeleteCascade("upstream", f.upstream))
+	require.NoError(t, s.DeleteCascade("filter", f.filter))
+	require.NoError(t, s.DeleteCascade("secret", f.secret))
+	_, err := s.GetUpstream(f.upstream)
+	assertIs(t, err, store.ErrNotFound)
+	_, err = s.GetFilter(f.filter)
+	assertIs(t, err, store.ErrNotFound)
+	_, err = s.GetSecret(f.secret)
+	assertIs(t, err, store.ErrNotFound)
+
+	sd, err := s.ServiceDetail(f.service)
+	require.NoError(t, err)
+	assert.Empty(t, sd.Secrets)
+	assert.Empty(t, sd.Filters)
+	require.Len(t, sd.Routes, 1)
+	assert.Empty(t, sd.Routes[0].Upstreams)
+	assert.Empty(t, sd.Routes[0].Filters)
+
+	// Services are deleted with their routes
+	require.NoError(t, s.DeleteCascade("service", f.service))
+	_, err = s.GetService(f.service)
+	assertIs(t, err, store.ErrNotFound)
+	_, err = s.GetRoute(f.service, f.route)
+	assertIs(t, err, store.ErrNotFound)
+	ss, err := s.ListProxyServices(f.proxy)
+	require.NoError(t, err)
+	assert.Empty(t, ss)
+
+	assertIs(t, s.DeleteCascade("service", f.service), store.ErrNotFound)
+	assert.Error(t, s.DeleteCascade("proxy", f.proxy))
+
+	assert.NoError(t, s.DisassociateProxyGlobalConfig(f.proxy, f.globalconfig))
+	assert.NoError(t, s.DeleteProxy(f.proxy))
+	assert.NoError(t, s.DeleteGlobalConfig(f.globalconfig))
+}
+
+func testRevision(t *testing.T, s store.Store, prefix string) {
+	proxy := prefix + "proxy"
+
+	rs, err := s.ListRevisions(proxy)
+	require.NoError(t, err)
+	assert.Empty(t, rs)
+	_, err = s.LatestRevision(proxy)
+	assertIs(t, err, store.ErrNotFound)
+
+	r1 := &store.Revision{Proxy: proxy, Author: "a", Config: json.RawMessage(`{"n":1}`), Diff: "+1"}
+	require.NoError(t, s.CreateRevision(r1))
+	assert.NotZero(t, r1.ID)
+	assert.False(t, r1.CreateTS.IsZero())
+
+	r2 := &store.Revision{Proxy: proxy, Author: "b", Config: json.RawMessage(`{"n":2}`), Diff: "-1\n+2"}
+	require.NoError(t, s.CreateRevision(r2))
+	assert.True(t, r2.ID > r1.ID)
+
+	rs, err = s.ListRevisions(proxy)
+	require.NoError(t, err)
+	if assert.Len(t, rs, 2) {
+		assert.Equal(t, r1.ID, rs[0].ID)
+		assert.Equal(t, r2.ID, rs[1].ID)
+	}
+
+	got, err := s.GetRevision(proxy, r1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "a", got.Author)
+	assert.Equal(t, "+1", got.Diff)
+	assert.JSONEq(t, `{"n":1}`, string(got.Config))
+
+	got, err = s.LatestRevision(proxy)
+	require.NoError(t, err)
+	assert.Equal(t, r2.ID, got.ID)
+
+	_, err = s.GetRevision(prefix+"other", r1.ID)
+	assertIs(t, err, store.ErrNotFound)
+}
+
+func testToken(t *testing.T, s store.Store, prefix string) {
+	name := prefix + "token"
+
+	_, err := s.GetToken(name)
+	assertIs(t, err, store.ErrNotFound)
+
+	tok := &store.Token{Name: name, Hash: prefix + "hash", Role: "operator", Proxies: []string{"gw"}}
+	require.NoError(t, s.CreateToken(tok))
+	assert.NotZero(t, tok.ID)
+	assert.False(t, tok.CreateTS.IsZero())
+	assertIs(t, s.CreateToken(&store.Token{Name: name, Hash: prefix + "other", Role: "admin"}), store.ErrExists)
+
+	got, err := s.GetToken(name)
+	require.NoError(t, err)
+	assert.Equal(t, "operator", got.Role)
+	assert.Equal(t, []string{"gw"}, got.Proxies)
+	assert.Empty(t, got.Services)
+
+	got, err = s.TokenByHash(prefix + "hash")
+	require.NoError(t, err)
+	assert.Equal(t, name, got.Name)
+	_, err = s.TokenByHash(prefix + "other")
+	assertIs(t, err, store.ErrNotFound)
+
+	ts, err := s.ListTokens()
+	require.NoError(t, err)
+	assert.Contains(t, tokenNames(ts), name)
+
+	require.NoError(t, s.DeleteToken(name))
+	assertIs(t, s.DeleteToken(name), store.ErrNotFound)
+	_, err = s.TokenByHash(prefix + "hash")
+	assertIs(t, err, store.ErrNotFound)
+}
+
+func testAudit(t *testing.T, s store.Store, prefix string) {
+	svc := "service:" + prefix + "service"
+
+	r1 := &store.AuditRecord{
+		Token:    "t",
+		IP:       "127.0.0.1",
+		Method:   "PATCH",
+		Endpoint: "/service/:service_name",
+		Path:     "/service/" + prefix + "service",
+		Objects:  []string{svc},
+		Before:   json.RawMessage(`{"fqdn":"a"}`),
+		After:    json.RawMessage(`{"fqdn":"b"}`),
+		Code:     201,
+	}
+	require.NoError(t, s.CreateAuditRecord(r1))
+	assert.NotZero(t, r1.ID)
+	assert.False(t, r1.CreateTS.IsZero())
+
+	r2 := &store.AuditRecord{Method: "POST", Endpoint: "/proxy", Path: "/proxy", Code: 400}
+	require.NoError(t, s.CreateAuditRecord(r2))
+	assert.True(t, r2.ID > r1.ID)
+
+	rs, err := s.ListAuditRecords(r1.CreateTS, svc)
+	require.NoError(t, err)
+	if assert.Len(t, rs, 1) {
+		assert.Equal(t, r1.ID, rs[0].ID)
+		assert.Equal(t, "t", rs[0].Token)
+		assert.Equal(t, []string{svc}, rs[0].Objects)
+		assert.JSONEq(t, `{"fqdn":"b"}`, string(rs[0].After))
+		assert.Equal(t, 201, rs[0].Code)
+	}
+
+	rs, err = s.ListAuditRecords(r1.CreateTS, "")
+	require.NoError(t, err)
+	ids := []int64{}
+	for _, r := range rs {
+		ids = append(ids, r.ID)
+	}
+	assert.Contains(t, ids, r1.ID)
+	assert.Contains(t, ids, r2.ID)
+
+	rs, err = s.ListAuditRecords(r2.CreateTS.Add(time.Hour), "")
+	require.NoError(t, err)
+	assert.Empty(t, rs)
+}
+
+func testWebhook(t *testing.T, s store.Store, prefix string) {
+	name := prefix + "webhook"
+
+	_, err := s.GetWebhook(name)
+	assertIs(t, err, store.ErrNotFound)
+
+	w := &store.Webhook{Name: name, URL: "https://example.com/hook", Secret: "key", Events: []string{"service.*"}}
+	require.NoError(t, s.CreateWebhook(w))
+	assert.NotZero(t, w.ID)
+	assert.False(t, w.CreateTS.IsZero())
+	assertIs(t, s.CreateWebhook(&store.Webhook{Name: name, URL: "https://example.com/other"}), store.ErrExists)
+
+	got, err := s.GetWebhook(name)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/hook", got.URL)
+	assert.Equal(t, "key", got.Secret)
+	assert.Equal(t, []string{"service.*"}, got.Events)
+
+	ws, err := s.ListWebhooks()
+	require.NoError(t, err)
+	assert.Contains(t, webhookNames(ws), name)
+
+	require.NoError(t, s.DeleteWebhook(name))
+	assertIs(t, s.DeleteWebhook(name), store.ErrNotFound)
+}
+
+func testRestoreProxy(t *testing.T, s store.Store, prefix string) {
+	f := createFixture(t, s, prefix)
+	associateFixture(t, s, f)
+
+	pd, err := s.ProxyDetail(f.proxy)
+	require.NoError(t, err)
+
+	// Change the configuration of the proxy every way RestoreProxy undoes
+	u, err := s.GetUpstream(f.upstream)
+	require.NoError(t, err)
+	u.IP = "127.0.0.2"
+	require.NoError(t, s.UpdateUpstream(u))
+	require.NoError(t, s.CreateRoute(&store.Route{Service: f.service, Name: f.route + "2", Prefix: "/2"}))
+	require.NoError(t, s.DisassociateServiceSecret(f.service, f.secret))
+	require.NoError(t, s.DisassociateProxyGlobalConfig(f.proxy, f.globalconfig))
+	require.NoError(t, s.DisassociateRouteFilter(f.service, f.route, f.filter))
+
+	// but the certificates managed by ACME
+	sec, err := s.GetSecret(f.secret)
+	require.NoError(t, err)
+	sec.ACMEService = f.service
+	require.NoError(t, s.UpdateSecret(sec))
+
+	r := &store.Revision{Proxy: f.proxy, Author: "a", Diff: "rollback"}
+	require.NoError(t, s.RestoreProxy(pd, r))
+	assert.NotZero(t, r.ID)
+
+	sec, err = s.GetSecret(f.secret)
+	require.NoError(t, err)
+	assert.Equal(t, f.service, sec.ACMEService)
+
+	got, err := s.ProxyDetail(f.proxy)
+	require.NoError(t, err)
+	if assert.Len(t, got.GlobalConfigs, 1) {
+		assert.Equal(t, f.globalconfig, got.GlobalConfigs[0].GlobalConfig.Name)
+	}
+	if assert.Len(t, got.Services, 1) {
+		sd := got.Services[0].Service
+		if assert.Len(t, sd.Routes, 1) {
+			rd := sd.Routes[0]
+			assert.Equal(t, f.route, rd.Name)
+			if assert.Len(t, rd.Upstreams, 1) {
+				assert.Equal(t, "127.0.0.1", rd.Upstreams[0].Upstream.IP)
+			}
+			assert.Len(t, rd.Filters, 1)
+		}
+		assert.Len(t, sd.Secrets, 1)
+		assert.Len(t, sd.Filters, 1)
+	}
+
+	latest, err := s.LatestRevision(f.proxy)
+	require.NoError(t, err)
+	assert.Equal(t, r.ID, latest.ID)
+
+	deleteFixture(t, s, f)
+}
+
+func proxyNames(ps []store.Proxy) []string {
+	var names []string
+	for _, p := range ps {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func serviceNames(ss []store.Service) []string {
+	var names []string
+	for _, s := range ss {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func upstreamNames(us []store.Upstream) []string {
+	var names []string
+	for _, u := range us {
+		names = append(names, u.Name)
+	}
+	return names
+}
+
+func secretNames(ss []store.Secret) []string {
+	var names []string
+	for _, s := range ss {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func filterNames(fs []store.Filter) []string {
+	var names []string
+	for _, f := range fs {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func globalConfigNames(gcs []store.GlobalConfig) []string {
+	var names []string
+	for _, gc := range gcs {
+		names = append(names, gc.Name)
+	}
+	return names
+}
+
+func tokenNames(ts []store.Token) []string {
+	var names []string
+	for _, t := range ts {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+func webhookNames(ws []store.Webhook) []string {
+	var names []string
+	for _, w := range ws {
+		names = append(names, w.Name)
+	}
+	return names
+}
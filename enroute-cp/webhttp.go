@@ -8,9 +8,13 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	_ "github.com/saarasio/enroute/enroute-cp/docs"
+	"github.com/saarasio/enroute/enroute-cp/store"
 	webhttp "github.com/saarasio/enroute/enroute-cp/webhttp"
+	"github.com/saarasio/enroute/enroute-dp/saarasconfig"
 	"github.com/swaggo/echo-swagger"
+	"golang.org/x/crypto/acme"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -25,6 +29,7 @@ import (
 // @name Authorization
 func main() {
 	e := echo.New()
+	e.HTTPErrorHandler = webhttp.HTTPErrorHandler
 
 	// enviornment
 	webhttp.HOST = os.Getenv("DB_HOST")
@@ -40,6 +45,71 @@ func main() {
 
 	fmt.Printf(" DB_HOST set to [%s] DB_PORT set to [%s] SECRET set to [%s]\n", webhttp.HOST, webhttp.PORT, webhttp.SECRET)
 
+	// store, in DB_FILE if set, else on Hasura at DB_HOST:DB_PORT
+	if db_file := os.Getenv("DB_FILE"); db_file != "" {
+		db, err := store.OpenLocal(db_file)
+		if err != nil {
+			e.Logger.Fatal(err)
+		}
+		// only the latest revisions and audit records are kept in it
+		for env, limit := range map[string]*int{
+			"DB_FILE_REVISION_LIMIT": &db.RevisionLimit,
+			"DB_FILE_AUDIT_LIMIT":    &db.AuditLimit,
+		} {
+			if v := os.Getenv(env); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 0 {
+					e.Logger.Fatalf("%s must be a number of records, or 0 to keep them all", env)
+				}
+				*limit = n
+			}
+		}
+		webhttp.DB = db
+		fmt.Printf(" DB_FILE set to [%s], using local store keeping [%d] revisions per proxy and [%d] audit records\n",
+			db_file, db.RevisionLimit, db.AuditLimit)
+	} else {
+		webhttp.DB = store.NewHasura(webhttp.HOST, webhttp.PORT)
+		fmt.Printf(" DB_FILE not set, using hasura store\n")
+	}
+
+	// audit records are written to AUDIT_FILE as well if set
+	if audit_file := os.Getenv("AUDIT_FILE"); audit_file != "" {
+		if err := webhttp.SetAuditFile(audit_file); err != nil {
+			e.Logger.Fatal(err)
+		}
+		fmt.Printf(" AUDIT_FILE set to [%s]\n", audit_file)
+	}
+
+	// ACME
+	webhttp.ACME_DIRECTORY_URL = os.Getenv("ACME_DIRECTORY_URL")
+	webhttp.ACME_EMAIL = os.Getenv("ACME_EMAIL")
+	webhttp.ACME_CA_BUNDLE = os.Getenv("ACME_CA_BUNDLE")
+	webhttp.ACME_HTTP01_UPSTREAM_IP = os.Getenv("ACME_HTTP01_UPSTREAM_IP")
+	webhttp.ACME_HTTP01_UPSTREAM_PORT = os.Getenv("ACME_HTTP01_UPSTREAM_PORT")
+
+	if webhttp.ACME_DIRECTORY_URL == "" {
+		webhttp.ACME_DIRECTORY_URL = acme.LetsEncryptURL
+	}
+	if webhttp.ACME_HTTP01_UPSTREAM_PORT == "" {
+		webhttp.ACME_HTTP01_UPSTREAM_PORT = "1323"
+	}
+
+	fmt.Printf(" ACME_DIRECTORY_URL set to [%s] ACME_HTTP01_UPSTREAM set to [%s:%s]\n",
+		webhttp.ACME_DIRECTORY_URL, webhttp.ACME_HTTP01_UPSTREAM_IP, webhttp.ACME_HTTP01_UPSTREAM_PORT)
+
+	// usage reports are opt-in, sent to USAGE_REPORT_URL if set
+	switch strings.ToLower(os.Getenv("SEND_ANON_STAT")) {
+	case "yes", "true", "1":
+		url := os.Getenv("USAGE_REPORT_URL")
+		if url == "" {
+			url = webhttp.USAGE_REPORT_URL
+		}
+		webhttp.USAGE_SINK = &webhttp.HTTPUsageSink{URL: url}
+		fmt.Printf(" SEND_ANON_STAT set, sending usage reports to [%s]\n", url)
+	default:
+		fmt.Printf(" SEND_ANON_STAT not set, not sending usage reports\n")
+	}
+
 	// middleware
 
 	config := middleware.KeyAuthConfig{
@@ -48,20 +118,26 @@ func main() {
 			if strings.HasPrefix(uri, "/swagger") {
 				return true
 			}
+			if strings.HasPrefix(uri, saarasconfig.ACME_HTTP01_CHALLENGE_PREFIX) {
+				return true
+			}
+			// enroute-dp authenticates too, with --enroute-cp-token
 			return false
 		},
 		KeyLookup:  "header:" + echo.HeaderAuthorization,
 		AuthScheme: "Bearer",
-		Validator: func(key string, c echo.Context) (bool, error) {
-			return key == webhttp.SECRET, nil
-		},
+		Validator:  webhttp.Authenticate,
 	}
 
+	// Requests carry WEBAPP_SECRET or an API token created with it
 	if webhttp.SECRET != "" {
 		e.Use(middleware.KeyAuthWithConfig(config))
 	}
+	e.Use(webhttp.Audit)
+	e.Use(webhttp.Authorize)
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 	e.Use(middleware.Logger())
+	e.Use(webhttp.RecordRevisions)
 
 	e.HideBanner = true
 	webhttp.Add_proxy_routes(e)
@@ -69,6 +145,15 @@ func main() {
 	webhttp.Add_upstream_routes(e)
 	webhttp.Add_secret_routes(e)
 	webhttp.Add_filter_routes(e)
+	webhttp.Add_acme_routes(e)
+	webhttp.Add_graphql_routes(e)
+	webhttp.Add_revision_routes(e)
+	webhttp.Add_watch_routes(e)
+	webhttp.Add_token_routes(e)
+	webhttp.Add_audit_routes(e)
+	webhttp.Add_webhook_routes(e)
+	webhttp.Add_usage_routes(e)
 	go webhttp.Reporter()
+	go webhttp.ACMERenewer()
 	e.Logger.Fatal(e.Start("0.0.0.0:1323"))
 }
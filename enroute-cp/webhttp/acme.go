@@ -0,0 +1,468 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package webhttp
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/saarasio/enroute/enroute-dp/saarasconfig"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme"
+)
+
+// ACME directory to obtain certificates from, defaults to Let's Encrypt
+var ACME_DIRECTORY_URL string
+
+// Contact email registered with the ACME account
+var ACME_EMAIL string
+
+// PEM bundle of CAs to trust when talking to the ACME directory, eg: pebble's CA
+var ACME_CA_BUNDLE string
+
+// Address at which the gateway reaches enroute-cp to serve HTTP-01 challenges
+var ACME_HTTP01_UPSTREAM_IP string
+var ACME_HTTP01_UPSTREAM_PORT string
+
+// Certificates are renewed when they expire within this window
+var ACME_RENEW_BEFORE = 30 * 24 * time.Hour
+
+// Interval at which certificates are checked for renewal
+var ACME_RENEW_INTERVAL = 12 * time.Hour
+
+const (
+	// Name of the secret holding the ACME account key
+	acmeAccountSecret = "enroute-acme-account"
+
+	// Issued certificates are stored in secrets named acme-<service_name>,
+	// marked with the service they were issued for
+	acmeSecretPrefix = "acme-"
+
+	// Name of the upstream and route used to answer HTTP-01 challenges
+	acmeHTTP01Name = "enroute-acme-http01"
+)
+
+// acmeChallenges holds the key authorizations of pending HTTP-01 challenges by token.
+type acmeChallenges struct {
+	mu     sync.RWMutex
+	tokens map[string]string
+}
+
+func (ac *acmeChallenges) put(token, keyAuth string) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if ac.tokens == nil {
+		ac.tokens = make(map[string]string)
+	}
+	ac.tokens[token] = keyAuth
+}
+
+func (ac *acmeChallenges) get(token string) (string, bool) {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	keyAuth, ok := ac.tokens[token]
+	return keyAuth, ok
+}
+
+func (ac *acmeChallenges) delete(token string) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	delete(ac.tokens, token)
+}
+
+var challenges acmeChallenges
+
+// acmeMu serializes certificate issuance
+var acmeMu sync.Mutex
+
+func acmeHTTPClient() (*http.Client, error) {
+	if ACME_CA_BUNDLE == "" {
+		return http.DefaultClient, nil
+	}
+	pemCerts, err := ioutil.ReadFile(ACME_CA_BUNDLE)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading ACME CA bundle")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemCerts) {
+		return nil, fmt.Errorf("no certificates found in %s", ACME_CA_BUNDLE)
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}, nil
+}
+
+func encodeECKey(key *ecdsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})), nil
+}
+
+func decodeECKey(keyPEM string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, errors.New("no PEM data found in key")
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+// acmeAccountKey returns the ACME account key, creating and storing it on first use.
+func acmeAccountKey(log *logrus.Entry) (crypto.Signer, error) {
+	s, err := DB.GetSecret(acmeAccountSecret)
+	if err != nil && !isNotFound(err) {
+		return nil, err
+	}
+
+	if s != nil && s.Key != "" {
+		return decodeECKey(s.Key)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := encodeECKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := acmeSaveSecret(acmeAccountSecret, "", keyPEM, ""); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// acmeSaveSecret creates or updates the secret secret_name with key and
+// cert, marking it as issued for service_name if it is not empty.
+func acmeSaveSecret(secret_name, service_name, keyPEM, certPEM string) error {
+	s, err := DB.GetSecret(secret_name)
+	if isNotFound(err) {
+		return DB.CreateSecret(&store.Secret{Name: secret_name, Key: keyPEM, Cert: certPEM, ACMEService: service_name})
+	}
+	if err != nil {
+		return err
+	}
+
+	s.Key = keyPEM
+	s.Cert = certPEM
+	s.ACMEService = service_name
+	return DB.UpdateSecret(s)
+}
+
+// acmeClient returns a client for ACME_DIRECTORY_URL with a registered account.
+func acmeClient(ctx context.Context, log *logrus.Entry) (*acme.Client, error) {
+	key, err := acmeAccountKey(log)
+	if err != nil {
+		return nil, err
+	}
+	hc, err := acmeHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	client := &acme.Client{
+		Key:          key,
+		HTTPClient:   hc,
+		DirectoryURL: ACME_DIRECTORY_URL,
+	}
+
+	var contact []string
+	if ACME_EMAIL != "" {
+		contact = []string{"mailto:" + ACME_EMAIL}
+	}
+	if _, err := client.Register(ctx, &acme.Account{Contact: contact}, acme.AcceptTOS); err != nil && err != acme.ErrAccountAlreadyExists {
+		return nil, errors.Wrap(err, "registering ACME account")
+	}
+	return client, nil
+}
+
+// acmeObtainCertificate runs an ACME order for fqdn, answering HTTP-01
+// challenges through ac. It returns the PEM encoded key and certificate chain.
+func acmeObtainCertificate(ctx context.Context, client *acme.Client, ac *acmeChallenges, fqdn string) (string, string, error) {
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(fqdn))
+	if err != nil {
+		return "", "", errors.Wrap(err, "creating ACME order")
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		authz, err := client.GetAuthorization(ctx, authzURL)
+		if err != nil {
+			return "", "", errors.Wrap(err, "fetching ACME authorization")
+		}
+		if authz.Status == acme.StatusValid {
+			continue
+		}
+
+		var chal *acme.Challenge
+		for _, c := range authz.Challenges {
+			if c.Type == "http-01" {
+				chal = c
+				break
+			}
+		}
+		if chal == nil {
+			return "", "", fmt.Errorf("no http-01 challenge offered for %s", fqdn)
+		}
+
+		keyAuth, err := client.HTTP01ChallengeResponse(chal.Token)
+		if err != nil {
+			return "", "", err
+		}
+		ac.put(chal.Token, keyAuth)
+		defer ac.delete(chal.Token)
+
+		if _, err := client.Accept(ctx, chal); err != nil {
+			return "", "", errors.Wrap(err, "accepting http-01 challenge")
+		}
+		if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+			return "", "", errors.Wrap(err, "waiting for ACME authorization")
+		}
+	}
+
+	if order, err = client.WaitOrder(ctx, order.URI); err != nil {
+		return "", "", errors.Wrap(err, "waiting for ACME order")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: fqdn},
+		DNSNames: []string{fqdn},
+	}, key)
+	if err != nil {
+		return "", "", err
+	}
+
+	der, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return "", "", errors.Wrap(err, "finalizing ACME order")
+	}
+
+	var certPEM bytes.Buffer
+	for _, d := range der {
+		pem.Encode(&certPEM, &pem.Block{Type: "CERTIFICATE", Bytes: d})
+	}
+	keyPEM, err := encodeECKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	return keyPEM, certPEM.String(), nil
+}
+
+// acmeSetupChallengeRoute routes ACME HTTP-01 challenges for the service
+// through the gateway to enroute-cp.
+func acmeSetupChallengeRoute(service_name string, log *logrus.Entry) error {
+	if ACME_HTTP01_UPSTREAM_IP == "" {
+		return errors.New("ACME_HTTP01_UPSTREAM_IP not set, cannot route HTTP-01 challenges to enroute-cp")
+	}
+
+	port, err := strconv.Atoi(ACME_HTTP01_UPSTREAM_PORT)
+	if err != nil {
+		return fmt.Errorf("invalid ACME_HTTP01_UPSTREAM_PORT %q", ACME_HTTP01_UPSTREAM_PORT)
+	}
+
+	// The upstream may exist from an earlier issuance, it is updated in
+	// case enroute-cp moved
+	u := &store.Upstream{
+		Name:   acmeHTTP01Name,
+		IP:     ACME_HTTP01_UPSTREAM_IP,
+		Port:   port,
+		HCPath: saarasconfig.ACME_HTTP01_CHALLENGE_PREFIX,
+		Weight: 100,
+	}
+	err = DB.CreateUpstream(u)
+	if isExists(err) {
+		err = DB.UpdateUpstream(u)
+	}
+	if err != nil {
+		return errors.Wrap(err, "creating challenge upstream")
+	}
+
+	r, err := DB.GetRoute(service_name, acmeHTTP01Name)
+	switch {
+	case isNotFound(err):
+		err = DB.CreateRoute(&store.Route{
+			Service: service_name,
+			Name:    acmeHTTP01Name,
+			Prefix:  saarasconfig.ACME_HTTP01_CHALLENGE_PREFIX,
+		})
+	case err == nil && r.Prefix != saarasconfig.ACME_HTTP01_CHALLENGE_PREFIX:
+		err = fmt.Errorf("route %s exists with prefix %s", acmeHTTP01Name, r.Prefix)
+	}
+	if err != nil {
+		return errors.Wrap(err, "creating challenge route")
+	}
+
+	err = DB.AssociateRouteUpstream(service_name, acmeHTTP01Name, acmeHTTP01Name)
+	return errors.Wrap(err, "associating challenge route with upstream")
+}
+
+// acmeIssue obtains a certificate for the Fqdn of the service and stores it
+// in the secret acme-<service_name> associated with the service.
+func acmeIssue(ctx context.Context, service_name string, log *logrus.Entry) (string, error) {
+	acmeMu.Lock()
+	defer acmeMu.Unlock()
+
+	s, err := db_get_one_service(service_name)
+	if err != nil {
+		return "", errors.Wrap(err, "fetching service")
+	}
+	if s.Fqdn == "" {
+		return "", fmt.Errorf("service %s not found or has no fqdn", service_name)
+	}
+
+	if err := acmeSetupChallengeRoute(service_name, log); err != nil {
+		return "", err
+	}
+
+	client, err := acmeClient(ctx, log)
+	if err != nil {
+		return "", err
+	}
+
+	keyPEM, certPEM, err := acmeObtainCertificate(ctx, client, &challenges, s.Fqdn)
+	if err != nil {
+		return "", err
+	}
+
+	secret_name := acmeSecretPrefix + service_name
+	if err := acmeSaveSecret(secret_name, service_name, keyPEM, certPEM); err != nil {
+		return "", err
+	}
+	if err := DB.AssociateServiceSecret(service_name, secret_name); err != nil {
+		return "", err
+	}
+
+	log.Infof("Issued certificate for [%s] stored in secret [%s]\n", s.Fqdn, secret_name)
+	return secret_name, nil
+}
+
+// acmeNeedsRenewal returns true if the first certificate in certPEM
+// expires before now+window or cannot be parsed.
+func acmeNeedsRenewal(certPEM string, now time.Time, window time.Duration) bool {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return true
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true
+	}
+	return now.Add(window).After(cert.NotAfter)
+}
+
+// acmeRenew reissues certificates in acme-<service_name> secrets close to expiry.
+func acmeRenew(log *logrus.Entry) {
+	ss, err := DB.ListSecrets()
+	if err != nil {
+		log.Errorf("Error when listing secrets [%v]\n", err)
+		return
+	}
+
+	for _, s := range ss {
+		if s.ACMEService == "" {
+			continue
+		}
+		if !acmeNeedsRenewal(s.Cert, time.Now(), ACME_RENEW_BEFORE) {
+			continue
+		}
+		service_name := s.ACMEService
+		ids := map[string]string{"secret": s.Name}
+		before := auditSnapshot(ids)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		_, err := acmeIssue(ctx, service_name, log)
+		cancel()
+		revised := SaveRevisions("acme")
+		if err != nil {
+			log.Errorf("Failed to renew certificate in secret [%s] [%v]\n", s.Name, err)
+			continue
+		}
+		sendWebhooks(newWebhookEvent("secret", s.Name, []string{"secret:" + s.Name}, before, auditSnapshot(ids), revised, "acme"))
+	}
+}
+
+// ACMERenewer periodically renews certificates issued through ACME.
+func ACMERenewer() {
+	log2 := logrus.StandardLogger()
+	log := log2.WithField("context", "acme")
+
+	for {
+		acmeRenew(log)
+		time.Sleep(ACME_RENEW_INTERVAL)
+	}
+}
+
+// @Summary Issue a certificate for the service fqdn using ACME HTTP-01
+// @Description Obtain a certificate for the service fqdn from the ACME directory and store it in secret acme-{service_name}.
+// @Description The HTTP-01 challenge is served through the gateway, the fqdn must resolve to it.
+// @Tags service, secret
+// @Accept  json
+// @Produce  json
+// @Param service_name path string true "Name of service"
+// @Success 201 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
+// @Router /service/{service_name}/acme [post]
+// @Security ApiKeyAuth
+func POST_Service_ACME(c echo.Context) error {
+	log2 := logrus.StandardLogger()
+	log := log2.WithField("context", "web-http")
+
+	service_name := c.Param("service_name")
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Minute)
+	defer cancel()
+
+	secret_name, err := acmeIssue(ctx, service_name, log)
+	if err != nil {
+		log.Errorf("Failed to issue certificate for service [%s] [%v]\n", service_name, err)
+		return badRequest(c, &APIError{Code: ErrorInvalid, Message: err.Error(), Object: "service:" + service_name})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]string{"secret_name": secret_name})
+}
+
+// GET_ACME_Challenge answers HTTP-01 challenges forwarded by the gateway.
+// It is reached without an API key.
+func GET_ACME_Challenge(c echo.Context) error {
+	token := c.Param("token")
+	if token == "" {
+		// health check of the challenge upstream
+		return c.String(http.StatusOK, "")
+	}
+	keyAuth, ok := challenges.get(token)
+	if !ok {
+		return c.String(http.StatusNotFound, "")
+	}
+	return c.String(http.StatusOK, keyAuth)
+}
+
+func Add_acme_routes(e *echo.Echo) {
+	e.POST("/service/:service_name/acme", POST_Service_ACME)
+	e.GET(saarasconfig.ACME_HTTP01_CHALLENGE_PREFIX, GET_ACME_Challenge)
+	e.GET(saarasconfig.ACME_HTTP01_CHALLENGE_PREFIX+":token", GET_ACME_Challenge)
+}
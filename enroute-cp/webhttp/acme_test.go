@@ -0,0 +1,222 @@
+package webhttp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+)
+
+func TestACMEChallenge(t *testing.T) {
+	e := echo.New()
+	Add_acme_routes(e)
+
+	challenges.put("token1", "token1.thumbprint")
+	defer challenges.delete("token1")
+
+	tests := map[string]struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		"known token": {
+			path:     "/.well-known/acme-challenge/token1",
+			wantCode: http.StatusOK,
+			wantBody: "token1.thumbprint",
+		},
+		"unknown token": {
+			path:     "/.well-known/acme-challenge/token2",
+			wantCode: http.StatusNotFound,
+		},
+		"health check": {
+			path:     "/.well-known/acme-challenge/",
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, tc.wantBody, rec.Body.String())
+		})
+	}
+}
+
+func selfSignedCertPEM(t *testing.T, notAfter time.Time) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestACMENeedsRenewal(t *testing.T) {
+	now := time.Now()
+	window := 30 * 24 * time.Hour
+
+	tests := map[string]struct {
+		cert string
+		want bool
+	}{
+		"expires after window": {
+			cert: selfSignedCertPEM(t, now.Add(60*24*time.Hour)),
+			want: false,
+		},
+		"expires within window": {
+			cert: selfSignedCertPEM(t, now.Add(10*24*time.Hour)),
+			want: true,
+		},
+		"expired": {
+			cert: selfSignedCertPEM(t, now.Add(-time.Hour)),
+			want: true,
+		},
+		"no certificate": {
+			cert: "",
+			want: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, acmeNeedsRenewal(tc.cert, now, window))
+		})
+	}
+}
+
+func TestACMESetupChallengeRoute(t *testing.T) {
+	newTestAPI(t)
+	defer func() { ACME_HTTP01_UPSTREAM_IP, ACME_HTTP01_UPSTREAM_PORT = "", "" }()
+	log := logrus.StandardLogger().WithField("context", "acme")
+
+	require.NoError(t, DB.CreateService(&store.Service{Name: "svc", Fqdn: "example.com"}))
+	require.NoError(t, DB.CreateService(&store.Service{Name: "other", Fqdn: "other.example.com"}))
+
+	ACME_HTTP01_UPSTREAM_IP, ACME_HTTP01_UPSTREAM_PORT = "127.0.0.1", "nope"
+	assert.Error(t, acmeSetupChallengeRoute("svc", log))
+
+	ACME_HTTP01_UPSTREAM_PORT = "1323"
+	require.NoError(t, acmeSetupChallengeRoute("svc", log))
+
+	// The upstream follows enroute-cp when it moves
+	ACME_HTTP01_UPSTREAM_IP = "10.0.0.1"
+	require.NoError(t, acmeSetupChallengeRoute("svc", log))
+	u, err := DB.GetUpstream(acmeHTTP01Name)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", u.IP)
+	assert.Equal(t, 1323, u.Port)
+	rs, err := DB.ListUpstreamRoutes(acmeHTTP01Name)
+	require.NoError(t, err)
+	assert.Len(t, rs, 1)
+
+	// A route of the same name routing elsewhere is not taken over
+	require.NoError(t, DB.CreateRoute(&store.Route{Service: "other", Name: acmeHTTP01Name, Prefix: "/"}))
+	assert.Error(t, acmeSetupChallengeRoute("other", log))
+}
+
+func TestACMESaveSecret(t *testing.T) {
+	newTestAPI(t)
+
+	// Only secrets saved for a service are renewed
+	require.NoError(t, DB.CreateSecret(&store.Secret{Name: "acme-user"}))
+	require.NoError(t, acmeSaveSecret("acme-svc", "svc", "key", "cert"))
+	require.NoError(t, acmeSaveSecret(acmeAccountSecret, "", "key", ""))
+
+	for name, want := range map[string]string{"acme-user": "", "acme-svc": "svc", acmeAccountSecret: ""} {
+		s, err := DB.GetSecret(name)
+		require.NoError(t, err)
+		assert.Equal(t, want, s.ACMEService, name)
+	}
+}
+
+// TestACMEObtainCertificatePebble runs an order against a pebble ACME test server,
+//
+//   PEBBLE_VA_ALWAYS_VALID=1 pebble -config test/config/pebble-config.json
+//   PEBBLE_DIRECTORY_URL=https://localhost:14000/dir PEBBLE_CA_BUNDLE=test/certs/pebble.minica.pem go test ./webhttp -run Pebble
+//
+// Without PEBBLE_VA_ALWAYS_VALID pebble validates HTTP-01 challenges on port 5002 of
+// PEBBLE_FQDN, which is answered by a challenge server started by the test.
+func TestACMEObtainCertificatePebble(t *testing.T) {
+	directory := os.Getenv("PEBBLE_DIRECTORY_URL")
+	if directory == "" {
+		t.Skip("PEBBLE_DIRECTORY_URL not set")
+	}
+	fqdn := os.Getenv("PEBBLE_FQDN")
+	if fqdn == "" {
+		fqdn = "enroute.example.com"
+	}
+
+	e := echo.New()
+	e.HideBanner = true
+	Add_acme_routes(e)
+	l, err := net.Listen("tcp", ":5002")
+	if err == nil {
+		srv := &http.Server{Handler: e}
+		go srv.Serve(l)
+		defer srv.Close()
+	}
+
+	ACME_CA_BUNDLE = os.Getenv("PEBBLE_CA_BUNDLE")
+	defer func() { ACME_CA_BUNDLE = "" }()
+	hc, err := acmeHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &acme.Client{Key: key, HTTPClient: hc, DirectoryURL: directory}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := client.Register(ctx, &acme.Account{}, acme.AcceptTOS); err != nil {
+		t.Fatal(err)
+	}
+
+	keyPEM, certPEM, err := acmeObtainCertificate(ctx, client, &challenges, fqdn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = decodeECKey(keyPEM)
+	assert.NoError(t, err)
+
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		t.Fatal("no certificate issued")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{fqdn}, cert.DNSNames)
+	assert.False(t, acmeNeedsRenewal(certPEM, time.Now(), 24*time.Hour))
+}
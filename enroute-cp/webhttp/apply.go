@@ -0,0 +1,753 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package webhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/saarasio/enroute/enroute-dp/saarasconfig"
+	"github.com/sirupsen/logrus"
+)
+
+// The configuration of a proxy can be applied as a whole, in the shape
+// GET /proxy/dump returns (config.EnrouteConfig in enroutectl). Objects are
+// matched by name. Fields missing from the document keep their current
+// values, while the associations of the proxy, and of the services and
+// routes in the document, become exactly those listed, so a missing list
+// is an empty one.
+
+// applyChange is a change made by applying a configuration
+type applyChange struct {
+	Op     string `json:"op"`
+	Object string `json:"object"`
+	Name   string `json:"name"`
+}
+
+// applyResult is the response to PUT /proxy/:proxy_name/config
+type applyResult struct {
+	DryRun     bool          `json:"dry_run"`
+	Changes    []applyChange `json:"changes"`
+	Diff       string        `json:"diff"`
+	RevisionID int64         `json:"revision_id,omitempty"`
+}
+
+// applyDoc is an object of the configuration applied
+type applyDoc map[string]interface{}
+
+// applyPlan holds the configuration of a proxy planned from a document,
+// along with the changes it makes and the problems found in the document.
+type applyPlan struct {
+	log     *logrus.Entry
+	errs    []*APIError
+	dbErr   error
+	changes []applyChange
+
+	// Objects planned so far, so that objects listed more than once
+	// are only changed once
+	upstreams     map[string]*store.Upstream
+	secrets       map[string]*store.Secret
+	filters       map[string]*store.Filter
+	globalconfigs map[string]*store.GlobalConfig
+	services      map[string]bool
+}
+
+func newApplyPlan(log *logrus.Entry) *applyPlan {
+	return &applyPlan{
+		log:           log,
+		changes:       []applyChange{},
+		upstreams:     map[string]*store.Upstream{},
+		secrets:       map[string]*store.Secret{},
+		filters:       map[string]*store.Filter{},
+		globalconfigs: map[string]*store.GlobalConfig{},
+		services:      map[string]bool{},
+	}
+}
+
+// fail records a problem with the object at path of the document.
+func (p *applyPlan) fail(path string, format string, a ...interface{}) {
+	p.errs = append(p.errs, invalid("", "", path, "%s: %s", path, fmt.Sprintf(format, a...)))
+}
+
+// invalid records e, returned by the validation of the object at path.
+func (p *applyPlan) invalid(path string, e *APIError) {
+	e.Message = path + ": " + e.Message
+	if e.Field != "" {
+		e.Field = path + "." + e.Field
+	}
+	p.errs = append(p.errs, e)
+}
+
+func (p *applyPlan) change(op, object, name string) {
+	p.changes = append(p.changes, applyChange{Op: op, Object: object, Name: name})
+}
+
+// get returns false if err is not nil, keeping err unless the object
+// looked up is only missing.
+func (p *applyPlan) get(err error) bool {
+	if err != nil && !isNotFound(err) && p.dbErr == nil {
+		p.dbErr = err
+	}
+	return err == nil
+}
+
+// object records the creation of the object planned, or its update if it
+// differs from cur in fields.
+func (p *applyPlan) object(object, name string, exists bool, cur, planned interface{}, fields string) {
+	if !exists {
+		p.change("create", object, name)
+		return
+	}
+	a, err := dbPick(cur, fields)
+	if err != nil {
+		p.get(err)
+		return
+	}
+	b, err := dbPick(planned, fields)
+	if err != nil {
+		p.get(err)
+		return
+	}
+	if !reflect.DeepEqual(a, b) {
+		p.change("update", object, name)
+	}
+}
+
+// associations records the changes turning the names associated with
+// owner from cur into planned.
+func (p *applyPlan) associations(object, owner string, cur, planned []string) {
+	in := map[string]bool{}
+	for _, name := range cur {
+		in[name] = true
+	}
+	for _, name := range planned {
+		if !in[name] {
+			p.change("associate", object, owner+"/"+name)
+		}
+		delete(in, name)
+	}
+	for _, name := range cur {
+		if in[name] {
+			p.change("disassociate", object, owner+"/"+name)
+		}
+	}
+}
+
+// list returns the objects in the list key of d.
+func (p *applyPlan) list(d applyDoc, key, path string) []applyDoc {
+	v, ok := d[key]
+	if !ok || v == nil {
+		return nil
+	}
+	l, ok := v.([]interface{})
+	if !ok {
+		p.fail(path, "%s must be a list", key)
+		return nil
+	}
+	ds := make([]applyDoc, 0, len(l))
+	for i, e := range l {
+		m, ok := e.(map[string]interface{})
+		if !ok {
+			p.fail(fmt.Sprintf("%s.%s[%d]", path, key, i), "must be an object")
+			continue
+		}
+		ds = append(ds, m)
+	}
+	return ds
+}
+
+// child returns the object in key of d, like the service of a
+// proxy_services entry.
+func (p *applyPlan) child(d applyDoc, key, path string) (applyDoc, bool) {
+	m, ok := d[key].(map[string]interface{})
+	if !ok {
+		p.fail(path, "missing %s", key)
+	}
+	return m, ok
+}
+
+func (p *applyPlan) name(d applyDoc, key, path string) (string, bool) {
+	name, ok := d[key].(string)
+	if !ok || name == "" {
+		p.fail(path, "missing %s", key)
+		return "", false
+	}
+	return name, true
+}
+
+// mergeObject sets out to cur with the fields in d, leaving out ids and
+// timestamps which only the store sets.
+func mergeObject(cur interface{}, d applyDoc, out interface{}) error {
+	var m map[string]interface{}
+	b, err := json.Marshal(cur)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	if m == nil {
+		m = map[string]interface{}{}
+	}
+	for k, v := range d {
+		if strings.HasSuffix(k, "_id") || k == "create_ts" || k == "update_ts" {
+			continue
+		}
+		m[k] = v
+	}
+	if b, err = json.Marshal(m); err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+func (p *applyPlan) upstream(d applyDoc, path string) *store.Upstream {
+	name, ok := p.name(d, "upstream_name", path)
+	if !ok {
+		return nil
+	}
+
+	// Copy d with the numbers sent as strings converted, where an empty
+	// string leaves the current value
+	doc := applyDoc{}
+	for k, v := range d {
+		doc[k] = v
+	}
+	for _, k := range upstreamIntFields {
+		s, ok := doc[k].(string)
+		if !ok {
+			continue
+		}
+		if s == "" {
+			delete(doc, k)
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			p.fail(path, "invalid %s %q", k, s)
+			return nil
+		}
+		doc[k] = n
+	}
+
+	cur, err := DB.GetUpstream(name)
+	exists := p.get(err)
+	u := new(store.Upstream)
+	if err := mergeObject(cur, doc, u); err != nil {
+		p.fail(path, "%v", err)
+		return nil
+	}
+	if e := validate_upstream(upstreamOf(u)); e != nil {
+		p.invalid(path, e)
+		return nil
+	}
+
+	if prev, ok := p.upstreams[name]; ok {
+		if !p.same(prev, u, revisionUpstreamFields) {
+			p.fail(path, "conflicting definitions of upstream %s", name)
+		}
+		return prev
+	}
+	p.upstreams[name] = u
+	p.object("upstream", name, exists, cur, u, revisionUpstreamFields)
+	return u
+}
+
+func (p *applyPlan) secret(d applyDoc, path string) *store.Secret {
+	name, ok := p.name(d, "secret_name", path)
+	if !ok {
+		return nil
+	}
+
+	cur, err := DB.GetSecret(name)
+	exists := p.get(err)
+	s := new(store.Secret)
+	if err := mergeObject(cur, d, s); err != nil {
+		p.fail(path, "%v", err)
+		return nil
+	}
+	if s.TLSConfig != "" {
+		if _, err := saarasconfig.UnmarshalTLSConfig(s.TLSConfig); err != nil {
+			p.fail(path, "Invalid TLS config: %v", err)
+			return nil
+		}
+	}
+
+	if prev, ok := p.secrets[name]; ok {
+		if !p.same(prev, s, revisionSecretFields) {
+			p.fail(path, "conflicting definitions of secret %s", name)
+		}
+		return prev
+	}
+	p.secrets[name] = s
+	p.object("secret", name, exists, cur, s, revisionSecretFields)
+	return s
+}
+
+func (p *applyPlan) filter(d applyDoc, path string) *store.Filter {
+	name, ok := p.name(d, "filter_name", path)
+	if !ok {
+		return nil
+	}
+
+	cur, err := DB.GetFilter(name)
+	exists := p.get(err)
+	f := new(store.Filter)
+	if err := mergeObject(cur, d, f); err != nil {
+		p.fail(path, "%v", err)
+		return nil
+	}
+	if !isFilterTypeValid(f.Type) {
+		p.fail(path, "Filter type not supported %s", f.Type)
+		return nil
+	}
+	if _, ok := d["filter_config"]; ok || !exists {
+		args := map[string]interface{}{}
+		setConfigJson(p.log, f.Type, f.Config, &args)
+		f.ConfigJSON = rawJSON(args["config_json"])
+	}
+
+	if prev, ok := p.filters[name]; ok {
+		if !p.same(prev, f, revisionFilterFields) {
+			p.fail(path, "conflicting definitions of filter %s", name)
+		}
+		return prev
+	}
+	p.filters[name] = f
+	p.object("filter", name, exists, cur, f, revisionFilterFields)
+	return f
+}
+
+func (p *applyPlan) globalConfig(d applyDoc, path string) *store.GlobalConfig {
+	name, ok := p.name(d, "globalconfig_name", path)
+	if !ok {
+		return nil
+	}
+
+	cur, err := DB.GetGlobalConfig(name)
+	exists := p.get(err)
+	gc := new(store.GlobalConfig)
+	if err := mergeObject(cur, d, gc); err != nil {
+		p.fail(path, "%v", err)
+		return nil
+	}
+	if !isGlobalConfigTypeValid(gc.Type) {
+		p.fail(path, "Globalconfig type not supported %s", gc.Type)
+		return nil
+	}
+	if _, ok := d["config"]; ok || !exists {
+		config_json, err := globalConfigJSON(p.log, gc.Type, gc.Config)
+		if err != nil {
+			p.fail(path, "Invalid TLS config: %v", err)
+			return nil
+		}
+		gc.ConfigJSON = rawJSON(config_json)
+	}
+
+	if prev, ok := p.globalconfigs[name]; ok {
+		if !p.same(prev, gc, revisionGlobalConfigFields) {
+			p.fail(path, "conflicting definitions of globalconfig %s", name)
+		}
+		return prev
+	}
+	p.globalconfigs[name] = gc
+	p.object("globalconfig", name, exists, cur, gc, revisionGlobalConfigFields)
+	return gc
+}
+
+// same returns true if a and b have the same fields.
+func (p *applyPlan) same(a, b interface{}, fields string) bool {
+	pa, err := dbPick(a, fields)
+	if err != nil {
+		p.get(err)
+		return true
+	}
+	pb, err := dbPick(b, fields)
+	if err != nil {
+		p.get(err)
+		return true
+	}
+	return reflect.DeepEqual(pa, pb)
+}
+
+func (p *applyPlan) route(service string, cur *store.RouteDetail, d applyDoc, path string) *store.RouteDetail {
+	name, _ := d["route_name"].(string)
+	rname := service + "/" + name
+
+	rd := &store.RouteDetail{
+		Upstreams: []store.RouteUpstream{},
+		Filters:   []store.RouteFilter{},
+	}
+	var curRoute *store.Route
+	if cur != nil {
+		curRoute = &cur.Route
+	}
+	if err := mergeObject(curRoute, d, &rd.Route); err != nil {
+		p.fail(path, "%v", err)
+		return nil
+	}
+	rd.Service = service
+	r := &Route{Route_name: rd.Name, Route_prefix: rd.Prefix, Route_config: rd.Config}
+	if e := validate_service_route(service, r); e != nil {
+		p.invalid(path, e)
+		return nil
+	}
+	if _, ok := d["route_config"]; ok || (cur == nil && d["config_json"] == nil) {
+		args := map[string]interface{}{}
+		setRouteConfigJson(p.log, rd.Config, &args)
+		rd.ConfigJSON = rawJSON(args["config_json"])
+	}
+	p.object("route", rname, cur != nil, curRoute, &rd.Route, revisionRouteFields)
+
+	var curNames, names []string
+	if cur != nil {
+		for _, ru := range cur.Upstreams {
+			curNames = append(curNames, ru.Upstream.Name)
+		}
+	}
+	seen := map[string]bool{}
+	for i, e := range p.list(d, "route_upstreams", path) {
+		epath := fmt.Sprintf("%s.route_upstreams[%d]", path, i)
+		ud, ok := p.child(e, "upstream", epath)
+		if !ok {
+			continue
+		}
+		u := p.upstream(ud, epath+".upstream")
+		if u == nil || seen[u.Name] {
+			continue
+		}
+		seen[u.Name] = true
+		names = append(names, u.Name)
+		rd.Upstreams = append(rd.Upstreams, store.RouteUpstream{Upstream: *u})
+	}
+	p.associations("route_upstream", rname, curNames, names)
+
+	curNames, names = nil, nil
+	if cur != nil {
+		for _, rf := range cur.Filters {
+			curNames = append(curNames, rf.Filter.Name)
+		}
+	}
+	seen = map[string]bool{}
+	for i, e := range p.list(d, "route_filters", path) {
+		epath := fmt.Sprintf("%s.route_filters[%d]", path, i)
+		fd, ok := p.child(e, "filter", epath)
+		if !ok {
+			continue
+		}
+		f := p.filter(fd, epath+".filter")
+		if f == nil || seen[f.Name] {
+			continue
+		}
+		seen[f.Name] = true
+		names = append(names, f.Name)
+		rd.Filters = append(rd.Filters, store.RouteFilter{Filter: *f})
+	}
+	p.associations("route_filter", rname, curNames, names)
+
+	sort.Slice(rd.Upstreams, func(i, j int) bool { return rd.Upstreams[i].Upstream.Name < rd.Upstreams[j].Upstream.Name })
+	sort.Slice(rd.Filters, func(i, j int) bool { return rd.Filters[i].Filter.Name < rd.Filters[j].Filter.Name })
+	return rd
+}
+
+func (p *applyPlan) service(d applyDoc, path string) *store.ServiceDetail {
+	name, ok := p.name(d, "service_name", path)
+	if !ok {
+		return nil
+	}
+	if p.services[name] {
+		p.fail(path, "service %s is listed more than once", name)
+		return nil
+	}
+	p.services[name] = true
+
+	cur, err := DB.ServiceDetail(name)
+	exists := p.get(err)
+	var curService *store.Service
+	if exists {
+		curService = &cur.Service
+	}
+
+	sd := &store.ServiceDetail{
+		Routes:  []store.RouteDetail{},
+		Secrets: []store.ServiceSecret{},
+		Filters: []store.ServiceFilter{},
+	}
+	if err := mergeObject(curService, d, &sd.Service); err != nil {
+		p.fail(path, "%v", err)
+		return nil
+	}
+	s := &Service{Service_name: sd.Name, Fqdn: sd.Fqdn}
+	if e := validate_service(s); e != nil {
+		p.invalid(path, e)
+		return nil
+	}
+	p.object("service", name, exists, curService, &sd.Service, revisionServiceFields)
+
+	curRoutes := map[string]*store.RouteDetail{}
+	if exists {
+		for i := range cur.Routes {
+			curRoutes[cur.Routes[i].Name] = &cur.Routes[i]
+		}
+	}
+	routes := map[string]bool{}
+	for i, rd := range p.list(d, "routes", path) {
+		rpath := fmt.Sprintf("%s.routes[%d]", path, i)
+		rname, ok := p.name(rd, "route_name", rpath)
+		if !ok {
+			continue
+		}
+		if routes[rname] {
+			p.fail(rpath, "route %s is listed more than once", rname)
+			continue
+		}
+		routes[rname] = true
+		if r := p.route(name, curRoutes[rname], rd, rpath); r != nil {
+			sd.Routes = append(sd.Routes, *r)
+		}
+	}
+	if exists {
+		for _, r := range cur.Routes {
+			if !routes[r.Name] {
+				p.change("delete", "route", name+"/"+r.Name)
+			}
+		}
+	}
+
+	var curNames, names []string
+	if exists {
+		for _, ss := range cur.Secrets {
+			curNames = append(curNames, ss.Secret.Name)
+		}
+	}
+	seen := map[string]bool{}
+	for i, e := range p.list(d, "service_secrets", path) {
+		epath := fmt.Sprintf("%s.service_secrets[%d]", path, i)
+		sec, ok := p.child(e, "secret", epath)
+		if !ok {
+			continue
+		}
+		s := p.secret(sec, epath+".secret")
+		if s == nil || seen[s.Name] {
+			continue
+		}
+		seen[s.Name] = true
+		names = append(names, s.Name)
+		sd.Secrets = append(sd.Secrets, store.ServiceSecret{Secret: *s})
+	}
+	p.associations("service_secret", name, curNames, names)
+
+	curNames, names = nil, nil
+	if exists {
+		for _, sf := range cur.Filters {
+			curNames = append(curNames, sf.Filter.Name)
+		}
+	}
+	seen = map[string]bool{}
+	for i, e := range p.list(d, "service_filters", path) {
+		epath := fmt.Sprintf("%s.service_filters[%d]", path, i)
+		fd, ok := p.child(e, "filter", epath)
+		if !ok {
+			continue
+		}
+		f := p.filter(fd, epath+".filter")
+		if f == nil || seen[f.Name] {
+			continue
+		}
+		seen[f.Name] = true
+		names = append(names, f.Name)
+		sd.Filters = append(sd.Filters, store.ServiceFilter{Filter: *f})
+	}
+	p.associations("service_filter", name, curNames, names)
+
+	sort.Slice(sd.Routes, func(i, j int) bool { return sd.Routes[i].Name < sd.Routes[j].Name })
+	sort.Slice(sd.Secrets, func(i, j int) bool { return sd.Secrets[i].Secret.Name < sd.Secrets[j].Secret.Name })
+	sort.Slice(sd.Filters, func(i, j int) bool { return sd.Filters[i].Filter.Name < sd.Filters[j].Filter.Name })
+	return sd
+}
+
+// proxy returns the configuration of the proxy cur planned from d. cur
+// is nil if the proxy does not exist.
+func (p *applyPlan) proxy(name string, cur *store.ProxyDetail, d applyDoc) *store.ProxyDetail {
+	pd := &store.ProxyDetail{
+		Proxy:         store.Proxy{Name: name},
+		GlobalConfigs: []store.ProxyGlobalConfig{},
+		Services:      []store.ProxyService{},
+	}
+	if cur == nil {
+		p.change("create", "proxy", name)
+	} else {
+		pd.Proxy = cur.Proxy
+	}
+
+	var curNames, names []string
+	if cur != nil {
+		for _, pg := range cur.GlobalConfigs {
+			curNames = append(curNames, pg.GlobalConfig.Name)
+		}
+	}
+	seen := map[string]bool{}
+	for i, e := range p.list(d, "proxy_globalconfigs", "proxy") {
+		epath := fmt.Sprintf("proxy_globalconfigs[%d]", i)
+		gd, ok := p.child(e, "globalconfig", epath)
+		if !ok {
+			continue
+		}
+		gc := p.globalConfig(gd, epath+".globalconfig")
+		if gc == nil || seen[gc.Name] {
+			continue
+		}
+		seen[gc.Name] = true
+		names = append(names, gc.Name)
+		pd.GlobalConfigs = append(pd.GlobalConfigs, store.ProxyGlobalConfig{GlobalConfig: *gc})
+	}
+	p.associations("proxy_globalconfig", name, curNames, names)
+
+	curNames, names = nil, nil
+	if cur != nil {
+		for _, ps := range cur.Services {
+			curNames = append(curNames, ps.Service.Name)
+		}
+	}
+	for i, e := range p.list(d, "proxy_services", "proxy") {
+		epath := fmt.Sprintf("proxy_services[%d]", i)
+		sd, ok := p.child(e, "service", epath)
+		if !ok {
+			continue
+		}
+		s := p.service(sd, epath+".service")
+		if s == nil {
+			continue
+		}
+		names = append(names, s.Name)
+		pd.Services = append(pd.Services, store.ProxyService{Service: *s})
+	}
+	p.associations("proxy_service", name, curNames, names)
+
+	sort.Slice(pd.GlobalConfigs, func(i, j int) bool {
+		return pd.GlobalConfigs[i].GlobalConfig.Name < pd.GlobalConfigs[j].GlobalConfig.Name
+	})
+	sort.Slice(pd.Services, func(i, j int) bool { return pd.Services[i].Service.Name < pd.Services[j].Service.Name })
+	return pd
+}
+
+// proxyConfigDoc returns the configuration of proxy_name in the document
+// read from r.
+func proxyConfigDoc(r io.Reader, proxy_name string) (applyDoc, error) {
+	var doc struct {
+		Data struct {
+			Proxies []applyDoc `json:"saaras_db_proxy"`
+		} `json:"data"`
+	}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("Invalid configuration: %v", err)
+	}
+	for _, d := range doc.Data.Proxies {
+		if d["proxy_name"] == proxy_name {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("No configuration for proxy %s in data.saaras_db_proxy", proxy_name)
+}
+
+// @Summary Apply the configuration of a proxy
+// @Description Set the configuration of a proxy, in the shape returned by GET /proxy/dump, in one transaction. Objects are matched by name, fields left out keep their values, and the associations become those listed. With dryRun set, the changes are returned without being made.
+// @Tags proxy, operational-verbs
+// @Accept  json
+// @Produce  json
+// @Param proxy_name path string true "Name of proxy"
+// @Param dryRun query bool false "Only return the changes to be made"
+// @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Router /proxy/{proxy_name}/config [put]
+// @Security ApiKeyAuth
+func PUT_Proxy_Config(c echo.Context) error {
+	log2 := logrus.StandardLogger()
+	log := log2.WithField("context", "web-http")
+
+	proxy_name := c.Param("proxy_name")
+
+	dryRun := false
+	if v := c.QueryParam("dryRun"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return badRequest(c, invalid("", "", "dryRun", "Invalid dryRun value %s", v))
+		}
+		dryRun = b
+	}
+
+	d, err := proxyConfigDoc(c.Request().Body, proxy_name)
+	if err != nil {
+		return badRequest(c, &APIError{Code: ErrorBadRequest, Message: err.Error()})
+	}
+
+	cur, err := DB.ProxyDetail(proxy_name)
+	if err != nil && !isNotFound(err) {
+		return dbError(c, err)
+	}
+	if isNotFound(err) {
+		cur = nil
+	}
+
+	p := newApplyPlan(log)
+	planned := p.proxy(proxy_name, cur, d)
+	if p.dbErr != nil {
+		return dbError(c, p.dbErr)
+	}
+	if len(p.errs) > 0 {
+		return badRequest(c, p.errs...)
+	}
+
+	msg, err := tokenAllowsConfig(c, planned)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if msg != "" {
+		return apiErrors(c, http.StatusForbidden, &APIError{Code: ErrorForbidden, Message: msg})
+	}
+
+	var curConfig json.RawMessage
+	if cur != nil {
+		if curConfig, err = revisionConfig(cur); err != nil {
+			return dbError(c, err)
+		}
+	}
+	config, err := revisionConfig(planned)
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	res := applyResult{
+		DryRun:  dryRun,
+		Changes: p.changes,
+		Diff:    unifiedDiff("current", "applied", revisionText(curConfig), revisionText(config)),
+	}
+	if dryRun || len(res.Changes) == 0 {
+		return c.JSON(http.StatusOK, res)
+	}
+
+	r := &store.Revision{
+		Proxy:  proxy_name,
+		Author: requestAuthor(c),
+		Config: config,
+		Diff:   res.Diff,
+	}
+	if err := DB.RestoreProxy(planned, r); err != nil {
+		return dbError(c, err)
+	}
+	res.RevisionID = r.ID
+
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,161 @@
+package webhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPUTProxyConfig(t *testing.T) {
+	api := newTestAPI(t, RecordRevisions)
+	Add_proxy_routes(api.Echo)
+	Add_service_routes(api.Echo)
+	Add_upstream_routes(api.Echo)
+	Add_revision_routes(api.Echo)
+	do := api.do
+
+	apply := func(path, body string) applyResult {
+		rec := do(http.MethodPut, path, body)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var res applyResult
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+		return res
+	}
+
+	config := `{"data": {"saaras_db_proxy": [{
+	  "proxy_name": "gw",
+	  "proxy_services": [{"service": {
+	    "service_name": "svc",
+	    "fqdn": "example.com",
+	    "routes": [{
+	      "route_name": "r",
+	      "route_prefix": "/",
+	      "route_upstreams": [{"upstream": {
+	        "upstream_name": "u",
+	        "upstream_ip": "10.0.0.1",
+	        "upstream_port": "80",
+	        "upstream_hc_path": "/health"
+	      }}]
+	    }]
+	  }}]
+	}]}}`
+
+	// A dry run changes nothing
+	res := apply("/proxy/gw/config?dryRun=true", config)
+	assert.True(t, res.DryRun)
+	assert.Contains(t, res.Changes, applyChange{Op: "create", Object: "proxy", Name: "gw"})
+	assert.Contains(t, res.Changes, applyChange{Op: "create", Object: "upstream", Name: "u"})
+	assert.Contains(t, res.Changes, applyChange{Op: "associate", Object: "route_upstream", Name: "svc/r/u"})
+	assert.Regexp(t, `\n\+\s+"upstream_ip": "10.0.0.1"`, res.Diff)
+	_, err := DB.GetProxy("gw")
+	assert.True(t, isNotFound(err))
+
+	res = apply("/proxy/gw/config", config)
+	assert.False(t, res.DryRun)
+	assert.NotZero(t, res.RevisionID)
+	u, err := DB.GetUpstream("u")
+	require.NoError(t, err)
+	assert.Equal(t, 80, u.Port)
+	assert.Equal(t, "/health", u.HCPath)
+
+	// Applying a dump of the proxy changes nothing
+	rec := do(http.MethodGet, "/proxy/dump/gw", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	res = apply("/proxy/gw/config", rec.Body.String())
+	assert.Empty(t, res.Changes)
+	assert.Empty(t, res.Diff)
+	assert.Zero(t, res.RevisionID)
+
+	// Fields left out keep their values, lists left out are emptied
+	res = apply("/proxy/gw/config", `{"data": {"saaras_db_proxy": [{
+	  "proxy_name": "gw",
+	  "proxy_services": [{"service": {
+	    "service_name": "svc",
+	    "routes": [{
+	      "route_name": "r2",
+	      "route_prefix": "/v2",
+	      "route_upstreams": [{"upstream": {"upstream_name": "u", "upstream_ip": "10.0.0.2"}}]
+	    }]
+	  }}]
+	}]}}`)
+	assert.ElementsMatch(t, []applyChange{
+		{Op: "update", Object: "upstream", Name: "u"},
+		{Op: "create", Object: "route", Name: "svc/r2"},
+		{Op: "associate", Object: "route_upstream", Name: "svc/r2/u"},
+		{Op: "delete", Object: "route", Name: "svc/r"},
+	}, res.Changes)
+	u, err = DB.GetUpstream("u")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", u.IP)
+	assert.Equal(t, "/health", u.HCPath)
+	s, err := DB.GetService("svc")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", s.Fqdn)
+	_, err = DB.GetRoute("svc", "r")
+	assert.True(t, isNotFound(err))
+
+	// The revision saved is the one the middleware would save
+	rs, err := DB.ListRevisions("gw")
+	require.NoError(t, err)
+	require.Len(t, rs, 2)
+	assert.Equal(t, res.RevisionID, rs[1].ID)
+
+	tests := map[string]struct {
+		body string
+		want string
+	}{
+		"no proxy": {
+			body: `{"data": {"saaras_db_proxy": [{"proxy_name": "other"}]}}`,
+			want: "No configuration for proxy gw",
+		},
+		"bad json": {
+			body: `{"data": `,
+			want: "Invalid configuration",
+		},
+		"missing name": {
+			body: `{"data": {"saaras_db_proxy": [{"proxy_name": "gw", "proxy_services": [{"service": {"fqdn": "x"}}]}]}}`,
+			want: "proxy_services[0].service: missing service_name",
+		},
+		"bad port": {
+			body: `{"data": {"saaras_db_proxy": [{"proxy_name": "gw", "proxy_services": [{"service": {
+			  "service_name": "svc",
+			  "routes": [{"route_name": "r", "route_prefix": "/",
+			    "route_upstreams": [{"upstream": {"upstream_name": "u", "upstream_port": "x"}}]}]
+			}}]}]}}`,
+			want: "proxy_services[0].service.routes[0].route_upstreams[0].upstream: invalid upstream_port",
+		},
+		"conflicting upstreams": {
+			body: `{"data": {"saaras_db_proxy": [{"proxy_name": "gw", "proxy_services": [{"service": {
+			  "service_name": "svc",
+			  "routes": [
+			    {"route_name": "a", "route_prefix": "/a", "route_upstreams": [{"upstream": {"upstream_name": "u", "upstream_port": 80}}]},
+			    {"route_name": "b", "route_prefix": "/b", "route_upstreams": [{"upstream": {"upstream_name": "u", "upstream_port": 81}}]}
+			  ]
+			}}]}]}}`,
+			want: "conflicting definitions of upstream u",
+		},
+		"bad filter type": {
+			body: `{"data": {"saaras_db_proxy": [{"proxy_name": "gw", "proxy_services": [{"service": {
+			  "service_name": "svc",
+			  "service_filters": [{"filter": {"filter_name": "f", "filter_type": "nope"}}]
+			}}]}]}}`,
+			want: "Filter type not supported nope",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := do(http.MethodPut, "/proxy/gw/config", tc.body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.want)
+		})
+	}
+
+	// Nothing was changed by the invalid configurations
+	rs, err = DB.ListRevisions("gw")
+	require.NoError(t, err)
+	assert.Len(t, rs, 2)
+}
@@ -0,0 +1,295 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package webhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/sirupsen/logrus"
+)
+
+// Every request changing the configuration is audited: who made it, what
+// it changed, the object changed before and after it, with secret keys
+// replaced by their digest, and its result. Records are kept in DB, and
+// also written as json lines to the audit file if one is set. Successful
+// changes are posted to webhooks as well.
+
+// auditFile is where audit records are written, if set
+var auditFile struct {
+	sync.Mutex
+	f *os.File
+}
+
+// SetAuditFile appends audit records to the file at path as well.
+func SetAuditFile(path string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	auditFile.Lock()
+	defer auditFile.Unlock()
+	if auditFile.f != nil {
+		auditFile.f.Close()
+	}
+	auditFile.f = f
+	return nil
+}
+
+func writeAuditFile(r *store.AuditRecord) error {
+	auditFile.Lock()
+	defer auditFile.Unlock()
+	if auditFile.f == nil {
+		return nil
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = auditFile.f.Write(append(b, '\n'))
+	return err
+}
+
+// auditKind returns the kind of object named by the path parameter or
+// body field key, like service for service_name or service_name_dst.
+func auditKind(key string) string {
+	switch {
+	case key == "revision_id":
+		return "revision"
+	case strings.HasSuffix(key, "_name"):
+		return strings.TrimSuffix(key, "_name")
+	case strings.HasSuffix(key, "_name_src"), strings.HasSuffix(key, "_name_dst"):
+		return key[:len(key)-len("_name_src")]
+	}
+	return ""
+}
+
+// auditBody returns the fields of the body of req, which is left to be
+// read again.
+func auditBody(req *http.Request) url.Values {
+	if req.Body == nil {
+		return nil
+	}
+	b, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	if err != nil || len(b) == 0 {
+		return nil
+	}
+
+	if strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		var m map[string]interface{}
+		if json.Unmarshal(b, &m) != nil {
+			return nil
+		}
+		vs := url.Values{}
+		for k, v := range m {
+			if s, ok := v.(string); ok {
+				vs.Set(k, s)
+			}
+		}
+		return vs
+	}
+	vs, _ := url.ParseQuery(string(b))
+	return vs
+}
+
+// auditObjects returns the names of the objects c changes by kind. They
+// are named by the path, or by the body for objects being created.
+func auditObjects(c echo.Context) map[string]string {
+	ids := map[string]string{}
+	set := func(key, value string) {
+		kind := auditKind(key)
+		if kind == "" || value == "" {
+			return
+		}
+		// Copies change their destination
+		if _, ok := ids[kind]; ok && !strings.HasSuffix(key, "_dst") {
+			return
+		}
+		ids[kind] = value
+	}
+
+	for _, p := range c.ParamNames() {
+		set(p, c.Param(p))
+	}
+	body := auditBody(c.Request())
+	for k := range body {
+		set(k, body.Get(k))
+	}
+	// Proxies are created with their name only
+	if c.Path() == "/proxy" {
+		set("proxy_name", body.Get("name"))
+	}
+	return ids
+}
+
+// auditObject returns the kind and name of the object among ids whose
+// snapshot is taken. Associations are part of the object holding them,
+// like the upstreams of a route.
+func auditObject(ids map[string]string) (string, string) {
+	switch {
+	case ids["route"] != "" && ids["service"] != "":
+		return "route", ids["service"] + "/" + ids["route"]
+	case ids["service"] != "":
+		return "service", ids["service"]
+	}
+	for _, kind := range []string{"proxy", "upstream", "secret", "filter", "globalconfig", "token"} {
+		if ids[kind] != "" {
+			return kind, ids[kind]
+		}
+	}
+	return "", ""
+}
+
+// auditSnapshot returns the object among ids which c changes, in json
+// with secret keys redacted, or nil if it does not exist.
+func auditSnapshot(ids map[string]string) json.RawMessage {
+	var v interface{}
+	var err error
+
+	kind, name := auditObject(ids)
+	switch kind {
+	case "route":
+		var sd *store.ServiceDetail
+		if sd, err = DB.ServiceDetail(ids["service"]); err == nil {
+			for _, rd := range sd.Routes {
+				if rd.Name == ids["route"] {
+					v = rd
+				}
+			}
+		}
+	case "service":
+		v, err = DB.ServiceDetail(name)
+	case "proxy":
+		v, err = DB.ProxyDetail(name)
+	case "upstream":
+		v, err = DB.GetUpstream(name)
+	case "secret":
+		v, err = DB.GetSecret(name)
+	case "filter":
+		v, err = DB.GetFilter(name)
+	case "globalconfig":
+		v, err = DB.GetGlobalConfig(name)
+	case "token":
+		var t *store.Token
+		if t, err = DB.GetToken(name); err == nil {
+			v, err = dbPick(t, tokenFields)
+		}
+	}
+	if err != nil || v == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil
+	}
+	return rawJSON(redactKeys(m))
+}
+
+// Audit is a middleware saving an audit record of every request which
+// may change the configuration.
+func Audit(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		switch c.Request().Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return next(c)
+		}
+		if c.Path() == GraphQLPath {
+			return next(c)
+		}
+
+		ids := auditObjects(c)
+		r := &store.AuditRecord{
+			IP:       c.RealIP(),
+			Method:   c.Request().Method,
+			Endpoint: c.Path(),
+			Path:     c.Request().URL.Path,
+			Objects:  []string{},
+			Before:   auditSnapshot(ids),
+		}
+		for kind, name := range ids {
+			if kind == "route" && ids["service"] != "" {
+				name = ids["service"] + "/" + name
+			}
+			r.Objects = append(r.Objects, kind+":"+name)
+		}
+		sort.Strings(r.Objects)
+
+		err := next(c)
+
+		if t := requestToken(c); t != nil {
+			r.Token = t.Name
+		}
+		r.After = auditSnapshot(ids)
+		r.Code = c.Response().Status
+		if err != nil {
+			r.Code = http.StatusInternalServerError
+			if he, ok := err.(*echo.HTTPError); ok {
+				r.Code = he.Code
+			}
+		}
+
+		log := logrus.StandardLogger().WithField("context", "web-http")
+		if err := DB.CreateAuditRecord(r); err != nil {
+			log.Errorf("Error when saving audit record [%v]\n", err)
+		}
+		if err := writeAuditFile(r); err != nil {
+			log.Errorf("Error when writing audit record [%v]\n", err)
+		}
+
+		if r.Code < http.StatusMultipleChoices {
+			kind, name := auditObject(ids)
+			sendWebhooks(newWebhookEvent(kind, name, r.Objects, r.Before, r.After, revisedProxies(c), requestAuthor(c)))
+		}
+		return err
+	}
+}
+
+// @Summary List audit records
+// @Description List the changes requested through the API, oldest first
+// @Tags audit
+// @Accept  json
+// @Produce  json
+// @Param since query string false "Only list records since this time, in RFC 3339"
+// @Param object query string false "Only list records of changes to this object, as kind:name, like service:svc or route:svc/route"
+// @Success 200 {} int OK
+// @Router /audit [get]
+// @Security ApiKeyAuth
+func GET_Audit(c echo.Context) error {
+	var since time.Time
+	if s := c.QueryParam("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return badRequest(c, invalid("", "", "since", "Please provide since in RFC 3339, like 2020-06-01T00:00:00Z"))
+		}
+		since = t
+	}
+
+	rs, err := DB.ListAuditRecords(since, c.QueryParam("object"))
+	if err != nil {
+		return dbError(c, err)
+	}
+	return dbRows(c, http.StatusOK, "saaras_db_audit", rs,
+		"audit_id token_name client_ip method endpoint path objects before after code create_ts")
+}
+
+func Add_audit_routes(e *echo.Echo) {
+	e.GET("/audit", GET_Audit)
+}
@@ -0,0 +1,98 @@
+package webhttp
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	api := newTestAPI(t, Audit)
+	Add_service_routes(api.Echo)
+	Add_secret_routes(api.Echo)
+	Add_audit_routes(api.Echo)
+	do := api.do
+
+	file := filepath.Join(t.TempDir(), "audit.log")
+	require.NoError(t, SetAuditFile(file))
+	defer func() {
+		auditFile.f.Close()
+		auditFile.f = nil
+	}()
+
+	audit := func(query string) []store.AuditRecord {
+		rec := do(http.MethodGet, "/audit"+query, "")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp struct {
+			Data struct {
+				Saaras_db_audit []store.AuditRecord `json:"saaras_db_audit"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		return resp.Data.Saaras_db_audit
+	}
+
+	start := time.Now().UTC().Add(-time.Second)
+	require.Less(t, do(http.MethodPost, "/service", `{"service_name": "svc", "fqdn": "a.example.com"}`).Code, 300)
+	require.Less(t, do(http.MethodPatch, "/service/svc", `{"fqdn": "b.example.com"}`).Code, 300)
+	require.Less(t, do(http.MethodPost, "/service/svc/route", `{"route_name": "r", "route_prefix": "/"}`).Code, 300)
+	require.Less(t, do(http.MethodPost, "/secret", `{"secret_name": "s", "secret_key": "very secret"}`).Code, 300)
+	require.Less(t, do(http.MethodDelete, "/secret/s", "").Code, 300)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/service/nope", "").Code)
+
+	// Reads are not audited
+	rs := audit("")
+	require.Len(t, rs, 6)
+
+	created := rs[0]
+	assert.Equal(t, "POST", created.Method)
+	assert.Equal(t, "/service", created.Endpoint)
+	assert.Equal(t, []string{"service:svc"}, created.Objects)
+	assert.JSONEq(t, "null", string(created.Before))
+	assert.Contains(t, string(created.After), `"fqdn":"a.example.com"`)
+
+	patched := rs[1]
+	assert.Equal(t, "/service/:service_name", patched.Endpoint)
+	assert.Contains(t, string(patched.Before), `"fqdn":"a.example.com"`)
+	assert.Contains(t, string(patched.After), `"fqdn":"b.example.com"`)
+	assert.Equal(t, http.StatusCreated, patched.Code)
+
+	assert.Equal(t, []string{"route:svc/r", "service:svc"}, rs[2].Objects)
+
+	// Secret keys are redacted
+	assert.Equal(t, []string{"secret:s"}, rs[3].Objects)
+	assert.NotContains(t, string(rs[3].After), "very secret")
+	assert.Contains(t, string(rs[3].After), `"secret_key":"sha256:`)
+	assert.Equal(t, rs[3].After, rs[4].Before)
+	assert.JSONEq(t, "null", string(rs[4].After))
+
+	assert.Equal(t, http.StatusNotFound, rs[5].Code)
+
+	rs = audit("?object=service:svc")
+	assert.Len(t, rs, 3)
+	rs = audit("?object=route:svc/r&since=" + start.Format(time.RFC3339))
+	assert.Len(t, rs, 1)
+	rs = audit("?since=" + time.Now().UTC().Add(time.Hour).Format(time.RFC3339))
+	assert.Empty(t, rs)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/audit?since=yesterday", "").Code)
+
+	// Records are written to the audit file as well
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+	lines := 0
+	for s := bufio.NewScanner(f); s.Scan(); lines++ {
+		var r store.AuditRecord
+		require.NoError(t, json.Unmarshal(s.Bytes(), &r))
+		assert.NotZero(t, r.ID)
+	}
+	assert.Equal(t, 6, lines)
+}
@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package webhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/sirupsen/logrus"
+)
+
+// DB holds the configuration served by the API. It is set up by main,
+// either on Hasura at HOST:PORT or in a local file.
+var DB store.Store
+
+// Responses keep the shape of the Hasura responses the API returned
+// when it queried Hasura directly, so that existing clients keep working:
+//
+//   {"data": {"saaras_db_proxy": [...]}}
+//   {"data": {"insert_saaras_db_proxy": {"affected_rows": 1}}}
+//   {"errors": [{"code": "not-found", "message": "...", "object": "proxy:gw"}]}
+
+// field is one field of a selection, optionally renamed to alias.
+type field struct {
+	name  string
+	alias string
+	sel   selection
+}
+
+// selection is the set of fields of an object to respond with. It is
+// written like a GraphQL selection set, with nested objects in braces and
+// renamed fields as "alias: name".
+type selection []field
+
+func parseSelection(s string) selection {
+	r := strings.NewReplacer("{", " { ", "}", " } ", ":", ": ")
+	sel, _ := parseFields(strings.Fields(r.Replace(s)), 0)
+	return sel
+}
+
+func parseFields(tok []string, i int) (selection, int) {
+	var sel selection
+	var alias string
+	for i < len(tok) {
+		switch t := tok[i]; {
+		case t == "{":
+			var sub selection
+			sub, i = parseFields(tok, i+1)
+			if len(sel) > 0 {
+				sel[len(sel)-1].sel = sub
+			}
+		case t == "}":
+			return sel, i + 1
+		case strings.HasSuffix(t, ":"):
+			alias = strings.TrimSuffix(t, ":")
+			i++
+		default:
+			if alias == "" {
+				alias = t
+			}
+			sel = append(sel, field{name: t, alias: alias})
+			alias = ""
+			i++
+		}
+	}
+	return sel, i
+}
+
+func (sel selection) pick(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			out[i] = sel.pick(v[i])
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(sel))
+		for _, f := range sel {
+			fv, ok := v[f.name]
+			if !ok {
+				continue
+			}
+			if f.sel != nil {
+				fv = f.sel.pick(fv)
+			}
+			out[f.alias] = fv
+		}
+		return out
+	}
+	return v
+}
+
+// dbPick returns v in json, as decoded into interface{}, keeping only the
+// fields in selection.
+func dbPick(v interface{}, fields string) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var out interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&out); err != nil {
+		return nil, err
+	}
+	return parseSelection(fields).pick(out), nil
+}
+
+// dbData returns rows as the data of a response to a query on table,
+// keeping only the fields in selection.
+func dbData(table string, rows interface{}, fields string) (map[string]interface{}, error) {
+	v, err := dbPick(rows, fields)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		v = []interface{}{}
+	}
+
+	return map[string]interface{}{
+		"data": map[string]interface{}{
+			table: v,
+		},
+	}, nil
+}
+
+// dbRows responds with rows as the result of a query on table, keeping
+// only the fields in selection.
+func dbRows(c echo.Context, code int, table string, rows interface{}, fields string) error {
+	data, err := dbData(table, rows, fields)
+	if err != nil {
+		return dbError(c, err)
+	}
+	return c.JSON(code, data)
+}
+
+// dbList responds with rows like dbRows, or with no rows if the object
+// they were listed for was not found.
+func dbList(c echo.Context, code int, table string, rows interface{}, err error, fields string) error {
+	if errors.Is(err, store.ErrNotFound) {
+		return dbRows(c, code, table, nil, fields)
+	}
+	if err != nil {
+		return dbError(c, err)
+	}
+	return dbRows(c, code, table, rows, fields)
+}
+
+// dbOne responds with row like dbRows, or with no rows if it was not found.
+func dbOne(c echo.Context, code int, table string, row interface{}, err error, fields string) error {
+	if errors.Is(err, store.ErrNotFound) {
+		return dbRows(c, code, table, nil, fields)
+	}
+	if err != nil {
+		return dbError(c, err)
+	}
+	return dbRows(c, code, table, []interface{}{row}, fields)
+}
+
+// dbAffected responds with the number of rows changed by mutation.
+func dbAffected(c echo.Context, code int, mutation string, n int) error {
+	return c.JSON(code, map[string]interface{}{
+		"data": map[string]interface{}{
+			mutation: map[string]int{"affected_rows": n},
+		},
+	})
+}
+
+// dbError responds with err returned by DB.
+func dbError(c echo.Context, err error) error {
+	log := logrus.StandardLogger().WithField("context", "web-http")
+	log.Errorf("Error when accessing store [%v]\n", err)
+
+	code, e := storeError(err)
+	return apiErrors(c, code, e)
+}
+
+// rawJSON returns v in json, to be saved in a config_json column.
+func rawJSON(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// ignoreExists returns nil if err is store.ErrExists, and err otherwise.
+func ignoreExists(err error) error {
+	if errors.Is(err, store.ErrExists) {
+		return nil
+	}
+	return err
+}
+
+// isExists returns true if err is store.ErrExists.
+func isExists(err error) bool {
+	return errors.Is(err, store.ErrExists)
+}
+
+// isNotFound returns true if err is store.ErrNotFound.
+func isNotFound(err error) bool {
+	return errors.Is(err, store.ErrNotFound)
+}
@@ -0,0 +1,171 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package webhttp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around changes
+const diffContext = 3
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffOps returns the edits turning the lines a into b.
+func diffOps(a, b []string) []diffOp {
+	// Only the lines between the common prefix and suffix need the
+	// quadratic search, which keeps small changes to large configs cheap
+	p := 0
+	for p < len(a) && p < len(b) && a[p] == b[p] {
+		p++
+	}
+	s := 0
+	for s < len(a)-p && s < len(b)-p && a[len(a)-1-s] == b[len(b)-1-s] {
+		s++
+	}
+
+	var ops []diffOp
+	for _, l := range a[:p] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	ops = append(ops, lcsOps(a[p:len(a)-s], b[p:len(b)-s])...)
+	for _, l := range a[len(a)-s:] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	return ops
+}
+
+// lcsOps returns the edits turning a into b which keep their longest
+// common subsequence.
+func lcsOps(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+
+	// l[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	l := make([][]int, n+1)
+	for i := range l {
+		l[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				l[i][j] = l[i+1][j+1] + 1
+			case l[i+1][j] >= l[i][j+1]:
+				l[i][j] = l[i+1][j]
+			default:
+				l[i][j] = l[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case l[i+1][j] >= l[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// unifiedDiff returns the changes from a to b in unified format, with the
+// names from and to in the header, or "" if a and b are equal.
+func unifiedDiff(from, to, a, b string) string {
+	ops := diffOps(splitLines(a), splitLines(b))
+
+	// aLine[k] and bLine[k] are the numbers of lines of a and b before ops[k]
+	aLine := make([]int, len(ops)+1)
+	bLine := make([]int, len(ops)+1)
+	for k, op := range ops {
+		aLine[k+1], bLine[k+1] = aLine[k], bLine[k]
+		if op.kind != '+' {
+			aLine[k+1]++
+		}
+		if op.kind != '-' {
+			bLine[k+1]++
+		}
+	}
+
+	var sb strings.Builder
+	for k := 0; k < len(ops); {
+		for k < len(ops) && ops[k].kind == ' ' {
+			k++
+		}
+		if k == len(ops) {
+			break
+		}
+
+		// A hunk holds the changes separated by at most twice the context
+		start := k - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := k
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContext {
+				break
+			}
+			end = next
+		}
+		stop := end + diffContext
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", from, to)
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n",
+			hunkRange(aLine[start], aLine[stop]-aLine[start]),
+			hunkRange(bLine[start], bLine[stop]-bLine[start]))
+		for _, op := range ops[start:stop] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			sb.WriteByte('\n')
+		}
+		k = stop
+	}
+	return sb.String()
+}
+
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
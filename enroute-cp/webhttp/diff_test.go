@@ -0,0 +1,70 @@
+package webhttp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := map[string]struct {
+		a, b string
+		want string
+	}{
+		"equal": {
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		"from empty": {
+			a: "",
+			b: "a\nb\n",
+			want: `--- old
++++ new
+@@ -0,0 +1,2 @@
++a
++b
+`,
+		},
+		"change in the middle": {
+			a: "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b: "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			want: `--- old
++++ new
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`,
+		},
+		"two hunks": {
+			a: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b: "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			want: `--- old
++++ new
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -9,4 +9,3 @@
+ 9
+ 10
+ 11
+-12
+`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, unifiedDiff("old", "new", tc.a, tc.b))
+		})
+	}
+}
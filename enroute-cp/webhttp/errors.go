@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package webhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/saarasio/enroute/enroute-cp/store"
+)
+
+// Errors are responded with in the shape of Hasura errors, each with a
+// code, a message and, when known, the field of the request body and the
+// object it is about:
+//
+//   {"errors": [{"code": "invalid", "message": "...", "field": "upstream_port", "object": "upstream:u1"}]}
+//
+// Objects are named as in the audit log, like service:svc or route:svc/r.
+
+const (
+	// ErrorInvalid is for a field of a request which is missing or invalid
+	ErrorInvalid = "invalid"
+	// ErrorBadRequest is for a request which cannot be read
+	ErrorBadRequest   = "bad-request"
+	ErrorUnauthorized = "unauthorized"
+	ErrorForbidden    = "forbidden"
+	ErrorNotFound     = "not-found"
+	ErrorExists       = "exists"
+	ErrorInUse        = "in-use"
+	ErrorInternal     = "internal"
+)
+
+// APIError is a problem with a request.
+type APIError struct {
+	// Code is one of invalid, bad-request, unauthorized, forbidden,
+	// not-found, exists, in-use or internal, or the status text of other
+	// errors, like method-not-allowed
+	Code    string `json:"code" example:"invalid"`
+	Message string `json:"message" example:"Please provide a valid port value"`
+	// Field is the field of the request body the error is about
+	Field string `json:"field,omitempty" example:"upstream_port"`
+	// Object is the object the error is about, as kind:name
+	Object string `json:"object,omitempty" example:"upstream:u1"`
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// ErrorResponse is the body of the response to a request which failed.
+type ErrorResponse struct {
+	Errors []*APIError `json:"errors"`
+
+	// References are the objects using an object which could not be
+	// deleted because of them
+	References *References `json:"references,omitempty"`
+}
+
+// invalid returns the error for field of a request about the object kind
+// named name, which may be empty if it is not known yet.
+func invalid(kind, name, field, format string, a ...interface{}) *APIError {
+	e := &APIError{Code: ErrorInvalid, Message: fmt.Sprintf(format, a...), Field: field}
+	if name != "" {
+		e.Object = kind + ":" + name
+	}
+	return e
+}
+
+// apiErrors responds with errs.
+func apiErrors(c echo.Context, code int, errs ...*APIError) error {
+	return c.JSON(code, ErrorResponse{Errors: errs})
+}
+
+// badRequest responds with errs, the problems found in a request.
+func badRequest(c echo.Context, errs ...*APIError) error {
+	return apiErrors(c, http.StatusBadRequest, errs...)
+}
+
+// statusCode returns the code for errors with the HTTP status code, like
+// method-not-allowed.
+func statusCode(code int) string {
+	if code == http.StatusInternalServerError {
+		return ErrorInternal
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(code)), " ", "-")
+}
+
+// storeError returns the status code and error for err returned by DB.
+func storeError(err error) (int, *APIError) {
+	code, e := http.StatusInternalServerError, &APIError{Code: ErrorInternal, Message: err.Error()}
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		code, e.Code = http.StatusNotFound, ErrorNotFound
+	case errors.Is(err, store.ErrExists):
+		code, e.Code = http.StatusConflict, ErrorExists
+	case errors.Is(err, store.ErrInUse):
+		code, e.Code = http.StatusConflict, ErrorInUse
+	}
+
+	var oe *store.ObjectError
+	if errors.As(err, &oe) {
+		e.Object = oe.Kind + ":" + oe.Name
+	}
+	return code, e
+}
+
+// httpError returns the error for he, returned by echo or a middleware.
+func httpError(he *echo.HTTPError) *APIError {
+	e := &APIError{Code: statusCode(he.Code), Message: fmt.Sprint(he.Message)}
+
+	// Fields of the body of the wrong type
+	var te *json.UnmarshalTypeError
+	if errors.As(he.Internal, &te) {
+		e.Code = ErrorInvalid
+		e.Field = te.Field
+		e.Message = fmt.Sprintf("%s must be a %s, not a %s", te.Field, te.Type, te.Value)
+	}
+	return e
+}
+
+// HTTPErrorHandler responds to requests which failed with an error not
+// responded with yet, like those of binding the request body.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	code, e := http.StatusInternalServerError, &APIError{Code: ErrorInternal, Message: err.Error()}
+	if he, ok := err.(*echo.HTTPError); ok {
+		code, e = he.Code, httpError(he)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(code)
+	} else {
+		err = apiErrors(c, code, e)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
@@ -0,0 +1,117 @@
+package webhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestBodiesAndErrors(t *testing.T) {
+	api := newTestAPI(t)
+	api.HTTPErrorHandler = HTTPErrorHandler
+	Add_upstream_routes(api.Echo)
+	Add_secret_routes(api.Echo)
+
+	do := func(method, path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, contentType)
+		return api.serve(req)
+	}
+
+	// Upstreams are posted in json, with numbers as numbers or strings,
+	// or in forms
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/upstream", echo.MIMEApplicationJSON,
+		`{"upstream_name": "u1", "upstream_ip": "10.0.0.1", "upstream_port": 80, "upstream_hc_path": "/", "upstream_weight": 100}`).Code)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/upstream", echo.MIMEApplicationJSON,
+		`{"upstream_name": "u2", "upstream_ip": "10.0.0.2", "upstream_port": "8080", "upstream_hc_path": "/", "upstream_weight": "0"}`).Code)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/upstream", echo.MIMEApplicationForm,
+		"upstream_name=u3&upstream_ip=10.0.0.3&upstream_port=443&upstream_hc_path=/&upstream_weight=1").Code)
+
+	u, err := DB.GetUpstream("u1")
+	require.NoError(t, err)
+	assert.Equal(t, 80, u.Port)
+	assert.Equal(t, 100, u.Weight)
+	u, err = DB.GetUpstream("u3")
+	require.NoError(t, err)
+	assert.Equal(t, 443, u.Port)
+
+	// Secret keys are uploaded in forms, or sent in json
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/secret", echo.MIMEApplicationJSON, `{"secret_name": "s"}`).Code)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/secret/s/key", echo.MIMEApplicationJSON, `{"secret_key": "json key"}`).Code)
+	s, err := DB.GetSecret("s")
+	require.NoError(t, err)
+	assert.Equal(t, "json key", s.Key)
+
+	var form bytes.Buffer
+	w := multipart.NewWriter(&form)
+	fw, err := w.CreateFormFile("Secret_key", "key.pem")
+	require.NoError(t, err)
+	fw.Write([]byte("form key"))
+	require.NoError(t, w.Close())
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/secret/s/key", w.FormDataContentType(), form.String()).Code)
+	s, err = DB.GetSecret("s")
+	require.NoError(t, err)
+	assert.Equal(t, "form key", s.Key)
+
+	tests := map[string]struct {
+		method, path, body string
+		code               int
+		want               APIError
+	}{
+		"missing field": {
+			http.MethodPost, "/upstream", `{"upstream_ip": "10.0.0.1"}`,
+			http.StatusBadRequest, APIError{Code: ErrorInvalid, Field: "upstream_name"},
+		},
+		"invalid field": {
+			http.MethodPatch, "/upstream/u1", `{"upstream_port": 70000}`,
+			http.StatusBadRequest, APIError{Code: ErrorInvalid, Field: "upstream_port", Object: "upstream:u1"},
+		},
+		"field of the wrong type": {
+			http.MethodPost, "/upstream", `{"upstream_name": ["u4"]}`,
+			http.StatusBadRequest, APIError{Code: ErrorInvalid, Field: "upstream_name"},
+		},
+		"file of the wrong type": {
+			http.MethodPost, "/secret/s/cert", `{"secret_cert": 1}`,
+			http.StatusBadRequest, APIError{Code: ErrorInvalid, Field: "secret_cert"},
+		},
+		"malformed body": {
+			http.MethodPost, "/upstream", `{"upstream_name": `,
+			http.StatusBadRequest, APIError{Code: ErrorBadRequest},
+		},
+		"not found": {
+			http.MethodPatch, "/upstream/nope", `{"upstream_port": 80}`,
+			http.StatusNotFound, APIError{Code: ErrorNotFound, Object: "upstream:nope"},
+		},
+		"exists": {
+			http.MethodPost, "/upstream", `{"upstream_name": "u1", "upstream_ip": "10.0.0.1", "upstream_port": 80, "upstream_hc_path": "/", "upstream_weight": 1}`,
+			http.StatusConflict, APIError{Code: ErrorExists, Object: "upstream:u1"},
+		},
+		"unknown route": {
+			http.MethodGet, "/nope", "",
+			http.StatusNotFound, APIError{Code: ErrorNotFound},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := do(tc.method, tc.path, echo.MIMEApplicationJSON, tc.body)
+			require.Equal(t, tc.code, rec.Code, rec.Body.String())
+
+			var resp ErrorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			require.Len(t, resp.Errors, 1)
+			got := resp.Errors[0]
+			assert.NotEmpty(t, got.Message)
+			got.Message = ""
+			assert.Equal(t, tc.want, *got)
+		})
+	}
+}
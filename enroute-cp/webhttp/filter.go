@@ -1,10 +1,8 @@
 package webhttp
 
 import (
-	"bytes"
-	"encoding/json"
 	"github.com/labstack/echo/v4"
-	"github.com/saarasio/enroute/enroute-dp/saaras"
+	"github.com/saarasio/enroute/enroute-cp/store"
 	"github.com/saarasio/enroute/enroute-dp/saarasconfig"
 	"net/http"
 
@@ -12,11 +10,24 @@ import (
 )
 
 type FilterConfig struct {
-	Filter_name   string `json:"filter_name" xml:"filter_name" form:"filter_name" query:"filter_name"`
-	Filter_type   string `json:"filter_type" xml:"filter_type" form:"filter_type" query:"filter_type"`
+	Filter_name   string `json:"filter_name" xml:"filter_name" form:"filter_name" query:"filter_name" example:"lua-filter"`
+	Filter_type   string `json:"filter_type" xml:"filter_type" form:"filter_type" query:"filter_type" example:"http_filter_lua"`
 	Filter_config string `json:"filter_config" xml:"filter_config" form:"filter_config" query:"filter_config"`
 }
 
+const filterFields = `filter_id filter_name filter_type config_json`
+
+// filtersNamed returns the filters in fs named filter_name
+func filtersNamed(fs []store.Filter, filter_name string) []store.Filter {
+	var found []store.Filter
+	for _, f := range fs {
+		if f.Name == filter_name {
+			found = append(found, f)
+		}
+	}
+	return found
+}
+
 // @Summary Get all filters in db
 // @Description Get all filters in db
 // @Tags filter
@@ -26,33 +37,12 @@ type FilterConfig struct {
 // @Router /filter [get]
 // @Security ApiKeyAuth
 func GET_FilterConfig(c echo.Context) error {
-	var buf bytes.Buffer
-
-	var args map[string]string
-	args = make(map[string]string)
-
-	Q := `
-
-query get_all_filterconfig {
-    saaras_db_filter {
-    filter_id
-    filter_name
-    filter_type
-    config_json
-  }
-}
-    `
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	fs, err := DB.ListFilters()
+	if err != nil {
+		return dbError(c, err)
 	}
 
-	buf2 := bytes.Replace(buf.Bytes(), []byte("config_json"), []byte("filter_config"), -1)
-
-	return c.JSONBlob(http.StatusOK, buf2)
+	return dbRows(c, http.StatusOK, "saaras_db_filter", fs, "filter_id filter_name filter_type filter_config: config_json")
 }
 
 // @Summary Get filters detail for provided name
@@ -65,37 +55,10 @@ query get_all_filterconfig {
 // @Router /filter/{filter_name} [get]
 // @Security ApiKeyAuth
 func GET_One_FilterConfig(c echo.Context) error {
-	var buf bytes.Buffer
-
-	var args map[string]string
-	args = make(map[string]string)
-
-	Q := `
-
-query get_all_filter($filter_name: String!) {
-  saaras_db_filter(where: {filter_name: {_eq: $filter_name}}) {
-    filter_id
-    filter_name
-    filter_type
-    config_json
-  }
-}
-
-`
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	filter_name := c.Param("filter_name")
-	args["filter_name"] = filter_name
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
 
-	buf2 := bytes.Replace(buf.Bytes(), []byte("config_json"), []byte("filter_config"), -1)
-
-	return c.JSONBlob(http.StatusOK, buf2)
+	f, err := DB.GetFilter(filter_name)
+	return dbOne(c, http.StatusOK, "saaras_db_filter", f, err, "filter_id filter_name filter_type filter_config: config_json")
 }
 
 func setConfigJson(log *logrus.Entry, filter_type string, filter_config string, args *map[string]interface{}) {
@@ -148,33 +111,14 @@ func isFilterTypeValid(filter_type string) bool {
 // @Produce  json
 // @Param Name body webhttp.FilterConfig true "Name of filter to create"
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /filter [post]
 // @Security ApiKeyAuth
 func POST_FilterConfig(c echo.Context) error {
-	var buf bytes.Buffer
-
 	var args map[string]interface{}
 	args = make(map[string]interface{})
 
-	Q := `
-
-mutation insert_into_filter($filter_name: String!, $filter_type: String, $filter_config: String!, $config_json: jsonb) {
-  insert_saaras_db_filter
-  (
-    objects:
-    {
-        filter_name: $filter_name,
-        filter_type: $filter_type,
-        filter_config: $filter_config,
-        config_json: $config_json
-      },
-    on_conflict: { constraint:filter_pkey, update_columns: update_ts }
-  ) 
-  {
-    affected_rows
-  }
-}
-`
 	log2 := logrus.StandardLogger()
 	log := log2.WithField("context", "web-http")
 
@@ -184,154 +128,82 @@ mutation insert_into_filter($filter_name: String!, $filter_type: String, $filter
 	}
 
 	if len(fc.Filter_name) == 0 {
-		return c.JSON(http.StatusBadRequest, "{\"Error\" : \" Please provide name of Filter using Filter_name field \"}")
+		return badRequest(c, invalid("filter", "", "filter_name", "Please provide name of Filter using filter_name field"))
 	}
 
-	args["filter_name"] = fc.Filter_name
-	args["filter_type"] = fc.Filter_type
-	args["filter_config"] = fc.Filter_config
-
 	if !isFilterTypeValid(fc.Filter_type) {
-		return c.JSON(http.StatusBadRequest, "{\"Error\" : \"Invalid filter type \"}")
+		return badRequest(c, invalid("filter", fc.Filter_name, "filter_type", "Invalid filter type %s", fc.Filter_type))
 	}
 
 	setConfigJson(log, fc.Filter_type, fc.Filter_config, &args)
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQueryGenericVals(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
-
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
-}
-
-func getFilterType(filter_name string) string {
-
-	var Q = `
-	query GetFilterTypeForFilterName($filter_name: String!) {
-		saaras_db_filter(where: {filter_name: {_eq: $filter_name}}) {
-			filter_type
-		}
-	}
-	`
-	var buf bytes.Buffer
-
-	var args map[string]interface{}
-	args = make(map[string]interface{})
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
-	args["filter_name"] = filter_name
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQueryGenericVals(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
-
-	type Q_Response struct {
-		Data struct {
-			SaarasDbFilter []struct {
-				FilterType string `json:"filter_type"`
-			} `json:"saaras_db_filter"`
-		} `json:"data"`
-	}
-
-	var qr Q_Response
-	err := json.Unmarshal(buf.Bytes(), &qr)
-
+	err := DB.CreateFilter(&store.Filter{
+		Name:       fc.Filter_name,
+		Type:       fc.Filter_type,
+		Config:     fc.Filter_config,
+		ConfigJSON: rawJSON(args["config_json"]),
+	})
 	if err != nil {
-		return ""
+		return dbError(c, err)
 	}
 
-	var filter_type string
-
-	for _, onef := range qr.Data.SaarasDbFilter {
-		filter_type = onef.FilterType
-	}
-
-	return filter_type
+	return dbAffected(c, http.StatusOK, "insert_saaras_db_filter", 1)
 }
 
 // @Summary Set filter config from file
 // @Description Set filter config from file
+// @Description The config is uploaded as the Config file of a form, or sent as filter_config in json
 // @Tags filter
 // @Param filter_name path string true "Name of filter_name for which to list config"
-// @Accept  json
+// @Accept  mpfd,json
 // @Produce  json
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /filter/:filter_name/config [post]
 // @Security ApiKeyAuth
 func POST_One_FilterConfig(c echo.Context) error {
-	var buf bytes.Buffer
-
 	var args map[string]interface{}
 	args = make(map[string]interface{})
 
-	Q := `
-
-    mutation update_filter($filter_name: String!, $filter_config: String!, $config_json: jsonb) {
-        update_saaras_db_filter (
-            where:
-            {
-                filter_name: {_eq: $filter_name}
-            }
-            _set:
-            {
-                filter_config: $filter_config
-                config_json: $config_json
-            }
-        )
-        {
-            affected_rows
-        }
-    }
-`
 	log2 := logrus.StandardLogger()
 	log := log2.WithField("context", "web-http")
 
 	filter_name := c.Param("filter_name")
 
 	// Read config from file
-	file, err := c.FormFile("Config")
-	if file == nil {
-		return c.JSON(http.StatusBadRequest, "{\"Error\" : \"Config empty\"}")
-	}
-	src, err := file.Open()
+	config_from_file, err := formFile(c, "Config", "filter_config")
 	if err != nil {
 		return err
 	}
-	defer src.Close()
-	buf2 := new(bytes.Buffer)
-	buf2.ReadFrom(src)
-	config_from_file := buf2.String()
-
-	// We already have the globalconfig_name from params
-	// gc_name := c.Param("globalconfig_name")
-
-	if len(filter_name) == 0 {
-		return c.JSON(http.StatusBadRequest, "{\"Error\" : \"Please provide filter name\"}")
+	if config_from_file == "" {
+		return badRequest(c, invalid("filter", filter_name, "filter_config", "Config empty"))
 	}
 
-	args["filter_name"] = filter_name
-	args["filter_config"] = config_from_file
+	f, err := DB.GetFilter(filter_name)
+	if err != nil {
+		return dbError(c, err)
+	}
 
 	// TODO: Check filter_type is one of the allowed types
-	filter_type := getFilterType(filter_name)
+	filter_type := f.Type
 
 	if !isFilterTypeValid(filter_type) {
-		return c.JSON(http.StatusBadRequest, "{\"Error\" : \"Cannot find filter or type not recognized\n\"}")
+		return badRequest(c, invalid("filter", filter_name, "filter_type", "Filter type %s not recognized", filter_type))
 	}
 
 	setConfigJson(log, filter_type, config_from_file, &args)
 
 	log.Errorf("config_json set to [%s]\n", args["config_json"])
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQueryGenericVals(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	f.Config = config_from_file
+	f.ConfigJSON = rawJSON(args["config_json"])
+
+	if err := DB.UpdateFilter(f); err != nil {
+		return dbError(c, err)
 	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return dbAffected(c, http.StatusOK, "update_saaras_db_filter", 1)
 }
 
 // @Summary Update filter config - update filter type
@@ -342,34 +214,10 @@ func POST_One_FilterConfig(c echo.Context) error {
 // @Produce  json
 // @Param Name body webhttp.FilterConfig true "Name of filter to create"
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
 // @Router /filter/:filter_name [patch]
 // @Security ApiKeyAuth
 func PATCH_One_FilterConfig(c echo.Context) error {
-	var buf bytes.Buffer
-
-	var args map[string]interface{}
-	args = make(map[string]interface{})
-
-	Q := `
-	mutation update_filter($filter_name: String!, $filter_type: String!) {
-		update_saaras_db_filter (
-			where:
-			{
-				filter_name: {_eq: $filter_name}
-			}
-			_set:
-			{
-				filter_type: $filter_type                
-			}
-		)
-		{
-			affected_rows
-		}
-	}
-`
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	filter_name := c.Param("filter_name")
 
 	fc := new(FilterConfig)
@@ -378,69 +226,58 @@ func PATCH_One_FilterConfig(c echo.Context) error {
 	}
 
 	if len(fc.Filter_type) == 0 {
-		return c.JSON(http.StatusBadRequest, "{\"Error\" : \" Please provide name of Filter using Filter_name field \"}")
+		return badRequest(c, invalid("filter", filter_name, "filter_type", "Please provide type of Filter using filter_type field"))
 	}
 
 	if !isFilterTypeValid(fc.Filter_type) {
-		return c.JSON(http.StatusBadRequest, "{\"Error\" : \"Cannot find filter or type not recognized\n\"}")
+		return badRequest(c, invalid("filter", filter_name, "filter_type", "Invalid filter type %s", fc.Filter_type))
 	}
 
-	args["filter_name"] = filter_name
-	args["filter_type"] = fc.Filter_type
+	f, err := DB.GetFilter(filter_name)
+	if err != nil {
+		return dbError(c, err)
+	}
 
-	log.Errorf("config_json set to [%s]\n", args["config_json"])
+	f.Type = fc.Filter_type
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQueryGenericVals(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	if err := DB.UpdateFilter(f); err != nil {
+		return dbError(c, err)
 	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return dbAffected(c, http.StatusOK, "update_saaras_db_filter", 1)
 }
 
 // @Summary Delete filter config
 // @Description Delete filter config
+// @Description Deleting a filter which is in use responds with its references, unless cascade is set to remove its associations with services and routes first
 // @Tags filter
 // @Param filter_name path string true "Name of filter_name for which to list config"
+// @Param cascade query bool false "Remove the associations of the filter first"
 // @Accept  json
 // @Produce  json
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /filter/{filter_name} [delete]
 // @Security ApiKeyAuth
 func DELETE_FilterConfig(c echo.Context) error {
-	var buf bytes.Buffer
-
-	var args map[string]string
-	args = make(map[string]string)
-
-	Q := `
-
-mutation delete_filter($filter_name: String!) {
-  delete_saaras_db_filter (
-    where:
-    {
-      filter_name: {_eq: $filter_name}
-    }
-  )
-  {
-    affected_rows
-  }
-}
-
-`
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	filter_name := c.Param("filter_name")
-	args["filter_name"] = filter_name
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
+	return dbDeleteReferenced(c, filterReferences, filter_name, DB.DeleteFilter, "delete_saaras_db_filter")
+}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+// @Summary Get the objects referencing a filter
+// @Description Get the services and routes using a filter, and the proxies serving them
+// @Tags filter
+// @Accept  json
+// @Produce  json
+// @Param filter_name path string true "Name of filter"
+// @Success 200 {object} webhttp.References
+// @Router /filter/{filter_name}/references [get]
+// @Security ApiKeyAuth
+func GET_Filter_References(c echo.Context) error {
+	return dbReferences(c, filterReferences, c.Param("filter_name"))
 }
 
 // @Summary Associate a filter with route
@@ -452,62 +289,20 @@ mutation delete_filter($filter_name: String!) {
 // @Accept  json
 // @Produce  json
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /service/{service_name}/route/{route_name}/filter/{filter_name} [post]
 // @Security ApiKeyAuth
 func POST_Service_Route_FilterConfig_Association(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	service_name := c.Param("service_name")
 	route_name := c.Param("route_name")
 	filter_name := c.Param("filter_name")
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	args["service_name"] = service_name
-	args["route_name"] = route_name
-	args["filter_name"] = filter_name
-
-	var Q = `
-
-mutation insert_into_route_filter($service_name : String!, $route_name : String!, $filter_name : String!) {
-    insert_saaras_db_route_filter(
-      objects: 
-      {
-        filter: {
-          data: 
-          {
-            filter_name: $filter_name
-          } on_conflict: {constraint: filter_filter_name_key, update_columns: update_ts}
-        }, 
-        route: {
-          data: 
-          {
-            route_name: $route_name, 
-            service: {
-              data: 
-              {
-                service_name: $service_name
-              } on_conflict: {constraint: service_service_name_key, update_columns: update_ts}
-            }
-          }, 
-          on_conflict: {constraint: route_service_id_route_name_key, update_columns: update_ts}}
-      } on_conflict: {constraint: route_filter_pkey, update_columns: update_ts}
-    ) {
-    affected_rows
-  }
-}
-
-`
-
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	if err := DB.AssociateRouteFilter(service_name, route_name, filter_name); err != nil {
+		return dbError(c, err)
 	}
-	return c.JSONBlob(http.StatusCreated, buf.Bytes())
+	return dbAffected(c, http.StatusCreated, "insert_saaras_db_route_filter", 1)
 }
 
 // @Summary Associate a filter with service
@@ -518,54 +313,24 @@ mutation insert_into_route_filter($service_name : String!, $route_name : String!
 // @Accept  json
 // @Produce  json
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /service/{service_name}/filter/{filter_name} [post]
 // @Security ApiKeyAuth
 func POST_Service_FilterConfig_Association(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	service_name := c.Param("service_name")
 	filter_name := c.Param("filter_name")
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	args["service_name"] = service_name
-	args["filter_name"] = filter_name
-
-	var Q = `
-
-mutation insert_into_service_filter($service_name : String!, $filter_name : String!) {
-    insert_saaras_db_service_filter(
-      objects:
-      {
-        filter: {
-          data:
-          {
-            filter_name: $filter_name
-          } on_conflict: {constraint: filter_filter_name_key, update_columns: update_ts}
-        },
-        service: {
-          data:
-          {
-            service_name: $service_name,
-          },
-          on_conflict: {constraint:service_service_name_key, update_columns: update_ts}}
-      } on_conflict: {constraint:service_filter_pkey, update_columns: update_ts}
-    ) {
-    affected_rows
-  }
-  
-}
+	// The service is created if it does not exist
+	if err := DB.CreateService(&store.Service{Name: service_name}); err != nil {
+		return dbError(c, err)
+	}
 
-`
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	if err := DB.AssociateServiceFilter(service_name, filter_name); err != nil {
+		return dbError(c, err)
 	}
-	return c.JSONBlob(http.StatusCreated, buf.Bytes())
+	return dbAffected(c, http.StatusCreated, "insert_saaras_db_service_filter", 1)
 }
 
 // @Summary Return filter associated with a service
@@ -579,45 +344,11 @@ mutation insert_into_service_filter($service_name : String!, $filter_name : Stri
 // @Router /service/{service_name}/filter/{filter_name} [get]
 // @Security ApiKeyAuth
 func GET_Service_FilterConfig_Association(c echo.Context) error {
-
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	service_name := c.Param("service_name")
 	filter_name := c.Param("filter_name")
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	args["service_name"] = service_name
-	args["filter_name"] = filter_name
-
-	var Q = `
-
-query get_filter_for_service($service_name: String!, $filter_name:String!) {
-  saaras_db_filter(
-    where :
-    {
-      _and:[
-        {filter_name: {_eq: $filter_name}},
-        {service_filters: {service: {service_name: {_eq: $service_name}}}}
-      ]
-    }
-  ) {
-    filter_id
-    filter_name
-    filter_type
-    config_json
-  }
-}
-`
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
-	return c.JSONBlob(http.StatusCreated, buf.Bytes())
+	fs, err := DB.ListServiceFilters(service_name)
+	return dbList(c, http.StatusCreated, "saaras_db_filter", filtersNamed(fs, filter_name), err, filterFields)
 }
 
 // @Summary Return filters associated with a service
@@ -630,44 +361,10 @@ query get_filter_for_service($service_name: String!, $filter_name:String!) {
 // @Router /service/{service_name}/filter [get]
 // @Security ApiKeyAuth
 func GET_Service_All_FilterConfig_Association(c echo.Context) error {
-
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	service_name := c.Param("service_name")
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	args["service_name"] = service_name
-
-	var Q = `
-
-query get_filter_for_service($service_name: String!) {
-  saaras_db_filter(
-    where :
-    {
-      _and:[
-        {service_filters: {service: {service_name: {_eq: $service_name}}}}
-      ]
-    }
-  ) {
-    filter_id
-    filter_name
-    filter_type
-    config_json
-  }
-}
-
-`
-
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
-	return c.JSONBlob(http.StatusCreated, buf.Bytes())
+	fs, err := DB.ListServiceFilters(service_name)
+	return dbList(c, http.StatusCreated, "saaras_db_filter", fs, err, filterFields)
 }
 
 // @Summary Delete filter associated with a service
@@ -678,48 +375,19 @@ query get_filter_for_service($service_name: String!) {
 // @Accept  json
 // @Produce  json
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /service/{service_name}/filter/{filter_name} [delete]
 // @Security ApiKeyAuth
 func DELETE_Service_FilterConfig_Association(c echo.Context) error {
-
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	service_name := c.Param("service_name")
 	filter_name := c.Param("filter_name")
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	args["service_name"] = service_name
-	args["filter_name"] = filter_name
-
-	var Q = `
-
-mutation delete_service_filter($service_name: String!, $filter_name:String!) {
-  delete_saaras_db_service_filter
-  (
-    where : 
-    {
-      _and:[
-        {filter: {filter_name: {_eq: $filter_name}}},
-        {service: {service_name: {_eq: $service_name}}}
-      ] 
-    }
-  ) {
-   affected_rows
-  } 
-}
-
-`
-
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	if err := DB.DisassociateServiceFilter(service_name, filter_name); err != nil {
+		return dbError(c, err)
 	}
-	return c.JSONBlob(http.StatusCreated, buf.Bytes())
+	return dbAffected(c, http.StatusCreated, "delete_saaras_db_service_filter", 1)
 }
 
 // @Summary Return filter associated with a service route
@@ -734,50 +402,12 @@ mutation delete_service_filter($service_name: String!, $filter_name:String!) {
 // @Router /service/{service_name}/route/{route_name}/filter/{filter_name} [get]
 // @Security ApiKeyAuth
 func GET_Service_Route_FilterConfig_Association(c echo.Context) error {
-
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	service_name := c.Param("service_name")
 	route_name := c.Param("route_name")
 	filter_name := c.Param("filter_name")
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	args["service_name"] = service_name
-	args["route_name"] = route_name
-	args["filter_name"] = filter_name
-
-	var Q = `
-
-query get_filter_for_service_route2($service_name: String!, $route_name: String!, $filter_name:String!) {
-  saaras_db_filter(
-    where : 
-    {
-      _and:[
-        {filter_name: {_eq: $filter_name}},
-        {route_filters: {route: {service: {service_name: {_eq: $service_name}}}}},
-        {route_filters: {route: {route_name: {_eq: $route_name}}}}
-      ] 
-    }
-  ) {
-    filter_id
-    filter_name
-    filter_type
-    config_json
-  } 
-}
-
-`
-
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
-	return c.JSONBlob(http.StatusCreated, buf.Bytes())
+	fs, err := DB.ListRouteFilters(service_name, route_name)
+	return dbList(c, http.StatusCreated, "saaras_db_filter", filtersNamed(fs, filter_name), err, filterFields)
 }
 
 // @Summary Return filters associated with a service route
@@ -791,47 +421,11 @@ query get_filter_for_service_route2($service_name: String!, $route_name: String!
 // @Router /service/{service_name}/route/{route_name}/filter [get]
 // @Security ApiKeyAuth
 func GET_Service_Route_All_FilterConfig_Association(c echo.Context) error {
-
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	service_name := c.Param("service_name")
 	route_name := c.Param("route_name")
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	args["service_name"] = service_name
-	args["route_name"] = route_name
-
-	var Q = `
-
-query get_filter_for_service_route2($service_name: String!, $route_name: String!) {
-  saaras_db_filter(
-    where : 
-    {
-      _and:[
-        {route_filters: {route: {service: {service_name: {_eq: $service_name}}}}},
-        {route_filters: {route: {route_name: {_eq: $route_name}}}}
-      ] 
-    }
-  ) {
-    filter_id
-    filter_name
-    filter_type
-    config_json
-  } 
-}
-
-`
-
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
-	return c.JSONBlob(http.StatusCreated, buf.Bytes())
+	fs, err := DB.ListRouteFilters(service_name, route_name)
+	return dbList(c, http.StatusCreated, "saaras_db_filter", fs, err, filterFields)
 }
 
 // @Summary Delete filter associated with a service route
@@ -843,51 +437,20 @@ query get_filter_for_service_route2($service_name: String!, $route_name: String!
 // @Accept  json
 // @Produce  json
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /service/{service_name}/route/{route_name}/filter/{filter_name} [delete]
 // @Security ApiKeyAuth
 func DELETE_Service_Route_FilterConfig_Association(c echo.Context) error {
-
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	service_name := c.Param("service_name")
 	route_name := c.Param("route_name")
 	filter_name := c.Param("filter_name")
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	args["service_name"] = service_name
-	args["route_name"] = route_name
-	args["filter_name"] = filter_name
-
-	var Q = `
-
-mutation delete_route_filter($service_name: String!, $route_name: String!, $filter_name:String!) {
-  delete_saaras_db_route_filter
-  (
-    where : 
-    {
-      _and:[
-        {filter: {filter_name: {_eq: $filter_name}}},
-        {route: {service: {service_name: {_eq: $service_name}}}},
-        {route: {route_name: {_eq: $route_name}}}
-      ] 
-    }
-  ) {
-   affected_rows
-  } 
-}
-
-`
-
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	if err := DB.DisassociateRouteFilter(service_name, route_name, filter_name); err != nil {
+		return dbError(c, err)
 	}
-	return c.JSONBlob(http.StatusCreated, buf.Bytes())
+	return dbAffected(c, http.StatusCreated, "delete_saaras_db_route_filter", 1)
 }
 
 func Add_filter_routes(e *echo.Echo) {
@@ -898,6 +461,7 @@ func Add_filter_routes(e *echo.Echo) {
 	e.POST("/filter/:filter_name/config", POST_One_FilterConfig)
 	e.PATCH("/filter/:filter_name", PATCH_One_FilterConfig)
 	e.DELETE("/filter/:filter_name", DELETE_FilterConfig)
+	e.GET("/filter/:filter_name/references", GET_Filter_References)
 
 	// route to filter association
 	e.POST("/service/:service_name/route/:route_name/filter/:filter_name",
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package webhttp
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/saarasio/enroute/enroute-cp/store"
+)
+
+// GraphQLPath is where enroute-dp fetches its configuration from.
+const GraphQLPath = "/v1/graphql"
+
+// graphqlRequest is a query as sent by saaras.FetchConfig
+type graphqlRequest struct {
+	Query     string            `json:"query"`
+	Variables map[string]string `json:"variables"`
+}
+
+// proxyServiceRow is a row of saaras_db_proxy_service
+type proxyServiceRow struct {
+	Proxy   proxyGlobalConfigs  `json:"proxy"`
+	Service store.ServiceDetail `json:"service"`
+}
+
+type proxyGlobalConfigs struct {
+	GlobalConfigs []store.ProxyGlobalConfig `json:"proxy_globalconfigs"`
+}
+
+// querySelection returns the selection set of table in query, or false if
+// query does not select from table.
+func querySelection(query, table string) (string, bool) {
+	i := -1
+	for j := 0; j+len(table) <= len(query); j++ {
+		if query[j:j+len(table)] != table {
+			continue
+		}
+		if (j == 0 || !isNameChar(query[j-1])) &&
+			(j+len(table) == len(query) || !isNameChar(query[j+len(table)])) {
+			i = j
+			break
+		}
+	}
+	if i < 0 {
+		return "", false
+	}
+	q := query[i+len(table):]
+
+	start := strings.Index(q, "{")
+	if p := strings.Index(q, "("); p >= 0 && p < start {
+		// Skip the arguments, which hold braces of their own
+		end := strings.Index(q, ")")
+		if end < 0 {
+			return "", false
+		}
+		q = q[end:]
+		start = strings.Index(q, "{")
+	}
+	if start < 0 {
+		return "", false
+	}
+
+	depth := 0
+	for j := start; j < len(q); j++ {
+		switch q[j] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return q[start+1 : j], true
+			}
+		}
+	}
+	return "", false
+}
+
+func isNameChar(b byte) bool {
+	return b == '_' || b == '$' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// POST_GraphQL answers the query enroute-dp fetches the configuration of
+// a proxy with (saaras.QGatewayHost) from DB, so that enroute-dp can be
+// pointed at enroute-cp whichever store it uses. Tokens scoped to proxies
+// may only fetch theirs.
+func POST_GraphQL(c echo.Context) error {
+	r := new(graphqlRequest)
+	if err := c.Bind(r); err != nil {
+		return err
+	}
+
+	proxy := r.Variables["proxy_name"]
+	if t := requestToken(c); t != nil && (len(t.Proxies) > 0 || len(t.Services) > 0) && !contains(t.Proxies, proxy) {
+		return apiErrors(c, http.StatusForbidden, &APIError{Code: ErrorForbidden, Message: "Token " + t.Name + " is not scoped to proxy " + proxy})
+	}
+
+	fields, ok := querySelection(r.Query, "saaras_db_proxy_service")
+	if !ok {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"errors": []map[string]string{{"message": "query not supported"}},
+		})
+	}
+
+	p, err := DB.ProxyDetail(proxy)
+	if err != nil && !isNotFound(err) {
+		return dbError(c, err)
+	}
+
+	rows := []proxyServiceRow{}
+	if p != nil {
+		for _, ps := range p.Services {
+			rows = append(rows, proxyServiceRow{
+				Proxy:   proxyGlobalConfigs{GlobalConfigs: p.GlobalConfigs},
+				Service: ps.Service,
+			})
+		}
+	}
+
+	return dbRows(c, http.StatusOK, "saaras_db_proxy_service", rows, fields)
+}
+
+func Add_graphql_routes(e *echo.Echo) {
+	e.POST(GraphQLPath, POST_GraphQL)
+}
@@ -0,0 +1,91 @@
+package webhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/saarasio/enroute/enroute-dp/saaras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuerySelection(t *testing.T) {
+	tests := map[string]struct {
+		query string
+		want  string
+		ok    bool
+	}{
+		"with arguments": {
+			query: `query q($p: String!) { t(where: {p: {_eq: $p}}) { a b { c } } }`,
+			want:  " a b { c } ",
+			ok:    true,
+		},
+		"without arguments": {
+			query: `query { t { a } }`,
+			want:  " a ",
+			ok:    true,
+		},
+		"other table": {
+			query: `query { u { a } }`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := querySelection(tc.query, "t")
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestPOSTGraphQL(t *testing.T) {
+	api := newTestAPI(t)
+	Add_graphql_routes(api.Echo)
+	db := DB
+
+	require.NoError(t, db.CreateProxy("gw"))
+	require.NoError(t, db.CreateService(&store.Service{Name: "svc", Fqdn: "example.com"}))
+	require.NoError(t, db.CreateRoute(&store.Route{Service: "svc", Name: "r", Prefix: "/"}))
+	require.NoError(t, db.CreateUpstream(&store.Upstream{Name: "u", IP: "10.0.0.1", Port: 8080, Weight: 100}))
+	require.NoError(t, db.CreateGlobalConfig(&store.GlobalConfig{Name: "g", Type: "globalconfig_ratelimit", Config: "{}"}))
+	require.NoError(t, db.AssociateRouteUpstream("svc", "r", "u"))
+	require.NoError(t, db.AssociateProxyService("gw", "svc"))
+	require.NoError(t, db.AssociateProxyGlobalConfig("gw", "g"))
+
+	fetch := func(proxy_name string) saaras.DataPayloadSaarasApp2 {
+		body, err := json.Marshal(map[string]interface{}{
+			"query":     saaras.QGatewayHost,
+			"variables": map[string]string{"proxy_name": proxy_name},
+		})
+		require.NoError(t, err)
+
+		rec := api.do(http.MethodPost, GraphQLPath, string(body))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var gr saaras.DataPayloadSaarasApp2
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&gr))
+		return gr
+	}
+
+	gr := fetch("gw")
+	assert.Empty(t, gr.Errors)
+	require.Len(t, gr.Data.Saaras_db_proxy_service, 1)
+
+	ps := gr.Data.Saaras_db_proxy_service[0]
+	assert.Equal(t, "svc", ps.Service.Service_name)
+	assert.Equal(t, "example.com", ps.Service.Fqdn)
+	require.Len(t, ps.Service.Routes, 1)
+	assert.Equal(t, "/", ps.Service.Routes[0].Route_prefix)
+	require.Len(t, ps.Service.Routes[0].Route_upstreams, 1)
+	assert.Equal(t, "10.0.0.1", ps.Service.Routes[0].Route_upstreams[0].Upstream.Upstream_ip)
+	assert.Equal(t, int32(8080), ps.Service.Routes[0].Route_upstreams[0].Upstream.Upstream_port)
+	require.Len(t, ps.Proxy.ProxyGlobalconfigs, 1)
+	assert.Equal(t, "globalconfig_ratelimit", ps.Proxy.ProxyGlobalconfigs[0].Globalconfig.GlobalconfigType)
+
+	gr = fetch("missing")
+	assert.Empty(t, gr.Errors)
+	assert.Empty(t, gr.Data.Saaras_db_proxy_service)
+}
@@ -2,229 +2,183 @@ package webhttp
 
 import (
 	"bytes"
+	"encoding/json"
 	"github.com/labstack/echo/v4"
+	"github.com/saarasio/enroute/enroute-cp/store"
 	"github.com/saarasio/enroute/enroute-dp/ratelim"
-	"github.com/saarasio/enroute/enroute-dp/saaras"
 	"github.com/saarasio/enroute/enroute-dp/saarasconfig"
 	"net/http"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
 
 type Proxy struct {
-	Name string `json:"name" xml:"name" form:"name" query:"name"`
+	Name string `json:"name" xml:"name" form:"name" query:"name" example:"gw"`
 }
 
+// Upstream is an upstream as it is posted. Its numbers are kept as sent in
+// forms, and may be sent in json as numbers or strings.
 type Upstream struct {
-	Upstream_name                       string `json:"upstream_name" xml:"upstream_name" form:"upstream_name" query:"upstream_name"`
-	Upstream_ip                         string `json:"upstream_ip" xml:"upstream_ip" form:"upstream_ip" query:"upstream_ip"`
-	Upstream_port                       string `json:"upstream_port" xml:"upstream_port" form:"upstream_port" query:"upstream_port"`
-	Upstream_hc_path                    string `json:"upstream_hc_path" xml:"upstream_hc_path" form:"upstream_hc_path" query:"upstream_hc_path"`
+	Upstream_name                       string `json:"upstream_name" xml:"upstream_name" form:"upstream_name" query:"upstream_name" example:"u1"`
+	Upstream_ip                         string `json:"upstream_ip" xml:"upstream_ip" form:"upstream_ip" query:"upstream_ip" example:"10.0.0.1"`
+	Upstream_port                       string `json:"upstream_port" xml:"upstream_port" form:"upstream_port" query:"upstream_port" swaggertype:"integer" example:"80"`
+	Upstream_hc_path                    string `json:"upstream_hc_path" xml:"upstream_hc_path" form:"upstream_hc_path" query:"upstream_hc_path" example:"/"`
 	Upstream_hc_host                    string `json:"upstream_hc_host" xml:"upstream_hc_host" form:"upstream_hc_host" query:"upstream_hc_host"`
-	Upstream_weight                     string `json:"upstream_weight" xml:"upstream_weight" form:"upstream_weight" query:"upstream_weight"`
-	Upstream_hc_intervalseconds         string `json:"upstream_hc_intervalseconds" xml:"upstream_hc_intervalseconds" form:"upstream_hc_intervalseconds" query:"upstream_hc_intervalseconds"`
-	Upstream_hc_unhealthythresholdcount string `json:"upstream_hc_unhealthythresholdcount" xml:"upstream_hc_unhealthythresholdcount" form:"upstream_hc_unhealthythresholdcount" query:"upstream_hc_unhealthythresholdcount"`
-	Upstream_hc_healthythresholdcount   string `json:"upstream_hc_healthythresholdcount" xml:"upstream_hc_healthythresholdcount" form:"upstream_hc_healthythresholdcount" query:"upstream_hc_healthythresholdcount"`
+	Upstream_weight                     string `json:"upstream_weight" xml:"upstream_weight" form:"upstream_weight" query:"upstream_weight" swaggertype:"integer" example:"100"`
+	Upstream_hc_intervalseconds         string `json:"upstream_hc_intervalseconds" xml:"upstream_hc_intervalseconds" form:"upstream_hc_intervalseconds" query:"upstream_hc_intervalseconds" swaggertype:"integer"`
+	Upstream_hc_unhealthythresholdcount string `json:"upstream_hc_unhealthythresholdcount" xml:"upstream_hc_unhealthythresholdcount" form:"upstream_hc_unhealthythresholdcount" query:"upstream_hc_unhealthythresholdcount" swaggertype:"integer"`
+	Upstream_hc_healthythresholdcount   string `json:"upstream_hc_healthythresholdcount" xml:"upstream_hc_healthythresholdcount" form:"upstream_hc_healthythresholdcount" query:"upstream_hc_healthythresholdcount" swaggertype:"integer"`
 	Upstream_strategy                   string `json:"upstream_strategy" xml:"upstream_strategy" form:"upstream_strategy" query:"upstream_strategy"`
 	Upstream_validation_cacertificate   string `json:"upstream_validation_cacertificate" xml:"upstream_validation_cacertificate" form:"upstream_validation_cacertificate" query:"upstream_validation_cacertificate"`
 	Upstream_validation_subjectname     string `json:"upstream_validation_subjectname" xml:"upstream_validation_subjectname" form:"upstream_validation_subjectname" query:"upstream_validation_subjectname"`
 	Upstream_protocol                   string `json:"upstream_protocol" xml:"upstream_protocol" form:"upstream_protocol" query:"upstream_protocol"`
-	Upstream_hc_timeoutseconds          string `json:"upstream_hc_timeoutseconds" xml:"upstream_hc_timeoutseconds" form:"upstream_hc_timeoutseconds" query:"upstream_hc_timeoutseconds"`
+	Upstream_hc_timeoutseconds          string `json:"upstream_hc_timeoutseconds" xml:"upstream_hc_timeoutseconds" form:"upstream_hc_timeoutseconds" query:"upstream_hc_timeoutseconds" swaggertype:"integer"`
 
 	// Upstream_config holds configuration in json. Use this config if present. Else, fallback to individual fields above
 	Upstream_config string `json:"upstream_config" xml:"upstream_config" form:"upstream_config" query:"upstream_config"`
 }
 
+// The fields of an upstream which hold numbers
+var upstreamIntFields = []string{
+	"upstream_port",
+	"upstream_weight",
+	"upstream_hc_intervalseconds",
+	"upstream_hc_timeoutseconds",
+	"upstream_hc_unhealthythresholdcount",
+	"upstream_hc_healthythresholdcount",
+}
+
+// UnmarshalJSON sets u from b, taking numbers for the fields which hold
+// numbers as well as strings.
+func (u *Upstream) UnmarshalJSON(b []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	for _, k := range upstreamIntFields {
+		var n json.Number
+		if v, ok := m[k]; ok && len(v) > 0 && v[0] != '"' && json.Unmarshal(v, &n) == nil {
+			m[k], _ = json.Marshal(n.String())
+		}
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	type upstream Upstream
+	return json.Unmarshal(b, (*upstream)(u))
+}
+
 type Secret struct {
-	Secret_name string `json:"secret_name" xml:"secret_name" form:"secret_name" query:"secret_name"`
+	Secret_name string `json:"secret_name" xml:"secret_name" form:"secret_name" query:"secret_name" example:"demo-secret"`
 	Secret_key  string `json:"secret_key" xml:"secret_key" form:"secret_key" query:"secret_key"`
 	Secret_cert string `json:"secret_cert" xml:"secret_cert" form:"secret_cert" query:"secret_cert"`
 	Secret_sni  string `json:"secret_sni" xml:"secret_sni" form:"secret_sni" query:"secret_sni"`
 
+	// Secret_tls_config holds the TLS parameters (saarasconfig.TLSConfig) in json of the service using this secret
+	Secret_tls_config string `json:"secret_tls_config" xml:"secret_tls_config" form:"secret_tls_config" query:"secret_tls_config"`
+
 	// Secret_config holds configuration in json. Use this config if present. Else, fallback to individual fields above
 	Secret_config string `json:"secret_config" xml:"secret_config" form:"secret_config" query:"secret_config"`
 }
 
 type GlobalConfig struct {
-	Globalconfig_name string `json:"globalconfig_name" xml:"globalconfig_name" form:"globalconfig_name" query:"globalconfig_name"`
-	Globalconfig_type string `json:"globalconfig_type" xml:"globalconfig_type" form:"globalconfig_type" query:"globalconfig_type"`
+	Globalconfig_name string `json:"globalconfig_name" xml:"globalconfig_name" form:"globalconfig_name" query:"globalconfig_name" example:"ratelimit"`
+	Globalconfig_type string `json:"globalconfig_type" xml:"globalconfig_type" form:"globalconfig_type" query:"globalconfig_type" example:"globalconfig_ratelimit"`
 	Config            string `json:"config" xml:"config" form:"config" query:"config"`
 }
 
-var QCreateProxy string = `
-    mutation create_proxy($proxy_name : String!){
-      insert_saaras_db_proxy(objects: {proxy_name: $proxy_name},
-        on_conflict: {constraint: proxy_proxy_name_key, update_columns: create_ts}) {
-        affected_rows
-      }
-    }
-`
+// formFile returns the content of the file uploaded as field of a form,
+// or of the field jsonField of a json body, or "" if it is not set.
+func formFile(c echo.Context, field, jsonField string) (string, error) {
+	req := c.Request()
+	if strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return "", echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+		}
+		var s string
+		if v, ok := body[jsonField]; ok {
+			if err := json.Unmarshal(v, &s); err != nil {
+				if te, ok := err.(*json.UnmarshalTypeError); ok {
+					te.Field = jsonField
+				}
+				return "", echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+			}
+		}
+		return s, nil
+	}
 
-var QGetProxy string = `
-query get_proxies {
-  saaras_db_proxy {
-    proxy_id
-    proxy_name
-    create_ts
-    update_ts
-  }
+	file, err := c.FormFile(field)
+	if err == http.ErrMissingFile {
+		return "", nil
+	}
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(src); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
 }
-`
 
-var QGetOneProxy string = `
-query get_proxies($proxy_name: String!) {
-  saaras_db_proxy(where: {proxy_name: {_eq: $proxy_name}}) {
-    proxy_id
-    proxy_name
-    create_ts
-    update_ts
-  }
-}
-`
+// Fields of the objects returned by the API
+const proxyFields = `proxy_id proxy_name create_ts update_ts`
 
-var QDeleteProxy string = `
-mutation delete_proxy($proxy_name: String!) {
-  delete_saaras_db_proxy(where: {proxy_name: {_eq: $proxy_name}}) {
-    affected_rows
-  }
-}
-`
+const serviceFields = `service_id service_name fqdn create_ts update_ts`
 
-var QCreateProxyService string = `
-      mutation create_proxy_service($proxy_name : String!, $fqdn : String!, $service_name : String!) {
-				# Create service
-        insert_saaras_db_service
-        (
-          objects:
-          {
-            fqdn: $fqdn,
-            service_name: $service_name
-          } on_conflict: {constraint: service_service_name_key, update_columns:[fqdn, service_name]}
-        )
-        {
-          returning
-          {
-            create_ts
+const proxyDetailFields = `
+    proxy_id
+    proxy_name
+    proxy_globalconfigs {
+      globalconfig {
+        globalconfig_name
+        globalconfig_type
+      }
+    }
+    proxy_services {
+      service {
+        service_id
+        service_name
+        fqdn
+        service_secrets {
+          secret {
+            secret_name
+            secret_key
+            secret_cert
           }
         }
-
-        # Associate a service to a proxy
-        insert_saaras_db_proxy_service(
-          objects:
-          {
-            proxy:
-            {
-              data:
-              {
-                proxy_name: $proxy_name
-              }, on_conflict: {constraint: proxy_proxy_name_key, update_columns: update_ts}
-            },
-            service:
-            {
-              data:
-              {
-                service_name: $service_name
-              }, on_conflict: {constraint: service_service_name_key, update_columns: update_ts}
+        routes {
+          route_id
+          route_name
+          route_prefix
+          route_filters {
+            filter {
+              filter_name
+              filter_type
             }
           }
-        )
-        {
-          affected_rows
-        }
-      }
-`
-
-var QGetProxyService string = `
-	query get_proxy_service($proxy_name: String!) {
-	  saaras_db_service(where: {proxy_services: {proxy: {proxy_name: {_eq: $proxy_name}}}}) {
-	    service_id
-	    service_name
-	    fqdn
-	    create_ts
-	    update_ts
-	  }
-	}
-`
-
-var QGetProxyServiceAssociation string = `
-query get_proxy_service($proxy_name: String!, $service_name: String!) {
-  saaras_db_service(where: {_and: 
-    {proxy_services: {proxy: {proxy_name: {_eq: $proxy_name}}}}, service_name: {_eq: $service_name}}) {
-    service_id
-    service_name
-    fqdn
-    create_ts
-    update_ts
-  }
-}
-`
-
-var QDeleteProxyService = `
-
-mutation delete_proxy_service($service_name: String!, $proxy_name: String!) {
-  delete_saaras_db_proxy_service(where: {
-		_and:
-			{
-				proxy: {proxy_name: {_eq: $proxy_name}}, 
-				service: {service_name: {_eq: $service_name}}
-			}
-		}) {
-    affected_rows
-  }
-  delete_saaras_db_service(where: {service_name: {_eq: $service_name}}) {
-    affected_rows
-  }
-}
-
-`
-
-var QDeleteProxyServiceAssociation = `
-
-mutation delete_proxy_service($service_name: String!, $proxy_name: String!) {
-  delete_saaras_db_proxy_service(where: {
-		_and:
-			{
-				proxy: {proxy_name: {_eq: $proxy_name}}, 
-				service: {service_name: {_eq: $service_name}}
-			}
-		}) {
-    affected_rows
-  }
-}
-`
-
-var QCreateProxyServiceAssociation = `
-      mutation create_proxy_service($proxy_name : String!, $service_name : String!) {
-        # Associate a service to a proxy
-        insert_saaras_db_proxy_service(
-          objects:
-          {
-            proxy:
-            {
-              data:
-              {
-                proxy_name: $proxy_name
-              }, on_conflict: {constraint: proxy_proxy_name_key, update_columns: update_ts}
-            },
-            service:
-            {
-              data:
-              {
-                service_name: $service_name
-              }, on_conflict: {constraint: service_service_name_key, update_columns: update_ts}
+          route_upstreams {
+            upstream {
+              upstream_name
+              upstream_ip
+              upstream_port
             }
           }
-        )
-        {
-          affected_rows
         }
       }
+    }
 `
 
-var QGetAllProxyDetail = `
-query get_proxy_detail {
-  saaras_db_proxy {
-    proxy_id
+const oneProxyDetailFields = `
     proxy_name
     proxy_globalconfigs {
       globalconfig {
@@ -234,9 +188,15 @@ query get_proxy_detail {
     }
     proxy_services {
       service {
-        service_id
         service_name
         fqdn
+        service_filters {
+          filter {
+            filter_name
+            filter_type
+            filter_config
+          }
+        }
         service_secrets {
           secret {
             secret_name
@@ -245,13 +205,13 @@ query get_proxy_detail {
           }
         }
         routes {
-          route_id
           route_name
           route_prefix
+          config_json
           route_filters {
             filter {
               filter_name
-             filter_type
+              filter_type
             }
           }
           route_upstreams {
@@ -264,27 +224,61 @@ query get_proxy_detail {
         }
       }
     }
-  }
-}
 `
 
 // Read from DB_HOST and DB_PORT environment variables
 var HOST string
 var PORT string
 var ID string
-var USAGE string
 var SECRET string
 
 func isGlobalConfigTypeValid(filter_type string) bool {
 	switch filter_type {
 	case saarasconfig.PROXY_CONFIG_RATELIMIT:
 		return true
+	case saarasconfig.PROXY_CONFIG_TLS:
+		return true
 	default:
 		return false
 	}
 	return false
 }
 
+// globalConfigJSON returns config of a global config of type
+// globalconfig_type in json, as saved in its config_json. It fails if a
+// TLS config is invalid.
+func globalConfigJSON(log *logrus.Entry, globalconfig_type, config string) (interface{}, error) {
+	var config_json interface{}
+	var err2 error
+
+	if globalconfig_type == saarasconfig.PROXY_CONFIG_TLS {
+		tc, err := saarasconfig.UnmarshalTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		config_json = tc
+	} else if len(config) > 0 && config != "" {
+		cfg, err := ratelim.UnmarshalRateLimitGlobalConfig(config)
+		err2 = err
+		if err == nil {
+			config_json = cfg
+		} else {
+			// TODO: bad config, return with error !!
+			log.Errorf("Failed to decode [%+v] \n", config)
+		}
+	}
+
+	if config_json == nil {
+		if err2 != nil {
+			var rlc ratelim.RateLimitGlobalConfig
+			rlc.Domain = "enroute"
+			config_json = rlc
+		}
+	}
+
+	return config_json, nil
+}
+
 // @Summary Create a proxy
 // @Description Create a proxy
 // @Tags proxy
@@ -292,30 +286,22 @@ func isGlobalConfigTypeValid(filter_type string) bool {
 // @Produce  json
 // @Param Name body webhttp.Proxy true "Name of proxy to create"
 // @Success 201 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /proxy [post]
 // @Security ApiKeyAuth
 func POST_Proxy(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	p := new(Proxy)
 	if err := c.Bind(p); err != nil {
 		return err
 	}
 
 	if len(p.Name) == 0 {
-		return c.JSON(http.StatusBadRequest, "Please provide name of proxy using Name field")
+		return badRequest(c, invalid("proxy", "", "name", "Please provide name of proxy using name field"))
 	}
 
-	args["proxy_name"] = p.Name
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	if err := saaras.RunDBQuery(url, QCreateProxy, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	if err := DB.CreateProxy(p.Name); err != nil {
+		return dbError(c, err)
 	}
 	return c.JSON(http.StatusCreated, p)
 }
@@ -329,19 +315,12 @@ func POST_Proxy(c echo.Context) error {
 // @Router /proxy [get]
 // @Security ApiKeyAuth
 func GET_Proxy(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQuery(url, QGetProxy, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	ps, err := DB.ListProxies()
+	if err != nil {
+		return dbError(c, err)
 	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return dbRows(c, http.StatusOK, "saaras_db_proxy", ps, proxyFields)
 }
 
 // @Summary Get all proxy details
@@ -353,19 +332,12 @@ func GET_Proxy(c echo.Context) error {
 // @Router /proxy/dump [get]
 // @Security ApiKeyAuth
 func GET_Proxy_Detail(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQuery(url, QGetAllProxyDetail, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	pds, err := DB.ListProxyDetails()
+	if err != nil {
+		return dbError(c, err)
 	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return dbRows(c, http.StatusOK, "saaras_db_proxy", pds, proxyDetailFields)
 }
 
 // @Summary Get details of specified proxy
@@ -378,75 +350,10 @@ func GET_Proxy_Detail(c echo.Context) error {
 // @Router /proxy/dump/{proxy_name} [get]
 // @Security ApiKeyAuth
 func GET_One_Proxy_Detail(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
-	var QGetOneProxyDetail = `
-query get_one_proxy_detail($proxy_name:String!) {
-  saaras_db_proxy(where: {proxy_name: {_eq: $proxy_name}}) {
-    proxy_name
-    proxy_globalconfigs {
-      globalconfig {
-        globalconfig_name
-        globalconfig_type
-      }
-    }
-    proxy_services {
-      service {
-        service_name
-        fqdn
-        service_filters{
-          filter {
-            filter_name
-            filter_type
-            filter_config
-          }
-        }
-        service_secrets {
-          secret {
-            secret_name
-            secret_key
-            secret_cert
-          }
-        }
-        routes {
-          route_name
-          route_prefix
-          config_json
-          route_filters {
-            filter {
-              filter_name
-              filter_type
-            }
-          }
-          route_upstreams {
-            upstream {
-              upstream_name
-              upstream_ip
-              upstream_port
-            }
-          }
-        }
-      }
-    }
-  }
-}
-
-`
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
 	proxy_name := c.Param("proxy_name")
-	args["proxy_name"] = proxy_name
-
-	if err := saaras.RunDBQuery(url, QGetOneProxyDetail, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	pd, err := DB.ProxyDetail(proxy_name)
+	return dbOne(c, http.StatusOK, "saaras_db_proxy", pd, err, oneProxyDetailFields)
 }
 
 // @Summary List services associated with proxy
@@ -459,21 +366,10 @@ query get_one_proxy_detail($proxy_name:String!) {
 // @Router /proxy/{proxy_name}/service [get]
 // @Security ApiKeyAuth
 func GET_Proxy_Service(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
 	proxy_name := c.Param("proxy_name")
 
-	args["proxy_name"] = proxy_name
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQuery(url, QGetProxyService, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
-
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	ss, err := DB.ListProxyServices(proxy_name)
+	return dbList(c, http.StatusOK, "saaras_db_service", ss, err, serviceFields)
 }
 
 // @Summary Get all globalconfigs in db
@@ -485,33 +381,13 @@ func GET_Proxy_Service(c echo.Context) error {
 // @Router /globalconfig [get]
 // @Security ApiKeyAuth
 func GET_GlobalConfig(c echo.Context) error {
-	var buf bytes.Buffer
-
-	var args map[string]string
-	args = make(map[string]string)
-
-	Q := `
-
-query get_all_globalconfig {
-    saaras_db_globalconfig {
-    globalconfig_id
-    globalconfig_name
-    globalconfig_type
-    config_json
-  }
-}
-    `
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	gcs, err := DB.ListGlobalConfigs()
+	if err != nil {
+		return dbError(c, err)
 	}
 
-	buf2 := bytes.Replace(buf.Bytes(), []byte("config_json"), []byte("config"), -1)
-
-	return c.JSONBlob(http.StatusOK, buf2)
+	return dbRows(c, http.StatusOK, "saaras_db_globalconfig", gcs,
+		"globalconfig_id globalconfig_name globalconfig_type config: config_json")
 }
 
 // @Summary Get globalconfigs for provided name
@@ -524,35 +400,11 @@ query get_all_globalconfig {
 // @Router /globalconfig/{globalconfig_name} [get]
 // @Security ApiKeyAuth
 func GET_One_GlobalConfig(c echo.Context) error {
-	var buf bytes.Buffer
-
-	var args map[string]string
-	args = make(map[string]string)
-
-	Q := `
-
-query get_all_globalconfig($globalconfig_name: String!) {
-  saaras_db_globalconfig(where: {globalconfig_name: {_eq: $globalconfig_name}}) {
-    globalconfig_id
-    globalconfig_name
-    globalconfig_type
-    config_json
-  }
-}
-
-`
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	globalconfig_name := c.Param("globalconfig_name")
-	args["globalconfig_name"] = globalconfig_name
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	gc, err := DB.GetGlobalConfig(globalconfig_name)
+	return dbOne(c, http.StatusOK, "saaras_db_globalconfig", gc, err,
+		"globalconfig_id globalconfig_name globalconfig_type config_json")
 }
 
 // @Summary Create globalconfig
@@ -561,35 +413,11 @@ query get_all_globalconfig($globalconfig_name: String!) {
 // @Accept  json
 // @Produce  json
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /globalconfig [post]
 // @Security ApiKeyAuth
 func POST_GlobalConfig(c echo.Context) error {
-	var buf bytes.Buffer
-
-	var args map[string]interface{}
-	args = make(map[string]interface{})
-
-	Q := `
-
-    mutation insert_into_globalconfig($globalconfig_name: String!, $globalconfig_type: String, $config: String!, $config_json: jsonb) {
-        insert_saaras_db_globalconfig
-        (
-            objects:
-            {
-                globalconfig_name: $globalconfig_name,
-                globalconfig_type: $globalconfig_type,
-                config: $config,
-                config_json: $config_json
-            },
-            on_conflict: { constraint: proxy_config_pkey, update_columns: update_ts }
-        ) 
-        {
-            affected_rows
-        }
-    }
-
-`
-
 	log2 := logrus.StandardLogger()
 	log := log2.WithField("context", "web-http")
 
@@ -599,133 +427,92 @@ func POST_GlobalConfig(c echo.Context) error {
 	}
 
 	if len(gc.Globalconfig_name) == 0 {
-		return c.JSON(http.StatusBadRequest, "Please provide name of Global config using Globalconfig_name field")
+		return badRequest(c, invalid("globalconfig", "", "globalconfig_name", "Please provide name of Global config using globalconfig_name field"))
 	}
 
 	if !isGlobalConfigTypeValid(gc.Globalconfig_type) {
-		return c.JSON(http.StatusBadRequest, "{\"Error\" : \"Invalid globalconfig type\"}")
+		return badRequest(c, invalid("globalconfig", gc.Globalconfig_name, "globalconfig_type", "Invalid globalconfig type %s", gc.Globalconfig_type))
 	}
 
-	args["globalconfig_name"] = gc.Globalconfig_name
-	args["globalconfig_type"] = gc.Globalconfig_type
-	args["config"] = gc.Config
-	var err2 error
-
-	if len(gc.Config) > 0 && gc.Config != "" {
-		cfg, err := ratelim.UnmarshalRateLimitGlobalConfig(gc.Config)
-		err2 = err
-		if err == nil {
-			args["config_json"] = cfg
-		} else {
-			// TODO: bad config, return with error !!
-			log.Errorf("Failed to decode [%+v] \n", gc.Config)
-		}
-	}
-
-	if _, ok := args["config_json"]; !ok {
-		if err2 != nil {
-			var rlc ratelim.RateLimitGlobalConfig
-			rlc.Domain = "enroute"
-			args["config_json"] = rlc
-		}
+	config_json, err := globalConfigJSON(log, gc.Globalconfig_type, gc.Config)
+	if err != nil {
+		return badRequest(c, invalid("globalconfig", gc.Globalconfig_name, "config", "Invalid TLS config: %v", err))
 	}
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQueryGenericVals(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	err = DB.CreateGlobalConfig(&store.GlobalConfig{
+		Name:       gc.Globalconfig_name,
+		Type:       gc.Globalconfig_type,
+		Config:     gc.Config,
+		ConfigJSON: rawJSON(config_json),
+	})
+	if err != nil {
+		return dbError(c, err)
 	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return dbAffected(c, http.StatusOK, "insert_saaras_db_globalconfig", 1)
 }
 
 // @Summary Set globalconfig from file
 // @Description Set globalconfig from file
+// @Description The config is uploaded as the Config file of a form, or sent as config in json
 // @Tags globalconfig
 // @Param globalconfig_name path string true "Name of globalconfig_name for which to list config"
-// @Accept  json
+// @Accept  mpfd,json
 // @Produce  json
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /globalconfig/{globalconfig_name}/config [post]
 // @Security ApiKeyAuth
 func POST_GlobalConfig_Config(c echo.Context) error {
-	var buf bytes.Buffer
-
-	var args map[string]interface{}
-	args = make(map[string]interface{})
-
-	Q := `
-
-    mutation update_globalconfig($globalconfig_name: String!, $config: String!, $config_json: jsonb) {
-  update_saaras_db_globalconfig (
-    where:
-    {
-      globalconfig_name: {_eq: $globalconfig_name}
-    }
-    _set:
-    {
-      config: $config
-      config_json: $config_json
-    }
-  )
-  {
-    affected_rows
-  }
-}
-
-`
 	log2 := logrus.StandardLogger()
 	log := log2.WithField("context", "web-http")
 
 	globalconfig_name := c.Param("globalconfig_name")
 
 	// Read config from file
-	file, err := c.FormFile("Config")
-	if file == nil {
-		return c.JSON(http.StatusBadRequest, "{\"Error\" : \"Config empty\"}")
-	}
-	src, err := file.Open()
+	config_from_file, err := formFile(c, "Config", "config")
 	if err != nil {
 		return err
 	}
-	defer src.Close()
-	buf2 := new(bytes.Buffer)
-	buf2.ReadFrom(src)
-	config_from_file := buf2.String()
-
-	// We already have the globalconfig_name from params
-	// gc_name := c.Param("globalconfig_name")
+	if config_from_file == "" {
+		return badRequest(c, invalid("globalconfig", globalconfig_name, "config", "Config empty"))
+	}
 
-	if len(globalconfig_name) == 0 {
-		return c.JSON(http.StatusBadRequest, "{\"Error\" : \"Please provide globalconfig name\"}")
+	gc, err := DB.GetGlobalConfig(globalconfig_name)
+	if err != nil {
+		return dbError(c, err)
 	}
 
-	args["globalconfig_name"] = globalconfig_name
-	args["config"] = config_from_file
+	var config_json interface{}
 
 	if len(config_from_file) > 0 {
 		cfg, err := ratelim.UnmarshalRateLimitGlobalConfig(config_from_file)
 		if err == nil {
-			args["config_json"] = cfg
+			config_json = cfg
 		} else {
 			// TODO: bad config, return with error !!
 			log.Errorf("Failed to decode [%+v] \n", config_from_file)
 		}
 	}
 
-	if _, ok := args["config_json"]; !ok {
-		args["config_json"] = struct {
+	if config_json == nil {
+		config_json = struct {
 			Domain string `json:"domain"`
 		}{
 			Domain: "enroute",
 		}
 	}
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQueryGenericVals(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	gc.Config = config_from_file
+	gc.ConfigJSON = rawJSON(config_json)
+
+	if err := DB.UpdateGlobalConfig(gc); err != nil {
+		return dbError(c, err)
 	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return dbAffected(c, http.StatusOK, "update_saaras_db_globalconfig", 1)
 }
 
 // @Summary Update globalconfig type
@@ -735,34 +522,11 @@ func POST_GlobalConfig_Config(c echo.Context) error {
 // @Accept  json
 // @Produce  json
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
 // @Router /globalconfig/{globalconfig_name} [patch]
 // @Security ApiKeyAuth
 func PATCH_GlobalConfig(c echo.Context) error {
-	var buf bytes.Buffer
-
-	var args map[string]interface{}
-	args = make(map[string]interface{})
-
-	Q := `
-    mutation update_globalconfig($globalconfig_name: String! $globalconfig_type: String!) {
-        update_saaras_db_globalconfig (
-            where:
-            {
-                globalconfig_name: {_eq: $globalconfig_name}
-            }
-            _set:
-            {
-                globalconfig_type: $globalconfig_type
-            }
-        )
-        {
-            affected_rows
-        }
-    }
-`
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	gc := new(GlobalConfig)
 	if err := c.Bind(gc); err != nil {
 		return err
@@ -771,18 +535,21 @@ func PATCH_GlobalConfig(c echo.Context) error {
 	globalconfig_name := c.Param("globalconfig_name")
 
 	if !isGlobalConfigTypeValid(gc.Globalconfig_type) {
-		return c.JSON(http.StatusBadRequest, "{\"Error\" : \"Invalid globalconfig type\"}")
+		return badRequest(c, invalid("globalconfig", globalconfig_name, "globalconfig_type", "Invalid globalconfig type %s", gc.Globalconfig_type))
 	}
 
-	args["globalconfig_name"] = globalconfig_name
-	args["globalconfig_type"] = gc.Globalconfig_type
+	gc_in_db, err := DB.GetGlobalConfig(globalconfig_name)
+	if err != nil {
+		return dbError(c, err)
+	}
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQueryGenericVals(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	gc_in_db.Type = gc.Globalconfig_type
+
+	if err := DB.UpdateGlobalConfig(gc_in_db); err != nil {
+		return dbError(c, err)
 	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return dbAffected(c, http.StatusOK, "update_saaras_db_globalconfig", 1)
 }
 
 // @Summary Delete globalconfig
@@ -792,42 +559,19 @@ func PATCH_GlobalConfig(c echo.Context) error {
 // @Accept  json
 // @Produce  json
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /globalconfig/{globalconfig_name} [delete]
 // @Security ApiKeyAuth
 func DELETE_GlobalConfig(c echo.Context) error {
-	var buf bytes.Buffer
-
-	var args map[string]string
-	args = make(map[string]string)
-
-	Q := `
-
-mutation delete_globalconfig($globalconfig_name: String!) {
-  delete_saaras_db_globalconfig (
-    where:
-    {
-      globalconfig_name: {_eq: $globalconfig_name}
-    }
-  )
-  {
-    affected_rows
-  }
-}
-
-`
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	globalconfig_name := c.Param("globalconfig_name")
-	args["globalconfig_name"] = globalconfig_name
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	if err := DB.DeleteGlobalConfig(globalconfig_name); err != nil {
+		return dbError(c, err)
 	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return dbAffected(c, http.StatusOK, "delete_saaras_db_globalconfig", 1)
 }
 
 // @Summary Associate a globalconfig with proxy
@@ -838,64 +582,23 @@ mutation delete_globalconfig($globalconfig_name: String!) {
 // @Accept  json
 // @Produce  json
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /proxy/{proxy_name}/globalconfig/{globalconfig_name} [post]
 // @Security ApiKeyAuth
 func POST_Proxy_GlobalConfig_Association(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	proxy_name := c.Param("proxy_name")
 	globalconfig_name := c.Param("globalconfig_name")
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	args["proxy_name"] = proxy_name
-	args["globalconfig_name"] = globalconfig_name
-
-	var Q = `
-
-mutation create_proxy_globalconfig($proxy_name: String!, $globalconfig_name: String!) {
-  insert_saaras_db_proxy_globalconfig(objects: 
-    {
-      proxy: 
-      {
-        data: 
-        {
-          proxy_name: $proxy_name
-        }, 
-        on_conflict: 
-        {
-          constraint: proxy_proxy_name_key, 
-          update_columns: update_ts
-        }
-      }, 
-      globalconfig: 
-      {
-        data: 
-        {
-          globalconfig_name: $globalconfig_name
-        }, 
-        on_conflict: 
-        {
-          constraint: globalconfig_globalconfig_name_key, 
-          update_columns: update_ts
-        }
-      }
-    }) {
-    affected_rows
-  }
-}
-
-    `
+	if err := DB.CreateProxy(proxy_name); err != nil {
+		return dbError(c, err)
+	}
 
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	if err := DB.AssociateProxyGlobalConfig(proxy_name, globalconfig_name); err != nil {
+		return dbError(c, err)
 	}
-	return c.JSONBlob(http.StatusCreated, buf.Bytes())
+	return dbAffected(c, http.StatusCreated, "insert_saaras_db_proxy_globalconfig", 1)
 }
 
 // @Summary Disassociate a globalconfig from proxy
@@ -906,44 +609,19 @@ mutation create_proxy_globalconfig($proxy_name: String!, $globalconfig_name: Str
 // @Accept  json
 // @Produce  json
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /proxy/{proxy_name}/globalconfig/{globalconfig_name} [delete]
 // @Security ApiKeyAuth
 func DELETE_Proxy_GlobalConfig_Association(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	proxy_name := c.Param("proxy_name")
 	globalconfig_name := c.Param("globalconfig_name")
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	args["proxy_name"] = proxy_name
-	args["globalconfig_name"] = globalconfig_name
-
-	var Q = `
-
-mutation delete_proxy_globalconfig($globalconfig_name: String!, $proxy_name: String!) {
-  delete_saaras_db_proxy_globalconfig(where: {
-        _and:
-            {
-                proxy: {proxy_name: {_eq: $proxy_name}},
-                globalconfig: {globalconfig_name: {_eq: $globalconfig_name}}
-            }
-        }) {
-    affected_rows
-  }
-}
-
-`
-
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	if err := DB.DisassociateProxyGlobalConfig(proxy_name, globalconfig_name); err != nil {
+		return dbError(c, err)
 	}
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return dbAffected(c, http.StatusOK, "delete_saaras_db_proxy_globalconfig", 1)
 }
 
 // @Summary Return specified globalconfig associated with this proxy
@@ -957,42 +635,11 @@ mutation delete_proxy_globalconfig($globalconfig_name: String!, $proxy_name: Str
 // @Router /proxy/{proxy_name}/globalconfig/{globalconfig_name} [get]
 // @Security ApiKeyAuth
 func GET_Proxy_GlobalConfig_Association(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
 	proxy_name := c.Param("proxy_name")
 
-	args["proxy_name"] = proxy_name
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	var Q = `
-
-query get_proxy_globalconfig2($proxy_name: String!) {
-  saaras_db_globalconfig(
-    where:
-    {
-      proxy_globalconfigs: {proxy: {proxy_name:{_eq: $proxy_name}}}
-    }
-  ) {
-    globalconfig_id
-    globalconfig_name
-    globalconfig_type
-    create_ts
-    update_ts
-  }
-}
-
-	`
-
-	if err := saaras.RunDBQuery(url, Q, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
-
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	gcs, err := DB.ListProxyGlobalConfigs(proxy_name)
+	return dbList(c, http.StatusOK, "saaras_db_globalconfig", gcs, err,
+		"globalconfig_id globalconfig_name globalconfig_type create_ts update_ts")
 }
 
 // @Summary Delete a proxy
@@ -1002,26 +649,19 @@ query get_proxy_globalconfig2($proxy_name: String!) {
 // @Accept  json
 // @Produce  json
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /proxy/{proxy_name} [delete]
 // @Security ApiKeyAuth
 func DELETE_Proxy(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	proxy_name := c.Param("proxy_name")
-	args["proxy_name"] = proxy_name
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQuery(url, QDeleteProxy, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	if err := DB.DeleteProxy(proxy_name); err != nil {
+		return dbError(c, err)
 	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
-
+	return dbAffected(c, http.StatusOK, "delete_saaras_db_proxy", 1)
 }
 
 // @Summary Get information about a proxy
@@ -1034,23 +674,10 @@ func DELETE_Proxy(c echo.Context) error {
 // @Router /proxy/{proxy_name} [get]
 // @Security ApiKeyAuth
 func GET_One_Proxy(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	proxy_name := c.Param("proxy_name")
-	args["proxy_name"] = proxy_name
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQuery(url, QGetOneProxy, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
-
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
 
+	p, err := DB.GetProxy(proxy_name)
+	return dbOne(c, http.StatusOK, "saaras_db_proxy", p, err, proxyFields)
 }
 
 // @Summary Associate a service with proxy
@@ -1061,28 +688,27 @@ func GET_One_Proxy(c echo.Context) error {
 // @Accept  json
 // @Produce  json
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /proxy/{proxy_name}/service/{service_name} [post]
 // @Security ApiKeyAuth
 func POST_Proxy_Service_Association(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	proxy_name := c.Param("proxy_name")
 	service_name := c.Param("service_name")
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	args["proxy_name"] = proxy_name
-	args["service_name"] = service_name
+	// The proxy and service are created if they do not exist
+	if err := DB.CreateProxy(proxy_name); err != nil {
+		return dbError(c, err)
+	}
+	if err := DB.CreateService(&store.Service{Name: service_name}); err != nil {
+		return dbError(c, err)
+	}
 
-	if err := saaras.RunDBQuery(url, QCreateProxyServiceAssociation, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	if err := DB.AssociateProxyService(proxy_name, service_name); err != nil {
+		return dbError(c, err)
 	}
-	return c.JSONBlob(http.StatusCreated, buf.Bytes())
+	return dbAffected(c, http.StatusCreated, "insert_saaras_db_proxy_service", 1)
 }
 
 // @Summary Disassociate a service from proxy
@@ -1093,28 +719,19 @@ func POST_Proxy_Service_Association(c echo.Context) error {
 // @Accept  json
 // @Produce  json
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /proxy/{proxy_name}/service/{service_name} [delete]
 // @Security ApiKeyAuth
 func DELETE_Proxy_Service_Association(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	proxy_name := c.Param("proxy_name")
 	service_name := c.Param("service_name")
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	args["proxy_name"] = proxy_name
-	args["service_name"] = service_name
-
-	if err := saaras.RunDBQuery(url, QDeleteProxyServiceAssociation, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	if err := DB.DisassociateProxyService(proxy_name, service_name); err != nil {
+		return dbError(c, err)
 	}
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return dbAffected(c, http.StatusOK, "delete_saaras_db_proxy_service", 1)
 }
 
 // @Summary Return specified service associated with this proxy
@@ -1128,25 +745,19 @@ func DELETE_Proxy_Service_Association(c echo.Context) error {
 // @Router /proxy/{proxy_name}/service/{service_name} [get]
 // @Security ApiKeyAuth
 func GET_Proxy_Service_Association(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
 	proxy_name := c.Param("proxy_name")
 	service_name := c.Param("service_name")
 
-	args["proxy_name"] = proxy_name
-	args["service_name"] = service_name
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
+	ss, err := DB.ListProxyServices(proxy_name)
 
-	if err := saaras.RunDBQuery(url, QGetProxyServiceAssociation, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	var found []store.Service
+	for _, s := range ss {
+		if s.Name == service_name {
+			found = append(found, s)
+		}
 	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return dbList(c, http.StatusOK, "saaras_db_service", found, err, serviceFields)
 }
 
 func GET_Health_Check(c echo.Context) error {
@@ -1187,6 +798,7 @@ func Add_proxy_routes(e *echo.Echo) {
 	// Support for operational-verbs
 	e.GET("/proxy/dump", GET_Proxy_Detail)
 	e.GET("/proxy/dump/:proxy_name", GET_One_Proxy_Detail)
+	e.PUT("/proxy/:proxy_name/config", PUT_Proxy_Config)
 
 	e.GET("/health", GET_Health_Check)
 }
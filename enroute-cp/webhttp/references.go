@@ -0,0 +1,222 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package webhttp
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/saarasio/enroute/enroute-cp/store"
+)
+
+// Upstreams, filters, secrets and services cannot be deleted while other
+// objects reference them. Deleting one responds with its references, or
+// with ?cascade=true removes the associations making them along with it,
+// all at once.
+
+// References are the objects referencing an object, directly or through
+// other objects, like the proxies serving a route using an upstream.
+// Routes are named service/route.
+type References struct {
+	Proxies  []string `json:"proxies"`
+	Services []string `json:"services"`
+	Routes   []string `json:"routes"`
+
+	// Secrets and Filters are associated with a service
+	Secrets []string `json:"secrets,omitempty"`
+	Filters []string `json:"filters,omitempty"`
+}
+
+// referenced is an object about to be deleted along with its references.
+type referenced struct {
+	kind, name string
+	refs       References
+
+	// users are the objects using the object directly, like route svc/r
+	users []string
+}
+
+func newReferenced(kind, name string) *referenced {
+	return &referenced{
+		kind: kind,
+		name: name,
+		refs: References{Proxies: []string{}, Services: []string{}, Routes: []string{}},
+	}
+}
+
+func (r *referenced) use(user string) {
+	r.users = append(r.users, user)
+}
+
+func appendName(names []string, name string) []string {
+	if contains(names, name) {
+		return names
+	}
+	return append(names, name)
+}
+
+// addRoutes adds rs and their services to the references of r.
+func (r *referenced) addRoutes(rs []store.Route) {
+	for _, rt := range rs {
+		r.refs.Routes = appendName(r.refs.Routes, rt.Service+"/"+rt.Name)
+		r.refs.Services = appendName(r.refs.Services, rt.Service)
+	}
+}
+
+// addServices adds ss to the references of r.
+func (r *referenced) addServices(ss []store.Service) {
+	for _, s := range ss {
+		r.refs.Services = appendName(r.refs.Services, s.Name)
+	}
+}
+
+// addProxies adds the proxies of the services referencing r, and sorts
+// the references.
+func (r *referenced) addProxies() error {
+	for _, s := range r.refs.Services {
+		ps, err := DB.ListServiceProxies(s)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+		for _, p := range ps {
+			r.refs.Proxies = appendName(r.refs.Proxies, p.Name)
+		}
+	}
+	sort.Strings(r.refs.Proxies)
+	sort.Strings(r.refs.Services)
+	sort.Strings(r.refs.Routes)
+	return nil
+}
+
+func upstreamReferences(name string) (*referenced, error) {
+	r := newReferenced("upstream", name)
+	rs, err := DB.ListUpstreamRoutes(name)
+	if err != nil {
+		return nil, err
+	}
+	r.addRoutes(rs)
+	for _, rt := range rs {
+		r.use("route " + rt.Service + "/" + rt.Name)
+	}
+	return r, r.addProxies()
+}
+
+func filterReferences(name string) (*referenced, error) {
+	r := newReferenced("filter", name)
+	ss, err := DB.ListFilterServices(name)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := DB.ListFilterRoutes(name)
+	if err != nil {
+		return nil, err
+	}
+	r.addServices(ss)
+	r.addRoutes(rs)
+	for _, s := range ss {
+		r.use("service " + s.Name)
+	}
+	for _, rt := range rs {
+		r.use("route " + rt.Service + "/" + rt.Name)
+	}
+	return r, r.addProxies()
+}
+
+func secretReferences(name string) (*referenced, error) {
+	r := newReferenced("secret", name)
+	ss, err := DB.ListSecretServices(name)
+	if err != nil {
+		return nil, err
+	}
+	r.addServices(ss)
+	for _, s := range ss {
+		r.use("service " + s.Name)
+	}
+	return r, r.addProxies()
+}
+
+// serviceReferences returns the proxies serving the service, along with
+// its routes, secrets and filters. Its routes are deleted by a cascade.
+func serviceReferences(name string) (*referenced, error) {
+	r := newReferenced("service", name)
+	sd, err := DB.ServiceDetail(name)
+	if err != nil {
+		return nil, err
+	}
+	ps, err := DB.ListServiceProxies(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range ps {
+		r.refs.Proxies = append(r.refs.Proxies, p.Name)
+		r.use("proxy " + p.Name)
+	}
+	for _, rd := range sd.Routes {
+		r.refs.Routes = append(r.refs.Routes, name+"/"+rd.Name)
+		r.use("route " + name + "/" + rd.Name)
+	}
+	for _, ss := range sd.Secrets {
+		r.refs.Secrets = append(r.refs.Secrets, ss.Secret.Name)
+		r.use("secret " + ss.Secret.Name)
+	}
+	for _, sf := range sd.Filters {
+		r.refs.Filters = append(r.refs.Filters, sf.Filter.Name)
+		r.use("filter " + sf.Filter.Name)
+	}
+	return r, nil
+}
+
+// dbDeleteReferenced deletes the object found by references with del,
+// responding with its references if it is in use, unless the request
+// cascades to them, deleting it along with its associations.
+func dbDeleteReferenced(c echo.Context, references func(string) (*referenced, error), name string, del func(string) error, mutation string) error {
+	cascade := false
+	if v := c.QueryParam("cascade"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return badRequest(c, invalid("", "", "cascade", "Invalid cascade value %s", v))
+		}
+		cascade = b
+	}
+
+	r, err := references(name)
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	if len(r.users) > 0 && !cascade {
+		return c.JSON(http.StatusConflict, ErrorResponse{
+			Errors: []*APIError{{
+				Code:    ErrorInUse,
+				Message: r.kind + " " + r.name + " is used by " + strings.Join(r.users, ", ") + ", delete it with cascade=true to remove them first",
+				Object:  r.kind + ":" + r.name,
+			}},
+			References: &r.refs,
+		})
+	}
+
+	if cascade {
+		err = DB.DeleteCascade(r.kind, name)
+	} else {
+		err = del(name)
+	}
+	if err != nil {
+		return dbError(c, err)
+	}
+	return dbAffected(c, http.StatusOK, mutation, 1)
+}
+
+// dbReferences responds with the references of the object found by
+// references.
+func dbReferences(c echo.Context, references func(string) (*referenced, error), name string) error {
+	r, err := references(name)
+	if err != nil {
+		return dbError(c, err)
+	}
+	return c.JSON(http.StatusOK, r.refs)
+}
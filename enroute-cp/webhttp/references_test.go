@@ -0,0 +1,101 @@
+package webhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteReferenced(t *testing.T) {
+	api := newTestAPI(t)
+	Add_upstream_routes(api.Echo)
+	Add_filter_routes(api.Echo)
+	Add_secret_routes(api.Echo)
+	Add_service_routes(api.Echo)
+
+	require.NoError(t, DB.CreateProxy("gw"))
+	require.NoError(t, DB.CreateService(&store.Service{Name: "svc", Fqdn: "example.com"}))
+	require.NoError(t, DB.CreateService(&store.Service{Name: "other", Fqdn: "other.example.com"}))
+	require.NoError(t, DB.CreateRoute(&store.Route{Service: "svc", Name: "r", Prefix: "/"}))
+	require.NoError(t, DB.CreateUpstream(&store.Upstream{Name: "u", IP: "10.0.0.1", Port: 80}))
+	require.NoError(t, DB.CreateUpstream(&store.Upstream{Name: "unused", IP: "10.0.0.2", Port: 80}))
+	require.NoError(t, DB.CreateSecret(&store.Secret{Name: "s"}))
+	require.NoError(t, DB.CreateFilter(&store.Filter{Name: "f", Type: "http_filter_lua"}))
+	require.NoError(t, DB.AssociateProxyService("gw", "svc"))
+	require.NoError(t, DB.AssociateRouteUpstream("svc", "r", "u"))
+	require.NoError(t, DB.AssociateRouteFilter("svc", "r", "f"))
+	require.NoError(t, DB.AssociateServiceFilter("other", "f"))
+	require.NoError(t, DB.AssociateServiceSecret("svc", "s"))
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		return api.do(method, path, "")
+	}
+
+	tests := []struct {
+		object string
+		path   string
+		want   References
+	}{{
+		object: "upstream:u",
+		path:   "/upstream/u",
+		want:   References{Proxies: []string{"gw"}, Services: []string{"svc"}, Routes: []string{"svc/r"}},
+	}, {
+		object: "filter:f",
+		path:   "/filter/f",
+		want:   References{Proxies: []string{"gw"}, Services: []string{"other", "svc"}, Routes: []string{"svc/r"}},
+	}, {
+		object: "secret:s",
+		path:   "/secret/s",
+		want:   References{Proxies: []string{"gw"}, Services: []string{"svc"}, Routes: []string{}},
+	}, {
+		object: "service:svc",
+		path:   "/service/svc",
+		// The associations of the route were removed with the upstream
+		// and filter above
+		want: References{Proxies: []string{"gw"}, Services: []string{}, Routes: []string{"svc/r"}},
+	}}
+
+	// Deletes cascade in order, so that the references of each object
+	// are those left by the previous ones
+	for _, tc := range tests {
+		t.Run(tc.object, func(t *testing.T) {
+			rec := do(http.MethodGet, tc.path+"/references")
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			var refs References
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&refs))
+			assert.Equal(t, tc.want, refs)
+
+			rec = do(http.MethodDelete, tc.path)
+			require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+			var resp ErrorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, ErrorInUse, resp.Errors[0].Code)
+			assert.Equal(t, tc.object, resp.Errors[0].Object)
+			if assert.NotNil(t, resp.References) {
+				assert.Equal(t, tc.want, *resp.References)
+			}
+
+			assert.Equal(t, http.StatusBadRequest, do(http.MethodDelete, tc.path+"?cascade=maybe").Code)
+			rec = do(http.MethodDelete, tc.path+"?cascade=true")
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.Equal(t, http.StatusNotFound, do(http.MethodGet, tc.path+"/references").Code)
+		})
+	}
+
+	// The service was removed from its proxy, and its route deleted
+	ps, err := DB.ListProxyServices("gw")
+	require.NoError(t, err)
+	assert.Empty(t, ps)
+	_, err = DB.GetRoute("svc", "r")
+	assert.True(t, isNotFound(err))
+
+	// Objects which are not referenced are deleted without cascade
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/upstream/unused").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/upstream/unused").Code)
+}
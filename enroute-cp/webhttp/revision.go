@@ -0,0 +1,487 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package webhttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/sirupsen/logrus"
+)
+
+// Every change to the configuration of a proxy is saved as a revision
+// holding its whole configuration, as in GET /proxy/dump/:proxy_name but
+// without ids and timestamps, which change without the configuration
+// changing. A proxy can be rolled back to any of its revisions.
+
+// The fields of each object kept in revisions
+const (
+	revisionServiceFields      = `service_name fqdn`
+	revisionRouteFields        = `route_name route_prefix route_config config_json`
+	revisionSecretFields       = `secret_name secret_key secret_cert secret_sni secret_tls_config`
+	revisionFilterFields       = `filter_name filter_type filter_config config_json`
+	revisionGlobalConfigFields = `globalconfig_name globalconfig_type config config_json`
+	revisionUpstreamFields     = `upstream_name upstream_ip upstream_port upstream_weight
+    upstream_hc_path upstream_hc_host upstream_hc_intervalseconds upstream_hc_timeoutseconds
+    upstream_hc_unhealthythresholdcount upstream_hc_healthythresholdcount upstream_strategy
+    upstream_validation_cacertificate upstream_validation_subjectname upstream_protocol`
+)
+
+const revisionConfigFields = `proxy_name
+  proxy_globalconfigs { globalconfig { ` + revisionGlobalConfigFields + ` } }
+  proxy_services { service { ` + revisionServiceFields + `
+    routes { ` + revisionRouteFields + `
+      route_upstreams { upstream { ` + revisionUpstreamFields + ` } }
+      route_filters { filter { ` + revisionFilterFields + ` } } }
+    service_secrets { secret { ` + revisionSecretFields + ` } }
+    service_filters { filter { ` + revisionFilterFields + ` } } } }`
+
+const revisionFields = `revision_id proxy_name author diff create_ts`
+
+// revisionMu serializes saving revisions, so that a change made by
+// concurrent requests is saved once.
+var revisionMu sync.Mutex
+
+// revisionConfig returns the configuration of the proxy pd kept in
+// revisions.
+func revisionConfig(pd *store.ProxyDetail) (json.RawMessage, error) {
+	v, err := dbPick(pd, revisionConfigFields)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// revisionText returns config indented to be diffed, with secret keys
+// replaced by their digest.
+func revisionText(config json.RawMessage) string {
+	if len(config) == 0 {
+		return ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(config, &v); err != nil {
+		return string(config)
+	}
+	b, err := json.MarshalIndent(redactKeys(v), "", "  ")
+	if err != nil {
+		return string(config)
+	}
+	return string(b) + "\n"
+}
+
+func redactKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		for i := range v {
+			v[i] = redactKeys(v[i])
+		}
+	case map[string]interface{}:
+		for k, fv := range v {
+			if s, ok := fv.(string); ok && k == "secret_key" && s != "" {
+				sum := sha256.Sum256([]byte(s))
+				v[k] = "sha256:" + hex.EncodeToString(sum[:8])
+				continue
+			}
+			v[k] = redactKeys(fv)
+		}
+	}
+	return v
+}
+
+func revisionName(r *store.Revision) string {
+	if r == nil {
+		return "none"
+	}
+	return fmt.Sprintf("revision %d", r.ID)
+}
+
+// latestRevision returns the latest revision of proxy, or nil if it has none.
+func latestRevision(proxy string) (*store.Revision, error) {
+	r, err := DB.LatestRevision(proxy)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	return r, err
+}
+
+// saveRevision saves a revision of the proxy pd if its configuration
+// changed since its latest revision, returning true if it did.
+func saveRevision(pd *store.ProxyDetail, author string) (bool, error) {
+	config, err := revisionConfig(pd)
+	if err != nil {
+		return false, err
+	}
+	prev, err := latestRevision(pd.Name)
+	if err != nil {
+		return false, err
+	}
+
+	var prevText string
+	if prev != nil {
+		prevText = revisionText(prev.Config)
+	}
+	text := revisionText(config)
+	if text == prevText {
+		return false, nil
+	}
+
+	return true, DB.CreateRevision(&store.Revision{
+		Proxy:  pd.Name,
+		Author: author,
+		Config: config,
+		Diff:   unifiedDiff(revisionName(prev), "current", prevText, text),
+	})
+}
+
+// saveRevisions saves a revision of the proxies named, or of every proxy
+// if names is nil, whose configuration changed, returning their names.
+func saveRevisions(author string, names []string) ([]string, error) {
+	var pds []store.ProxyDetail
+	if names == nil {
+		var err error
+		if pds, err = DB.ListProxyDetails(); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range names {
+		pd, err := DB.ProxyDetail(name)
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		pds = append(pds, *pd)
+	}
+
+	revised := []string{}
+	for i := range pds {
+		saved, err := saveRevision(&pds[i], author)
+		if err != nil {
+			return revised, err
+		}
+		if saved {
+			revised = append(revised, pds[i].Name)
+		}
+	}
+	return revised, nil
+}
+
+// referencingProxies returns the proxies referencing the object found by
+// references, or none if it does not exist.
+func referencingProxies(references func(string) (*referenced, error), name string) ([]string, error) {
+	r, err := references(name)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.refs.Proxies, nil
+}
+
+// touchedProxies adds to names the proxies using the objects ids, whose
+// configuration changes when those objects do.
+func touchedProxies(names []string, ids map[string]string) ([]string, error) {
+	add := func(ps []string, err error) error {
+		for _, p := range ps {
+			names = appendName(names, p)
+		}
+		return err
+	}
+	serviceProxies := func(service string) ([]string, error) {
+		ps, err := DB.ListServiceProxies(service)
+		if isNotFound(err) {
+			return nil, nil
+		}
+		ns := []string{}
+		for _, p := range ps {
+			ns = append(ns, p.Name)
+		}
+		return ns, err
+	}
+	globalConfigProxies := func(globalconfig string) ([]string, error) {
+		ps, err := DB.ListProxies()
+		if err != nil {
+			return nil, err
+		}
+		ns := []string{}
+		for _, p := range ps {
+			gcs, err := DB.ListProxyGlobalConfigs(p.Name)
+			if err != nil && !isNotFound(err) {
+				return nil, err
+			}
+			for _, gc := range gcs {
+				if gc.Name == globalconfig {
+					ns = append(ns, p.Name)
+				}
+			}
+		}
+		return ns, nil
+	}
+
+	for kind, name := range ids {
+		var err error
+		switch kind {
+		case "proxy":
+			err = add([]string{name}, nil)
+		case "service":
+			err = add(serviceProxies(name))
+		case "upstream":
+			err = add(referencingProxies(upstreamReferences, name))
+		case "filter":
+			err = add(referencingProxies(filterReferences, name))
+		case "secret":
+			err = add(referencingProxies(secretReferences, name))
+		case "globalconfig":
+			err = add(globalConfigProxies(name))
+		}
+		if err != nil {
+			return names, err
+		}
+	}
+	return names, nil
+}
+
+// SaveRevisions saves a revision of every proxy whose configuration was
+// changed by author outside of a request, like the renewal of a
+// certificate, returning their names.
+func SaveRevisions(author string) []string {
+	revisionMu.Lock()
+	defer revisionMu.Unlock()
+
+	revised, err := saveRevisions(author, nil)
+	if err != nil {
+		log := logrus.StandardLogger().WithField("context", "web-http")
+		log.Errorf("Error when saving revisions [%v]\n", err)
+	}
+	configChanges.notify()
+	return revised
+}
+
+// revisedKey is where the names of the proxies revised after a request
+// are kept in its context
+const revisedKey = "revised"
+
+// revisedProxies returns the names of the proxies whose configuration
+// was changed by the request c.
+func revisedProxies(c echo.Context) []string {
+	revised, _ := c.Get(revisedKey).([]string)
+	if revised == nil {
+		return []string{}
+	}
+	return revised
+}
+
+// RecordRevisions is a middleware saving a revision of every proxy whose
+// configuration was changed by a request, and waking up the streams
+// watching them. The author of the revisions is the token the request was
+// made with, or else the address it came from.
+//
+// Only the proxies using the objects the request names, before or after
+// it, are revised, or every proxy if it names none.
+func RecordRevisions(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		switch c.Request().Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return next(c)
+		}
+		if c.Request().URL.Path == GraphQLPath {
+			return next(c)
+		}
+
+		// Objects deleted by the request no longer reference their
+		// proxies after it
+		ids := auditObjects(c)
+		var names []string
+		var err error
+		if len(ids) > 0 {
+			if names, err = touchedProxies([]string{}, ids); err != nil {
+				c.Logger().Errorf("Error when finding proxies to revise [%v]", err)
+				names = nil
+			}
+		}
+
+		if err := next(c); err != nil || c.Response().Status >= http.StatusMultipleChoices {
+			return err
+		}
+
+		if names != nil {
+			if names, err = touchedProxies(names, ids); err != nil {
+				c.Logger().Errorf("Error when finding proxies to revise [%v]", err)
+				names = nil
+			}
+		}
+
+		revisionMu.Lock()
+		revised, err := saveRevisions(requestAuthor(c), names)
+		revisionMu.Unlock()
+		if err != nil {
+			c.Logger().Errorf("Error when saving revisions [%v]", err)
+		}
+		c.Set(revisedKey, revised)
+		configChanges.notify()
+		return nil
+	}
+}
+
+// revisionID returns the revision id in the path parameter param.
+func revisionID(c echo.Context, param string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(param), 10, 64)
+	return id, err == nil
+}
+
+func badRevisionID(c echo.Context, param string) error {
+	return badRequest(c, invalid("", "", param, "Invalid revision id %s", c.Param(param)))
+}
+
+// @Summary List revisions of a proxy
+// @Description Get the revisions of the configuration of a proxy, oldest first, with the changes each made
+// @Tags proxy, revision
+// @Accept  json
+// @Produce  json
+// @Param proxy_name path string true "Name of proxy"
+// @Success 200 {} int OK
+// @Router /proxy/{proxy_name}/revision [get]
+// @Security ApiKeyAuth
+func GET_Proxy_Revision(c echo.Context) error {
+	proxy_name := c.Param("proxy_name")
+	rs, err := DB.ListRevisions(proxy_name)
+	return dbList(c, http.StatusOK, "saaras_db_revision", rs, err, revisionFields)
+}
+
+// @Summary Get a revision of a proxy
+// @Description Get a revision of a proxy along with the configuration it holds
+// @Tags proxy, revision
+// @Accept  json
+// @Produce  json
+// @Param proxy_name path string true "Name of proxy"
+// @Param revision_id path int true "Id of revision"
+// @Success 200 {} int OK
+// @Router /proxy/{proxy_name}/revision/{revision_id} [get]
+// @Security ApiKeyAuth
+func GET_One_Proxy_Revision(c echo.Context) error {
+	id, ok := revisionID(c, "revision_id")
+	if !ok {
+		return badRevisionID(c, "revision_id")
+	}
+	r, err := DB.GetRevision(c.Param("proxy_name"), id)
+	return dbOne(c, http.StatusOK, "saaras_db_revision", r, err, revisionFields+" config")
+}
+
+// @Summary Diff two revisions of a proxy
+// @Description Get the changes to the configuration of a proxy from one revision to another
+// @Tags proxy, revision
+// @Accept  json
+// @Produce  json
+// @Param proxy_name path string true "Name of proxy"
+// @Param revision_id path int true "Id of revision to diff from"
+// @Param to_revision_id path int true "Id of revision to diff to"
+// @Success 200 {} int OK
+// @Router /proxy/{proxy_name}/revision/{revision_id}/diff/{to_revision_id} [get]
+// @Security ApiKeyAuth
+func GET_Proxy_Revision_Diff(c echo.Context) error {
+	proxy_name := c.Param("proxy_name")
+	from, ok := revisionID(c, "revision_id")
+	if !ok {
+		return badRevisionID(c, "revision_id")
+	}
+	to, ok := revisionID(c, "to_revision_id")
+	if !ok {
+		return badRevisionID(c, "to_revision_id")
+	}
+
+	rf, err := DB.GetRevision(proxy_name, from)
+	if err != nil {
+		return dbError(c, err)
+	}
+	rt, err := DB.GetRevision(proxy_name, to)
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"from": from,
+		"to":   to,
+		"diff": unifiedDiff(revisionName(rf), revisionName(rt), revisionText(rf.Config), revisionText(rt.Config)),
+	})
+}
+
+// @Summary Roll back a proxy to a revision
+// @Description Restore the configuration of a proxy held in a revision, recording it as a new revision
+// @Tags proxy, revision
+// @Accept  json
+// @Produce  json
+// @Param proxy_name path string true "Name of proxy"
+// @Param revision_id path int true "Id of revision to roll back to"
+// @Success 201 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
+// @Router /proxy/{proxy_name}/revision/{revision_id}/rollback [post]
+// @Security ApiKeyAuth
+func POST_Proxy_Revision_Rollback(c echo.Context) error {
+	proxy_name := c.Param("proxy_name")
+	id, ok := revisionID(c, "revision_id")
+	if !ok {
+		return badRevisionID(c, "revision_id")
+	}
+
+	rev, err := DB.GetRevision(proxy_name, id)
+	if err != nil {
+		return dbError(c, err)
+	}
+	var pd store.ProxyDetail
+	if err := json.Unmarshal(rev.Config, &pd); err != nil {
+		return dbError(c, err)
+	}
+	pd.Name = proxy_name
+
+	msg, err := tokenAllowsConfig(c, &pd)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if msg != "" {
+		return apiErrors(c, http.StatusForbidden, &APIError{Code: ErrorForbidden, Message: msg})
+	}
+
+	var cur string
+	p, err := DB.ProxyDetail(proxy_name)
+	switch {
+	case err == nil:
+		config, err := revisionConfig(p)
+		if err != nil {
+			return dbError(c, err)
+		}
+		cur = revisionText(config)
+	case !isNotFound(err):
+		return dbError(c, err)
+	}
+
+	r := &store.Revision{
+		Proxy:  proxy_name,
+		Author: requestAuthor(c),
+		Config: rev.Config,
+		Diff:   unifiedDiff("current", revisionName(rev), cur, revisionText(rev.Config)),
+	}
+	if err := DB.RestoreProxy(&pd, r); err != nil {
+		return dbError(c, err)
+	}
+
+	return dbRows(c, http.StatusCreated, "saaras_db_revision", []*store.Revision{r}, revisionFields)
+}
+
+func Add_revision_routes(e *echo.Echo) {
+	e.GET("/proxy/:proxy_name/revision", GET_Proxy_Revision)
+	e.GET("/proxy/:proxy_name/revision/:revision_id", GET_One_Proxy_Revision)
+	e.GET("/proxy/:proxy_name/revision/:revision_id/diff/:to_revision_id", GET_Proxy_Revision_Diff)
+	e.POST("/proxy/:proxy_name/revision/:revision_id/rollback", POST_Proxy_Revision_Rollback)
+}
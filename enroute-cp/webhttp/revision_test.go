@@ -0,0 +1,129 @@
+package webhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type revisionsResponse struct {
+	Data struct {
+		Saaras_db_revision []store.Revision `json:"saaras_db_revision"`
+	} `json:"data"`
+}
+
+func TestRevisions(t *testing.T) {
+	api := newTestAPI(t, RecordRevisions)
+	Add_proxy_routes(api.Echo)
+	Add_service_routes(api.Echo)
+	Add_upstream_routes(api.Echo)
+	Add_revision_routes(api.Echo)
+	do := api.do
+
+	revisions := func() []store.Revision {
+		rec := do(http.MethodGet, "/proxy/gw/revision", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var rr revisionsResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&rr))
+		return rr.Data.Saaras_db_revision
+	}
+
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/proxy", `{"name": "gw"}`).Code)
+	require.Len(t, revisions(), 1)
+
+	require.Less(t, do(http.MethodPost, "/service", `{"service_name": "svc", "fqdn": "example.com"}`).Code, 300)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/proxy/gw/service/svc", "").Code)
+	require.Less(t, do(http.MethodPost, "/upstream", `{"upstream_name": "u", "upstream_ip": "10.0.0.1", "upstream_port": "80", "upstream_weight": "100", "upstream_hc_path": "/"}`).Code, 300)
+	require.Less(t, do(http.MethodPost, "/service/svc/route", `{"route_name": "r", "route_prefix": "/"}`).Code, 300)
+	require.Less(t, do(http.MethodPost, "/service/svc/route/r/upstream/u", "").Code, 300)
+
+	// Reading does not save revisions
+	do(http.MethodGet, "/proxy/dump/gw", "")
+
+	rs := revisions()
+	require.Len(t, rs, 4)
+	before := rs[len(rs)-1]
+	assert.Regexp(t, `\n\+\s+"upstream_ip": "10.0.0.1"`, before.Diff)
+
+	require.Less(t, do(http.MethodPatch, "/upstream/u", `{"upstream_ip": "10.0.0.2"}`).Code, 300)
+	rs = revisions()
+	require.Len(t, rs, 5)
+	after := rs[len(rs)-1]
+	assert.Regexp(t, `\n-\s+"upstream_ip": "10.0.0.1"`, after.Diff)
+	assert.Regexp(t, `\n\+\s+"upstream_ip": "10.0.0.2"`, after.Diff)
+
+	rec := do(http.MethodGet, "/proxy/gw/revision/"+strconv.FormatInt(before.ID, 10)+"/diff/"+strconv.FormatInt(after.ID, 10), "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var diff struct {
+		Diff string `json:"diff"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&diff))
+	assert.Equal(t, strings.SplitN(after.Diff, "\n", 3)[2], strings.SplitN(diff.Diff, "\n", 3)[2])
+
+	rec = do(http.MethodPost, "/proxy/gw/revision/"+strconv.FormatInt(before.ID, 10)+"/rollback", "")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	u, err := DB.GetUpstream("u")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", u.IP)
+
+	// The rollback is a revision of its own
+	rs = revisions()
+	require.Len(t, rs, 6)
+	r1, err := DB.GetRevision("gw", before.ID)
+	require.NoError(t, err)
+	r2, err := DB.GetRevision("gw", rs[5].ID)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(r1.Config), string(r2.Config))
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/proxy/gw/revision/1000/rollback", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/proxy/gw/revision/x", "").Code)
+
+	// Proxies which no longer use an object deleted are revised
+	require.Equal(t, http.StatusOK, do(http.MethodDelete, "/upstream/u?cascade=true", "").Code)
+	rs = revisions()
+	require.Len(t, rs, 7)
+	assert.Regexp(t, `\n-\s+"upstream_ip": "10.0.0.1"`, rs[6].Diff)
+}
+
+func TestTouchedProxies(t *testing.T) {
+	newTestAPI(t)
+	db := DB
+
+	for _, p := range []string{"gw", "other", "idle"} {
+		require.NoError(t, db.CreateProxy(p))
+	}
+	require.NoError(t, db.CreateService(&store.Service{Name: "svc", Fqdn: "example.com"}))
+	require.NoError(t, db.CreateRoute(&store.Route{Service: "svc", Name: "r", Prefix: "/"}))
+	require.NoError(t, db.CreateUpstream(&store.Upstream{Name: "u", IP: "10.0.0.1", Port: 8080, Weight: 100}))
+	require.NoError(t, db.CreateGlobalConfig(&store.GlobalConfig{Name: "g", Type: "globalconfig_ratelimit", Config: "{}"}))
+	require.NoError(t, db.AssociateRouteUpstream("svc", "r", "u"))
+	require.NoError(t, db.AssociateProxyService("gw", "svc"))
+	require.NoError(t, db.AssociateProxyGlobalConfig("other", "g"))
+
+	tests := map[string]struct {
+		ids  map[string]string
+		want []string
+	}{
+		"proxy":        {map[string]string{"proxy": "idle"}, []string{"idle"}},
+		"service":      {map[string]string{"service": "svc"}, []string{"gw"}},
+		"route":        {map[string]string{"service": "svc", "route": "r"}, []string{"gw"}},
+		"upstream":     {map[string]string{"upstream": "u"}, []string{"gw"}},
+		"globalconfig": {map[string]string{"globalconfig": "g"}, []string{"other"}},
+		"unused":       {map[string]string{"upstream": "missing"}, []string{}},
+		"token":        {map[string]string{"token": "ci"}, []string{}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := touchedProxies([]string{}, tc.ids)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tc.want, got)
+		})
+	}
+}
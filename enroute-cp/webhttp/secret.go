@@ -4,98 +4,34 @@
 package webhttp
 
 import (
-	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"github.com/labstack/echo/v4"
-	"github.com/saarasio/enroute/enroute-dp/saaras"
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/saarasio/enroute/enroute-dp/saarasconfig"
 	"net/http"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
-var QCreateSecret = `
-mutation insert_secret($secret_name:String!, $secret_key:String!, $secret_cert:String, $secret_sni:String)
-{
-  insert_saaras_db_secret(
-    objects: 
-    {
-      secret_name: $secret_name, 
-      secret_key: $secret_key, 
-      secret_cert: $secret_cert, 
-      secret_sni: $secret_sni
-    }
-  ) 
-  {
-    affected_rows
-  }
-}
-`
-
-var QCreateSecretKey = `
-mutation insert_secret($secret_name: String!, $secret_key: String!) {
-  update_saaras_db_secret(
-    where: {secret_name: {_eq: $secret_name}}, 
-    _set: {secret_key: $secret_key}) {
-    affected_rows
-  }
-}
-`
-
-var QGetSecret = `
-query get_secret {
-  saaras_db_secret {
-    secret_name
-    secret_key
-    secret_cert
-    secret_sni
-    create_ts
-    update_ts
-  }
-}
-`
-
-var QUpdateSecretKey = `
-mutation update_secret_key($secret_name: String!, $secret_key: String!){
-  update_saaras_db_secret(
-    where: 
-    {
-      secret_name: {_eq: $secret_name}
-    }, 
-    _set: 
-    {
-      secret_key: $secret_key
-    }
-  ) 
-  {
-    affected_rows
-  }
-}
-`
-
-var QUpdateSecretCert = `
-mutation update_secret_key($secret_name: String!, $secret_cert: String!){
-  update_saaras_db_secret(
-    where: 
-    {
-      secret_name: {_eq: $secret_name}
-    }, 
-    _set: 
-    {
-      secret_cert: $secret_cert
-    }
-  ) 
-  {
-    affected_rows
-  }
-}
-`
+// db_update_secret changes the secret secret_name with update and responds
+// with the result.
+func db_update_secret(c echo.Context, secret_name string, update func(s *store.Secret)) error {
+	s, err := DB.GetSecret(secret_name)
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	update(s)
 
-var QDeleteSecret = `
-mutation delete_secret($secret_name: String!){
-        delete_saaras_db_secret(where: {secret_name: {_eq: $secret_name}}) {
-                affected_rows
-        }
+	if err := DB.UpdateSecret(s); err != nil {
+		return dbError(c, err)
+	}
+	return dbAffected(c, http.StatusCreated, "update_saaras_db_secret", 1)
 }
-`
 
 // @Summary Create a secret
 // @Tags secret
@@ -103,36 +39,35 @@ mutation delete_secret($secret_name: String!){
 // @Produce  json
 // @Param Secret body webhttp.Secret true "Secret to create"
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /secret [post]
 // @Security ApiKeyAuth
 func POST_Secret(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	s := new(Secret)
 	if err := c.Bind(s); err != nil {
 		return err
 	}
 
 	if len(s.Secret_name) == 0 {
-		return c.JSON(http.StatusBadRequest, "Please provide secret name")
+		return badRequest(c, invalid("secret", "", "secret_name", "Please provide secret name using secret_name field"))
 	}
 
-	args["secret_name"] = s.Secret_name
-	args["secret_key"] = s.Secret_key
-	args["secret_cert"] = s.Secret_cert
-	args["secret_sni"] = s.Secret_sni
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
+	if _, err := saarasconfig.UnmarshalTLSConfig(s.Secret_tls_config); err != nil {
+		return badRequest(c, invalid("secret", s.Secret_name, "secret_tls_config", "Invalid TLS config: %v", err))
+	}
 
-	if err := saaras.RunDBQuery(url, QCreateSecret, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	err := DB.CreateSecret(&store.Secret{
+		Name:      s.Secret_name,
+		Key:       s.Secret_key,
+		Cert:      s.Secret_cert,
+		SNI:       s.Secret_sni,
+		TLSConfig: s.Secret_tls_config,
+	})
+	if err != nil {
+		return dbError(c, err)
 	}
-	return c.JSONBlob(http.StatusCreated, buf.Bytes())
+	return dbAffected(c, http.StatusCreated, "insert_saaras_db_secret", 1)
 }
 
 // POST key from a file
@@ -141,50 +76,30 @@ func POST_Secret(c echo.Context) error {
 // @Summary Set the secret key from file
 // @Description Set the secret key from file
 // @Description Example curl -X POST -F 'Secret_key=@private_key.pem' http://localhost:1323/secret/testsecret/key | python -m json.tool
+// @Description The key may be sent as secret_key in json as well
 // @Tags secret
-// @Accept  json
+// @Accept  mpfd,json
 // @Produce  json
 // @Param secret_name path string true "Name of secret"
 // @Param secret_key formData file true "Location of file holding the secret key"
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /secret/{secret_name}/key [post]
 // @Security ApiKeyAuth
 func POST_Secret_Key(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
+	secret_name := c.Param("secret_name")
 
-	file, err := c.FormFile("Secret_key")
-	if file == nil {
-		return c.JSON(http.StatusBadRequest, "{\"Error\" : \"Secret_key empty\"}")
-	}
-	src, err := file.Open()
+	secret_key, err := formFile(c, "Secret_key", "secret_key")
 	if err != nil {
 		return err
 	}
-	defer src.Close()
-	buf2 := new(bytes.Buffer)
-	buf2.ReadFrom(src)
-	secret_key := buf2.String()
-
-	secret_name := c.Param("secret_name")
-
-	if len(secret_name) == 0 {
-		return c.JSON(http.StatusBadRequest, "{\"Error\" : \"Please provide secret name\"}")
+	if secret_key == "" {
+		return badRequest(c, invalid("secret", secret_name, "secret_key", "Secret_key empty"))
 	}
 
-	args["secret_name"] = secret_name
-	args["secret_key"] = secret_key
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	if err := saaras.RunDBQuery(url, QUpdateSecretKey, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
-	return c.JSONBlob(http.StatusCreated, buf.Bytes())
+	return db_update_secret(c, secret_name, func(s *store.Secret) { s.Key = secret_key })
 }
 
 // POST key from a file
@@ -193,54 +108,145 @@ func POST_Secret_Key(c echo.Context) error {
 // @Summary Set the secret cert from file
 // @Description Set the secret cert from file
 // @Description Example curl -X POST -F 'Secret_cert=@certificate.pem' http://localhost:1323/secret/testsecret/cert | python -m json.tool
+// @Description The cert may be sent as secret_cert in json as well
 // @Tags secret
-// @Accept  json
+// @Accept  mpfd,json
 // @Produce  json
 // @Param secret_name path string true "Name of secret"
 // @Param secret_cert formData file true "Location of file holding the secret cert"
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /secret/{secret_name}/cert [post]
 // @Security ApiKeyAuth
 func POST_Secret_Cert(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
+	secret_name := c.Param("secret_name")
 
-	file, err := c.FormFile("Secret_cert")
-	if file == nil {
-		return c.JSON(http.StatusBadRequest, "{\"Error\" : \"Secret_cert empty\"}")
-	}
-	src, err := file.Open()
+	secret_cert, err := formFile(c, "Secret_cert", "secret_cert")
 	if err != nil {
 		return err
 	}
-	defer src.Close()
-	buf2 := new(bytes.Buffer)
-	buf2.ReadFrom(src)
-	secret_cert := buf2.String()
+	if secret_cert == "" {
+		return badRequest(c, invalid("secret", secret_name, "secret_cert", "Secret_cert empty"))
+	}
+
+	return db_update_secret(c, secret_name, func(s *store.Secret) { s.Cert = secret_cert })
+}
+
+// @Summary Set the TLS parameters of the service using this secret
+// @Description Set the minimum and maximum TLS version, cipher suites and ECDH curves
+// @Description Example curl -X POST -d 'Secret_tls_config={"minimum_protocol_version":"1.2","cipher_suites":["ECDHE-RSA-AES256-GCM-SHA384"]}' http://localhost:1323/secret/testsecret/tls | python -m json.tool
+// @Tags secret
+// @Accept  json
+// @Produce  json
+// @Param secret_name path string true "Name of secret"
+// @Param Secret body webhttp.Secret true "TLS parameters in Secret_tls_config"
+// @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
+// @Router /secret/{secret_name}/tls [post]
+// @Security ApiKeyAuth
+func POST_Secret_TLS(c echo.Context) error {
+	s := new(Secret)
+	if err := c.Bind(s); err != nil {
+		return err
+	}
 
 	secret_name := c.Param("secret_name")
 
-	if len(secret_name) == 0 {
-		return c.JSON(http.StatusBadRequest, "Please provide secret name")
+	if _, err := saarasconfig.UnmarshalTLSConfig(s.Secret_tls_config); err != nil {
+		return badRequest(c, invalid("secret", secret_name, "secret_tls_config", "Invalid TLS config: %v", err))
+	}
+
+	return db_update_secret(c, secret_name, func(sec *store.Secret) { sec.TLSConfig = s.Secret_tls_config })
+}
+
+// CertInfo holds the parsed fields of the certificate in a secret
+type CertInfo struct {
+	Subject   string    `json:"subject"`
+	SANs      []string  `json:"sans"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// SecretWithCertInfo is a secret as stored, along with its parsed certificate
+type SecretWithCertInfo struct {
+	Secret_name       string `json:"secret_name"`
+	Secret_key        string `json:"secret_key"`
+	Secret_cert       string `json:"secret_cert"`
+	Secret_sni        string `json:"secret_sni"`
+	Secret_tls_config string `json:"secret_tls_config"`
+	// Secret_acme_service is the service the certificate was issued for
+	// over ACME, if it is renewed by enroute-cp
+	Secret_acme_service string    `json:"secret_acme_service,omitempty"`
+	Create_ts           string    `json:"create_ts"`
+	Update_ts           string    `json:"update_ts"`
+	Secret_cert_info    *CertInfo `json:"secret_cert_info,omitempty"`
+}
+
+type secretsResponse struct {
+	Data *struct {
+		Saaras_db_secret []SecretWithCertInfo `json:"saaras_db_secret"`
+	} `json:"data"`
+}
+
+// parseCertInfo parses the first certificate in certPEM. It returns
+// nil if certPEM is empty.
+func parseCertInfo(certPEM string) *CertInfo {
+	if certPEM == "" {
+		return nil
 	}
 
-	args["secret_name"] = secret_name
-	args["secret_cert"] = secret_cert
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return &CertInfo{Error: "no certificate found in PEM data"}
+	}
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return &CertInfo{Error: err.Error()}
+	}
+
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+
+	return &CertInfo{
+		Subject:   cert.Subject.String(),
+		SANs:      sans,
+		NotBefore: cert.NotBefore.UTC(),
+		NotAfter:  cert.NotAfter.UTC(),
+	}
+}
+
+// addCertInfo adds the parsed certificate to every secret in a get_secret response
+func addCertInfo(resp []byte) ([]byte, error) {
+	var sr secretsResponse
+	if err := json.Unmarshal(resp, &sr); err != nil {
+		return nil, err
+	}
+	if sr.Data == nil {
+		return nil, errors.New("no data in response")
+	}
 
-	if err := saaras.RunDBQuery(url, QUpdateSecretCert, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	for i := range sr.Data.Saaras_db_secret {
+		s := &sr.Data.Saaras_db_secret[i]
+		s.Secret_cert_info = parseCertInfo(s.Secret_cert)
 	}
-	return c.JSONBlob(http.StatusCreated, buf.Bytes())
+
+	return json.Marshal(sr)
 }
 
 // @Summary List all secrets
-// @Description Get a list of all secrets for all services
+// @Description Get a list of all secrets for all services, with the subject, SANs and expiry of each certificate in secret_cert_info
 // @Tags secret
 // @Accept  json
 // @Produce  json
@@ -248,45 +254,63 @@ func POST_Secret_Cert(c echo.Context) error {
 // @Router /secret [get]
 // @Security ApiKeyAuth
 func GET_Secret(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
 	log2 := logrus.StandardLogger()
 	log := log2.WithField("context", "web-http")
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQuery(url, QGetSecret, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	ss, err := DB.ListSecrets()
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	data, err := dbData("saaras_db_secret", ss,
+		"secret_name secret_key secret_cert secret_sni secret_tls_config secret_acme_service create_ts update_ts")
+	if err != nil {
+		return dbError(c, err)
+	}
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	resp, err := addCertInfo(buf)
+	if err != nil {
+		log.Errorf("Error when parsing secrets [%v]\n", err)
+		return c.JSONBlob(http.StatusOK, buf)
 	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return c.JSONBlob(http.StatusOK, resp)
 }
 
 // @Summary Delete a secret
+// @Description Deleting a secret which is in use responds with its references, unless cascade is set to remove its associations with services first
 // @Tags secret
 // @Accept  json
 // @Produce  json
 // @Param secret_name path string true "Name of secret"
+// @Param cascade query bool false "Remove the associations of the secret first"
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /secret/{secret_name} [delete]
 // @Security ApiKeyAuth
 func DELETE_Secret(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
 	secret_name := c.Param("secret_name")
-	args["secret_name"] = secret_name
-	if err := saaras.RunDBQuery(url, QDeleteSecret, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return dbDeleteReferenced(c, secretReferences, secret_name, DB.DeleteSecret, "delete_saaras_db_secret")
+}
+
+// @Summary Get the objects referencing a secret
+// @Description Get the services using a secret and the proxies serving them
+// @Tags secret
+// @Accept  json
+// @Produce  json
+// @Param secret_name path string true "Name of secret"
+// @Success 200 {object} webhttp.References
+// @Router /secret/{secret_name}/references [get]
+// @Security ApiKeyAuth
+func GET_Secret_References(c echo.Context) error {
+	return dbReferences(c, secretReferences, c.Param("secret_name"))
 }
 
 func Add_secret_routes(e *echo.Echo) {
@@ -296,6 +320,8 @@ func Add_secret_routes(e *echo.Echo) {
 	e.POST("/secret", POST_Secret)
 	e.POST("/secret/:secret_name/key", POST_Secret_Key)
 	e.POST("/secret/:secret_name/cert", POST_Secret_Cert)
+	e.POST("/secret/:secret_name/tls", POST_Secret_TLS)
 	//	e.POST("/secret/:secret_name/sni", POST_Secret_SNI)
 	e.DELETE("/secret/:secret_name", DELETE_Secret)
+	e.GET("/secret/:secret_name/references", GET_Secret_References)
 }
@@ -0,0 +1,100 @@
+package webhttp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCertInfo(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notBefore := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := time.Date(2020, 7, 30, 0, 0, 0, 0, time.UTC)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com", Organization: []string{"Saaras"}},
+		DNSNames:     []string{"example.com", "www.example.com"},
+		IPAddresses:  []net.IP{net.ParseIP("10.0.0.1")},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+
+	tests := map[string]struct {
+		cert string
+		want *CertInfo
+	}{
+		"certificate": {
+			cert: certPEM,
+			want: &CertInfo{
+				Subject:   "CN=example.com,O=Saaras",
+				SANs:      []string{"example.com", "www.example.com", "10.0.0.1"},
+				NotBefore: notBefore,
+				NotAfter:  notAfter,
+			},
+		},
+		"no certificate": {
+			cert: "",
+			want: nil,
+		},
+		"invalid certificate": {
+			cert: "not a certificate",
+			want: &CertInfo{Error: "no certificate found in PEM data"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, parseCertInfo(tc.cert))
+		})
+	}
+}
+
+func TestAddCertInfo(t *testing.T) {
+	cert := selfSignedCertPEM(t, time.Date(2020, 7, 30, 0, 0, 0, 0, time.UTC))
+	in, err := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{
+			"saaras_db_secret": []map[string]string{{
+				"secret_name": "acme-test",
+				"secret_cert": cert,
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := addCertInfo(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sr secretsResponse
+	if err := json.Unmarshal(out, &sr); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, sr.Data.Saaras_db_secret, 1)
+	assert.Equal(t, "acme-test", sr.Data.Saaras_db_secret[0].Secret_name)
+	assert.Equal(t, cert, sr.Data.Saaras_db_secret[0].Secret_cert)
+	assert.Equal(t, time.Date(2020, 7, 30, 0, 0, 0, 0, time.UTC), sr.Data.Saaras_db_secret[0].Secret_cert_info.NotAfter)
+
+	_, err = addCertInfo([]byte(`{"errors":[{"message":"field not found"}]}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,347 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package webhttp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/saarasio/enroute/enroute-cp/store"
+)
+
+// API tokens authenticate requests along with WEBAPP_SECRET, which acts as
+// an admin token. A token has a role:
+//
+//  read-only  may only read
+//  operator   may also change the configuration
+//  admin      may also manage tokens and webhooks, and read the audit log
+//
+// A token may be scoped to proxies and services, in which case it may only
+// be used on the routes of a proxy in its scope (/proxy/:proxy_name/...)
+// or of a service in its scope (/service/:service_name/...). The services
+// of a proxy in scope are in scope as well. Applying or rolling back the
+// configuration of a proxy overwrites its services, so those must be in
+// scope too.
+
+const (
+	TokenRoleReadOnly = "read-only"
+	TokenRoleOperator = "operator"
+	TokenRoleAdmin    = "admin"
+)
+
+// tokenKey is where the token a request was authenticated with is kept
+// in its context
+const tokenKey = "token"
+
+type Token struct {
+	Token_name     string   `json:"token_name" xml:"token_name" form:"token_name" query:"token_name" example:"ci"`
+	Token_role     string   `json:"token_role" xml:"token_role" form:"token_role" query:"token_role" example:"operator"`
+	Token_proxies  []string `json:"token_proxies" xml:"token_proxies" form:"token_proxies" query:"token_proxies"`
+	Token_services []string `json:"token_services" xml:"token_services" form:"token_services" query:"token_services"`
+}
+
+// The digest of tokens is never returned
+const tokenFields = `token_id token_name token_role token_proxies token_services create_ts`
+
+// newToken is a token as created, along with its value
+type newToken struct {
+	store.Token
+	Value string `json:"token"`
+}
+
+func hashToken(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func isTokenRoleValid(role string) bool {
+	switch role {
+	case TokenRoleReadOnly, TokenRoleOperator, TokenRoleAdmin:
+		return true
+	default:
+		return false
+	}
+}
+
+// Authenticate validates the key a request carries, keeping the token it
+// is in the context of the request for Authorize.
+func Authenticate(key string, c echo.Context) (bool, error) {
+	if SECRET != "" && subtle.ConstantTimeCompare([]byte(key), []byte(SECRET)) == 1 {
+		c.Set(tokenKey, &store.Token{Role: TokenRoleAdmin})
+		return true, nil
+	}
+
+	t, err := DB.TokenByHash(hashToken(key))
+	if isNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	c.Set(tokenKey, t)
+	return true, nil
+}
+
+// requestToken returns the token c was authenticated with, or nil if it
+// was not authenticated.
+func requestToken(c echo.Context) *store.Token {
+	t, _ := c.Get(tokenKey).(*store.Token)
+	return t
+}
+
+// requestAuthor returns who made the request c, the name of its token or
+// else the address it came from.
+func requestAuthor(c echo.Context) string {
+	if t := requestToken(c); t != nil && t.Name != "" {
+		return t.Name
+	}
+	return c.RealIP()
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceInScope returns true if the service is in the scope of t, itself
+// or through one of its proxies.
+func serviceInScope(t *store.Token, service string) (bool, error) {
+	if contains(t.Services, service) {
+		return true, nil
+	}
+	ps, err := DB.ListServiceProxies(service)
+	if isNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, p := range ps {
+		if contains(t.Proxies, p.Name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func tokenName(t *store.Token) string {
+	if t.Name == "" {
+		return "Secret"
+	}
+	return "Token " + t.Name
+}
+
+// tokenAllows returns why t does not allow the request c, or "" if it does.
+func tokenAllows(t *store.Token, c echo.Context) (string, error) {
+	name := tokenName(t)
+
+	// Tokens, webhooks and the audit log are for admins only
+	if strings.HasPrefix(c.Path(), "/token") || strings.HasPrefix(c.Path(), "/webhook") || strings.HasPrefix(c.Path(), "/audit") {
+		if t.Role != TokenRoleAdmin || len(t.Proxies) > 0 || len(t.Services) > 0 {
+			return name + " cannot access " + c.Path(), nil
+		}
+		return "", nil
+	}
+
+	// GraphQL queries are POSTed but only read the configuration of the
+	// proxy they name, which POST_GraphQL checks the scope of
+	graphql := c.Path() == GraphQLPath
+
+	switch t.Role {
+	case TokenRoleAdmin, TokenRoleOperator:
+	case TokenRoleReadOnly:
+		switch c.Request().Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if !graphql {
+				return name + " is read-only", nil
+			}
+		}
+	default:
+		return name + " has unknown role " + t.Role, nil
+	}
+
+	if graphql || len(t.Proxies) == 0 && len(t.Services) == 0 {
+		return "", nil
+	}
+
+	scoped := false
+	for _, p := range c.ParamNames() {
+		switch {
+		case p == "proxy_name":
+			scoped = true
+			if !contains(t.Proxies, c.Param(p)) {
+				return name + " is not scoped to proxy " + c.Param(p), nil
+			}
+		case strings.HasPrefix(p, "service_name"):
+			scoped = true
+			ok, err := serviceInScope(t, c.Param(p))
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				return name + " is not scoped to service " + c.Param(p), nil
+			}
+		}
+	}
+	if !scoped {
+		return name + " is scoped to proxies and services, " + c.Path() + " is not", nil
+	}
+	return "", nil
+}
+
+// tokenAllowsConfig returns why the token c was authenticated with does
+// not allow setting the configuration of a proxy to pd, or "" if it does.
+// Setting it overwrites the services of pd, so those which exist must be
+// in the scope of the token.
+func tokenAllowsConfig(c echo.Context, pd *store.ProxyDetail) (string, error) {
+	t := requestToken(c)
+	if t == nil || len(t.Proxies) == 0 && len(t.Services) == 0 {
+		return "", nil
+	}
+
+	for _, ps := range pd.Services {
+		service := ps.Service.Name
+		if _, err := DB.GetService(service); isNotFound(err) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		ok, err := serviceInScope(t, service)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return tokenName(t) + " is not scoped to service " + service, nil
+		}
+	}
+	return "", nil
+}
+
+// Authorize is a middleware allowing a request only if the token it was
+// authenticated with allows it. Requests which were not authenticated,
+// as with auth disabled, are allowed.
+func Authorize(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		t := requestToken(c)
+		if t == nil {
+			return next(c)
+		}
+
+		msg, err := tokenAllows(t, c)
+		if err != nil {
+			return dbError(c, err)
+		}
+		if msg != "" {
+			return apiErrors(c, http.StatusForbidden, &APIError{Code: ErrorForbidden, Message: msg})
+		}
+		return next(c)
+	}
+}
+
+// @Summary Create an API token
+// @Description Create an API token with a role, optionally scoped to proxies and services. The token is only returned here.
+// @Tags token
+// @Accept  json
+// @Produce  json
+// @Param Token body webhttp.Token true "Token to create"
+// @Success 201 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
+// @Router /token [post]
+// @Security ApiKeyAuth
+func POST_Token(c echo.Context) error {
+	tok := new(Token)
+	if err := c.Bind(tok); err != nil {
+		return err
+	}
+
+	if len(tok.Token_name) == 0 {
+		return badRequest(c, invalid("token", "", "token_name", "Please provide token name using token_name field"))
+	}
+	if !isTokenRoleValid(tok.Token_role) {
+		return badRequest(c, invalid("token", tok.Token_name, "token_role", "Please provide token role read-only, operator or admin using token_role field"))
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	value := hex.EncodeToString(b)
+
+	t := &store.Token{
+		Name:     tok.Token_name,
+		Hash:     hashToken(value),
+		Role:     tok.Token_role,
+		Proxies:  tok.Token_proxies,
+		Services: tok.Token_services,
+	}
+	if err := DB.CreateToken(t); err != nil {
+		return dbError(c, err)
+	}
+
+	return dbRows(c, http.StatusCreated, "saaras_db_token", []newToken{{Token: *t, Value: value}}, tokenFields+" token")
+}
+
+// @Summary List API tokens
+// @Description List API tokens, without their value
+// @Tags token
+// @Accept  json
+// @Produce  json
+// @Success 200 {} int OK
+// @Router /token [get]
+// @Security ApiKeyAuth
+func GET_Token(c echo.Context) error {
+	ts, err := DB.ListTokens()
+	return dbList(c, http.StatusOK, "saaras_db_token", ts, err, tokenFields)
+}
+
+// @Summary Get an API token
+// @Description Get an API token, without its value
+// @Tags token
+// @Accept  json
+// @Produce  json
+// @Param token_name path string true "Name of token"
+// @Success 200 {} int OK
+// @Router /token/{token_name} [get]
+// @Security ApiKeyAuth
+func GET_One_Token(c echo.Context) error {
+	t, err := DB.GetToken(c.Param("token_name"))
+	return dbOne(c, http.StatusOK, "saaras_db_token", t, err, tokenFields)
+}
+
+// @Summary Revoke an API token
+// @Description Revoke an API token, which cannot be used anymore
+// @Tags token
+// @Accept  json
+// @Produce  json
+// @Param token_name path string true "Name of token"
+// @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
+// @Router /token/{token_name} [delete]
+// @Security ApiKeyAuth
+func DELETE_Token(c echo.Context) error {
+	if err := DB.DeleteToken(c.Param("token_name")); err != nil {
+		return dbError(c, err)
+	}
+	return dbAffected(c, http.StatusOK, "delete_saaras_db_token", 1)
+}
+
+func Add_token_routes(e *echo.Echo) {
+	e.POST("/token", POST_Token)
+	e.GET("/token", GET_Token)
+	e.GET("/token/:token_name", GET_One_Token)
+	e.DELETE("/token/:token_name", DELETE_Token)
+}
@@ -0,0 +1,134 @@
+package webhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens(t *testing.T) {
+	SECRET = "secret"
+	defer func() { SECRET = "" }()
+
+	api := newTestAPI(t, middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{Validator: Authenticate}), Authorize, RecordRevisions)
+	Add_proxy_routes(api.Echo)
+	Add_service_routes(api.Echo)
+	Add_revision_routes(api.Echo)
+	Add_token_routes(api.Echo)
+	Add_webhook_routes(api.Echo)
+	Add_graphql_routes(api.Echo)
+
+	do := func(key, method, path, body string) *httptest.ResponseRecorder {
+		req := jsonRequest(method, path, body)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+key)
+		return api.serve(req)
+	}
+	create := func(body string) string {
+		rec := do("secret", http.MethodPost, "/token", body)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var resp struct {
+			Data struct {
+				Saaras_db_token []struct {
+					Token string `json:"token"`
+					Hash  string `json:"token_hash"`
+				} `json:"saaras_db_token"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		require.Len(t, resp.Data.Saaras_db_token, 1)
+		assert.Empty(t, resp.Data.Saaras_db_token[0].Hash)
+		return resp.Data.Saaras_db_token[0].Token
+	}
+
+	require.Equal(t, http.StatusCreated, do("secret", http.MethodPost, "/proxy", `{"name": "gw"}`).Code)
+	require.Equal(t, http.StatusCreated, do("secret", http.MethodPost, "/proxy", `{"name": "other"}`).Code)
+	for _, s := range []string{"svc", "svc2", "own"} {
+		require.Less(t, do("secret", http.MethodPost, "/service", `{"service_name": "`+s+`", "fqdn": "example.com"}`).Code, 300)
+	}
+	require.Equal(t, http.StatusCreated, do("secret", http.MethodPost, "/proxy/gw/service/svc", "").Code)
+	require.Equal(t, http.StatusCreated, do("secret", http.MethodPost, "/proxy/other/service/svc2", "").Code)
+
+	reader := create(`{"token_name": "reader", "token_role": "read-only"}`)
+	operator := create(`{"token_name": "gw-operator", "token_role": "operator", "token_proxies": ["gw"], "token_services": ["own"]}`)
+	admin := create(`{"token_name": "admin", "token_role": "admin"}`)
+	dp := create(`{"token_name": "gw-dp", "token_role": "read-only", "token_proxies": ["gw"]}`)
+	config := func(services ...string) string {
+		ps := []string{}
+		for _, s := range services {
+			ps = append(ps, `{"service": {"service_name": "`+s+`", "fqdn": "example.com"}}`)
+		}
+		return `{"data": {"saaras_db_proxy": [{"proxy_name": "gw", "proxy_services": [` + strings.Join(ps, ", ") + `]}]}}`
+	}
+	query := func(proxy string) string {
+		return `{"query": "query { saaras_db_proxy_service { service { service_name } } }", "variables": {"proxy_name": "` + proxy + `"}}`
+	}
+
+	// Only the digest of tokens is kept
+	tok, err := DB.GetToken("reader")
+	require.NoError(t, err)
+	assert.Equal(t, hashToken(reader), tok.Hash)
+
+	assert.Equal(t, http.StatusBadRequest, do("secret", http.MethodPost, "/token", `{"token_name": "x", "token_role": "root"}`).Code)
+	assert.Equal(t, http.StatusConflict, do("secret", http.MethodPost, "/token", `{"token_name": "admin", "token_role": "admin"}`).Code)
+
+	tests := map[string]struct {
+		key, method, path, body string
+		want                    int
+	}{
+		"unknown token":               {"nope", http.MethodGet, "/proxy", "", http.StatusUnauthorized},
+		"reader reads":                {reader, http.MethodGet, "/proxy", "", http.StatusOK},
+		"reader cannot write":         {reader, http.MethodPost, "/proxy", `{"name": "x"}`, http.StatusForbidden},
+		"reader cannot list tokens":   {reader, http.MethodGet, "/token", "", http.StatusForbidden},
+		"operator on its proxy":       {operator, http.MethodGet, "/proxy/gw/service", "", http.StatusOK},
+		"operator on another proxy":   {operator, http.MethodGet, "/proxy/other/service", "", http.StatusForbidden},
+		"operator on a proxy service": {operator, http.MethodPatch, "/service/svc", `{"fqdn": "a.example.com"}`, http.StatusCreated},
+		"operator on its service":     {operator, http.MethodPatch, "/service/own", `{"fqdn": "b.example.com"}`, http.StatusCreated},
+		"operator on another service": {operator, http.MethodPatch, "/service/svc2", `{"fqdn": "c.example.com"}`, http.StatusForbidden},
+		"operator on unscoped route":  {operator, http.MethodGet, "/proxy", "", http.StatusForbidden},
+		"operator cannot add tokens":  {operator, http.MethodPost, "/token", `{"token_name": "y", "token_role": "admin"}`, http.StatusForbidden},
+		"admin lists tokens":          {admin, http.MethodGet, "/token", "", http.StatusOK},
+		"operator cannot list hooks":  {operator, http.MethodGet, "/webhook", "", http.StatusForbidden},
+		"admin lists hooks":           {admin, http.MethodGet, "/webhook", "", http.StatusOK},
+		"graphql needs a key":         {"nope", http.MethodPost, GraphQLPath, query("gw"), http.StatusUnauthorized},
+		"reader fetches config":       {reader, http.MethodPost, GraphQLPath, query("other"), http.StatusOK},
+		"dp fetches its config":       {dp, http.MethodPost, GraphQLPath, query("gw"), http.StatusOK},
+		"dp fetches another config":   {dp, http.MethodPost, GraphQLPath, query("other"), http.StatusForbidden},
+		"dp cannot write":             {dp, http.MethodPost, "/proxy/gw/service/own", "", http.StatusForbidden},
+		"operator applies a service":  {operator, http.MethodPut, "/proxy/gw/config?dryRun=true", config("svc", "own", "new"), http.StatusOK},
+		"operator applies another":    {operator, http.MethodPut, "/proxy/gw/config?dryRun=true", config("svc", "svc2"), http.StatusForbidden},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := do(tc.key, tc.method, tc.path, tc.body)
+			assert.Equal(t, tc.want, rec.Code, rec.Body.String())
+		})
+	}
+
+	// Revisions are authored by the token which made the change
+	require.Less(t, do(operator, http.MethodPatch, "/service/svc", `{"fqdn": "d.example.com"}`).Code, 300)
+	r, err := DB.LatestRevision("gw")
+	require.NoError(t, err)
+	assert.Equal(t, "gw-operator", r.Author)
+
+	// Rolling back a proxy may only overwrite services in scope
+	require.Equal(t, http.StatusCreated, do(admin, http.MethodPost, "/proxy/gw/service/svc2", "").Code)
+	r, err = DB.LatestRevision("gw")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, do(admin, http.MethodDelete, "/proxy/gw/service/svc2", "").Code)
+	rollback := "/proxy/gw/revision/" + strconv.FormatInt(r.ID, 10) + "/rollback"
+	assert.Equal(t, http.StatusForbidden, do(operator, http.MethodPost, rollback, "").Code)
+	assert.Equal(t, http.StatusCreated, do(admin, http.MethodPost, rollback, "").Code)
+
+	// Revoked tokens cannot be used
+	require.Equal(t, http.StatusOK, do(admin, http.MethodDelete, "/token/reader", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, do(reader, http.MethodGet, "/proxy", "").Code)
+}
@@ -4,48 +4,16 @@
 package webhttp
 
 import (
-	"bytes"
-	"encoding/json"
 	"github.com/labstack/echo/v4"
-	"github.com/pkg/errors"
-	"github.com/saarasio/enroute/enroute-dp/saaras"
+	"github.com/saarasio/enroute/enroute-cp/store"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/sirupsen/logrus"
 	"net"
 )
 
-// Upstream CRUD queries
-
-var QCreateUpstream = `
-	mutation insert_upstream(
-		$upstream_name: String!, 
-		$upstream_ip: String!, 
-		$upstream_hc_path: String!, 
-		$upstream_port: Int!,
-		$upstream_protocol: String,
-		$upstream_weight: Int
-	) {
-	  insert_saaras_db_upstream(
-	    objects: {
-	      upstream_name: $upstream_name, 
-	      upstream_ip: $upstream_ip, 
-	      upstream_hc_path: $upstream_hc_path, 
-	      upstream_port: $upstream_port,
-	      upstream_weight: $upstream_weight,
-	      upstream_protocol: $upstream_protocol
-	    }
-	  ) {
-	    affected_rows
-	  }
-	}
-`
-
-var QGetUpstream = `
-query get_upstream {
-  saaras_db_upstream {
+const upstreamFields = `
     upstream_id
     upstream_name
     upstream_ip
@@ -63,99 +31,6 @@ query get_upstream {
     upstream_protocol
     create_ts
     update_ts
-  }
-}
-`
-
-// TODO: Capture all fields in upstream
-var QOneUpstream = `
-	query get_upstream($upstream_name : String!) {
-		saaras_db_upstream(where: {upstream_name: {_eq: $upstream_name}}) {
-    	upstream_id
-    	upstream_name
-    	upstream_ip
-    	upstream_port
-    	upstream_hc_path
-    	upstream_hc_host
-    	upstream_hc_intervalseconds
-    	upstream_hc_timeoutseconds
-    	upstream_hc_unhealthythresholdcount
-    	upstream_hc_healthythresholdcount
-    	upstream_strategy
-    	upstream_validation_cacertificate
-    	upstream_validation_subjectname
-        upstream_protocol
-		upstream_weight
-    	create_ts
-    	update_ts
-		}
-	}
-`
-
-var QDeleteUpstream = `
-mutation delete_upstream($upstream_name: String!) {
-  delete_saaras_db_upstream(where: {upstream_name: {_eq: $upstream_name}}) {
-    affected_rows
-  }
-}
-`
-
-var QPatchUpstream = `
-
-mutation update_upstream(
-     $upstream_name: String!,
-     $upstream_ip: String!,
-     $upstream_port: Int!,
-     $upstream_weight: Int!,
-     $upstream_hc_path: String!,
-     $upstream_hc_host: String!,
-     $upstream_hc_intervalseconds: Int!,
-     $upstream_hc_unhealthythresholdcount: Int!,
-     $upstream_hc_healthythresholdcount: Int!,
-     $upstream_strategy: String!,
-     $upstream_validation_cacertificate: String!,
-     $upstream_validation_subjectname: String!,
-     $upstream_protocol: String!,
-     $upstream_hc_timeoutseconds: Int!
- ) {
-     update_saaras_db_upstream(
-         where: {upstream_name: {_eq: $upstream_name}},
-         _set: {
-             upstream_ip: $upstream_ip,
-             upstream_port: $upstream_port,
-             upstream_weight: $upstream_weight,
-             upstream_hc_path: $upstream_hc_path,
-             upstream_hc_host: $upstream_hc_host,
-             upstream_hc_intervalseconds: $upstream_hc_intervalseconds,
-             upstream_hc_unhealthythresholdcount: $upstream_hc_unhealthythresholdcount,
-             upstream_hc_healthythresholdcount: $upstream_hc_healthythresholdcount,
-             upstream_strategy: $upstream_strategy,
-             upstream_validation_cacertificate: $upstream_validation_cacertificate,
-             upstream_validation_subjectname: $upstream_validation_subjectname,
-             upstream_protocol: $upstream_protocol,
-             upstream_hc_timeoutseconds: $upstream_hc_timeoutseconds
-         }
-     ) {
-         affected_rows
-     }
- }
-
-`
-
-var QUpstreamRoutes = `
-query get_upstream_routes($upstream_name: String!) {
-  saaras_db_upstream(where: {upstream_name: {_eq: $upstream_name}}) {
-    route_upstreams {
-      route {
-        route_id
-        route_name
-        route_prefix
-        create_ts
-        update_ts
-      }
-    }
-  }
-}
 `
 
 // @Summary Update an upstream
@@ -165,23 +40,20 @@ query get_upstream_routes($upstream_name: String!) {
 // @Param Upstream body webhttp.Upstream true "Upstream to update"
 // @Param upstream_name path string true "Name of upstream to update"
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
 // @Router /upstream/{upstream_name} [patch]
 // @Security ApiKeyAuth
 func PATCH_Upstream(c echo.Context) error {
 
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
 	log2 := logrus.StandardLogger()
 	log := log2.WithField("context", "web-http")
 
 	upstream_name := c.Param("upstream_name")
 
-	code2, buf2, u_in_db := db_get_one_upstream(upstream_name, true, log)
-
-	if code2 != http.StatusOK && code2 != http.StatusCreated {
-		return c.JSONBlob(code2, []byte(buf2))
+	u_in_db, err := db_get_one_upstream(upstream_name)
+	if err != nil {
+		return dbError(c, err)
 	}
 
 	u := new(Upstream)
@@ -255,94 +127,112 @@ func PATCH_Upstream(c echo.Context) error {
 		u_in_db.Upstream_hc_timeoutseconds = "-1"
 	}
 
-	code3, buf3 := validate_upstream(u_in_db)
-
-	if code3 != http.StatusOK {
-		return c.JSONBlob(code3, []byte(buf3))
+	if e := validate_upstream(u_in_db); e != nil {
+		return badRequest(c, e)
 	}
 
 	log.Infof(" Sending upstream values [%+v]\n", u_in_db)
 
-	args["upstream_name"] = u_in_db.Upstream_name
-	args["upstream_ip"] = u_in_db.Upstream_ip
-	args["upstream_port"] = u_in_db.Upstream_port
-	args["upstream_hc_path"] = u_in_db.Upstream_hc_path
-	args["upstream_hc_host"] = u_in_db.Upstream_hc_host
-	args["upstream_weight"] = u_in_db.Upstream_weight
-	args["upstream_hc_intervalseconds"] = u_in_db.Upstream_hc_intervalseconds
-	args["upstream_hc_unhealthythresholdcount"] = u_in_db.Upstream_hc_unhealthythresholdcount
-	args["upstream_hc_healthythresholdcount"] = u_in_db.Upstream_hc_healthythresholdcount
-	args["upstream_strategy"] = u_in_db.Upstream_strategy
-	args["upstream_validation_cacertificate"] = u_in_db.Upstream_validation_cacertificate
-	args["upstream_validation_subjectname"] = u_in_db.Upstream_validation_subjectname
-	args["upstream_protocol"] = u_in_db.Upstream_protocol
-	args["upstream_hc_timeoutseconds"] = u_in_db.Upstream_hc_timeoutseconds
-
-	log.Infof(" Sending upstream values ARGS [%+v]\n", args)
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	if err := saaras.RunDBQuery(url, QPatchUpstream, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	if err := db_update_upstream(u_in_db); err != nil {
+		return dbError(c, err)
+	}
+	return dbAffected(c, http.StatusOK, "update_saaras_db_upstream", 1)
 }
 
-func db_insert_upstream(u *Upstream, log *logrus.Entry) (int, string) {
-
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	args["upstream_name"] = u.Upstream_name
-	args["upstream_ip"] = u.Upstream_ip
-	args["upstream_hc_path"] = u.Upstream_hc_path
-	args["upstream_port"] = u.Upstream_port
-	args["upstream_weight"] = u.Upstream_weight
+func db_insert_upstream(u *Upstream, log *logrus.Entry) error {
+	su := &store.Upstream{
+		Name:   u.Upstream_name,
+		IP:     u.Upstream_ip,
+		HCPath: u.Upstream_hc_path,
+	}
+	su.Port, _ = strconv.Atoi(u.Upstream_port)
+	su.Weight, _ = strconv.Atoi(u.Upstream_weight)
 
 	if len(u.Upstream_protocol) > 0 {
 		if u.Upstream_protocol == "grpc" {
-			args["upstream_protocol"] = "grpc"
+			su.Protocol = "grpc"
 		}
 	}
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
+	log.Infof("db_insert_upstream() with [%+v]\n", su)
 
-	log.Infof("db_insert_upstream() with [%+v]\n", buf)
+	return DB.CreateUpstream(su)
+}
 
-	if err := saaras.RunDBQuery(url, QCreateUpstream, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-		return http.StatusBadRequest, buf.String()
+func db_get_one_upstream(upstream_name string) (*Upstream, error) {
+	su, err := DB.GetUpstream(upstream_name)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Infof("db_insert_upstream() returned with status [%+s]\n", buf.String())
+	return upstreamOf(su), nil
+}
 
-	return http.StatusCreated, buf.String()
+// upstreamOf returns su with its fields as they are posted.
+func upstreamOf(su *store.Upstream) *Upstream {
+	return &Upstream{
+		Upstream_name:                       su.Name,
+		Upstream_ip:                         su.IP,
+		Upstream_port:                       strconv.Itoa(su.Port),
+		Upstream_hc_path:                    su.HCPath,
+		Upstream_hc_host:                    su.HCHost,
+		Upstream_hc_intervalseconds:         strconv.Itoa(su.HCIntervalSeconds),
+		Upstream_hc_timeoutseconds:          strconv.Itoa(su.HCTimeoutSeconds),
+		Upstream_hc_unhealthythresholdcount: strconv.Itoa(su.HCUnhealthyThresholdCount),
+		Upstream_hc_healthythresholdcount:   strconv.Itoa(su.HCHealthyThresholdCount),
+		Upstream_strategy:                   su.Strategy,
+		Upstream_validation_cacertificate:   su.ValidationCACertificate,
+		Upstream_validation_subjectname:     su.ValidationSubjectName,
+		Upstream_protocol:                   su.Protocol,
+		Upstream_weight:                     strconv.Itoa(su.Weight),
+	}
 }
 
-func validate_upstream(u *Upstream) (int, string) {
+// db_update_upstream saves all fields of u, which have been validated.
+func db_update_upstream(u *Upstream) error {
+	su := &store.Upstream{
+		Name:                    u.Upstream_name,
+		IP:                      u.Upstream_ip,
+		HCPath:                  u.Upstream_hc_path,
+		HCHost:                  u.Upstream_hc_host,
+		Strategy:                u.Upstream_strategy,
+		ValidationCACertificate: u.Upstream_validation_cacertificate,
+		ValidationSubjectName:   u.Upstream_validation_subjectname,
+		Protocol:                u.Upstream_protocol,
+	}
+	su.Port, _ = strconv.Atoi(u.Upstream_port)
+	su.Weight, _ = strconv.Atoi(u.Upstream_weight)
+	su.HCIntervalSeconds, _ = strconv.Atoi(u.Upstream_hc_intervalseconds)
+	su.HCTimeoutSeconds, _ = strconv.Atoi(u.Upstream_hc_timeoutseconds)
+	su.HCUnhealthyThresholdCount, _ = strconv.Atoi(u.Upstream_hc_unhealthythresholdcount)
+	su.HCHealthyThresholdCount, _ = strconv.Atoi(u.Upstream_hc_healthythresholdcount)
+
+	return DB.UpdateUpstream(su)
+}
+
+func validate_upstream(u *Upstream) *APIError {
 	if len(u.Upstream_name) == 0 {
-		return http.StatusBadRequest, "Please provide upstream name using Name field"
+		return invalid("upstream", "", "upstream_name", "Please provide upstream name using upstream_name field")
 	}
 
 	if len(u.Upstream_ip) == 0 {
-		return http.StatusBadRequest, "Please provide Ip/host using Ip field"
+		return invalid("upstream", u.Upstream_name, "upstream_ip", "Please provide Ip/host using upstream_ip field")
 	}
 
 	if len(u.Upstream_port) == 0 {
-		return http.StatusBadRequest, "Please provide Port using Port field"
+		return invalid("upstream", u.Upstream_name, "upstream_port", "Please provide Port using upstream_port field")
 	}
 
 	port, err := strconv.Atoi(u.Upstream_port)
 
 	if port < 1 || port > 65535 || err != nil {
-		return http.StatusBadRequest, "Please provide a valid port value."
+		return invalid("upstream", u.Upstream_name, "upstream_port", "Please provide a valid port value")
 	}
 
 	weight, err2 := strconv.Atoi(u.Upstream_weight)
 
 	if weight < 0 || err2 != nil {
-		return http.StatusBadRequest, "{ \"Error\" : \"Please provide a valid weight value.\" }"
+		return invalid("upstream", u.Upstream_name, "upstream_weight", "Please provide a valid weight value")
 	}
 
 	// TODO: Should we make health check path mandatory? Without the path, the health checker is
@@ -357,7 +247,7 @@ func validate_upstream(u *Upstream) (int, string) {
 	if net.ParseIP(u.Upstream_ip) != nil {
 		// Alternatively, we could just use "/" default for healthchecks
 		if len(u.Upstream_hc_path) <= 0 {
-			return http.StatusBadRequest, "{ \"Error\" : \"Please provide a value for upstream_hc_path\" }"
+			return invalid("upstream", u.Upstream_name, "upstream_hc_path", "Please provide a value for upstream_hc_path")
 		}
 	}
 
@@ -366,7 +256,7 @@ func validate_upstream(u *Upstream) (int, string) {
 	//		  return http.StatusBadRequest, "\"Error\" : \"Please provide a value for upstream_hc_host\""
 	//}
 
-	return http.StatusOK, ""
+	return nil
 }
 
 // TODO: Add support for the following fields -
@@ -385,6 +275,8 @@ func validate_upstream(u *Upstream) (int, string) {
 // @Produce  json
 // @Param Upstream body webhttp.Upstream true "Upstream to create"
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /upstream [post]
 // @Security ApiKeyAuth
 func POST_Upstream(c echo.Context) error {
@@ -396,90 +288,16 @@ func POST_Upstream(c echo.Context) error {
 		return err
 	}
 
-	code, buf := validate_upstream(u)
-
-	if code != http.StatusOK {
-		return c.JSONBlob(code, []byte(buf))
+	if e := validate_upstream(u); e != nil {
+		return badRequest(c, e)
 	}
 
 	log.Infof("POST_Upstream() calling db_insert_upstream() with [%+v]\n", u)
 
-	code2, buf2 := db_insert_upstream(u, log)
-	return c.JSONBlob(code2, []byte(buf2))
-}
-
-func db_get_one_upstream(upstream_name string, decode bool, log *logrus.Entry) (int, string, *Upstream) {
-
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-	args["upstream_name"] = upstream_name
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-
-	if err := saaras.RunDBQuery(url, QOneUpstream, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
-
-	type SaarasDbUpstream struct {
-		CreateTs                          time.Time `json:"create_ts"`
-		UpdateTs                          time.Time `json:"update_ts"`
-		UpstreamHcHealthythresholdcount   int       `json:"upstream_hc_healthythresholdcount"`
-		UpstreamHcHost                    string    `json:"upstream_hc_host"`
-		UpstreamHcIntervalseconds         int       `json:"upstream_hc_intervalseconds"`
-		UpstreamHcPath                    string    `json:"upstream_hc_path"`
-		UpstreamHcTimeoutseconds          int       `json:"upstream_hc_timeoutseconds"`
-		UpstreamHcUnhealthythresholdcount int       `json:"upstream_hc_unhealthythresholdcount"`
-		UpstreamID                        int       `json:"upstream_id"`
-		UpstreamIP                        string    `json:"upstream_ip"`
-		UpstreamName                      string    `json:"upstream_name"`
-		UpstreamPort                      int       `json:"upstream_port"`
-		UpstreamStrategy                  string    `json:"upstream_strategy"`
-		UpstreamWeight                    int       `json:"upstream_weight"`
-		UpstreamValidationCacertificate   string    `json:"upstream_validation_cacertificate"`
-		UpstreamValidationSubjectname     string    `json:"upstream_validation_subjectname"`
-		UpstreamProtocol                  string    `json:"upstream_protocol"`
-	}
-
-	type Data struct {
-		SaarasDbUpstream []SaarasDbUpstream `json:"saaras_db_upstream"`
-	}
-
-	type AutoGenerated struct {
-		Data Data `json:"data"`
-	}
-
-	var u Upstream
-
-	if decode {
-		var gr AutoGenerated
-		log.Infof("Decoding :\n %s\n", buf.String())
-		if err := json.NewDecoder(&buf).Decode(&gr); err != nil {
-			errors.Wrap(err, "decoding response")
-			log.Errorf("Error when decoding json [%v]\n", err)
-			return http.StatusBadRequest, buf.String(), nil
-		}
-
-		if len(gr.Data.SaarasDbUpstream) > 0 {
-			u.Upstream_name = gr.Data.SaarasDbUpstream[0].UpstreamName
-			u.Upstream_ip = gr.Data.SaarasDbUpstream[0].UpstreamIP
-			u.Upstream_port = strconv.FormatInt(int64(gr.Data.SaarasDbUpstream[0].UpstreamPort), 10)
-			u.Upstream_hc_path = gr.Data.SaarasDbUpstream[0].UpstreamHcPath
-			u.Upstream_hc_host = gr.Data.SaarasDbUpstream[0].UpstreamHcHost
-			u.Upstream_hc_intervalseconds = strconv.FormatInt(int64(gr.Data.SaarasDbUpstream[0].UpstreamHcIntervalseconds), 10)
-			u.Upstream_hc_timeoutseconds = strconv.FormatInt(int64(gr.Data.SaarasDbUpstream[0].UpstreamHcTimeoutseconds), 10)
-			u.Upstream_hc_unhealthythresholdcount = strconv.FormatInt(int64(gr.Data.SaarasDbUpstream[0].UpstreamHcUnhealthythresholdcount), 10)
-			u.Upstream_hc_healthythresholdcount = strconv.FormatInt(int64(gr.Data.SaarasDbUpstream[0].UpstreamHcHealthythresholdcount), 10)
-			u.Upstream_strategy = gr.Data.SaarasDbUpstream[0].UpstreamStrategy
-			u.Upstream_validation_cacertificate = gr.Data.SaarasDbUpstream[0].UpstreamValidationCacertificate
-			u.Upstream_validation_subjectname = gr.Data.SaarasDbUpstream[0].UpstreamValidationSubjectname
-			u.Upstream_protocol = gr.Data.SaarasDbUpstream[0].UpstreamProtocol
-			u.Upstream_weight = strconv.FormatInt(int64(gr.Data.SaarasDbUpstream[0].UpstreamWeight), 10)
-		}
-
-		log.Infof("Decoded to upstream [%v]\n", u)
+	if err := db_insert_upstream(u, log); err != nil {
+		return dbError(c, err)
 	}
-
-	return http.StatusOK, buf.String(), &u
+	return dbAffected(c, http.StatusCreated, "insert_saaras_db_upstream", 1)
 }
 
 // @Summary Get info of an upstream
@@ -491,13 +309,10 @@ func db_get_one_upstream(upstream_name string, decode bool, log *logrus.Entry) (
 // @Router /upstream/{upstream_name} [get]
 // @Security ApiKeyAuth
 func GET_One_Upstream(c echo.Context) error {
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
 	upstream_name := c.Param("upstream_name")
-	code, buf, _ := db_get_one_upstream(upstream_name, false, log)
-	return c.JSONBlob(code, []byte(buf))
+
+	u, err := DB.GetUpstream(upstream_name)
+	return dbOne(c, http.StatusOK, "saaras_db_upstream", u, err, upstreamFields)
 }
 
 // @Summary Copy an upstream
@@ -516,17 +331,17 @@ func POST_Upstream_Copy(c echo.Context) error {
 
 	upstream_name_src := c.Param("upstream_name_src")
 	upstream_name_dst := c.Param("upstream_name_dst")
-	code, buf, u := db_get_one_upstream(upstream_name_src, true, log)
-
-	if code != http.StatusOK {
-		return c.JSONBlob(code, []byte(buf))
+	u, err := db_get_one_upstream(upstream_name_src)
+	if err != nil {
+		return dbError(c, err)
 	}
 
 	u.Upstream_name = upstream_name_dst
 	// TODO: Post with a query with all fields. the db_insert_upstream call right now only copies a few fields
-	code2, buf2 := db_insert_upstream(u, log)
-	return c.JSONBlob(code2, []byte(buf2))
-
+	if err := db_insert_upstream(u, log); err != nil {
+		return dbError(c, err)
+	}
+	return dbAffected(c, http.StatusCreated, "insert_saaras_db_upstream", 1)
 }
 
 // @Summary List all upstreams
@@ -538,45 +353,44 @@ func POST_Upstream_Copy(c echo.Context) error {
 // @Router /upstream [get]
 // @Security ApiKeyAuth
 func GET_Upstream(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	if err := saaras.RunDBQuery(url, QGetUpstream, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	us, err := DB.ListUpstreams()
+	if err != nil {
+		return dbError(c, err)
 	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return dbRows(c, http.StatusOK, "saaras_db_upstream", us, upstreamFields)
 }
 
 // @Summary Delete an upstream
+// @Description Deleting an upstream which is in use responds with its references, unless cascade is set to remove its associations with routes first
 // @Tags upstream
 // @Accept  json
 // @Produce  json
 // @Param upstream_name path string true "Name of upstream to delete"
+// @Param cascade query bool false "Remove the associations of the upstream first"
 // @Success 200 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
 // @Router /upstream/{upstream_name} [delete]
 // @Security ApiKeyAuth
 func DELETE_Upstream(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
-
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
-
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
 	upstream_name := c.Param("upstream_name")
-	args["upstream_name"] = upstream_name
-	if err := saaras.RunDBQuery(url, QDeleteUpstream, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
-	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return dbDeleteReferenced(c, upstreamReferences, upstream_name, DB.DeleteUpstream, "delete_saaras_db_upstream")
+}
+
+// @Summary Get the objects referencing an upstream
+// @Description Get the routes using an upstream, their services and the proxies serving them
+// @Tags upstream
+// @Accept  json
+// @Produce  json
+// @Param upstream_name path string true "Name of upstream"
+// @Success 200 {object} webhttp.References
+// @Router /upstream/{upstream_name}/references [get]
+// @Security ApiKeyAuth
+func GET_Upstream_References(c echo.Context) error {
+	return dbReferences(c, upstreamReferences, c.Param("upstream_name"))
 }
 
 // @Summary Get routes to which this upstream is associated
@@ -588,21 +402,23 @@ func DELETE_Upstream(c echo.Context) error {
 // @Router /upstream/{upstream_name}/route [get]
 // @Security ApiKeyAuth
 func GET_Upstream_Routes(c echo.Context) error {
-	var buf bytes.Buffer
-	var args map[string]string
-	args = make(map[string]string)
+	upstream_name := c.Param("upstream_name")
 
-	log2 := logrus.StandardLogger()
-	log := log2.WithField("context", "web-http")
+	rs, err := DB.ListUpstreamRoutes(upstream_name)
+	if err != nil {
+		return dbOne(c, http.StatusOK, "saaras_db_upstream", nil, err, "")
+	}
 
-	url := "http://" + HOST + ":" + PORT + "/v1/graphql"
-	upstream_name := c.Param("upstream_name")
-	args["upstream_name"] = upstream_name
-	if err := saaras.RunDBQuery(url, QUpstreamRoutes, &buf, args, log); err != nil {
-		log.Errorf("Error when running http request [%v]\n", err)
+	type routeUpstream struct {
+		Route store.Route `json:"route"`
+	}
+	rus := []routeUpstream{}
+	for _, r := range rs {
+		rus = append(rus, routeUpstream{Route: r})
 	}
 
-	return c.JSONBlob(http.StatusOK, buf.Bytes())
+	return dbOne(c, http.StatusOK, "saaras_db_upstream", map[string]interface{}{"route_upstreams": rus}, nil,
+		"route_upstreams { route { route_id route_name route_prefix create_ts update_ts } }")
 }
 
 func Add_upstream_routes(e *echo.Echo) {
@@ -616,6 +432,7 @@ func Add_upstream_routes(e *echo.Echo) {
 
 	// Get all routes associated with this upstream
 	e.GET("/upstream/:upstream_name/route", GET_Upstream_Routes)
+	e.GET("/upstream/:upstream_name/references", GET_Upstream_References)
 
 	// Support for operational-verbs
 	e.POST("/upstream/copy/:upstream_name_src/:upstream_name_dst", POST_Upstream_Copy)
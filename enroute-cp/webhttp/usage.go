@@ -1,21 +1,83 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
 package webhttp
 
 import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
-	"os"
 	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
 )
 
+// Anonymous usage reports are opt-in. They are only sent if USAGE_SINK is
+// set, every USAGE_INTERVAL, and GET /usage/preview shows the report
+// which would be sent and where. A report holds
+//
+//  enroutectluuid  the id enroutectl last checked the status with, if any
+//  uptime          how long enroute-cp has been running, like 1h2m3.4s
+//
+// and nothing else: no names, addresses or configuration.
+
+// USAGE_REPORT_URL is where HTTPUsageSink posts reports by default
+const USAGE_REPORT_URL = "https://racon.universalapigateway.com"
+
+var (
+	// USAGE_SINK is where usage reports are sent, none are if it is nil
+	USAGE_SINK UsageSink
+
+	USAGE_INTERVAL = time.Hour
+)
+
+// UsageReport is an anonymous usage report.
 // TODO: Seperate envoy, enroute stats
-type RaconPayload struct {
+type UsageReport struct {
 	EnrouteCtlUUID string `json:"enroutectluuid,omitempty"`
 	Uptime         string `json:"uptime,omitempty"`
 }
 
+// UsageSink is where usage reports are sent, like a collector.
+type UsageSink interface {
+	Send(r *UsageReport) error
+
+	// String describes where reports are sent, like their url.
+	String() string
+}
+
+// HTTPUsageSink posts usage reports in json to URL.
+type HTTPUsageSink struct {
+	URL string
+}
+
+var usageClient = &http.Client{Timeout: 30 * time.Second}
+
+func (s *HTTPUsageSink) Send(r *UsageReport) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	resp, err := usageClient.Post(s.URL, echo.MIMEApplicationJSON, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded %s", s.URL, resp.Status)
+	}
+	return nil
+}
+
+func (s *HTTPUsageSink) String() string {
+	return s.URL
+}
+
 var startTime time.Time
 
 func uptime() string {
@@ -26,52 +88,56 @@ func init() {
 	startTime = time.Now()
 }
 
-func sendOnce() {
-
-	var req *http.Request
-	var err error
-	client := &http.Client{}
-
-	post_arg := RaconPayload{
+func newUsageReport() *UsageReport {
+	return &UsageReport{
 		EnrouteCtlUUID: ID,
 		Uptime:         uptime(),
 	}
+}
 
-	post_arg_json, _ := json.Marshal(post_arg)
-
-	url := "https://racon.universalapigateway.com"
-	req, err = http.NewRequest("POST", string(url), bytes.NewBuffer(post_arg_json))
-	if err == nil {
-		req.Header.Add("Content-Type", "application/json")
-	} else {
-		fmt.Printf("Request creation error while running POST url - [%s]\n", url)
-	}
+// Reporter sends a usage report to USAGE_SINK every USAGE_INTERVAL, if it
+// is set.
+func Reporter() {
+	log := logrus.StandardLogger().WithField("context", "usage")
 
-	res, err := client.Do(req)
-	if res == nil {
-		// TODO: Better error reporting
-		// request failed
-		fmt.Printf("Request run error while running url - [%s]\n", url)
+	if USAGE_SINK == nil {
+		log.Infof("Not sending usage reports\n")
 		return
 	}
-	_, err2 := ioutil.ReadAll(res.Body)
-
-	fmt.Printf("Done: Post to url - [%s]\n", url)
-	if err2 != nil {
-		fmt.Printf("Error when reading response body [%s] \n", err2)
+	for {
+		if err := USAGE_SINK.Send(newUsageReport()); err != nil {
+			log.Warnf("Failed to send usage report to [%s] [%v]\n", USAGE_SINK, err)
+		}
+		time.Sleep(USAGE_INTERVAL)
 	}
-	res.Body.Close()
 }
 
-func Reporter() {
-	USAGE = os.Getenv("SEND_ANON_STAT")
+// UsagePreview is the usage report which would be sent, and where.
+type UsagePreview struct {
+	Enabled     bool         `json:"enabled"`
+	Destination string       `json:"destination,omitempty"`
+	Interval    string       `json:"interval,omitempty"`
+	Report      *UsageReport `json:"report"`
+}
 
-	for {
-		if USAGE != "no" {
-			sendOnce()
-		} else {
-			fmt.Printf("Not sending anon stat\n")
-		}
-		time.Sleep(60 * 60 * time.Minute)
+// @Summary Preview the usage report
+// @Description Show the anonymous usage report which would be sent, where and how often, or that none are sent.
+// @Tags usage
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} webhttp.UsagePreview
+// @Router /usage/preview [get]
+// @Security ApiKeyAuth
+func GET_Usage_Preview(c echo.Context) error {
+	p := UsagePreview{Report: newUsageReport()}
+	if USAGE_SINK != nil {
+		p.Enabled = true
+		p.Destination = USAGE_SINK.String()
+		p.Interval = USAGE_INTERVAL.String()
 	}
+	return c.JSON(http.StatusOK, p)
+}
+
+func Add_usage_routes(e *echo.Echo) {
+	e.GET("/usage/preview", GET_Usage_Preview)
 }
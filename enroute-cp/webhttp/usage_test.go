@@ -0,0 +1,53 @@
+package webhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsage(t *testing.T) {
+	var got UsageReport
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, echo.MIMEApplicationJSON, r.Header.Get(echo.HeaderContentType))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+	}))
+	defer srv.Close()
+
+	ID = "uuid"
+	defer func() { ID, USAGE_SINK = "", nil }()
+
+	e := echo.New()
+	Add_usage_routes(e)
+	preview := func() UsagePreview {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/usage/preview", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var p UsagePreview
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&p))
+		return p
+	}
+
+	// Reports are not sent unless a sink is set
+	p := preview()
+	assert.False(t, p.Enabled)
+	assert.Empty(t, p.Destination)
+	require.NotNil(t, p.Report)
+	assert.Equal(t, "uuid", p.Report.EnrouteCtlUUID)
+
+	USAGE_SINK = &HTTPUsageSink{URL: srv.URL}
+	p = preview()
+	assert.True(t, p.Enabled)
+	assert.Equal(t, srv.URL, p.Destination)
+	assert.Equal(t, "1h0m0s", p.Interval)
+
+	require.NoError(t, USAGE_SINK.Send(p.Report))
+	assert.Equal(t, *p.Report, got)
+
+	assert.Error(t, (&HTTPUsageSink{URL: srv.URL + "/nope\x7f"}).Send(p.Report))
+}
@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package webhttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// WatchPath is where enroute-dp watches the configuration of a proxy, so
+// that it fetches it only once it changed. GET WatchPath + proxy_name
+// streams server-sent events:
+//
+//  event: version
+//  data: {"proxy_name":"gw","version":"3f1c9a2e0b7d4c55"}
+//
+// An event is sent when the stream starts and whenever the version
+// changes. Comments are sent every watchHeartbeat to keep the stream
+// alive, so that a stream which stays silent longer has dropped.
+const WatchPath = "/v1/watch/"
+
+// watchHeartbeat is how often watch streams are kept alive. The version
+// is checked then as well, to catch changes made through another
+// enroute-cp sharing the store.
+var watchHeartbeat = 30 * time.Second
+
+// watchHub wakes up the watch streams when the configuration may have
+// changed.
+type watchHub struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+var configChanges watchHub
+
+// changed returns a channel closed on the next call to notify.
+func (h *watchHub) changed() <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.ch == nil {
+		h.ch = make(chan struct{})
+	}
+	return h.ch
+}
+
+func (h *watchHub) notify() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.ch != nil {
+		close(h.ch)
+		h.ch = nil
+	}
+}
+
+// configVersion returns the version of the configuration of proxy_name,
+// a digest of it as kept in revisions. A proxy which does not exist has an
+// empty configuration.
+func configVersion(proxy_name string) (string, error) {
+	var config json.RawMessage
+	pd, err := DB.ProxyDetail(proxy_name)
+	switch {
+	case err == nil:
+		if config, err = revisionConfig(pd); err != nil {
+			return "", err
+		}
+	case !isNotFound(err):
+		return "", err
+	}
+	sum := sha256.Sum256(config)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+func writeVersion(w *echo.Response, proxy_name, version string) error {
+	b, err := json.Marshal(map[string]string{"proxy_name": proxy_name, "version": version})
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: version\ndata: %s\n\n", b); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
+
+// @Summary Watch the configuration of a proxy
+// @Description Stream server-sent events with the version of the configuration of a proxy, when the stream starts and whenever it changes
+// @Tags proxy
+// @Produce text/event-stream
+// @Param proxy_name path string true "Name of proxy"
+// @Success 200 {} int OK
+// @Router /v1/watch/{proxy_name} [get]
+// @Security ApiKeyAuth
+func GET_Watch(c echo.Context) error {
+	log2 := logrus.StandardLogger()
+	log := log2.WithField("context", "web-http")
+
+	proxy_name := c.Param("proxy_name")
+
+	// Changes made from now on wake up the stream, so none is missed
+	changed := configChanges.changed()
+	version, err := configVersion(proxy_name)
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := writeVersion(w, proxy_name, version); err != nil {
+		return nil
+	}
+
+	heartbeat := time.NewTicker(watchHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-changed:
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return nil
+			}
+			w.Flush()
+		}
+
+		changed = configChanges.changed()
+		v, err := configVersion(proxy_name)
+		if err != nil {
+			log.Errorf("Error when computing version of proxy %s [%v]\n", proxy_name, err)
+			continue
+		}
+		if v == version {
+			continue
+		}
+		version = v
+		if err := writeVersion(w, proxy_name, version); err != nil {
+			return nil
+		}
+	}
+}
+
+func Add_watch_routes(e *echo.Echo) {
+	e.GET(WatchPath+":proxy_name", GET_Watch)
+}
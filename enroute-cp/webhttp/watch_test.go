@@ -0,0 +1,64 @@
+package webhttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGETWatch(t *testing.T) {
+	api := newTestAPI(t, RecordRevisions)
+	Add_proxy_routes(api.Echo)
+	Add_watch_routes(api.Echo)
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+WatchPath+"gw", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get(echo.HeaderContentType))
+
+	lines := bufio.NewScanner(resp.Body)
+	next := func() map[string]string {
+		var event map[string]string
+		for lines.Scan() {
+			l := lines.Text()
+			if strings.HasPrefix(l, "data: ") {
+				require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(l, "data: ")), &event))
+			}
+			if l == "" && event != nil {
+				return event
+			}
+		}
+		require.NoError(t, lines.Err())
+		return nil
+	}
+
+	first := next()
+	assert.Equal(t, "gw", first["proxy_name"])
+	assert.NotEmpty(t, first["version"])
+
+	// Reading changes nothing, creating the proxy changes its version
+	api.do(http.MethodGet, "/proxy", "")
+	require.Equal(t, http.StatusCreated, api.do(http.MethodPost, "/proxy", `{"name": "gw"}`).Code)
+
+	second := next()
+	assert.NotEqual(t, first["version"], second["version"])
+	v, err := configVersion("gw")
+	require.NoError(t, err)
+	assert.Equal(t, v, second["version"])
+}
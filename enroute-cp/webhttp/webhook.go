@@ -0,0 +1,320 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package webhttp
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/sirupsen/logrus"
+)
+
+// Webhooks are posted an event for every change to a proxy, service,
+// route, upstream, filter, secret or global config. An event is named
+// kind.action, like service.created, route.updated or secret.deleted, and
+// a webhook only gets the events matching one of its patterns, like
+// service.* or *.deleted, or all of them if it has none.
+//
+// Events are posted in json after the change is made, along with
+//
+//  X-Enroute-Event      the name of the event
+//  X-Enroute-Delivery   the id of the event
+//  X-Enroute-Signature  sha256=<hex HMAC-SHA256 of the body with the
+//                       secret of the webhook>, if it has one
+//
+// Posting an event fails on errors and on responses other than 2xx. It is
+// retried with exponential backoff, unless the webhook responded with a
+// 4xx other than 429, and given up after webhookAttempts. Events are posted
+// concurrently, so they may arrive out of order.
+
+var (
+	webhookClient   = &http.Client{Timeout: 10 * time.Second}
+	webhookAttempts = 5
+	webhookBackoff  = time.Second
+)
+
+// webhookKinds are the kinds of objects whose changes are posted
+var webhookKinds = map[string]bool{
+	"proxy":        true,
+	"service":      true,
+	"route":        true,
+	"upstream":     true,
+	"filter":       true,
+	"secret":       true,
+	"globalconfig": true,
+}
+
+type Webhook struct {
+	Webhook_name   string   `json:"webhook_name" xml:"webhook_name" form:"webhook_name" query:"webhook_name" example:"ci"`
+	Webhook_url    string   `json:"webhook_url" xml:"webhook_url" form:"webhook_url" query:"webhook_url" example:"https://ci.example.com/enroute"`
+	Webhook_secret string   `json:"webhook_secret" xml:"webhook_secret" form:"webhook_secret" query:"webhook_secret"`
+	Webhook_events []string `json:"webhook_events" xml:"webhook_events" form:"webhook_events" query:"webhook_events"`
+}
+
+// The secret of webhooks is never returned
+const webhookFields = `webhook_id webhook_name webhook_url webhook_events create_ts`
+
+// WebhookEvent is what is posted to webhooks.
+type WebhookEvent struct {
+	ID    string `json:"event_id"`
+	Event string `json:"event"`
+
+	// Object is the object changed as kind:name, like in the audit log,
+	// and Objects all those the change was made to, like the proxy and
+	// the service it was associated with.
+	Object  string   `json:"object"`
+	Objects []string `json:"objects"`
+
+	// Before and After hold the object in json with secret keys
+	// redacted, or null if it did not exist.
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+
+	// Proxies are the proxies whose configuration was changed.
+	Proxies []string `json:"proxies"`
+
+	// Author is the token the change was made with, or else the address
+	// it came from.
+	Author string    `json:"author"`
+	Time   time.Time `json:"time"`
+}
+
+// newWebhookEvent returns the event of the change of object from before
+// to after, or nil if it is not posted.
+func newWebhookEvent(kind, name string, objects []string, before, after json.RawMessage, proxies []string, author string) *WebhookEvent {
+	if !webhookKinds[kind] {
+		return nil
+	}
+
+	action := "updated"
+	switch {
+	case before == nil && after == nil:
+		return nil
+	case before == nil:
+		action = "created"
+	case after == nil:
+		action = "deleted"
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil
+	}
+	return &WebhookEvent{
+		ID:      hex.EncodeToString(b),
+		Event:   kind + "." + action,
+		Object:  kind + ":" + name,
+		Objects: objects,
+		Before:  before,
+		After:   after,
+		Proxies: proxies,
+		Author:  author,
+		Time:    time.Now().UTC(),
+	}
+}
+
+// webhookMatches returns true if w is posted events named event.
+func webhookMatches(w *store.Webhook, event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, p := range w.Events {
+		if ok, _ := path.Match(p, event); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookSignature returns the signature of body with secret.
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// postWebhook posts body to w once, returning whether it may be retried
+// if it fails.
+func postWebhook(w *store.Webhook, ev *WebhookEvent, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-Enroute-Event", ev.Event)
+	req.Header.Set("X-Enroute-Delivery", ev.ID)
+	if w.Secret != "" {
+		req.Header.Set("X-Enroute-Signature", webhookSignature(w.Secret, body))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook responded %s", resp.Status)
+	default:
+		return false, fmt.Errorf("webhook responded %s", resp.Status)
+	}
+}
+
+// deliverWebhook posts body to w, retrying with exponential backoff.
+func deliverWebhook(w store.Webhook, ev *WebhookEvent, body []byte) {
+	log := logrus.StandardLogger().WithField("context", "webhook")
+
+	backoff := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := postWebhook(&w, ev, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= webhookAttempts {
+			log.Errorf("Failed to post event [%s] [%s] to webhook [%s] after %d attempts [%v]\n",
+				ev.ID, ev.Event, w.Name, attempt, err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// sendWebhooks posts ev to the webhooks matching it, in the background.
+func sendWebhooks(ev *WebhookEvent) {
+	if ev == nil {
+		return
+	}
+	log := logrus.StandardLogger().WithField("context", "webhook")
+
+	ws, err := DB.ListWebhooks()
+	if err != nil {
+		log.Errorf("Error when listing webhooks [%v]\n", err)
+		return
+	}
+	var body []byte
+	for _, w := range ws {
+		if !webhookMatches(&w, ev.Event) {
+			continue
+		}
+		if body == nil {
+			if body, err = json.Marshal(ev); err != nil {
+				log.Errorf("Error when encoding event [%v]\n", err)
+				return
+			}
+		}
+		go deliverWebhook(w, ev, body)
+	}
+}
+
+// @Summary Create a webhook
+// @Description Create a webhook posted the events matching its patterns, like service.* or *.deleted, or all of them if it has none.
+// @Description Events are signed with HMAC-SHA256 in header X-Enroute-Signature if the webhook has a secret.
+// @Tags webhook
+// @Accept  json
+// @Produce  json
+// @Param Webhook body webhttp.Webhook true "Webhook to create"
+// @Success 201 {} int OK
+// @Failure 400 {object} webhttp.ErrorResponse
+// @Failure 409 {object} webhttp.ErrorResponse
+// @Router /webhook [post]
+// @Security ApiKeyAuth
+func POST_Webhook(c echo.Context) error {
+	wh := new(Webhook)
+	if err := c.Bind(wh); err != nil {
+		return err
+	}
+
+	if len(wh.Webhook_name) == 0 {
+		return badRequest(c, invalid("webhook", "", "webhook_name", "Please provide webhook name using webhook_name field"))
+	}
+	u, err := url.Parse(wh.Webhook_url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return badRequest(c, invalid("webhook", wh.Webhook_name, "webhook_url", "Please provide an http or https url using webhook_url field"))
+	}
+	for _, p := range wh.Webhook_events {
+		if _, err := path.Match(p, ""); err != nil {
+			return badRequest(c, invalid("webhook", wh.Webhook_name, "webhook_events", "Invalid event pattern %s", p))
+		}
+	}
+
+	w := &store.Webhook{
+		Name:   wh.Webhook_name,
+		URL:    wh.Webhook_url,
+		Secret: wh.Webhook_secret,
+		Events: wh.Webhook_events,
+	}
+	if err := DB.CreateWebhook(w); err != nil {
+		return dbError(c, err)
+	}
+	return dbRows(c, http.StatusCreated, "saaras_db_webhook", []store.Webhook{*w}, webhookFields)
+}
+
+// @Summary List webhooks
+// @Description List webhooks, without their secret
+// @Tags webhook
+// @Accept  json
+// @Produce  json
+// @Success 200 {} int OK
+// @Router /webhook [get]
+// @Security ApiKeyAuth
+func GET_Webhook(c echo.Context) error {
+	ws, err := DB.ListWebhooks()
+	return dbList(c, http.StatusOK, "saaras_db_webhook", ws, err, webhookFields)
+}
+
+// @Summary Get a webhook
+// @Description Get a webhook, without its secret
+// @Tags webhook
+// @Accept  json
+// @Produce  json
+// @Param webhook_name path string true "Name of webhook"
+// @Success 200 {} int OK
+// @Router /webhook/{webhook_name} [get]
+// @Security ApiKeyAuth
+func GET_One_Webhook(c echo.Context) error {
+	w, err := DB.GetWebhook(c.Param("webhook_name"))
+	return dbOne(c, http.StatusOK, "saaras_db_webhook", w, err, webhookFields)
+}
+
+// @Summary Delete a webhook
+// @Description Delete a webhook, which is not posted events anymore
+// @Tags webhook
+// @Accept  json
+// @Produce  json
+// @Param webhook_name path string true "Name of webhook"
+// @Success 200 {} int OK
+// @Failure 404 {object} webhttp.ErrorResponse
+// @Router /webhook/{webhook_name} [delete]
+// @Security ApiKeyAuth
+func DELETE_Webhook(c echo.Context) error {
+	if err := DB.DeleteWebhook(c.Param("webhook_name")); err != nil {
+		return dbError(c, err)
+	}
+	return dbAffected(c, http.StatusOK, "delete_saaras_db_webhook", 1)
+}
+
+func Add_webhook_routes(e *echo.Echo) {
+	e.POST("/webhook", POST_Webhook)
+	e.GET("/webhook", GET_Webhook)
+	e.GET("/webhook/:webhook_name", GET_One_Webhook)
+	e.DELETE("/webhook/:webhook_name", DELETE_Webhook)
+}
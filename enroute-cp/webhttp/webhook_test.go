@@ -0,0 +1,158 @@
+package webhttp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type postedEvent struct {
+	header http.Header
+	body   []byte
+	event  WebhookEvent
+}
+
+// webhookServer returns a server sending the events posted to it on the
+// channel, after failing the first fail posts.
+func webhookServer(t *testing.T, fail int32) (*httptest.Server, chan postedEvent) {
+	events := make(chan postedEvent, 10)
+	var posts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&posts, 1) <= fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var ev WebhookEvent
+		require.NoError(t, json.Unmarshal(body, &ev))
+		events <- postedEvent{r.Header, body, ev}
+	}))
+	return srv, events
+}
+
+func receive(t *testing.T, events chan postedEvent) postedEvent {
+	select {
+	case p := <-events:
+		return p
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event posted")
+	}
+	return postedEvent{}
+}
+
+func TestWebhooks(t *testing.T) {
+	backoff := webhookBackoff
+	webhookBackoff = time.Millisecond
+	defer func() { webhookBackoff = backoff }()
+
+	all, allEvents := webhookServer(t, 2)
+	defer all.Close()
+	upstreams, upstreamEvents := webhookServer(t, 0)
+	defer upstreams.Close()
+
+	api := newTestAPI(t, Audit, RecordRevisions)
+	Add_proxy_routes(api.Echo)
+	Add_service_routes(api.Echo)
+	Add_upstream_routes(api.Echo)
+	Add_webhook_routes(api.Echo)
+	do := api.do
+
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/webhook",
+		`{"webhook_name": "all", "webhook_url": "`+all.URL+`", "webhook_secret": "key"}`).Code)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/webhook",
+		`{"webhook_name": "upstreams", "webhook_url": "`+upstreams.URL+`", "webhook_events": ["upstream.*"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/webhook", `{"webhook_name": "x", "webhook_url": "ftp://example.com"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/webhook",
+		`{"webhook_name": "x", "webhook_url": "https://example.com", "webhook_events": ["["]}`).Code)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/webhook", `{"webhook_name": "all", "webhook_url": "https://example.com"}`).Code)
+
+	// Secrets are not returned
+	rec := do(http.MethodGet, "/webhook/all", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), all.URL)
+	assert.NotContains(t, rec.Body.String(), "webhook_secret")
+
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/proxy", `{"name": "gw"}`).Code)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/service", `{"service_name": "svc", "fqdn": "a.example.com"}`).Code)
+	require.Less(t, do(http.MethodPost, "/proxy/gw/service/svc", "").Code, 300)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPatch, "/upstream/nope", `{"upstream_port": 80}`).Code)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/upstream",
+		`{"upstream_name": "u", "upstream_ip": "10.0.0.1", "upstream_port": 80, "upstream_hc_path": "/", "upstream_weight": 1}`).Code)
+
+	// Events are retried, and may arrive in any order
+	posted := map[string]postedEvent{}
+	for i := 0; i < 4; i++ {
+		p := receive(t, allEvents)
+		posted[p.event.Event] = p
+	}
+
+	p := posted["proxy.created"]
+	assert.Equal(t, "proxy:gw", p.event.Object)
+	assert.Equal(t, []string{"gw"}, p.event.Proxies)
+
+	p = posted["service.created"]
+	assert.Equal(t, "service:svc", p.event.Object)
+	assert.JSONEq(t, "null", string(p.event.Before))
+	assert.Contains(t, string(p.event.After), "a.example.com")
+	assert.Empty(t, p.event.Proxies)
+
+	p = posted["service.updated"]
+	assert.Equal(t, "service:svc", p.event.Object)
+	assert.Equal(t, []string{"proxy:gw", "service:svc"}, p.event.Objects)
+	assert.Equal(t, []string{"gw"}, p.event.Proxies)
+
+	p = posted["upstream.created"]
+	assert.Equal(t, "upstream:u", p.event.Object)
+	assert.Equal(t, "upstream.created", p.header.Get("X-Enroute-Event"))
+	assert.Equal(t, p.event.ID, p.header.Get("X-Enroute-Delivery"))
+	assert.Equal(t, webhookSignature("key", p.body), p.header.Get("X-Enroute-Signature"))
+
+	// Webhooks only get the events matching their patterns
+	p = receive(t, upstreamEvents)
+	assert.Equal(t, "upstream.created", p.event.Event)
+	assert.Empty(t, p.header.Get("X-Enroute-Signature"))
+
+	require.Equal(t, http.StatusOK, do(http.MethodDelete, "/webhook/upstreams", "").Code)
+	require.Equal(t, http.StatusOK, do(http.MethodDelete, "/upstream/u", "").Code)
+	p = receive(t, allEvents)
+	assert.Equal(t, "upstream.deleted", p.event.Event)
+	assert.JSONEq(t, "null", string(p.event.After))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, allEvents)
+	assert.Empty(t, upstreamEvents)
+}
+
+func TestWebhookMatches(t *testing.T) {
+	tests := map[string]struct {
+		events []string
+		event  string
+		want   bool
+	}{
+		"no patterns":    {nil, "route.created", true},
+		"kind":           {[]string{"service.*"}, "service.updated", true},
+		"other kind":     {[]string{"service.*"}, "route.updated", false},
+		"action":         {[]string{"*.deleted"}, "secret.deleted", true},
+		"any pattern":    {[]string{"proxy.*", "*.deleted"}, "filter.deleted", true},
+		"exact":          {[]string{"upstream.created"}, "upstream.created", true},
+		"other action":   {[]string{"upstream.created"}, "upstream.updated", false},
+		"invalid":        {[]string{"["}, "proxy.created", false},
+		"everything":     {[]string{"*"}, "globalconfig.updated", true},
+		"partial prefix": {[]string{"service"}, "service.created", false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, webhookMatches(&store.Webhook{Events: tc.events}, tc.event))
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package webhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/saarasio/enroute/enroute-cp/store"
+	"github.com/stretchr/testify/require"
+)
+
+// testAPI serves the routes added to it from a local store, which is DB
+// until the test ends.
+type testAPI struct {
+	*echo.Echo
+}
+
+func newTestAPI(t *testing.T, middlewares ...echo.MiddlewareFunc) *testAPI {
+	db, err := store.OpenLocal(filepath.Join(t.TempDir(), "enroute.db"))
+	require.NoError(t, err)
+	DB = db
+	t.Cleanup(func() { DB = nil })
+
+	e := echo.New()
+	e.Use(middlewares...)
+	return &testAPI{Echo: e}
+}
+
+// jsonRequest returns a request with the json body.
+func jsonRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	return req
+}
+
+func (a *testAPI) serve(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	return rec
+}
+
+// do serves a request with the json body.
+func (a *testAPI) do(method, path, body string) *httptest.ResponseRecorder {
+	return a.serve(jsonRequest(method, path, body))
+}
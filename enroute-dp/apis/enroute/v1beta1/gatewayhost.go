@@ -68,6 +68,12 @@ type TLS struct {
 	SecretName string `json:"secretName,omitempty"`
 	// Minimum TLS version this vhost should negotiate
 	MinimumProtocolVersion string `json:"minimumProtocolVersion,omitempty"`
+	// Maximum TLS version this vhost should negotiate
+	MaximumProtocolVersion string `json:"maximumProtocolVersion,omitempty"`
+	// Cipher suites this vhost should offer for TLS 1.2 and below
+	CipherSuites []string `json:"cipherSuites,omitempty"`
+	// ECDH curves this vhost should offer
+	ECDHCurves []string `json:"ecdhCurves,omitempty"`
 	// If Passthrough is set to true, the SecretName will be ignored
 	// and the encrypted handshake will be passed through to the
 	// backing cluster.
@@ -266,8 +272,9 @@ type UpstreamValidation struct {
 
 // Status reports the current state of the GatewayHost
 type Status struct {
-	CurrentStatus string `json:"currentStatus"`
-	Description   string `json:"description"`
+	CurrentStatus string   `json:"currentStatus"`
+	Description   string   `json:"description"`
+	Warnings      []string `json:"warnings,omitempty"`
 }
 
 // +genclient
@@ -38,6 +38,8 @@ func registerBootstrap(app *kingpin.Application) (*kingpin.CmdClause, *bootstrap
 	bootstrap.Flag("envoy-cafile", "gRPC CA Filename for Envoy to load").Envar("ENVOY_CAFILE").StringVar(&ctx.config.GrpcCABundle)
 	bootstrap.Flag("envoy-cert-file", "gRPC Client cert filename for Envoy to load").Envar("ENVOY_CERT_FILE").StringVar(&ctx.config.GrpcClientCert)
 	bootstrap.Flag("envoy-key-file", "gRPC Client key filename for Envoy to load").Envar("ENVOY_KEY_FILE").StringVar(&ctx.config.GrpcClientKey)
+	bootstrap.Flag("proxy-name", "Name of the proxy Envoy serves, used to select its configuration").StringVar(&ctx.config.ProxyName)
+	bootstrap.Flag("ads", "Fetch all resources over a single Aggregated Discovery Service stream").BoolVar(&ctx.config.ADS)
 	bootstrap.Flag("namespace", "The namespace the Envoy container will run in").Envar("CONTOUR_NAMESPACE").Default("saaras-enroute").StringVar(&ctx.config.Namespace)
 	return bootstrap, &ctx
 }
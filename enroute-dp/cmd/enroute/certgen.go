@@ -35,6 +35,15 @@ func registerCertGen(app *kingpin.Application) (*kingpin.CmdClause, *certgenConf
 	certgenApp.Flag("incluster", "use in cluster configuration.").BoolVar(&certgenConfig.InCluster)
 	certgenApp.Flag("kubeconfig", "path to kubeconfig (if not in running inside a cluster)").Default(filepath.Join(os.Getenv("HOME"), ".kube", "config")).StringVar(&certgenConfig.KubeConfig)
 	certgenApp.Flag("namespace", "Kubernetes namespace, used for Kube objects").Default("heptio-contour").Envar("CONTOUR_NAMESPACE").StringVar(&certgenConfig.Namespace)
+	certgenApp.Flag("keep-ca-key", "Also output the CA key, as cakey.pem or the "+certgen.CAKeyPairSecretName+" Secret, so the certs can be rotated later").BoolVar(&certgenConfig.KeepCAKey)
+	certgenApp.Flag("rotate", "Reuse the existing CA and reissue the certs that are close to expiry, requires --keep-ca-key").BoolVar(&certgenConfig.Rotate)
+	certgenApp.Flag("ca-cert-file", "CA certificate to reuse when rotating").StringVar(&certgenConfig.CACertFile)
+	certgenApp.Flag("ca-key-file", "CA key to reuse when rotating").StringVar(&certgenConfig.CAKeyFile)
+	certgenApp.Flag("ca-secret", "Kubernetes Secret holding the CA cert and key to reuse when rotating").Default(certgen.CAKeyPairSecretName).StringVar(&certgenConfig.CASecret)
+	certgenApp.Flag("renew-before", "When rotating, reissue certs expiring within this duration").Default("720h").DurationVar(&certgenConfig.RenewBefore)
+	certgenApp.Flag("key-type", "Type of the generated keys, rsa or ecdsa (P-256)").Default(string(certgen.KeyTypeRSA)).EnumVar(&certgenConfig.KeyType, string(certgen.KeyTypeRSA), string(certgen.KeyTypeECDSA))
+	certgenApp.Flag("validity", "Validity of the generated certs").Default("8760h").DurationVar(&certgenConfig.Validity)
+	certgenApp.Flag("dns-name", "Additional DNS name for the enroute and envoy certs, may be repeated").StringsVar(&certgenConfig.DNSNames)
 	certgenApp.Arg("outputdir", "Directory to output any files to").Default("certs").StringVar(&certgenConfig.OutputDir)
 
 	return certgenApp, &certgenConfig
@@ -63,71 +72,166 @@ type certgenConfig struct {
 
 	// OutputPEM means that the certs generated will be output as PEM files in the current directory.
 	OutputPEM bool
+
+	// KeepCAKey means that the CA key is output along with the certs.
+	// Otherwise it is discarded, and the certs can not be rotated.
+	KeepCAKey bool
+
+	// Rotate means that the existing CA is reused and only the certs close to expiry are reissued.
+	Rotate bool
+
+	// CACertFile and CAKeyFile hold the CA to reuse when rotating. If unset, the
+	// CA is read from CASecret when outputting to Kubernetes, or from OutputDir.
+	CACertFile string
+	CAKeyFile  string
+
+	// CASecret is the Kubernetes Secret holding the CA to reuse when rotating.
+	CASecret string
+
+	// RenewBefore is how long before expiry a cert is reissued when rotating.
+	RenewBefore time.Duration
+
+	// KeyType is the type of the generated keys.
+	KeyType string
+
+	// Validity is how long the generated certs are valid for.
+	Validity time.Duration
+
+	// DNSNames are additional SANs of the enroute and envoy certs.
+	DNSNames []string
+}
+
+// certConfig returns the certgen.CertConfig of certs generated now.
+func (c *certgenConfig) certConfig(now time.Time) certgen.CertConfig {
+	return certgen.CertConfig{
+		KeyType:  certgen.KeyType(c.KeyType),
+		Expiry:   now.Add(c.Validity),
+		DNSNames: c.DNSNames,
+	}
 }
 
 // GenerateCerts performs the actual cert generation steps and then returns the certs for the output function.
 func GenerateCerts(certConfig *certgenConfig) (map[string][]byte, error) {
 
-	now := time.Now()
-	expiry := now.Add(24 * 365 * time.Hour)
-	caCertPEM, caKeyPEM, err := certgen.NewCA("Project Contour", expiry)
+	config := certConfig.certConfig(time.Now())
+	caCertPEM, caKeyPEM, err := certgen.NewCAWithConfig("Project Contour", config)
 	if err != nil {
 		return nil, err
 	}
 
-	contourCert, contourKey, err := certgen.NewCert(caCertPEM,
-		caKeyPEM,
-		expiry,
-		"enroute",
-		certConfig.Namespace,
-	)
+	newCerts := map[string][]byte{
+		"cacert.pem": caCertPEM,
+	}
+	if certConfig.KeepCAKey {
+		newCerts["cakey.pem"] = caKeyPEM
+	}
+	for service, prefix := range map[string]string{"enroute": "contour", "envoy": "envoy"} {
+		cert, key, err := certgen.NewCertWithConfig(caCertPEM, caKeyPEM, service, certConfig.Namespace, config)
+		if err != nil {
+			return nil, err
+		}
+		newCerts[prefix+"cert.pem"] = cert
+		newCerts[prefix+"key.pem"] = key
+	}
+
+	return newCerts, nil
+
+}
+
+// RotateCerts reuses the existing CA and reissues the enroute and envoy certs
+// that are missing, not issued by the CA, or close to expiry. Certs that are
+// still valid are returned as is, so the output function can rewrite them.
+func RotateCerts(certConfig *certgenConfig, kubeclient kubernetes.Interface) (map[string][]byte, error) {
+
+	var caCertPEM, caKeyPEM []byte
+	var err error
+	switch {
+	case certConfig.CACertFile != "" || certConfig.CAKeyFile != "":
+		caCertPEM, caKeyPEM, err = certgen.ReadCAFiles(certConfig.CACertFile, certConfig.CAKeyFile)
+	case certConfig.OutputKube:
+		caCertPEM, caKeyPEM, err = certgen.ReadCAKube(kubeclient, certConfig.Namespace, certConfig.CASecret)
+	default:
+		caCertPEM, caKeyPEM, err = certgen.ReadCAFiles(filepath.Join(certConfig.OutputDir, "cacert.pem"), filepath.Join(certConfig.OutputDir, "cakey.pem"))
+	}
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("reading CA: %v", err)
+	}
+
+	var existing map[string][]byte
+	if certConfig.OutputKube {
+		existing, err = certgen.ReadSecretsKube(kubeclient, certConfig.Namespace)
+	} else {
+		existing, err = certgen.ReadCertsPEM(certConfig.OutputDir)
 	}
-	envoyCert, envoyKey, err := certgen.NewCert(caCertPEM,
-		caKeyPEM,
-		expiry,
-		"envoy",
-		certConfig.Namespace,
-	)
 	if err != nil {
 		return nil, err
 	}
-	newCerts := make(map[string][]byte)
-	newCerts["cacert.pem"] = caCertPEM
-	newCerts["contourcert.pem"] = contourCert
-	newCerts["contourkey.pem"] = contourKey
-	newCerts["envoycert.pem"] = envoyCert
-	newCerts["envoykey.pem"] = envoyKey
 
-	return newCerts, nil
+	now := time.Now()
+	if certgen.NeedsRenewal(caCertPEM, caCertPEM, now, certConfig.RenewBefore) {
+		fmt.Fprintf(os.Stderr, "CA certificate expires within %v, run certgen with --keep-ca-key but without --rotate to generate a new CA\n", certConfig.RenewBefore)
+	}
 
+	config := certConfig.certConfig(now)
+	certs := map[string][]byte{
+		"cacert.pem": caCertPEM,
+		"cakey.pem":  caKeyPEM,
+	}
+	for service, prefix := range map[string]string{"enroute": "contour", "envoy": "envoy"} {
+		cert, key := existing[prefix+"cert.pem"], existing[prefix+"key.pem"]
+		if len(key) == 0 || certgen.NeedsRenewal(cert, caCertPEM, now, certConfig.RenewBefore) {
+			cert, key, err = certgen.NewCertWithConfig(caCertPEM, caKeyPEM, service, certConfig.Namespace, config)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Printf("%s cert reissued\n", service)
+		}
+		certs[prefix+"cert.pem"] = cert
+		certs[prefix+"key.pem"] = key
+	}
+
+	return certs, nil
 }
 
 // OutputCerts outputs the certs in certs as directed by config.
+// When rotating, existing files and Secrets are overwritten.
 func OutputCerts(config *certgenConfig,
-	kubeclient *kubernetes.Clientset,
+	kubeclient kubernetes.Interface,
 	certs map[string][]byte) {
 
 	if config.OutputPEM {
 		fmt.Printf("Outputting certs to PEM files in %s/\n", config.OutputDir)
-		check(certgen.WriteCertsPEM(config.OutputDir, certs))
+		check(certgen.WriteCertsPEM(config.OutputDir, certs, config.Rotate))
 	}
 
 	if config.OutputYAML {
 		fmt.Printf("Outputting certs to YAML files in %s/\n", config.OutputDir)
-		check(certgen.WriteSecretsYAML(config.OutputDir, config.Namespace, certs))
+		check(certgen.WriteSecretsYAML(config.OutputDir, config.Namespace, certs, config.Rotate))
 	}
 
 	if config.OutputKube {
 		fmt.Printf("Outputting certs to Kubernetes in namespace %s/\n", config.Namespace)
-		check(certgen.WriteSecretsKube(kubeclient, config.Namespace, certs))
+		check(certgen.WriteSecretsKube(kubeclient, config.Namespace, certs, config.Rotate))
 	}
 }
 
 func doCertgen(config *certgenConfig) {
-	generatedCerts, err := GenerateCerts(config)
+	var kubeclient kubernetes.Interface
+	if config.OutputKube {
+		kubeclient, _ = newClient(config.KubeConfig, config.InCluster)
+	}
+
+	if config.Rotate && !config.KeepCAKey {
+		check(fmt.Errorf("--rotate requires --keep-ca-key, as the CA key is written back along with the reissued certs"))
+	}
+
+	var generatedCerts map[string][]byte
+	var err error
+	if config.Rotate {
+		generatedCerts, err = RotateCerts(config, kubeclient)
+	} else {
+		generatedCerts, err = GenerateCerts(config)
+	}
 	check(err)
-	kubeclient, _ := newClient(config.KubeConfig, config.InCluster)
 	OutputCerts(config, kubeclient, generatedCerts)
 }
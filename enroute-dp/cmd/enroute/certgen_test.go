@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRotateCertsPEM(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := &certgenConfig{
+		Namespace:   "enroute",
+		OutputDir:   dir,
+		OutputPEM:   true,
+		KeepCAKey:   true,
+		KeyType:     "ecdsa",
+		Validity:    365 * 24 * time.Hour,
+		RenewBefore: 30 * 24 * time.Hour,
+	}
+	generated, err := GenerateCerts(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	OutputCerts(config, nil, generated)
+
+	// nothing is close to expiry, so rotating keeps all certs.
+	config.Rotate = true
+	rotated, err := RotateCerts(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range generated {
+		if !bytes.Equal(v, rotated[k]) {
+			t.Fatalf("%s: expected cert to be kept", k)
+		}
+	}
+
+	// everything is close to expiry, so rotating reissues the certs with the same CA.
+	config.RenewBefore = 2 * 365 * 24 * time.Hour
+	rotated, err = RotateCerts(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"cacert.pem", "cakey.pem"} {
+		if !bytes.Equal(generated[k], rotated[k]) {
+			t.Fatalf("%s: expected CA to be reused", k)
+		}
+	}
+	for _, k := range []string{"contourcert.pem", "contourkey.pem", "envoycert.pem", "envoykey.pem"} {
+		if bytes.Equal(generated[k], rotated[k]) {
+			t.Fatalf("%s: expected cert to be reissued", k)
+		}
+	}
+	OutputCerts(config, nil, rotated)
+
+	written, err := ioutil.ReadFile(dir + "/envoycert.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(written, rotated["envoycert.pem"]) {
+		t.Fatal("expected rotated cert to overwrite existing file")
+	}
+}
+
+func TestGenerateCertsDiscardsCAKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := &certgenConfig{
+		Namespace: "enroute",
+		OutputDir: dir,
+		OutputPEM: true,
+		KeyType:   "ecdsa",
+		Validity:  365 * 24 * time.Hour,
+	}
+	generated, err := GenerateCerts(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := generated["cakey.pem"]; ok {
+		t.Fatal("expected CA key not to be kept")
+	}
+	OutputCerts(config, nil, generated)
+	if _, err := os.Stat(dir + "/cakey.pem"); !os.IsNotExist(err) {
+		t.Fatalf("expected cakey.pem not to be written, got %v", err)
+	}
+}
@@ -17,7 +17,7 @@ import (
 	"fmt"
 	"os"
 
-	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	clientset "github.com/saarasio/enroute/enroute-dp/apis/generated/clientset/versioned"
 	"github.com/sirupsen/logrus"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
@@ -35,6 +35,8 @@ func main() {
 
 	certgenApp, certgenConfig := registerCertGen(app)
 
+	dryrun, dryrunCtx := registerDryRun(app)
+
 	cli := app.Command("cli", "A CLI client for enroute agent")
 	var client Client
 	cli.Flag("enroute", "enroute host:port.").Default("127.0.0.1:8001").StringVar(&client.ContourAddr)
@@ -62,6 +64,8 @@ func main() {
 		doBootstrap(bootstrapCtx)
 	case certgenApp.FullCommand():
 		doCertgen(certgenConfig)
+	case dryrun.FullCommand():
+		doDryRun(dryrunCtx)
 	case cds.FullCommand():
 		stream := client.ClusterStream()
 		watchstream(stream, resource.ClusterType, resources)
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// registerDryRun registers the dryrun subcommand and flags
+// with the Application provided.
+func registerDryRun(app *kingpin.Application) (*kingpin.CmdClause, *dryrunContext) {
+	var ctx dryrunContext
+	dryrun := app.Command("dryrun", "Show how GatewayHosts, filters, and global configs would change the configuration served, without applying them")
+	dryrun.Flag("debug-http", "address of the debug http endpoint of the enroute serving the configuration").Default("127.0.0.1:6060").StringVar(&ctx.debugAddr)
+	dryrun.Flag("proxy", "Name of the proxy whose configuration is changed, when more than one is served").StringVar(&ctx.proxy)
+	dryrun.Flag("replace", "Replace every GatewayHost, filter, and global config with those given, rather than adding to or updating them").BoolVar(&ctx.replace)
+	dryrun.Arg("files", "YAML or JSON files of the objects, - for stdin").Required().StringsVar(&ctx.files)
+	return dryrun, &ctx
+}
+
+// dryrunContext holds the configuration of the dryrun subcommand.
+type dryrunContext struct {
+	// debugAddr is the host:port of the debug http endpoint.
+	debugAddr string
+
+	// proxy names the proxy whose configuration is changed. It may be
+	// empty when a single proxy is served.
+	proxy string
+
+	// replace sets whether the objects replace, rather than add to, those
+	// the configuration is built from.
+	replace bool
+
+	// files hold the objects.
+	files []string
+}
+
+// doDryRun posts the objects to the dry run endpoint, and writes the
+// result to stdout.
+func doDryRun(ctx *dryrunContext) {
+	var body bytes.Buffer
+	for _, name := range ctx.files {
+		var buf []byte
+		var err error
+		if name == "-" {
+			buf, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			buf, err = ioutil.ReadFile(name)
+		}
+		check(err)
+		// each file holds one or more documents.
+		body.WriteString("\n---\n")
+		body.Write(buf)
+	}
+
+	resp, err := http.Post(ctx.url(), "application/yaml", &body)
+	check(err)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		check(fmt.Errorf("dry run failed: %s: %s", resp.Status, bytes.TrimSpace(msg)))
+	}
+	_, err = io.Copy(os.Stdout, resp.Body)
+	check(err)
+}
+
+// url returns the url of the dry run endpoint, with the query parameters
+// which select the proxy and whether the objects replace those it has.
+func (ctx *dryrunContext) url() string {
+	q := url.Values{}
+	if ctx.proxy != "" {
+		q.Set("proxy", ctx.proxy)
+	}
+	if ctx.replace {
+		q.Set("replace", "true")
+	}
+	u := url.URL{Scheme: "http", Host: ctx.debugAddr, Path: "/debug/dryrun", RawQuery: q.Encode()}
+	return u.String()
+}
@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package main
+
+import "testing"
+
+func TestDryRunContextURL(t *testing.T) {
+	tests := map[string]struct {
+		ctx  dryrunContext
+		want string
+	}{
+		"default": {
+			ctx:  dryrunContext{debugAddr: "127.0.0.1:6060"},
+			want: "http://127.0.0.1:6060/debug/dryrun",
+		},
+		"proxy": {
+			ctx:  dryrunContext{debugAddr: "127.0.0.1:6060", proxy: "gw"},
+			want: "http://127.0.0.1:6060/debug/dryrun?proxy=gw",
+		},
+		"replace": {
+			ctx:  dryrunContext{debugAddr: "127.0.0.1:6060", replace: true},
+			want: "http://127.0.0.1:6060/debug/dryrun?replace=true",
+		},
+		"proxy and replace": {
+			ctx:  dryrunContext{debugAddr: "127.0.0.1:6060", proxy: "gw 1", replace: true},
+			want: "http://127.0.0.1:6060/debug/dryrun?proxy=gw+1&replace=true",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := tc.ctx.url()
+			if tc.want != got {
+				t.Fatalf("expected: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -14,22 +14,21 @@
 package main
 
 import (
-	"crypto/rand"
 	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
-	"io/ioutil"
-	"log"
 	"net"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	clientset "github.com/saarasio/enroute/enroute-dp/apis/generated/clientset/versioned"
 	contourinformers "github.com/saarasio/enroute/enroute-dp/apis/generated/informers/externalversions"
+	"github.com/saarasio/enroute/enroute-dp/internal/certreload"
 	"github.com/saarasio/enroute/enroute-dp/internal/contour"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
 	"github.com/saarasio/enroute/enroute-dp/internal/debug"
@@ -37,9 +36,11 @@ import (
 	"github.com/saarasio/enroute/enroute-dp/internal/httpsvc"
 	"github.com/saarasio/enroute/enroute-dp/internal/k8s"
 	"github.com/saarasio/enroute/enroute-dp/internal/metrics"
+	"github.com/saarasio/enroute/enroute-dp/internal/snapshot"
 	"github.com/saarasio/enroute/enroute-dp/internal/workgroup"
 	"github.com/saarasio/enroute/enroute-dp/saaras"
 	"github.com/sirupsen/logrus"
+	grpcpkg "google.golang.org/grpc"
 	"gopkg.in/alecthomas/kingpin.v2"
 	coreinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
@@ -85,6 +86,7 @@ func registerServe(app *kingpin.Application) (*kingpin.CmdClause, *serveContext)
 
 	serve.Flag("xds-address", "xDS gRPC API address").Default("127.0.0.1").StringVar(&ctx.xdsAddr)
 	serve.Flag("xds-port", "xDS gRPC API port").Default("8001").IntVar(&ctx.xdsPort)
+	serve.Flag("xds-v2", "Also serve the v2 xDS API, for Envoys not yet using v3").BoolVar(&ctx.xdsV2)
 
 	serve.Flag("rl-address", "Rate Limit gRPC API address").Default("127.0.0.1").StringVar(&ctx.rlAddr)
 	serve.Flag("rl-port", "Rate Limit gRPC API port").Default("8003").IntVar(&ctx.rlPort)
@@ -103,6 +105,8 @@ func registerServe(app *kingpin.Application) (*kingpin.CmdClause, *serveContext)
 	serve.Flag("contour-key-file", "Contour key file name for serving gRPC over TLS").Envar("CONTOUR_KEY_FILE").StringVar(&ctx.contourKey)
 
 	serve.Flag("gatewayhost-root-namespaces", "Restrict contour to searching these namespaces for root gateway hosts").StringVar(&ctx.rootNamespaces)
+	serve.Flag("cert-expiry-warning", "Report a warning in the GatewayHost status when its TLS certificate expires within this duration").Default("336h").DurationVar(&ctx.certExpiryWarning)
+	serve.Flag("cert-expiry-check-interval", "How often certificates are checked for expiry when the configuration does not change, 0 to only check on changes").Default("1h").DurationVar(&ctx.certExpiryCheckInterval)
 
 	serve.Flag("ingress-class-name", "Contour IngressClass name").StringVar(&ctx.ingressClass)
 
@@ -116,8 +120,16 @@ func registerServe(app *kingpin.Application) (*kingpin.CmdClause, *serveContext)
 
 	serve.Flag("enroute-cp-ip", "IP address of enroute control plane").StringVar(&saaras.ENROUTE_CP_SERVER_IP)
 	serve.Flag("enroute-cp-port", "Port of enroute control plane").StringVar(&saaras.ENROUTE_CP_SERVER_PORT)
-	serve.Flag("enroute-name", "Name of this enroute instance - used to query its configuration").StringVar(&saaras.ENROUTE_NAME)
+	serve.Flag("enroute-name", "Name of a proxy whose configuration is served - may be repeated to serve several proxies").StringsVar(&ctx.enrouteNames)
 	serve.Flag("enroute-cp-proto", "Specify protocol to use - valid options are HTTP/HTTPS").StringVar(&saaras.ENROUTE_CP_PROTO)
+	serve.Flag("enroute-cp-token", "Key to authenticate to enroute control plane with - its WEBAPP_SECRET or an API token, like a read-only one scoped to the proxies served").Envar("ENROUTE_CP_TOKEN").StringVar(&saaras.ENROUTE_CP_TOKEN)
+
+	serve.Flag("holdoff-delay", "How long to hold a change for before rebuilding the configuration, in the hope more follow").DurationVar((*time.Duration)(&ctx.HoldoffDelay))
+	serve.Flag("holdoff-max-delay", "The longest to hold changes for while more keep arriving").DurationVar((*time.Duration)(&ctx.HoldoffMaxDelay))
+	serve.Flag("holdoff-adaptive", "Lengthen the holdoff delays when rebuilding the configuration is expensive").BoolVar(&ctx.HoldoffAdaptive)
+
+	serve.Flag("snapshot-dir", "Directory to save the configuration served to, and restore it from on restart").StringVar(&ctx.snapshotDir)
+	serve.Flag("snapshot-key-file", "File holding a base64 encoded 256 bit AES key to encrypt the secrets of snapshots with").StringVar(&ctx.snapshotKeyFile)
 
 	serve.Flag("mode-ingress", "Set to true to run enroute in ingress mode").BoolVar(&ctx.modeIngress)
 	serve.Flag("enable-ratelimit", "Set to true to enable ratelimit").BoolVar(&ctx.ratelimitEnabled)
@@ -133,6 +145,7 @@ type serveContext struct {
 	// contour's xds service parameters
 	xdsAddr                         string
 	xdsPort                         int
+	xdsV2                           bool
 	caFile, contourCert, contourKey string
 
 	// enroute's rate-limit service parameters
@@ -150,6 +163,12 @@ type serveContext struct {
 	// gatewayhost root namespaces
 	rootNamespaces string
 
+	// warn of certificates expiring within this duration
+	certExpiryWarning time.Duration
+
+	// rebuild this often to check certificates for expiry again
+	certExpiryCheckInterval time.Duration
+
 	// ingress class
 	ingressClass string
 
@@ -170,13 +189,151 @@ type serveContext struct {
 	httpsPort      int
 	httpsAccessLog string
 
+	// names of the proxies served in standalone mode
+	enrouteNames []string
+
+	// snapshots of the configuration served, restored on restart
+	snapshotDir     string
+	snapshotKeyFile string
+
+	// configuration rebuild holdoff parameters
+	HoldoffDelay    duration `json:"holdoff-delay"`
+	HoldoffMaxDelay duration `json:"holdoff-max-delay"`
+	HoldoffAdaptive bool     `json:"holdoff-adaptive"`
+
 	modeIngress      bool
 	ratelimitEnabled bool
 }
 
-// tlsconfig returns a new *tls.Config. If the context is not properly configured
-// for tls communication, tlsconfig returns nil.
-func (ctx *serveContext) tlsconfig() *tls.Config {
+// duration is a time.Duration which is read from the config file
+// as a string, for example "250ms".
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+// proxyCaches translates the configuration of a proxy to the xDS
+// resources served to its Envoys.
+type proxyCaches struct {
+	name string
+	ch   *contour.CacheHandler
+	hn   *contour.HoldoffNotifier
+	reh  *contour.ResourceEventHandler
+	et   *contour.EndpointsTranslator
+	pct  *contour.GlobalConfigTranslator
+}
+
+// newProxyCaches returns the caches of the proxy called name.
+func (ctx *serveContext) newProxyCaches(name string, log logrus.FieldLogger, rlsync chan string) *proxyCaches {
+	if name != "" {
+		log = log.WithField("proxy", name)
+	}
+
+	// establish our (poorly named) gRPC cache handler.
+	ch := &contour.CacheHandler{
+		ListenerVisitorConfig: contour.ListenerVisitorConfig{
+			UseProxyProto:  ctx.useProxyProto,
+			HTTPAddress:    ctx.httpAddr,
+			HTTPPort:       ctx.httpPort,
+			HTTPAccessLog:  ctx.httpAccessLog,
+			HTTPSAddress:   ctx.httpsAddr,
+			HTTPSPort:      ctx.httpsPort,
+			HTTPSAccessLog: ctx.httpsAccessLog,
+		},
+		ListenerCache:     contour.NewListenerCache(ctx.statsAddr, ctx.statsPort),
+		FieldLogger:       log.WithField("context", "CacheHandler"),
+		GatewayHostStatus: &k8s.GatewayHostStatus{},
+		Proxy:             name,
+	}
+
+	// changes are coalesced before the configuration is rebuilt.
+	hn := &contour.HoldoffNotifier{
+		Notifier:    ch,
+		FieldLogger: log.WithField("context", "HoldoffNotifier"),
+		Delay:       time.Duration(ctx.HoldoffDelay),
+		MaxDelay:    time.Duration(ctx.HoldoffMaxDelay),
+		Adaptive:    ctx.HoldoffAdaptive,
+		Proxy:       name,
+	}
+
+	// wrap the gRPC cache handler in a k8s resource event handler.
+	reh := &contour.ResourceEventHandler{
+		Notifier: hn,
+		KubernetesCache: dag.KubernetesCache{
+			GatewayHostRootNamespaces: ctx.gatewayHostRootNamespaces(),
+			CertExpiryWarningWindow:   ctx.certExpiryWarning,
+		},
+		IngressClass: ctx.ingressClass,
+		FieldLogger:  log.WithField("context", "resourceEventHandler"),
+	}
+
+	return &proxyCaches{
+		name: name,
+		ch:   ch,
+		hn:   hn,
+		reh:  reh,
+		et: &contour.EndpointsTranslator{
+			FieldLogger: log.WithField("context", "endpointstranslator"),
+		},
+		pct: &contour.GlobalConfigTranslator{
+			FieldLogger:          log.WithField("context", "proxyconfigtranslator"),
+			RateLimitSyncChannel: rlsync,
+		},
+	}
+}
+
+// resources returns the xDS resources of the proxy, keyed by type URL.
+func (p *proxyCaches) resources() map[string]grpc.Resource {
+	return map[string]grpc.Resource{
+		p.ch.ClusterCache.TypeURL():  &p.ch.ClusterCache,
+		p.ch.RouteCache.TypeURL():    &p.ch.RouteCache,
+		p.ch.ListenerCache.TypeURL(): &p.ch.ListenerCache,
+		p.et.TypeURL():               p.et,
+		p.ch.SecretCache.TypeURL():   &p.ch.SecretCache,
+	}
+}
+
+// snapshotPath returns the path of the snapshot of the proxy.
+func (ctx *serveContext) snapshotPath(p *proxyCaches) string {
+	name := p.name
+	if name == "" {
+		name = "enroute"
+	}
+	return filepath.Join(ctx.snapshotDir, name+".json")
+}
+
+// restoreSnapshot restores the caches of the proxy from its snapshot,
+// if one was saved.
+func (ctx *serveContext) restoreSnapshot(p *proxyCaches, key []byte, log logrus.FieldLogger) {
+	path := ctx.snapshotPath(p)
+	log = log.WithField("path", path)
+	msgs, err := snapshot.Load(path, key)
+	switch {
+	case os.IsNotExist(err):
+		log.Info("no snapshot to restore")
+	case err != nil:
+		log.WithError(err).Error("failed to restore snapshot")
+	default:
+		p.ch.Restore(msgs)
+		p.et.Restore(msgs)
+		log.WithField("count", len(msgs)).Info("restored snapshot")
+	}
+}
+
+// certReloader returns a *certreload.Reloader of the gRPC TLS keypair and CA
+// bundle. If the context is not properly configured for tls communication,
+// certReloader returns nil.
+func (ctx *serveContext) certReloader(log logrus.FieldLogger) *certreload.Reloader {
 	if ctx.caFile == "" && ctx.contourCert == "" && ctx.contourKey == "" {
 		// tls not enabled
 		return nil
@@ -186,23 +343,9 @@ func (ctx *serveContext) tlsconfig() *tls.Config {
 		log.Fatal("You must supply all three TLS parameters - --contour-cafile, --contour-cert-file, --contour-key-file, or none of them.")
 	}
 
-	cert, err := tls.LoadX509KeyPair(ctx.contourCert, ctx.contourKey)
+	r, err := certreload.New(ctx.caFile, ctx.contourCert, ctx.contourKey, log)
 	check(err)
-
-	ca, err := ioutil.ReadFile(ctx.caFile)
-	check(err)
-
-	certPool := x509.NewCertPool()
-	if ok := certPool.AppendCertsFromPEM(ca); !ok {
-		log.Fatalf("unable to append certificate in %s to CA pool", ctx.caFile)
-	}
-
-	return &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		ClientAuth:   tls.RequireAndVerifyClientCert,
-		ClientCAs:    certPool,
-		Rand:         rand.Reader,
-	}
+	return r
 }
 
 // gatewayHostRootNamespaces returns a slice of namespaces restricting where
@@ -259,70 +402,51 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		namespacedInformers = append(namespacedInformers, inf)
 	}
 
-	// step 3. establish our (poorly named) gRPC cache handler.
-	ch := contour.CacheHandler{
-		ListenerVisitorConfig: contour.ListenerVisitorConfig{
-			UseProxyProto:  ctx.useProxyProto,
-			HTTPAddress:    ctx.httpAddr,
-			HTTPPort:       ctx.httpPort,
-			HTTPAccessLog:  ctx.httpAccessLog,
-			HTTPSAddress:   ctx.httpsAddr,
-			HTTPSPort:      ctx.httpsPort,
-			HTTPSAccessLog: ctx.httpsAccessLog,
-		},
-		ListenerCache:     contour.NewListenerCache(ctx.statsAddr, ctx.statsPort),
-		FieldLogger:       log.WithField("context", "CacheHandler"),
-		GatewayHostStatus: &k8s.GatewayHostStatus{},
+	// step 3. establish a set of caches for each proxy served. In ingress
+	// mode the Kubernetes cluster is configured as a single proxy.
+	names := []string{""}
+	if !mode_ingress && len(ctx.enrouteNames) > 0 {
+		names = ctx.enrouteNames
 	}
-
-	// step 4. wrap the gRPC cache handler in a k8s resource event handler.
-	reh := contour.ResourceEventHandler{
-		Notifier: &contour.HoldoffNotifier{
-			Notifier:    &ch,
-			FieldLogger: log.WithField("context", "HoldoffNotifier"),
-		},
-		KubernetesCache: dag.KubernetesCache{
-			GatewayHostRootNamespaces: ctx.gatewayHostRootNamespaces(),
-		},
-		IngressClass: ctx.ingressClass,
-		FieldLogger:  log.WithField("context", "resourceEventHandler"),
+	var proxies []*proxyCaches
+	for _, name := range names {
+		p := ctx.newProxyCaches(name, log, c)
+		// Objects are added one at a time as the configuration source
+		// is first loaded, so nothing is built from them until it is
+		// in full. Until then, what is restored from a snapshot is served.
+		p.hn.Hold()
+		proxies = append(proxies, p)
 	}
 
+	// step 4. the first proxy is fed by the k8s informers.
+	reh, et, pct := proxies[0].reh, proxies[0].et, proxies[0].pct
+
 	// step 5. register out resource event handler with the k8s informers.
 	if mode_ingress {
-		coreInformers.Core().V1().Services().Informer().AddEventHandler(&reh)
-		coreInformers.Core().V1().Secrets().Informer().AddEventHandler(&reh)
-		coreInformers.Networking().V1beta1().Ingresses().Informer().AddEventHandler(&reh)
-		contourInformers.Enroute().V1beta1().GatewayHosts().Informer().AddEventHandler(&reh)
+		coreInformers.Core().V1().Services().Informer().AddEventHandler(reh)
+		coreInformers.Core().V1().Secrets().Informer().AddEventHandler(reh)
+		coreInformers.Networking().V1beta1().Ingresses().Informer().AddEventHandler(reh)
+		contourInformers.Enroute().V1beta1().GatewayHosts().Informer().AddEventHandler(reh)
 
 		// Add informers for each root-gatewayhost namespaces
 		for _, inf := range namespacedInformers {
-			inf.Core().V1().Secrets().Informer().AddEventHandler(&reh)
+			inf.Core().V1().Secrets().Informer().AddEventHandler(reh)
 		}
 		// If root-gatewayhosts are not defined, then add the informer for all namespaces
 		if len(namespacedInformers) == 0 {
-			coreInformers.Core().V1().Secrets().Informer().AddEventHandler(&reh)
+			coreInformers.Core().V1().Secrets().Informer().AddEventHandler(reh)
 		}
 	}
 
 	// step 5.5 register resource event handler with k8s informers
 	if mode_ingress {
-		contourInformers.Enroute().V1beta1().RouteFilters().Informer().AddEventHandler(&reh)
-		contourInformers.Enroute().V1beta1().HttpFilters().Informer().AddEventHandler(&reh)
-		contourInformers.Enroute().V1beta1().TLSCertificateDelegations().Informer().AddEventHandler(&reh)
+		contourInformers.Enroute().V1beta1().RouteFilters().Informer().AddEventHandler(reh)
+		contourInformers.Enroute().V1beta1().HttpFilters().Informer().AddEventHandler(reh)
+		contourInformers.Enroute().V1beta1().TLSCertificateDelegations().Informer().AddEventHandler(reh)
 	}
 
 	// step 6. endpoints updates are handled directly by the EndpointsTranslator
 	// due to their high update rate and their orthogonal nature.
-	et := &contour.EndpointsTranslator{
-		FieldLogger: log.WithField("context", "endpointstranslator"),
-	}
-
-	pct := &contour.GlobalConfigTranslator{
-		FieldLogger:          log.WithField("context", "proxyconfigtranslator"),
-		RateLimitSyncChannel: c,
-	}
-
 	if mode_ingress {
 		coreInformers.Core().V1().Endpoints().Informer().AddEventHandler(et)
 	}
@@ -330,15 +454,71 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 	// step 6.5
 	if mode_ingress {
 		contourInformers.Enroute().V1beta1().GlobalConfigs().Informer().AddEventHandler(pct)
+		// the DAG reads global configs for defaults, e.g. TLS parameters
+		contourInformers.Enroute().V1beta1().GlobalConfigs().Informer().AddEventHandler(reh)
 	}
 
 	// step 7. setup workgroup runner and register informers.
 	var g workgroup.Group
 	if mode_ingress {
-		g.Add(startInformer(coreInformers, log.WithField("context", "coreinformers")))
-		g.Add(startInformer(contourInformers, log.WithField("context", "contourinformers")))
+		var synced sync.WaitGroup
+		g.Add(startInformer(coreInformers, log.WithField("context", "coreinformers"), &synced))
+		g.Add(startInformer(contourInformers, log.WithField("context", "contourinformers"), &synced))
 		for _, inf := range namespacedInformers {
-			g.Add(startInformer(inf, log.WithField("context", "corenamespacedinformers")))
+			g.Add(startInformer(inf, log.WithField("context", "corenamespacedinformers"), &synced))
+		}
+
+		// build the configuration once the informer caches are synced.
+		g.Add(func(stop <-chan struct{}) error {
+			done := make(chan struct{})
+			go func() {
+				synced.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+				log.WithField("context", "informers").Println("caches synced")
+				proxies[0].hn.Release()
+			case <-stop:
+				return nil
+			}
+			<-stop
+			return nil
+		})
+	}
+
+	// step 7.5. restore the configuration last served, so it is served
+	// until the configuration source is heard from, and save it anew
+	// after each change.
+	if ctx.snapshotDir != "" {
+		var key []byte
+		if ctx.snapshotKeyFile != "" {
+			var err error
+			key, err = snapshot.LoadKey(ctx.snapshotKeyFile)
+			check(err)
+		}
+		for _, p := range proxies {
+			log := log.WithField("context", "snapshot").WithField("proxy", p.name)
+			ctx.restoreSnapshot(p, key, log)
+
+			w := &snapshot.Writer{
+				Path:        ctx.snapshotPath(p),
+				Key:         key,
+				FieldLogger: log,
+			}
+			for _, r := range p.resources() {
+				w.Resources = append(w.Resources, r)
+			}
+			g.Add(w.Start)
+		}
+	}
+
+	// Certificates come within the warning window of their expiry with
+	// no change to the configuration, so rebuild it now and then to
+	// check them again.
+	if ctx.certExpiryWarning > 0 && ctx.certExpiryCheckInterval > 0 {
+		for _, p := range proxies {
+			g.Add(p.reh.Recheck(ctx.certExpiryCheckInterval))
 		}
 	}
 
@@ -359,7 +539,19 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 	}
 	g.Add(metricsvc.Start)
 
-	// step 10. create debug service and register with workgroup.
+	// step 10. create debug service and register with workgroup. It
+	// serves the ACK/NACK status and contents of the xDS streams, and
+	// dry runs of candidate configurations.
+	configStatus := &grpc.ConfigStatus{}
+	resourceDump := &grpc.ResourceDump{Proxies: make(map[string]map[string]grpc.Resource)}
+	dryRunners := make(contour.DryRunners)
+	for _, p := range proxies {
+		resourceDump.Proxies[p.name] = p.resources()
+		dryRunners[p.name] = &contour.DryRunner{
+			CacheHandler:    p.ch,
+			KubernetesCache: &p.reh.KubernetesCache,
+		}
+	}
 	debugsvc := debug.Service{
 		Service: httpsvc.Service{
 			Addr:        ctx.debugAddr,
@@ -367,23 +559,57 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 			FieldLogger: log.WithField("context", "debugsvc"),
 		},
 		KubernetesCache: &reh.KubernetesCache,
+		ConfigStatus:    configStatus,
+		DryRun:          dryRunners,
+		Resources:       resourceDump.Handler,
 	}
 	g.Add(debugsvc.Start)
 
 	// step 11. register our custom metrics and plumb into cache handler
 	// and resource event handler.
 	metrics := metrics.NewMetrics(registry)
-	ch.Metrics = metrics
-	reh.Metrics = metrics
+	for _, p := range proxies {
+		p.ch.Metrics = metrics
+		p.hn.Metrics = metrics
+		p.reh.Metrics = metrics
+	}
+
+	// configs rejected by Envoys are reported in the GatewayHost status.
+	configStatus.Metrics = metrics
+	configStatus.OnRejectionChange = func() {
+		for _, p := range proxies {
+			p.ch.OnRejectedConfigsChange()
+		}
+	}
+	for _, p := range proxies {
+		p.ch.RejectedConfigs = func() []contour.RejectedConfig {
+			var rejected []contour.RejectedConfig
+			for _, st := range configStatus.Rejected() {
+				rejected = append(rejected, contour.RejectedConfig{
+					NodeID:  st.NodeID,
+					TypeURL: st.TypeURL,
+					Version: st.NackedVersion,
+					Error:   st.NackError,
+				})
+			}
+			return rejected
+		}
+	}
+
+	// step 12. create grpc handler and register with workgroup. The TLS
+	// keypair is reloaded when the files change on disk.
+	var tlsconfig *tls.Config
+	if r := ctx.certReloader(log.WithField("context", "certreload")); r != nil {
+		g.Add(r.Start)
+		tlsconfig = r.TLSConfig()
+	}
 
-	// step 12. create grpc handler and register with workgroup.
 	g.Add(func(stop <-chan struct{}) error {
 		log := log.WithField("context", "grpc")
 		addr := net.JoinHostPort(ctx.xdsAddr, strconv.Itoa(ctx.xdsPort))
 
 		var l net.Listener
 		var err error
-		tlsconfig := ctx.tlsconfig()
 		if tlsconfig != nil {
 			log.Info("Setting up TLS for gRPC")
 			l, err = tls.Listen("tcp", addr, tlsconfig)
@@ -397,26 +623,33 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 			}
 		}
 
-		s := grpc.NewAPI(log, map[string]grpc.Resource{
-			ch.ClusterCache.TypeURL():  &ch.ClusterCache,
-			ch.RouteCache.TypeURL():    &ch.RouteCache,
-			ch.ListenerCache.TypeURL(): &ch.ListenerCache,
-			et.TypeURL():               et,
-			ch.SecretCache.TypeURL():   &ch.SecretCache,
-		})
+		// a single proxy is served to every Envoy, several are each
+		// served to the Envoys which name them.
+		var s *grpcpkg.Server
+		if len(proxies) == 1 {
+			s = grpc.NewAPI(log, proxies[0].resources(), configStatus, ctx.xdsV2)
+		} else {
+			resources := make(map[string]map[string]grpc.Resource)
+			for _, p := range proxies {
+				resources[p.name] = p.resources()
+			}
+			s = grpc.NewProxyAPI(log, resources, configStatus, ctx.xdsV2)
+		}
 		log.Println("started")
 		defer log.Println("stopped")
 		return s.Serve(l)
 	})
 
 	if ctx.ratelimitEnabled {
-		SetupRateLimit(&g, log, ctx, c)
+		SetupRateLimit(&g, log, ctx, tlsconfig, c)
 	}
 
 	if !mode_ingress {
-		wl := log.WithField("context", "saaras")
-		saarasCloudCache := saaras.SaarasCloudCache{}
-		saaras.WatchCloudGatewayHost(&g, wl, &reh, et, pct, &saarasCloudCache)
+		for _, p := range proxies {
+			wl := log.WithField("context", "saaras").WithField("proxy", p.name)
+			saarasCloudCache := saaras.SaarasCloudCache{ProxyName: p.name}
+			saaras.WatchCloudGatewayHost(&g, wl, p.reh, p.et, p.pct, &saarasCloudCache, p.hn.Release)
+		}
 	}
 
 	// step 13. GO!
@@ -428,14 +661,19 @@ type informer interface {
 	Start(stopCh <-chan struct{})
 }
 
-func startInformer(inf informer, log logrus.FieldLogger) func(stop <-chan struct{}) error {
+// startInformer returns a function starting inf, which marks synced
+// done once the caches of inf are synced.
+func startInformer(inf informer, log logrus.FieldLogger, synced *sync.WaitGroup) func(stop <-chan struct{}) error {
+	synced.Add(1)
 	return func(stop <-chan struct{}) error {
-		log.Println("waiting for cache sync")
-		inf.WaitForCacheSync(stop)
-
 		log.Println("started")
 		defer log.Println("stopping")
 		inf.Start(stop)
+
+		log.Println("waiting for cache sync")
+		inf.WaitForCacheSync(stop)
+		synced.Done()
+
 		<-stop
 		return nil
 	}
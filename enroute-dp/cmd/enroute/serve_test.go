@@ -14,8 +14,10 @@
 package main
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestServeContextGatewayHostRootNamespaces(t *testing.T) {
@@ -64,3 +66,24 @@ func TestServeContextGatewayHostRootNamespaces(t *testing.T) {
 		})
 	}
 }
+
+func TestServeContextHoldoffConfig(t *testing.T) {
+	var ctx serveContext
+	config := `{"holdoff-delay": "250ms", "holdoff-max-delay": "2s", "holdoff-adaptive": true}`
+	if err := json.Unmarshal([]byte(config), &ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := time.Duration(ctx.HoldoffDelay), 250*time.Millisecond; got != want {
+		t.Fatalf("expected delay: %v, got: %v", want, got)
+	}
+	if got, want := time.Duration(ctx.HoldoffMaxDelay), 2*time.Second; got != want {
+		t.Fatalf("expected max delay: %v, got: %v", want, got)
+	}
+	if !ctx.HoldoffAdaptive {
+		t.Fatal("expected adaptive holdoff")
+	}
+
+	if err := json.Unmarshal([]byte(`{"holdoff-delay": "soon"}`), &ctx); err == nil {
+		t.Fatal("expected an error for an invalid duration")
+	}
+}
@@ -9,7 +9,7 @@ import (
 	"strconv"
 )
 
-func SetupRateLimit(g *workgroup.Group, log logrus.FieldLogger, ctx *serveContext, c chan string) {
+func SetupRateLimit(g *workgroup.Group, log logrus.FieldLogger, ctx *serveContext, tlsconfig *tls.Config, c chan string) {
 	log.Println("SetupRateLimit():\n")
 	g.Add(func(stop <-chan struct{}) error {
 		log := log.WithField("context", "grpc_ratelimit")
@@ -17,7 +17,6 @@ func SetupRateLimit(g *workgroup.Group, log logrus.FieldLogger, ctx *serveContex
 
 		var l net.Listener
 		var err error
-		tlsconfig := ctx.tlsconfig()
 		if tlsconfig != nil {
 			log.Info("Setting up TLS for gRPC")
 			l, err = tls.Listen("tcp", addr, tlsconfig)
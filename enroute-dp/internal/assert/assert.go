@@ -17,12 +17,9 @@ package assert
 import (
 	"testing"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	"github.com/golang/protobuf/proto"
-	"github.com/golang/protobuf/ptypes"
-	"github.com/golang/protobuf/ptypes/any"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/protobuf/testing/protocmp"
 )
 
 type Assert struct {
@@ -38,8 +35,10 @@ func Equal(t *testing.T, want, got interface{}) {
 func (a Assert) Equal(want, got interface{}) {
 	a.t.Helper()
 	opts := []cmp.Option{
-		cmpopts.IgnoreFields(v2.DiscoveryResponse{}, "VersionInfo", "Nonce"),
-		cmpopts.AcyclicTransformer("UnmarshalAny", unmarshalAny),
+		// messages are compared by their fields, with those held
+		// in an Any unmarshalled.
+		protocmp.Transform(),
+		protocmp.IgnoreFields(&envoy_discovery_v3.DiscoveryResponse{}, "version_info", "nonce"),
 		// errors to be equal only if both are nil or both are non-nil.
 		cmp.Comparer(func(x, y error) bool {
 			return (x == nil) == (y == nil)
@@ -50,18 +49,3 @@ func (a Assert) Equal(want, got interface{}) {
 		a.t.Fatal(diff)
 	}
 }
-
-func unmarshalAny(a *any.Any) proto.Message {
-	if a == nil {
-		return nil
-	}
-	pb, err := ptypes.Empty(a)
-	if err != nil {
-		panic(err.Error())
-	}
-	err = ptypes.UnmarshalAny(a, pb)
-	if err != nil {
-		panic(err.Error())
-	}
-	return pb
-}
@@ -22,13 +22,20 @@ import (
 	"fmt"
 	"path"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/client-go/kubernetes"
 )
 
+// CAKeyPairSecretName is the name of the Secret holding the CA
+// cert and key, used to reissue certificates when rotating.
+const CAKeyPairSecretName = "cakeypair"
+
 // WritePEM writes a certificate out to its filename in outputDir.
-func writePEM(outputDir, filename string, data []byte) error {
+// Existing files are only overwritten if force is set.
+func writePEM(outputDir, filename string, data []byte, force bool) error {
 	filepath := path.Join(outputDir, filename)
-	f, err := createFile(filepath, false)
+	f, err := createFile(filepath, force)
 	if err != nil {
 		return err
 	}
@@ -37,88 +44,97 @@ func writePEM(outputDir, filename string, data []byte) error {
 }
 
 // WriteCertsPEM writes out all the certs in certdata to
-// individual PEM files in outputDir
-func WriteCertsPEM(outputDir string, certdata map[string][]byte) error {
+// individual PEM files in outputDir. The CA key is written
+// if present in certdata.
+func WriteCertsPEM(outputDir string, certdata map[string][]byte, force bool) error {
 
-	err := writePEM(outputDir, "cacert.pem", certdata["cacert.pem"])
+	err := writePEM(outputDir, "cacert.pem", certdata["cacert.pem"], force)
 	if err != nil {
 		return err
 	}
-	err = writePEM(outputDir, "contourcert.pem", certdata["contourcert.pem"])
+	if cakey, ok := certdata["cakey.pem"]; ok {
+		err = writePEM(outputDir, "cakey.pem", cakey, force)
+		if err != nil {
+			return err
+		}
+	}
+	err = writePEM(outputDir, "contourcert.pem", certdata["contourcert.pem"], force)
 	if err != nil {
 		return err
 	}
-	err = writePEM(outputDir, "contourkey.pem", certdata["contourkey.pem"])
+	err = writePEM(outputDir, "contourkey.pem", certdata["contourkey.pem"], force)
 	if err != nil {
 		return err
 	}
-	err = writePEM(outputDir, "envoycert.pem", certdata["envoycert.pem"])
+	err = writePEM(outputDir, "envoycert.pem", certdata["envoycert.pem"], force)
 	if err != nil {
 		return err
 	}
-	return writePEM(outputDir, "envoykey.pem", certdata["envoykey.pem"])
+	return writePEM(outputDir, "envoykey.pem", certdata["envoykey.pem"], force)
 
 }
 
 // WriteSecretsYAML writes all the keypairs out to Kube Secrets in YAML form
-// in outputDir. The CA Secret only contains the cert.
-func WriteSecretsYAML(outputDir, namespace string, certdata map[string][]byte) error {
-	err := writeCACertSecret(outputDir, "cacert.pem", certdata["cacert.pem"])
+// in outputDir. The CA Secret only contains the cert, the CA key is written
+// to a separate keypair Secret if present in certdata.
+func WriteSecretsYAML(outputDir, namespace string, certdata map[string][]byte, force bool) error {
+	err := writeCACertSecret(outputDir, namespace, certdata["cacert.pem"], force)
 	if err != nil {
 		return err
 	}
-	err = writeKeyPairSecret(outputDir, "enroute", namespace, certdata["contourcert.pem"], certdata["contourkey.pem"])
+	if cakey, ok := certdata["cakey.pem"]; ok {
+		err = writeSecretYAML(outputDir, CAKeyPairSecretName+".yaml", newTLSSecret(CAKeyPairSecretName, namespace, cakey, certdata["cacert.pem"]), force)
+		if err != nil {
+			return err
+		}
+	}
+	err = writeKeyPairSecret(outputDir, "enroute", namespace, certdata["contourcert.pem"], certdata["contourkey.pem"], force)
 	if err != nil {
 		return err
 	}
 
-	return writeKeyPairSecret(outputDir, "envoy", namespace, certdata["envoycert.pem"], certdata["envoykey.pem"])
+	return writeKeyPairSecret(outputDir, "envoy", namespace, certdata["envoycert.pem"], certdata["envoykey.pem"], force)
 
 }
 
 // WriteSecretsKube writes all the keypairs out to Kube Secrets in the
-// passed Kube context.
-func WriteSecretsKube(client *kubernetes.Clientset, namespace string, certdata map[string][]byte) error {
-	err := writeCACertKube(client, namespace, certdata["cacert.pem"])
+// passed Kube context. Existing Secrets are only updated if force is set.
+func WriteSecretsKube(client kubernetes.Interface, namespace string, certdata map[string][]byte, force bool) error {
+	err := writeSecretKube(client, newCertOnlySecret("cacert", namespace, "cacert.pem", certdata["cacert.pem"]), force)
 	if err != nil {
 		return err
 	}
-	err = writeKeyPairKube(client, "enroute", namespace, certdata["contourcert.pem"], certdata["contourkey.pem"])
+	if cakey, ok := certdata["cakey.pem"]; ok {
+		err = writeSecretKube(client, newTLSSecret(CAKeyPairSecretName, namespace, cakey, certdata["cacert.pem"]), force)
+		if err != nil {
+			return err
+		}
+	}
+	err = writeKeyPairKube(client, "enroute", namespace, certdata["contourcert.pem"], certdata["contourkey.pem"], force)
 	if err != nil {
 		return err
 	}
 
-	return writeKeyPairKube(client, "envoy", namespace, certdata["envoycert.pem"], certdata["envoykey.pem"])
+	return writeKeyPairKube(client, "envoy", namespace, certdata["envoycert.pem"], certdata["envoykey.pem"], force)
 
 }
 
-func writeCACertSecret(outputDir, namespace string, cert []byte) error {
-	filename := path.Join(outputDir, "cacert.yaml")
+func writeCACertSecret(outputDir, namespace string, cert []byte, force bool) error {
 	secret := newCertOnlySecret("cacert", namespace, "cacert.pem", cert)
-	f, err := createFile(filename, false)
-	if err != nil {
-		return err
-	}
-	return checkFile(filename, writeSecret(f, secret))
+	return writeSecretYAML(outputDir, "cacert.yaml", secret, force)
 }
 
-func writeCACertKube(client *kubernetes.Clientset, namespace string, cert []byte) error {
-	secret := newCertOnlySecret("cacert", namespace, "cacert.pem", cert)
-	_, err := client.CoreV1().Secrets(namespace).Create(secret)
-	if err != nil {
-		return err
-	}
-	fmt.Print("secret/cacert created\n")
-	return nil
-}
-
-func writeKeyPairSecret(outputDir, service, namespace string, cert, key []byte) error {
+func writeKeyPairSecret(outputDir, service, namespace string, cert, key []byte, force bool) error {
 	filename := service + "cert.yaml"
 	secretname := service + "cert"
 
 	secret := newTLSSecret(secretname, namespace, key, cert)
+	return writeSecretYAML(outputDir, filename, secret, force)
+}
+
+func writeSecretYAML(outputDir, filename string, secret *corev1.Secret, force bool) error {
 	filepath := path.Join(outputDir, filename)
-	f, err := createFile(filepath, false)
+	f, err := createFile(filepath, force)
 	if err != nil {
 		return err
 	}
@@ -126,13 +142,25 @@ func writeKeyPairSecret(outputDir, service, namespace string, cert, key []byte)
 	return checkFile(filepath, err)
 }
 
-func writeKeyPairKube(client *kubernetes.Clientset, service, namespace string, cert, key []byte) error {
+func writeKeyPairKube(client kubernetes.Interface, service, namespace string, cert, key []byte, force bool) error {
 	secretname := service + "cert"
 	secret := newTLSSecret(secretname, namespace, key, cert)
-	_, err := client.CoreV1().Secrets(namespace).Create(secret)
+	return writeSecretKube(client, secret, force)
+}
+
+func writeSecretKube(client kubernetes.Interface, secret *corev1.Secret, force bool) error {
+	_, err := client.CoreV1().Secrets(secret.Namespace).Create(secret)
+	if errors.IsAlreadyExists(err) && force {
+		_, err = client.CoreV1().Secrets(secret.Namespace).Update(secret)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("secret/%s updated\n", secret.Name)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
-	fmt.Printf("secret/%s created\n", secretname)
+	fmt.Printf("secret/%s created\n", secret.Name)
 	return nil
 }
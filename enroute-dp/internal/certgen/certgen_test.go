@@ -17,6 +17,7 @@
 package certgen
 
 import (
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
@@ -74,6 +75,43 @@ func TestGeneratedCertsValid(t *testing.T) {
 
 }
 
+func TestGeneratedCertsWithConfig(t *testing.T) {
+
+	config := CertConfig{
+		KeyType:  KeyTypeECDSA,
+		Expiry:   time.Now().Add(24 * time.Hour),
+		DNSNames: []string{"enroute.example.com"},
+	}
+
+	cacert, cakey, err := NewCAWithConfig("enroute", config)
+	if err != nil {
+		t.Fatalf("Failed to generate CA cert: %s", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(cacert) {
+		t.Fatal("Failed to set up CA cert for testing, maybe it's an invalid PEM")
+	}
+
+	cert, key, err := NewCertWithConfig(cacert, cakey, "envoy", "heptio-contour", config)
+	if err != nil {
+		t.Fatalf("Failed to generate Envoy cert: %s", err)
+	}
+	if _, err := tls.X509KeyPair(cert, key); err != nil {
+		t.Fatalf("Generated keypair is invalid: %s", err)
+	}
+
+	for _, dnsname := range []string{"envoy", "envoy.heptio-contour.svc", "enroute.example.com"} {
+		if err := verifyCert(cert, roots, dnsname); err != nil {
+			t.Fatalf("Validating %s failed: %s", dnsname, err)
+		}
+	}
+
+	block, _ := pem.Decode(key)
+	if block == nil || block.Type != "EC PRIVATE KEY" {
+		t.Fatalf("expected an EC PRIVATE KEY, got %v", block)
+	}
+}
+
 func verifyCert(certPEM []byte, roots *x509.CertPool, dnsname string) error {
 	block, _ := pem.Decode(certPEM)
 	if block == nil {
@@ -17,6 +17,9 @@
 package certgen
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
@@ -33,11 +36,44 @@ import (
 // for RSA keys.
 const keySize = 2048
 
+// KeyType is the type of private key generated for a certificate.
+type KeyType string
+
+const (
+	// KeyTypeRSA generates 2048 bit RSA keys.
+	KeyTypeRSA KeyType = "rsa"
+	// KeyTypeECDSA generates ECDSA keys on the P-256 curve.
+	KeyTypeECDSA KeyType = "ecdsa"
+)
+
+// CertConfig holds the parameters of a generated certificate.
+type CertConfig struct {
+	// KeyType is the type of the generated key. Defaults to KeyTypeRSA.
+	KeyType KeyType
+
+	// Expiry is the time the certificate expires.
+	Expiry time.Time
+
+	// DNSNames are added to the subject alternative names of
+	// the service certificates.
+	DNSNames []string
+}
+
 // NewCert generates a new keypair given the CA keypair, the expiry time, the service name
 // ("contour" or "envoy"), and the Kubernetes namespace the service will run in (because
 // of the Kubernetes DNS schema.)
 // The return values are cert, key, err.
 func NewCert(caCertPEM, caKeyPEM []byte, expiry time.Time, service, namespace string) ([]byte, []byte, error) {
+	return NewCertWithConfig(caCertPEM, caKeyPEM, service, namespace, CertConfig{
+		KeyType: KeyTypeRSA,
+		Expiry:  expiry,
+	})
+}
+
+// NewCertWithConfig generates a new keypair for service, signed by the CA keypair,
+// with the key type, expiry and additional SANs of config.
+// The return values are cert, key, err.
+func NewCertWithConfig(caCertPEM, caKeyPEM []byte, service, namespace string, config CertConfig) ([]byte, []byte, error) {
 
 	caKeyPair, err := tls.X509KeyPair(caCertPEM, caKeyPEM)
 	if err != nil {
@@ -47,15 +83,24 @@ func NewCert(caCertPEM, caKeyPEM []byte, expiry time.Time, service, namespace st
 	if err != nil {
 		return nil, nil, err
 	}
-	caKey, ok := caKeyPair.PrivateKey.(*rsa.PrivateKey)
+	caKey, ok := caKeyPair.PrivateKey.(crypto.Signer)
 	if !ok {
 		return nil, nil, fmt.Errorf("CA private key has unexpected type %T", caKeyPair.PrivateKey)
 	}
 
-	newKey, err := rsa.GenerateKey(rand.Reader, keySize)
+	newKey, err := newPrivateKey(config.KeyType)
 	if err != nil {
 		return nil, nil, fmt.Errorf("cannot generate key: %v", err)
 	}
+	keyID, err := keyIdentifier(newKey.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyUsage := x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment
+	if config.KeyType != KeyTypeECDSA {
+		keyUsage |= x509.KeyUsageDataEncipherment | x509.KeyUsageKeyEncipherment
+	}
 
 	now := time.Now()
 	template := &x509.Certificate{
@@ -64,23 +109,20 @@ func NewCert(caCertPEM, caKeyPEM []byte, expiry time.Time, service, namespace st
 			CommonName: service,
 		},
 		NotBefore:    now.UTC().AddDate(0, 0, -1),
-		NotAfter:     expiry.UTC(),
-		SubjectKeyId: bigIntHash(newKey.N),
-		KeyUsage: x509.KeyUsageDigitalSignature |
-			x509.KeyUsageDataEncipherment |
-			x509.KeyUsageKeyEncipherment |
-			x509.KeyUsageContentCommitment,
-		DNSNames: serviceNames(service, namespace),
-	}
-	newCert, err := x509.CreateCertificate(rand.Reader, template, caCert, &newKey.PublicKey, caKey)
+		NotAfter:     config.Expiry.UTC(),
+		SubjectKeyId: keyID,
+		KeyUsage:     keyUsage,
+		DNSNames:     append(serviceNames(service, namespace), config.DNSNames...),
+	}
+	newCert, err := x509.CreateCertificate(rand.Reader, template, caCert, newKey.Public(), caKey)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	newKeyPEM := pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(newKey),
-	})
+	newKeyPEM, err := encodePrivateKey(newKey)
+	if err != nil {
+		return nil, nil, err
+	}
 	newCertPEM := pem.EncodeToMemory(&pem.Block{
 		Type:  "CERTIFICATE",
 		Bytes: newCert,
@@ -92,12 +134,30 @@ func NewCert(caCertPEM, caKeyPEM []byte, expiry time.Time, service, namespace st
 // NewCA generates a new CA, given the CA's CN and an expiry time.
 // The return order is cacert, cakey, error.
 func NewCA(cn string, expiry time.Time) ([]byte, []byte, error) {
+	return NewCAWithConfig(cn, CertConfig{
+		KeyType: KeyTypeRSA,
+		Expiry:  expiry,
+	})
+}
+
+// NewCAWithConfig generates a new CA, given the CA's CN and the key type
+// and expiry of config. The return order is cacert, cakey, error.
+func NewCAWithConfig(cn string, config CertConfig) ([]byte, []byte, error) {
 
-	key, err := rsa.GenerateKey(rand.Reader, keySize)
+	key, err := newPrivateKey(config.KeyType)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyID, err := keyIdentifier(key.Public())
 	if err != nil {
 		return nil, nil, err
 	}
 
+	keyUsage := x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign
+	if config.KeyType != KeyTypeECDSA {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
 	now := time.Now()
 	serial := newSerial(now)
 	template := &x509.Certificate{
@@ -107,13 +167,13 @@ func NewCA(cn string, expiry time.Time) ([]byte, []byte, error) {
 			SerialNumber: serial.String(),
 		},
 		NotBefore:             now.UTC().AddDate(0, 0, -1),
-		NotAfter:              expiry.UTC(),
-		SubjectKeyId:          bigIntHash(key.N),
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		NotAfter:              config.Expiry.UTC(),
+		SubjectKeyId:          keyID,
+		KeyUsage:              keyUsage,
 		IsCA:                  true,
 		BasicConstraintsValid: true,
 	}
-	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -121,13 +181,61 @@ func NewCA(cn string, expiry time.Time) ([]byte, []byte, error) {
 		Type:  "CERTIFICATE",
 		Bytes: certDER,
 	})
-	keyPEMData := pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(key),
-	})
+	keyPEMData, err := encodePrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
 	return certPEMData, keyPEMData, nil
 }
 
+// newPrivateKey generates a private key of the given type.
+func newPrivateKey(keyType KeyType) (crypto.Signer, error) {
+	switch keyType {
+	case KeyTypeRSA, "":
+		return rsa.GenerateKey(rand.Reader, keySize)
+	case KeyTypeECDSA:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", keyType)
+	}
+}
+
+// encodePrivateKey encodes an RSA key in PKCS #1 and an ECDSA key in SEC 1 PEM form.
+func encodePrivateKey(key crypto.Signer) ([]byte, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(k),
+		}), nil
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: der,
+		}), nil
+	default:
+		return nil, fmt.Errorf("private key has unexpected type %T", key)
+	}
+}
+
+// keyIdentifier returns the subject key identifier of a public key.
+func keyIdentifier(pub crypto.PublicKey) ([]byte, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return bigIntHash(k.N), nil
+	case *ecdsa.PublicKey:
+		h := sha1.New()
+		h.Write(elliptic.Marshal(k.Curve, k.X, k.Y))
+		return h.Sum(nil), nil
+	default:
+		return nil, fmt.Errorf("public key has unexpected type %T", pub)
+	}
+}
+
 func newSerial(now time.Time) *big.Int {
 	return big.NewInt(int64(now.Nanosecond()))
 }
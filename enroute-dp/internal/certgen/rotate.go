@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package certgen
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NeedsRenewal returns true if certPEM is missing, was not issued by
+// the CA in caCertPEM, or expires within renewBefore of now.
+func NeedsRenewal(certPEM, caCertPEM []byte, now time.Time, renewBefore time.Duration) bool {
+	cert, err := parseCertificate(certPEM)
+	if err != nil {
+		return true
+	}
+	ca, err := parseCertificate(caCertPEM)
+	if err != nil {
+		return true
+	}
+	if err := cert.CheckSignatureFrom(ca); err != nil {
+		return true
+	}
+	return now.Add(renewBefore).After(cert.NotAfter)
+}
+
+func parseCertificate(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no certificate found in PEM data")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// ReadCAFiles reads the CA cert and key from PEM files.
+// The return order is cacert, cakey, error.
+func ReadCAFiles(certFile, keyFile string) ([]byte, []byte, error) {
+	cert, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// ReadCAKube reads the CA cert and key from the named TLS Secret.
+// The return order is cacert, cakey, error.
+func ReadCAKube(client kubernetes.Interface, namespace, name string) ([]byte, []byte, error) {
+	secret, err := client.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, key := secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
+	if len(cert) == 0 || len(key) == 0 {
+		return nil, nil, fmt.Errorf("secret %s/%s does not hold a CA cert and key", namespace, name)
+	}
+	return cert, key, nil
+}
+
+// ReadCertsPEM reads the keypairs previously written by WriteCertsPEM
+// from outputDir. Missing files are skipped.
+func ReadCertsPEM(outputDir string) (map[string][]byte, error) {
+	certdata := make(map[string][]byte)
+	for _, filename := range []string{"contourcert.pem", "contourkey.pem", "envoycert.pem", "envoykey.pem"} {
+		data, err := ioutil.ReadFile(path.Join(outputDir, filename))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		certdata[filename] = data
+	}
+	return certdata, nil
+}
+
+// ReadSecretsKube reads the keypairs previously written by WriteSecretsKube
+// from the passed Kube context. Missing Secrets are skipped.
+func ReadSecretsKube(client kubernetes.Interface, namespace string) (map[string][]byte, error) {
+	certdata := make(map[string][]byte)
+	for service, prefix := range map[string]string{"enroute": "contour", "envoy": "envoy"} {
+		secret, err := client.CoreV1().Secrets(namespace).Get(service+"cert", metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		certdata[prefix+"cert.pem"] = secret.Data[corev1.TLSCertKey]
+		certdata[prefix+"key.pem"] = secret.Data[corev1.TLSPrivateKeyKey]
+	}
+	return certdata, nil
+}
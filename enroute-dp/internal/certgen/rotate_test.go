@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package certgen
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNeedsRenewal(t *testing.T) {
+	now := time.Now()
+	window := 30 * 24 * time.Hour
+
+	cacert, cakey, err := NewCA("enroute", now.Add(365*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	othercacert, othercakey, err := NewCA("other", now.Add(365*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newCert := func(cacert, cakey []byte, expiry time.Time) []byte {
+		cert, _, err := NewCert(cacert, cakey, expiry, "envoy", "heptio-contour")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	tests := map[string]struct {
+		cert []byte
+		want bool
+	}{
+		"expires after window": {
+			cert: newCert(cacert, cakey, now.Add(60*24*time.Hour)),
+			want: false,
+		},
+		"expires within window": {
+			cert: newCert(cacert, cakey, now.Add(10*24*time.Hour)),
+			want: true,
+		},
+		"issued by another CA": {
+			cert: newCert(othercacert, othercakey, now.Add(60*24*time.Hour)),
+			want: true,
+		},
+		"missing": {
+			cert: nil,
+			want: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := NeedsRenewal(tc.cert, cacert, now, window); got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSecretsKube(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	certdata := map[string][]byte{
+		"cacert.pem":      []byte("cacert"),
+		"cakey.pem":       []byte("cakey"),
+		"contourcert.pem": []byte("contourcert"),
+		"contourkey.pem":  []byte("contourkey"),
+		"envoycert.pem":   []byte("envoycert"),
+		"envoykey.pem":    []byte("envoykey"),
+	}
+
+	if err := WriteSecretsKube(client, "enroute", certdata, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSecretsKube(client, "enroute", certdata, false); err == nil {
+		t.Fatal("expected writing existing secrets without force to fail")
+	}
+
+	certdata["envoycert.pem"] = []byte("envoycert2")
+	if err := WriteSecretsKube(client, "enroute", certdata, true); err != nil {
+		t.Fatal(err)
+	}
+
+	cacert, cakey, err := ReadCAKube(client, "enroute", CAKeyPairSecretName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cacert, certdata["cacert.pem"]) || !bytes.Equal(cakey, certdata["cakey.pem"]) {
+		t.Fatalf("expected CA %q/%q, got %q/%q", certdata["cacert.pem"], certdata["cakey.pem"], cacert, cakey)
+	}
+
+	got, err := ReadSecretsKube(client, "enroute")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"contourcert.pem", "contourkey.pem", "envoycert.pem", "envoykey.pem"} {
+		if !bytes.Equal(got[k], certdata[k]) {
+			t.Fatalf("%s: expected %q, got %q", k, certdata[k], got[k])
+		}
+	}
+}
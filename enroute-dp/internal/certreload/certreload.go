@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+// Package certreload serves TLS keypairs and CA bundles from files,
+// reloading them when the files change on disk.
+package certreload
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// Reloader holds the TLS config of a server that verifies client certificates.
+// New connections are handed the keypair and CA bundle last loaded, existing
+// connections keep the ones they were established with.
+type Reloader struct {
+	CAFile, CertFile, KeyFile string
+	logrus.FieldLogger
+
+	mu     sync.RWMutex
+	config *tls.Config
+}
+
+// New returns a Reloader with the keypair and CA bundle loaded from the files.
+func New(caFile, certFile, keyFile string, log logrus.FieldLogger) (*Reloader, error) {
+	r := &Reloader{
+		CAFile:      caFile,
+		CertFile:    certFile,
+		KeyFile:     keyFile,
+		FieldLogger: log,
+	}
+	return r, r.Load()
+}
+
+// Load reads the keypair and CA bundle. If either fails to load,
+// the previously loaded ones are kept.
+func (r *Reloader) Load() error {
+	cert, err := tls.LoadX509KeyPair(r.CertFile, r.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	ca, err := ioutil.ReadFile(r.CAFile)
+	if err != nil {
+		return err
+	}
+
+	certPool := x509.NewCertPool()
+	if ok := certPool.AppendCertsFromPEM(ca); !ok {
+		return fmt.Errorf("unable to append certificate in %s to CA pool", r.CAFile)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    certPool,
+		Rand:         rand.Reader,
+	}
+	return nil
+}
+
+// TLSConfig returns a *tls.Config that hands each new connection
+// the keypair and CA bundle last loaded.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.config, nil
+		},
+	}
+}
+
+// Start fulfills the g.Start contract. It watches the directories holding
+// the files, rather than the files themselves, to follow Kubernetes Secret
+// volumes which replace a symlink when updated.
+// When stop is closed the watch is closed.
+func (r *Reloader) Start(stop <-chan struct{}) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	dirs := make(map[string]bool)
+	for _, f := range []string{r.CAFile, r.CertFile, r.KeyFile} {
+		dirs[filepath.Dir(f)] = true
+	}
+	for dir := range dirs {
+		if err := w.Add(dir); err != nil {
+			return err
+		}
+	}
+
+	r.Println("started")
+	defer r.Println("stopped")
+	for {
+		select {
+		case ev := <-w.Events:
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			if err := r.Load(); err != nil {
+				r.WithError(err).Error("reloading TLS certificates, keeping the previous ones")
+				continue
+			}
+			r.WithField("file", ev.Name).Info("reloaded TLS certificates")
+		case err := <-w.Errors:
+			r.WithError(err).Error("watching TLS certificates")
+		case <-stop:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package certreload
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/saarasio/enroute/enroute-dp/internal/certgen"
+	"github.com/sirupsen/logrus"
+)
+
+type keypair struct {
+	cacert, cakey, cert, key []byte
+}
+
+func newKeypair(t *testing.T) keypair {
+	t.Helper()
+	expiry := time.Now().Add(24 * time.Hour)
+	cacert, cakey, err := certgen.NewCA("enroute", expiry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, key, err := certgen.NewCert(cacert, cakey, expiry, "enroute", "enroute")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keypair{cacert: cacert, cakey: cakey, cert: cert, key: key}
+}
+
+func writeFile(t *testing.T, filename string, data []byte) {
+	t.Helper()
+	// write and rename, as a file is replaced in a Kubernetes Secret volume
+	if err := ioutil.WriteFile(filename+".tmp", data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filename+".tmp", filename); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// handshake connects to addr with a client cert issued by kp's CA and
+// returns the serial number of the server cert.
+func handshake(t *testing.T, addr string, kp keypair) string {
+	t.Helper()
+	clientcert, clientkey, err := certgen.NewCert(kp.cacert, kp.cakey, time.Now().Add(time.Hour), "envoy", "enroute")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(clientcert, clientkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(kp.cacert)
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      roots,
+		ServerName:   "enroute",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.String()
+}
+
+func TestReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certreload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "cacert.pem")
+	certFile := filepath.Join(dir, "contourcert.pem")
+	keyFile := filepath.Join(dir, "contourkey.pem")
+
+	kp1 := newKeypair(t)
+	writeFile(t, caFile, kp1.cacert)
+	writeFile(t, certFile, kp1.cert)
+	writeFile(t, keyFile, kp1.key)
+
+	r, err := New(caFile, certFile, keyFile, logrus.StandardLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- r.Start(stop) }()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", r.TLSConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}(conn)
+		}
+	}()
+
+	serial1 := handshake(t, l.Addr().String(), kp1)
+
+	// rotate to a keypair issued by a new CA.
+	kp2 := newKeypair(t)
+	writeFile(t, caFile, kp2.cacert)
+	writeFile(t, keyFile, kp2.key)
+	writeFile(t, certFile, kp2.cert)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.mu.RLock()
+		cert, _ := x509.ParseCertificate(r.config.Certificates[0].Certificate[0])
+		r.mu.RUnlock()
+		if cert.SerialNumber.String() != serial1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for certificates to be reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if serial2 := handshake(t, l.Addr().String(), kp2); serial2 == serial1 {
+		t.Fatalf("expected reloaded server certificate, got serial %s again", serial2)
+	}
+}
+
+func TestReloaderKeepsPreviousOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certreload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "cacert.pem")
+	certFile := filepath.Join(dir, "contourcert.pem")
+	keyFile := filepath.Join(dir, "contourkey.pem")
+
+	kp := newKeypair(t)
+	writeFile(t, caFile, kp.cacert)
+	writeFile(t, certFile, kp.cert)
+	writeFile(t, keyFile, kp.key)
+
+	r, err := New(caFile, certFile, keyFile, logrus.StandardLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := r.config
+
+	writeFile(t, keyFile, []byte("not a key"))
+	if err := r.Load(); err == nil {
+		t.Fatal("expected an error loading an invalid key")
+	}
+	if r.config != previous {
+		t.Fatal("expected the previous config to be kept")
+	}
+}
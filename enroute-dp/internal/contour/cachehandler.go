@@ -20,10 +20,19 @@
 package contour
 
 import (
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 	//"os"
 
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/golang/protobuf/proto"
 	"github.com/prometheus/client_golang/prometheus"
+	gatewayhostv1 "github.com/saarasio/enroute/enroute-dp/apis/enroute/v1beta1"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
 	//"github.com/saarasio/enroute/enroute-dp/internal/debug"
 	"github.com/saarasio/enroute/enroute-dp/internal/k8s"
@@ -42,6 +51,26 @@ type CacheHandler struct {
 	GatewayHostStatus *k8s.GatewayHostStatus
 	logrus.FieldLogger
 	*metrics.Metrics
+
+	// Proxy is the name of the proxy the caches are built for, which
+	// labels its metrics.
+	Proxy string
+
+	// RejectedConfigs, if set, returns the configs currently rejected
+	// by Envoys. They are reported as warnings in the status of the
+	// GatewayHosts they were generated from.
+	RejectedConfigs func() []RejectedConfig
+
+	mu       sync.Mutex
+	statuses map[dag.Meta]dag.Status // statuses of the last DAG built
+}
+
+// RejectedConfig is a config an Envoy rejected.
+type RejectedConfig struct {
+	NodeID  string
+	TypeURL string
+	Version string
+	Error   string
 }
 
 type statusable interface {
@@ -60,18 +89,118 @@ func (ch *CacheHandler) OnChange(kc *dag.KubernetesCache) {
 	ch.updateRoutes(dag)
 	ch.updateClusters(dag)
 	ch.updateGatewayHostMetric(dag)
-	ch.SetDAGLastRebuilt(time.Now())
+	ch.updateSecretMetric(dag)
+	ch.SetDAGLastRebuilt(ch.Proxy, time.Now())
+}
+
+// Restore replaces the contents of the caches with the clusters, routes,
+// listeners, and secrets of msgs, for example those saved by a previous
+// run. Other messages, and listeners the ListenerCache serves itself,
+// are ignored.
+func (ch *CacheHandler) Restore(msgs []proto.Message) {
+	clusters := make(map[string]*envoy_cluster_v3.Cluster)
+	routes := make(map[string]*envoy_route_v3.RouteConfiguration)
+	listeners := make(map[string]*envoy_listener_v3.Listener)
+	secrets := make(map[string]*envoy_tls_v3.Secret)
+	for _, msg := range msgs {
+		switch msg := msg.(type) {
+		case *envoy_cluster_v3.Cluster:
+			clusters[msg.Name] = msg
+		case *envoy_route_v3.RouteConfiguration:
+			routes[msg.Name] = msg
+		case *envoy_listener_v3.Listener:
+			if _, ok := ch.ListenerCache.staticValues[msg.Name]; !ok {
+				listeners[msg.Name] = msg
+			}
+		case *envoy_tls_v3.Secret:
+			secrets[msg.Name] = msg
+		}
+	}
+	ch.SecretCache.Update(secrets)
+	ch.ListenerCache.Update(listeners)
+	ch.RouteCache.Update(routes)
+	ch.ClusterCache.Update(clusters)
 }
 
 func (ch *CacheHandler) setGatewayHostStatus(st statusable) {
-	for _, s := range st.Statuses() {
-		err := ch.GatewayHostStatus.SetStatus(s.Status, s.Description, s.Object)
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.statuses = st.Statuses()
+	ch.writeGatewayHostStatus()
+}
+
+// OnRejectedConfigsChange rewrites the status of the GatewayHosts of the
+// last DAG built when an Envoy rejects a config or accepts a new one.
+func (ch *CacheHandler) OnRejectedConfigsChange() {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.writeGatewayHostStatus()
+}
+
+// writeGatewayHostStatus writes ch.statuses, with a warning for each
+// rejected config that mentions a resource generated from the GatewayHost.
+// ch.mu must be held.
+func (ch *CacheHandler) writeGatewayHostStatus() {
+	var rejected []RejectedConfig
+	if ch.RejectedConfigs != nil {
+		rejected = ch.RejectedConfigs()
+	}
+
+	for _, s := range ch.statuses {
+		warnings := s.Warnings
+		if w := rejectionWarnings(s.Object, rejected); len(w) > 0 {
+			warnings = append(append([]string{}, s.Warnings...), w...)
+		}
+		err := ch.GatewayHostStatus.SetStatus(s.Status, s.Description, warnings, s.Object)
 		if err != nil {
 			ch.Errorf("Error Setting Status of GatewayHost: %v", err)
 		}
 	}
 }
 
+// rejectionWarnings returns a warning for each rejected config whose
+// error mentions the virtual host, a cluster or the secret of ir.
+func rejectionWarnings(ir *gatewayhostv1.GatewayHost, rejected []RejectedConfig) []string {
+	if len(rejected) == 0 {
+		return nil
+	}
+
+	// cluster and secret names are of the form namespace/name/...
+	var names []string
+	var services []gatewayhostv1.Service
+	for _, route := range ir.Spec.Routes {
+		services = append(services, route.Services...)
+	}
+	if ir.Spec.TCPProxy != nil {
+		services = append(services, ir.Spec.TCPProxy.Services...)
+	}
+	for _, service := range services {
+		names = append(names, ir.Namespace+"/"+service.Name+"/")
+	}
+	if vhost := ir.Spec.VirtualHost; vhost != nil {
+		names = append(names, vhost.Fqdn)
+		if vhost.TLS != nil && vhost.TLS.SecretName != "" {
+			secret := vhost.TLS.SecretName
+			if !strings.Contains(secret, "/") {
+				secret = ir.Namespace + "/" + secret
+			}
+			names = append(names, secret+"/")
+		}
+	}
+
+	var warnings []string
+	for _, r := range rejected {
+		for _, name := range names {
+			if name != "" && strings.Contains(r.Error, name) {
+				warnings = append(warnings, fmt.Sprintf("%s version %s rejected by Envoy %q: %s",
+					strings.TrimPrefix(r.TypeURL, "type.googleapis.com/"), r.Version, r.NodeID, r.Error))
+				break
+			}
+		}
+	}
+	return warnings
+}
+
 func (ch *CacheHandler) updateSecrets(root dag.Visitable) {
 	secrets := visitSecrets(root)
 	ch.SecretCache.Update(secrets)
@@ -94,7 +223,7 @@ func (ch *CacheHandler) updateClusters(root dag.Visitable) {
 
 func (ch *CacheHandler) updateGatewayHostMetric(st statusable) {
 	metrics := calculateGatewayHostMetric(st)
-	ch.Metrics.SetGatewayHostMetric(metrics)
+	ch.Metrics.SetGatewayHostMetric(ch.Proxy, metrics)
 }
 
 func calculateGatewayHostMetric(st statusable) metrics.GatewayHostMetric {
@@ -128,3 +257,38 @@ func calculateGatewayHostMetric(st statusable) metrics.GatewayHostMetric {
 		Root:     metricRoots,
 	}
 }
+
+func (ch *CacheHandler) updateSecretMetric(root dag.Vertex) {
+	ch.Metrics.SetSecretMetric(ch.Proxy, calculateSecretMetric(root, ch.FieldLogger))
+}
+
+// calculateSecretMetric returns the certificate expiry of every
+// secret served, keyed by secret and SNI.
+func calculateSecretMetric(root dag.Vertex, log logrus.FieldLogger) map[metrics.SecretMeta]time.Time {
+	expiry := make(map[metrics.SecretMeta]time.Time)
+
+	var visit func(dag.Vertex)
+	visit = func(vertex dag.Vertex) {
+		switch svh := vertex.(type) {
+		case *dag.SecureVirtualHost:
+			if svh.Secret == nil {
+				return
+			}
+			notAfter, err := svh.Secret.NotAfter()
+			if err != nil {
+				log.Warnf("parsing certificate of secret %s/%s: %v", svh.Secret.Namespace(), svh.Secret.Name(), err)
+				return
+			}
+			expiry[metrics.SecretMeta{
+				Namespace: svh.Secret.Namespace(),
+				Name:      svh.Secret.Name(),
+				SNI:       svh.VirtualHost.Name,
+			}] = notAfter
+		default:
+			vertex.Visit(visit)
+		}
+	}
+	visit(root)
+
+	return expiry
+}
@@ -20,8 +20,15 @@ import (
 	"reflect"
 	"testing"
 
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
 	gatewayhostv1 "github.com/saarasio/enroute/enroute-dp/apis/enroute/v1beta1"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
+	"github.com/saarasio/enroute/enroute-dp/internal/envoy"
 	"github.com/saarasio/enroute/enroute-dp/internal/metrics"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -584,3 +591,104 @@ func TestGatewayHostMetrics(t *testing.T) {
 		})
 	}
 }
+
+func TestRejectionWarnings(t *testing.T) {
+	ir1 := &gatewayhostv1.GatewayHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "roots",
+			Name:      "example",
+		},
+		Spec: gatewayhostv1.GatewayHostSpec{
+			VirtualHost: &gatewayhostv1.VirtualHost{
+				Fqdn: "example.com",
+				TLS: &gatewayhostv1.TLS{
+					SecretName: "secret",
+				},
+			},
+			Routes: []gatewayhostv1.Route{{
+				Conditions: []gatewayhostv1.Condition{{
+					Prefix: "/",
+				}},
+				Services: []gatewayhostv1.Service{{
+					Name: "home",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	tests := map[string]struct {
+		rejected []RejectedConfig
+		want     []string
+	}{
+		"nothing rejected": {},
+		"cluster rejected": {
+			rejected: []RejectedConfig{{
+				NodeID:  "envoy-1",
+				TypeURL: "type.googleapis.com/envoy.config.cluster.v3.Cluster",
+				Version: "3",
+				Error:   "cluster roots/home/8080/da39a3ee5e: invalid",
+			}},
+			want: []string{`envoy.config.cluster.v3.Cluster version 3 rejected by Envoy "envoy-1": cluster roots/home/8080/da39a3ee5e: invalid`},
+		},
+		"secret rejected": {
+			rejected: []RejectedConfig{{
+				NodeID:  "envoy-1",
+				TypeURL: "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.Secret",
+				Version: "4",
+				Error:   "secret roots/secret/68621186db: invalid private key",
+			}},
+			want: []string{`envoy.extensions.transport_sockets.tls.v3.Secret version 4 rejected by Envoy "envoy-1": secret roots/secret/68621186db: invalid private key`},
+		},
+		"unrelated config rejected": {
+			rejected: []RejectedConfig{{
+				NodeID:  "envoy-1",
+				TypeURL: "type.googleapis.com/envoy.config.cluster.v3.Cluster",
+				Version: "3",
+				Error:   "cluster other/home/8080/da39a3ee5e: invalid",
+			}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := rejectionWarnings(ir1, tc.rejected)
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Fatalf("expected: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCacheHandlerRestore(t *testing.T) {
+	ch := CacheHandler{
+		ListenerCache: NewListenerCache("0.0.0.0", 8002),
+	}
+	stats := envoy.StatsListener("0.0.0.0", 8002)
+	cluster := &envoy_cluster_v3.Cluster{Name: "default/kuard/80"}
+	route := &envoy_route_v3.RouteConfiguration{Name: "ingress_http"}
+	listener := &envoy_listener_v3.Listener{Name: "ingress_http"}
+	secret := &envoy_tls_v3.Secret{Name: "default/secret/cd1b506996"}
+	cla := envoy.ClusterLoadAssignment("default/kuard")
+
+	ch.Restore([]proto.Message{cluster, route, listener, stats, secret, cla})
+
+	for name, tc := range map[string]struct {
+		got, want []proto.Message
+	}{
+		"clusters":  {got: ch.ClusterCache.Contents(), want: []proto.Message{cluster}},
+		"routes":    {got: ch.RouteCache.Contents(), want: []proto.Message{route}},
+		"listeners": {got: ch.ListenerCache.Contents(), want: []proto.Message{listener, stats}},
+		"secrets":   {got: ch.SecretCache.Contents(), want: []proto.Message{secret}},
+	} {
+		if diff := cmp.Diff(tc.want, tc.got, cmp.Comparer(proto.Equal)); diff != "" {
+			t.Errorf("%s: %s", name, diff)
+		}
+	}
+
+	var et EndpointsTranslator
+	et.Restore([]proto.Message{cluster, cla})
+	if diff := cmp.Diff([]proto.Message{cla}, et.Contents(), cmp.Comparer(proto.Equal)); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -20,8 +20,8 @@ import (
 	"sort"
 	"sync"
 
-	envoy_api_v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/golang/protobuf/proto"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
 	"github.com/saarasio/enroute/enroute-dp/internal/envoy"
@@ -29,49 +29,33 @@ import (
 
 // ClusterCache manages the contents of the gRPC CDS cache.
 type ClusterCache struct {
-	mu      sync.Mutex
-	values  map[string]*envoy_api_v2.Cluster
-	waiters []chan int
-	last    int
-}
-
-// Register registers ch to receive a value when Notify is called.
-// The value of last is the count of the times Notify has been called on this Cache.
-// It functions of a sequence counter, if the value of last supplied to Register
-// is less than the Cache's internal counter, then the caller has missed at least
-// one notification and will fire immediately.
-//
-// Sends by the broadcaster to ch must not block, therefor ch must have a capacity
-// of at least 1.
-func (c *ClusterCache) Register(ch chan int, last int) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if last < c.last {
-		// notify this channel immediately
-		ch <- c.last
-		return
-	}
-	c.waiters = append(c.waiters, ch)
+	mu     sync.Mutex
+	values map[string]*envoy_cluster_v3.Cluster
+	Cond
 }
 
 // Update replaces the contents of the cache with the supplied map.
-func (c *ClusterCache) Update(v map[string]*envoy_api_v2.Cluster) {
+// Waiters are notified of the clusters which were added, changed,
+// or removed. If none were, no notification is sent.
+func (c *ClusterCache) Update(v map[string]*envoy_cluster_v3.Cluster) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
+	var changed []string
+	for name, value := range v {
+		if old, ok := c.values[name]; !ok || !proto.Equal(old, value) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range c.values {
+		if _, ok := v[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
 	c.values = v
-	c.notify()
-}
-
-// notify notifies all registered waiters that an event has occurred.
-func (c *ClusterCache) notify() {
-	c.last++
+	c.mu.Unlock()
 
-	for _, ch := range c.waiters {
-		ch <- c.last
+	if len(changed) > 0 {
+		c.Notify(changed...)
 	}
-	c.waiters = c.waiters[:0]
 }
 
 // Contents returns a copy of the cache's contents.
@@ -109,19 +93,19 @@ type clusterByName []proto.Message
 func (c clusterByName) Len() int      { return len(c) }
 func (c clusterByName) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
 func (c clusterByName) Less(i, j int) bool {
-	return c[i].(*envoy_api_v2.Cluster).Name < c[j].(*envoy_api_v2.Cluster).Name
+	return c[i].(*envoy_cluster_v3.Cluster).Name < c[j].(*envoy_cluster_v3.Cluster).Name
 }
 
 func (*ClusterCache) TypeURL() string { return resource.ClusterType }
 
 type clusterVisitor struct {
-	clusters map[string]*envoy_api_v2.Cluster
+	clusters map[string]*envoy_cluster_v3.Cluster
 }
 
-// visitCluster produces a map of *envoy_api_v2.Clusters.
-func visitClusters(root dag.Vertex) map[string]*envoy_api_v2.Cluster {
+// visitCluster produces a map of *envoy_cluster_v3.Clusters.
+func visitClusters(root dag.Vertex) map[string]*envoy_cluster_v3.Cluster {
 	cv := clusterVisitor{
-		clusters: make(map[string]*envoy_api_v2.Cluster),
+		clusters: make(map[string]*envoy_cluster_v3.Cluster),
 	}
 	cv.visit(root)
 	return cv.clusters
@@ -17,13 +17,14 @@
 package contour
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 	//"os"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	envoy_api_v2_cluster "github.com/envoyproxy/go-control-plane/envoy/api/v2/cluster"
-	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/prometheus/client_golang/prometheus"
@@ -42,7 +43,7 @@ import (
 
 func TestClusterCacheContents(t *testing.T) {
 	tests := map[string]struct {
-		contents map[string]*v2.Cluster
+		contents map[string]*envoy_cluster_v3.Cluster
 		want     []proto.Message
 	}{
 		"empty": {
@@ -51,29 +52,29 @@ func TestClusterCacheContents(t *testing.T) {
 		},
 		"simple": {
 			contents: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/kuard/443/da39a3ee5e",
 					AltStatName:          "default_kuard_443",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/kuard",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				}),
 			want: []proto.Message{
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/kuard/443/da39a3ee5e",
 					AltStatName:          "default_kuard_443",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/kuard",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				},
 			},
@@ -92,82 +93,82 @@ func TestClusterCacheContents(t *testing.T) {
 
 func TestClusterCacheQuery(t *testing.T) {
 	tests := map[string]struct {
-		contents map[string]*v2.Cluster
+		contents map[string]*envoy_cluster_v3.Cluster
 		query    []string
 		want     []proto.Message
 	}{
 		"exact match": {
 			contents: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/kuard/443/da39a3ee5e",
 					AltStatName:          "default_kuard_443",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/kuard",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				}),
 			query: []string{"default/kuard/443/da39a3ee5e"},
 			want: []proto.Message{
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/kuard/443/da39a3ee5e",
 					AltStatName:          "default_kuard_443",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/kuard",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				},
 			},
 		},
 		"partial match": {
 			contents: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/kuard/443/da39a3ee5e",
 					AltStatName:          "default_kuard_443",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/kuard",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				}),
 			query: []string{"default/kuard/443/da39a3ee5e", "foo/bar/baz"},
 			want: []proto.Message{
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/kuard/443/da39a3ee5e",
 					AltStatName:          "default_kuard_443",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/kuard",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				},
 			},
 		},
 		"no match": {
 			contents: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/kuard/443/da39a3ee5e",
 					AltStatName:          "default_kuard_443",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/kuard",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				}),
 			query: []string{"foo/bar/baz"},
@@ -188,11 +189,11 @@ func TestClusterCacheQuery(t *testing.T) {
 func TestClusterVisit(t *testing.T) {
 	tests := map[string]struct {
 		objs []interface{}
-		want map[string]*v2.Cluster
+		want map[string]*envoy_cluster_v3.Cluster
 	}{
 		"nothing": {
 			objs: nil,
-			want: map[string]*v2.Cluster{},
+			want: map[string]*envoy_cluster_v3.Cluster{},
 		},
 		"single unnamed service": {
 			objs: []interface{}{
@@ -217,16 +218,16 @@ func TestClusterVisit(t *testing.T) {
 				),
 			},
 			want: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/kuard/443/da39a3ee5e",
 					AltStatName:          "default_kuard_443",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/kuard",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				}),
 		},
@@ -254,16 +255,16 @@ func TestClusterVisit(t *testing.T) {
 				),
 			},
 			want: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/kuard/443/da39a3ee5e",
 					AltStatName:          "default_kuard_443",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/kuard/https",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				}),
 		},
@@ -295,17 +296,17 @@ func TestClusterVisit(t *testing.T) {
 				),
 			},
 			want: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/kuard/80/da39a3ee5e",
 					AltStatName:          "default_kuard_80",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/kuard/http",
 					},
 					ConnectTimeout:       protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:             v2.Cluster_ROUND_ROBIN,
-					Http2ProtocolOptions: &envoy_api_v2_core.Http2ProtocolOptions{},
+					LbPolicy:             envoy_cluster_v3.Cluster_ROUND_ROBIN,
+					Http2ProtocolOptions: &envoy_core_v3.Http2ProtocolOptions{},
 					CommonLbConfig:       envoy.ClusterCommonLBConfig(),
 				},
 			),
@@ -334,16 +335,16 @@ func TestClusterVisit(t *testing.T) {
 				),
 			},
 			want: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "beurocra-7fe4b4/tiny-cog-7fe4b4/443/da39a3ee5e",
 					AltStatName:          "beurocratic-company-test-domain-1_tiny-cog-department-test-instance_443",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "beurocratic-company-test-domain-1/tiny-cog-department-test-instance/svc-0",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				}),
 		},
@@ -385,28 +386,28 @@ func TestClusterVisit(t *testing.T) {
 				}),
 			},
 			want: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/backend/80/da39a3ee5e",
 					AltStatName:          "default_backend_80",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/backend/http",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				},
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/backend/8080/da39a3ee5e",
 					AltStatName:          "default_backend_8080",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/backend/alt",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				},
 			),
@@ -444,30 +445,30 @@ func TestClusterVisit(t *testing.T) {
 				}),
 			},
 			want: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/backend/80/c184349821",
 					AltStatName:          "default_backend_80",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/backend/http",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
-					HealthChecks: []*envoy_api_v2_core.HealthCheck{{
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
+					HealthChecks: []*envoy_core_v3.HealthCheck{{
 						Timeout:            &duration.Duration{Seconds: 2},
 						Interval:           &duration.Duration{Seconds: 10},
 						UnhealthyThreshold: protobuf.UInt32(3),
 						HealthyThreshold:   protobuf.UInt32(2),
-						HealthChecker: &envoy_api_v2_core.HealthCheck_HttpHealthCheck_{
-							HttpHealthCheck: &envoy_api_v2_core.HealthCheck_HttpHealthCheck{
+						HealthChecker: &envoy_core_v3.HealthCheck_HttpHealthCheck_{
+							HttpHealthCheck: &envoy_core_v3.HealthCheck_HttpHealthCheck{
 								Path: "/healthy",
 								Host: "contour-envoy-healthcheck",
 							},
 						},
 					}},
-					CommonLbConfig:                envoy.ClusterCommonLBConfig(),
-					DrainConnectionsOnHostRemoval: true,
+					CommonLbConfig:            envoy.ClusterCommonLBConfig(),
+					IgnoreHealthOnHostRemoval: true,
 				},
 			),
 		},
@@ -509,30 +510,30 @@ func TestClusterVisit(t *testing.T) {
 				}),
 			},
 			want: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/backend/80/7f8051653a",
 					AltStatName:          "default_backend_80",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/backend/http",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
-					HealthChecks: []*envoy_api_v2_core.HealthCheck{{
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
+					HealthChecks: []*envoy_core_v3.HealthCheck{{
 						Timeout:            &duration.Duration{Seconds: 99},
 						Interval:           &duration.Duration{Seconds: 98},
 						UnhealthyThreshold: protobuf.UInt32(97),
 						HealthyThreshold:   protobuf.UInt32(96),
-						HealthChecker: &envoy_api_v2_core.HealthCheck_HttpHealthCheck_{
-							HttpHealthCheck: &envoy_api_v2_core.HealthCheck_HttpHealthCheck{
+						HealthChecker: &envoy_core_v3.HealthCheck_HttpHealthCheck_{
+							HttpHealthCheck: &envoy_core_v3.HealthCheck_HttpHealthCheck{
 								Path: "/healthy",
 								Host: "foo-bar-host",
 							},
 						},
 					}},
-					CommonLbConfig:                envoy.ClusterCommonLBConfig(),
-					DrainConnectionsOnHostRemoval: true,
+					CommonLbConfig:            envoy.ClusterCommonLBConfig(),
+					IgnoreHealthOnHostRemoval: true,
 				},
 			),
 		},
@@ -567,16 +568,16 @@ func TestClusterVisit(t *testing.T) {
 				}),
 			},
 			want: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/backend/80/f3b72af6a9",
 					AltStatName:          "default_backend_80",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/backend/http",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				},
 			),
@@ -612,16 +613,16 @@ func TestClusterVisit(t *testing.T) {
 				}),
 			},
 			want: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/backend/80/8bf87fefba",
 					AltStatName:          "default_backend_80",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/backend/http",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_LEAST_REQUEST,
+					LbPolicy:       envoy_cluster_v3.Cluster_LEAST_REQUEST,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				},
 			),
@@ -657,16 +658,16 @@ func TestClusterVisit(t *testing.T) {
 				}),
 			},
 			want: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/backend/80/58d888c08a",
 					AltStatName:          "default_backend_80",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/backend/http",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_RANDOM,
+					LbPolicy:       envoy_cluster_v3.Cluster_RANDOM,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				},
 			),
@@ -711,28 +712,28 @@ func TestClusterVisit(t *testing.T) {
 				}),
 			},
 			want: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/backend/80/58d888c08a",
 					AltStatName:          "default_backend_80",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/backend/http",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_RANDOM,
+					LbPolicy:       envoy_cluster_v3.Cluster_RANDOM,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				},
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/backend/80/8bf87fefba",
 					AltStatName:          "default_backend_80",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/backend/http",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_LEAST_REQUEST,
+					LbPolicy:       envoy_cluster_v3.Cluster_LEAST_REQUEST,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				},
 			),
@@ -768,16 +769,16 @@ func TestClusterVisit(t *testing.T) {
 				}),
 			},
 			want: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/backend/80/86d7a9c129",
 					AltStatName:          "default_backend_80",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/backend/http",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				},
 			),
@@ -813,18 +814,18 @@ func TestClusterVisit(t *testing.T) {
 				),
 			},
 			want: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/kuard/80/da39a3ee5e",
 					AltStatName:          "default_kuard_80",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/kuard/http",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
-					CircuitBreakers: &envoy_api_v2_cluster.CircuitBreakers{
-						Thresholds: []*envoy_api_v2_cluster.CircuitBreakers_Thresholds{{
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
+					CircuitBreakers: &envoy_cluster_v3.CircuitBreakers{
+						Thresholds: []*envoy_cluster_v3.CircuitBreakers_Thresholds{{
 							MaxConnections:     protobuf.UInt32(9000),
 							MaxPendingRequests: protobuf.UInt32(4096),
 							MaxRequests:        protobuf.UInt32(404),
@@ -863,16 +864,16 @@ func TestClusterVisit(t *testing.T) {
 				),
 			},
 			want: clustermap(
-				&v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/kuard/443/da39a3ee5e",
 					AltStatName:          "default_kuard_443",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-					EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/kuard/https",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				}),
 		},
@@ -918,22 +919,108 @@ func serviceWithAnnotations(ns, name string, annotations map[string]string, port
 	}
 }
 
-func cluster(c *v2.Cluster) *v2.Cluster {
+func cluster(c *envoy_cluster_v3.Cluster) *envoy_cluster_v3.Cluster {
 	// NOTE: Keep this in sync with envoy.defaultCluster().
-	defaults := &v2.Cluster{
+	defaults := &envoy_cluster_v3.Cluster{
 		ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
 		CommonLbConfig: envoy.ClusterCommonLBConfig(),
-		LbPolicy:       v2.Cluster_ROUND_ROBIN,
+		LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 	}
 
 	proto.Merge(defaults, c)
 	return defaults
 }
 
-func clustermap(clusters ...*v2.Cluster) map[string]*v2.Cluster {
-	m := make(map[string]*v2.Cluster)
+func clustermap(clusters ...*envoy_cluster_v3.Cluster) map[string]*envoy_cluster_v3.Cluster {
+	m := make(map[string]*envoy_cluster_v3.Cluster)
 	for _, c := range clusters {
 		m[c.Name] = cluster(c)
 	}
 	return m
 }
+
+// BenchmarkClusterCacheUpdate measures the work done by 5000 CDS streams,
+// each watching its own cluster, when one of 5000 clusters changes.
+// Streams woken by an update query and marshal their resources, as the
+// xDS server does before deciding whether to send them.
+func BenchmarkClusterCacheUpdate(b *testing.B) {
+	const clusters = 5000
+
+	cluster := func(i int, timeout time.Duration) *envoy_cluster_v3.Cluster {
+		return &envoy_cluster_v3.Cluster{
+			Name:                 fmt.Sprintf("default/svc%d/80/da39a3ee5e", i),
+			AltStatName:          fmt.Sprintf("default_svc%d_80", i),
+			ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+			EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
+				EdsConfig:   envoy.ConfigSource("enroute"),
+				ServiceName: fmt.Sprintf("default/svc%d", i),
+			},
+			ConnectTimeout: protobuf.Duration(timeout),
+			LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
+			CommonLbConfig: envoy.ClusterCommonLBConfig(),
+		}
+	}
+
+	// each update alternates between two sets of clusters which
+	// differ only in the connect timeout of the first.
+	updates := [2]map[string]*envoy_cluster_v3.Cluster{}
+	for u := range updates {
+		updates[u] = make(map[string]*envoy_cluster_v3.Cluster, clusters)
+		for i := 0; i < clusters; i++ {
+			timeout := 250 * time.Millisecond
+			if i == 0 {
+				timeout += time.Duration(u) * time.Millisecond
+			}
+			c := cluster(i, timeout)
+			updates[u][c.Name] = c
+		}
+	}
+
+	run := func(b *testing.B, hinted bool) {
+		var cc ClusterCache
+		cc.Update(updates[0])
+
+		done := make(chan struct{})
+		defer close(done)
+		var wg sync.WaitGroup
+		for i := 0; i < clusters; i++ {
+			names := []string{cluster(i, 0).Name}
+			var hints []string
+			if hinted {
+				hints = names
+			}
+			ch := make(chan int, 1)
+			cc.Register(ch, 1, hints...)
+			go func() {
+				for {
+					select {
+					case last := <-ch:
+						for _, msg := range cc.Query(names) {
+							if _, err := proto.Marshal(msg); err != nil {
+								b.Error(err)
+							}
+						}
+						cc.Register(ch, last, hints...)
+						wg.Done()
+					case <-done:
+						return
+					}
+				}
+			}()
+		}
+
+		woken := 1
+		if !hinted {
+			woken = clusters
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			wg.Add(woken)
+			cc.Update(updates[(i+1)%2])
+			wg.Wait()
+		}
+	}
+
+	b.Run("notify all", func(b *testing.B) { run(b, false) })
+	b.Run("notify changed", func(b *testing.B) { run(b, true) })
+}
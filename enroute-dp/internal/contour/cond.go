@@ -25,19 +25,31 @@ import "sync"
 // the waiters. This permits goroutines to wait on Cond events using select.
 type Cond struct {
 	mu      sync.Mutex
-	waiters []chan int
+	waiters []waiter
 	last    int
 }
 
+// waiter is a channel registered for a notification, and the names
+// of the resources it is interested in. A waiter without hints is
+// interested in every resource.
+type waiter struct {
+	ch    chan int
+	hints []string
+}
+
 // Register registers ch to receive a value when Notify is called.
 // The value of last is the count of the times Notify has been called on this Cond.
 // It functions of a sequence counter, if the value of last supplied to Register
 // is less than the Conds internal counter, then the caller has missed at least
 // one notification and will fire immediately.
 //
+// If hints are supplied, ch is only notified by calls to Notify which name
+// one of them, or which name no resources at all. Registering ch again
+// before it is notified replaces its hints.
+//
 // Sends by the broadcaster to ch must not block, therefor ch must have a capacity
 // of at least 1.
-func (c *Cond) Register(ch chan int, last int) {
+func (c *Cond) Register(ch chan int, last int, hints ...string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -46,17 +58,84 @@ func (c *Cond) Register(ch chan int, last int) {
 		ch <- c.last
 		return
 	}
-	c.waiters = append(c.waiters, ch)
+	// a channel registered again is interested in the hints given now.
+	for i := range c.waiters {
+		if c.waiters[i].ch == ch {
+			c.waiters[i].hints = hints
+			return
+		}
+	}
+	c.waiters = append(c.waiters, waiter{ch: ch, hints: hints})
 }
 
-// Notify notifies all registered waiters that an event has ocured.
-func (c *Cond) Notify() {
+// Unregister removes ch from the waiters, so that a goroutine which no
+// longer waits, like a stream which has ended, does not stay registered
+// until a change it was interested in.
+func (c *Cond) Unregister(ch chan int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.ch != ch {
+			waiters = append(waiters, w)
+		}
+	}
+	for i := len(waiters); i < len(c.waiters); i++ {
+		c.waiters[i] = waiter{}
+	}
+	c.waiters = waiters
+}
+
+// Notify notifies the registered waiters interested in the named
+// resources that an event has ocured. If no names are given every
+// waiter is notified.
+func (c *Cond) Notify(hints ...string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.last++
 
-	for _, ch := range c.waiters {
-		ch <- c.last
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if len(hints) == 0 || len(w.hints) == 0 || intersects(w.hints, hints) {
+			w.ch <- c.last
+			continue
+		}
+		// not interested, leave registered.
+		waiters = append(waiters, w)
+	}
+	// clear the tail of the slice so it does not hold on to channels.
+	for i := len(waiters); i < len(c.waiters); i++ {
+		c.waiters[i] = waiter{}
+	}
+	c.waiters = waiters
+}
+
+// intersects returns true if a and b have a value in common.
+func intersects(a, b []string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	// most notifications name a handful of resources,
+	// a linear scan of the smaller set is fastest.
+	if len(a) <= 8 {
+		for _, x := range a {
+			for _, y := range b {
+				if x == y {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	set := make(map[string]struct{}, len(a))
+	for _, x := range a {
+		set[x] = struct{}{}
+	}
+	for _, y := range b {
+		if _, ok := set[y]; ok {
+			return true
+		}
 	}
-	c.waiters = c.waiters[:0]
+	return false
 }
@@ -58,3 +58,89 @@ func TestCondRegisterAfterNotifyWithCorrectSequenceShouldNotBroadcast(t *testing
 	default:
 	}
 }
+
+func TestCondNotifyHints(t *testing.T) {
+	var c Cond
+	kuard := make(chan int, 1)
+	nginx := make(chan int, 1)
+	all := make(chan int, 1)
+	c.Register(kuard, 0, "default/kuard")
+	c.Register(nginx, 0, "default/nginx")
+	c.Register(all, 0)
+
+	notified := func(ch chan int) bool {
+		select {
+		case <-ch:
+			return true
+		default:
+			return false
+		}
+	}
+
+	c.Notify("default/kuard")
+	if !notified(kuard) {
+		t.Fatal("kuard was not notified of a change to default/kuard")
+	}
+	if notified(nginx) {
+		t.Fatal("nginx was notified of a change to default/kuard")
+	}
+	if !notified(all) {
+		t.Fatal("a waiter without hints was not notified")
+	}
+
+	// nginx is still registered, and is notified when no names are given.
+	c.Notify()
+	if !notified(nginx) {
+		t.Fatal("nginx was not notified of a change to every resource")
+	}
+}
+
+func TestCondUnregister(t *testing.T) {
+	var c Cond
+	kuard := make(chan int, 1)
+	nginx := make(chan int, 1)
+	c.Register(kuard, 0, "default/kuard")
+	c.Register(nginx, 0, "default/nginx")
+
+	// the waiter of a stream which has ended is released, rather than
+	// held until a change to default/kuard.
+	c.Unregister(kuard)
+	if len(c.waiters) != 1 || c.waiters[0].ch != nginx {
+		t.Fatalf("expected only nginx to be registered, got %v", c.waiters)
+	}
+
+	c.Notify()
+	select {
+	case v := <-kuard:
+		t.Fatal("kuard was notified after it was unregistered", v)
+	default:
+	}
+	select {
+	case <-nginx:
+	default:
+		t.Fatal("nginx was not notified")
+	}
+}
+
+func TestCondRegisterAgainReplacesHints(t *testing.T) {
+	var c Cond
+	ch := make(chan int, 1)
+	c.Register(ch, 0, "default/kuard")
+	c.Register(ch, 0, "default/nginx")
+	if len(c.waiters) != 1 {
+		t.Fatalf("expected ch to be registered once, got %d", len(c.waiters))
+	}
+
+	c.Notify("default/kuard")
+	select {
+	case v := <-ch:
+		t.Fatal("ch was notified of a change to hints it replaced", v)
+	default:
+	}
+	c.Notify("default/nginx")
+	select {
+	case <-ch:
+	default:
+		t.Fatal("ch was not notified of a change to default/nginx")
+	}
+}
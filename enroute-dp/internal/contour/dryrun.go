@@ -0,0 +1,282 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package contour
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+	gatewayhostv1 "github.com/saarasio/enroute/enroute-dp/apis/enroute/v1beta1"
+	"github.com/saarasio/enroute/enroute-dp/apis/generated/clientset/versioned/scheme"
+	"github.com/saarasio/enroute/enroute-dp/internal/dag"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// maxDryRunBytes limits the size of the candidate objects of a dry run.
+const maxDryRunBytes = 10 << 20
+
+// DryRun is the result of building a candidate configuration without
+// serving it. It holds the status of each GatewayHost of the candidate,
+// and how the resources it produces differ from those served.
+type DryRun struct {
+	Statuses  []DryRunStatus `json:"statuses"`
+	Clusters  ResourceDiff   `json:"clusters"`
+	Routes    ResourceDiff   `json:"routes"`
+	Listeners ResourceDiff   `json:"listeners"`
+	Secrets   ResourceDiff   `json:"secrets"`
+}
+
+// DryRunStatus is the status a GatewayHost would be given.
+type DryRunStatus struct {
+	Namespace   string   `json:"namespace"`
+	Name        string   `json:"name"`
+	Vhost       string   `json:"vhost,omitempty"`
+	Status      string   `json:"status"`
+	Description string   `json:"description"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// ResourceDiff holds the names of the resources of a type which a
+// candidate configuration adds, removes, and changes.
+type ResourceDiff struct {
+	Added   []string         `json:"added,omitempty"`
+	Removed []string         `json:"removed,omitempty"`
+	Changed []ResourceChange `json:"changed,omitempty"`
+}
+
+// ResourceChange is a resource a candidate configuration changes.
+type ResourceChange struct {
+	Name string `json:"name"`
+
+	// Diff describes the change, in the format of cmp.Diff. It is
+	// empty for secrets, whose contents are not disclosed.
+	Diff string `json:"diff,omitempty"`
+}
+
+// A DryRunner builds candidate configurations from the objects of
+// KubernetesCache, and compares them with those served by CacheHandler.
+type DryRunner struct {
+	*CacheHandler
+	KubernetesCache *dag.KubernetesCache
+}
+
+// DryRun builds the configuration produced by inserting objs into a copy
+// of the KubernetesCache, and compares it with the one served. If replace
+// is true, the GatewayHosts, filters, and global configs of the copy are
+// removed first, so objs holds the whole candidate configuration. What is
+// served, and the status of the GatewayHosts, are left unchanged.
+func (d *DryRunner) DryRun(objs []interface{}, replace bool) *DryRun {
+	kc := d.KubernetesCache.Clone()
+	if replace {
+		kc.RemoveGatewayHostConfig()
+	}
+	for _, obj := range objs {
+		kc.Insert(obj)
+	}
+	root := dag.BuildDAG(kc)
+
+	var result DryRun
+	for _, st := range root.Statuses() {
+		result.Statuses = append(result.Statuses, DryRunStatus{
+			Namespace:   st.Object.Namespace,
+			Name:        st.Object.Name,
+			Vhost:       st.Vhost,
+			Status:      st.Status,
+			Description: st.Description,
+			Warnings:    st.Warnings,
+		})
+	}
+	sort.Slice(result.Statuses, func(i, j int) bool {
+		a, b := result.Statuses[i], result.Statuses[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	var clusters, routes, listeners, secrets []proto.Message
+	for _, v := range visitClusters(root) {
+		clusters = append(clusters, v)
+	}
+	for _, v := range visitRoutes(root) {
+		routes = append(routes, v)
+	}
+	for _, v := range visitListeners(root, &d.ListenerVisitorConfig) {
+		listeners = append(listeners, v)
+	}
+	for _, v := range d.ListenerCache.staticValues {
+		listeners = append(listeners, v)
+	}
+	for _, v := range visitSecrets(root) {
+		secrets = append(secrets, v)
+	}
+
+	result.Clusters = diffResources(d.ClusterCache.Contents(), clusters, true)
+	result.Routes = diffResources(d.RouteCache.Contents(), routes, true)
+	result.Listeners = diffResources(d.ListenerCache.Contents(), listeners, true)
+	result.Secrets = diffResources(d.SecretCache.Contents(), secrets, false)
+	return &result
+}
+
+// ServeHTTP runs a dry run of the GatewayHosts, filters, and global configs
+// in the request body, as a stream of YAML or JSON documents, and writes
+// the result as JSON. The replace query parameter sets whether they replace
+// those the configuration is built from.
+func (d *DryRunner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "dry runs must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	objs, err := decodeObjects(http.MaxBytesReader(w, r.Body, maxDryRunBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(d.DryRun(objs, r.URL.Query().Get("replace") == "true")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// DryRunners holds the DryRunner of each proxy, keyed by name.
+type DryRunners map[string]*DryRunner
+
+// ServeHTTP runs a dry run like DryRunner.ServeHTTP against the proxy
+// named by the proxy query parameter, which may be omitted when a
+// single proxy is served.
+func (ds DryRunners) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	proxy := r.URL.Query().Get("proxy")
+	if proxy == "" && len(ds) == 1 {
+		for name := range ds {
+			proxy = name
+		}
+	}
+	d, ok := ds[proxy]
+	if !ok {
+		var names []string
+		for name := range ds {
+			names = append(names, strconv.Quote(name))
+		}
+		sort.Strings(names)
+		http.Error(w, fmt.Sprintf("unknown proxy %q, expected one of %s", proxy, strings.Join(names, ", ")), http.StatusNotFound)
+		return
+	}
+	d.ServeHTTP(w, r)
+}
+
+// decodeObjects decodes the GatewayHosts, filters, and global configs
+// of a stream of YAML or JSON documents.
+func decodeObjects(r io.Reader) ([]interface{}, error) {
+	dec := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	deserializer := scheme.Codecs.UniversalDeserializer()
+	var objs []interface{}
+	for {
+		var raw runtime.RawExtension
+		if err := dec.Decode(&raw); err == io.EOF {
+			return objs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(raw.Raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw.Raw), []byte("null")) {
+			// an empty document.
+			continue
+		}
+		obj, gvk, err := deserializer.Decode(raw.Raw, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		switch obj.(type) {
+		case *gatewayhostv1.GatewayHost, *gatewayhostv1.RouteFilter, *gatewayhostv1.HttpFilter, *gatewayhostv1.GlobalConfig:
+			objs = append(objs, obj)
+		default:
+			return nil, fmt.Errorf("%s is not a GatewayHost, RouteFilter, HttpFilter, or GlobalConfig", gvk.Kind)
+		}
+	}
+}
+
+// diffResources compares the resources served with those of a candidate
+// configuration. If disclose is false the changes are not described.
+func diffResources(served, candidate []proto.Message, disclose bool) ResourceDiff {
+	before := make(map[string]proto.Message, len(served))
+	for _, msg := range served {
+		before[protoName(msg)] = msg
+	}
+
+	var diff ResourceDiff
+	after := make(map[string]bool, len(candidate))
+	for _, msg := range candidate {
+		name := protoName(msg)
+		after[name] = true
+		old, ok := before[name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, name)
+		case !proto.Equal(old, msg):
+			change := ResourceChange{Name: name}
+			if disclose {
+				change.Diff = cmp.Diff(jsonValue(old), jsonValue(msg))
+			}
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+	for name := range before {
+		if !after[name] {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Name < diff.Changed[j].Name })
+	return diff
+}
+
+// jsonValue returns msg in its JSON form, decoded into maps and slices,
+// so that a diff of two messages reads like their JSON.
+func jsonValue(msg proto.Message) interface{} {
+	m := jsonpb.Marshaler{OrigName: true}
+	s, err := m.MarshalToString(msg)
+	if err != nil {
+		return err.Error()
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return err.Error()
+	}
+	return v
+}
+
+// protoName returns the name of a cluster, route, listener, or secret.
+func protoName(msg proto.Message) string {
+	switch msg := msg.(type) {
+	case *envoy_cluster_v3.Cluster:
+		return msg.Name
+	case *envoy_route_v3.RouteConfiguration:
+		return msg.Name
+	case *envoy_listener_v3.Listener:
+		return msg.Name
+	case *envoy_tls_v3.Secret:
+		return msg.Name
+	default:
+		return ""
+	}
+}
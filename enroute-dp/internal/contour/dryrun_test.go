@@ -0,0 +1,239 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package contour
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gatewayhostv1 "github.com/saarasio/enroute/enroute-dp/apis/enroute/v1beta1"
+	"github.com/saarasio/enroute/enroute-dp/internal/dag"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func dryRunGatewayHost(service string) *gatewayhostv1.GatewayHost {
+	return &gatewayhostv1.GatewayHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: "default",
+		},
+		Spec: gatewayhostv1.GatewayHostSpec{
+			VirtualHost: &gatewayhostv1.VirtualHost{
+				Fqdn: "www.example.com",
+			},
+			Routes: []gatewayhostv1.Route{{
+				Conditions: []gatewayhostv1.Condition{{
+					Prefix: "/",
+				}},
+				Services: []gatewayhostv1.Service{{
+					Name: service,
+					Port: 80,
+				}},
+			}},
+		},
+	}
+}
+
+func dryRunService(name string) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol: "TCP",
+				Port:     80,
+			}},
+		},
+	}
+}
+
+// newDryRunner returns a DryRunner serving the configuration built from objs.
+func newDryRunner(objs ...interface{}) *DryRunner {
+	var kc dag.KubernetesCache
+	for _, obj := range objs {
+		kc.Insert(obj)
+	}
+	ch := &CacheHandler{
+		ListenerCache: NewListenerCache("0.0.0.0", 8002),
+	}
+	root := dag.BuildDAG(&kc)
+	ch.updateSecrets(root)
+	ch.updateListeners(root)
+	ch.updateRoutes(root)
+	ch.updateClusters(root)
+	return &DryRunner{CacheHandler: ch, KubernetesCache: &kc}
+}
+
+func TestDryRun(t *testing.T) {
+	d := newDryRunner(dryRunService("kuard"), dryRunService("nginx"), dryRunGatewayHost("kuard"))
+
+	// the served configuration is unchanged.
+	got := d.DryRun([]interface{}{dryRunGatewayHost("kuard")}, false)
+	if len(got.Clusters.Added)+len(got.Clusters.Removed)+len(got.Clusters.Changed) != 0 {
+		t.Fatalf("expected no cluster changes, got: %+v", got.Clusters)
+	}
+	if len(got.Routes.Changed) != 0 {
+		t.Fatalf("expected no route changes, got: %+v", got.Routes)
+	}
+	if len(got.Statuses) != 1 || got.Statuses[0].Status != dag.StatusValid {
+		t.Fatalf("expected a valid GatewayHost, got: %+v", got.Statuses)
+	}
+
+	// the route is moved to another service.
+	got = d.DryRun([]interface{}{dryRunGatewayHost("nginx")}, false)
+	if len(got.Clusters.Added) != 1 || !strings.HasPrefix(got.Clusters.Added[0], "default/nginx/80/") {
+		t.Fatalf("expected the nginx cluster to be added, got: %+v", got.Clusters)
+	}
+	if len(got.Clusters.Removed) != 1 || !strings.HasPrefix(got.Clusters.Removed[0], "default/kuard/80/") {
+		t.Fatalf("expected the kuard cluster to be removed, got: %+v", got.Clusters)
+	}
+	if len(got.Routes.Changed) != 1 || got.Routes.Changed[0].Name != "ingress_http" || !strings.Contains(got.Routes.Changed[0].Diff, "nginx") {
+		t.Fatalf("expected ingress_http to change, got: %+v", got.Routes)
+	}
+
+	// the dry run did not change what is served.
+	if len(d.ClusterCache.Contents()) != 1 {
+		t.Fatalf("expected the served clusters to be unchanged, got: %v", d.ClusterCache.Contents())
+	}
+
+	// every GatewayHost is removed.
+	got = d.DryRun(nil, true)
+	if len(got.Statuses) != 0 {
+		t.Fatalf("expected no statuses, got: %+v", got.Statuses)
+	}
+	if len(got.Clusters.Removed) != 1 {
+		t.Fatalf("expected the kuard cluster to be removed, got: %+v", got.Clusters)
+	}
+}
+
+func TestDryRunServeHTTP(t *testing.T) {
+	d := newDryRunner(dryRunService("kuard"), dryRunService("nginx"), dryRunGatewayHost("kuard"))
+
+	tests := map[string]struct {
+		method, body string
+		want         int
+		contains     string
+	}{
+		"gatewayhost": {
+			method: http.MethodPost,
+			body: `---
+apiVersion: enroute.saaras.io/v1beta1
+kind: GatewayHost
+metadata:
+  name: simple
+  namespace: default
+spec:
+  virtualhost:
+    fqdn: www.example.com
+  routes:
+  - conditions:
+    - prefix: /
+    services:
+    - name: nginx
+      port: 80
+`,
+			want:     http.StatusOK,
+			contains: `"default/nginx/80/`,
+		},
+		"not a gatewayhost": {
+			method: http.MethodPost,
+			body: `apiVersion: v1
+kind: Service
+metadata:
+  name: kuard
+`,
+			want: http.StatusBadRequest,
+		},
+		"get": {
+			method: http.MethodGet,
+			want:   http.StatusMethodNotAllowed,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			d.ServeHTTP(rec, httptest.NewRequest(tc.method, "/debug/dryrun", strings.NewReader(tc.body)))
+			if rec.Code != tc.want {
+				t.Fatalf("expected status %d, got %d: %s", tc.want, rec.Code, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tc.contains) {
+				t.Fatalf("expected body to contain %q, got: %s", tc.contains, rec.Body)
+			}
+		})
+	}
+}
+
+func TestDryRunnersServeHTTP(t *testing.T) {
+	body := `---
+apiVersion: enroute.saaras.io/v1beta1
+kind: GatewayHost
+metadata:
+  name: simple
+  namespace: default
+spec:
+  virtualhost:
+    fqdn: www.example.com
+  routes:
+  - conditions:
+    - prefix: /
+    services:
+    - name: kuard
+      port: 80
+`
+	gw1 := newDryRunner(dryRunService("kuard"), dryRunGatewayHost("kuard"))
+	gw2 := newDryRunner(dryRunService("kuard"))
+
+	tests := map[string]struct {
+		ds       DryRunners
+		query    string
+		want     int
+		contains string
+	}{
+		"single proxy": {
+			ds:   DryRunners{"gw1": gw1},
+			want: http.StatusOK,
+		},
+		"proxy with the candidate served": {
+			ds:       DryRunners{"gw1": gw1, "gw2": gw2},
+			query:    "?proxy=gw1",
+			want:     http.StatusOK,
+			contains: `"clusters": {}`,
+		},
+		"proxy without the candidate served": {
+			ds:       DryRunners{"gw1": gw1, "gw2": gw2},
+			query:    "?proxy=gw2",
+			want:     http.StatusOK,
+			contains: `"default/kuard/80/`,
+		},
+		"proxy required": {
+			ds:       DryRunners{"gw1": gw1, "gw2": gw2},
+			want:     http.StatusNotFound,
+			contains: `expected one of "gw1", "gw2"`,
+		},
+		"unknown proxy": {
+			ds:    DryRunners{"gw1": gw1},
+			query: "?proxy=gw3",
+			want:  http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tc.ds.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/debug/dryrun"+tc.query, strings.NewReader(body)))
+			if rec.Code != tc.want {
+				t.Fatalf("expected status %d, got %d: %s", tc.want, rec.Code, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tc.contains) {
+				t.Fatalf("expected body to contain %q, got: %s", tc.contains, rec.Body)
+			}
+		})
+	}
+}
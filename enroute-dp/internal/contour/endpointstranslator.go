@@ -21,9 +21,8 @@ import (
 	"strings"
 	"sync"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	envoy_api_v2_endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
-	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
+	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/golang/protobuf/proto"
 	"github.com/saarasio/enroute/enroute-dp/internal/envoy"
 	"github.com/sirupsen/logrus"
@@ -87,7 +86,7 @@ func (e *EndpointsTranslator) Query(names []string) []proto.Message {
 	for _, n := range names {
 		v, ok := e.entries[n]
 		if !ok {
-			v = &v2.ClusterLoadAssignment{
+			v = &envoy_endpoint_v3.ClusterLoadAssignment{
 				ClusterName: n,
 			}
 		}
@@ -102,11 +101,25 @@ type clusterLoadAssignmentsByName []proto.Message
 func (c clusterLoadAssignmentsByName) Len() int      { return len(c) }
 func (c clusterLoadAssignmentsByName) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
 func (c clusterLoadAssignmentsByName) Less(i, j int) bool {
-	return c[i].(*v2.ClusterLoadAssignment).ClusterName < c[j].(*v2.ClusterLoadAssignment).ClusterName
+	return c[i].(*envoy_endpoint_v3.ClusterLoadAssignment).ClusterName < c[j].(*envoy_endpoint_v3.ClusterLoadAssignment).ClusterName
 }
 
 func (*EndpointsTranslator) TypeURL() string { return resource.EndpointType }
 
+// Restore adds the ClusterLoadAssignments of msgs to the translator, for
+// example those saved by a previous run. Other messages are ignored.
+func (e *EndpointsTranslator) Restore(msgs []proto.Message) {
+	var changed []string
+	for _, msg := range msgs {
+		if a, ok := msg.(*envoy_endpoint_v3.ClusterLoadAssignment); ok && e.Add(a) {
+			changed = append(changed, a.ClusterName)
+		}
+	}
+	if len(changed) > 0 {
+		e.Notify(changed...)
+	}
+}
+
 func (e *EndpointsTranslator) addEndpoints(ep *v1.Endpoints) {
 	e.recomputeClusterLoadAssignment(nil, ep)
 }
@@ -132,7 +145,13 @@ func (e *EndpointsTranslator) recomputeClusterLoadAssignment(oldep, newep *v1.En
 		return
 	}
 
-	defer e.Notify()
+	// names of the cluster load assignments which changed.
+	var changed []string
+	defer func() {
+		if len(changed) > 0 {
+			e.Notify(changed...)
+		}
+	}()
 
 	if oldep == nil {
 		oldep = &v1.Endpoints{
@@ -146,7 +165,7 @@ func (e *EndpointsTranslator) recomputeClusterLoadAssignment(oldep, newep *v1.En
 		}
 	}
 
-	clas := make(map[string]*v2.ClusterLoadAssignment)
+	clas := make(map[string]*envoy_endpoint_v3.ClusterLoadAssignment)
 	// add or update endpoints
 	for _, s := range newep.Subsets {
 		// skip any subsets that don't have ready addresses
@@ -162,10 +181,10 @@ func (e *EndpointsTranslator) recomputeClusterLoadAssignment(oldep, newep *v1.En
 			portname := p.Name
 			cla, ok := clas[portname]
 			if !ok {
-				cla = &v2.ClusterLoadAssignment{
+				cla = &envoy_endpoint_v3.ClusterLoadAssignment{
 					ClusterName: servicename(newep.ObjectMeta, portname),
-					Endpoints: []*envoy_api_v2_endpoint.LocalityLbEndpoints{{
-						LbEndpoints: make([]*envoy_api_v2_endpoint.LbEndpoint, 0, 1),
+					Endpoints: []*envoy_endpoint_v3.LocalityLbEndpoints{{
+						LbEndpoints: make([]*envoy_endpoint_v3.LbEndpoint, 0, 1),
 					}},
 				}
 				clas[portname] = cla
@@ -179,7 +198,9 @@ func (e *EndpointsTranslator) recomputeClusterLoadAssignment(oldep, newep *v1.En
 
 	// iterate all the defined clusters and add or update them.
 	for _, a := range clas {
-		e.Add(a)
+		if e.Add(a) {
+			changed = append(changed, a.ClusterName)
+		}
 	}
 
 	// iterate over the ports in the old spec, remove any that are not
@@ -194,7 +215,10 @@ func (e *EndpointsTranslator) recomputeClusterLoadAssignment(oldep, newep *v1.En
 			portname := p.Name
 			if _, ok := clas[portname]; !ok {
 				// port is not present in the list added / updated, so remove it
-				e.Remove(servicename(oldep.ObjectMeta, portname))
+				name := servicename(oldep.ObjectMeta, portname)
+				if e.Remove(name) {
+					changed = append(changed, name)
+				}
 			}
 		}
 	}
@@ -202,26 +226,36 @@ func (e *EndpointsTranslator) recomputeClusterLoadAssignment(oldep, newep *v1.En
 
 type clusterLoadAssignmentCache struct {
 	mu      sync.Mutex
-	entries map[string]*v2.ClusterLoadAssignment
+	entries map[string]*envoy_endpoint_v3.ClusterLoadAssignment
 }
 
 // Add adds an entry to the cache. If a ClusterLoadAssignment with the same
-// name exists, it is replaced.
-func (c *clusterLoadAssignmentCache) Add(a *v2.ClusterLoadAssignment) {
+// name exists, it is replaced. Add returns false if the entry was already
+// present with the same value.
+func (c *clusterLoadAssignmentCache) Add(a *envoy_endpoint_v3.ClusterLoadAssignment) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if c.entries == nil {
-		c.entries = make(map[string]*v2.ClusterLoadAssignment)
+		c.entries = make(map[string]*envoy_endpoint_v3.ClusterLoadAssignment)
+	}
+	if old, ok := c.entries[a.ClusterName]; ok && proto.Equal(old, a) {
+		return false
 	}
 	c.entries[a.ClusterName] = a
+	return true
 }
 
 // Remove removes the named entry from the cache. If the entry
-// is not present in the cache, the operation is a no-op.
-func (c *clusterLoadAssignmentCache) Remove(name string) {
+// is not present in the cache, the operation is a no-op and
+// Remove returns false.
+func (c *clusterLoadAssignmentCache) Remove(name string) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if _, ok := c.entries[name]; !ok {
+		return false
+	}
 	delete(c.entries, name)
+	return true
 }
 
 // Contents returns a copy of the contents of the cache.
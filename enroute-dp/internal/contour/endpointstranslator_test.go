@@ -17,19 +17,22 @@
 package contour
 
 import (
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	"github.com/golang/protobuf/proto"
 	"github.com/google/go-cmp/cmp"
 	"github.com/saarasio/enroute/enroute-dp/internal/envoy"
+	"google.golang.org/protobuf/testing/protocmp"
 	v1 "k8s.io/api/core/v1"
 )
 
 func TestEndpointsTranslatorContents(t *testing.T) {
 	tests := map[string]struct {
-		contents map[string]*v2.ClusterLoadAssignment
+		contents map[string]*envoy_endpoint_v3.ClusterLoadAssignment
 		want     []proto.Message
 	}{
 		"empty": {
@@ -55,7 +58,7 @@ func TestEndpointsTranslatorContents(t *testing.T) {
 			var et EndpointsTranslator
 			et.entries = tc.contents
 			got := et.Contents()
-			if diff := cmp.Diff(tc.want, got); diff != "" {
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
 				t.Fatal(diff)
 			}
 		})
@@ -64,7 +67,7 @@ func TestEndpointsTranslatorContents(t *testing.T) {
 
 func TestEndpointCacheQuery(t *testing.T) {
 	tests := map[string]struct {
-		contents map[string]*v2.ClusterLoadAssignment
+		contents map[string]*envoy_endpoint_v3.ClusterLoadAssignment
 		query    []string
 		want     []proto.Message
 	}{
@@ -113,7 +116,7 @@ func TestEndpointCacheQuery(t *testing.T) {
 			var et EndpointsTranslator
 			et.entries = tc.contents
 			got := et.Query(tc.query)
-			if diff := cmp.Diff(tc.want, got); diff != "" {
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
 				t.Fatal(diff)
 			}
 		})
@@ -248,7 +251,7 @@ func TestEndpointsTranslatorRemoveEndpoints(t *testing.T) {
 			tc.setup(et)
 			et.OnDelete(tc.ep)
 			got := et.Contents()
-			if diff := cmp.Diff(tc.want, got); diff != "" {
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
 				t.Fatal(diff)
 			}
 		})
@@ -312,7 +315,7 @@ func TestEndpointsTranslatorRecomputeClusterLoadAssignment(t *testing.T) {
 			var et EndpointsTranslator
 			et.recomputeClusterLoadAssignment(tc.oldep, tc.newep)
 			got := et.Contents()
-			if diff := cmp.Diff(tc.want, got); diff != "" {
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
 				t.Fatal(diff)
 			}
 		})
@@ -334,7 +337,7 @@ func TestEndpointsTranslatorScaleToZeroEndpoints(t *testing.T) {
 	}
 	got := et.Contents()
 
-	if diff := cmp.Diff(want, got); diff != "" {
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
 		t.Fatal(diff)
 	}
 
@@ -351,10 +354,101 @@ func TestEndpointsTranslatorScaleToZeroEndpoints(t *testing.T) {
 	}
 }
 
-func clusterloadassignments(clas ...*v2.ClusterLoadAssignment) map[string]*v2.ClusterLoadAssignment {
-	m := make(map[string]*v2.ClusterLoadAssignment)
+func clusterloadassignments(clas ...*envoy_endpoint_v3.ClusterLoadAssignment) map[string]*envoy_endpoint_v3.ClusterLoadAssignment {
+	m := make(map[string]*envoy_endpoint_v3.ClusterLoadAssignment)
 	for _, cla := range clas {
 		m[cla.ClusterName] = cla
 	}
 	return m
 }
+
+func TestEndpointsTranslatorNoopUpdateDoesNotNotify(t *testing.T) {
+	var et EndpointsTranslator
+	e1 := endpoints("default", "simple", v1.EndpointSubset{
+		Addresses: addresses("192.168.183.24"),
+		Ports:     ports(8080),
+	})
+	et.OnAdd(e1)
+
+	ch := make(chan int, 1)
+	et.Register(ch, 1)
+
+	// e2 is a copy of e1, so the cluster load assignment is unchanged.
+	e2 := e1.DeepCopy()
+	et.OnUpdate(e1, e2)
+	select {
+	case v := <-ch:
+		t.Fatal("notified of a noop update with seq", v)
+	default:
+	}
+}
+
+// BenchmarkEndpointsTranslatorUpdate measures the work done by 5000 EDS
+// streams, each watching its own cluster, when one cluster's endpoints
+// change. Streams woken by an update query and marshal their resources,
+// as the xDS server does before deciding whether to send them.
+func BenchmarkEndpointsTranslatorUpdate(b *testing.B) {
+	const clusters = 5000
+
+	run := func(b *testing.B, hinted bool) {
+		var et EndpointsTranslator
+		for i := 0; i < clusters; i++ {
+			et.OnAdd(endpoints("default", fmt.Sprintf("svc%d", i), v1.EndpointSubset{
+				Addresses: addresses("192.168.183.24"),
+				Ports:     ports(8080),
+			}))
+		}
+
+		done := make(chan struct{})
+		defer close(done)
+		var wg sync.WaitGroup
+		for i := 0; i < clusters; i++ {
+			names := []string{fmt.Sprintf("default/svc%d", i)}
+			var hints []string
+			if hinted {
+				hints = names
+			}
+			ch := make(chan int, 1)
+			et.Register(ch, clusters, hints...)
+			go func() {
+				for {
+					select {
+					case last := <-ch:
+						for _, msg := range et.Query(names) {
+							if _, err := proto.Marshal(msg); err != nil {
+								b.Error(err)
+							}
+						}
+						et.Register(ch, last, hints...)
+						wg.Done()
+					case <-done:
+						return
+					}
+				}
+			}()
+		}
+
+		woken := 1
+		if !hinted {
+			woken = clusters
+		}
+		old := endpoints("default", "svc0", v1.EndpointSubset{
+			Addresses: addresses("192.168.183.24"),
+			Ports:     ports(8080),
+		})
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			ep := endpoints("default", "svc0", v1.EndpointSubset{
+				Addresses: addresses(fmt.Sprintf("192.168.183.%d", i%2)),
+				Ports:     ports(8080),
+			})
+			wg.Add(woken)
+			et.OnUpdate(old, ep)
+			wg.Wait()
+			old = ep
+		}
+	}
+
+	b.Run("notify all", func(b *testing.B) { run(b, false) })
+	b.Run("notify changed", func(b *testing.B) { run(b, true) })
+}
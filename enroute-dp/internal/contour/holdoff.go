@@ -21,16 +21,21 @@ package contour
 
 import (
 	"sync"
-	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
+	"github.com/saarasio/enroute/enroute-dp/internal/metrics"
 	"github.com/sirupsen/logrus"
 )
 
 const (
 	holdoffDelay    = 100 * time.Millisecond
 	holdoffMaxDelay = 500 * time.Millisecond
+
+	// holdoffAdaptiveFactor is the multiple of the duration of the
+	// last rebuild the max delay is lengthened to in adaptive mode.
+	holdoffAdaptiveFactor = 5
 )
 
 // A HoldoffNotifier delays calls to OnChange in the hope of
@@ -41,43 +46,144 @@ type HoldoffNotifier struct {
 
 	logrus.FieldLogger
 
+	// Metrics, if set, records the coalesced changes and the
+	// duration of each rebuild.
+	*metrics.Metrics
+
+	// Delay is how long a change is held for in the hope that more
+	// follow. If zero, it defaults to 100ms.
+	Delay time.Duration
+
+	// MaxDelay is the longest changes are held for while more keep
+	// arriving. If zero, it defaults to 500ms.
+	MaxDelay time.Duration
+
+	// Adaptive lengthens the delays when rebuilds are expensive. The
+	// delay is at least the duration of the last rebuild, and the max
+	// delay at least five times it.
+	Adaptive bool
+
+	// Proxy labels the metrics of this notifier.
+	Proxy string
+
 	mu      sync.Mutex
 	timer   *time.Timer
 	last    time.Time
-	pending counter
+	rebuild time.Duration        // duration of the last rebuild
+	pending map[string]int       // changes since the last rebuild, by kind
+	kind    string               // kind of the latest change
+	held    *dag.KubernetesCache // changed while held, if set
+	holding bool
+}
+
+// Hold keeps changes pending, rather than rebuilding on them, until
+// Release is called. This keeps the configuration served, like one
+// restored from a snapshot, from being replaced by one built from a
+// source which is not fully loaded yet.
+func (hn *HoldoffNotifier) Hold() {
+	hn.mu.Lock()
+	defer hn.mu.Unlock()
+	hn.holding = true
+}
+
+// Release rebuilds with the changes held since Hold, if any, and
+// rebuilds on changes again.
+func (hn *HoldoffNotifier) Release() {
+	hn.mu.Lock()
+	defer hn.mu.Unlock()
+	if !hn.holding {
+		return
+	}
+	hn.holding = false
+	if kc := hn.held; kc != nil {
+		hn.held = nil
+		hn.update(kc, "releasing held update")
+	}
 }
 
 func (hn *HoldoffNotifier) OnChange(kc *dag.KubernetesCache) {
-	hn.pending.inc()
+	hn.OnChangeKind("unknown", kc)
+}
+
+// OnChangeKind records the kind of the change, by which the metrics
+// of the rebuild it triggers or is coalesced into are labelled.
+func (hn *HoldoffNotifier) OnChangeKind(kind string, kc *dag.KubernetesCache) {
 	hn.mu.Lock()
 	defer hn.mu.Unlock()
+	if hn.pending == nil {
+		hn.pending = make(map[string]int)
+	}
+	hn.pending[kind]++
+	hn.kind = kind
+	if hn.holding {
+		hn.held = kc
+		return
+	}
 	if hn.timer != nil {
 		hn.timer.Stop()
 	}
-	since := time.Since(hn.last)
-	if since > holdoffMaxDelay {
+	delay, maxDelay := hn.delays()
+	if time.Since(hn.last) > maxDelay {
 		// update immediately
-		hn.WithField("last_update", since).WithField("pending", hn.pending.reset()).Info("forcing update")
-		hn.Notifier.OnChange(kc)
-		hn.last = time.Now()
+		hn.update(kc, "forcing update")
 		return
 	}
 
-	hn.timer = time.AfterFunc(holdoffDelay, func() {
+	hn.timer = time.AfterFunc(delay, func() {
 		hn.mu.Lock()
 		defer hn.mu.Unlock()
-		hn.WithField("last_update", time.Since(hn.last)).WithField("pending", hn.pending.reset()).Info("performing delayed update")
-		hn.Notifier.OnChange(kc)
-		hn.last = time.Now()
+		hn.update(kc, "performing delayed update")
 	})
 }
 
-// counter holds an atomically incrementing counter.
-type counter uint64
-
-func (c *counter) inc() uint64 {
-	return atomic.AddUint64((*uint64)(c), 1)
+// delays returns the delay and max delay of the next update.
+// hn.mu must be held.
+func (hn *HoldoffNotifier) delays() (time.Duration, time.Duration) {
+	delay, maxDelay := hn.Delay, hn.MaxDelay
+	if delay <= 0 {
+		delay = holdoffDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = holdoffMaxDelay
+	}
+	if hn.Adaptive {
+		if hn.rebuild > delay {
+			delay = hn.rebuild
+		}
+		if d := holdoffAdaptiveFactor * hn.rebuild; d > maxDelay {
+			maxDelay = d
+		}
+	}
+	if maxDelay < delay {
+		maxDelay = delay
+	}
+	return delay, maxDelay
 }
-func (c *counter) reset() uint64 {
-	return atomic.SwapUint64((*uint64)(c), 0)
+
+// update calls the Notifier with the changes pending. The rebuild is
+// triggered by the latest change, into which the others are coalesced.
+// hn.mu must be held.
+func (hn *HoldoffNotifier) update(kc *dag.KubernetesCache, msg string) {
+	pending, kind := hn.pending, hn.kind
+	hn.pending = nil
+	total := 0
+	for _, n := range pending {
+		total += n
+	}
+	hn.WithField("last_update", time.Since(hn.last)).WithField("pending", total).WithField("kind", kind).Info(msg)
+
+	start := time.Now()
+	hn.Notifier.OnChange(kc)
+	hn.last = time.Now()
+	hn.rebuild = hn.last.Sub(start)
+
+	if hn.Metrics != nil {
+		pending[kind]--
+		for k, n := range pending {
+			if n > 0 {
+				hn.HoldoffCoalescedCounter.With(prometheus.Labels{"kind": k, "proxy": hn.Proxy}).Add(float64(n))
+			}
+		}
+		hn.DAGRebuildSummary.With(prometheus.Labels{"kind": kind, "proxy": hn.Proxy}).Observe(hn.rebuild.Seconds())
+	}
 }
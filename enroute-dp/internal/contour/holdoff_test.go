@@ -0,0 +1,242 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package contour
+
+import (
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	gatewayhostv1 "github.com/saarasio/enroute/enroute-dp/apis/enroute/v1beta1"
+	"github.com/saarasio/enroute/enroute-dp/internal/dag"
+	"github.com/saarasio/enroute/enroute-dp/internal/metrics"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestHoldoffNotifierDelays(t *testing.T) {
+	tests := map[string]struct {
+		hn           *HoldoffNotifier
+		delay, limit time.Duration
+	}{
+		"defaults": {
+			hn:    &HoldoffNotifier{},
+			delay: holdoffDelay,
+			limit: holdoffMaxDelay,
+		},
+		"configured": {
+			hn:    &HoldoffNotifier{Delay: time.Second, MaxDelay: 5 * time.Second},
+			delay: time.Second,
+			limit: 5 * time.Second,
+		},
+		"max delay shorter than delay": {
+			hn:    &HoldoffNotifier{Delay: time.Second, MaxDelay: time.Millisecond},
+			delay: time.Second,
+			limit: time.Second,
+		},
+		"fast rebuild": {
+			hn:    &HoldoffNotifier{Adaptive: true, rebuild: time.Millisecond},
+			delay: holdoffDelay,
+			limit: holdoffMaxDelay,
+		},
+		"slow rebuild": {
+			hn:    &HoldoffNotifier{Adaptive: true, rebuild: 2 * time.Second},
+			delay: 2 * time.Second,
+			limit: 10 * time.Second,
+		},
+		"slow rebuild, not adaptive": {
+			hn:    &HoldoffNotifier{rebuild: 2 * time.Second},
+			delay: holdoffDelay,
+			limit: holdoffMaxDelay,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			delay, limit := tc.hn.delays()
+			if delay != tc.delay || limit != tc.limit {
+				t.Fatalf("expected: %v, %v, got: %v, %v", tc.delay, tc.limit, delay, limit)
+			}
+		})
+	}
+}
+
+// notifierFunc adapts a func to a Notifier.
+type notifierFunc func(*dag.KubernetesCache)
+
+func (f notifierFunc) OnChange(kc *dag.KubernetesCache) { f(kc) }
+
+func TestHoldoffNotifierCoalesces(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	var mu sync.Mutex
+	calls := 0
+	done := make(chan struct{}, 10)
+	hn := &HoldoffNotifier{
+		Notifier: notifierFunc(func(*dag.KubernetesCache) {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			done <- struct{}{}
+		}),
+		FieldLogger: log,
+		Metrics:     metrics.NewMetrics(prometheus.NewRegistry()),
+		Delay:       10 * time.Millisecond,
+		MaxDelay:    time.Minute,
+	}
+
+	var kc dag.KubernetesCache
+	// the first change is forced, as nothing has been built yet.
+	hn.OnChangeKind("gatewayhost", &kc)
+	<-done
+	for _, kind := range []string{"service", "service", "gatewayhost"} {
+		hn.OnChangeKind(kind, &kc)
+	}
+	<-done
+
+	select {
+	case <-done:
+		t.Fatal("changes were not coalesced")
+	case <-time.After(50 * time.Millisecond):
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 {
+		t.Fatalf("expected 2 rebuilds, got %d", calls)
+	}
+
+	// the services were coalesced into the rebuild triggered by the
+	// latest change, to a GatewayHost.
+	var m io_prometheus_client.Metric
+	if err := hn.HoldoffCoalescedCounter.With(prometheus.Labels{"kind": "service", "proxy": ""}).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetCounter().GetValue(); got != 2 {
+		t.Fatalf("expected 2 coalesced service changes, got %v", got)
+	}
+	m.Reset()
+	if err := hn.HoldoffCoalescedCounter.With(prometheus.Labels{"kind": "gatewayhost", "proxy": ""}).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetCounter().GetValue(); got != 0 {
+		t.Fatalf("expected no coalesced gatewayhost changes, got %v", got)
+	}
+	m.Reset()
+	if err := hn.DAGRebuildSummary.With(prometheus.Labels{"kind": "gatewayhost", "proxy": ""}).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetSummary().GetSampleCount(); got != 2 {
+		t.Fatalf("expected 2 rebuilds triggered by gatewayhost changes, got %v", got)
+	}
+}
+
+// kindNotifierFunc adapts a func to a KindNotifier.
+type kindNotifierFunc func(string)
+
+func (f kindNotifierFunc) OnChange(*dag.KubernetesCache)                    { f("") }
+func (f kindNotifierFunc) OnChangeKind(kind string, _ *dag.KubernetesCache) { f(kind) }
+
+func TestResourceEventHandlerChangeKind(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	var got []string
+	reh := &ResourceEventHandler{
+		Notifier:    kindNotifierFunc(func(kind string) { got = append(got, kind) }),
+		Metrics:     metrics.NewMetrics(prometheus.NewRegistry()),
+		FieldLogger: log,
+	}
+
+	svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "kuard", Namespace: "default"}}
+	gh := &gatewayhostv1.GatewayHost{ObjectMeta: metav1.ObjectMeta{Name: "kuard", Namespace: "default"}}
+	reh.OnAdd(svc)
+	reh.OnAdd(gh)
+	reh.OnDelete(cache.DeletedFinalStateUnknown{Key: "default/kuard", Obj: svc})
+	reh.OnAdd(&gatewayhostv1.GlobalConfig{ObjectMeta: metav1.ObjectMeta{Name: "gc", Namespace: "default"}})
+
+	want := []string{"service", "gatewayhost", "service", "globalconfig"}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected: %v, got: %v", want, got)
+	}
+}
+
+func TestResourceEventHandlerRecheck(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	done := make(chan struct{}, 10)
+	reh := &ResourceEventHandler{
+		Notifier: notifierFunc(func(*dag.KubernetesCache) {
+			done <- struct{}{}
+		}),
+		FieldLogger: log,
+	}
+
+	stop := make(chan struct{})
+	exited := make(chan error)
+	go func() { exited <- reh.Recheck(time.Millisecond)(stop) }()
+
+	// rebuilds happen with no change at all
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("expected a rebuild")
+		}
+	}
+
+	close(stop)
+	if err := <-exited; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHoldoffNotifierHold(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	var mu sync.Mutex
+	calls := 0
+	hn := &HoldoffNotifier{
+		Notifier: notifierFunc(func(*dag.KubernetesCache) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+		}),
+		FieldLogger: log,
+	}
+	rebuilds := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+
+	// nothing is built while held, not even the first change.
+	hn.Hold()
+	var kc dag.KubernetesCache
+	for i := 0; i < 3; i++ {
+		hn.OnChange(&kc)
+	}
+	time.Sleep(2 * holdoffMaxDelay)
+	if got := rebuilds(); got != 0 {
+		t.Fatalf("expected no rebuild while held, got %d", got)
+	}
+
+	// the held changes are built at once when released.
+	hn.Release()
+	if got := rebuilds(); got != 1 {
+		t.Fatalf("expected 1 rebuild on release, got %d", got)
+	}
+	hn.Release()
+	if got := rebuilds(); got != 1 {
+		t.Fatalf("expected no rebuild on a second release, got %d", got)
+	}
+}
@@ -20,6 +20,8 @@
 package contour
 
 import (
+	"time"
+
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/prometheus/client_golang/prometheus"
@@ -27,8 +29,10 @@ import (
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
 	"github.com/saarasio/enroute/enroute-dp/internal/metrics"
 	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 const DEFAULT_INGRESS_CLASS = "contour"
@@ -59,6 +63,45 @@ type Notifier interface {
 	OnChange(*dag.KubernetesCache)
 }
 
+// KindNotifier is a Notifier which is also told the kind of change it
+// is notified of, like the HoldoffNotifier, which labels its metrics
+// with it.
+type KindNotifier interface {
+	Notifier
+
+	// OnChangeKind is called in place of OnChange, with the kind
+	// of the change returned by changeKind.
+	OnChangeKind(kind string, kc *dag.KubernetesCache)
+}
+
+// changeKind returns the kind of the change to obj, by which the DAG
+// rebuilds it triggers are labelled.
+func changeKind(obj interface{}) string {
+	if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = d.Obj
+	}
+	switch obj.(type) {
+	case *gatewayhostv1.GatewayHost:
+		return "gatewayhost"
+	case *v1.Service:
+		return "service"
+	case *v1.Secret:
+		return "secret"
+	case *v1beta1.Ingress:
+		return "ingress"
+	case *gatewayhostv1.TLSCertificateDelegation:
+		return "tlscertificatedelegation"
+	case *gatewayhostv1.GlobalConfig:
+		return "globalconfig"
+	case *gatewayhostv1.HttpFilter:
+		return "httpfilter"
+	case *gatewayhostv1.RouteFilter:
+		return "routefilter"
+	default:
+		return "unknown"
+	}
+}
+
 func (reh *ResourceEventHandler) OnAdd(obj interface{}) {
 	timer := prometheus.NewTimer(reh.ResourceEventHandlerSummary.With(prometheus.Labels{"op": "OnAdd"}))
 	defer timer.ObserveDuration()
@@ -67,7 +110,7 @@ func (reh *ResourceEventHandler) OnAdd(obj interface{}) {
 	}
 	reh.WithField("op", "add").Debugf("%T", obj)
 	reh.Insert(obj)
-	reh.update()
+	reh.update(changeKind(obj))
 }
 
 func (reh *ResourceEventHandler) OnUpdate(oldObj, newObj interface{}) {
@@ -92,7 +135,7 @@ func (reh *ResourceEventHandler) OnUpdate(oldObj, newObj interface{}) {
 		reh.WithField("op", "update").Debugf("%T", newObj)
 		reh.Remove(oldObj)
 		reh.Insert(newObj)
-		reh.update()
+		reh.update(changeKind(newObj))
 
 	}
 }
@@ -103,13 +146,38 @@ func (reh *ResourceEventHandler) OnDelete(obj interface{}) {
 	// no need to check ingress class here
 	reh.WithField("op", "delete").Debugf("%T", obj)
 	reh.Remove(obj)
-	reh.update()
+	reh.update(changeKind(obj))
 }
 
-func (reh *ResourceEventHandler) update() {
+// update notifies the Notifier of a change of kind.
+func (reh *ResourceEventHandler) update(kind string) {
+	if kn, ok := reh.Notifier.(KindNotifier); ok {
+		kn.OnChangeKind(kind, &reh.KubernetesCache)
+		return
+	}
 	reh.OnChange(&reh.KubernetesCache)
 }
 
+// Recheck returns a function notifying the Notifier every interval until
+// stop is closed, even though nothing changed, so that what depends on
+// the time, like the warnings of certificates about to expire, is
+// evaluated again.
+func (reh *ResourceEventHandler) Recheck(interval time.Duration) func(stop <-chan struct{}) error {
+	return func(stop <-chan struct{}) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				reh.WithField("op", "recheck").Debug("rebuilding")
+				reh.update("recheck")
+			case <-stop:
+				return nil
+			}
+		}
+	}
+}
+
 // validIngressClass returns true iff:
 //
 // 1. obj is not of type *v1beta1.Ingress or gatewayhostv1.GatewayHost.
@@ -21,11 +21,10 @@ import (
 	"sync"
 
 	//envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
-	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	//envoy_api_v2_accesslog "github.com/envoyproxy/go-control-plane/envoy/config/filter/accesslog/v2"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
 	"github.com/golang/protobuf/proto"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
 	"github.com/saarasio/enroute/enroute-dp/internal/envoy"
@@ -131,59 +130,43 @@ func (lvc *ListenerVisitorConfig) httpsAccessLog() string {
 // ListenerCache manages the contents of the gRPC LDS cache.
 type ListenerCache struct {
 	mu           sync.Mutex
-	values       map[string]*v2.Listener
-	staticValues map[string]*v2.Listener
-	waiters      []chan int
-	last         int
+	values       map[string]*envoy_listener_v3.Listener
+	staticValues map[string]*envoy_listener_v3.Listener
+	Cond
 }
 
 // NewListenerCache returns an instance of a ListenerCache
 func NewListenerCache(address string, port int) ListenerCache {
 	stats := envoy.StatsListener(address, port)
 	return ListenerCache{
-		staticValues: map[string]*v2.Listener{
+		staticValues: map[string]*envoy_listener_v3.Listener{
 			stats.Name: stats,
 		},
 	}
 }
 
-// Register registers ch to receive a value when Notify is called.
-// The value of last is the count of the times Notify has been called on this Cache.
-// It functions of a sequence counter, if the value of last supplied to Register
-// is less than the Cache's internal counter, then the caller has missed at least
-// one notification and will fire immediately.
-//
-// Sends by the broadcaster to ch must not block, therefor ch must have a capacity
-// of at least 1.
-func (c *ListenerCache) Register(ch chan int, last int) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if last < c.last {
-		// notify this channel immediately
-		ch <- c.last
-		return
-	}
-	c.waiters = append(c.waiters, ch)
-}
-
 // Update replaces the contents of the cache with the supplied map.
-func (c *ListenerCache) Update(v map[string]*v2.Listener) {
+// Waiters are notified of the listeners which were added, changed,
+// or removed. If none were, no notification is sent.
+func (c *ListenerCache) Update(v map[string]*envoy_listener_v3.Listener) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
+	var changed []string
+	for name, value := range v {
+		if old, ok := c.values[name]; !ok || !proto.Equal(old, value) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range c.values {
+		if _, ok := v[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
 	c.values = v
-	c.notify()
-}
-
-// notify notifies all registered waiters that an event has occurred.
-func (c *ListenerCache) notify() {
-	c.last++
+	c.mu.Unlock()
 
-	for _, ch := range c.waiters {
-		ch <- c.last
+	if len(changed) > 0 {
+		c.Notify(changed...)
 	}
-	c.waiters = c.waiters[:0]
 }
 
 // Contents returns a copy of the cache's contents.
@@ -229,7 +212,7 @@ type listenersByName []proto.Message
 func (l listenersByName) Len() int      { return len(l) }
 func (l listenersByName) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
 func (l listenersByName) Less(i, j int) bool {
-	return l[i].(*v2.Listener).Name < l[j].(*v2.Listener).Name
+	return l[i].(*envoy_listener_v3.Listener).Name < l[j].(*envoy_listener_v3.Listener).Name
 }
 
 func (*ListenerCache) TypeURL() string { return resource.ListenerType }
@@ -237,7 +220,7 @@ func (*ListenerCache) TypeURL() string { return resource.ListenerType }
 type listenerVisitor struct {
 	*ListenerVisitorConfig
 
-	listeners map[string]*v2.Listener
+	listeners map[string]*envoy_listener_v3.Listener
 	// 6-5-2020 - If we find a dag.VirtualHost, we add the listener
 	// in visit() just like dag.SecureVirtualHost
 	// This simplifies switch/case here and elsewhere
@@ -245,10 +228,10 @@ type listenerVisitor struct {
 }
 
 // Entry-point from builder
-func visitListeners(root dag.Vertex, lvc *ListenerVisitorConfig) map[string]*v2.Listener {
+func visitListeners(root dag.Vertex, lvc *ListenerVisitorConfig) map[string]*envoy_listener_v3.Listener {
 	lv := listenerVisitor{
 		ListenerVisitorConfig: lvc,
-		listeners: map[string]*v2.Listener{
+		listeners: map[string]*envoy_listener_v3.Listener{
 			ENVOY_HTTPS_LISTENER: envoy.Listener(
 				ENVOY_HTTPS_LISTENER,
 				lvc.httpsAddress(), lvc.httpsPort(),
@@ -280,7 +263,7 @@ func visitListeners(root dag.Vertex, lvc *ListenerVisitorConfig) map[string]*v2.
 	return lv.listeners
 }
 
-func proxyProtocol(useProxy bool) []*envoy_api_v2_listener.ListenerFilter {
+func proxyProtocol(useProxy bool) []*envoy_listener_v3.ListenerFilter {
 	if useProxy {
 		return envoy.ListenerFilters(
 			envoy.ProxyProtocol(),
@@ -289,7 +272,7 @@ func proxyProtocol(useProxy bool) []*envoy_api_v2_listener.ListenerFilter {
 	return nil
 }
 
-func secureProxyProtocol(useProxy bool) []*envoy_api_v2_listener.ListenerFilter {
+func secureProxyProtocol(useProxy bool) []*envoy_listener_v3.ListenerFilter {
 	return append(proxyProtocol(useProxy), envoy.TLSInspector())
 }
 
@@ -316,7 +299,8 @@ func (v *listenerVisitor) visit(vertex dag.Vertex) {
 			alpnProtos = nil // do not offer ALPN
 		}
 
-		fc := envoy.FilterChainTLS(vh.VirtualHost.Name, vh.Secret, filters, vh.MinProtoVersion, alpnProtos...)
+		tlsParams := envoy.TLSParams(vh.MinProtoVersion, vh.MaxProtoVersion, vh.CipherSuites, vh.ECDHCurves)
+		fc := envoy.FilterChainTLS(vh.VirtualHost.Name, vh.Secret, filters, tlsParams, alpnProtos...)
 
 		v.listeners[ENVOY_HTTPS_LISTENER].FilterChains = append(v.listeners[ENVOY_HTTPS_LISTENER].FilterChains, fc)
 	default:
@@ -20,9 +20,8 @@ import (
 	"sort"
 	"sync"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
-	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/golang/protobuf/proto"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
 	"github.com/saarasio/enroute/enroute-dp/internal/envoy"
@@ -30,49 +29,33 @@ import (
 
 // RouteCache manages the contents of the gRPC RDS cache.
 type RouteCache struct {
-	mu      sync.Mutex
-	values  map[string]*v2.RouteConfiguration
-	waiters []chan int
-	last    int
-}
-
-// Register registers ch to receive a value when Notify is called.
-// The value of last is the count of the times Notify has been called on this Cache.
-// It functions of a sequence counter, if the value of last supplied to Register
-// is less than the Cache's internal counter, then the caller has missed at least
-// one notification and will fire immediately.
-//
-// Sends by the broadcaster to ch must not block, therefor ch must have a capacity
-// of at least 1.
-func (c *RouteCache) Register(ch chan int, last int) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if last < c.last {
-		// notify this channel immediately
-		ch <- c.last
-		return
-	}
-	c.waiters = append(c.waiters, ch)
+	mu     sync.Mutex
+	values map[string]*envoy_route_v3.RouteConfiguration
+	Cond
 }
 
 // Update replaces the contents of the cache with the supplied map.
-func (c *RouteCache) Update(v map[string]*v2.RouteConfiguration) {
+// Waiters are notified of the route configurations which were added, changed,
+// or removed. If none were, no notification is sent.
+func (c *RouteCache) Update(v map[string]*envoy_route_v3.RouteConfiguration) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
+	var changed []string
+	for name, value := range v {
+		if old, ok := c.values[name]; !ok || !proto.Equal(old, value) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range c.values {
+		if _, ok := v[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
 	c.values = v
-	c.notify()
-}
-
-// notify notifies all registered waiters that an event has occurred.
-func (c *RouteCache) notify() {
-	c.last++
+	c.mu.Unlock()
 
-	for _, ch := range c.waiters {
-		ch <- c.last
+	if len(changed) > 0 {
+		c.Notify(changed...)
 	}
-	c.waiters = c.waiters[:0]
 }
 
 // Contents returns a copy of the cache's contents.
@@ -100,7 +83,7 @@ func (c *RouteCache) Query(names []string) []proto.Message {
 			// not the same as returning nil, we're choosing to
 			// say "the configuration you asked for _does exists_,
 			// but it contains no useful information.
-			v = &v2.RouteConfiguration{
+			v = &envoy_route_v3.RouteConfiguration{
 				Name: n,
 			}
 		}
@@ -115,19 +98,19 @@ type routeConfigurationsByName []proto.Message
 func (r routeConfigurationsByName) Len() int      { return len(r) }
 func (r routeConfigurationsByName) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
 func (r routeConfigurationsByName) Less(i, j int) bool {
-	return r[i].(*v2.RouteConfiguration).Name < r[j].(*v2.RouteConfiguration).Name
+	return r[i].(*envoy_route_v3.RouteConfiguration).Name < r[j].(*envoy_route_v3.RouteConfiguration).Name
 }
 
 // TypeURL returns the string type of RouteCache Resource.
 func (*RouteCache) TypeURL() string { return resource.RouteType }
 
 type routeVisitor struct {
-	routes map[string]*v2.RouteConfiguration
+	routes map[string]*envoy_route_v3.RouteConfiguration
 }
 
-func visitRoutes(root dag.Vertex) map[string]*v2.RouteConfiguration {
+func visitRoutes(root dag.Vertex) map[string]*envoy_route_v3.RouteConfiguration {
 	rv := routeVisitor{
-		routes: map[string]*v2.RouteConfiguration{
+		routes: map[string]*envoy_route_v3.RouteConfiguration{
 			"ingress_http": {
 				Name: "ingress_http",
 			},
@@ -156,7 +139,7 @@ func (v *routeVisitor) visit(vertex dag.Vertex) {
 							// no services for this route, skip it.
 							return
 						}
-						rr := &envoy_api_v2_route.Route{
+						rr := &envoy_route_v3.Route{
 							Match:               envoy.RouteMatchNew(r),
 							Action:              envoy.RouteRoute(r),
 							RequestHeadersToAdd: envoy.RouteHeaders(),
@@ -182,7 +165,7 @@ func (v *routeVisitor) visit(vertex dag.Vertex) {
 							// no services for this route, skip it.
 							return
 						}
-						vhost.Routes = append(vhost.Routes, &envoy_api_v2_route.Route{
+						vhost.Routes = append(vhost.Routes, &envoy_route_v3.Route{
 							Match:               envoy.RouteMatchNew(r),
 							Action:              envoy.RouteRoute(r),
 							RequestHeadersToAdd: envoy.RouteHeaders(),
@@ -205,24 +188,24 @@ func (v *routeVisitor) visit(vertex dag.Vertex) {
 	}
 }
 
-type virtualHostsByName []*envoy_api_v2_route.VirtualHost
+type virtualHostsByName []*envoy_route_v3.VirtualHost
 
 func (v virtualHostsByName) Len() int           { return len(v) }
 func (v virtualHostsByName) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
 func (v virtualHostsByName) Less(i, j int) bool { return v[i].Name < v[j].Name }
 
-type longestRouteFirst []*envoy_api_v2_route.Route
+type longestRouteFirst []*envoy_route_v3.Route
 
 func (l longestRouteFirst) Len() int      { return len(l) }
 func (l longestRouteFirst) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
 func (l longestRouteFirst) Less(i, j int) bool {
-	a, ok := l[i].Match.PathSpecifier.(*envoy_api_v2_route.RouteMatch_Prefix)
+	a, ok := l[i].Match.PathSpecifier.(*envoy_route_v3.RouteMatch_Prefix)
 	if !ok {
 		// ignore non prefix matches
 		return false
 	}
 
-	b, ok := l[j].Match.PathSpecifier.(*envoy_api_v2_route.RouteMatch_Prefix)
+	b, ok := l[j].Match.PathSpecifier.(*envoy_route_v3.RouteMatch_Prefix)
 	if !ok {
 		// ignore non prefix matches
 		return false
@@ -20,8 +20,8 @@ import (
 	"sort"
 	"sync"
 
-	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
-	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/golang/protobuf/proto"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
 	"github.com/saarasio/enroute/enroute-dp/internal/envoy"
@@ -29,49 +29,33 @@ import (
 
 // SecretCache manages the contents of the gRPC SDS cache.
 type SecretCache struct {
-	mu      sync.Mutex
-	values  map[string]*envoy_api_v2_auth.Secret
-	waiters []chan int
-	last    int
-}
-
-// Register registers ch to receive a value when Notify is called.
-// The value of last is the count of the times Notify has been called on this Cache.
-// It functions of a sequence counter, if the value of last supplied to Register
-// is less than the Cache's internal counter, then the caller has missed at least
-// one notification and will fire immediately.
-//
-// Sends by the broadcaster to ch must not block, therefor ch must have a capacity
-// of at least 1.
-func (c *SecretCache) Register(ch chan int, last int) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if last < c.last {
-		// notify this channel immediately
-		ch <- c.last
-		return
-	}
-	c.waiters = append(c.waiters, ch)
+	mu     sync.Mutex
+	values map[string]*envoy_tls_v3.Secret
+	Cond
 }
 
 // Update replaces the contents of the cache with the supplied map.
-func (c *SecretCache) Update(v map[string]*envoy_api_v2_auth.Secret) {
+// Waiters are notified of the secrets which were added, changed,
+// or removed. If none were, no notification is sent.
+func (c *SecretCache) Update(v map[string]*envoy_tls_v3.Secret) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
+	var changed []string
+	for name, value := range v {
+		if old, ok := c.values[name]; !ok || !proto.Equal(old, value) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range c.values {
+		if _, ok := v[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
 	c.values = v
-	c.notify()
-}
-
-// notify notifies all registered waiters that an event has occurred.
-func (c *SecretCache) notify() {
-	c.last++
+	c.mu.Unlock()
 
-	for _, ch := range c.waiters {
-		ch <- c.last
+	if len(changed) > 0 {
+		c.Notify(changed...)
 	}
-	c.waiters = c.waiters[:0]
 }
 
 // Contents returns a copy of the cache's contents.
@@ -107,19 +91,19 @@ type secretsByName []proto.Message
 func (s secretsByName) Len() int      { return len(s) }
 func (s secretsByName) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 func (s secretsByName) Less(i, j int) bool {
-	return s[i].(*envoy_api_v2_auth.Secret).Name < s[j].(*envoy_api_v2_auth.Secret).Name
+	return s[i].(*envoy_tls_v3.Secret).Name < s[j].(*envoy_tls_v3.Secret).Name
 }
 
 func (*SecretCache) TypeURL() string { return resource.SecretType }
 
 type secretVisitor struct {
-	secrets map[string]*envoy_api_v2_auth.Secret
+	secrets map[string]*envoy_tls_v3.Secret
 }
 
-// visitSecrets produces a map of *envoy_api_v2_auth.Secret
-func visitSecrets(root dag.Vertex) map[string]*envoy_api_v2_auth.Secret {
+// visitSecrets produces a map of *envoy_tls_v3.Secret
+func visitSecrets(root dag.Vertex) map[string]*envoy_tls_v3.Secret {
 	sv := secretVisitor{
-		secrets: make(map[string]*envoy_api_v2_auth.Secret),
+		secrets: make(map[string]*envoy_tls_v3.Secret),
 	}
 	sv.visit(root)
 	return sv.secrets
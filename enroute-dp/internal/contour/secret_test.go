@@ -7,14 +7,15 @@ import (
 	"reflect"
 	"testing"
 
-	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
-	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	"github.com/golang/protobuf/proto"
 	"github.com/google/go-cmp/cmp"
 	"github.com/prometheus/client_golang/prometheus"
 	gatewayhostv1 "github.com/saarasio/enroute/enroute-dp/apis/enroute/v1beta1"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
 	"github.com/saarasio/enroute/enroute-dp/internal/metrics"
+	"google.golang.org/protobuf/testing/protocmp"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -23,7 +24,7 @@ import (
 
 func TestSecretCacheContents(t *testing.T) {
 	tests := map[string]struct {
-		contents map[string]*envoy_api_v2_auth.Secret
+		contents map[string]*envoy_tls_v3.Secret
 		want     []proto.Message
 	}{
 		"empty": {
@@ -45,7 +46,7 @@ func TestSecretCacheContents(t *testing.T) {
 			var sc SecretCache
 			sc.Update(tc.contents)
 			got := sc.Contents()
-			if diff := cmp.Diff(tc.want, got); diff != "" {
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
 				t.Fatal(diff)
 			}
 		})
@@ -54,7 +55,7 @@ func TestSecretCacheContents(t *testing.T) {
 
 func TestSecretCacheQuery(t *testing.T) {
 	tests := map[string]struct {
-		contents map[string]*envoy_api_v2_auth.Secret
+		contents map[string]*envoy_tls_v3.Secret
 		query    []string
 		want     []proto.Message
 	}{
@@ -91,7 +92,7 @@ func TestSecretCacheQuery(t *testing.T) {
 			var sc SecretCache
 			sc.Update(tc.contents)
 			got := sc.Query(tc.query)
-			if diff := cmp.Diff(tc.want, got); diff != "" {
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
 				t.Fatal(diff)
 			}
 		})
@@ -101,18 +102,18 @@ func TestSecretCacheQuery(t *testing.T) {
 func TestSecretVisit(t *testing.T) {
 	tests := map[string]struct {
 		objs []interface{}
-		want map[string]*envoy_api_v2_auth.Secret
+		want map[string]*envoy_tls_v3.Secret
 	}{
 		"nothing": {
 			objs: nil,
-			want: map[string]*envoy_api_v2_auth.Secret{},
+			want: map[string]*envoy_tls_v3.Secret{},
 		},
 		"unassociated secrets": {
 			objs: []interface{}{
 				tlssecret("default", "secret-a", secretdata("cert", "key")),
 				tlssecret("default", "secret-b", secretdata("cert", "key")),
 			},
-			want: map[string]*envoy_api_v2_auth.Secret{},
+			want: map[string]*envoy_tls_v3.Secret{},
 		},
 		"simple ingress with secret": {
 			objs: []interface{}{
@@ -385,26 +386,26 @@ func TestSecretVisit(t *testing.T) {
 	}
 }
 
-func secretmap(secrets ...*envoy_api_v2_auth.Secret) map[string]*envoy_api_v2_auth.Secret {
-	m := make(map[string]*envoy_api_v2_auth.Secret)
+func secretmap(secrets ...*envoy_tls_v3.Secret) map[string]*envoy_tls_v3.Secret {
+	m := make(map[string]*envoy_tls_v3.Secret)
 	for _, s := range secrets {
 		m[s.Name] = s
 	}
 	return m
 }
 
-func secret(name, cert, key string) *envoy_api_v2_auth.Secret {
-	return &envoy_api_v2_auth.Secret{
+func secret(name, cert, key string) *envoy_tls_v3.Secret {
+	return &envoy_tls_v3.Secret{
 		Name: name,
-		Type: &envoy_api_v2_auth.Secret_TlsCertificate{
-			TlsCertificate: &envoy_api_v2_auth.TlsCertificate{
-				PrivateKey: &envoy_api_v2_core.DataSource{
-					Specifier: &envoy_api_v2_core.DataSource_InlineBytes{
+		Type: &envoy_tls_v3.Secret_TlsCertificate{
+			TlsCertificate: &envoy_tls_v3.TlsCertificate{
+				PrivateKey: &envoy_core_v3.DataSource{
+					Specifier: &envoy_core_v3.DataSource_InlineBytes{
 						InlineBytes: []byte(key),
 					},
 				},
-				CertificateChain: &envoy_api_v2_core.DataSource{
-					Specifier: &envoy_api_v2_core.DataSource_InlineBytes{
+				CertificateChain: &envoy_core_v3.DataSource{
+					Specifier: &envoy_core_v3.DataSource_InlineBytes{
 						InlineBytes: []byte(cert),
 					},
 				},
@@ -20,14 +20,15 @@ import (
 	"testing"
 	"time"
 
-	envoy_api_v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
-	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
-	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	"github.com/google/go-cmp/cmp"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
 	"github.com/saarasio/enroute/enroute-dp/internal/envoy"
 	"github.com/saarasio/enroute/enroute-dp/internal/protobuf"
+	"google.golang.org/protobuf/testing/protocmp"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -36,7 +37,7 @@ import (
 func TestVisitClusters(t *testing.T) {
 	tests := map[string]struct {
 		root dag.Visitable
-		want map[string]*envoy_api_v2.Cluster
+		want map[string]*envoy_cluster_v3.Cluster
 	}{
 		"TCPService forward": {
 			root: &dag.Listener{
@@ -64,16 +65,16 @@ func TestVisitClusters(t *testing.T) {
 				),
 			},
 			want: clustermap(
-				&envoy_api_v2.Cluster{
+				&envoy_cluster_v3.Cluster{
 					Name:                 "default/example/443/da39a3ee5e",
 					AltStatName:          "default_example_443",
-					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_api_v2.Cluster_EDS),
-					EdsClusterConfig: &envoy_api_v2.Cluster_EdsClusterConfig{
+					ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+					EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 						EdsConfig:   envoy.ConfigSource("enroute"),
 						ServiceName: "default/example",
 					},
 					ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-					LbPolicy:       envoy_api_v2.Cluster_ROUND_ROBIN,
+					LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 					CommonLbConfig: envoy.ClusterCommonLBConfig(),
 				},
 			),
@@ -83,7 +84,7 @@ func TestVisitClusters(t *testing.T) {
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			got := visitClusters(tc.root)
-			if diff := cmp.Diff(tc.want, got); diff != "" {
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
 				t.Fatal(diff)
 			}
 		})
@@ -107,7 +108,7 @@ func TestVisitListeners(t *testing.T) {
 
 	tests := map[string]struct {
 		root dag.Visitable
-		want map[string]*envoy_api_v2.Listener
+		want map[string]*envoy_listener_v3.Listener
 	}{
 		"TCPService forward": {
 			root: &dag.Listener{
@@ -127,19 +128,19 @@ func TestVisitListeners(t *testing.T) {
 								Data: secretdata("certificate", "key"),
 							},
 						},
-						MinProtoVersion: envoy_api_v2_auth.TlsParameters_TLSv1_1,
+						MinProtoVersion: envoy_tls_v3.TlsParameters_TLSv1_1,
 					},
 				),
 			},
 			want: listenermap(
-				&envoy_api_v2.Listener{
+				&envoy_listener_v3.Listener{
 					Name:    ENVOY_HTTPS_LISTENER,
 					Address: envoy.SocketAddress("0.0.0.0", 8443),
-					FilterChains: []*envoy_api_v2_listener.FilterChain{{
-						FilterChainMatch: &envoy_api_v2_listener.FilterChainMatch{
+					FilterChains: []*envoy_listener_v3.FilterChain{{
+						FilterChainMatch: &envoy_listener_v3.FilterChainMatch{
 							ServerNames: []string{"tcpproxy.example.com"},
 						},
-						TransportSocket: transportSocket(envoy_api_v2_auth.TlsParameters_TLSv1_1),
+						TransportSocket: transportSocket(envoy_tls_v3.TlsParameters_TLSv1_1),
 						Filters:         envoy.Filters(envoy.TCPProxy(ENVOY_HTTPS_LISTENER, p1, DEFAULT_HTTPS_ACCESS_LOG)),
 					}},
 					ListenerFilters: envoy.ListenerFilters(
@@ -153,7 +154,7 @@ func TestVisitListeners(t *testing.T) {
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			got := visitListeners(tc.root, new(ListenerVisitorConfig))
-			if diff := cmp.Diff(tc.want, got); diff != "" {
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
 				t.Fatal(diff)
 			}
 		})
@@ -163,7 +164,7 @@ func TestVisitListeners(t *testing.T) {
 func TestVisitSecrets(t *testing.T) {
 	tests := map[string]struct {
 		root dag.Visitable
-		want map[string]*envoy_api_v2_auth.Secret
+		want map[string]*envoy_tls_v3.Secret
 	}{
 		"TCPService forward": {
 			root: &dag.Listener{
@@ -198,17 +199,17 @@ func TestVisitSecrets(t *testing.T) {
 					},
 				),
 			},
-			want: secretmap(&envoy_api_v2_auth.Secret{
+			want: secretmap(&envoy_tls_v3.Secret{
 				Name: "default/secret/735ad571c1",
-				Type: &envoy_api_v2_auth.Secret_TlsCertificate{
-					TlsCertificate: &envoy_api_v2_auth.TlsCertificate{
-						PrivateKey: &envoy_api_v2_core.DataSource{
-							Specifier: &envoy_api_v2_core.DataSource_InlineBytes{
+				Type: &envoy_tls_v3.Secret_TlsCertificate{
+					TlsCertificate: &envoy_tls_v3.TlsCertificate{
+						PrivateKey: &envoy_core_v3.DataSource{
+							Specifier: &envoy_core_v3.DataSource_InlineBytes{
 								InlineBytes: []byte("key"),
 							},
 						},
-						CertificateChain: &envoy_api_v2_core.DataSource{
-							Specifier: &envoy_api_v2_core.DataSource_InlineBytes{
+						CertificateChain: &envoy_core_v3.DataSource{
+							Specifier: &envoy_core_v3.DataSource_InlineBytes{
 								InlineBytes: []byte("certificate"),
 							},
 						},
@@ -221,7 +222,7 @@ func TestVisitSecrets(t *testing.T) {
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			got := visitSecrets(tc.root)
-			if diff := cmp.Diff(tc.want, got); diff != "" {
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
 				t.Fatal(diff)
 			}
 		})
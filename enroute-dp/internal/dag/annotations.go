@@ -22,7 +22,7 @@ import (
 	"strings"
 	"time"
 
-	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -133,15 +133,15 @@ func websocketRoutes(i *v1beta1.Ingress) map[string]bool {
 
 // MinProtoVersion returns the TLS protocol version specified by an ingress annotation
 // or default if non present.
-func MinProtoVersion(version string) envoy_api_v2_auth.TlsParameters_TlsProtocol {
+func MinProtoVersion(version string) envoy_tls_v3.TlsParameters_TlsProtocol {
 	switch version {
 	case "1.3":
-		return envoy_api_v2_auth.TlsParameters_TLSv1_3
+		return envoy_tls_v3.TlsParameters_TLSv1_3
 	case "1.2":
-		return envoy_api_v2_auth.TlsParameters_TLSv1_2
+		return envoy_tls_v3.TlsParameters_TLSv1_2
 	default:
 		// any other value is interpreted as TLS/1.1
-		return envoy_api_v2_auth.TlsParameters_TLSv1_1
+		return envoy_tls_v3.TlsParameters_TLSv1_1
 	}
 }
 
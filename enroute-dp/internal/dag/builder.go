@@ -23,12 +23,13 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
-	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	gatewayhostv1 "github.com/saarasio/enroute/enroute-dp/apis/enroute/v1beta1"
 	cfg "github.com/saarasio/enroute/enroute-dp/saarasconfig"
 	"github.com/sirupsen/logrus"
@@ -59,6 +60,7 @@ func (b *builder) reset() {
 	b.httpfilters = make(map[HttpFilterMeta]*cfg.SaarasRouteFilter, len(b.httpfilters))
 
 	b.statuses = make(map[Meta]Status, len(b.statuses))
+	b.warnings = make(map[Meta][]string, len(b.warnings))
 }
 
 // A builder holds the state of one invocation of Builder.Build.
@@ -73,9 +75,13 @@ type builder struct {
 	routefilters map[RouteFilterMeta]*cfg.SaarasRouteFilter
 	httpfilters  map[HttpFilterMeta]*cfg.SaarasRouteFilter
 
+	// TLS parameters applied to secure virtual hosts that do not set their own.
+	tlsdefaults cfg.TLSConfig
+
 	orphaned map[Meta]bool
 
 	statuses map[Meta]Status
+	warnings map[Meta][]string
 	log      logrus.FieldLogger
 }
 
@@ -277,6 +283,8 @@ func (b *builder) compute() *DAG {
 	b.source.mu.RLock() // blocks mutation of the underlying cache until compute is done.
 	defer b.source.mu.RUnlock()
 
+	b.tlsdefaults = b.tlsDefaults()
+
 	// setup secure vhosts if there is a matching secret
 	// we do this first so that the set of active secure vhosts is stable
 	// during computeIngresses.
@@ -319,15 +327,15 @@ func isBlank(s string) bool {
 
 // minProtoVersion returns the TLS protocol version specified by an ingress annotation
 // or default if non present.
-func minProtoVersion(version string) envoy_api_v2_auth.TlsParameters_TlsProtocol {
+func minProtoVersion(version string) envoy_tls_v3.TlsParameters_TlsProtocol {
 	switch version {
 	case "1.3":
-		return envoy_api_v2_auth.TlsParameters_TLSv1_3
+		return envoy_tls_v3.TlsParameters_TLSv1_3
 	case "1.2":
-		return envoy_api_v2_auth.TlsParameters_TLSv1_2
+		return envoy_tls_v3.TlsParameters_TLSv1_2
 	default:
 		// any other value is interpreted as TLS/1.1
-		return envoy_api_v2_auth.TlsParameters_TLSv1_1
+		return envoy_tls_v3.TlsParameters_TLSv1_1
 	}
 }
 
@@ -373,11 +381,17 @@ func (b *builder) computeSecureVirtualhosts() {
 		for _, tls := range ing.Spec.TLS {
 			m := splitSecret(tls.SecretName, ing.Namespace)
 			if sec := b.lookupSecret(m, validSecret); sec != nil && b.delegationPermitted(m, ing.Namespace) {
+				version := compatAnnotation(ing, "tls-minimum-protocol-version")
+				tc, err := b.mergeTLSConfig(cfg.TLSConfig{MinimumProtocolVersion: version})
+				if err != nil {
+					// ingresses have no status to report this on
+					b.log.Errorf("ingress %s/%s: not serving TLS: %v", ing.Namespace, ing.Name, err)
+					continue
+				}
 				for _, host := range tls.Hosts {
 					svhost := b.lookupSecureVirtualHost(host)
 					svhost.Secret = sec
-					version := compatAnnotation(ing, "tls-minimum-protocol-version")
-					svhost.MinProtoVersion = minProtoVersion(version)
+					setTLSParameters(svhost, tc)
 				}
 			}
 		}
@@ -518,9 +532,15 @@ func (b *builder) computeGatewayHosts() {
 			sec := b.lookupSecret(m, validSecret)
 			secretInvalidOrNotFound := sec == nil
 			if sec != nil && b.delegationPermitted(m, ir.Namespace) {
+				tc, err := b.tlsConfig(tls)
+				if err != nil {
+					b.setStatus(Status{Object: ir, Status: StatusInvalid, Description: fmt.Sprintf("Spec.VirtualHost.TLS: %v", err), Vhost: host})
+					continue
+				}
 				svhost := b.lookupSecureVirtualHost(host)
 				svhost.Secret = sec
-				svhost.MinProtoVersion = minProtoVersion(ir.Spec.VirtualHost.TLS.MinimumProtocolVersion)
+				setTLSParameters(svhost, tc)
+				b.checkCertExpiry(ir, sec)
 				enforceTLS = true
 				b.SetupHttpFilters(&svhost.VirtualHost, ir.Spec.VirtualHost, ir.Namespace)
 			}
@@ -606,6 +626,12 @@ func (b *builder) DAG() *DAG {
 			b.setStatus(Status{Object: ir, Status: StatusOrphaned, Description: "this GatewayHost is not part of a delegation chain from a root GatewayHost"})
 		}
 	}
+	for m, warnings := range b.warnings {
+		if st, ok := b.statuses[m]; ok {
+			st.Warnings = warnings
+			b.statuses[m] = st
+		}
+	}
 	dag.statuses = b.statuses
 	return &dag
 }
@@ -621,6 +647,32 @@ func (b *builder) setStatus(st Status) {
 	}
 }
 
+// addWarning records a warning to be reported in the status of a GatewayHost.
+func (b *builder) addWarning(ir *gatewayhostv1.GatewayHost, warning string) {
+	m := Meta{name: ir.Name, namespace: ir.Namespace}
+	if b.warnings == nil {
+		b.warnings = make(map[Meta][]string)
+	}
+	b.warnings[m] = append(b.warnings[m], warning)
+}
+
+// checkCertExpiry warns if the certificate in sec expires within
+// the CertExpiryWarningWindow of the KubernetesCache.
+func (b *builder) checkCertExpiry(ir *gatewayhostv1.GatewayHost, sec *Secret) {
+	window := b.source.CertExpiryWarningWindow
+	if window <= 0 {
+		return
+	}
+	notAfter, err := sec.NotAfter()
+	if err != nil {
+		return
+	}
+	if time.Now().Add(window).After(notAfter) {
+		b.addWarning(ir, fmt.Sprintf("TLS certificate in secret %s/%s expires at %s",
+			sec.Namespace(), sec.Name(), notAfter.UTC().Format(time.RFC3339)))
+	}
+}
+
 // setOrphaned records an gatewayhost as orphaned.
 func (b *builder) setOrphaned(ir *gatewayhostv1.GatewayHost) {
 	if b.orphaned == nil {
@@ -907,4 +959,5 @@ type Status struct {
 	Status      string
 	Description string
 	Vhost       string
+	Warnings    []string
 }
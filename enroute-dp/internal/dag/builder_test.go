@@ -21,9 +21,10 @@ import (
 	"testing"
 	"time"
 
-	"github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	"github.com/google/go-cmp/cmp"
 	gatewayhostv1 "github.com/saarasio/enroute/enroute-dp/apis/enroute/v1beta1"
+	cfg "github.com/saarasio/enroute/enroute-dp/saarasconfig"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -1010,6 +1011,73 @@ func TestDAGInsert(t *testing.T) {
 		},
 	}
 
+	// ir9a has a TLS maximum version, cipher suites and ECDH curves
+	ir9a := &gatewayhostv1.GatewayHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "default",
+		},
+		Spec: gatewayhostv1.GatewayHostSpec{
+			VirtualHost: &gatewayhostv1.VirtualHost{
+				Fqdn: "foo.com",
+				TLS: &gatewayhostv1.TLS{
+					SecretName:             "secret",
+					MinimumProtocolVersion: "1.2",
+					MaximumProtocolVersion: "1.2",
+					CipherSuites:           []string{"ECDHE-RSA-AES256-GCM-SHA384"},
+					ECDHCurves:             []string{"X25519"},
+				},
+			},
+			Routes: []gatewayhostv1.Route{{
+				Conditions: []gatewayhostv1.Condition{{
+					Prefix: "/",
+				}},
+				Services: []gatewayhostv1.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	// ir9b has TLS but sets no TLS parameters
+	ir9b := &gatewayhostv1.GatewayHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "default",
+		},
+		Spec: gatewayhostv1.GatewayHostSpec{
+			VirtualHost: &gatewayhostv1.VirtualHost{
+				Fqdn: "foo.com",
+				TLS: &gatewayhostv1.TLS{
+					SecretName: "secret",
+				},
+			},
+			Routes: []gatewayhostv1.Route{{
+				Conditions: []gatewayhostv1.Condition{{
+					Prefix: "/",
+				}},
+				Services: []gatewayhostv1.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	// gctls sets the default TLS parameters of all secure virtual hosts
+	gctls := &gatewayhostv1.GlobalConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tls",
+			Namespace: "default",
+		},
+		Spec: gatewayhostv1.GlobalConfigSpec{
+			Name:   "tls",
+			Type:   cfg.PROXY_CONFIG_TLS,
+			Config: `{ "minimum_protocol_version": "1.2", "cipher_suites": [ "ECDHE-ECDSA-AES256-GCM-SHA384" ], "ecdh_curves": [ "P-256" ] }`,
+		},
+	}
+
 	// ir10 has a websocket route
 	ir10 := &gatewayhostv1.GatewayHost{
 		ObjectMeta: metav1.ObjectMeta{
@@ -2194,7 +2262,7 @@ func TestDAGInsert(t *testing.T) {
 								},
 							},
 							Secret:          secret(sec1),
-							MinProtoVersion: envoy_api_v2_auth.TlsParameters_TLSv1_1,
+							MinProtoVersion: envoy_tls_v3.TlsParameters_TLSv1_1,
 						},
 					),
 				},
@@ -2330,7 +2398,7 @@ func TestDAGInsert(t *testing.T) {
 									routeUpgrade("/", httpService(s1)),
 								),
 							},
-							MinProtoVersion: envoy_api_v2_auth.TlsParameters_TLSv1_2,
+							MinProtoVersion: envoy_tls_v3.TlsParameters_TLSv1_2,
 							Secret:          secret(sec1),
 						},
 					),
@@ -2357,7 +2425,7 @@ func TestDAGInsert(t *testing.T) {
 									routeUpgrade("/", httpService(s1)),
 								),
 							},
-							MinProtoVersion: envoy_api_v2_auth.TlsParameters_TLSv1_3,
+							MinProtoVersion: envoy_tls_v3.TlsParameters_TLSv1_3,
 							Secret:          secret(sec1),
 						},
 					),
@@ -2382,6 +2450,89 @@ func TestDAGInsert(t *testing.T) {
 				},
 			),
 		},
+		"insert gatewayhost with tls parameters": {
+			objs: []interface{}{
+				ir9a, s1, sec1,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("foo.com", routeUpgrade("/", httpService(s1))),
+					),
+				}, &Listener{
+					Port: 443,
+					VirtualHosts: virtualhosts(
+						&SecureVirtualHost{
+							VirtualHost: VirtualHost{
+								Name:   "foo.com",
+								routes: routemap(routeUpgrade("/", httpService(s1))),
+							},
+							MinProtoVersion: envoy_tls_v3.TlsParameters_TLSv1_2,
+							MaxProtoVersion: envoy_tls_v3.TlsParameters_TLSv1_2,
+							CipherSuites:    []string{"ECDHE-RSA-AES256-GCM-SHA384"},
+							ECDHCurves:      []string{"X25519"},
+							Secret:          secret(sec1),
+						},
+					),
+				},
+			),
+		},
+		"insert gatewayhost with tls global config defaults": {
+			objs: []interface{}{
+				ir9b, s1, sec1, gctls,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("foo.com", routeUpgrade("/", httpService(s1))),
+					),
+				}, &Listener{
+					Port: 443,
+					VirtualHosts: virtualhosts(
+						&SecureVirtualHost{
+							VirtualHost: VirtualHost{
+								Name:   "foo.com",
+								routes: routemap(routeUpgrade("/", httpService(s1))),
+							},
+							MinProtoVersion: envoy_tls_v3.TlsParameters_TLSv1_2,
+							CipherSuites:    []string{"ECDHE-ECDSA-AES256-GCM-SHA384"},
+							ECDHCurves:      []string{"P-256"},
+							Secret:          secret(sec1),
+						},
+					),
+				},
+			),
+		},
+		"insert gatewayhost with tls parameters overriding global config": {
+			objs: []interface{}{
+				ir9a, s1, sec1, gctls,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("foo.com", routeUpgrade("/", httpService(s1))),
+					),
+				}, &Listener{
+					Port: 443,
+					VirtualHosts: virtualhosts(
+						&SecureVirtualHost{
+							VirtualHost: VirtualHost{
+								Name:   "foo.com",
+								routes: routemap(routeUpgrade("/", httpService(s1))),
+							},
+							MinProtoVersion: envoy_tls_v3.TlsParameters_TLSv1_2,
+							MaxProtoVersion: envoy_tls_v3.TlsParameters_TLSv1_2,
+							CipherSuites:    []string{"ECDHE-RSA-AES256-GCM-SHA384"},
+							ECDHCurves:      []string{"X25519"},
+							Secret:          secret(sec1),
+						},
+					),
+				},
+			),
+		},
 		"insert gatewayhost referencing two backends, one missing": {
 			objs: []interface{}{
 				ir2, s2,
@@ -2423,7 +2574,7 @@ func TestDAGInsert(t *testing.T) {
 									prefixroute("/", httpService(s1)),
 								),
 							},
-							MinProtoVersion: envoy_api_v2_auth.TlsParameters_TLSv1_3,
+							MinProtoVersion: envoy_tls_v3.TlsParameters_TLSv1_3,
 							Secret:          secret(sec1),
 						},
 					),
@@ -3222,7 +3373,7 @@ func TestDAGInsert(t *testing.T) {
 		//							VirtualHost: VirtualHost{
 		//								Name: "example.com",
 		//							},
-		//							MinProtoVersion: envoy_api_v2_auth.TlsParameters_TLSv1_1,
+		//							MinProtoVersion: envoy_tls_v3.TlsParameters_TLSv1_1,
 		//							Secret:          secret(sec1),
 		//							TCPProxy: &TCPProxy{
 		//								Clusters: clusters(service(s9)),
@@ -3908,6 +4059,79 @@ func TestDAGGatewayHostStatus(t *testing.T) {
 		},
 	}
 
+	// ir17 offers a cipher suite envoy does not support
+	ir17 := &gatewayhostv1.GatewayHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "roots",
+			Name:      "example",
+		},
+		Spec: gatewayhostv1.GatewayHostSpec{
+			VirtualHost: &gatewayhostv1.VirtualHost{
+				Fqdn: "example.com",
+				TLS: &gatewayhostv1.TLS{
+					SecretName:   "secret",
+					CipherSuites: []string{"DES-CBC3-SHA"},
+				},
+			},
+			Routes: []gatewayhostv1.Route{{
+				Conditions: []gatewayhostv1.Condition{{
+					Prefix: "/foo",
+				}},
+				Services: []gatewayhostv1.Service{{
+					Name: "home",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	// ir18 requires TLS 1.3, above the maximum of gctlsmax
+	ir18 := &gatewayhostv1.GatewayHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "roots",
+			Name:      "example",
+		},
+		Spec: gatewayhostv1.GatewayHostSpec{
+			VirtualHost: &gatewayhostv1.VirtualHost{
+				Fqdn: "example.com",
+				TLS: &gatewayhostv1.TLS{
+					SecretName:             "secret",
+					MinimumProtocolVersion: "1.3",
+				},
+			},
+			Routes: []gatewayhostv1.Route{{
+				Conditions: []gatewayhostv1.Condition{{
+					Prefix: "/foo",
+				}},
+				Services: []gatewayhostv1.Service{{
+					Name: "home",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	gctlsmax := &gatewayhostv1.GlobalConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tls",
+			Namespace: "roots",
+		},
+		Spec: gatewayhostv1.GlobalConfigSpec{
+			Name:   "tls",
+			Type:   cfg.PROXY_CONFIG_TLS,
+			Config: `{ "maximum_protocol_version": "1.2" }`,
+		},
+	}
+
+	sec1 := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "roots",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+
 	s4 := &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "home",
@@ -4037,6 +4261,14 @@ func TestDAGGatewayHostStatus(t *testing.T) {
 		//	objs: []interface{}{ir15},
 		//	want: []Status{{Object: ir15, Status: "invalid", Description: `Spec.VirtualHost.Fqdn "example.*.com" cannot use wildcards`, Vhost: "example.*.com"}},
 		//},
+		"unsupported tls cipher suite shows invalid status": {
+			objs: []interface{}{ir17, s4, sec1},
+			want: []Status{{Object: ir17, Status: "invalid", Description: `Spec.VirtualHost.TLS: unsupported cipher suite "DES-CBC3-SHA"`, Vhost: "example.com"}},
+		},
+		"tls minimum above the global maximum shows invalid status": {
+			objs: []interface{}{ir18, s4, sec1, gctlsmax},
+			want: []Status{{Object: ir18, Status: "invalid", Description: `Spec.VirtualHost.TLS: minimum TLS protocol version "1.3" is greater than maximum "1.2" of the global TLS config`, Vhost: "example.com"}},
+		},
 		"missing service shows invalid status": {
 			objs: []interface{}{ir16},
 			want: []Status{{Object: ir16, Status: "invalid", Description: `Service [invalid:8080] is invalid or missing`, Vhost: ""}},
@@ -4074,6 +4306,93 @@ func TestDAGGatewayHostStatus(t *testing.T) {
 	}
 }
 
+func TestDAGGatewayHostCertExpiryWarning(t *testing.T) {
+	ir1 := &gatewayhostv1.GatewayHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "roots",
+			Name:      "example",
+		},
+		Spec: gatewayhostv1.GatewayHostSpec{
+			VirtualHost: &gatewayhostv1.VirtualHost{
+				Fqdn: "example.com",
+				TLS: &gatewayhostv1.TLS{
+					SecretName: "secret",
+				},
+			},
+			Routes: []gatewayhostv1.Route{{
+				Conditions: []gatewayhostv1.Condition{{
+					Prefix: "/",
+				}},
+				Services: []gatewayhostv1.Service{{
+					Name: "home",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	sec1 := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "roots",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "home",
+			Namespace: "roots",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Name:     "http",
+				Protocol: "TCP",
+				Port:     8080,
+			}},
+		},
+	}
+
+	tests := map[string]struct {
+		window time.Duration
+		want   []string
+	}{
+		"warning disabled": {
+			window: 0,
+		},
+		"certificate expires after window": {
+			window: time.Hour,
+		},
+		"certificate expires within window": {
+			window: 100 * 365 * 24 * time.Hour,
+			want:   []string{"TLS certificate in secret roots/secret expires at 2029-12-02T01:34:33Z"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			kc := &KubernetesCache{
+				GatewayHostRootNamespaces: []string{"roots"},
+				CertExpiryWarningWindow:   tc.window,
+			}
+			for _, o := range []interface{}{ir1, sec1, s1} {
+				kc.Insert(o)
+			}
+
+			dag := BuildDAG(kc)
+			want := []Status{{Object: ir1, Status: "valid", Description: "valid GatewayHost", Vhost: "example.com", Warnings: tc.want}}
+			var got []Status
+			for _, st := range dag.Statuses() {
+				got = append(got, st)
+			}
+			if !cmp.Equal(want, got) {
+				t.Fatalf("expected:\n%v\ngot\n%v", want, got)
+			}
+		})
+	}
+}
+
 func TestDAGGatewayHostUniqueFQDNs(t *testing.T) {
 	ir1 := &gatewayhostv1.GatewayHost{
 		ObjectMeta: metav1.ObjectMeta{
@@ -4488,7 +4807,7 @@ func securevirtualhost(name string, sec *v1.Secret, routes ...*Route) *SecureVir
 			Name:   name,
 			routes: routemap(routes...),
 		},
-		MinProtoVersion: envoy_api_v2_auth.TlsParameters_TLSv1_1,
+		MinProtoVersion: envoy_tls_v3.TlsParameters_TLSv1_1,
 		Secret:          secret(sec),
 	}
 }
@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package dag
+
+import (
+	"fmt"
+	"sort"
+
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	gatewayhostv1 "github.com/saarasio/enroute/enroute-dp/apis/enroute/v1beta1"
+	cfg "github.com/saarasio/enroute/enroute-dp/saarasconfig"
+)
+
+// tlsDefaults returns the TLS parameters of the PROXY_CONFIG_TLS
+// global config. If more than one is present, the first by
+// namespace/name wins. Invalid global configs are logged and ignored.
+func (b *builder) tlsDefaults() cfg.TLSConfig {
+	var configs []*gatewayhostv1.GlobalConfig
+	for m, gc := range b.source.globalconfigs {
+		if m.config_type == cfg.PROXY_CONFIG_TLS {
+			configs = append(configs, gc)
+		}
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Namespace+"/"+configs[i].Name < configs[j].Namespace+"/"+configs[j].Name
+	})
+
+	for _, gc := range configs {
+		tc, err := cfg.UnmarshalTLSConfig(gc.Spec.Config)
+		if err != nil {
+			b.log.Errorf("ignoring global config %s/%s: %v", gc.Namespace, gc.Name, err)
+			continue
+		}
+		return tc
+	}
+	return cfg.TLSConfig{}
+}
+
+// tlsConfig returns the TLS parameters of a GatewayHost, with
+// any parameter it leaves unset taken from the global TLS defaults.
+func (b *builder) tlsConfig(tls *gatewayhostv1.TLS) (cfg.TLSConfig, error) {
+	min := tls.MinimumProtocolVersion
+	// an unrecognised minimum version falls back to TLS 1.1
+	// rather than invalidating the GatewayHost.
+	if !cfg.ValidTLSProtocolVersion(min) {
+		min = "1.1"
+	}
+	tc, err := b.mergeTLSConfig(cfg.TLSConfig{
+		MinimumProtocolVersion: min,
+		MaximumProtocolVersion: tls.MaximumProtocolVersion,
+		CipherSuites:           tls.CipherSuites,
+		ECDHCurves:             tls.ECDHCurves,
+	})
+	if err != nil {
+		return tc, err
+	}
+	return tc, tc.Validate()
+}
+
+// mergeTLSConfig fills the parameters tc leaves unset from the global TLS
+// defaults. It returns an error if the merged minimum version is greater
+// than the merged maximum, naming the global TLS config if either comes
+// from it.
+func (b *builder) mergeTLSConfig(tc cfg.TLSConfig) (cfg.TLSConfig, error) {
+	merged := tc
+	merged.MinimumProtocolVersion = stringOrDefault(tc.MinimumProtocolVersion, b.tlsdefaults.MinimumProtocolVersion)
+	merged.MaximumProtocolVersion = stringOrDefault(tc.MaximumProtocolVersion, b.tlsdefaults.MaximumProtocolVersion)
+	if len(merged.CipherSuites) == 0 {
+		merged.CipherSuites = b.tlsdefaults.CipherSuites
+	}
+	if len(merged.ECDHCurves) == 0 {
+		merged.ECDHCurves = b.tlsdefaults.ECDHCurves
+	}
+
+	// versions are of the form 1.x, so they compare lexically
+	min, max := merged.MinimumProtocolVersion, merged.MaximumProtocolVersion
+	if min != "" && max != "" && min > max {
+		origin := func(own string) string {
+			if own == "" {
+				return " of the global TLS config"
+			}
+			return ""
+		}
+		return merged, fmt.Errorf("minimum TLS protocol version %q%s is greater than maximum %q%s",
+			min, origin(tc.MinimumProtocolVersion), max, origin(tc.MaximumProtocolVersion))
+	}
+	return merged, nil
+}
+
+// setTLSParameters applies tc to svhost.
+func setTLSParameters(svhost *SecureVirtualHost, tc cfg.TLSConfig) {
+	svhost.MinProtoVersion = minProtoVersion(tc.MinimumProtocolVersion)
+	svhost.MaxProtoVersion = maxProtoVersion(tc.MaximumProtocolVersion)
+	svhost.CipherSuites = tc.CipherSuites
+	svhost.ECDHCurves = tc.ECDHCurves
+}
+
+// maxProtoVersion returns the TLS protocol version specified by version
+// or TLS_AUTO if not present.
+func maxProtoVersion(version string) envoy_tls_v3.TlsParameters_TlsProtocol {
+	switch version {
+	case "1.3":
+		return envoy_tls_v3.TlsParameters_TLSv1_3
+	case "1.2":
+		return envoy_tls_v3.TlsParameters_TLSv1_2
+	case "1.1":
+		return envoy_tls_v3.TlsParameters_TLSv1_1
+	default:
+		return envoy_tls_v3.TlsParameters_TLS_AUTO
+	}
+}
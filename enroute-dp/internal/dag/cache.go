@@ -20,6 +20,7 @@ package dag
 
 import (
 	"sync"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
@@ -36,6 +37,11 @@ type KubernetesCache struct {
 	// namespace.
 	GatewayHostRootNamespaces []string
 
+	// CertExpiryWarningWindow is how long before its TLS certificate
+	// expires a GatewayHost status carries a warning. Zero disables
+	// the warning.
+	CertExpiryWarningWindow time.Duration
+
 	mu sync.RWMutex
 
 	ingresses    map[Meta]*v1beta1.Ingress
@@ -44,8 +50,9 @@ type KubernetesCache struct {
 	delegations  map[Meta]*gatewayhostv1.TLSCertificateDelegation
 	services     map[Meta]*v1.Service
 
-	routefilters map[RouteFilterMeta]*gatewayhostv1.RouteFilter
-	httpfilters  map[HttpFilterMeta]*gatewayhostv1.HttpFilter
+	routefilters  map[RouteFilterMeta]*gatewayhostv1.RouteFilter
+	httpfilters   map[HttpFilterMeta]*gatewayhostv1.HttpFilter
+	globalconfigs map[GlobalConfigMeta]*gatewayhostv1.GlobalConfig
 }
 
 // Meta holds the name and namespace of a Kubernetes object.
@@ -61,6 +68,10 @@ type HttpFilterMeta struct {
 	filter_type, name, namespace string
 }
 
+type GlobalConfigMeta struct {
+	config_type, name, namespace string
+}
+
 // Insert inserts obj into the KubernetesCache.
 // If an object with a matching type, name, and namespace exists, it will be overwritten.
 func (kc *KubernetesCache) Insert(obj interface{}) {
@@ -112,6 +123,13 @@ func (kc *KubernetesCache) Insert(obj interface{}) {
 		}
 		kc.routefilters[m] = obj
 
+	case *gatewayhostv1.GlobalConfig:
+		m := GlobalConfigMeta{config_type: obj.Spec.Type, name: obj.Name, namespace: obj.Namespace}
+		if kc.globalconfigs == nil {
+			kc.globalconfigs = make(map[GlobalConfigMeta]*gatewayhostv1.GlobalConfig)
+		}
+		kc.globalconfigs[m] = obj
+
 	default:
 		// not an interesting object
 	}
@@ -155,7 +173,66 @@ func (kc *KubernetesCache) remove(obj interface{}) {
 	case *gatewayhostv1.RouteFilter:
 		m := RouteFilterMeta{filter_type: obj.Spec.Type, name: obj.Name, namespace: obj.Namespace}
 		delete(kc.routefilters, m)
+
+	case *gatewayhostv1.GlobalConfig:
+		m := GlobalConfigMeta{config_type: obj.Spec.Type, name: obj.Name, namespace: obj.Namespace}
+		delete(kc.globalconfigs, m)
 	default:
 		// not interesting
 	}
 }
+
+// Clone returns a copy of kc, which may be changed without changing kc.
+// The objects held are shared by both.
+func (kc *KubernetesCache) Clone() *KubernetesCache {
+	kc.mu.RLock()
+	defer kc.mu.RUnlock()
+	c := &KubernetesCache{
+		GatewayHostRootNamespaces: kc.GatewayHostRootNamespaces,
+		CertExpiryWarningWindow:   kc.CertExpiryWarningWindow,
+		ingresses:                 make(map[Meta]*v1beta1.Ingress, len(kc.ingresses)),
+		gatewayhosts:              make(map[Meta]*gatewayhostv1.GatewayHost, len(kc.gatewayhosts)),
+		secrets:                   make(map[Meta]*v1.Secret, len(kc.secrets)),
+		delegations:               make(map[Meta]*gatewayhostv1.TLSCertificateDelegation, len(kc.delegations)),
+		services:                  make(map[Meta]*v1.Service, len(kc.services)),
+		routefilters:              make(map[RouteFilterMeta]*gatewayhostv1.RouteFilter, len(kc.routefilters)),
+		httpfilters:               make(map[HttpFilterMeta]*gatewayhostv1.HttpFilter, len(kc.httpfilters)),
+		globalconfigs:             make(map[GlobalConfigMeta]*gatewayhostv1.GlobalConfig, len(kc.globalconfigs)),
+	}
+	for k, v := range kc.ingresses {
+		c.ingresses[k] = v
+	}
+	for k, v := range kc.gatewayhosts {
+		c.gatewayhosts[k] = v
+	}
+	for k, v := range kc.secrets {
+		c.secrets[k] = v
+	}
+	for k, v := range kc.delegations {
+		c.delegations[k] = v
+	}
+	for k, v := range kc.services {
+		c.services[k] = v
+	}
+	for k, v := range kc.routefilters {
+		c.routefilters[k] = v
+	}
+	for k, v := range kc.httpfilters {
+		c.httpfilters[k] = v
+	}
+	for k, v := range kc.globalconfigs {
+		c.globalconfigs[k] = v
+	}
+	return c
+}
+
+// RemoveGatewayHostConfig removes the GatewayHosts, RouteFilters,
+// HttpFilters, and GlobalConfigs from kc.
+func (kc *KubernetesCache) RemoveGatewayHostConfig() {
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	kc.gatewayhosts = nil
+	kc.routefilters = nil
+	kc.httpfilters = nil
+	kc.globalconfigs = nil
+}
@@ -23,7 +23,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	gatewayhostv1 "github.com/saarasio/enroute/enroute-dp/apis/enroute/v1beta1"
 	cfg "github.com/saarasio/enroute/enroute-dp/saarasconfig"
 	"k8s.io/api/core/v1"
@@ -221,8 +221,17 @@ func (v *VirtualHost) Visit(f func(Vertex)) {
 type SecureVirtualHost struct {
 	VirtualHost
 
-	// TLS minimum protocol version. Defaults to envoy_api_v2_auth.TlsParameters_TLS_AUTO
-	MinProtoVersion envoy_api_v2_auth.TlsParameters_TlsProtocol
+	// TLS minimum protocol version. Defaults to envoy_tls_v3.TlsParameters_TLS_AUTO
+	MinProtoVersion envoy_tls_v3.TlsParameters_TlsProtocol
+
+	// TLS maximum protocol version. If TLS_AUTO, envoy.TLSParams picks the default.
+	MaxProtoVersion envoy_tls_v3.TlsParameters_TlsProtocol
+
+	// Cipher suites offered for TLS 1.2 and below. If empty, envoy.TLSParams picks the default.
+	CipherSuites []string
+
+	// ECDH curves offered. If empty, envoy's default curves are used.
+	ECDHCurves []string
 
 	// The cert and key for this host.
 	*Secret
@@ -383,6 +392,16 @@ func (s *Secret) Cert() []byte {
 	return s.Object.Data[v1.TLSCertKey]
 }
 
+// NotAfter returns the expiry time of the secret's tls certificate,
+// the first certificate if it holds a chain.
+func (s *Secret) NotAfter() (time.Time, error) {
+	cert, err := leafCertificate(s.Cert())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
 // PrivateKey returns the secret's tls private key
 func (s *Secret) PrivateKey() []byte {
 	return s.Object.Data[v1.TLSPrivateKeyKey]
@@ -142,6 +142,22 @@ func validateCertificate(data []byte) error {
 	return nil
 }
 
+// leafCertificate returns the first certificate in the PEM data.
+func leafCertificate(data []byte) (*x509.Certificate, error) {
+	for containsPEMHeader(data) {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("failed to parse PEM block")
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		return x509.ParseCertificate(block.Bytes)
+	}
+	return nil, errors.New("failed to locate certificate")
+}
+
 func hasCommonName(c *x509.Certificate) bool {
 	return strings.TrimSpace(c.Subject.CommonName) != ""
 }
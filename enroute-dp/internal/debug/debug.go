@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"net/http/pprof"
 
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
 	"github.com/saarasio/enroute/enroute-dp/internal/httpsvc"
 )
@@ -31,6 +32,26 @@ type Service struct {
 	httpsvc.Service
 
 	KubernetesCache *dag.KubernetesCache
+
+	// ConfigStatus holds the ACK/NACK status of the xDS streams.
+	ConfigStatus http.Handler
+
+	// DryRun builds candidate configurations without serving them.
+	DryRun http.Handler
+
+	// Resources, if set, returns a http.Handler serving the contents
+	// of the xDS resource with typeURL.
+	Resources func(typeURL string) http.Handler
+}
+
+// resourcePaths maps the paths the xDS resources are served on to
+// their type URLs.
+var resourcePaths = map[string]string{
+	"/debug/xds/clusters":  resource.ClusterType,
+	"/debug/xds/endpoints": resource.EndpointType,
+	"/debug/xds/listeners": resource.ListenerType,
+	"/debug/xds/routes":    resource.RouteType,
+	"/debug/xds/secrets":   resource.SecretType,
 }
 
 // Start fulfills the g.Start contract.
@@ -38,6 +59,17 @@ type Service struct {
 func (svc *Service) Start(stop <-chan struct{}) error {
 	registerProfile(&svc.ServeMux)
 	registerDotWriter(&svc.ServeMux, svc.KubernetesCache)
+	if svc.ConfigStatus != nil {
+		svc.ServeMux.Handle("/debug/xds/status", svc.ConfigStatus)
+	}
+	if svc.DryRun != nil {
+		svc.ServeMux.Handle("/debug/dryrun", svc.DryRun)
+	}
+	if svc.Resources != nil {
+		for path, typeURL := range resourcePaths {
+			svc.ServeMux.Handle(path, svc.Resources(typeURL))
+		}
+	}
 	return svc.Service.Start(stop)
 }
 
@@ -21,9 +21,11 @@ import (
 	"testing"
 	"time"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	envoy_cluster "github.com/envoyproxy/go-control-plane/envoy/api/v2/cluster"
-	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoy_service_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	gatewayhostv1 "github.com/saarasio/enroute/enroute-dp/apis/enroute/v1beta1"
 	"github.com/saarasio/enroute/enroute-dp/internal/envoy"
 	"github.com/saarasio/enroute/enroute-dp/internal/protobuf"
@@ -65,13 +67,13 @@ func TestClusterLongServiceName(t *testing.T) {
 	))
 
 	// check that it's been translated correctly.
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "2",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "1",
 		Resources: resources(t,
 			cluster("default/kbujbkuh-c83ceb/8080/da39a3ee5e", "default/kbujbkuhdod66gjdmwmijz8xzgsx1nkfbrloezdjiulquzk4x3p0nnvpzi8r", "default_kbujbkuhdod66gjdmwmijz8xzgsx1nkfbrloezdjiulquzk4x3p0nnvpzi8r_8080"),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "2",
+		Nonce:   "1",
 	}, streamCDS(t, cc))
 }
 
@@ -127,13 +129,13 @@ func TestClusterAddUpdateDelete(t *testing.T) {
 	})
 	rh.OnAdd(s1)
 
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "3",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "1",
 		Resources: resources(t,
 			cluster("default/kuard/80/da39a3ee5e", "default/kuard", "default_kuard_80"),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "3",
+		Nonce:   "1",
 	}, streamCDS(t, cc))
 
 	// s2 is the same as s2, but the service port has a name
@@ -148,13 +150,13 @@ func TestClusterAddUpdateDelete(t *testing.T) {
 	rh.OnUpdate(s1, s2)
 
 	// check that we get two CDS records because the port is now named.
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "4",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "2",
 		Resources: resources(t,
 			cluster("default/kuard/80/da39a3ee5e", "default/kuard/http", "default_kuard_80"),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "4",
+		Nonce:   "2",
 	}, streamCDS(t, cc))
 
 	// s3 is like s2, but has a second named port. The k8s spec
@@ -179,14 +181,14 @@ func TestClusterAddUpdateDelete(t *testing.T) {
 
 	// check that we get four CDS records. Order is important
 	// because the CDS cache is sorted.
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "5",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "3",
 		Resources: resources(t,
 			cluster("default/kuard/443/da39a3ee5e", "default/kuard/https", "default_kuard_443"),
 			cluster("default/kuard/80/da39a3ee5e", "default/kuard/http", "default_kuard_80"),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "5",
+		Nonce:   "3",
 	}, streamCDS(t, cc))
 
 	// s4 is s3 with the http port removed.
@@ -204,13 +206,13 @@ func TestClusterAddUpdateDelete(t *testing.T) {
 
 	// check that we get two CDS records only, and that the 80 and http
 	// records have been removed even though the service object remains.
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "6",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "4",
 		Resources: resources(t,
 			cluster("default/kuard/443/da39a3ee5e", "default/kuard/https", "default_kuard_443"),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "6",
+		Nonce:   "4",
 	}, streamCDS(t, cc))
 }
 
@@ -264,14 +266,14 @@ func TestClusterRenameUpdateDelete(t *testing.T) {
 	)
 
 	rh.OnAdd(s1)
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "2",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "1",
 		Resources: resources(t,
 			cluster("default/kuard/443/da39a3ee5e", "default/kuard/https", "default_kuard_443"),
 			cluster("default/kuard/80/da39a3ee5e", "default/kuard/http", "default_kuard_80"),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "2",
+		Nonce:   "1",
 	}, streamCDS(t, cc))
 
 	// s2 removes the name on port 80, moves it to port 443 and deletes the https port
@@ -284,34 +286,34 @@ func TestClusterRenameUpdateDelete(t *testing.T) {
 	)
 
 	rh.OnUpdate(s1, s2)
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "3",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "2",
 		Resources: resources(t,
 			cluster("default/kuard/443/da39a3ee5e", "default/kuard", "default_kuard_443"),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "3",
+		Nonce:   "2",
 	}, streamCDS(t, cc))
 
 	// now replace s2 with s1 to check it works in the other direction.
 	rh.OnUpdate(s2, s1)
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "4",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "3",
 		Resources: resources(t,
 			cluster("default/kuard/443/da39a3ee5e", "default/kuard/https", "default_kuard_443"),
 			cluster("default/kuard/80/da39a3ee5e", "default/kuard/http", "default_kuard_80"),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "4",
+		Nonce:   "3",
 	}, streamCDS(t, cc))
 
 	// cleanup and check
 	rh.OnDelete(s1)
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "5",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "4",
 		Resources:   resources(t),
 		TypeUrl:     clusterType,
-		Nonce:       "5",
+		Nonce:       "4",
 	}, streamCDS(t, cc))
 }
 
@@ -343,13 +345,13 @@ func TestIssue243(t *testing.T) {
 			},
 		)
 		rh.OnAdd(s1)
-		assertEqual(t, &v2.DiscoveryResponse{
-			VersionInfo: "2",
+		assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+			VersionInfo: "1",
 			Resources: resources(t,
 				cluster("default/kuard/80/da39a3ee5e", "default/kuard", "default_kuard_80"),
 			),
 			TypeUrl: clusterType,
-			Nonce:   "2",
+			Nonce:   "1",
 		}, streamCDS(t, cc))
 	})
 }
@@ -386,13 +388,13 @@ func TestIssue247(t *testing.T) {
 		},
 	)
 	rh.OnAdd(s1)
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "2",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "1",
 		Resources: resources(t,
 			cluster("default/kuard/80/da39a3ee5e", "default/kuard", "default_kuard_80"),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "2",
+		Nonce:   "1",
 	}, streamCDS(t, cc))
 }
 func TestCDSResourceFiltering(t *testing.T) {
@@ -445,32 +447,32 @@ func TestCDSResourceFiltering(t *testing.T) {
 		},
 	)
 	rh.OnAdd(s2)
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "3",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "2",
 		Resources: resources(t,
 			// note, resources are sorted by Cluster.Name
 			cluster("default/httpbin/8080/da39a3ee5e", "default/httpbin", "default_httpbin_8080"),
 			cluster("default/kuard/80/da39a3ee5e", "default/kuard", "default_kuard_80"),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "3",
+		Nonce:   "2",
 	}, streamCDS(t, cc))
 
 	// assert we can filter on one resource
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "3",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "2",
 		Resources: resources(t,
 			cluster("default/kuard/80/da39a3ee5e", "default/kuard", "default_kuard_80"),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "3",
+		Nonce:   "2",
 	}, streamCDS(t, cc, "default/kuard/80/da39a3ee5e"))
 
 	// assert a non matching filter returns a response with no entries.
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "3",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "2",
 		TypeUrl:     clusterType,
-		Nonce:       "3",
+		Nonce:       "2",
 	}, streamCDS(t, cc, "default/httpbin/9000"))
 }
 
@@ -510,21 +512,21 @@ func TestClusterCircuitbreakerAnnotations(t *testing.T) {
 	rh.OnAdd(s1)
 
 	// check that it's been translated correctly.
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "2",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "1",
 		Resources: resources(t,
-			&v2.Cluster{
+			&envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/8080/da39a3ee5e",
 				AltStatName:          "default_kuard_8080",
-				ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   envoy.ConfigSource("enroute"),
 					ServiceName: "default/kuard",
 				},
 				ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:       v2.Cluster_ROUND_ROBIN,
-				CircuitBreakers: &envoy_cluster.CircuitBreakers{
-					Thresholds: []*envoy_cluster.CircuitBreakers_Thresholds{{
+				LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
+				CircuitBreakers: &envoy_cluster_v3.CircuitBreakers{
+					Thresholds: []*envoy_cluster_v3.CircuitBreakers_Thresholds{{
 						MaxConnections:     protobuf.UInt32(9000),
 						MaxPendingRequests: protobuf.UInt32(4096),
 						MaxRequests:        protobuf.UInt32(404),
@@ -535,7 +537,7 @@ func TestClusterCircuitbreakerAnnotations(t *testing.T) {
 			},
 		),
 		TypeUrl: clusterType,
-		Nonce:   "2",
+		Nonce:   "1",
 	}, streamCDS(t, cc))
 
 	// update s1 with slightly weird values
@@ -556,21 +558,21 @@ func TestClusterCircuitbreakerAnnotations(t *testing.T) {
 	rh.OnUpdate(s1, s2)
 
 	// check that it's been translated correctly.
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "3",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "2",
 		Resources: resources(t,
-			&v2.Cluster{
+			&envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/8080/da39a3ee5e",
 				AltStatName:          "default_kuard_8080",
-				ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   envoy.ConfigSource("enroute"),
 					ServiceName: "default/kuard",
 				},
 				ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:       v2.Cluster_ROUND_ROBIN,
-				CircuitBreakers: &envoy_cluster.CircuitBreakers{
-					Thresholds: []*envoy_cluster.CircuitBreakers_Thresholds{{
+				LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
+				CircuitBreakers: &envoy_cluster_v3.CircuitBreakers{
+					Thresholds: []*envoy_cluster_v3.CircuitBreakers_Thresholds{{
 						MaxPendingRequests: protobuf.UInt32(9999),
 					}},
 				},
@@ -578,7 +580,7 @@ func TestClusterCircuitbreakerAnnotations(t *testing.T) {
 			},
 		),
 		TypeUrl: clusterType,
-		Nonce:   "3",
+		Nonce:   "2",
 	}, streamCDS(t, cc))
 }
 
@@ -631,13 +633,13 @@ func TestClusterPerServiceParameters(t *testing.T) {
 		},
 	})
 
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "2",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "1",
 		Resources: resources(t,
 			cluster("default/kuard/80/da39a3ee5e", "default/kuard", "default_kuard_80"),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "2",
+		Nonce:   "1",
 	}, streamCDS(t, cc))
 }
 
@@ -690,36 +692,36 @@ func TestClusterLoadBalancerStrategyPerRoute(t *testing.T) {
 		},
 	})
 
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "2",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "1",
 		Resources: resources(t,
-			&v2.Cluster{
+			&envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/80/58d888c08a",
 				AltStatName:          "default_kuard_80",
-				ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   envoy.ConfigSource("enroute"),
 					ServiceName: "default/kuard",
 				},
 				ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:       v2.Cluster_RANDOM,
+				LbPolicy:       envoy_cluster_v3.Cluster_RANDOM,
 				CommonLbConfig: envoy.ClusterCommonLBConfig(),
 			},
-			&v2.Cluster{
+			&envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/80/8bf87fefba",
 				AltStatName:          "default_kuard_80",
-				ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   envoy.ConfigSource("enroute"),
 					ServiceName: "default/kuard",
 				},
 				ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:       v2.Cluster_LEAST_REQUEST,
+				LbPolicy:       envoy_cluster_v3.Cluster_LEAST_REQUEST,
 				CommonLbConfig: envoy.ClusterCommonLBConfig(),
 			},
 		),
 		TypeUrl: clusterType,
-		Nonce:   "2",
+		Nonce:   "1",
 	}, streamCDS(t, cc))
 
 }
@@ -765,13 +767,13 @@ func TestClusterWithHealthChecks(t *testing.T) {
 		},
 	})
 
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "2",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "1",
 		Resources: resources(t,
 			clusterWithHealthCheck("default/kuard/80/bc862a33ca", "default/kuard", "default_kuard_80", "/healthz", true),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "2",
+		Nonce:   "1",
 	}, streamCDS(t, cc))
 }
 
@@ -814,13 +816,13 @@ func TestClusterServiceTLSBackend(t *testing.T) {
 	}
 	rh.OnAdd(s1)
 
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "2",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "1",
 		Resources: resources(t,
 			tlscluster("default/kuard/443/da39a3ee5e", "default/kuard/securebackend", "default_kuard_443", nil, ""),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "2",
+		Nonce:   "1",
 	}, streamCDS(t, cc))
 }
 
@@ -879,8 +881,8 @@ func TestClusterServiceTLSBackendCAValidation(t *testing.T) {
 
 	rh.OnAdd(ir1)
 
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "3",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "1",
 		Resources: resources(t,
 			tlscluster(
 				"default/kuard/443/da39a3ee5e",
@@ -890,7 +892,7 @@ func TestClusterServiceTLSBackendCAValidation(t *testing.T) {
 				""),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "3",
+		Nonce:   "1",
 	}, streamCDS(t, cc))
 
 	ir2 := &gatewayhostv1.GatewayHost{
@@ -918,8 +920,8 @@ func TestClusterServiceTLSBackendCAValidation(t *testing.T) {
 
 	rh.OnUpdate(ir1, ir2)
 
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "4",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "2",
 		Resources: resources(t,
 			tlscluster(
 				"default/kuard/443/98c0f31c72",
@@ -929,7 +931,7 @@ func TestClusterServiceTLSBackendCAValidation(t *testing.T) {
 				"subjname"),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "4",
+		Nonce:   "2",
 	}, streamCDS(t, cc))
 }
 
@@ -960,13 +962,13 @@ func TestExternalNameService(t *testing.T) {
 	})
 	rh.OnAdd(s1)
 
-	assertEqual(t, &v2.DiscoveryResponse{
-		VersionInfo: "2",
+	assertEqual(t, &envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "1",
 		Resources: resources(t,
 			externalnamecluster("default/kuard/80/da39a3ee5e", "default/kuard/", "default_kuard_80", "foo.io", 80),
 		),
 		TypeUrl: clusterType,
-		Nonce:   "2",
+		Nonce:   "1",
 	}, streamCDS(t, cc))
 }
 
@@ -983,41 +985,41 @@ func serviceWithAnnotations(ns, name string, annotations map[string]string, port
 	}
 }
 
-func streamCDS(t *testing.T, cc *grpc.ClientConn, rn ...string) *v2.DiscoveryResponse {
+func streamCDS(t *testing.T, cc *grpc.ClientConn, rn ...string) *envoy_discovery_v3.DiscoveryResponse {
 	t.Helper()
-	rds := v2.NewClusterDiscoveryServiceClient(cc)
+	rds := envoy_service_cluster_v3.NewClusterDiscoveryServiceClient(cc)
 	st, err := rds.StreamClusters(context.TODO())
 	check(t, err)
-	return stream(t, st, &v2.DiscoveryRequest{
+	return stream(t, st, &envoy_discovery_v3.DiscoveryRequest{
 		TypeUrl:       clusterType,
 		ResourceNames: rn,
 	})
 }
 
-func cluster(name, servicename, statName string) *v2.Cluster {
-	return &v2.Cluster{
+func cluster(name, servicename, statName string) *envoy_cluster_v3.Cluster {
+	return &envoy_cluster_v3.Cluster{
 		Name:                 name,
-		ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
+		ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
 		AltStatName:          statName,
-		EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+		EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 			EdsConfig:   envoy.ConfigSource("enroute"),
 			ServiceName: servicename,
 		},
 		ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-		LbPolicy:       v2.Cluster_ROUND_ROBIN,
+		LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 		CommonLbConfig: envoy.ClusterCommonLBConfig(),
 	}
 }
 
-func externalnamecluster(name, servicename, statName, externalName string, port int) *v2.Cluster {
-	return &v2.Cluster{
+func externalnamecluster(name, servicename, statName, externalName string, port int) *envoy_cluster_v3.Cluster {
+	return &envoy_cluster_v3.Cluster{
 		Name:                 name,
-		ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_STRICT_DNS),
+		ClusterDiscoveryType: envoy.ClusterDiscoveryType(envoy_cluster_v3.Cluster_STRICT_DNS),
 		AltStatName:          statName,
 		ConnectTimeout:       protobuf.Duration(250 * time.Millisecond),
-		LbPolicy:             v2.Cluster_ROUND_ROBIN,
+		LbPolicy:             envoy_cluster_v3.Cluster_ROUND_ROBIN,
 		CommonLbConfig:       envoy.ClusterCommonLBConfig(),
-		LoadAssignment: &v2.ClusterLoadAssignment{
+		LoadAssignment: &envoy_endpoint_v3.ClusterLoadAssignment{
 			ClusterName: servicename,
 			Endpoints: envoy.Endpoints(
 				envoy.SocketAddress(externalName, port),
@@ -1026,7 +1028,7 @@ func externalnamecluster(name, servicename, statName, externalName string, port
 	}
 }
 
-func tlscluster(name, servicename, statsName string, ca []byte, subjectName string) *v2.Cluster {
+func tlscluster(name, servicename, statsName string, ca []byte, subjectName string) *envoy_cluster_v3.Cluster {
 	c := cluster(name, servicename, statsName)
 	c.TransportSocket = envoy.UpstreamTLSTransportSocket(
 		envoy.UpstreamTLSContext(ca, subjectName),
@@ -1034,20 +1036,20 @@ func tlscluster(name, servicename, statsName string, ca []byte, subjectName stri
 	return c
 }
 
-func clusterWithHealthCheck(name, servicename, statName, healthCheckPath string, drainConnOnHostRemoval bool) *v2.Cluster {
+func clusterWithHealthCheck(name, servicename, statName, healthCheckPath string, drainConnOnHostRemoval bool) *envoy_cluster_v3.Cluster {
 	c := cluster(name, servicename, statName)
-	c.HealthChecks = []*envoy_api_v2_core.HealthCheck{{
+	c.HealthChecks = []*envoy_core_v3.HealthCheck{{
 		Timeout:            protobuf.Duration(2 * time.Second),
 		Interval:           protobuf.Duration(10 * time.Second),
 		UnhealthyThreshold: protobuf.UInt32(3),
 		HealthyThreshold:   protobuf.UInt32(2),
-		HealthChecker: &envoy_api_v2_core.HealthCheck_HttpHealthCheck_{
-			HttpHealthCheck: &envoy_api_v2_core.HealthCheck_HttpHealthCheck{
+		HealthChecker: &envoy_core_v3.HealthCheck_HttpHealthCheck_{
+			HttpHealthCheck: &envoy_core_v3.HealthCheck_HttpHealthCheck{
 				Host: "contour-envoy-healthcheck",
 				Path: healthCheckPath,
 			},
 		},
 	}}
-	c.DrainConnectionsOnHostRemoval = drainConnOnHostRemoval
+	c.IgnoreHealthOnHostRemoval = drainConnOnHostRemoval
 	return c
 }
@@ -23,10 +23,11 @@ import (
 
 	gatewayhostv1 "github.com/saarasio/enroute/enroute-dp/apis/enroute/v1beta1"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
-	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
-	envoy_config_v2_tcpproxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	envoy_service_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/service/listener/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/saarasio/enroute/enroute-dp/apis/generated/clientset/versioned/fake"
 	"github.com/saarasio/enroute/enroute-dp/internal/assert"
@@ -48,7 +49,7 @@ func TestNonTLSListener(t *testing.T) {
 
 	// assert that without any ingress objects registered
 	// there are no active listeners
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "0",
 		Resources: resources(t,
 			staticListener(),
@@ -84,10 +85,10 @@ func TestNonTLSListener(t *testing.T) {
 
 	// add it and assert that we now have a ingress_http listener
 	rh.OnAdd(i1)
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "1",
 		Resources: resources(t,
-			&v2.Listener{
+			&envoy_listener_v3.Listener{
 				Name:         "ingress_http",
 				Address:      envoy.SocketAddress("0.0.0.0", 8080),
 				FilterChains: envoy.FilterChains(envoy.HTTPConnectionManager("ingress_http", "/dev/stdout", nil)),
@@ -114,7 +115,7 @@ func TestNonTLSListener(t *testing.T) {
 
 	// update i1 to i2 and verify that ingress_http has gone.
 	rh.OnUpdate(i1, i2)
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "2",
 		Resources: resources(t,
 			staticListener(),
@@ -140,10 +141,10 @@ func TestNonTLSListener(t *testing.T) {
 
 	// update i2 to i3 and check that ingress_http has returned
 	rh.OnUpdate(i2, i3)
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "3",
 		Resources: resources(t,
-			&v2.Listener{
+			&envoy_listener_v3.Listener{
 				Name:         "ingress_http",
 				Address:      envoy.SocketAddress("0.0.0.0", 8080),
 				FilterChains: envoy.FilterChains(envoy.HTTPConnectionManager("ingress_http", "/dev/stdout", nil)),
@@ -205,7 +206,7 @@ func TestTLSListener(t *testing.T) {
 	rh.OnAdd(s1)
 
 	// assert that there is only a static listener
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "1",
 		Resources: resources(t,
 			staticListener(),
@@ -216,15 +217,15 @@ func TestTLSListener(t *testing.T) {
 
 	// add ingress and assert the existence of ingress_http and ingres_https
 	rh.OnAdd(i1)
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "2",
 		Resources: resources(t,
-			&v2.Listener{
+			&envoy_listener_v3.Listener{
 				Name:         "ingress_http",
 				Address:      envoy.SocketAddress("0.0.0.0", 8080),
 				FilterChains: envoy.FilterChains(envoy.HTTPConnectionManager("ingress_http", "/dev/stdout", nil)),
 			},
-			&v2.Listener{
+			&envoy_listener_v3.Listener{
 				Name:    "ingress_https",
 				Address: envoy.SocketAddress("0.0.0.0", 8443),
 				ListenerFilters: envoy.ListenerFilters(
@@ -258,10 +259,10 @@ func TestTLSListener(t *testing.T) {
 
 	// update i1 to i2 and verify that ingress_http has gone.
 	rh.OnUpdate(i1, i2)
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "3",
 		Resources: resources(t,
-			&v2.Listener{
+			&envoy_listener_v3.Listener{
 				Name:    "ingress_https",
 				Address: envoy.SocketAddress("0.0.0.0", 8443),
 				ListenerFilters: envoy.ListenerFilters(
@@ -277,7 +278,7 @@ func TestTLSListener(t *testing.T) {
 
 	// delete secret and assert that ingress_https is removed
 	rh.OnDelete(s1)
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "4",
 		Resources: resources(t,
 			staticListener(),
@@ -374,7 +375,7 @@ func TestGatewayHostTLSListener(t *testing.T) {
 	rh.OnAdd(secret1)
 
 	// assert that there is only a static listener
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "1",
 		Resources: resources(t,
 			staticListener(),
@@ -383,7 +384,7 @@ func TestGatewayHostTLSListener(t *testing.T) {
 		Nonce:   "1",
 	}, streamLDS(t, cc))
 
-	l1 := &v2.Listener{
+	l1 := &envoy_listener_v3.Listener{
 		Name:    "ingress_https",
 		Address: envoy.SocketAddress("0.0.0.0", 8443),
 		ListenerFilters: envoy.ListenerFilters(
@@ -398,10 +399,10 @@ func TestGatewayHostTLSListener(t *testing.T) {
 	// add ingress and assert the existence of ingress_http and ingres_https
 	rh.OnAdd(i1)
 
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "3",
 		Resources: resources(t,
-			&v2.Listener{
+			&envoy_listener_v3.Listener{
 				Name:         "ingress_http",
 				Address:      envoy.SocketAddress("0.0.0.0", 8080),
 				FilterChains: envoy.FilterChains(envoy.HTTPConnectionManager("ingress_http", "/dev/stdout", nil)),
@@ -415,10 +416,10 @@ func TestGatewayHostTLSListener(t *testing.T) {
 
 	// delete secret and assert that ingress_https is removed
 	rh.OnDelete(secret1)
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "4",
 		Resources: resources(t,
-			&v2.Listener{
+			&envoy_listener_v3.Listener{
 				Name:         "ingress_http",
 				Address:      envoy.SocketAddress("0.0.0.0", 8080),
 				FilterChains: envoy.FilterChains(envoy.HTTPConnectionManager("ingress_http", "/dev/stdout", nil)),
@@ -432,20 +433,20 @@ func TestGatewayHostTLSListener(t *testing.T) {
 	rh.OnDelete(i1)
 	// add secret
 	rh.OnAdd(secret1)
-	l2 := &v2.Listener{
+	l2 := &envoy_listener_v3.Listener{
 		Name:    "ingress_https",
 		Address: envoy.SocketAddress("0.0.0.0", 8443),
 		ListenerFilters: envoy.ListenerFilters(
 			envoy.TLSInspector(),
 		),
-		FilterChains: []*envoy_api_v2_listener.FilterChain{
+		FilterChains: []*envoy_listener_v3.FilterChain{
 			envoy.FilterChainTLS(
 				"kuard.example.com",
 				&dag.Secret{Object: secret1},
 				envoy.Filters(
 					envoy.HTTPConnectionManager("ingress_https", "/dev/stdout", nil),
 				),
-				envoy_api_v2_auth.TlsParameters_TLSv1_3,
+				envoy.TLSParams(envoy_tls_v3.TlsParameters_TLSv1_3, envoy_tls_v3.TlsParameters_TLS_AUTO, nil, nil),
 				"h2", "http/1.1",
 			),
 		},
@@ -453,10 +454,10 @@ func TestGatewayHostTLSListener(t *testing.T) {
 
 	// add ingress and assert the existence of ingress_http and ingres_https
 	rh.OnAdd(i2)
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "7",
 		Resources: resources(t,
-			&v2.Listener{
+			&envoy_listener_v3.Listener{
 				Name:         "ingress_http",
 				Address:      envoy.SocketAddress("0.0.0.0", 8080),
 				FilterChains: envoy.FilterChains(envoy.HTTPConnectionManager("ingress_http", "/dev/stdout", nil)),
@@ -520,10 +521,10 @@ func TestLDSFilter(t *testing.T) {
 
 	// add ingress and fetch ingress_https
 	rh.OnAdd(i1)
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "2",
 		Resources: resources(t,
-			&v2.Listener{
+			&envoy_listener_v3.Listener{
 				Name:    "ingress_https",
 				Address: envoy.SocketAddress("0.0.0.0", 8443),
 				ListenerFilters: envoy.ListenerFilters(
@@ -537,10 +538,10 @@ func TestLDSFilter(t *testing.T) {
 	}, streamLDS(t, cc, "ingress_https"))
 
 	// fetch ingress_http
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "2",
 		Resources: resources(t,
-			&v2.Listener{
+			&envoy_listener_v3.Listener{
 				Name:         "ingress_http",
 				Address:      envoy.SocketAddress("0.0.0.0", 8080),
 				FilterChains: envoy.FilterChains(envoy.HTTPConnectionManager("ingress_http", "/dev/stdout", nil)),
@@ -551,7 +552,7 @@ func TestLDSFilter(t *testing.T) {
 	}, streamLDS(t, cc, "ingress_http"))
 
 	// fetch something non existent.
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "2",
 		TypeUrl:     listenerType,
 		Nonce:       "2",
@@ -563,7 +564,7 @@ func TestLDSStreamEmpty(t *testing.T) {
 	defer done()
 
 	// assert that streaming LDS with no ingresses does not stall.
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "0",
 		TypeUrl:     listenerType,
 		Nonce:       "0",
@@ -607,10 +608,10 @@ func TestLDSTLSMinimumProtocolVersion(t *testing.T) {
 
 	// add ingress and fetch ingress_https
 	rh.OnAdd(i1)
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "3",
 		Resources: resources(t,
-			&v2.Listener{
+			&envoy_listener_v3.Listener{
 				Name:    "ingress_https",
 				Address: envoy.SocketAddress("0.0.0.0", 8443),
 				ListenerFilters: envoy.ListenerFilters(
@@ -643,26 +644,26 @@ func TestLDSTLSMinimumProtocolVersion(t *testing.T) {
 	// update tls version and fetch ingress_https
 	rh.OnUpdate(i1, i2)
 
-	l1 := &v2.Listener{
+	l1 := &envoy_listener_v3.Listener{
 		Name:    "ingress_https",
 		Address: envoy.SocketAddress("0.0.0.0", 8443),
 		ListenerFilters: envoy.ListenerFilters(
 			envoy.TLSInspector(),
 		),
-		FilterChains: []*envoy_api_v2_listener.FilterChain{
+		FilterChains: []*envoy_listener_v3.FilterChain{
 			envoy.FilterChainTLS(
 				"kuard.example.com",
 				&dag.Secret{Object: s1},
 				envoy.Filters(
 					envoy.HTTPConnectionManager("ingress_https", "/dev/stdout", nil),
 				),
-				envoy_api_v2_auth.TlsParameters_TLSv1_3,
+				envoy.TLSParams(envoy_tls_v3.TlsParameters_TLSv1_3, envoy_tls_v3.TlsParameters_TLS_AUTO, nil, nil),
 				"h2", "http/1.1",
 			),
 		},
 	}
 
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "4",
 		Resources: resources(t,
 			l1,
@@ -680,7 +681,7 @@ func TestLDSIngressHTTPUseProxyProtocol(t *testing.T) {
 
 	// assert that without any ingress objects registered
 	// there is only a static listener
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "0",
 		Resources: resources(t,
 			staticListener(),
@@ -716,10 +717,10 @@ func TestLDSIngressHTTPUseProxyProtocol(t *testing.T) {
 	// add it and assert that we now have a ingress_http listener using
 	// the proxy protocol (the true param to filterchain)
 	rh.OnAdd(i1)
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "1",
 		Resources: resources(t,
-			&v2.Listener{
+			&envoy_listener_v3.Listener{
 				Name:    "ingress_http",
 				Address: envoy.SocketAddress("0.0.0.0", 8080),
 				ListenerFilters: envoy.ListenerFilters(
@@ -772,7 +773,7 @@ func TestLDSIngressHTTPSUseProxyProtocol(t *testing.T) {
 	rh.OnAdd(s1)
 
 	// assert that there is only a static listener
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "1",
 		Resources: resources(t,
 			staticListener(),
@@ -799,7 +800,7 @@ func TestLDSIngressHTTPSUseProxyProtocol(t *testing.T) {
 	// are using proxy protocol
 	rh.OnAdd(i1)
 
-	ingress_https := &v2.Listener{
+	ingress_https := &envoy_listener_v3.Listener{
 		Name:    "ingress_https",
 		Address: envoy.SocketAddress("0.0.0.0", 8443),
 		ListenerFilters: envoy.ListenerFilters(
@@ -808,10 +809,10 @@ func TestLDSIngressHTTPSUseProxyProtocol(t *testing.T) {
 		),
 		FilterChains: filterchaintls("kuard.example.com", s1, envoy.HTTPConnectionManager("ingress_https", "/dev/stdout", nil), "h2", "http/1.1"),
 	}
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "2",
 		Resources: resources(t,
-			&v2.Listener{
+			&envoy_listener_v3.Listener{
 				Name:    "ingress_http",
 				Address: envoy.SocketAddress("0.0.0.0", 8080),
 				ListenerFilters: envoy.ListenerFilters(
@@ -868,7 +869,7 @@ func TestLDSCustomAddressAndPort(t *testing.T) {
 	rh.OnAdd(s1)
 
 	// assert that there is only a static listener
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "1",
 		Resources: resources(t,
 			staticListener(),
@@ -895,12 +896,12 @@ func TestLDSCustomAddressAndPort(t *testing.T) {
 	// are using proxy protocol
 	rh.OnAdd(i1)
 
-	ingress_http := &v2.Listener{
+	ingress_http := &envoy_listener_v3.Listener{
 		Name:         "ingress_http",
 		Address:      envoy.SocketAddress("127.0.0.100", 9100),
 		FilterChains: envoy.FilterChains(envoy.HTTPConnectionManager("ingress_http", "/dev/stdout", nil)),
 	}
-	ingress_https := &v2.Listener{
+	ingress_https := &envoy_listener_v3.Listener{
 		Name:    "ingress_https",
 		Address: envoy.SocketAddress("127.0.0.200", 9200),
 		ListenerFilters: envoy.ListenerFilters(
@@ -908,7 +909,7 @@ func TestLDSCustomAddressAndPort(t *testing.T) {
 		),
 		FilterChains: filterchaintls("kuard.example.com", s1, envoy.HTTPConnectionManager("ingress_https", "/dev/stdout", nil), "h2", "http/1.1"),
 	}
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "2",
 		Resources: resources(t,
 			ingress_http,
@@ -973,7 +974,7 @@ func TestLDSCustomAccessLogPaths(t *testing.T) {
 	rh.OnAdd(s1)
 
 	// assert that there is only a static listener
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "1",
 		Resources: resources(t,
 			staticListener(),
@@ -984,12 +985,12 @@ func TestLDSCustomAccessLogPaths(t *testing.T) {
 
 	rh.OnAdd(i1)
 
-	ingress_http := &v2.Listener{
+	ingress_http := &envoy_listener_v3.Listener{
 		Name:         "ingress_http",
 		Address:      envoy.SocketAddress("0.0.0.0", 8080),
 		FilterChains: envoy.FilterChains(envoy.HTTPConnectionManager("ingress_http", "/tmp/http_access.log", nil)),
 	}
-	ingress_https := &v2.Listener{
+	ingress_https := &envoy_listener_v3.Listener{
 		Name:    "ingress_https",
 		Address: envoy.SocketAddress("0.0.0.0", 8443),
 		ListenerFilters: envoy.ListenerFilters(
@@ -997,7 +998,7 @@ func TestLDSCustomAccessLogPaths(t *testing.T) {
 		),
 		FilterChains: filterchaintls("kuard.example.com", s1, envoy.HTTPConnectionManager("ingress_https", "/tmp/https_access.log", nil), "h2", "http/1.1"),
 	}
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "2",
 		Resources: resources(t,
 			ingress_http,
@@ -1019,7 +1020,7 @@ func TestLDSGatewayHostInsideRootNamespaces(t *testing.T) {
 	defer done()
 
 	// assert that there is only a static listener
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "0",
 		Resources: resources(t,
 			staticListener(),
@@ -1067,10 +1068,10 @@ func TestLDSGatewayHostInsideRootNamespaces(t *testing.T) {
 	rh.OnAdd(ir1)
 
 	// assert there is an active listener
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "2",
 		Resources: resources(t,
-			&v2.Listener{
+			&envoy_listener_v3.Listener{
 				Name:         "ingress_http",
 				Address:      envoy.SocketAddress("0.0.0.0", 8080),
 				FilterChains: envoy.FilterChains(envoy.HTTPConnectionManager("ingress_http", "/dev/stdout", nil)),
@@ -1092,7 +1093,7 @@ func TestLDSGatewayHostOutsideRootNamespaces(t *testing.T) {
 	defer done()
 
 	// assert that there is only a static listener
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "0",
 		Resources: resources(t,
 			staticListener(),
@@ -1125,7 +1126,7 @@ func TestLDSGatewayHostOutsideRootNamespaces(t *testing.T) {
 	rh.OnAdd(ir1)
 
 	// assert that there is only a static listener
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "1",
 		Resources: resources(t,
 			staticListener(),
@@ -1145,7 +1146,7 @@ func TestGatewayHostHTTPS(t *testing.T) {
 	defer done()
 
 	// assert that there is only a static listener
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "0",
 		Resources: resources(t,
 			staticListener(),
@@ -1215,13 +1216,13 @@ func TestGatewayHostHTTPS(t *testing.T) {
 	// add gatewayhost
 	rh.OnAdd(ir1)
 
-	ingressHTTP := &v2.Listener{
+	ingressHTTP := &envoy_listener_v3.Listener{
 		Name:         "ingress_http",
 		Address:      envoy.SocketAddress("0.0.0.0", 8080),
 		FilterChains: envoy.FilterChains(envoy.HTTPConnectionManager("ingress_http", "/dev/stdout", nil)),
 	}
 
-	ingressHTTPS := &v2.Listener{
+	ingressHTTPS := &envoy_listener_v3.Listener{
 		Name:    "ingress_https",
 		Address: envoy.SocketAddress("0.0.0.0", 8443),
 		ListenerFilters: envoy.ListenerFilters(
@@ -1229,7 +1230,7 @@ func TestGatewayHostHTTPS(t *testing.T) {
 		),
 		FilterChains: filterchaintls("example.com", s1, envoy.HTTPConnectionManager("ingress_https", "/dev/stdout", nil), "h2", "http/1.1"),
 	}
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "3",
 		Resources: resources(t,
 			ingressHTTP,
@@ -1285,14 +1286,14 @@ func TestLDSGatewayHostTCPProxyTLSPassthrough(t *testing.T) {
 	rh.OnAdd(svc)
 	rh.OnAdd(i1)
 
-	ingressHTTPS := &v2.Listener{
+	ingressHTTPS := &envoy_listener_v3.Listener{
 		Name:    "ingress_https",
 		Address: envoy.SocketAddress("0.0.0.0", 8443),
-		FilterChains: []*envoy_api_v2_listener.FilterChain{{
+		FilterChains: []*envoy_listener_v3.FilterChain{{
 			Filters: envoy.Filters(
 				tcpproxy(t, "ingress_https", "default/correct-backend/80/da39a3ee5e"),
 			),
-			FilterChainMatch: &envoy_api_v2_listener.FilterChainMatch{
+			FilterChainMatch: &envoy_listener_v3.FilterChainMatch{
 				ServerNames: []string{"kuard-tcp.example.com"},
 			},
 		}},
@@ -1301,7 +1302,7 @@ func TestLDSGatewayHostTCPProxyTLSPassthrough(t *testing.T) {
 		),
 	}
 
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "2",
 		Resources: resources(t,
 			ingressHTTPS,
@@ -1367,7 +1368,7 @@ func TestLDSGatewayHostTCPForward(t *testing.T) {
 	rh.OnAdd(svc)
 	rh.OnAdd(i1)
 
-	ingressHTTPS := &v2.Listener{
+	ingressHTTPS := &envoy_listener_v3.Listener{
 		Name:         "ingress_https",
 		Address:      envoy.SocketAddress("0.0.0.0", 8443),
 		FilterChains: filterchaintls("kuard-tcp.example.com", s1, tcpproxy(t, "ingress_https", "default/correct-backend/80/da39a3ee5e")),
@@ -1376,7 +1377,7 @@ func TestLDSGatewayHostTCPForward(t *testing.T) {
 		),
 	}
 
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "3",
 		Resources: resources(t,
 			ingressHTTPS,
@@ -1393,7 +1394,7 @@ func TestGatewayHostTLSCertificateDelegation(t *testing.T) {
 	defer done()
 
 	// assert that there is only a static listener
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "0",
 		Resources: resources(t,
 			staticListener(),
@@ -1456,14 +1457,14 @@ func TestGatewayHostTLSCertificateDelegation(t *testing.T) {
 		},
 	})
 
-	ingress_http := &v2.Listener{
+	ingress_http := &envoy_listener_v3.Listener{
 		Name:         "ingress_http",
 		Address:      envoy.SocketAddress("0.0.0.0", 8080),
 		FilterChains: envoy.FilterChains(envoy.HTTPConnectionManager("ingress_http", "/dev/stdout", nil)),
 	}
 
 	// assert there is no ingress_https because there is no matching secret.
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "3",
 		Resources: resources(t,
 			ingress_http,
@@ -1490,7 +1491,7 @@ func TestGatewayHostTLSCertificateDelegation(t *testing.T) {
 	}
 	rh.OnAdd(t1)
 
-	ingress_https := &v2.Listener{
+	ingress_https := &envoy_listener_v3.Listener{
 		Name:    "ingress_https",
 		Address: envoy.SocketAddress("0.0.0.0", 8443),
 		ListenerFilters: envoy.ListenerFilters(
@@ -1499,7 +1500,7 @@ func TestGatewayHostTLSCertificateDelegation(t *testing.T) {
 		FilterChains: filterchaintls("example.com", s1, envoy.HTTPConnectionManager("ingress_https", "/dev/stdout", nil), "h2", "http/1.1"),
 	}
 
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "4",
 		Resources: resources(t,
 			ingress_http,
@@ -1527,7 +1528,7 @@ func TestGatewayHostTLSCertificateDelegation(t *testing.T) {
 	}
 	rh.OnUpdate(t1, t2)
 
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "5",
 		Resources: resources(t,
 			ingress_http,
@@ -1555,7 +1556,7 @@ func TestGatewayHostTLSCertificateDelegation(t *testing.T) {
 	}
 	rh.OnUpdate(t2, t3)
 
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "6",
 		Resources: resources(t,
 			ingress_http,
@@ -1582,7 +1583,7 @@ func TestGatewayHostTLSCertificateDelegation(t *testing.T) {
 	}
 	rh.OnUpdate(t3, t4)
 
-	assert.Equal(t, &v2.DiscoveryResponse{
+	assert.Equal(t, &envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "7",
 		Resources: resources(t,
 			ingress_http,
@@ -1594,12 +1595,12 @@ func TestGatewayHostTLSCertificateDelegation(t *testing.T) {
 
 }
 
-func streamLDS(t *testing.T, cc *grpc.ClientConn, rn ...string) *v2.DiscoveryResponse {
+func streamLDS(t *testing.T, cc *grpc.ClientConn, rn ...string) *envoy_discovery_v3.DiscoveryResponse {
 	t.Helper()
-	rds := v2.NewListenerDiscoveryServiceClient(cc)
+	rds := envoy_service_listener_v3.NewListenerDiscoveryServiceClient(cc)
 	st, err := rds.StreamListeners(context.TODO())
 	check(t, err)
-	return stream(t, st, &v2.DiscoveryRequest{
+	return stream(t, st, &envoy_discovery_v3.DiscoveryRequest{
 		TypeUrl:       listenerType,
 		ResourceNames: rn,
 	})
@@ -1612,27 +1613,27 @@ func backend(name string, port intstr.IntOrString) *v1beta1.IngressBackend {
 	}
 }
 
-func filterchaintls(domain string, secret *v1.Secret, filter *envoy_api_v2_listener.Filter, alpn ...string) []*envoy_api_v2_listener.FilterChain {
-	return []*envoy_api_v2_listener.FilterChain{
+func filterchaintls(domain string, secret *v1.Secret, filter *envoy_listener_v3.Filter, alpn ...string) []*envoy_listener_v3.FilterChain {
+	return []*envoy_listener_v3.FilterChain{
 		envoy.FilterChainTLS(
 			domain,
 			&dag.Secret{Object: secret},
-			[]*envoy_api_v2_listener.Filter{
+			[]*envoy_listener_v3.Filter{
 				filter,
 			},
-			envoy_api_v2_auth.TlsParameters_TLSv1_1,
+			envoy.TLSParams(envoy_tls_v3.TlsParameters_TLSv1_1, envoy_tls_v3.TlsParameters_TLS_AUTO, nil, nil),
 			alpn...,
 		),
 	}
 }
 
-func tcpproxy(t *testing.T, statPrefix, cluster string) *envoy_api_v2_listener.Filter {
-	return &envoy_api_v2_listener.Filter{
+func tcpproxy(t *testing.T, statPrefix, cluster string) *envoy_listener_v3.Filter {
+	return &envoy_listener_v3.Filter{
 		Name: wellknown.TCPProxy,
-		ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{
-			TypedConfig: toAny(t, &envoy_config_v2_tcpproxy.TcpProxy{
+		ConfigType: &envoy_listener_v3.Filter_TypedConfig{
+			TypedConfig: toAny(t, &tcp.TcpProxy{
 				StatPrefix: statPrefix,
-				ClusterSpecifier: &envoy_config_v2_tcpproxy.TcpProxy_Cluster{
+				ClusterSpecifier: &tcp.TcpProxy_Cluster{
 					Cluster: cluster,
 				},
 				AccessLog:   envoy.FileAccessLog("/dev/stdout"),
@@ -1642,6 +1643,6 @@ func tcpproxy(t *testing.T, statPrefix, cluster string) *envoy_api_v2_listener.F
 	}
 }
 
-func staticListener() *v2.Listener {
+func staticListener() *envoy_listener_v3.Listener {
 	return envoy.StatsListener(statsAddress, statsPort)
 }
@@ -19,8 +19,8 @@ package e2e
 import (
 	"testing"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	"github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
 	"github.com/saarasio/enroute/enroute-dp/internal/envoy"
 	v1 "k8s.io/api/core/v1"
@@ -55,11 +55,11 @@ func TestSDSVisibility(t *testing.T) {
 
 	// assert that the secret is _not_ visible as it is
 	// not referenced by any ingress/gatewayhost
-	c.Request(secretType).Equals(&v2.DiscoveryResponse{
-		VersionInfo: "1",
+	c.Request(secretType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "0",
 		Resources:   resources(t),
 		TypeUrl:     secretType,
-		Nonce:       "1",
+		Nonce:       "0",
 	})
 
 	// i1 is a tls ingress
@@ -81,13 +81,13 @@ func TestSDSVisibility(t *testing.T) {
 	// TODO(dfc) #1165: secret should not be present if the ingress does not
 	// have any valid routes.
 	// i1 has a default route to backend:80, but there is no matching service.
-	c.Request(secretType).Equals(&v2.DiscoveryResponse{
-		VersionInfo: "2",
+	c.Request(secretType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "1",
 		Resources: resources(t,
 			secret(s1),
 		),
 		TypeUrl: secretType,
-		Nonce:   "2",
+		Nonce:   "1",
 	})
 }
 
@@ -131,25 +131,25 @@ func TestSDSShouldNotIncrementVersionNumberForUnrelatedSecret(t *testing.T) {
 	}
 	rh.OnAdd(i1)
 
-	c.Request(secretType).Equals(&v2.DiscoveryResponse{
-		VersionInfo: "2",
+	c.Request(secretType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "1",
 		Resources: resources(t,
 			secret(s1),
 		),
 		TypeUrl: secretType,
-		Nonce:   "2",
+		Nonce:   "1",
 	})
 
 	// verify that requesting the same resource without change
 	// does not bump the current version_info.
 
-	c.Request(secretType).Equals(&v2.DiscoveryResponse{
-		VersionInfo: "2",
+	c.Request(secretType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "1",
 		Resources: resources(t,
 			secret(s1),
 		),
 		TypeUrl: secretType,
-		Nonce:   "2",
+		Nonce:   "1",
 	})
 
 	// s2 is not referenced by any active ingress object.
@@ -170,7 +170,7 @@ func TestSDSShouldNotIncrementVersionNumberForUnrelatedSecret(t *testing.T) {
 
 	// TODO(dfc) 1166: currently Contour will rebuild all the xDS tables
 	// when an unrelated secret changes.
-	c.Request(secretType).Equals(&v2.DiscoveryResponse{
+	c.Request(secretType).Equals(&envoy_discovery_v3.DiscoveryResponse{
 		VersionInfo: "2",
 		Resources: resources(t,
 			secret(s1),
@@ -222,15 +222,15 @@ func TestSDSshouldNotPublishInvalidSecret(t *testing.T) {
 	rh.OnAdd(i1)
 
 	// SDS should be empty
-	c.Request(secretType).Equals(&v2.DiscoveryResponse{
-		VersionInfo: "2",
+	c.Request(secretType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		VersionInfo: "0",
 		Resources:   resources(t),
 		TypeUrl:     secretType,
-		Nonce:       "2",
+		Nonce:       "0",
 	})
 }
 
-func secret(sec *v1.Secret) *envoy_api_v2_auth.Secret {
+func secret(sec *v1.Secret) *envoy_tls_v3.Secret {
 	return envoy.Secret(&dag.Secret{
 		Object: sec,
 	})
@@ -19,23 +19,24 @@ package envoy
 import (
 	"testing"
 
-	accesslog_v2 "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v2"
-	envoy_accesslog "github.com/envoyproxy/go-control-plane/envoy/config/filter/accesslog/v2"
+	envoy_accesslog_v3 "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	envoy_file_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/file/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
 )
 
 func TestFileAccessLog(t *testing.T) {
 	tests := map[string]struct {
 		path string
-		want []*envoy_accesslog.AccessLog
+		want []*envoy_accesslog_v3.AccessLog
 	}{
 		"stdout": {
 			path: "/dev/stdout",
-			want: []*envoy_accesslog.AccessLog{{
+			want: []*envoy_accesslog_v3.AccessLog{{
 				Name: wellknown.FileAccessLog,
-				ConfigType: &envoy_accesslog.AccessLog_TypedConfig{
-					TypedConfig: toAny(&accesslog_v2.FileAccessLog{
+				ConfigType: &envoy_accesslog_v3.AccessLog_TypedConfig{
+					TypedConfig: toAny(&envoy_file_v3.FileAccessLog{
 						Path: "/dev/stdout",
 					}),
 				},
@@ -45,7 +46,7 @@ func TestFileAccessLog(t *testing.T) {
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			got := FileAccessLog(tc.path)
-			if diff := cmp.Diff(tc.want, got); diff != "" {
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
 				t.Fatal(diff)
 			}
 		})
@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package envoy
+
+import (
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+)
+
+// ADSConfigSource returns a *envoy_core_v3.ConfigSource which
+// fetches resources over the Aggregated Discovery Service stream.
+func ADSConfigSource() *envoy_core_v3.ConfigSource {
+	return &envoy_core_v3.ConfigSource{
+		ConfigSourceSpecifier: &envoy_core_v3.ConfigSource_Ads{
+			Ads: new(envoy_core_v3.AggregatedConfigSource),
+		},
+		ResourceApiVersion: envoy_core_v3.ApiVersion_V3,
+	}
+}
+
+// ADSResource returns a copy of msg whose EDS, RDS, and SDS config
+// sources are replaced with ADSConfigSource, so an Envoy bootstrapped
+// with ADS fetches them over the same stream as the msg itself.
+// Messages without config sources are returned unchanged.
+func ADSResource(msg proto.Message) proto.Message {
+	switch msg := msg.(type) {
+	case *envoy_cluster_v3.Cluster:
+		if msg.EdsClusterConfig == nil {
+			return msg
+		}
+		c := proto.Clone(msg).(*envoy_cluster_v3.Cluster)
+		c.EdsClusterConfig.EdsConfig = ADSConfigSource()
+		return c
+	case *envoy_listener_v3.Listener:
+		l := proto.Clone(msg).(*envoy_listener_v3.Listener)
+		for _, fc := range l.FilterChains {
+			for _, f := range fc.Filters {
+				adsHTTPConnectionManager(f)
+			}
+			adsDownstreamTLSContext(fc.TransportSocket)
+		}
+		return l
+	default:
+		return msg
+	}
+}
+
+// adsHTTPConnectionManager replaces the RDS config source of an
+// HTTP Connection Manager filter.
+func adsHTTPConnectionManager(f *envoy_listener_v3.Filter) {
+	if f.Name != wellknown.HTTPConnectionManager || f.GetTypedConfig() == nil {
+		return
+	}
+	var hcm http.HttpConnectionManager
+	if err := ptypes.UnmarshalAny(f.GetTypedConfig(), &hcm); err != nil {
+		return
+	}
+	rds := hcm.GetRds()
+	if rds == nil {
+		return
+	}
+	rds.ConfigSource = ADSConfigSource()
+	f.ConfigType = &envoy_listener_v3.Filter_TypedConfig{
+		TypedConfig: toAny(&hcm),
+	}
+}
+
+// adsDownstreamTLSContext replaces the SDS config sources of a
+// downstream TLS transport socket.
+func adsDownstreamTLSContext(ts *envoy_core_v3.TransportSocket) {
+	if ts.GetTypedConfig() == nil {
+		return
+	}
+	var tls envoy_tls_v3.DownstreamTlsContext
+	if err := ptypes.UnmarshalAny(ts.GetTypedConfig(), &tls); err != nil {
+		return
+	}
+	sds := tls.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs()
+	if len(sds) == 0 {
+		return
+	}
+	for _, s := range sds {
+		s.SdsConfig = ADSConfigSource()
+	}
+	ts.ConfigType = &envoy_core_v3.TransportSocket_TypedConfig{
+		TypedConfig: toAny(&tls),
+	}
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package envoy
+
+import (
+	"testing"
+
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/proto"
+	"github.com/saarasio/enroute/enroute-dp/internal/assert"
+)
+
+func TestADSResource(t *testing.T) {
+	httpFilter := func(rds *http.Rds) *envoy_listener_v3.Filter {
+		return &envoy_listener_v3.Filter{
+			Name: wellknown.HTTPConnectionManager,
+			ConfigType: &envoy_listener_v3.Filter_TypedConfig{
+				TypedConfig: toAny(&http.HttpConnectionManager{
+					StatPrefix: rds.RouteConfigName,
+					RouteSpecifier: &http.HttpConnectionManager_Rds{
+						Rds: rds,
+					},
+				}),
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		msg  proto.Message
+		want proto.Message
+	}{
+		"eds cluster": {
+			msg: &envoy_cluster_v3.Cluster{
+				Name:             "default/kuard/80",
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{EdsConfig: ConfigSource("enroute"), ServiceName: "default/kuard"},
+			},
+			want: &envoy_cluster_v3.Cluster{
+				Name:             "default/kuard/80",
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{EdsConfig: ADSConfigSource(), ServiceName: "default/kuard"},
+			},
+		},
+		"static cluster": {
+			msg:  &envoy_cluster_v3.Cluster{Name: "service-stats"},
+			want: &envoy_cluster_v3.Cluster{Name: "service-stats"},
+		},
+		"listener": {
+			msg: &envoy_listener_v3.Listener{
+				Name: "ingress_https",
+				FilterChains: []*envoy_listener_v3.FilterChain{{
+					Filters:         []*envoy_listener_v3.Filter{httpFilter(&http.Rds{RouteConfigName: "https/example.com", ConfigSource: ConfigSource("enroute")})},
+					TransportSocket: DownstreamTLSTransportSocket(DownstreamTLSContext("default/secret/735ad571c1", nil, "h2", "http/1.1")),
+				}},
+			},
+			want: &envoy_listener_v3.Listener{
+				Name: "ingress_https",
+				FilterChains: []*envoy_listener_v3.FilterChain{{
+					Filters: []*envoy_listener_v3.Filter{httpFilter(&http.Rds{RouteConfigName: "https/example.com", ConfigSource: ADSConfigSource()})},
+					TransportSocket: DownstreamTLSTransportSocket(func() *envoy_tls_v3.DownstreamTlsContext {
+						tls := DownstreamTLSContext("default/secret/735ad571c1", nil, "h2", "http/1.1")
+						tls.CommonTlsContext.TlsCertificateSdsSecretConfigs[0].SdsConfig = ADSConfigSource()
+						return tls
+					}()),
+				}},
+			},
+		},
+		"route configuration": {
+			msg:  &envoy_route_v3.RouteConfiguration{Name: "ingress_http"},
+			want: &envoy_route_v3.RouteConfiguration{Name: "ingress_http"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			before := proto.Clone(tc.msg)
+			got := ADSResource(tc.msg)
+			assert.Equal(t, tc.want, got)
+			// the resource held by the cache must not be modified.
+			assert.Equal(t, before, tc.msg)
+		})
+	}
+}
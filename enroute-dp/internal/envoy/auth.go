@@ -17,8 +17,9 @@
 package envoy
 
 import (
-	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
-	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
 )
 
 var (
@@ -47,12 +48,12 @@ var (
 	}
 )
 
-// UpstreamTLSContext creates an envoy_api_v2_auth.UpstreamTlsContext. By default
+// UpstreamTLSContext creates an envoy_tls_v3.UpstreamTlsContext. By default
 // UpstreamTLSContext returns a HTTP/1.1 TLS enabled context. A list of
 // additional ALPN protocols can be provided.
-func UpstreamTLSContext(ca []byte, subjectName string, alpnProtocols ...string) *envoy_api_v2_auth.UpstreamTlsContext {
-	context := &envoy_api_v2_auth.UpstreamTlsContext{
-		CommonTlsContext: &envoy_api_v2_auth.CommonTlsContext{
+func UpstreamTLSContext(ca []byte, subjectName string, alpnProtocols ...string) *envoy_tls_v3.UpstreamTlsContext {
+	context := &envoy_tls_v3.UpstreamTlsContext{
+		CommonTlsContext: &envoy_tls_v3.CommonTlsContext{
 			AlpnProtocols: alpnProtocols,
 		},
 	}
@@ -70,7 +71,7 @@ func UpstreamTLSContext(ca []byte, subjectName string, alpnProtocols ...string)
 	return context
 }
 
-func validationContext(ca []byte, subjectName string) *envoy_api_v2_auth.CommonTlsContext_ValidationContext {
+func validationContext(ca []byte, subjectName string) *envoy_tls_v3.CommonTlsContext_ValidationContext {
 	if len(ca) < 1 {
 		// no ca provided, nothing to do
 		return nil
@@ -81,29 +82,53 @@ func validationContext(ca []byte, subjectName string) *envoy_api_v2_auth.CommonT
 		return nil
 	}
 
-	return &envoy_api_v2_auth.CommonTlsContext_ValidationContext{
-		ValidationContext: &envoy_api_v2_auth.CertificateValidationContext{
-			TrustedCa: &envoy_api_v2_core.DataSource{
+	return &envoy_tls_v3.CommonTlsContext_ValidationContext{
+		ValidationContext: &envoy_tls_v3.CertificateValidationContext{
+			TrustedCa: &envoy_core_v3.DataSource{
 				// TODO(dfc) update this for SDS
-				Specifier: &envoy_api_v2_core.DataSource_InlineBytes{
+				Specifier: &envoy_core_v3.DataSource_InlineBytes{
 					InlineBytes: ca,
 				},
 			},
-			VerifySubjectAltName: []string{subjectName},
+			MatchSubjectAltNames: matchSubjectAltName(subjectName),
 		},
 	}
 }
 
+// matchSubjectAltName returns a StringMatcher which matches exactly name.
+func matchSubjectAltName(name string) []*matcher.StringMatcher {
+	return []*matcher.StringMatcher{{
+		MatchPattern: &matcher.StringMatcher_Exact{
+			Exact: name,
+		},
+	}}
+}
+
+// TLSParams returns the TlsParameters of a DownstreamTlsContext.
+// An unset maximum version defaults to TLS 1.3 and an empty list
+// of cipher suites to the default ciphers. An empty list of ECDH
+// curves leaves the choice of curves to envoy.
+func TLSParams(tlsMinProtoVersion, tlsMaxProtoVersion envoy_tls_v3.TlsParameters_TlsProtocol, cipherSuites, ecdhCurves []string) *envoy_tls_v3.TlsParameters {
+	if tlsMaxProtoVersion == envoy_tls_v3.TlsParameters_TLS_AUTO {
+		tlsMaxProtoVersion = envoy_tls_v3.TlsParameters_TLSv1_3
+	}
+	if len(cipherSuites) == 0 {
+		cipherSuites = ciphers
+	}
+	return &envoy_tls_v3.TlsParameters{
+		TlsMinimumProtocolVersion: tlsMinProtoVersion,
+		TlsMaximumProtocolVersion: tlsMaxProtoVersion,
+		CipherSuites:              cipherSuites,
+		EcdhCurves:                ecdhCurves,
+	}
+}
+
 // DownstreamTLSContext creates a new DownstreamTlsContext.
-func DownstreamTLSContext(secretName string, tlsMinProtoVersion envoy_api_v2_auth.TlsParameters_TlsProtocol, alpnProtos ...string) *envoy_api_v2_auth.DownstreamTlsContext {
-	return &envoy_api_v2_auth.DownstreamTlsContext{
-		CommonTlsContext: &envoy_api_v2_auth.CommonTlsContext{
-			TlsParams: &envoy_api_v2_auth.TlsParameters{
-				TlsMinimumProtocolVersion: tlsMinProtoVersion,
-				TlsMaximumProtocolVersion: envoy_api_v2_auth.TlsParameters_TLSv1_3,
-				CipherSuites:              ciphers,
-			},
-			TlsCertificateSdsSecretConfigs: []*envoy_api_v2_auth.SdsSecretConfig{{
+func DownstreamTLSContext(secretName string, tlsParams *envoy_tls_v3.TlsParameters, alpnProtos ...string) *envoy_tls_v3.DownstreamTlsContext {
+	return &envoy_tls_v3.DownstreamTlsContext{
+		CommonTlsContext: &envoy_tls_v3.CommonTlsContext{
+			TlsParams: tlsParams,
+			TlsCertificateSdsSecretConfigs: []*envoy_tls_v3.SdsSecretConfig{{
 				Name:      secretName,
 				SdsConfig: ConfigSource("enroute"),
 			}},
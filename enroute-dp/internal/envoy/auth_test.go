@@ -19,9 +19,10 @@ package envoy
 import (
 	"testing"
 
-	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
-	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
 )
 
 func TestUpstreamTLSContext(t *testing.T) {
@@ -29,46 +30,46 @@ func TestUpstreamTLSContext(t *testing.T) {
 		ca            []byte
 		subjectName   string
 		alpnProtocols []string
-		want          *envoy_api_v2_auth.UpstreamTlsContext
+		want          *envoy_tls_v3.UpstreamTlsContext
 	}{
 		"no alpn, no validation": {
-			want: &envoy_api_v2_auth.UpstreamTlsContext{
-				CommonTlsContext: &envoy_api_v2_auth.CommonTlsContext{},
+			want: &envoy_tls_v3.UpstreamTlsContext{
+				CommonTlsContext: &envoy_tls_v3.CommonTlsContext{},
 			},
 		},
 		"h2, no validation": {
 			alpnProtocols: []string{"h2c"},
-			want: &envoy_api_v2_auth.UpstreamTlsContext{
-				CommonTlsContext: &envoy_api_v2_auth.CommonTlsContext{
+			want: &envoy_tls_v3.UpstreamTlsContext{
+				CommonTlsContext: &envoy_tls_v3.CommonTlsContext{
 					AlpnProtocols: []string{"h2c"},
 				},
 			},
 		},
 		"no alpn, missing altname": {
 			ca: []byte("ca"),
-			want: &envoy_api_v2_auth.UpstreamTlsContext{
-				CommonTlsContext: &envoy_api_v2_auth.CommonTlsContext{},
+			want: &envoy_tls_v3.UpstreamTlsContext{
+				CommonTlsContext: &envoy_tls_v3.CommonTlsContext{},
 			},
 		},
 		"no alpn, missing ca": {
 			subjectName: "www.example.com",
-			want: &envoy_api_v2_auth.UpstreamTlsContext{
-				CommonTlsContext: &envoy_api_v2_auth.CommonTlsContext{},
+			want: &envoy_tls_v3.UpstreamTlsContext{
+				CommonTlsContext: &envoy_tls_v3.CommonTlsContext{},
 			},
 		},
 		"no alpn, ca and altname": {
 			ca:          []byte("ca"),
 			subjectName: "www.example.com",
-			want: &envoy_api_v2_auth.UpstreamTlsContext{
-				CommonTlsContext: &envoy_api_v2_auth.CommonTlsContext{
-					ValidationContextType: &envoy_api_v2_auth.CommonTlsContext_ValidationContext{
-						ValidationContext: &envoy_api_v2_auth.CertificateValidationContext{
-							TrustedCa: &envoy_api_v2_core.DataSource{
-								Specifier: &envoy_api_v2_core.DataSource_InlineBytes{
+			want: &envoy_tls_v3.UpstreamTlsContext{
+				CommonTlsContext: &envoy_tls_v3.CommonTlsContext{
+					ValidationContextType: &envoy_tls_v3.CommonTlsContext_ValidationContext{
+						ValidationContext: &envoy_tls_v3.CertificateValidationContext{
+							TrustedCa: &envoy_core_v3.DataSource{
+								Specifier: &envoy_core_v3.DataSource_InlineBytes{
 									InlineBytes: []byte("ca"),
 								},
 							},
-							VerifySubjectAltName: []string{"www.example.com"},
+							MatchSubjectAltNames: matchSubjectAltName("www.example.com"),
 						},
 					},
 				},
@@ -79,7 +80,46 @@ func TestUpstreamTLSContext(t *testing.T) {
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			got := UpstreamTLSContext(tc.ca, tc.subjectName, tc.alpnProtocols...)
-			if diff := cmp.Diff(tc.want, got); diff != "" {
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestTLSParams(t *testing.T) {
+	tests := map[string]struct {
+		min, max     envoy_tls_v3.TlsParameters_TlsProtocol
+		cipherSuites []string
+		ecdhCurves   []string
+		want         *envoy_tls_v3.TlsParameters
+	}{
+		"defaults": {
+			min: envoy_tls_v3.TlsParameters_TLSv1_1,
+			want: &envoy_tls_v3.TlsParameters{
+				TlsMinimumProtocolVersion: envoy_tls_v3.TlsParameters_TLSv1_1,
+				TlsMaximumProtocolVersion: envoy_tls_v3.TlsParameters_TLSv1_3,
+				CipherSuites:              ciphers,
+			},
+		},
+		"max version, ciphers and curves": {
+			min:          envoy_tls_v3.TlsParameters_TLSv1_2,
+			max:          envoy_tls_v3.TlsParameters_TLSv1_2,
+			cipherSuites: []string{"ECDHE-RSA-AES256-GCM-SHA384"},
+			ecdhCurves:   []string{"X25519", "P-256"},
+			want: &envoy_tls_v3.TlsParameters{
+				TlsMinimumProtocolVersion: envoy_tls_v3.TlsParameters_TLSv1_2,
+				TlsMaximumProtocolVersion: envoy_tls_v3.TlsParameters_TLSv1_2,
+				CipherSuites:              []string{"ECDHE-RSA-AES256-GCM-SHA384"},
+				EcdhCurves:                []string{"X25519", "P-256"},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := TLSParams(tc.min, tc.max, tc.cipherSuites, tc.ecdhCurves)
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
 				t.Fatal(diff)
 			}
 		})
@@ -22,19 +22,19 @@ import (
 	"strings"
 	"time"
 
-	api "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
-	clusterv2 "github.com/envoyproxy/go-control-plane/envoy/api/v2/cluster"
-	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
-	bootstrap "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v2"
+	bootstrap "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	"github.com/saarasio/enroute/enroute-dp/internal/protobuf"
 )
 
 //func RateLimitConfig(c *BootstrapConfig) *ratelimit.RateLimitServiceConfig {
 //    rls := ratelimit.RateLimitServiceConfig{
-//        GrpcService: &envoy_api_v2_core.GrpcService{
-//            TargetSpecifier: &envoy_api_v2_core.GrpcService_EnvoyGrpc_{
-//                EnvoyGrpc: &envoy_api_v2_core.GrpcService_EnvoyGrpc{
+//        GrpcService: &envoy_core_v3.GrpcService{
+//            TargetSpecifier: &envoy_core_v3.GrpcService_EnvoyGrpc_{
+//                EnvoyGrpc: &envoy_core_v3.GrpcService_EnvoyGrpc{
 //                    ClusterName: "enroute",
 //                },
 //            },
@@ -51,28 +51,28 @@ func Bootstrap(c *BootstrapConfig) *bootstrap.Bootstrap {
 			CdsConfig: ConfigSource("enroute"),
 		},
 		StaticResources: &bootstrap.Bootstrap_StaticResources{
-			Clusters: []*api.Cluster{{
+			Clusters: []*envoy_cluster_v3.Cluster{{
 				Name:                 "enroute",
 				AltStatName:          strings.Join([]string{c.Namespace, "enroute", strconv.Itoa(intOrDefault(c.XDSGRPCPort, 8001))}, "_"),
 				ConnectTimeout:       protobuf.Duration(5 * time.Second),
-				ClusterDiscoveryType: ClusterDiscoveryType(api.Cluster_STRICT_DNS),
-				LbPolicy:             api.Cluster_ROUND_ROBIN,
-				LoadAssignment: &api.ClusterLoadAssignment{
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_STRICT_DNS),
+				LbPolicy:             envoy_cluster_v3.Cluster_ROUND_ROBIN,
+				LoadAssignment: &envoy_endpoint_v3.ClusterLoadAssignment{
 					ClusterName: "enroute",
 					Endpoints: Endpoints(
 						SocketAddress(stringOrDefault(c.XDSAddress, "127.0.0.1"), intOrDefault(c.XDSGRPCPort, 8001)),
 					),
 				},
-				Http2ProtocolOptions: new(envoy_api_v2_core.Http2ProtocolOptions), // enables http2
-				CircuitBreakers: &clusterv2.CircuitBreakers{
-					Thresholds: []*clusterv2.CircuitBreakers_Thresholds{{
-						Priority:           envoy_api_v2_core.RoutingPriority_HIGH,
+				Http2ProtocolOptions: new(envoy_core_v3.Http2ProtocolOptions), // enables http2
+				CircuitBreakers: &envoy_cluster_v3.CircuitBreakers{
+					Thresholds: []*envoy_cluster_v3.CircuitBreakers_Thresholds{{
+						Priority:           envoy_core_v3.RoutingPriority_HIGH,
 						MaxConnections:     protobuf.UInt32(100000),
 						MaxPendingRequests: protobuf.UInt32(100000),
 						MaxRequests:        protobuf.UInt32(60000000),
 						MaxRetries:         protobuf.UInt32(50),
 					}, {
-						Priority:           envoy_api_v2_core.RoutingPriority_DEFAULT,
+						Priority:           envoy_core_v3.RoutingPriority_DEFAULT,
 						MaxConnections:     protobuf.UInt32(100000),
 						MaxPendingRequests: protobuf.UInt32(100000),
 						MaxRequests:        protobuf.UInt32(60000000),
@@ -84,24 +84,24 @@ func Bootstrap(c *BootstrapConfig) *bootstrap.Bootstrap {
 					Name:                 "enroute_ratelimit",
 					AltStatName:          strings.Join([]string{c.Namespace, "enroute", strconv.Itoa(intOrDefault(c.RLPort, 8003))}, "_"),
 					ConnectTimeout:       protobuf.Duration(5 * time.Second),
-					ClusterDiscoveryType: ClusterDiscoveryType(api.Cluster_STRICT_DNS),
-					LbPolicy:             api.Cluster_ROUND_ROBIN,
-					LoadAssignment: &api.ClusterLoadAssignment{
+					ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_STRICT_DNS),
+					LbPolicy:             envoy_cluster_v3.Cluster_ROUND_ROBIN,
+					LoadAssignment: &envoy_endpoint_v3.ClusterLoadAssignment{
 						ClusterName: "enroute_ratelimit",
 						Endpoints: Endpoints(
 							SocketAddress(stringOrDefault(c.RLAddress, "127.0.0.1"), intOrDefault(c.RLPort, 8003)),
 						),
 					},
-					Http2ProtocolOptions: new(envoy_api_v2_core.Http2ProtocolOptions), // enables http2
-					CircuitBreakers: &clusterv2.CircuitBreakers{
-						Thresholds: []*clusterv2.CircuitBreakers_Thresholds{{
-							Priority:           envoy_api_v2_core.RoutingPriority_HIGH,
+					Http2ProtocolOptions: new(envoy_core_v3.Http2ProtocolOptions), // enables http2
+					CircuitBreakers: &envoy_cluster_v3.CircuitBreakers{
+						Thresholds: []*envoy_cluster_v3.CircuitBreakers_Thresholds{{
+							Priority:           envoy_core_v3.RoutingPriority_HIGH,
 							MaxConnections:     protobuf.UInt32(100000),
 							MaxPendingRequests: protobuf.UInt32(100000),
 							MaxRequests:        protobuf.UInt32(60000000),
 							MaxRetries:         protobuf.UInt32(50),
 						}, {
-							Priority:           envoy_api_v2_core.RoutingPriority_DEFAULT,
+							Priority:           envoy_core_v3.RoutingPriority_DEFAULT,
 							MaxConnections:     protobuf.UInt32(100000),
 							MaxPendingRequests: protobuf.UInt32(100000),
 							MaxRequests:        protobuf.UInt32(60000000),
@@ -113,9 +113,9 @@ func Bootstrap(c *BootstrapConfig) *bootstrap.Bootstrap {
 					Name:                 "service-stats",
 					AltStatName:          strings.Join([]string{c.Namespace, "service-stats", strconv.Itoa(intOrDefault(c.AdminPort, 9001))}, "_"),
 					ConnectTimeout:       protobuf.Duration(250 * time.Millisecond),
-					ClusterDiscoveryType: ClusterDiscoveryType(api.Cluster_LOGICAL_DNS),
-					LbPolicy:             api.Cluster_ROUND_ROBIN,
-					LoadAssignment: &api.ClusterLoadAssignment{
+					ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_LOGICAL_DNS),
+					LbPolicy:             envoy_cluster_v3.Cluster_ROUND_ROBIN,
+					LoadAssignment: &envoy_endpoint_v3.ClusterLoadAssignment{
 						ClusterName: "service-stats",
 						Endpoints: Endpoints(
 							SocketAddress(stringOrDefault(c.AdminAddress, "127.0.0.1"), intOrDefault(c.AdminPort, 9001)),
@@ -129,6 +129,18 @@ func Bootstrap(c *BootstrapConfig) *bootstrap.Bootstrap {
 		},
 	}
 
+	if c.ProxyName != "" {
+		b.Node = &envoy_core_v3.Node{Cluster: c.ProxyName}
+	}
+
+	if c.ADS {
+		b.DynamicResources = &bootstrap.Bootstrap_DynamicResources{
+			AdsConfig: ConfigSource("enroute").GetApiConfigSource(),
+			LdsConfig: ADSConfigSource(),
+			CdsConfig: ADSConfigSource(),
+		}
+	}
+
 	if c.GrpcClientCert != "" || c.GrpcClientKey != "" || c.GrpcCABundle != "" {
 		// If one of the two TLS options is not empty, they all must be not empty
 		if !(c.GrpcClientCert != "" && c.GrpcClientKey != "" && c.GrpcCABundle != "") {
@@ -142,7 +154,7 @@ func Bootstrap(c *BootstrapConfig) *bootstrap.Bootstrap {
 	return b
 }
 
-func upstreamFileTLSContext(cafile, certfile, keyfile string) *envoy_api_v2_auth.UpstreamTlsContext {
+func upstreamFileTLSContext(cafile, certfile, keyfile string) *envoy_tls_v3.UpstreamTlsContext {
 	if certfile == "" {
 		// Nothig to do
 		return nil
@@ -157,31 +169,31 @@ func upstreamFileTLSContext(cafile, certfile, keyfile string) *envoy_api_v2_auth
 		// You currently must supply a CA file, not just use others.
 		return nil
 	}
-	context := &envoy_api_v2_auth.UpstreamTlsContext{
-		CommonTlsContext: &envoy_api_v2_auth.CommonTlsContext{
-			TlsCertificates: []*envoy_api_v2_auth.TlsCertificate{
+	context := &envoy_tls_v3.UpstreamTlsContext{
+		CommonTlsContext: &envoy_tls_v3.CommonTlsContext{
+			TlsCertificates: []*envoy_tls_v3.TlsCertificate{
 				{
-					CertificateChain: &envoy_api_v2_core.DataSource{
-						Specifier: &envoy_api_v2_core.DataSource_Filename{
+					CertificateChain: &envoy_core_v3.DataSource{
+						Specifier: &envoy_core_v3.DataSource_Filename{
 							Filename: certfile,
 						},
 					},
-					PrivateKey: &envoy_api_v2_core.DataSource{
-						Specifier: &envoy_api_v2_core.DataSource_Filename{
+					PrivateKey: &envoy_core_v3.DataSource{
+						Specifier: &envoy_core_v3.DataSource_Filename{
 							Filename: keyfile,
 						},
 					},
 				},
 			},
-			ValidationContextType: &envoy_api_v2_auth.CommonTlsContext_ValidationContext{
-				ValidationContext: &envoy_api_v2_auth.CertificateValidationContext{
-					TrustedCa: &envoy_api_v2_core.DataSource{
-						Specifier: &envoy_api_v2_core.DataSource_Filename{
+			ValidationContextType: &envoy_tls_v3.CommonTlsContext_ValidationContext{
+				ValidationContext: &envoy_tls_v3.CertificateValidationContext{
+					TrustedCa: &envoy_core_v3.DataSource{
+						Specifier: &envoy_core_v3.DataSource_Filename{
 							Filename: cafile,
 						},
 					},
 					// TODO(youngnick): Does there need to be a flag wired down to here?
-					VerifySubjectAltName: []string{"enroute"},
+					MatchSubjectAltNames: matchSubjectAltName("enroute"),
 				},
 			},
 		},
@@ -243,4 +255,13 @@ type BootstrapConfig struct {
 
 	// GrpcClientKey is the filename that contains a client key for secure gRPC with TLS.
 	GrpcClientKey string
+
+	// ADS configures Envoy to fetch all resources over a single
+	// Aggregated Discovery Service stream.
+	ADS bool
+
+	// ProxyName is the name of the proxy Envoy serves. It is sent to
+	// enroute as the cluster of the Envoy node, and selects the
+	// configuration Envoy is served.
+	ProxyName string
 }
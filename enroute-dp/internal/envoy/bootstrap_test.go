@@ -19,7 +19,7 @@ package envoy
 import (
 	"testing"
 
-	bootstrap "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v2"
+	bootstrap "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"github.com/saarasio/enroute/enroute-dp/internal/assert"
@@ -152,6 +152,7 @@ func TestBootstrap(t *testing.T) {
     "lds_config": {
       "api_config_source": {
         "api_type": "GRPC",
+        "transport_api_version": "V3",
         "grpc_services": [
           {
             "envoy_grpc": {
@@ -159,11 +160,13 @@ func TestBootstrap(t *testing.T) {
             }
           }
         ]
-      }
+      },
+      "resource_api_version": "V3"
     },
     "cds_config": {
       "api_config_source": {
         "api_type": "GRPC",
+        "transport_api_version": "V3",
         "grpc_services": [
           {
             "envoy_grpc": {
@@ -171,7 +174,8 @@ func TestBootstrap(t *testing.T) {
             }
           }
         ]
-      }
+      },
+      "resource_api_version": "V3"
     }
   },
   "admin": {
@@ -311,6 +315,7 @@ func TestBootstrap(t *testing.T) {
     "lds_config": {
       "api_config_source": {
         "api_type": "GRPC",
+        "transport_api_version": "V3",
         "grpc_services": [
           {
             "envoy_grpc": {
@@ -318,11 +323,13 @@ func TestBootstrap(t *testing.T) {
             }
           }
         ]
-      }
+      },
+      "resource_api_version": "V3"
     },
     "cds_config": {
       "api_config_source": {
         "api_type": "GRPC",
+        "transport_api_version": "V3",
         "grpc_services": [
           {
             "envoy_grpc": {
@@ -330,7 +337,8 @@ func TestBootstrap(t *testing.T) {
             }
           }
         ]
-      }
+      },
+      "resource_api_version": "V3"
     }
   },
   "admin": {
@@ -471,6 +479,7 @@ func TestBootstrap(t *testing.T) {
     "lds_config": {
       "api_config_source": {
         "api_type": "GRPC",
+        "transport_api_version": "V3",
         "grpc_services": [
           {
             "envoy_grpc": {
@@ -478,11 +487,13 @@ func TestBootstrap(t *testing.T) {
             }
           }
         ]
-      }
+      },
+      "resource_api_version": "V3"
     },
     "cds_config": {
       "api_config_source": {
         "api_type": "GRPC",
+        "transport_api_version": "V3",
         "grpc_services": [
           {
             "envoy_grpc": {
@@ -490,7 +501,8 @@ func TestBootstrap(t *testing.T) {
             }
           }
         ]
-      }
+      },
+      "resource_api_version": "V3"
     }
   },
   "admin": {
@@ -633,6 +645,7 @@ func TestBootstrap(t *testing.T) {
     "lds_config": {
       "api_config_source": {
         "api_type": "GRPC",
+        "transport_api_version": "V3",
         "grpc_services": [
           {
             "envoy_grpc": {
@@ -640,11 +653,13 @@ func TestBootstrap(t *testing.T) {
             }
           }
         ]
-      }
+      },
+      "resource_api_version": "V3"
     },
     "cds_config": {
       "api_config_source": {
         "api_type": "GRPC",
+        "transport_api_version": "V3",
         "grpc_services": [
           {
             "envoy_grpc": {
@@ -652,7 +667,8 @@ func TestBootstrap(t *testing.T) {
             }
           }
         ]
-      }
+      },
+      "resource_api_version": "V3"
     }
   },
   "admin": {
@@ -793,6 +809,7 @@ func TestBootstrap(t *testing.T) {
     "lds_config": {
       "api_config_source": {
         "api_type": "GRPC",
+        "transport_api_version": "V3",
         "grpc_services": [
           {
             "envoy_grpc": {
@@ -800,11 +817,13 @@ func TestBootstrap(t *testing.T) {
             }
           }
         ]
-      }
+      },
+      "resource_api_version": "V3"
     },
     "cds_config": {
       "api_config_source": {
         "api_type": "GRPC",
+        "transport_api_version": "V3",
         "grpc_services": [
           {
             "envoy_grpc": {
@@ -812,7 +831,8 @@ func TestBootstrap(t *testing.T) {
             }
           }
         ]
-      }
+      },
+      "resource_api_version": "V3"
     }
   },
   "admin": {
@@ -881,7 +901,7 @@ func TestBootstrap(t *testing.T) {
         "transport_socket": {
           "name":"tls",
           "typed_config": {
-            "@type":"type.googleapis.com/envoy.api.v2.auth.UpstreamTlsContext",
+            "@type":"type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.UpstreamTlsContext",
             "common_tls_context": {
               "tls_certificates": [
                 {
@@ -897,8 +917,10 @@ func TestBootstrap(t *testing.T) {
                 "trusted_ca": {
                   "filename": "CA.cert"
                 },
-                "verify_subject_alt_name": [
-                  "enroute"
+                "match_subject_alt_names": [
+                  {
+                    "exact": "enroute"
+                  }
                 ]
               }
             }
@@ -980,6 +1002,7 @@ func TestBootstrap(t *testing.T) {
     "lds_config": {
       "api_config_source": {
         "api_type": "GRPC",
+        "transport_api_version": "V3",
         "grpc_services": [
           {
             "envoy_grpc": {
@@ -987,11 +1010,13 @@ func TestBootstrap(t *testing.T) {
             }
           }
         ]
-      }
+      },
+      "resource_api_version": "V3"
     },
     "cds_config": {
       "api_config_source": {
         "api_type": "GRPC",
+        "transport_api_version": "V3",
         "grpc_services": [
           {
             "envoy_grpc": {
@@ -999,8 +1024,321 @@ func TestBootstrap(t *testing.T) {
             }
           }
         ]
+      },
+      "resource_api_version": "V3"
+    }
+  },
+  "admin": {
+    "access_log_path": "/dev/null",
+    "address": {
+      "socket_address": {
+        "address": "127.0.0.1",
+        "port_value": 9001
       }
     }
+  }
+}`,
+		},
+		"--ads": {
+			config: BootstrapConfig{Namespace: "testing-ns", ADS: true},
+			want: `{
+  "static_resources": {
+    "clusters": [
+      {
+        "name": "enroute",
+        "alt_stat_name": "testing-ns_enroute_8001",
+        "type": "STRICT_DNS",
+        "connect_timeout": "5s",
+        "load_assignment": {
+          "cluster_name": "enroute",
+          "endpoints": [
+            {
+              "lb_endpoints": [
+                {
+                  "endpoint": {
+                    "address": {
+                      "socket_address": {
+                        "address": "127.0.0.1",
+                        "port_value": 8001
+                      }
+                    }
+                  }
+                }
+              ]
+            }
+          ]
+        },
+        "circuit_breakers": {
+          "thresholds": [
+            {
+              "priority": "HIGH",
+              "max_connections": 100000,
+              "max_pending_requests": 100000,
+              "max_requests": 60000000,
+              "max_retries": 50
+            },
+            {
+              "max_connections": 100000,
+              "max_pending_requests": 100000,
+              "max_requests": 60000000,
+              "max_retries": 50
+            }
+          ]
+        },
+        "http2_protocol_options": {}
+      },
+      {
+        "name": "enroute_ratelimit",
+        "alt_stat_name": "testing-ns_enroute_8003",
+        "type": "STRICT_DNS",
+        "connect_timeout": "5s",
+        "load_assignment": {
+          "cluster_name": "enroute_ratelimit",
+          "endpoints": [
+            {
+              "lb_endpoints": [
+                {
+                  "endpoint": {
+                    "address": {
+                      "socket_address": {
+                        "address": "127.0.0.1",
+                        "port_value": 8003
+                      }
+                    }
+                  }
+                }
+              ]
+            }
+          ]
+        },
+        "circuit_breakers": {
+          "thresholds": [
+            {
+              "priority": "HIGH",
+              "max_connections": 100000,
+              "max_pending_requests": 100000,
+              "max_requests": 60000000,
+              "max_retries": 50
+            },
+            {
+              "max_connections": 100000,
+              "max_pending_requests": 100000,
+              "max_requests": 60000000,
+              "max_retries": 50
+            }
+          ]
+        },
+        "http2_protocol_options": {}
+      },
+      {
+        "name": "service-stats",
+        "alt_stat_name": "testing-ns_service-stats_9001",
+        "type": "LOGICAL_DNS",
+        "connect_timeout": "0.250s",
+        "load_assignment": {
+          "cluster_name": "service-stats",
+          "endpoints": [   
+            {                          
+              "lb_endpoints": [
+                {
+                  "endpoint": {
+                    "address": {
+                      "socket_address": {
+                        "address": "127.0.0.1",
+                        "port_value": 9001
+                      }    
+                    }     
+                  }
+                }          
+              ]                        
+            }
+          ]
+        }
+      }
+    ]
+  },
+  "dynamic_resources": {
+    "lds_config": {
+      "ads": {},
+      "resource_api_version": "V3"
+    },
+    "cds_config": {
+      "ads": {},
+      "resource_api_version": "V3"
+    },
+    "ads_config": {
+      "api_type": "GRPC",
+      "transport_api_version": "V3",
+      "grpc_services": [
+        {
+          "envoy_grpc": {
+            "cluster_name": "enroute"
+          }
+        }
+      ]
+    }
+  },
+  "admin": {
+    "access_log_path": "/dev/null",
+    "address": {
+      "socket_address": {
+        "address": "127.0.0.1",
+        "port_value": 9001
+      }
+    }
+  }
+}`,
+		},
+		"--proxy-name=gw1": {
+			config: BootstrapConfig{Namespace: "testing-ns", ProxyName: "gw1"},
+			want: `{
+  "node": {
+    "cluster": "gw1"
+  },
+  "static_resources": {
+    "clusters": [
+      {
+        "name": "enroute",
+        "alt_stat_name": "testing-ns_enroute_8001",
+        "type": "STRICT_DNS",
+        "connect_timeout": "5s",
+        "load_assignment": {
+          "cluster_name": "enroute",
+          "endpoints": [
+            {
+              "lb_endpoints": [
+                {
+                  "endpoint": {
+                    "address": {
+                      "socket_address": {
+                        "address": "127.0.0.1",
+                        "port_value": 8001
+                      }
+                    }
+                  }
+                }
+              ]
+            }
+          ]
+        },
+        "circuit_breakers": {
+          "thresholds": [
+            {
+              "priority": "HIGH",
+              "max_connections": 100000,
+              "max_pending_requests": 100000,
+              "max_requests": 60000000,
+              "max_retries": 50
+            },
+            {
+              "max_connections": 100000,
+              "max_pending_requests": 100000,
+              "max_requests": 60000000,
+              "max_retries": 50
+            }
+          ]
+        },
+        "http2_protocol_options": {}
+      },
+      {
+        "name": "enroute_ratelimit",
+        "alt_stat_name": "testing-ns_enroute_8003",
+        "type": "STRICT_DNS",
+        "connect_timeout": "5s",
+        "load_assignment": {
+          "cluster_name": "enroute_ratelimit",
+          "endpoints": [
+            {
+              "lb_endpoints": [
+                {
+                  "endpoint": {
+                    "address": {
+                      "socket_address": {
+                        "address": "127.0.0.1",
+                        "port_value": 8003
+                      }
+                    }
+                  }
+                }
+              ]
+            }
+          ]
+        },
+        "circuit_breakers": {
+          "thresholds": [
+            {
+              "priority": "HIGH",
+              "max_connections": 100000,
+              "max_pending_requests": 100000,
+              "max_requests": 60000000,
+              "max_retries": 50
+            },
+            {
+              "max_connections": 100000,
+              "max_pending_requests": 100000,
+              "max_requests": 60000000,
+              "max_retries": 50
+            }
+          ]
+        },
+        "http2_protocol_options": {}
+      },
+      {
+        "name": "service-stats",
+        "alt_stat_name": "testing-ns_service-stats_9001",
+        "type": "LOGICAL_DNS",
+        "connect_timeout": "0.250s",
+        "load_assignment": {
+          "cluster_name": "service-stats",
+          "endpoints": [   
+            {                          
+              "lb_endpoints": [
+                {
+                  "endpoint": {
+                    "address": {
+                      "socket_address": {
+                        "address": "127.0.0.1",
+                        "port_value": 9001
+                      }    
+                    }     
+                  }
+                }          
+              ]                        
+            }
+          ]
+        }
+      }
+    ]
+  },
+  "dynamic_resources": {
+    "lds_config": {
+      "api_config_source": {
+        "api_type": "GRPC",
+        "transport_api_version": "V3",
+        "grpc_services": [
+          {
+            "envoy_grpc": {
+              "cluster_name": "enroute"
+            }
+          }
+        ]
+      },
+      "resource_api_version": "V3"
+    },
+    "cds_config": {
+      "api_config_source": {
+        "api_type": "GRPC",
+        "transport_api_version": "V3",
+        "grpc_services": [
+          {
+            "envoy_grpc": {
+              "cluster_name": "enroute"
+            }
+          }
+        ]
+      },
+      "resource_api_version": "V3"
+    }
   },
   "admin": {
     "access_log_path": "/dev/null",
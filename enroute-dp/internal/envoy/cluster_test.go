@@ -20,15 +20,15 @@ import (
 	"testing"
 	"time"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	envoy_cluster "github.com/envoyproxy/go-control-plane/envoy/api/v2/cluster"
-	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
-	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type"
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_type_v3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/google/go-cmp/cmp"
 	gatewayhostv1 "github.com/saarasio/enroute/enroute-dp/apis/enroute/v1beta1"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
 	"github.com/saarasio/enroute/enroute-dp/internal/protobuf"
+	"google.golang.org/protobuf/testing/protocmp"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -68,7 +68,7 @@ func TestCluster(t *testing.T) {
 
 	tests := map[string]struct {
 		cluster *dag.Cluster
-		want    *v2.Cluster
+		want    *envoy_cluster_v3.Cluster
 	}{
 		"simple service": {
 			cluster: &dag.Cluster{
@@ -76,16 +76,16 @@ func TestCluster(t *testing.T) {
 					TCPService: service(s1),
 				},
 			},
-			want: &v2.Cluster{
+			want: &envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/443/da39a3ee5e",
 				AltStatName:          "default_kuard_443",
-				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   ConfigSource("enroute"),
 					ServiceName: "default/kuard/http",
 				},
 				ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:       v2.Cluster_ROUND_ROBIN,
+				LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 				CommonLbConfig: ClusterCommonLBConfig(),
 			},
 		},
@@ -96,17 +96,17 @@ func TestCluster(t *testing.T) {
 					Protocol:   "h2c",
 				},
 			},
-			want: &v2.Cluster{
+			want: &envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/443/da39a3ee5e",
 				AltStatName:          "default_kuard_443",
-				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   ConfigSource("enroute"),
 					ServiceName: "default/kuard/http",
 				},
 				ConnectTimeout:       protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:             v2.Cluster_ROUND_ROBIN,
-				Http2ProtocolOptions: &envoy_api_v2_core.Http2ProtocolOptions{},
+				LbPolicy:             envoy_cluster_v3.Cluster_ROUND_ROBIN,
+				Http2ProtocolOptions: &envoy_core_v3.Http2ProtocolOptions{},
 				CommonLbConfig:       ClusterCommonLBConfig(),
 			},
 		},
@@ -117,20 +117,20 @@ func TestCluster(t *testing.T) {
 					Protocol:   "h2",
 				},
 			},
-			want: &v2.Cluster{
+			want: &envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/443/da39a3ee5e",
 				AltStatName:          "default_kuard_443",
-				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   ConfigSource("enroute"),
 					ServiceName: "default/kuard/http",
 				},
 				ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:       v2.Cluster_ROUND_ROBIN,
+				LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 				TransportSocket: UpstreamTLSTransportSocket(
 					UpstreamTLSContext(nil, "", "h2"),
 				),
-				Http2ProtocolOptions: &envoy_api_v2_core.Http2ProtocolOptions{},
+				Http2ProtocolOptions: &envoy_core_v3.Http2ProtocolOptions{},
 				CommonLbConfig:       ClusterCommonLBConfig(),
 			},
 		},
@@ -140,13 +140,13 @@ func TestCluster(t *testing.T) {
 					TCPService: *externalnameservice(s2),
 				},
 			},
-			want: &v2.Cluster{
+			want: &envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/443/da39a3ee5e",
 				AltStatName:          "default_kuard_443",
-				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_STRICT_DNS),
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_STRICT_DNS),
 				LoadAssignment:       StaticClusterLoadAssignment(externalnameservice(s2)),
 				ConnectTimeout:       protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:             v2.Cluster_ROUND_ROBIN,
+				LbPolicy:             envoy_cluster_v3.Cluster_ROUND_ROBIN,
 				CommonLbConfig:       ClusterCommonLBConfig(),
 			},
 		},
@@ -157,16 +157,16 @@ func TestCluster(t *testing.T) {
 					Protocol:   "tls",
 				},
 			},
-			want: &v2.Cluster{
+			want: &envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/443/da39a3ee5e",
 				AltStatName:          "default_kuard_443",
-				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   ConfigSource("enroute"),
 					ServiceName: "default/kuard/http",
 				},
 				ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:       v2.Cluster_ROUND_ROBIN,
+				LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 				TransportSocket: UpstreamTLSTransportSocket(
 					UpstreamTLSContext(nil, ""),
 				),
@@ -194,16 +194,16 @@ func TestCluster(t *testing.T) {
 					SubjectName: "foo.bar.io",
 				},
 			},
-			want: &v2.Cluster{
+			want: &envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/443/3ac4e90987",
 				AltStatName:          "default_kuard_443",
-				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   ConfigSource("enroute"),
 					ServiceName: "default/kuard/http",
 				},
 				ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:       v2.Cluster_ROUND_ROBIN,
+				LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 				TransportSocket: UpstreamTLSTransportSocket(
 					UpstreamTLSContext([]byte("cacert"), "foo.bar.io"),
 				),
@@ -220,18 +220,18 @@ func TestCluster(t *testing.T) {
 					},
 				},
 			},
-			want: &v2.Cluster{
+			want: &envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/443/da39a3ee5e",
 				AltStatName:          "default_kuard_443",
-				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   ConfigSource("enroute"),
 					ServiceName: "default/kuard/http",
 				},
 				ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:       v2.Cluster_ROUND_ROBIN,
-				CircuitBreakers: &envoy_cluster.CircuitBreakers{
-					Thresholds: []*envoy_cluster.CircuitBreakers_Thresholds{{
+				LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
+				CircuitBreakers: &envoy_cluster_v3.CircuitBreakers{
+					Thresholds: []*envoy_cluster_v3.CircuitBreakers_Thresholds{{
 						MaxConnections: protobuf.UInt32(9000),
 					}},
 				},
@@ -248,18 +248,18 @@ func TestCluster(t *testing.T) {
 					},
 				},
 			},
-			want: &v2.Cluster{
+			want: &envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/443/da39a3ee5e",
 				AltStatName:          "default_kuard_443",
-				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   ConfigSource("enroute"),
 					ServiceName: "default/kuard/http",
 				},
 				ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:       v2.Cluster_ROUND_ROBIN,
-				CircuitBreakers: &envoy_cluster.CircuitBreakers{
-					Thresholds: []*envoy_cluster.CircuitBreakers_Thresholds{{
+				LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
+				CircuitBreakers: &envoy_cluster_v3.CircuitBreakers{
+					Thresholds: []*envoy_cluster_v3.CircuitBreakers_Thresholds{{
 						MaxPendingRequests: protobuf.UInt32(4096),
 					}},
 				},
@@ -276,18 +276,18 @@ func TestCluster(t *testing.T) {
 					},
 				},
 			},
-			want: &v2.Cluster{
+			want: &envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/443/da39a3ee5e",
 				AltStatName:          "default_kuard_443",
-				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   ConfigSource("enroute"),
 					ServiceName: "default/kuard/http",
 				},
 				ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:       v2.Cluster_ROUND_ROBIN,
-				CircuitBreakers: &envoy_cluster.CircuitBreakers{
-					Thresholds: []*envoy_cluster.CircuitBreakers_Thresholds{{
+				LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
+				CircuitBreakers: &envoy_cluster_v3.CircuitBreakers{
+					Thresholds: []*envoy_cluster_v3.CircuitBreakers_Thresholds{{
 						MaxRequests: protobuf.UInt32(404),
 					}},
 				},
@@ -304,18 +304,18 @@ func TestCluster(t *testing.T) {
 					},
 				},
 			},
-			want: &v2.Cluster{
+			want: &envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/443/da39a3ee5e",
 				AltStatName:          "default_kuard_443",
-				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   ConfigSource("enroute"),
 					ServiceName: "default/kuard/http",
 				},
 				ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:       v2.Cluster_ROUND_ROBIN,
-				CircuitBreakers: &envoy_cluster.CircuitBreakers{
-					Thresholds: []*envoy_cluster.CircuitBreakers_Thresholds{{
+				LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
+				CircuitBreakers: &envoy_cluster_v3.CircuitBreakers{
+					Thresholds: []*envoy_cluster_v3.CircuitBreakers_Thresholds{{
 						MaxRetries: protobuf.UInt32(7),
 					}},
 				},
@@ -329,16 +329,16 @@ func TestCluster(t *testing.T) {
 				},
 				LoadBalancerStrategy: "Random",
 			},
-			want: &v2.Cluster{
+			want: &envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/443/58d888c08a",
 				AltStatName:          "default_kuard_443",
-				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   ConfigSource("enroute"),
 					ServiceName: "default/kuard/http",
 				},
 				ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:       v2.Cluster_RANDOM,
+				LbPolicy:       envoy_cluster_v3.Cluster_RANDOM,
 				CommonLbConfig: ClusterCommonLBConfig(),
 			},
 		},
@@ -349,16 +349,16 @@ func TestCluster(t *testing.T) {
 				},
 				LoadBalancerStrategy: "Cookie",
 			},
-			want: &v2.Cluster{
+			want: &envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/443/e4f81994fe",
 				AltStatName:          "default_kuard_443",
-				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   ConfigSource("enroute"),
 					ServiceName: "default/kuard/http",
 				},
 				ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:       v2.Cluster_RING_HASH,
+				LbPolicy:       envoy_cluster_v3.Cluster_RING_HASH,
 				CommonLbConfig: ClusterCommonLBConfig(),
 			},
 		},
@@ -370,16 +370,16 @@ func TestCluster(t *testing.T) {
 					ServicePort: &s1.Spec.Ports[0],
 				},
 			},
-			want: &v2.Cluster{
+			want: &envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/443/da39a3ee5e",
 				AltStatName:          "default_kuard_443",
-				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   ConfigSource("enroute"),
 					ServiceName: "default/kuard/http",
 				},
 				ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:       v2.Cluster_ROUND_ROBIN,
+				LbPolicy:       envoy_cluster_v3.Cluster_ROUND_ROBIN,
 				CommonLbConfig: ClusterCommonLBConfig(),
 			},
 		},
@@ -393,25 +393,25 @@ func TestCluster(t *testing.T) {
 					Path: "/healthz",
 				},
 			},
-			want: &v2.Cluster{
+			want: &envoy_cluster_v3.Cluster{
 				Name:                 "default/kuard/443/bc862a33ca",
 				AltStatName:          "default_kuard_443",
-				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
-				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
 					EdsConfig:   ConfigSource("enroute"),
 					ServiceName: "default/kuard/http",
 				},
-				ConnectTimeout:                protobuf.Duration(250 * time.Millisecond),
-				LbPolicy:                      v2.Cluster_ROUND_ROBIN,
-				CommonLbConfig:                ClusterCommonLBConfig(),
-				DrainConnectionsOnHostRemoval: true,
-				HealthChecks: []*envoy_api_v2_core.HealthCheck{{
+				ConnectTimeout:            protobuf.Duration(250 * time.Millisecond),
+				LbPolicy:                  envoy_cluster_v3.Cluster_ROUND_ROBIN,
+				CommonLbConfig:            ClusterCommonLBConfig(),
+				IgnoreHealthOnHostRemoval: true,
+				HealthChecks: []*envoy_core_v3.HealthCheck{{
 					Timeout:            durationOrDefault(0, hcTimeout),
 					Interval:           durationOrDefault(0, hcInterval),
 					UnhealthyThreshold: countOrDefault(0, hcUnhealthyThreshold),
 					HealthyThreshold:   countOrDefault(0, hcHealthyThreshold),
-					HealthChecker: &envoy_api_v2_core.HealthCheck_HttpHealthCheck_{
-						HttpHealthCheck: &envoy_api_v2_core.HealthCheck_HttpHealthCheck{
+					HealthChecker: &envoy_core_v3.HealthCheck_HttpHealthCheck_{
+						HttpHealthCheck: &envoy_core_v3.HealthCheck_HttpHealthCheck{
 							Host: hcHost,
 							Path: "/healthz",
 						},
@@ -424,7 +424,7 @@ func TestCluster(t *testing.T) {
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			got := Cluster(tc.cluster)
-			if diff := cmp.Diff(tc.want, got); diff != "" {
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
 				t.Fatal(diff)
 			}
 		})
@@ -532,17 +532,17 @@ func TestClustername(t *testing.T) {
 }
 
 func TestLBPolicy(t *testing.T) {
-	tests := map[string]v2.Cluster_LbPolicy{
-		"WeightedLeastRequest": v2.Cluster_LEAST_REQUEST,
-		"Random":               v2.Cluster_RANDOM,
-		"":                     v2.Cluster_ROUND_ROBIN,
-		"unknown":              v2.Cluster_ROUND_ROBIN,
-		"Cookie":               v2.Cluster_RING_HASH,
+	tests := map[string]envoy_cluster_v3.Cluster_LbPolicy{
+		"WeightedLeastRequest": envoy_cluster_v3.Cluster_LEAST_REQUEST,
+		"Random":               envoy_cluster_v3.Cluster_RANDOM,
+		"":                     envoy_cluster_v3.Cluster_ROUND_ROBIN,
+		"unknown":              envoy_cluster_v3.Cluster_ROUND_ROBIN,
+		"Cookie":               envoy_cluster_v3.Cluster_RING_HASH,
 
 		// RingHash and Maglev were removed as options in 0.13.
 		// See #1150
-		"RingHash": v2.Cluster_ROUND_ROBIN,
-		"Maglev":   v2.Cluster_ROUND_ROBIN,
+		"RingHash": envoy_cluster_v3.Cluster_ROUND_ROBIN,
+		"Maglev":   envoy_cluster_v3.Cluster_ROUND_ROBIN,
 	}
 
 	for strategy, want := range tests {
@@ -627,7 +627,7 @@ func TestAnyPositive(t *testing.T) {
 func TestU32nil(t *testing.T) {
 	assert := func(want, got *wrappers.UInt32Value) {
 		t.Helper()
-		if diff := cmp.Diff(want, got); diff != "" {
+		if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
 			t.Fatal(diff)
 		}
 	}
@@ -638,12 +638,12 @@ func TestU32nil(t *testing.T) {
 
 func TestClusterCommonLBConfig(t *testing.T) {
 	got := ClusterCommonLBConfig()
-	want := &v2.Cluster_CommonLbConfig{
-		HealthyPanicThreshold: &envoy_type.Percent{ // Disable HealthyPanicThreshold
+	want := &envoy_cluster_v3.Cluster_CommonLbConfig{
+		HealthyPanicThreshold: &envoy_type_v3.Percent{ // Disable HealthyPanicThreshold
 			Value: 0,
 		},
 	}
-	if diff := cmp.Diff(want, got); diff != "" {
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
 		t.Fatal(diff)
 	}
 }
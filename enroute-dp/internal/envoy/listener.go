@@ -20,12 +20,11 @@ import (
 	"sort"
 	"time"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
-	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
-	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
-	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
-	tcp "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
@@ -50,22 +49,22 @@ const HTTPDefaultIdleTimeout = 60 * time.Second
 const TCPDefaultIdleTimeout = 9001 * time.Second
 
 // TLSInspector returns a new TLS inspector listener filter.
-func TLSInspector() *envoy_api_v2_listener.ListenerFilter {
-	return &envoy_api_v2_listener.ListenerFilter{
+func TLSInspector() *envoy_listener_v3.ListenerFilter {
+	return &envoy_listener_v3.ListenerFilter{
 		Name: wellknown.TlsInspector,
 	}
 }
 
 // ProxyProtocol returns a new Proxy Protocol listener filter.
-func ProxyProtocol() *envoy_api_v2_listener.ListenerFilter {
-	return &envoy_api_v2_listener.ListenerFilter{
+func ProxyProtocol() *envoy_listener_v3.ListenerFilter {
+	return &envoy_listener_v3.ListenerFilter{
 		Name: wellknown.ProxyProtocol,
 	}
 }
 
-// Listener returns a new v2.Listener for the supplied address, port, and filters.
-func Listener(name, address string, port int, lf []*envoy_api_v2_listener.ListenerFilter, filters ...*envoy_api_v2_listener.Filter) *v2.Listener {
-	l := &v2.Listener{
+// Listener returns a new envoy_listener_v3.Listener for the supplied address, port, and filters.
+func Listener(name, address string, port int, lf []*envoy_listener_v3.ListenerFilter, filters ...*envoy_listener_v3.Filter) *envoy_listener_v3.Listener {
+	l := &envoy_listener_v3.Listener{
 		Name:            name,
 		Address:         SocketAddress(address, port),
 		ListenerFilters: lf,
@@ -73,7 +72,7 @@ func Listener(name, address string, port int, lf []*envoy_api_v2_listener.Listen
 	if len(filters) > 0 {
 		l.FilterChains = append(
 			l.FilterChains,
-			&envoy_api_v2_listener.FilterChain{
+			&envoy_listener_v3.FilterChain{
 				Filters: filters,
 			},
 		)
@@ -83,33 +82,20 @@ func Listener(name, address string, port int, lf []*envoy_api_v2_listener.Listen
 
 // HTTPConnectionManager creates a new HTTP Connection Manager filter
 // for the supplied route and access log.
-func HTTPConnectionManager(routename, accessLogPath string, vh *dag.Vertex) *envoy_api_v2_listener.Filter {
-	return &envoy_api_v2_listener.Filter{
+func HTTPConnectionManager(routename, accessLogPath string, vh *dag.Vertex) *envoy_listener_v3.Filter {
+	return &envoy_listener_v3.Filter{
 		Name: wellknown.HTTPConnectionManager,
-		ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{
+		ConfigType: &envoy_listener_v3.Filter_TypedConfig{
 			TypedConfig: toAny(&http.HttpConnectionManager{
 				StatPrefix: routename,
 				RouteSpecifier: &http.HttpConnectionManager_Rds{
 					Rds: &http.Rds{
 						RouteConfigName: routename,
-						ConfigSource: &envoy_api_v2_core.ConfigSource{
-							ConfigSourceSpecifier: &envoy_api_v2_core.ConfigSource_ApiConfigSource{
-								ApiConfigSource: &envoy_api_v2_core.ApiConfigSource{
-									ApiType: envoy_api_v2_core.ApiConfigSource_GRPC,
-									GrpcServices: []*envoy_api_v2_core.GrpcService{{
-										TargetSpecifier: &envoy_api_v2_core.GrpcService_EnvoyGrpc_{
-											EnvoyGrpc: &envoy_api_v2_core.GrpcService_EnvoyGrpc{
-												ClusterName: "enroute",
-											},
-										},
-									}},
-								},
-							},
-						},
+						ConfigSource:    ConfigSource("enroute"),
 					},
 				},
 				HttpFilters: httpFilters(vh),
-				HttpProtocolOptions: &envoy_api_v2_core.Http1ProtocolOptions{
+				HttpProtocolOptions: &envoy_core_v3.Http1ProtocolOptions{
 					// Enable support for HTTP/1.0 requests that carry
 					// a Host: header. See #537.
 					AcceptHttp_10: true,
@@ -117,7 +103,7 @@ func HTTPConnectionManager(routename, accessLogPath string, vh *dag.Vertex) *env
 				AccessLog:        FileAccessLog(accessLogPath),
 				UseRemoteAddress: protobuf.Bool(true),
 				NormalizePath:    protobuf.Bool(true),
-				CommonHttpProtocolOptions: &envoy_api_v2_core.HttpProtocolOptions{
+				CommonHttpProtocolOptions: &envoy_core_v3.HttpProtocolOptions{
 					// Sets the idle timeout for HTTP connections to 60 seconds.
 					// This is chosen as a rough default to stop idle connections wasting resources,
 					// without stopping slow connections from being terminated too quickly.
@@ -133,13 +119,13 @@ func HTTPConnectionManager(routename, accessLogPath string, vh *dag.Vertex) *env
 }
 
 // TCPProxy creates a new TCPProxy filter.
-func TCPProxy(statPrefix string, proxy *dag.TCPProxy, accessLogPath string) *envoy_api_v2_listener.Filter {
+func TCPProxy(statPrefix string, proxy *dag.TCPProxy, accessLogPath string) *envoy_listener_v3.Filter {
 	idleTimeout := protobuf.Duration(9001 * time.Second)
 	switch len(proxy.Clusters) {
 	case 1:
-		return &envoy_api_v2_listener.Filter{
+		return &envoy_listener_v3.Filter{
 			Name: wellknown.TCPProxy,
-			ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{
+			ConfigType: &envoy_listener_v3.Filter_TypedConfig{
 				TypedConfig: toAny(&tcp.TcpProxy{
 					StatPrefix: statPrefix,
 					ClusterSpecifier: &tcp.TcpProxy_Cluster{
@@ -163,9 +149,9 @@ func TCPProxy(statPrefix string, proxy *dag.TCPProxy, accessLogPath string) *env
 			})
 		}
 		sort.Stable(clustersByNameAndWeight(clusters))
-		return &envoy_api_v2_listener.Filter{
+		return &envoy_listener_v3.Filter{
 			Name: wellknown.TCPProxy,
-			ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{
+			ConfigType: &envoy_listener_v3.Filter_TypedConfig{
 				TypedConfig: toAny(&tcp.TcpProxy{
 					StatPrefix: statPrefix,
 					ClusterSpecifier: &tcp.TcpProxy_WeightedClusters{
@@ -192,28 +178,28 @@ func (c clustersByNameAndWeight) Less(i, j int) bool {
 	return c[i].Name < c[j].Name
 }
 
-// SocketAddress creates a new TCP envoy_api_v2_core.Address.
-func SocketAddress(address string, port int) *envoy_api_v2_core.Address {
+// SocketAddress creates a new TCP envoy_core_v3.Address.
+func SocketAddress(address string, port int) *envoy_core_v3.Address {
 	if address == "::" {
-		return &envoy_api_v2_core.Address{
-			Address: &envoy_api_v2_core.Address_SocketAddress{
-				SocketAddress: &envoy_api_v2_core.SocketAddress{
-					Protocol:   envoy_api_v2_core.SocketAddress_TCP,
+		return &envoy_core_v3.Address{
+			Address: &envoy_core_v3.Address_SocketAddress{
+				SocketAddress: &envoy_core_v3.SocketAddress{
+					Protocol:   envoy_core_v3.SocketAddress_TCP,
 					Address:    address,
 					Ipv4Compat: true,
-					PortSpecifier: &envoy_api_v2_core.SocketAddress_PortValue{
+					PortSpecifier: &envoy_core_v3.SocketAddress_PortValue{
 						PortValue: uint32(port),
 					},
 				},
 			},
 		}
 	}
-	return &envoy_api_v2_core.Address{
-		Address: &envoy_api_v2_core.Address_SocketAddress{
-			SocketAddress: &envoy_api_v2_core.SocketAddress{
-				Protocol: envoy_api_v2_core.SocketAddress_TCP,
+	return &envoy_core_v3.Address{
+		Address: &envoy_core_v3.Address_SocketAddress{
+			SocketAddress: &envoy_core_v3.SocketAddress{
+				Protocol: envoy_core_v3.SocketAddress_TCP,
 				Address:  address,
-				PortSpecifier: &envoy_api_v2_core.SocketAddress_PortValue{
+				PortSpecifier: &envoy_core_v3.SocketAddress_PortValue{
 					PortValue: uint32(port),
 				},
 			},
@@ -221,50 +207,50 @@ func SocketAddress(address string, port int) *envoy_api_v2_core.Address {
 	}
 }
 
-// Filters returns a []*envoy_api_v2_listener.Filter for the supplied filters.
-func Filters(filters ...*envoy_api_v2_listener.Filter) []*envoy_api_v2_listener.Filter {
+// Filters returns a []*envoy_listener_v3.Filter for the supplied filters.
+func Filters(filters ...*envoy_listener_v3.Filter) []*envoy_listener_v3.Filter {
 	if len(filters) == 0 {
 		return nil
 	}
 	return filters
 }
 
-// FilterChain retruns a *envoy_api_v2_listener.FilterChain for the supplied filters.
-func FilterChain(filters ...*envoy_api_v2_listener.Filter) *envoy_api_v2_listener.FilterChain {
-	return &envoy_api_v2_listener.FilterChain{
+// FilterChain retruns a *envoy_listener_v3.FilterChain for the supplied filters.
+func FilterChain(filters ...*envoy_listener_v3.Filter) *envoy_listener_v3.FilterChain {
+	return &envoy_listener_v3.FilterChain{
 		Filters: filters,
 	}
 }
 
-// FilterChains returns a []*envoy_api_v2_listener.FilterChain for the supplied filters.
-func FilterChains(filters ...*envoy_api_v2_listener.Filter) []*envoy_api_v2_listener.FilterChain {
+// FilterChains returns a []*envoy_listener_v3.FilterChain for the supplied filters.
+func FilterChains(filters ...*envoy_listener_v3.Filter) []*envoy_listener_v3.FilterChain {
 	if len(filters) == 0 {
 		return nil
 	}
-	return []*envoy_api_v2_listener.FilterChain{
+	return []*envoy_listener_v3.FilterChain{
 		FilterChain(filters...),
 	}
 }
 
-// FilterChainTLS returns a TLS enabled envoy_api_v2_listener.FilterChain,
-func FilterChainTLS(domain string, secret *dag.Secret, filters []*envoy_api_v2_listener.Filter, tlsMinProtoVersion envoy_api_v2_auth.TlsParameters_TlsProtocol, alpnProtos ...string) *envoy_api_v2_listener.FilterChain {
-	fc := &envoy_api_v2_listener.FilterChain{
+// FilterChainTLS returns a TLS enabled envoy_listener_v3.FilterChain,
+func FilterChainTLS(domain string, secret *dag.Secret, filters []*envoy_listener_v3.Filter, tlsParams *envoy_tls_v3.TlsParameters, alpnProtos ...string) *envoy_listener_v3.FilterChain {
+	fc := &envoy_listener_v3.FilterChain{
 		Filters: filters,
-		FilterChainMatch: &envoy_api_v2_listener.FilterChainMatch{
+		FilterChainMatch: &envoy_listener_v3.FilterChainMatch{
 			ServerNames: []string{domain},
 		},
 	}
 	// attach certificate data to this listener if provided.
 	if secret != nil {
 		fc.TransportSocket = DownstreamTLSTransportSocket(
-			DownstreamTLSContext(Secretname(secret), tlsMinProtoVersion, alpnProtos...),
+			DownstreamTLSContext(Secretname(secret), tlsParams, alpnProtos...),
 		)
 	}
 	return fc
 }
 
-// ListenerFilters returns a []*envoy_api_v2_listener.ListenerFilter for the supplied listener filters.
-func ListenerFilters(filters ...*envoy_api_v2_listener.ListenerFilter) []*envoy_api_v2_listener.ListenerFilter {
+// ListenerFilters returns a []*envoy_listener_v3.ListenerFilter for the supplied listener filters.
+func ListenerFilters(filters ...*envoy_listener_v3.ListenerFilter) []*envoy_listener_v3.ListenerFilter {
 	return filters
 }
 
@@ -4,13 +4,12 @@
 package envoy
 
 import (
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
-	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
-	httplua "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/lua/v2"
-	httprl "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/rate_limit/v2"
-	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
-	envoy_config_ratelimit_v2 "github.com/envoyproxy/go-control-plane/envoy/config/ratelimit/v2"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_config_ratelimit_v3 "github.com/envoyproxy/go-control-plane/envoy/config/ratelimit/v3"
+	httplua "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/lua/v3"
+	httprl "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ratelimit/v3"
+	http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	types "github.com/golang/protobuf/ptypes"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
@@ -21,14 +20,16 @@ func httpRateLimitTypedConfig(vh dag.Vertex) *http.HttpFilter_TypedConfig {
 	return &http.HttpFilter_TypedConfig{
 		TypedConfig: toAny(&httprl.RateLimit{
 			Domain: "enroute",
-			RateLimitService: &envoy_config_ratelimit_v2.RateLimitServiceConfig{
-				GrpcService: &envoy_api_v2_core.GrpcService{
-					TargetSpecifier: &envoy_api_v2_core.GrpcService_EnvoyGrpc_{
-						EnvoyGrpc: &envoy_api_v2_core.GrpcService_EnvoyGrpc{
+			RateLimitService: &envoy_config_ratelimit_v3.RateLimitServiceConfig{
+				GrpcService: &envoy_core_v3.GrpcService{
+					TargetSpecifier: &envoy_core_v3.GrpcService_EnvoyGrpc_{
+						EnvoyGrpc: &envoy_core_v3.GrpcService_EnvoyGrpc{
 							ClusterName: "enroute_ratelimit",
 						},
 					},
 				},
+				// Envoy calls the v3 rate limit service, rather than the v2 one.
+				TransportApiVersion: envoy_core_v3.ApiVersion_V3,
 			},
 		}),
 	}
@@ -36,7 +37,7 @@ func httpRateLimitTypedConfig(vh dag.Vertex) *http.HttpFilter_TypedConfig {
 
 //- name: envoy.lua
 //            typed_config:
-//              "@type": type.googleapis.com/envoy.config.filter.http.lua.v2.Lua
+//              "@type": type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua
 //              inline_code: |
 //                local mylibrary = require("lib.mylibrary")
 //
@@ -90,7 +91,7 @@ func addLuaFilterConfigIfPresent(http_filters *[]*http.HttpFilter, v *dag.Vertex
 	if lua_filter != nil {
 		*http_filters = append(*http_filters,
 			&http.HttpFilter{
-				Name:       "envoy.lua",
+				Name:       wellknown.Lua,
 				ConfigType: httpLuaTypedConfig(lua_filter),
 			})
 	}
@@ -189,7 +190,7 @@ func dagFilterToHttpFilter(df *cfg.SaarasRouteFilter) *http.HttpFilter {
 		switch df.Filter_type {
 		case cfg.FILTER_TYPE_HTTP_LUA:
 			lua_http_filter := &http.HttpFilter{
-				Name:       "envoy.lua",
+				Name:       wellknown.Lua,
 				ConfigType: httpLuaTypedConfig(df),
 			}
 			return lua_http_filter
@@ -267,11 +268,11 @@ func Find(slice []string, val string) (int, bool) {
 	return -1, false
 }
 
-func remove(slice []*envoy_api_v2_listener.Filter, s int) []*envoy_api_v2_listener.Filter {
+func remove(slice []*envoy_listener_v3.Filter, s int) []*envoy_listener_v3.Filter {
 	return append(slice[:s], slice[s+1:]...)
 }
 
-func AddHttpFilterToListener(l *v2.Listener, dag_filters *dag.HttpFilter, name string) {
+func AddHttpFilterToListener(l *envoy_listener_v3.Listener, dag_filters *dag.HttpFilter, name string) {
 	if l != nil && l.FilterChains != nil {
 		var done bool = false
 		for _, filterchain := range l.FilterChains {
@@ -289,7 +290,7 @@ func AddHttpFilterToListener(l *v2.Listener, dag_filters *dag.HttpFilter, name s
 								types.UnmarshalAny(config, httpConnManagerConfig)
 								httpConnManagerConfig.HttpFilters =
 									updateHttpVHFilters(&httpConnManagerConfig.HttpFilters, dag_filters)
-								one_filter.ConfigType = &envoy_api_v2_listener.Filter_TypedConfig{
+								one_filter.ConfigType = &envoy_listener_v3.Filter_TypedConfig{
 									TypedConfig: toAny(httpConnManagerConfig),
 								}
 
@@ -317,7 +318,7 @@ func AddHttpFilterToListener(l *v2.Listener, dag_filters *dag.HttpFilter, name s
 							types.UnmarshalAny(config, httpConnManagerConfig)
 							httpConnManagerConfig.HttpFilters =
 								updateHttpVHFilters(&httpConnManagerConfig.HttpFilters, dag_filters)
-							one_filter.ConfigType = &envoy_api_v2_listener.Filter_TypedConfig{
+							one_filter.ConfigType = &envoy_listener_v3.Filter_TypedConfig{
 								TypedConfig: toAny(httpConnManagerConfig),
 							}
 
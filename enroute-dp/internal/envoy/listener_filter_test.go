@@ -3,10 +3,9 @@ package envoy
 import (
 	"testing"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
-	httplua "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/lua/v2"
-	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	httplua "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/lua/v3"
+	http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/saarasio/enroute/enroute-dp/internal/assert"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
@@ -25,9 +24,9 @@ func TestAddLuaHTTPVHFilter(t *testing.T) {
     end
     `
 
-	filter_in := &envoy_api_v2_listener.Filter{
+	filter_in := &envoy_listener_v3.Filter{
 		Name: wellknown.HTTPConnectionManager,
-		ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{
+		ConfigType: &envoy_listener_v3.Filter_TypedConfig{
 			TypedConfig: toAny(&http.HttpConnectionManager{
 				HttpFilters: []*http.HttpFilter{
 					&http.HttpFilter{
@@ -55,9 +54,9 @@ func TestAddLuaHTTPVHFilter(t *testing.T) {
 		},
 	}
 
-	filter_out := &envoy_api_v2_listener.Filter{
+	filter_out := &envoy_listener_v3.Filter{
 		Name: wellknown.HTTPConnectionManager,
-		ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{
+		ConfigType: &envoy_listener_v3.Filter_TypedConfig{
 			TypedConfig: toAny(&http.HttpConnectionManager{
 				HttpFilters: []*http.HttpFilter{
 					&http.HttpFilter{
@@ -88,26 +87,26 @@ func TestAddLuaHTTPVHFilter(t *testing.T) {
 	}
 
 	tests := map[string]struct {
-		l           v2.Listener
+		l           *envoy_listener_v3.Listener
 		dag_filters dag.HttpFilter
 		name        string
-		want        v2.Listener
+		want        *envoy_listener_v3.Listener
 	}{
 		"No listener No filter": {
-			l:           v2.Listener{},
+			l:           &envoy_listener_v3.Listener{},
 			dag_filters: dag.HttpFilter{},
-			want:        v2.Listener{},
+			want:        &envoy_listener_v3.Listener{},
 		},
 
 		"Lua filter in DAG Filters": {
-			l: v2.Listener{
+			l: &envoy_listener_v3.Listener{
 				FilterChains: FilterChains(filter_in),
 			},
 			dag_filters: dag.HttpFilter{
 				Filters: dag_filters_in,
 			},
 			name: "",
-			want: v2.Listener{
+			want: &envoy_listener_v3.Listener{
 				FilterChains: FilterChains(filter_out),
 			},
 		},
@@ -115,9 +114,8 @@ func TestAddLuaHTTPVHFilter(t *testing.T) {
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			AddHttpFilterToListener(&tc.l, &tc.dag_filters, tc.name)
-			got := tc.l
-			assert.Equal(t, tc.want, got)
+			AddHttpFilterToListener(tc.l, &tc.dag_filters, tc.name)
+			assert.Equal(t, tc.want, tc.l)
 		})
 	}
 }
@@ -20,19 +20,21 @@ import (
 	"testing"
 	"time"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
-	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
-	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	httprl "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ratelimit/v3"
+	http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	//httprl "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/rate_limit/v2"
-	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
-	envoy_config_v2_tcpproxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
 	//envoy_config_ratelimit_v2 "github.com/envoyproxy/go-control-plane/envoy/config/ratelimit/v2"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/google/go-cmp/cmp"
 	"github.com/saarasio/enroute/enroute-dp/internal/assert"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
 	"github.com/saarasio/enroute/enroute-dp/internal/protobuf"
+	"google.golang.org/protobuf/testing/protocmp"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
@@ -41,18 +43,18 @@ func TestListener(t *testing.T) {
 	tests := map[string]struct {
 		name, address string
 		port          int
-		lf            []*envoy_api_v2_listener.ListenerFilter
-		f             []*envoy_api_v2_listener.Filter
-		want          *v2.Listener
+		lf            []*envoy_listener_v3.ListenerFilter
+		f             []*envoy_listener_v3.Filter
+		want          *envoy_listener_v3.Listener
 	}{
 		"insecure listener": {
 			name:    "http",
 			address: "0.0.0.0",
 			port:    9000,
-			f: []*envoy_api_v2_listener.Filter{
+			f: []*envoy_listener_v3.Filter{
 				HTTPConnectionManager("http", "/dev/null", nil),
 			},
-			want: &v2.Listener{
+			want: &envoy_listener_v3.Listener{
 				Name:    "http",
 				Address: SocketAddress("0.0.0.0", 9000),
 				FilterChains: FilterChains(
@@ -64,13 +66,13 @@ func TestListener(t *testing.T) {
 			name:    "http-proxy",
 			address: "0.0.0.0",
 			port:    9000,
-			lf: []*envoy_api_v2_listener.ListenerFilter{
+			lf: []*envoy_listener_v3.ListenerFilter{
 				ProxyProtocol(),
 			},
-			f: []*envoy_api_v2_listener.Filter{
+			f: []*envoy_listener_v3.Filter{
 				HTTPConnectionManager("http-proxy", "/dev/null", nil),
 			},
-			want: &v2.Listener{
+			want: &envoy_listener_v3.Listener{
 				Name:    "http-proxy",
 				Address: SocketAddress("0.0.0.0", 9000),
 				ListenerFilters: ListenerFilters(
@@ -85,10 +87,10 @@ func TestListener(t *testing.T) {
 			name:    "https",
 			address: "0.0.0.0",
 			port:    9000,
-			lf: []*envoy_api_v2_listener.ListenerFilter{
+			lf: []*envoy_listener_v3.ListenerFilter{
 				TLSInspector(),
 			},
-			want: &v2.Listener{
+			want: &envoy_listener_v3.Listener{
 				Name:    "https",
 				Address: SocketAddress("0.0.0.0", 9000),
 				ListenerFilters: ListenerFilters(
@@ -100,11 +102,11 @@ func TestListener(t *testing.T) {
 			name:    "https-proxy",
 			address: "0.0.0.0",
 			port:    9000,
-			lf: []*envoy_api_v2_listener.ListenerFilter{
+			lf: []*envoy_listener_v3.ListenerFilter{
 				ProxyProtocol(),
 				TLSInspector(),
 			},
-			want: &v2.Listener{
+			want: &envoy_listener_v3.Listener{
 				Name:    "https-proxy",
 				Address: SocketAddress("0.0.0.0", 9000),
 				ListenerFilters: ListenerFilters(
@@ -118,7 +120,7 @@ func TestListener(t *testing.T) {
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			got := Listener(tc.name, tc.address, tc.port, tc.lf, tc.f...)
-			if diff := cmp.Diff(tc.want, got); diff != "" {
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
 				t.Fatal(diff)
 			}
 		})
@@ -132,35 +134,35 @@ func TestSocketAddress(t *testing.T) {
 	)
 
 	got := SocketAddress(addr, port)
-	want := &envoy_api_v2_core.Address{
-		Address: &envoy_api_v2_core.Address_SocketAddress{
-			SocketAddress: &envoy_api_v2_core.SocketAddress{
-				Protocol: envoy_api_v2_core.SocketAddress_TCP,
+	want := &envoy_core_v3.Address{
+		Address: &envoy_core_v3.Address_SocketAddress{
+			SocketAddress: &envoy_core_v3.SocketAddress{
+				Protocol: envoy_core_v3.SocketAddress_TCP,
 				Address:  addr,
-				PortSpecifier: &envoy_api_v2_core.SocketAddress_PortValue{
+				PortSpecifier: &envoy_core_v3.SocketAddress_PortValue{
 					PortValue: port,
 				},
 			},
 		},
 	}
-	if diff := cmp.Diff(want, got); diff != "" {
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
 		t.Fatal(diff)
 	}
 
 	got = SocketAddress("::", port)
-	want = &envoy_api_v2_core.Address{
-		Address: &envoy_api_v2_core.Address_SocketAddress{
-			SocketAddress: &envoy_api_v2_core.SocketAddress{
-				Protocol:   envoy_api_v2_core.SocketAddress_TCP,
+	want = &envoy_core_v3.Address{
+		Address: &envoy_core_v3.Address_SocketAddress{
+			SocketAddress: &envoy_core_v3.SocketAddress{
+				Protocol:   envoy_core_v3.SocketAddress_TCP,
 				Address:    "::",
 				Ipv4Compat: true, // Set only for ipv6-any "::"
-				PortSpecifier: &envoy_api_v2_core.SocketAddress_PortValue{
+				PortSpecifier: &envoy_core_v3.SocketAddress_PortValue{
 					PortValue: port,
 				},
 			},
 		},
 	}
-	if diff := cmp.Diff(want, got); diff != "" {
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
 		t.Fatal(diff)
 	}
 }
@@ -168,12 +170,12 @@ func TestSocketAddress(t *testing.T) {
 func TestDownstreamTLSContext(t *testing.T) {
 	const secretName = "default/tls-cert"
 
-	got := DownstreamTLSContext(secretName, envoy_api_v2_auth.TlsParameters_TLSv1_1, "h2", "http/1.1")
-	want := &envoy_api_v2_auth.DownstreamTlsContext{
-		CommonTlsContext: &envoy_api_v2_auth.CommonTlsContext{
-			TlsParams: &envoy_api_v2_auth.TlsParameters{
-				TlsMinimumProtocolVersion: envoy_api_v2_auth.TlsParameters_TLSv1_1,
-				TlsMaximumProtocolVersion: envoy_api_v2_auth.TlsParameters_TLSv1_3,
+	got := DownstreamTLSContext(secretName, TLSParams(envoy_tls_v3.TlsParameters_TLSv1_1, envoy_tls_v3.TlsParameters_TLS_AUTO, nil, nil), "h2", "http/1.1")
+	want := &envoy_tls_v3.DownstreamTlsContext{
+		CommonTlsContext: &envoy_tls_v3.CommonTlsContext{
+			TlsParams: &envoy_tls_v3.TlsParameters{
+				TlsMinimumProtocolVersion: envoy_tls_v3.TlsParameters_TLSv1_1,
+				TlsMaximumProtocolVersion: envoy_tls_v3.TlsParameters_TLSv1_3,
 				CipherSuites: []string{
 					"[ECDHE-ECDSA-AES128-GCM-SHA256|ECDHE-ECDSA-CHACHA20-POLY1305]",
 					"[ECDHE-RSA-AES128-GCM-SHA256|ECDHE-RSA-CHACHA20-POLY1305]",
@@ -185,27 +187,29 @@ func TestDownstreamTLSContext(t *testing.T) {
 					"ECDHE-RSA-AES256-SHA",
 				},
 			},
-			TlsCertificateSdsSecretConfigs: []*envoy_api_v2_auth.SdsSecretConfig{{
+			TlsCertificateSdsSecretConfigs: []*envoy_tls_v3.SdsSecretConfig{{
 				Name: secretName,
-				SdsConfig: &envoy_api_v2_core.ConfigSource{
-					ConfigSourceSpecifier: &envoy_api_v2_core.ConfigSource_ApiConfigSource{
-						ApiConfigSource: &envoy_api_v2_core.ApiConfigSource{
-							ApiType: envoy_api_v2_core.ApiConfigSource_GRPC,
-							GrpcServices: []*envoy_api_v2_core.GrpcService{{
-								TargetSpecifier: &envoy_api_v2_core.GrpcService_EnvoyGrpc_{
-									EnvoyGrpc: &envoy_api_v2_core.GrpcService_EnvoyGrpc{
+				SdsConfig: &envoy_core_v3.ConfigSource{
+					ConfigSourceSpecifier: &envoy_core_v3.ConfigSource_ApiConfigSource{
+						ApiConfigSource: &envoy_core_v3.ApiConfigSource{
+							ApiType:             envoy_core_v3.ApiConfigSource_GRPC,
+							TransportApiVersion: envoy_core_v3.ApiVersion_V3,
+							GrpcServices: []*envoy_core_v3.GrpcService{{
+								TargetSpecifier: &envoy_core_v3.GrpcService_EnvoyGrpc_{
+									EnvoyGrpc: &envoy_core_v3.GrpcService_EnvoyGrpc{
 										ClusterName: "enroute",
 									},
 								},
 							}},
 						},
 					},
+					ResourceApiVersion: envoy_core_v3.ApiVersion_V3,
 				},
 			}},
 			AlpnProtocols: []string{"h2", "http/1.1"},
 		},
 	}
-	if diff := cmp.Diff(want, got); diff != "" {
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
 		t.Fatal(diff)
 	}
 }
@@ -214,32 +218,34 @@ func TestHTTPConnectionManager(t *testing.T) {
 	tests := map[string]struct {
 		routename string
 		accesslog string
-		want      *envoy_api_v2_listener.Filter
+		want      *envoy_listener_v3.Filter
 	}{
 		"default": {
 			routename: "default/kuard",
 			accesslog: "/dev/stdout",
-			want: &envoy_api_v2_listener.Filter{
+			want: &envoy_listener_v3.Filter{
 				Name: wellknown.HTTPConnectionManager,
-				ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{
+				ConfigType: &envoy_listener_v3.Filter_TypedConfig{
 					TypedConfig: toAny(&http.HttpConnectionManager{
 						StatPrefix: "default/kuard",
 						RouteSpecifier: &http.HttpConnectionManager_Rds{
 							Rds: &http.Rds{
 								RouteConfigName: "default/kuard",
-								ConfigSource: &envoy_api_v2_core.ConfigSource{
-									ConfigSourceSpecifier: &envoy_api_v2_core.ConfigSource_ApiConfigSource{
-										ApiConfigSource: &envoy_api_v2_core.ApiConfigSource{
-											ApiType: envoy_api_v2_core.ApiConfigSource_GRPC,
-											GrpcServices: []*envoy_api_v2_core.GrpcService{{
-												TargetSpecifier: &envoy_api_v2_core.GrpcService_EnvoyGrpc_{
-													EnvoyGrpc: &envoy_api_v2_core.GrpcService_EnvoyGrpc{
+								ConfigSource: &envoy_core_v3.ConfigSource{
+									ConfigSourceSpecifier: &envoy_core_v3.ConfigSource_ApiConfigSource{
+										ApiConfigSource: &envoy_core_v3.ApiConfigSource{
+											ApiType:             envoy_core_v3.ApiConfigSource_GRPC,
+											TransportApiVersion: envoy_core_v3.ApiVersion_V3,
+											GrpcServices: []*envoy_core_v3.GrpcService{{
+												TargetSpecifier: &envoy_core_v3.GrpcService_EnvoyGrpc_{
+													EnvoyGrpc: &envoy_core_v3.GrpcService_EnvoyGrpc{
 														ClusterName: "enroute",
 													},
 												},
 											}},
 										},
 									},
+									ResourceApiVersion: envoy_core_v3.ApiVersion_V3,
 								},
 							},
 						},
@@ -257,9 +263,9 @@ func TestHTTPConnectionManager(t *testing.T) {
 							//			TypedConfig: toAny(&httprl.RateLimit{
 							//				Domain: "enroute",
 							//				RateLimitService: &envoy_config_ratelimit_v2.RateLimitServiceConfig{
-							//					GrpcService: &envoy_api_v2_core.GrpcService{
-							//						TargetSpecifier: &envoy_api_v2_core.GrpcService_EnvoyGrpc_{
-							//							EnvoyGrpc: &envoy_api_v2_core.GrpcService_EnvoyGrpc{
+							//					GrpcService: &envoy_core_v3.GrpcService{
+							//						TargetSpecifier: &envoy_core_v3.GrpcService_EnvoyGrpc_{
+							//							EnvoyGrpc: &envoy_core_v3.GrpcService_EnvoyGrpc{
 							//								ClusterName: "enroute_ratelimit",
 							//							},
 							//						},
@@ -273,7 +279,7 @@ func TestHTTPConnectionManager(t *testing.T) {
 								Name:       wellknown.Router,
 								ConfigType: nil,
 							}},
-						HttpProtocolOptions: &envoy_api_v2_core.Http1ProtocolOptions{
+						HttpProtocolOptions: &envoy_core_v3.Http1ProtocolOptions{
 							// Enable support for HTTP/1.0 requests that carry
 							// a Host: header. See #537.
 							AcceptHttp_10: true,
@@ -281,7 +287,7 @@ func TestHTTPConnectionManager(t *testing.T) {
 						AccessLog:        FileAccessLog("/dev/stdout"),
 						UseRemoteAddress: protobuf.Bool(true),
 						NormalizePath:    protobuf.Bool(true),
-						CommonHttpProtocolOptions: &envoy_api_v2_core.HttpProtocolOptions{
+						CommonHttpProtocolOptions: &envoy_core_v3.HttpProtocolOptions{
 							IdleTimeout: protobuf.Duration(60 * time.Second),
 						},
 						PreserveExternalRequestId: true,
@@ -330,18 +336,18 @@ func TestTCPProxy(t *testing.T) {
 
 	tests := map[string]struct {
 		proxy *dag.TCPProxy
-		want  *envoy_api_v2_listener.Filter
+		want  *envoy_listener_v3.Filter
 	}{
 		"single cluster": {
 			proxy: &dag.TCPProxy{
 				Clusters: []*dag.Cluster{c1},
 			},
-			want: &envoy_api_v2_listener.Filter{
+			want: &envoy_listener_v3.Filter{
 				Name: wellknown.TCPProxy,
-				ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{
-					TypedConfig: toAny(&envoy_config_v2_tcpproxy.TcpProxy{
+				ConfigType: &envoy_listener_v3.Filter_TypedConfig{
+					TypedConfig: toAny(&tcp.TcpProxy{
 						StatPrefix: statPrefix,
-						ClusterSpecifier: &envoy_config_v2_tcpproxy.TcpProxy_Cluster{
+						ClusterSpecifier: &tcp.TcpProxy_Cluster{
 							Cluster: Clustername(c1),
 						},
 						AccessLog:   FileAccessLog(accessLogPath),
@@ -354,14 +360,14 @@ func TestTCPProxy(t *testing.T) {
 			proxy: &dag.TCPProxy{
 				Clusters: []*dag.Cluster{c2, c1},
 			},
-			want: &envoy_api_v2_listener.Filter{
+			want: &envoy_listener_v3.Filter{
 				Name: wellknown.TCPProxy,
-				ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{
-					TypedConfig: toAny(&envoy_config_v2_tcpproxy.TcpProxy{
+				ConfigType: &envoy_listener_v3.Filter_TypedConfig{
+					TypedConfig: toAny(&tcp.TcpProxy{
 						StatPrefix: statPrefix,
-						ClusterSpecifier: &envoy_config_v2_tcpproxy.TcpProxy_WeightedClusters{
-							WeightedClusters: &envoy_config_v2_tcpproxy.TcpProxy_WeightedCluster{
-								Clusters: []*envoy_config_v2_tcpproxy.TcpProxy_WeightedCluster_ClusterWeight{{
+						ClusterSpecifier: &tcp.TcpProxy_WeightedClusters{
+							WeightedClusters: &tcp.TcpProxy_WeightedCluster{
+								Clusters: []*tcp.TcpProxy_WeightedCluster_ClusterWeight{{
 									Name:   Clustername(c1),
 									Weight: 1,
 								}, {
@@ -381,9 +387,20 @@ func TestTCPProxy(t *testing.T) {
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			got := TCPProxy(statPrefix, tc.proxy, accessLogPath)
-			if diff := cmp.Diff(tc.want, got); diff != "" {
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
 				t.Fatal(diff)
 			}
 		})
 	}
 }
+
+func TestHTTPRateLimitTransportAPIVersion(t *testing.T) {
+	var c httprl.RateLimit
+	if err := ptypes.UnmarshalAny(httpRateLimitTypedConfig(nil).TypedConfig, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.RateLimitService.TransportApiVersion; got != envoy_core_v3.ApiVersion_V3 {
+		t.Fatalf("expected transport_api_version V3, got %v", got)
+	}
+}
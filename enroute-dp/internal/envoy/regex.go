@@ -14,7 +14,7 @@
 package envoy
 
 import (
-	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
 	"github.com/saarasio/enroute/enroute-dp/internal/protobuf"
 )
 
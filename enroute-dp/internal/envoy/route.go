@@ -23,19 +23,18 @@ import (
 	"strings"
 	"time"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
-	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
 	"github.com/saarasio/enroute/enroute-dp/internal/protobuf"
 )
 
-// RouteRoute creates a envoy_api_v2_route.Route_Route for the services supplied.
+// RouteRoute creates a envoy_route_v3.Route_Route for the services supplied.
 // If len(services) is greater than one, the route's action will be a
 // weighted cluster.
-func RouteRoute(r *dag.Route) *envoy_api_v2_route.Route_Route {
-	ra := envoy_api_v2_route.RouteAction{
+func RouteRoute(r *dag.Route) *envoy_route_v3.Route_Route {
+	ra := envoy_route_v3.RouteAction{
 		RetryPolicy:   retryPolicy(r),
 		Timeout:       responseTimeout(r),
 		PrefixRewrite: r.PrefixRewrite,
@@ -46,7 +45,7 @@ func RouteRoute(r *dag.Route) *envoy_api_v2_route.Route_Route {
 
 	if r.Websocket {
 		ra.UpgradeConfigs = append(ra.UpgradeConfigs,
-			&envoy_api_v2_route.RouteAction_UpgradeConfig{
+			&envoy_route_v3.RouteAction_UpgradeConfig{
 				UpgradeType: "websocket",
 			},
 		)
@@ -54,27 +53,27 @@ func RouteRoute(r *dag.Route) *envoy_api_v2_route.Route_Route {
 
 	switch len(r.Clusters) {
 	case 1:
-		ra.ClusterSpecifier = &envoy_api_v2_route.RouteAction_Cluster{
+		ra.ClusterSpecifier = &envoy_route_v3.RouteAction_Cluster{
 			Cluster: Clustername(r.Clusters[0]),
 		}
 	default:
-		ra.ClusterSpecifier = &envoy_api_v2_route.RouteAction_WeightedClusters{
+		ra.ClusterSpecifier = &envoy_route_v3.RouteAction_WeightedClusters{
 			WeightedClusters: weightedClusters(r.Clusters),
 		}
 	}
-	return &envoy_api_v2_route.Route_Route{
+	return &envoy_route_v3.Route_Route{
 		Route: &ra,
 	}
 }
 
 // hashPolicy returns a slice of hash policies iff at least one of the route's
 // clusters supplied uses the `Cookie` load balancing stategy.
-func hashPolicy(r *dag.Route) []*envoy_api_v2_route.RouteAction_HashPolicy {
+func hashPolicy(r *dag.Route) []*envoy_route_v3.RouteAction_HashPolicy {
 	for _, c := range r.Clusters {
 		if c.LoadBalancerStrategy == "Cookie" {
-			return []*envoy_api_v2_route.RouteAction_HashPolicy{{
-				PolicySpecifier: &envoy_api_v2_route.RouteAction_HashPolicy_Cookie_{
-					Cookie: &envoy_api_v2_route.RouteAction_HashPolicy_Cookie{
+			return []*envoy_route_v3.RouteAction_HashPolicy{{
+				PolicySpecifier: &envoy_route_v3.RouteAction_HashPolicy_Cookie_{
+					Cookie: &envoy_route_v3.RouteAction_HashPolicy_Cookie{
 						Name: "X-Contour-Session-Affinity",
 						Ttl:  protobuf.Duration(0),
 						Path: "/",
@@ -113,7 +112,7 @@ func timeout(d time.Duration) *duration.Duration {
 	}
 }
 
-func retryPolicy(r *dag.Route) *envoy_api_v2_route.RetryPolicy {
+func retryPolicy(r *dag.Route) *envoy_route_v3.RetryPolicy {
 	if r.RetryPolicy == nil {
 		return nil
 	}
@@ -121,7 +120,7 @@ func retryPolicy(r *dag.Route) *envoy_api_v2_route.RetryPolicy {
 		return nil
 	}
 
-	rp := &envoy_api_v2_route.RetryPolicy{
+	rp := &envoy_route_v3.RetryPolicy{
 		RetryOn: r.RetryPolicy.RetryOn,
 	}
 	if r.RetryPolicy.NumRetries > 0 {
@@ -134,10 +133,10 @@ func retryPolicy(r *dag.Route) *envoy_api_v2_route.RetryPolicy {
 }
 
 // UpgradeHTTPS returns a route Action that redirects the request to HTTPS.
-func UpgradeHTTPS() *envoy_api_v2_route.Route_Redirect {
-	return &envoy_api_v2_route.Route_Redirect{
-		Redirect: &envoy_api_v2_route.RedirectAction{
-			SchemeRewriteSpecifier: &envoy_api_v2_route.RedirectAction_HttpsRedirect{
+func UpgradeHTTPS() *envoy_route_v3.Route_Redirect {
+	return &envoy_route_v3.Route_Redirect{
+		Redirect: &envoy_route_v3.RedirectAction{
+			SchemeRewriteSpecifier: &envoy_route_v3.RedirectAction_HttpsRedirect{
 				HttpsRedirect: true,
 			},
 		},
@@ -145,19 +144,19 @@ func UpgradeHTTPS() *envoy_api_v2_route.Route_Redirect {
 }
 
 // RouteHeaders returns a list of headers to be applied at the Route level on envoy
-func RouteHeaders() []*envoy_api_v2_core.HeaderValueOption {
+func RouteHeaders() []*envoy_core_v3.HeaderValueOption {
 	return Headers(
 		AppendHeader("x-request-start", "t=%START_TIME(%s.%3f)%"),
 	)
 }
 
 // weightedClusters returns a route.WeightedCluster for multiple services.
-func weightedClusters(clusters []*dag.Cluster) *envoy_api_v2_route.WeightedCluster {
-	var wc envoy_api_v2_route.WeightedCluster
+func weightedClusters(clusters []*dag.Cluster) *envoy_route_v3.WeightedCluster {
+	var wc envoy_route_v3.WeightedCluster
 	var total uint32
 	for _, cluster := range clusters {
 		total += cluster.Weight
-		wc.Clusters = append(wc.Clusters, &envoy_api_v2_route.WeightedCluster_ClusterWeight{
+		wc.Clusters = append(wc.Clusters, &envoy_route_v3.WeightedCluster_ClusterWeight{
 			Name:   Clustername(cluster),
 			Weight: protobuf.UInt32(cluster.Weight),
 		})
@@ -176,43 +175,43 @@ func weightedClusters(clusters []*dag.Cluster) *envoy_api_v2_route.WeightedClust
 }
 
 // RouteMatch creates a RouteMatch for the supplied prefix/regex.
-func RouteMatch(path string) *envoy_api_v2_route.RouteMatch {
+func RouteMatch(path string) *envoy_route_v3.RouteMatch {
 	// Check if path contains a regex
 	if strings.ContainsAny(path, "^+*[]%") {
-		return &envoy_api_v2_route.RouteMatch{
-			PathSpecifier: &envoy_api_v2_route.RouteMatch_Regex{
-				Regex: path,
+		return &envoy_route_v3.RouteMatch{
+			PathSpecifier: &envoy_route_v3.RouteMatch_SafeRegex{
+				SafeRegex: SafeRegexMatch(path),
 			},
 		}
 	}
-	return &envoy_api_v2_route.RouteMatch{
-		PathSpecifier: &envoy_api_v2_route.RouteMatch_Prefix{
+	return &envoy_route_v3.RouteMatch{
+		PathSpecifier: &envoy_route_v3.RouteMatch_Prefix{
 			Prefix: path,
 		},
 	}
 }
 
-// RouteMatch creates a *envoy_api_v2_route.RouteMatch for the supplied *dag.Route.
-func RouteMatchNew(route *dag.Route) *envoy_api_v2_route.RouteMatch {
+// RouteMatch creates a *envoy_route_v3.RouteMatch for the supplied *dag.Route.
+func RouteMatchNew(route *dag.Route) *envoy_route_v3.RouteMatch {
 	switch c := route.PathCondition.(type) {
 	case *dag.RegexCondition:
-		return &envoy_api_v2_route.RouteMatch{
-			PathSpecifier: &envoy_api_v2_route.RouteMatch_SafeRegex{
+		return &envoy_route_v3.RouteMatch{
+			PathSpecifier: &envoy_route_v3.RouteMatch_SafeRegex{
 				SafeRegex: SafeRegexMatch(c.Regex),
 			},
 			QueryParameters: nil,
 			Headers:         headerMatcher(route.HeaderConditions),
 		}
 	case *dag.PrefixCondition:
-		return &envoy_api_v2_route.RouteMatch{
-			PathSpecifier: &envoy_api_v2_route.RouteMatch_Prefix{
+		return &envoy_route_v3.RouteMatch{
+			PathSpecifier: &envoy_route_v3.RouteMatch_Prefix{
 				Prefix: c.Prefix,
 			},
 			QueryParameters: nil,
 			Headers:         headerMatcher(route.HeaderConditions),
 		}
 	default:
-		return &envoy_api_v2_route.RouteMatch{
+		return &envoy_route_v3.RouteMatch{
 			QueryParameters: nil,
 			Headers:         headerMatcher(route.HeaderConditions),
 		}
@@ -220,20 +219,20 @@ func RouteMatchNew(route *dag.Route) *envoy_api_v2_route.RouteMatch {
 }
 
 // VirtualHost creates a new route.VirtualHost.
-func VirtualHost(hostname string) *envoy_api_v2_route.VirtualHost {
+func VirtualHost(hostname string) *envoy_route_v3.VirtualHost {
 	domains := []string{hostname}
 	if hostname != "*" {
 		domains = append(domains, hostname+":*")
 	}
-	return &envoy_api_v2_route.VirtualHost{
+	return &envoy_route_v3.VirtualHost{
 		Name:    hashname(60, hostname),
 		Domains: domains,
 	}
 }
 
-// RouteConfiguration returns a *v2.RouteConfiguration.
-func RouteConfiguration(name string, virtualhosts ...*envoy_api_v2_route.VirtualHost) *v2.RouteConfiguration {
-	return &v2.RouteConfiguration{
+// RouteConfiguration returns a *envoy_route_v3.RouteConfiguration.
+func RouteConfiguration(name string, virtualhosts ...*envoy_route_v3.VirtualHost) *envoy_route_v3.RouteConfiguration {
+	return &envoy_route_v3.RouteConfiguration{
 		Name:         name,
 		VirtualHosts: virtualhosts,
 		RequestHeadersToAdd: Headers(
@@ -242,7 +241,7 @@ func RouteConfiguration(name string, virtualhosts ...*envoy_api_v2_route.Virtual
 	}
 }
 
-type clusterWeightByName []*envoy_api_v2_route.WeightedCluster_ClusterWeight
+type clusterWeightByName []*envoy_route_v3.WeightedCluster_ClusterWeight
 
 func (c clusterWeightByName) Len() int      { return len(c) }
 func (c clusterWeightByName) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
@@ -254,13 +253,13 @@ func (c clusterWeightByName) Less(i, j int) bool {
 
 }
 
-func Headers(first *envoy_api_v2_core.HeaderValueOption, rest ...*envoy_api_v2_core.HeaderValueOption) []*envoy_api_v2_core.HeaderValueOption {
-	return append([]*envoy_api_v2_core.HeaderValueOption{first}, rest...)
+func Headers(first *envoy_core_v3.HeaderValueOption, rest ...*envoy_core_v3.HeaderValueOption) []*envoy_core_v3.HeaderValueOption {
+	return append([]*envoy_core_v3.HeaderValueOption{first}, rest...)
 }
 
-func AppendHeader(key, value string) *envoy_api_v2_core.HeaderValueOption {
-	return &envoy_api_v2_core.HeaderValueOption{
-		Header: &envoy_api_v2_core.HeaderValue{
+func AppendHeader(key, value string) *envoy_core_v3.HeaderValueOption {
+	return &envoy_core_v3.HeaderValueOption{
+		Header: &envoy_core_v3.HeaderValue{
 			Key:   key,
 			Value: value,
 		},
@@ -268,22 +267,22 @@ func AppendHeader(key, value string) *envoy_api_v2_core.HeaderValueOption {
 	}
 }
 
-func headerMatcher(headers []dag.HeaderCondition) []*envoy_api_v2_route.HeaderMatcher {
-	var envoyHeaders []*envoy_api_v2_route.HeaderMatcher
+func headerMatcher(headers []dag.HeaderCondition) []*envoy_route_v3.HeaderMatcher {
+	var envoyHeaders []*envoy_route_v3.HeaderMatcher
 
 	for _, h := range headers {
-		header := &envoy_api_v2_route.HeaderMatcher{
+		header := &envoy_route_v3.HeaderMatcher{
 			Name:        h.Name,
 			InvertMatch: h.Invert,
 		}
 
 		switch h.MatchType {
 		case "exact":
-			header.HeaderMatchSpecifier = &envoy_api_v2_route.HeaderMatcher_ExactMatch{ExactMatch: h.Value}
+			header.HeaderMatchSpecifier = &envoy_route_v3.HeaderMatcher_ExactMatch{ExactMatch: h.Value}
 		case "contains":
 			header.HeaderMatchSpecifier = containsMatch(h.Value)
 		case "present":
-			header.HeaderMatchSpecifier = &envoy_api_v2_route.HeaderMatcher_PresentMatch{PresentMatch: true}
+			header.HeaderMatchSpecifier = &envoy_route_v3.HeaderMatcher_PresentMatch{PresentMatch: true}
 		}
 		envoyHeaders = append(envoyHeaders, header)
 	}
@@ -292,13 +291,13 @@ func headerMatcher(headers []dag.HeaderCondition) []*envoy_api_v2_route.HeaderMa
 
 // containsMatch returns a HeaderMatchSpecifier which will match the
 // supplied substring
-func containsMatch(s string) *envoy_api_v2_route.HeaderMatcher_SafeRegexMatch {
+func containsMatch(s string) *envoy_route_v3.HeaderMatcher_SafeRegexMatch {
 	// convert the substring s into a regular expression that matches s.
 	// note that Envoy expects the expression to match the entire string, not just the substring
 	// formed from s. see [projectcontour/contour/#1751 & envoyproxy/envoy#8283]
 	regex := fmt.Sprintf(".*%s.*", regexp.QuoteMeta(s))
 
-	return &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
+	return &envoy_route_v3.HeaderMatcher_SafeRegexMatch{
 		SafeRegexMatch: SafeRegexMatch(regex),
 	}
 }
@@ -20,9 +20,8 @@ import (
 	"testing"
 	"time"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
-	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	"github.com/saarasio/enroute/enroute-dp/internal/assert"
 	"github.com/saarasio/enroute/enroute-dp/internal/dag"
 	"github.com/saarasio/enroute/enroute-dp/internal/protobuf"
@@ -64,15 +63,15 @@ func TestRouteRoute(t *testing.T) {
 
 	tests := map[string]struct {
 		route *dag.Route
-		want  *envoy_api_v2_route.Route_Route
+		want  *envoy_route_v3.Route_Route
 	}{
 		"single service": {
 			route: &dag.Route{
 				Clusters: []*dag.Cluster{c1},
 			},
-			want: &envoy_api_v2_route.Route_Route{
-				Route: &envoy_api_v2_route.RouteAction{
-					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+			want: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
 						Cluster: "default/kuard/8080/da39a3ee5e",
 					},
 				},
@@ -83,12 +82,12 @@ func TestRouteRoute(t *testing.T) {
 				Websocket: true,
 				Clusters:  []*dag.Cluster{c1},
 			},
-			want: &envoy_api_v2_route.Route_Route{
-				Route: &envoy_api_v2_route.RouteAction{
-					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+			want: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
 						Cluster: "default/kuard/8080/da39a3ee5e",
 					},
-					UpgradeConfigs: []*envoy_api_v2_route.RouteAction_UpgradeConfig{{
+					UpgradeConfigs: []*envoy_route_v3.RouteAction_UpgradeConfig{{
 						UpgradeType: "websocket",
 					}},
 				},
@@ -111,11 +110,11 @@ func TestRouteRoute(t *testing.T) {
 					},
 				}},
 			},
-			want: &envoy_api_v2_route.Route_Route{
-				Route: &envoy_api_v2_route.RouteAction{
-					ClusterSpecifier: &envoy_api_v2_route.RouteAction_WeightedClusters{
-						WeightedClusters: &envoy_api_v2_route.WeightedCluster{
-							Clusters: []*envoy_api_v2_route.WeightedCluster_ClusterWeight{{
+			want: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_WeightedClusters{
+						WeightedClusters: &envoy_route_v3.WeightedCluster{
+							Clusters: []*envoy_route_v3.WeightedCluster_ClusterWeight{{
 								Name:   "default/kuard/8080/da39a3ee5e",
 								Weight: protobuf.UInt32(0),
 							}, {
@@ -146,11 +145,11 @@ func TestRouteRoute(t *testing.T) {
 					},
 				}},
 			},
-			want: &envoy_api_v2_route.Route_Route{
-				Route: &envoy_api_v2_route.RouteAction{
-					ClusterSpecifier: &envoy_api_v2_route.RouteAction_WeightedClusters{
-						WeightedClusters: &envoy_api_v2_route.WeightedCluster{
-							Clusters: []*envoy_api_v2_route.WeightedCluster_ClusterWeight{{
+			want: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_WeightedClusters{
+						WeightedClusters: &envoy_route_v3.WeightedCluster{
+							Clusters: []*envoy_route_v3.WeightedCluster_ClusterWeight{{
 								Name:   "default/kuard/8080/da39a3ee5e",
 								Weight: protobuf.UInt32(0),
 							}, {
@@ -160,7 +159,7 @@ func TestRouteRoute(t *testing.T) {
 							TotalWeight: protobuf.UInt32(90),
 						},
 					},
-					UpgradeConfigs: []*envoy_api_v2_route.RouteAction_UpgradeConfig{{
+					UpgradeConfigs: []*envoy_route_v3.RouteAction_UpgradeConfig{{
 						UpgradeType: "websocket",
 					}},
 				},
@@ -174,9 +173,9 @@ func TestRouteRoute(t *testing.T) {
 				},
 				Clusters: []*dag.Cluster{c1},
 			},
-			want: &envoy_api_v2_route.Route_Route{
-				Route: &envoy_api_v2_route.RouteAction{
-					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+			want: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
 						Cluster: "default/kuard/8080/da39a3ee5e",
 					},
 				},
@@ -191,12 +190,12 @@ func TestRouteRoute(t *testing.T) {
 				},
 				Clusters: []*dag.Cluster{c1},
 			},
-			want: &envoy_api_v2_route.Route_Route{
-				Route: &envoy_api_v2_route.RouteAction{
-					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+			want: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
 						Cluster: "default/kuard/8080/da39a3ee5e",
 					},
-					RetryPolicy: &envoy_api_v2_route.RetryPolicy{
+					RetryPolicy: &envoy_route_v3.RetryPolicy{
 						RetryOn:       "503",
 						NumRetries:    protobuf.UInt32(6),
 						PerTryTimeout: protobuf.Duration(100 * time.Millisecond),
@@ -211,9 +210,9 @@ func TestRouteRoute(t *testing.T) {
 				},
 				Clusters: []*dag.Cluster{c1},
 			},
-			want: &envoy_api_v2_route.Route_Route{
-				Route: &envoy_api_v2_route.RouteAction{
-					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+			want: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
 						Cluster: "default/kuard/8080/da39a3ee5e",
 					},
 					Timeout: protobuf.Duration(90 * time.Second),
@@ -227,9 +226,9 @@ func TestRouteRoute(t *testing.T) {
 				},
 				Clusters: []*dag.Cluster{c1},
 			},
-			want: &envoy_api_v2_route.Route_Route{
-				Route: &envoy_api_v2_route.RouteAction{
-					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+			want: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
 						Cluster: "default/kuard/8080/da39a3ee5e",
 					},
 					Timeout: protobuf.Duration(0),
@@ -244,9 +243,9 @@ func TestRouteRoute(t *testing.T) {
 		//				},
 		//				Clusters: []*dag.Cluster{c1},
 		//			},
-		//			want: &envoy_api_v2_route.Route_Route{
-		//				Route: &envoy_api_v2_route.RouteAction{
-		//					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+		//			want: &envoy_route_v3.Route_Route{
+		//				Route: &envoy_route_v3.RouteAction{
+		//					ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
 		//						Cluster: "default/kuard/8080/da39a3ee5e",
 		//					},
 		//					IdleTimeout: protobuf.Duration(600 * time.Second),
@@ -260,9 +259,9 @@ func TestRouteRoute(t *testing.T) {
 		//				},
 		//				Clusters: []*dag.Cluster{c1},
 		//			},
-		//			want: &envoy_api_v2_route.Route_Route{
-		//				Route: &envoy_api_v2_route.RouteAction{
-		//					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+		//			want: &envoy_route_v3.Route_Route{
+		//				Route: &envoy_route_v3.RouteAction{
+		//					ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
 		//						Cluster: "default/kuard/8080/da39a3ee5e",
 		//					},
 		//					IdleTimeout: protobuf.Duration(0),
@@ -274,14 +273,14 @@ func TestRouteRoute(t *testing.T) {
 			route: &dag.Route{
 				Clusters: []*dag.Cluster{c2},
 			},
-			want: &envoy_api_v2_route.Route_Route{
-				Route: &envoy_api_v2_route.RouteAction{
-					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+			want: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
 						Cluster: "default/kuard/8080/e4f81994fe",
 					},
-					HashPolicy: []*envoy_api_v2_route.RouteAction_HashPolicy{{
-						PolicySpecifier: &envoy_api_v2_route.RouteAction_HashPolicy_Cookie_{
-							Cookie: &envoy_api_v2_route.RouteAction_HashPolicy_Cookie{
+					HashPolicy: []*envoy_route_v3.RouteAction_HashPolicy{{
+						PolicySpecifier: &envoy_route_v3.RouteAction_HashPolicy_Cookie_{
+							Cookie: &envoy_route_v3.RouteAction_HashPolicy_Cookie{
 								Name: "X-Contour-Session-Affinity",
 								Ttl:  protobuf.Duration(0),
 								Path: "/",
@@ -295,11 +294,11 @@ func TestRouteRoute(t *testing.T) {
 			route: &dag.Route{
 				Clusters: []*dag.Cluster{c2, c2},
 			},
-			want: &envoy_api_v2_route.Route_Route{
-				Route: &envoy_api_v2_route.RouteAction{
-					ClusterSpecifier: &envoy_api_v2_route.RouteAction_WeightedClusters{
-						WeightedClusters: &envoy_api_v2_route.WeightedCluster{
-							Clusters: []*envoy_api_v2_route.WeightedCluster_ClusterWeight{{
+			want: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_WeightedClusters{
+						WeightedClusters: &envoy_route_v3.WeightedCluster{
+							Clusters: []*envoy_route_v3.WeightedCluster_ClusterWeight{{
 								Name:   "default/kuard/8080/e4f81994fe",
 								Weight: protobuf.UInt32(1),
 							}, {
@@ -309,9 +308,9 @@ func TestRouteRoute(t *testing.T) {
 							TotalWeight: protobuf.UInt32(2),
 						},
 					},
-					HashPolicy: []*envoy_api_v2_route.RouteAction_HashPolicy{{
-						PolicySpecifier: &envoy_api_v2_route.RouteAction_HashPolicy_Cookie_{
-							Cookie: &envoy_api_v2_route.RouteAction_HashPolicy_Cookie{
+					HashPolicy: []*envoy_route_v3.RouteAction_HashPolicy{{
+						PolicySpecifier: &envoy_route_v3.RouteAction_HashPolicy_Cookie_{
+							Cookie: &envoy_route_v3.RouteAction_HashPolicy_Cookie{
 								Name: "X-Contour-Session-Affinity",
 								Ttl:  protobuf.Duration(0),
 								Path: "/",
@@ -325,11 +324,11 @@ func TestRouteRoute(t *testing.T) {
 			route: &dag.Route{
 				Clusters: []*dag.Cluster{c2, c1},
 			},
-			want: &envoy_api_v2_route.Route_Route{
-				Route: &envoy_api_v2_route.RouteAction{
-					ClusterSpecifier: &envoy_api_v2_route.RouteAction_WeightedClusters{
-						WeightedClusters: &envoy_api_v2_route.WeightedCluster{
-							Clusters: []*envoy_api_v2_route.WeightedCluster_ClusterWeight{{
+			want: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_WeightedClusters{
+						WeightedClusters: &envoy_route_v3.WeightedCluster{
+							Clusters: []*envoy_route_v3.WeightedCluster_ClusterWeight{{
 								Name:   "default/kuard/8080/da39a3ee5e",
 								Weight: protobuf.UInt32(1),
 							}, {
@@ -339,9 +338,9 @@ func TestRouteRoute(t *testing.T) {
 							TotalWeight: protobuf.UInt32(2),
 						},
 					},
-					HashPolicy: []*envoy_api_v2_route.RouteAction_HashPolicy{{
-						PolicySpecifier: &envoy_api_v2_route.RouteAction_HashPolicy_Cookie_{
-							Cookie: &envoy_api_v2_route.RouteAction_HashPolicy_Cookie{
+					HashPolicy: []*envoy_route_v3.RouteAction_HashPolicy{{
+						PolicySpecifier: &envoy_route_v3.RouteAction_HashPolicy_Cookie_{
+							Cookie: &envoy_route_v3.RouteAction_HashPolicy_Cookie{
 								Name: "X-Contour-Session-Affinity",
 								Ttl:  protobuf.Duration(0),
 								Path: "/",
@@ -364,7 +363,7 @@ func TestRouteRoute(t *testing.T) {
 func TestWeightedClusters(t *testing.T) {
 	tests := map[string]struct {
 		clusters []*dag.Cluster
-		want     *envoy_api_v2_route.WeightedCluster
+		want     *envoy_route_v3.WeightedCluster
 	}{
 		"multiple services w/o weights": {
 			clusters: []*dag.Cluster{{
@@ -384,8 +383,8 @@ func TestWeightedClusters(t *testing.T) {
 					},
 				},
 			}},
-			want: &envoy_api_v2_route.WeightedCluster{
-				Clusters: []*envoy_api_v2_route.WeightedCluster_ClusterWeight{{
+			want: &envoy_route_v3.WeightedCluster{
+				Clusters: []*envoy_route_v3.WeightedCluster_ClusterWeight{{
 					Name:   "default/kuard/8080/da39a3ee5e",
 					Weight: protobuf.UInt32(1),
 				}, {
@@ -415,8 +414,8 @@ func TestWeightedClusters(t *testing.T) {
 				},
 				Weight: 20,
 			}},
-			want: &envoy_api_v2_route.WeightedCluster{
-				Clusters: []*envoy_api_v2_route.WeightedCluster_ClusterWeight{{
+			want: &envoy_route_v3.WeightedCluster{
+				Clusters: []*envoy_route_v3.WeightedCluster_ClusterWeight{{
 					Name:   "default/kuard/8080/da39a3ee5e",
 					Weight: protobuf.UInt32(80),
 				}, {
@@ -454,8 +453,8 @@ func TestWeightedClusters(t *testing.T) {
 					},
 				},
 			}},
-			want: &envoy_api_v2_route.WeightedCluster{
-				Clusters: []*envoy_api_v2_route.WeightedCluster_ClusterWeight{{
+			want: &envoy_route_v3.WeightedCluster{
+				Clusters: []*envoy_route_v3.WeightedCluster_ClusterWeight{{
 					Name:   "default/kuard/8080/da39a3ee5e",
 					Weight: protobuf.UInt32(80),
 				}, {
@@ -481,16 +480,16 @@ func TestWeightedClusters(t *testing.T) {
 func TestRouteConfiguration(t *testing.T) {
 	tests := map[string]struct {
 		name         string
-		virtualhosts []*envoy_api_v2_route.VirtualHost
-		want         *v2.RouteConfiguration
+		virtualhosts []*envoy_route_v3.VirtualHost
+		want         *envoy_route_v3.RouteConfiguration
 	}{
 
 		"empty": {
 			name: "ingress_http",
-			want: &v2.RouteConfiguration{
+			want: &envoy_route_v3.RouteConfiguration{
 				Name: "ingress_http",
-				RequestHeadersToAdd: []*envoy_api_v2_core.HeaderValueOption{{
-					Header: &envoy_api_v2_core.HeaderValue{
+				RequestHeadersToAdd: []*envoy_core_v3.HeaderValueOption{{
+					Header: &envoy_core_v3.HeaderValue{
 						Key:   "x-request-start",
 						Value: "t=%START_TIME(%s.%3f)%",
 					},
@@ -503,13 +502,13 @@ func TestRouteConfiguration(t *testing.T) {
 			virtualhosts: virtualhosts(
 				VirtualHost("www.example.com"),
 			),
-			want: &v2.RouteConfiguration{
+			want: &envoy_route_v3.RouteConfiguration{
 				Name: "ingress_https",
 				VirtualHosts: virtualhosts(
 					VirtualHost("www.example.com"),
 				),
-				RequestHeadersToAdd: []*envoy_api_v2_core.HeaderValueOption{{
-					Header: &envoy_api_v2_core.HeaderValue{
+				RequestHeadersToAdd: []*envoy_core_v3.HeaderValueOption{{
+					Header: &envoy_core_v3.HeaderValue{
 						Key:   "x-request-start",
 						Value: "t=%START_TIME(%s.%3f)%",
 					},
@@ -531,12 +530,12 @@ func TestVirtualHost(t *testing.T) {
 	tests := map[string]struct {
 		hostname string
 		port     int
-		want     *envoy_api_v2_route.VirtualHost
+		want     *envoy_route_v3.VirtualHost
 	}{
 		"default hostname": {
 			hostname: "*",
 			port:     9999,
-			want: &envoy_api_v2_route.VirtualHost{
+			want: &envoy_route_v3.VirtualHost{
 				Name:    "*",
 				Domains: []string{"*"},
 			},
@@ -544,7 +543,7 @@ func TestVirtualHost(t *testing.T) {
 		"www.example.com": {
 			hostname: "www.example.com",
 			port:     9999,
-			want: &envoy_api_v2_route.VirtualHost{
+			want: &envoy_route_v3.VirtualHost{
 				Name:    "www.example.com",
 				Domains: []string{"www.example.com", "www.example.com:*"},
 			},
@@ -560,9 +559,9 @@ func TestVirtualHost(t *testing.T) {
 
 func TestUpgradeHTTPS(t *testing.T) {
 	got := UpgradeHTTPS()
-	want := &envoy_api_v2_route.Route_Redirect{
-		Redirect: &envoy_api_v2_route.RedirectAction{
-			SchemeRewriteSpecifier: &envoy_api_v2_route.RedirectAction_HttpsRedirect{
+	want := &envoy_route_v3.Route_Redirect{
+		Redirect: &envoy_route_v3.RedirectAction{
+			SchemeRewriteSpecifier: &envoy_route_v3.RedirectAction_HttpsRedirect{
 				HttpsRedirect: true,
 			},
 		},
@@ -574,7 +573,7 @@ func TestUpgradeHTTPS(t *testing.T) {
 func TestRouteMatchNew(t *testing.T) {
 	tests := map[string]struct {
 		route *dag.Route
-		want  *envoy_api_v2_route.RouteMatch
+		want  *envoy_route_v3.RouteMatch
 	}{
 		"contains match with dashes": {
 			route: &dag.Route{
@@ -585,11 +584,11 @@ func TestRouteMatchNew(t *testing.T) {
 					Invert:    false,
 				}},
 			},
-			want: &envoy_api_v2_route.RouteMatch{
-				Headers: []*envoy_api_v2_route.HeaderMatcher{{
+			want: &envoy_route_v3.RouteMatch{
+				Headers: []*envoy_route_v3.HeaderMatcher{{
 					Name:        "x-header",
 					InvertMatch: false,
-					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
+					HeaderMatchSpecifier: &envoy_route_v3.HeaderMatcher_SafeRegexMatch{
 						SafeRegexMatch: SafeRegexMatch(".*11-22-33-44.*"),
 					},
 				}},
@@ -604,11 +603,11 @@ func TestRouteMatchNew(t *testing.T) {
 					Invert:    false,
 				}},
 			},
-			want: &envoy_api_v2_route.RouteMatch{
-				Headers: []*envoy_api_v2_route.HeaderMatcher{{
+			want: &envoy_route_v3.RouteMatch{
+				Headers: []*envoy_route_v3.HeaderMatcher{{
 					Name:        "x-header",
 					InvertMatch: false,
-					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
+					HeaderMatchSpecifier: &envoy_route_v3.HeaderMatcher_SafeRegexMatch{
 						SafeRegexMatch: SafeRegexMatch(".*11\\.22\\.33\\.44.*"),
 					},
 				}},
@@ -623,11 +622,11 @@ func TestRouteMatchNew(t *testing.T) {
 					Invert:    false,
 				}},
 			},
-			want: &envoy_api_v2_route.RouteMatch{
-				Headers: []*envoy_api_v2_route.HeaderMatcher{{
+			want: &envoy_route_v3.RouteMatch{
+				Headers: []*envoy_route_v3.HeaderMatcher{{
 					Name:        "x-header",
 					InvertMatch: false,
-					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
+					HeaderMatchSpecifier: &envoy_route_v3.HeaderMatcher_SafeRegexMatch{
 						SafeRegexMatch: SafeRegexMatch(".*11\\.\\[22\\]\\.\\*33\\.44.*"),
 					},
 				}},
@@ -639,8 +638,8 @@ func TestRouteMatchNew(t *testing.T) {
 					Prefix: "/foo",
 				},
 			},
-			want: &envoy_api_v2_route.RouteMatch{
-				PathSpecifier: &envoy_api_v2_route.RouteMatch_Prefix{
+			want: &envoy_route_v3.RouteMatch{
+				PathSpecifier: &envoy_route_v3.RouteMatch_Prefix{
 					Prefix: "/foo",
 				},
 			},
@@ -651,8 +650,8 @@ func TestRouteMatchNew(t *testing.T) {
 					Regex: "/v.1/*",
 				},
 			},
-			want: &envoy_api_v2_route.RouteMatch{
-				PathSpecifier: &envoy_api_v2_route.RouteMatch_SafeRegex{
+			want: &envoy_route_v3.RouteMatch{
+				PathSpecifier: &envoy_route_v3.RouteMatch_SafeRegex{
 					// note, unlike header conditions this is not a quoted regex because
 					// the value comes directly from the Ingress.Paths.Path value which
 					// is permitted to be a bare regex.
@@ -670,4 +669,4 @@ func TestRouteMatchNew(t *testing.T) {
 	}
 }
 
-func virtualhosts(v ...*envoy_api_v2_route.VirtualHost) []*envoy_api_v2_route.VirtualHost { return v }
+func virtualhosts(v ...*envoy_route_v3.VirtualHost) []*envoy_route_v3.VirtualHost { return v }
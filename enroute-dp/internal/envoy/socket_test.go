@@ -16,21 +16,21 @@ package envoy
 import (
 	"testing"
 
-	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
-	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	"github.com/saarasio/enroute/enroute-dp/internal/assert"
 )
 
 func TestUpstreamTLSTransportSocket(t *testing.T) {
 	tests := map[string]struct {
-		ctxt *envoy_api_v2_auth.UpstreamTlsContext
-		want *envoy_api_v2_core.TransportSocket
+		ctxt *envoy_tls_v3.UpstreamTlsContext
+		want *envoy_core_v3.TransportSocket
 	}{
 		"h2": {
 			ctxt: UpstreamTLSContext(nil, "", "h2"),
-			want: &envoy_api_v2_core.TransportSocket{
+			want: &envoy_core_v3.TransportSocket{
 				Name: "tls",
-				ConfigType: &envoy_api_v2_core.TransportSocket_TypedConfig{
+				ConfigType: &envoy_core_v3.TransportSocket_TypedConfig{
 					TypedConfig: toAny(UpstreamTLSContext(nil, "", "h2")),
 				},
 			},
@@ -47,15 +47,15 @@ func TestUpstreamTLSTransportSocket(t *testing.T) {
 
 func TestDownstreamTLSTransportSocket(t *testing.T) {
 	tests := map[string]struct {
-		ctxt *envoy_api_v2_auth.DownstreamTlsContext
-		want *envoy_api_v2_core.TransportSocket
+		ctxt *envoy_tls_v3.DownstreamTlsContext
+		want *envoy_core_v3.TransportSocket
 	}{
 		"default/tls": {
-			ctxt: DownstreamTLSContext("default/tls", envoy_api_v2_auth.TlsParameters_TLSv1_1, "h2", "http/1.1"),
-			want: &envoy_api_v2_core.TransportSocket{
+			ctxt: DownstreamTLSContext("default/tls", TLSParams(envoy_tls_v3.TlsParameters_TLSv1_1, envoy_tls_v3.TlsParameters_TLS_AUTO, nil, nil), "h2", "http/1.1"),
+			want: &envoy_core_v3.TransportSocket{
 				Name: "tls",
-				ConfigType: &envoy_api_v2_core.TransportSocket_TypedConfig{
-					TypedConfig: toAny(DownstreamTLSContext("default/tls", envoy_api_v2_auth.TlsParameters_TLSv1_1, "h2", "http/1.1")),
+				ConfigType: &envoy_core_v3.TransportSocket_TypedConfig{
+					TypedConfig: toAny(DownstreamTLSContext("default/tls", TLSParams(envoy_tls_v3.TlsParameters_TLSv1_1, envoy_tls_v3.TlsParameters_TLS_AUTO, nil, nil), "h2", "http/1.1")),
 				},
 			},
 		},
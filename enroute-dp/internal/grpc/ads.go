@@ -0,0 +1,300 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/saarasio/enroute/enroute-dp/internal/envoy"
+	"github.com/sirupsen/logrus"
+)
+
+// adsOrder is the order in which responses are sent on an ADS stream when
+// more than one resource type has changed. Clusters are sent before the
+// endpoints which populate them, and listeners before the routes they
+// reference, so Envoy never holds a reference to a resource it has not
+// yet been sent.
+var adsOrder = []string{
+	resource.ClusterType,
+	resource.EndpointType,
+	resource.ListenerType,
+	resource.RouteType,
+	resource.SecretType,
+}
+
+// adsType is the state of one resource type multiplexed on an ADS stream.
+type adsType struct {
+	Resource
+	ch         chan int
+	last       int      // version of the last notification received
+	names      []string // resource names of the last request
+	registered bool     // ch is registered for a notification
+	pending    bool     // a response is due
+	sent       sentResources
+}
+
+// register registers ch for a notification of a change to the resources
+// named by the last request, if it is not registered already.
+func (t *adsType) register() {
+	if !t.registered {
+		t.registered = true
+		t.Register(t.ch, t.last, t.names...)
+	}
+}
+
+// notified records a notification of version last, which is now due to be sent.
+func (t *adsType) notified(last int) {
+	t.registered = false
+	t.last = last
+	t.pending = true
+}
+
+type adsUpdate struct {
+	typeURL string
+	last    int
+}
+
+// ads processes an Aggregated Discovery Service stream of DiscoveryRequests
+// for every registered resource type.
+func (xh *xdsHandler) ads(st grpcStream) (err error) {
+	connection := xh.connections.next()
+	log := xh.WithField("connection", connection).WithField("ads", true)
+	defer xh.status.closed(connection)
+
+	defer func() {
+		if err != nil {
+			log.WithError(err).Error("stream terminated")
+		} else {
+			log.Info("stream terminated")
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(st.Context())
+	defer cancel()
+
+	// requests are received on their own goroutine so that the stream
+	// can wait for a request and a notification at the same time.
+	requests := make(chan *envoy_discovery_v3.DiscoveryRequest)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			req, err := st.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case requests <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	updates := make(chan adsUpdate)
+	types := make(map[string]*adsType)
+	defer func() {
+		for _, t := range types {
+			t.Unregister(t.ch)
+		}
+	}()
+
+	// the resources served on this stream are chosen by the node
+	// which sent the first request.
+	var resources map[string]Resource
+
+	for {
+		select {
+		case req := <-requests:
+			if resources == nil {
+				resources, err = xh.resourcesFor(req.Node)
+				if err != nil {
+					return err
+				}
+			}
+			t, ok := types[req.TypeUrl]
+			if !ok {
+				r, ok := resources[req.TypeUrl]
+				if !ok {
+					return fmt.Errorf("no resource registered for typeURL %q", req.TypeUrl)
+				}
+				t = &adsType{Resource: r, ch: make(chan int, 1), last: -1}
+				types[req.TypeUrl] = t
+				go forward(ctx, req.TypeUrl, t.ch, updates)
+			}
+
+			// record whether Envoy accepted the last response of this type.
+			xh.status.request(connection, req)
+			if req.ErrorDetail != nil {
+				log.WithField("type_url", req.TypeUrl).WithField("response_nonce", req.ResponseNonce).WithField("error_detail", req.ErrorDetail.Message).Warn("config rejected")
+			}
+
+			log.WithField("version_info", req.VersionInfo).WithField("resource_names", req.ResourceNames).WithField("type_url", req.TypeUrl).WithField("response_nonce", req.ResponseNonce).Info("stream_wait")
+
+			// Envoy changes the resource names of a type it already
+			// holds as clusters and listeners come and go. Those
+			// resources are sent now, rather than on the next change.
+			// ch is registered for changes to the resources named, so it
+			// is registered again for those newly named.
+			if t.last >= 0 && !equalNames(t.names, req.ResourceNames) {
+				t.pending = true
+				t.sent.retain(req.ResourceNames)
+				if t.registered {
+					t.Unregister(t.ch)
+					t.registered = false
+				}
+			}
+			t.names = req.ResourceNames
+
+			// at most one notification is outstanding per type, as
+			// Register sends to ch, which has room for one value.
+			t.register()
+		case u := <-updates:
+			types[u.typeURL].notified(u.last)
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		// collect notifications which have already arrived so that
+		// they are sent in adsOrder.
+	drain:
+		for {
+			select {
+			case u := <-updates:
+				types[u.typeURL].notified(u.last)
+			default:
+				break drain
+			}
+		}
+
+		if err := xh.adsRespond(st, connection, log, types); err != nil {
+			return err
+		}
+	}
+}
+
+// forward passes the notifications received on ch to updates until ctx is done.
+func forward(ctx context.Context, typeURL string, ch <-chan int, updates chan<- adsUpdate) {
+	for {
+		select {
+		case last := <-ch:
+			select {
+			case updates <- adsUpdate{typeURL: typeURL, last: last}:
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// adsRespond sends a response for each pending type, in adsOrder.
+func (xh *xdsHandler) adsRespond(st grpcStream, connection uint64, log logrus.FieldLogger, types map[string]*adsType) error {
+	for _, typeURL := range adsTypeURLs(types) {
+		t := types[typeURL]
+		if !t.pending {
+			continue
+		}
+
+		var contents []proto.Message
+		switch len(t.names) {
+		case 0:
+			contents = t.Contents()
+		default:
+			contents = t.Query(t.names)
+		}
+
+		contents, changed, err := t.sent.changes(contents, len(t.names) > 0 && partialTypes[typeURL])
+		if err != nil {
+			return err
+		}
+		if !changed {
+			// nothing to send, so Envoy will not acknowledge this
+			// version. Wait for the next notification instead.
+			t.pending = false
+			t.register()
+			log.WithField("type_url", typeURL).WithField("version_info", t.last).Debug("no changes")
+			continue
+		}
+
+		// config sources in the resources point back at this stream.
+		resources := make([]proto.Message, 0, len(contents))
+		for _, r := range contents {
+			resources = append(resources, envoy.ADSResource(r))
+		}
+
+		any, err := toAny(typeURL, resources)
+		if err != nil {
+			return err
+		}
+
+		resp := &envoy_discovery_v3.DiscoveryResponse{
+			VersionInfo: strconv.Itoa(t.last),
+			Resources:   any,
+			TypeUrl:     typeURL,
+			Nonce:       strconv.Itoa(t.last),
+		}
+		if err := st.Send(resp); err != nil {
+			return err
+		}
+		t.pending = false
+		xh.status.sent(connection, resp.TypeUrl, resp.VersionInfo)
+		log.WithField("type_url", typeURL).WithField("version_info", resp.VersionInfo).WithField("count", len(resources)).Info("response")
+	}
+	return nil
+}
+
+// adsTypeURLs returns the type URLs of types in adsOrder, followed by
+// any others in lexical order.
+func adsTypeURLs(types map[string]*adsType) []string {
+	var typeURLs, others []string
+	for _, typeURL := range adsOrder {
+		if _, ok := types[typeURL]; ok {
+			typeURLs = append(typeURLs, typeURL)
+		}
+	}
+	for typeURL := range types {
+		if !contains(adsOrder, typeURL) {
+			others = append(others, typeURL)
+		}
+	}
+	sort.Strings(others)
+	return append(typeURLs, others...)
+}
+
+func contains(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// equalNames returns true if a and b hold the same resource names, in any order.
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	names := make(map[string]int, len(a))
+	for _, n := range a {
+		names[n]++
+	}
+	for _, n := range b {
+		if names[n] == 0 {
+			return false
+		}
+		names[n]--
+	}
+	return true
+}
@@ -0,0 +1,288 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"testing"
+
+	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/saarasio/enroute/enroute-dp/internal/contour"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestADSRespondOrder(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	newType := func(typeURL string, pending bool) *adsType {
+		return &adsType{
+			Resource: &mockResource{
+				contents: func() []proto.Message { return nil },
+				typeurl:  func() string { return typeURL },
+			},
+			pending: pending,
+		}
+	}
+	types := map[string]*adsType{
+		"com.heptio.potato":    newType("com.heptio.potato", true),
+		resource.RouteType:     newType(resource.RouteType, true),
+		resource.ListenerType:  newType(resource.ListenerType, true),
+		resource.SecretType:    newType(resource.SecretType, false),
+		resource.EndpointType:  newType(resource.EndpointType, true),
+		resource.ClusterType:   newType(resource.ClusterType, true),
+		"com.heptio.artichoke": newType("com.heptio.artichoke", true),
+	}
+
+	var got []string
+	xh := xdsHandler{FieldLogger: log}
+	err := xh.adsRespond(&mockStream{
+		send: func(resp *envoy_discovery_v3.DiscoveryResponse) error {
+			got = append(got, resp.TypeUrl)
+			return nil
+		},
+	}, 1, log, types)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		resource.ClusterType,
+		resource.EndpointType,
+		resource.ListenerType,
+		resource.RouteType,
+		"com.heptio.artichoke",
+		"com.heptio.potato",
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected:\n%v\ngot:\n%v", want, got)
+	}
+	for typeURL, at := range types {
+		if at.pending {
+			t.Errorf("%s: expected response to be sent", typeURL)
+		}
+	}
+}
+
+func TestADSStream(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	xh := xdsHandler{
+		FieldLogger: log,
+		resources: map[string]Resource{
+			resource.EndpointType: &mockResource{
+				register: func(ch chan int, last int) {
+					// a single version is available.
+					if last < 0 {
+						ch <- 0
+					}
+				},
+				query: func(names []string) []proto.Message {
+					var cla []proto.Message
+					for _, name := range names {
+						cla = append(cla, &envoy_endpoint_v3.ClusterLoadAssignment{ClusterName: name})
+					}
+					return cla
+				},
+				typeurl: func() string { return resource.EndpointType },
+			},
+		},
+	}
+
+	requests := make(chan *envoy_discovery_v3.DiscoveryRequest, 1)
+	requests <- &envoy_discovery_v3.DiscoveryRequest{TypeUrl: resource.EndpointType, ResourceNames: []string{"default/kuard"}}
+
+	done := make(chan struct{})
+	var got [][]string
+	err := xh.ads(&mockStream{
+		context: context.Background,
+		recv: func() (*envoy_discovery_v3.DiscoveryRequest, error) {
+			select {
+			case req := <-requests:
+				return req, nil
+			case <-done:
+				return nil, io.EOF
+			}
+		},
+		send: func(resp *envoy_discovery_v3.DiscoveryResponse) error {
+			var names []string
+			for _, any := range resp.Resources {
+				var cla envoy_endpoint_v3.ClusterLoadAssignment
+				if err := proto.Unmarshal(any.Value, &cla); err != nil {
+					t.Fatal(err)
+				}
+				names = append(names, cla.ClusterName)
+			}
+			got = append(got, names)
+			switch len(got) {
+			case 1:
+				// acknowledge, subscribing to another cluster's endpoints.
+				requests <- &envoy_discovery_v3.DiscoveryRequest{TypeUrl: resource.EndpointType, ResourceNames: []string{"default/kuard", "default/nginx"}, VersionInfo: "0", ResponseNonce: "0"}
+			case 2:
+				close(done)
+			}
+			return nil
+		},
+	})
+	if err != io.EOF {
+		t.Fatalf("expected: %v, got: %v", io.EOF, err)
+	}
+
+	// the second request changes the resource names, which are
+	// sent without waiting for the endpoints to change. Endpoints
+	// already sent are not sent again.
+	want := [][]string{
+		{"default/kuard"},
+		{"default/nginx"},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected:\n%v\ngot:\n%v", want, got)
+	}
+}
+
+func TestEqualNames(t *testing.T) {
+	tests := map[string]struct {
+		a, b []string
+		want bool
+	}{
+		"nil":         {want: true},
+		"same order":  {a: []string{"a", "b"}, b: []string{"a", "b"}, want: true},
+		"other order": {a: []string{"a", "b"}, b: []string{"b", "a"}, want: true},
+		"added":       {a: []string{"a"}, b: []string{"a", "b"}, want: false},
+		"replaced":    {a: []string{"a", "a"}, b: []string{"a", "b"}, want: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := equalNames(tc.a, tc.b); got != tc.want {
+				t.Fatalf("expected: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+// benchResource counts the streams waiting for a notification, which
+// register again once they have handled the last.
+type benchResource struct {
+	Resource
+	hinted  bool
+	waiting *sync.WaitGroup
+}
+
+func (r *benchResource) Register(ch chan int, last int, names ...string) {
+	if !r.hinted {
+		names = nil
+	}
+	r.Resource.Register(ch, last, names...)
+	if last >= 0 {
+		r.waiting.Done()
+	}
+}
+
+// BenchmarkADSEndpointsUpdate measures the work done by 5000 ADS streams,
+// each requesting the endpoints of its own cluster, when one cluster's
+// endpoints change. Unlike BenchmarkEndpointsTranslatorUpdate the streams
+// are served by the ADS handler, which is done with a notification once it
+// has either sent a response and had it acknowledged, or found no changes.
+func BenchmarkADSEndpointsUpdate(b *testing.B) {
+	const clusters = 5000
+
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	endpoints := func(name, ip string) *v1.Endpoints {
+		return &v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Subsets: []v1.EndpointSubset{{
+				Addresses: []v1.EndpointAddress{{IP: ip}},
+				Ports:     []v1.EndpointPort{{Port: 8080}},
+			}},
+		}
+	}
+
+	run := func(b *testing.B, hinted bool) {
+		var et contour.EndpointsTranslator
+		for i := 0; i < clusters; i++ {
+			et.OnAdd(endpoints(fmt.Sprintf("svc%d", i), "192.168.183.24"))
+		}
+
+		var waiting sync.WaitGroup
+		xh := xdsHandler{
+			FieldLogger: log,
+			resources: map[string]Resource{
+				resource.EndpointType: &benchResource{Resource: &et, hinted: hinted, waiting: &waiting},
+			},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var streams sync.WaitGroup
+		defer func() {
+			cancel()
+			streams.Wait()
+		}()
+
+		waiting.Add(clusters)
+		for i := 0; i < clusters; i++ {
+			req := &envoy_discovery_v3.DiscoveryRequest{
+				TypeUrl:       resource.EndpointType,
+				ResourceNames: []string{fmt.Sprintf("default/svc%d", i)},
+			}
+			requests := make(chan *envoy_discovery_v3.DiscoveryRequest, 1)
+			requests <- req
+			streams.Add(1)
+			go func() {
+				defer streams.Done()
+				xh.ads(&mockStream{
+					context: func() context.Context { return ctx },
+					recv: func() (*envoy_discovery_v3.DiscoveryRequest, error) {
+						select {
+						case req := <-requests:
+							return req, nil
+						case <-ctx.Done():
+							return nil, io.EOF
+						}
+					},
+					send: func(resp *envoy_discovery_v3.DiscoveryResponse) error {
+						// acknowledge each response.
+						ack := proto.Clone(req).(*envoy_discovery_v3.DiscoveryRequest)
+						ack.VersionInfo = resp.VersionInfo
+						ack.ResponseNonce = resp.Nonce
+						requests <- ack
+						return nil
+					},
+				})
+			}()
+		}
+		waiting.Wait()
+
+		woken := 1
+		if !hinted {
+			woken = clusters
+		}
+		old := endpoints("svc0", "192.168.183.24")
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			ep := endpoints("svc0", fmt.Sprintf("192.168.183.%d", i%2))
+			waiting.Add(woken)
+			et.OnUpdate(old, ep)
+			waiting.Wait()
+			old = ep
+		}
+		b.StopTimer()
+	}
+
+	b.Run("notify all", func(b *testing.B) { run(b, false) })
+	b.Run("notify changed", func(b *testing.B) { run(b, true) })
+}
@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package grpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/saarasio/enroute/enroute-dp/internal/metrics"
+)
+
+// StreamStatus records the config sent to, and acknowledged by, an Envoy
+// on one xDS stream for one type URL.
+type StreamStatus struct {
+	Connection       uint64 `json:"connection"`
+	NodeID           string `json:"node_id"`
+	TypeURL          string `json:"type_url"`
+	LastSentVersion  string `json:"last_sent_version"`
+	LastAckedVersion string `json:"last_acked_version"`
+
+	// NackedVersion and NackError are set while the last
+	// config sent on the stream is rejected.
+	NackedVersion string `json:"nacked_version,omitempty"`
+	NackError     string `json:"nack_error,omitempty"`
+
+	// NackCount is the number of configs rejected on the stream.
+	NackCount int `json:"nack_count"`
+}
+
+type streamKey struct {
+	connection uint64
+	typeURL    string
+}
+
+// ConfigStatus tracks the StreamStatus of every connected Envoy.
+type ConfigStatus struct {
+	// Metrics, if set, receives the sent and acked versions
+	// and the rejected configs of every stream.
+	*metrics.Metrics
+
+	// OnRejectionChange, if set, is called when an Envoy rejects
+	// a config, or a rejected config is superseded.
+	OnRejectionChange func()
+
+	mu      sync.Mutex
+	streams map[streamKey]*StreamStatus
+}
+
+// request records the ACK or NACK carried by req.
+func (cs *ConfigStatus) request(connection uint64, req *envoy_discovery_v3.DiscoveryRequest) {
+	if cs == nil {
+		return
+	}
+
+	cs.mu.Lock()
+	st := cs.stream(connection, req.TypeUrl)
+	if st.NodeID == "" && req.Node != nil {
+		st.NodeID = req.Node.Id
+	}
+
+	changed := false
+	switch {
+	case req.ResponseNonce == "":
+		// the initial request on the stream, nothing to acknowledge.
+	case req.ErrorDetail != nil:
+		// the nonce is the version of the rejected config.
+		st.NackedVersion = req.ResponseNonce
+		st.NackError = req.ErrorDetail.Message
+		st.NackCount++
+		changed = true
+		if cs.Metrics != nil {
+			cs.XDSNackCounter.WithLabelValues(st.NodeID, st.TypeURL).Inc()
+		}
+	default:
+		st.LastAckedVersion = req.VersionInfo
+		if st.NackedVersion != "" {
+			st.NackedVersion, st.NackError = "", ""
+			changed = true
+		}
+	}
+	cs.updateMetrics()
+	cs.mu.Unlock()
+
+	if changed && cs.OnRejectionChange != nil {
+		cs.OnRejectionChange()
+	}
+}
+
+// sent records the version of the config sent on a stream.
+func (cs *ConfigStatus) sent(connection uint64, typeURL, version string) {
+	if cs == nil {
+		return
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.stream(connection, typeURL).LastSentVersion = version
+	cs.updateMetrics()
+}
+
+// closed forgets the streams of a connection.
+func (cs *ConfigStatus) closed(connection uint64) {
+	if cs == nil {
+		return
+	}
+
+	cs.mu.Lock()
+	changed := false
+	for k, st := range cs.streams {
+		if k.connection == connection {
+			changed = changed || st.NackedVersion != ""
+			delete(cs.streams, k)
+		}
+	}
+	cs.updateMetrics()
+	cs.mu.Unlock()
+
+	if changed && cs.OnRejectionChange != nil {
+		cs.OnRejectionChange()
+	}
+}
+
+// stream returns the StreamStatus of connection and typeURL, creating it on first use.
+// cs.mu must be held.
+func (cs *ConfigStatus) stream(connection uint64, typeURL string) *StreamStatus {
+	if cs.streams == nil {
+		cs.streams = make(map[streamKey]*StreamStatus)
+	}
+	k := streamKey{connection: connection, typeURL: typeURL}
+	st, ok := cs.streams[k]
+	if !ok {
+		st = &StreamStatus{Connection: connection, TypeURL: typeURL}
+		cs.streams[k] = st
+	}
+	return st
+}
+
+// updateMetrics writes the status of every stream to cs.Metrics.
+// cs.mu must be held.
+func (cs *ConfigStatus) updateMetrics() {
+	if cs.Metrics == nil {
+		return
+	}
+
+	m := metrics.XDSMetric{
+		Sent:     make(map[metrics.XDSMeta]float64),
+		Acked:    make(map[metrics.XDSMeta]float64),
+		Rejected: make(map[metrics.XDSMeta]int),
+	}
+	for _, st := range cs.streams {
+		meta := metrics.XDSMeta{NodeID: st.NodeID, TypeURL: st.TypeURL}
+		if v, err := strconv.ParseFloat(st.LastSentVersion, 64); err == nil {
+			m.Sent[meta] = v
+		}
+		if v, err := strconv.ParseFloat(st.LastAckedVersion, 64); err == nil {
+			m.Acked[meta] = v
+		}
+		if st.NackedVersion != "" {
+			m.Rejected[meta] = 1
+		} else if _, ok := m.Rejected[meta]; !ok {
+			m.Rejected[meta] = 0
+		}
+	}
+	cs.SetXDSMetric(m)
+}
+
+// Streams returns the status of every stream, ordered by connection and type URL.
+func (cs *ConfigStatus) Streams() []StreamStatus {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	streams := make([]StreamStatus, 0, len(cs.streams))
+	for _, st := range cs.streams {
+		streams = append(streams, *st)
+	}
+	sort.Slice(streams, func(i, j int) bool {
+		if streams[i].Connection != streams[j].Connection {
+			return streams[i].Connection < streams[j].Connection
+		}
+		return streams[i].TypeURL < streams[j].TypeURL
+	})
+	return streams
+}
+
+// Rejected returns the status of the streams whose last config was rejected.
+func (cs *ConfigStatus) Rejected() []StreamStatus {
+	if cs == nil {
+		return nil
+	}
+
+	var rejected []StreamStatus
+	for _, st := range cs.Streams() {
+		if st.NackedVersion != "" {
+			rejected = append(rejected, st)
+		}
+	}
+	return rejected
+}
+
+// ServeHTTP writes the status of every stream as JSON.
+func (cs *ConfigStatus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(cs.Streams()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
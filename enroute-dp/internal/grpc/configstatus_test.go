@@ -0,0 +1,171 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/saarasio/enroute/enroute-dp/internal/metrics"
+	"github.com/sirupsen/logrus"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+)
+
+func TestConfigStatus(t *testing.T) {
+	const typeURL = "com.heptio.potato"
+	node := &envoy_core_v3.Node{Id: "envoy-1"}
+
+	tests := map[string]struct {
+		requests []*envoy_discovery_v3.DiscoveryRequest
+		want     StreamStatus
+		changes  int
+	}{
+		"initial request": {
+			requests: []*envoy_discovery_v3.DiscoveryRequest{
+				{TypeUrl: typeURL, Node: node},
+			},
+			want: StreamStatus{Connection: 1, NodeID: "envoy-1", TypeURL: typeURL, LastSentVersion: "0"},
+		},
+		"ack": {
+			requests: []*envoy_discovery_v3.DiscoveryRequest{
+				{TypeUrl: typeURL, Node: node},
+				{TypeUrl: typeURL, Node: node, VersionInfo: "0", ResponseNonce: "0"},
+			},
+			want: StreamStatus{Connection: 1, NodeID: "envoy-1", TypeURL: typeURL, LastSentVersion: "1", LastAckedVersion: "0"},
+		},
+		"nack": {
+			requests: []*envoy_discovery_v3.DiscoveryRequest{
+				{TypeUrl: typeURL, Node: node},
+				{TypeUrl: typeURL, Node: node, VersionInfo: "0", ResponseNonce: "0"},
+				{TypeUrl: typeURL, Node: node, VersionInfo: "0", ResponseNonce: "1", ErrorDetail: &rpcstatus.Status{Message: "cluster default/kuard/80/da39a3ee5e is invalid"}},
+			},
+			want: StreamStatus{
+				Connection:       1,
+				NodeID:           "envoy-1",
+				TypeURL:          typeURL,
+				LastSentVersion:  "2",
+				LastAckedVersion: "0",
+				NackedVersion:    "1",
+				NackError:        "cluster default/kuard/80/da39a3ee5e is invalid",
+				NackCount:        1,
+			},
+			// the rejection, and the rejected stream closing.
+			changes: 2,
+		},
+		"nack superseded": {
+			requests: []*envoy_discovery_v3.DiscoveryRequest{
+				{TypeUrl: typeURL, Node: node},
+				{TypeUrl: typeURL, Node: node, VersionInfo: "", ResponseNonce: "0", ErrorDetail: &rpcstatus.Status{Message: "invalid"}},
+				{TypeUrl: typeURL, Node: node, VersionInfo: "1", ResponseNonce: "1"},
+			},
+			want:    StreamStatus{Connection: 1, NodeID: "envoy-1", TypeURL: typeURL, LastSentVersion: "2", LastAckedVersion: "1", NackCount: 1},
+			changes: 2,
+		},
+	}
+
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			changes := 0
+			cs := &ConfigStatus{
+				Metrics:           metrics.NewMetrics(prometheus.NewRegistry()),
+				OnRejectionChange: func() { changes++ },
+			}
+			// each notification changes the contents, so is sent.
+			version := 0
+			xh := xdsHandler{
+				FieldLogger: log,
+				resources: map[string]Resource{
+					typeURL: &mockResource{
+						register: func(ch chan int, i int) {
+							ch <- i + 1
+						},
+						contents: func() []proto.Message {
+							version++
+							return []proto.Message{&envoy_endpoint_v3.ClusterLoadAssignment{ClusterName: strconv.Itoa(version)}}
+						},
+						typeurl: func() string { return typeURL },
+					},
+				},
+				status: cs,
+			}
+
+			var got []StreamStatus
+			requests := tc.requests
+			err := xh.stream(&mockStream{
+				context: context.Background,
+				recv: func() (*envoy_discovery_v3.DiscoveryRequest, error) {
+					if len(requests) == 0 {
+						// record the status before the stream is closed.
+						got = cs.Streams()
+						return nil, io.EOF
+					}
+					req := requests[0]
+					requests = requests[1:]
+					return req, nil
+				},
+				send: func(resp *envoy_discovery_v3.DiscoveryResponse) error {
+					return nil
+				},
+			})
+			if err != io.EOF {
+				t.Fatalf("expected: %v, got: %v", io.EOF, err)
+			}
+
+			want := []StreamStatus{tc.want}
+			if !reflect.DeepEqual(want, got) {
+				t.Fatalf("expected:\n%+v\ngot:\n%+v", want, got)
+			}
+			if changes != tc.changes {
+				t.Fatalf("expected %d rejection changes, got %d", tc.changes, changes)
+			}
+			if len(cs.Streams()) != 0 {
+				t.Fatalf("expected closed stream to be forgotten, got %+v", cs.Streams())
+			}
+		})
+	}
+}
+
+func TestConfigStatusServeHTTP(t *testing.T) {
+	cs := &ConfigStatus{}
+	cs.request(1, &envoy_discovery_v3.DiscoveryRequest{TypeUrl: "com.heptio.potato", Node: &envoy_core_v3.Node{Id: "envoy-1"}})
+	cs.sent(1, "com.heptio.potato", "1")
+	cs.request(1, &envoy_discovery_v3.DiscoveryRequest{TypeUrl: "com.heptio.potato", ResponseNonce: "1", ErrorDetail: &rpcstatus.Status{Message: "invalid"}})
+
+	rec := httptest.NewRecorder()
+	cs.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/xds/status", nil))
+
+	var got []StreamStatus
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := []StreamStatus{{
+		Connection:      1,
+		NodeID:          "envoy-1",
+		TypeURL:         "com.heptio.potato",
+		LastSentVersion: "1",
+		NackedVersion:   "1",
+		NackError:       "invalid",
+		NackCount:       1,
+	}}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected:\n%+v\ngot:\n%+v", want, got)
+	}
+	if !reflect.DeepEqual(want, cs.Rejected()) {
+		t.Fatalf("expected rejected:\n%+v\ngot:\n%+v", want, cs.Rejected())
+	}
+}
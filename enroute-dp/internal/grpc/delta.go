@@ -0,0 +1,301 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/any"
+)
+
+type deltaStream interface {
+	Context() context.Context
+	Send(*envoy_discovery_v3.DeltaDiscoveryResponse) error
+	Recv() (*envoy_discovery_v3.DeltaDiscoveryRequest, error)
+}
+
+// deltaState is the view of a resource type held by the Envoy on
+// the other end of an incremental xDS stream.
+type deltaState struct {
+	// wildcard is true if Envoy subscribed to every resource
+	// by naming none in its first request.
+	wildcard bool
+
+	// subscribed holds the names of the resources Envoy subscribed to.
+	subscribed map[string]bool
+
+	// versions holds the version of each resource sent to Envoy.
+	versions map[string]string
+}
+
+// subscribe applies the subscriptions carried by req.
+// It returns true if Envoy subscribed to a resource it was not sent.
+func (ds *deltaState) subscribe(req *envoy_discovery_v3.DeltaDiscoveryRequest) bool {
+	added := false
+	for _, name := range req.ResourceNamesSubscribe {
+		if !ds.subscribed[name] {
+			ds.subscribed[name] = true
+			added = true
+		}
+	}
+	for _, name := range req.ResourceNamesUnsubscribe {
+		delete(ds.subscribed, name)
+		delete(ds.versions, name)
+	}
+	return added
+}
+
+// diff returns the resources of contents Envoy does not hold at their
+// current version, and the names of the resources it holds which no
+// longer exist. ds.versions is updated as if the diff had been sent.
+func (ds *deltaState) diff(typeURL string, contents []proto.Message) ([]*envoy_discovery_v3.Resource, []string, error) {
+	var resources []*envoy_discovery_v3.Resource
+	current := make(map[string]bool, len(contents))
+	for _, msg := range contents {
+		name := resourceName(msg)
+		if !ds.wildcard && !ds.subscribed[name] {
+			continue
+		}
+		current[name] = true
+
+		value, err := marshalDeterministic(msg)
+		if err != nil {
+			return nil, nil, err
+		}
+		version := resourceVersion(value)
+		if ds.versions[name] == version {
+			continue
+		}
+		ds.versions[name] = version
+		resources = append(resources, &envoy_discovery_v3.Resource{
+			Name:     name,
+			Version:  version,
+			Resource: &any.Any{TypeUrl: typeURL, Value: value},
+		})
+	}
+
+	var removed []string
+	for name := range ds.versions {
+		if !current[name] {
+			removed = append(removed, name)
+			delete(ds.versions, name)
+		}
+	}
+	sort.Strings(removed)
+	return resources, removed, nil
+}
+
+// delta processes an incremental xDS stream of DeltaDiscoveryRequests.
+// Rather than the full contents of the resource, each response holds the
+// resources which were added or changed since the last response, and the
+// names of those which were removed.
+func (xh *xdsHandler) delta(st deltaStream) (err error) {
+	connection := xh.connections.next()
+	log := xh.WithField("connection", connection).WithField("delta", true)
+	defer xh.status.closed(connection)
+
+	defer func() {
+		if err != nil {
+			log.WithError(err).Error("stream terminated")
+		} else {
+			log.Info("stream terminated")
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(st.Context())
+	defer cancel()
+
+	// requests are received on their own goroutine so that the stream
+	// can wait for a request and a notification at the same time.
+	requests := make(chan *envoy_discovery_v3.DeltaDiscoveryRequest)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			req, err := st.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case requests <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		r     Resource
+		ds    *deltaState
+		ch    = make(chan int, 1)
+		last  = -1
+		sent  = false // whether the initial response has been sent
+		ready = false // whether a notification has been received
+	)
+	defer func() {
+		if r != nil {
+			r.Unregister(ch)
+		}
+	}()
+
+	// respond sends the resources which changed since the last response.
+	respond := func() error {
+		var contents []proto.Message
+		switch {
+		case ds.wildcard:
+			contents = r.Contents()
+		default:
+			names := make([]string, 0, len(ds.subscribed))
+			for name := range ds.subscribed {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			contents = r.Query(names)
+		}
+
+		resources, removed, err := ds.diff(r.TypeURL(), contents)
+		if err != nil {
+			return err
+		}
+
+		// Envoy waits for the first response before completing its
+		// initialisation, so it is sent even if it is empty.
+		if sent && len(resources) == 0 && len(removed) == 0 {
+			log.WithField("type_url", r.TypeURL()).WithField("version_info", last).Debug("no changes")
+			return nil
+		}
+
+		resp := &envoy_discovery_v3.DeltaDiscoveryResponse{
+			SystemVersionInfo: strconv.Itoa(last),
+			Resources:         resources,
+			RemovedResources:  removed,
+			TypeUrl:           r.TypeURL(),
+			Nonce:             strconv.Itoa(last),
+		}
+		if err := st.Send(resp); err != nil {
+			return err
+		}
+		sent = true
+		xh.status.sent(connection, resp.TypeUrl, resp.SystemVersionInfo)
+		log.WithField("type_url", resp.TypeUrl).WithField("version_info", resp.SystemVersionInfo).WithField("count", len(resources)).WithField("removed", len(removed)).Info("response")
+		return nil
+	}
+
+	for {
+		select {
+		case req := <-requests:
+			if r == nil {
+				resources, err := xh.resourcesFor(req.Node)
+				if err != nil {
+					return err
+				}
+				var ok bool
+				r, ok = resources[req.TypeUrl]
+				if !ok {
+					return fmt.Errorf("no resource registered for typeURL %q", req.TypeUrl)
+				}
+				ds = &deltaState{
+					wildcard:   len(req.ResourceNamesSubscribe) == 0,
+					subscribed: make(map[string]bool),
+					versions:   make(map[string]string),
+				}
+				// resources Envoy already holds, from a previous stream.
+				for name, version := range req.InitialResourceVersions {
+					ds.versions[name] = version
+				}
+
+				// internally all registration values start at zero so
+				// registering a last that is less than zero will generate
+				// a response immediately.
+				r.Register(ch, last)
+			} else if req.TypeUrl != r.TypeURL() {
+				return fmt.Errorf("typeURL %q requested on a %q stream", req.TypeUrl, r.TypeURL())
+			}
+
+			// record whether Envoy accepted the last response on this
+			// stream. As with the other xDS streams the nonce of a
+			// response is its version.
+			xh.status.request(connection, &envoy_discovery_v3.DiscoveryRequest{
+				VersionInfo:   req.ResponseNonce,
+				Node:          req.Node,
+				TypeUrl:       req.TypeUrl,
+				ResponseNonce: req.ResponseNonce,
+				ErrorDetail:   req.ErrorDetail,
+			})
+			if req.ErrorDetail != nil {
+				log.WithField("type_url", req.TypeUrl).WithField("response_nonce", req.ResponseNonce).WithField("error_detail", req.ErrorDetail.Message).Warn("config rejected")
+			}
+
+			log.WithField("subscribe", req.ResourceNamesSubscribe).WithField("unsubscribe", req.ResourceNamesUnsubscribe).WithField("type_url", req.TypeUrl).WithField("response_nonce", req.ResponseNonce).Info("stream_wait")
+
+			// resources Envoy newly subscribed to are sent now,
+			// rather than on the next change.
+			if ds.subscribe(req) && ready {
+				if err := respond(); err != nil {
+					return err
+				}
+			}
+		case last = <-ch:
+			ready = true
+			if err := respond(); err != nil {
+				return err
+			}
+			// unlike the other xDS streams a notification is always
+			// outstanding, as Envoy does not acknowledge a notification
+			// which leaves its resources unchanged.
+			r.Register(ch, last)
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// resourceName returns the name by which Envoy refers to msg.
+func resourceName(msg proto.Message) string {
+	switch msg := msg.(type) {
+	case *envoy_cluster_v3.Cluster:
+		return msg.Name
+	case *envoy_endpoint_v3.ClusterLoadAssignment:
+		return msg.ClusterName
+	case *envoy_listener_v3.Listener:
+		return msg.Name
+	case *envoy_route_v3.RouteConfiguration:
+		return msg.Name
+	case *envoy_tls_v3.Secret:
+		return msg.Name
+	default:
+		return ""
+	}
+}
+
+// marshalDeterministic marshals msg with map entries in a stable
+// order, so the same resource always has the same version.
+func marshalDeterministic(msg proto.Message) ([]byte, error) {
+	var b proto.Buffer
+	b.SetDeterministic(true)
+	if err := b.Marshal(msg); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// resourceVersion returns the version of a resource marshalled to value.
+func resourceVersion(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,261 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package grpc
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/sirupsen/logrus"
+)
+
+// notifier is a Resource whose contents are replaced by the test.
+type notifier struct {
+	typeURL string
+
+	mu       sync.Mutex
+	contents []proto.Message
+	last     int
+	waiter   chan int
+}
+
+func (n *notifier) Contents() []proto.Message {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.contents
+}
+
+func (n *notifier) Query(names []string) []proto.Message {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var values []proto.Message
+	for _, name := range names {
+		for _, msg := range n.contents {
+			if resourceName(msg) == name {
+				values = append(values, msg)
+			}
+		}
+	}
+	return values
+}
+
+func (n *notifier) Register(ch chan int, last int, _ ...string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if last < n.last {
+		ch <- n.last
+		return
+	}
+	n.waiter = ch
+}
+
+func (n *notifier) Unregister(ch chan int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.waiter == ch {
+		n.waiter = nil
+	}
+}
+
+func (n *notifier) TypeURL() string { return n.typeURL }
+
+// update replaces the contents once the stream is waiting for a
+// notification, so that each update is handled on its own.
+func (n *notifier) update(contents ...proto.Message) {
+	for {
+		n.mu.Lock()
+		if n.waiter != nil {
+			break
+		}
+		n.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	defer n.mu.Unlock()
+	n.contents = contents
+	n.last++
+	n.waiter <- n.last
+	n.waiter = nil
+}
+
+type mockDeltaStream struct {
+	requests  chan *envoy_discovery_v3.DeltaDiscoveryRequest
+	responses chan *envoy_discovery_v3.DeltaDiscoveryResponse
+}
+
+func (m *mockDeltaStream) Context() context.Context { return context.Background() }
+
+func (m *mockDeltaStream) Send(resp *envoy_discovery_v3.DeltaDiscoveryResponse) error {
+	m.responses <- resp
+	return nil
+}
+
+func (m *mockDeltaStream) Recv() (*envoy_discovery_v3.DeltaDiscoveryRequest, error) {
+	req, ok := <-m.requests
+	if !ok {
+		return nil, io.EOF
+	}
+	return req, nil
+}
+
+// deltaResponse is the part of a DeltaDiscoveryResponse under test.
+type deltaResponse struct {
+	version   string
+	resources []string
+	removed   []string
+}
+
+// runDelta runs a delta stream against r, sending requests and
+// checking the responses which follow each step.
+func runDelta(t *testing.T, r Resource, steps func(st *mockDeltaStream, expect func(deltaResponse))) {
+	t.Helper()
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	xh := xdsHandler{
+		FieldLogger: log,
+		resources:   map[string]Resource{r.TypeURL(): r},
+	}
+
+	st := &mockDeltaStream{
+		requests:  make(chan *envoy_discovery_v3.DeltaDiscoveryRequest),
+		responses: make(chan *envoy_discovery_v3.DeltaDiscoveryResponse, 10),
+	}
+	done := make(chan error)
+	go func() { done <- xh.delta(st) }()
+
+	steps(st, func(want deltaResponse) {
+		t.Helper()
+		select {
+		case resp := <-st.responses:
+			got := deltaResponse{version: resp.SystemVersionInfo, removed: resp.RemovedResources}
+			for _, r := range resp.Resources {
+				got.resources = append(got.resources, r.Name)
+			}
+			if !reflect.DeepEqual(want, got) {
+				t.Fatalf("expected:\n%+v\ngot:\n%+v", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %+v", want)
+		}
+	})
+
+	close(st.requests)
+	if err := <-done; err != io.EOF {
+		t.Fatalf("expected: %v, got: %v", io.EOF, err)
+	}
+	select {
+	case resp := <-st.responses:
+		t.Fatalf("unexpected response: %+v", resp)
+	default:
+	}
+}
+
+func TestDeltaWildcard(t *testing.T) {
+	r := &notifier{
+		typeURL: resource.ClusterType,
+		last:    1,
+		contents: []proto.Message{
+			&envoy_cluster_v3.Cluster{Name: "default/kuard/80"},
+			&envoy_cluster_v3.Cluster{Name: "default/nginx/80"},
+		},
+	}
+
+	runDelta(t, r, func(st *mockDeltaStream, expect func(deltaResponse)) {
+		st.requests <- &envoy_discovery_v3.DeltaDiscoveryRequest{TypeUrl: resource.ClusterType}
+		expect(deltaResponse{version: "1", resources: []string{"default/kuard/80", "default/nginx/80"}})
+
+		// no changes, no response.
+		r.update(
+			&envoy_cluster_v3.Cluster{Name: "default/kuard/80"},
+			&envoy_cluster_v3.Cluster{Name: "default/nginx/80"},
+		)
+		r.update(
+			&envoy_cluster_v3.Cluster{Name: "default/kuard/80", AltStatName: "default_kuard_80"},
+			&envoy_cluster_v3.Cluster{Name: "default/httpbin/80"},
+		)
+		expect(deltaResponse{
+			version:   "3",
+			resources: []string{"default/kuard/80", "default/httpbin/80"},
+			removed:   []string{"default/nginx/80"},
+		})
+		st.requests <- &envoy_discovery_v3.DeltaDiscoveryRequest{TypeUrl: resource.ClusterType, ResponseNonce: "3"}
+	})
+
+	// the stream has ended, so it is no longer waiting for a notification.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.waiter != nil {
+		t.Fatal("stream was not unregistered when it ended")
+	}
+}
+
+func TestDeltaSubscribe(t *testing.T) {
+	r := &notifier{
+		typeURL: resource.EndpointType,
+		last:    1,
+		contents: []proto.Message{
+			&envoy_endpoint_v3.ClusterLoadAssignment{ClusterName: "default/kuard"},
+			&envoy_endpoint_v3.ClusterLoadAssignment{ClusterName: "default/nginx"},
+			&envoy_endpoint_v3.ClusterLoadAssignment{ClusterName: "default/httpbin"},
+		},
+	}
+
+	runDelta(t, r, func(st *mockDeltaStream, expect func(deltaResponse)) {
+		st.requests <- &envoy_discovery_v3.DeltaDiscoveryRequest{TypeUrl: resource.EndpointType, ResourceNamesSubscribe: []string{"default/kuard"}}
+		expect(deltaResponse{version: "1", resources: []string{"default/kuard"}})
+
+		// newly subscribed resources are sent immediately.
+		st.requests <- &envoy_discovery_v3.DeltaDiscoveryRequest{TypeUrl: resource.EndpointType, ResponseNonce: "1", ResourceNamesSubscribe: []string{"default/nginx"}}
+		expect(deltaResponse{version: "1", resources: []string{"default/nginx"}})
+
+		// unsubscribed resources are no longer sent.
+		st.requests <- &envoy_discovery_v3.DeltaDiscoveryRequest{TypeUrl: resource.EndpointType, ResponseNonce: "1", ResourceNamesUnsubscribe: []string{"default/kuard"}}
+		// the next request is received once the last has been handled.
+		st.requests <- &envoy_discovery_v3.DeltaDiscoveryRequest{TypeUrl: resource.EndpointType, ResponseNonce: "1"}
+		r.update(
+			&envoy_endpoint_v3.ClusterLoadAssignment{ClusterName: "default/kuard", Endpoints: []*envoy_endpoint_v3.LocalityLbEndpoints{{}}},
+			&envoy_endpoint_v3.ClusterLoadAssignment{ClusterName: "default/nginx", Endpoints: []*envoy_endpoint_v3.LocalityLbEndpoints{{}}},
+			&envoy_endpoint_v3.ClusterLoadAssignment{ClusterName: "default/httpbin", Endpoints: []*envoy_endpoint_v3.LocalityLbEndpoints{{}}},
+		)
+		expect(deltaResponse{version: "2", resources: []string{"default/nginx"}})
+	})
+}
+
+func TestDeltaInitialResourceVersions(t *testing.T) {
+	kuard := &envoy_cluster_v3.Cluster{Name: "default/kuard/80"}
+	value, err := marshalDeterministic(kuard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &notifier{
+		typeURL:  resource.ClusterType,
+		last:     1,
+		contents: []proto.Message{kuard, &envoy_cluster_v3.Cluster{Name: "default/nginx/80"}},
+	}
+
+	runDelta(t, r, func(st *mockDeltaStream, expect func(deltaResponse)) {
+		st.requests <- &envoy_discovery_v3.DeltaDiscoveryRequest{
+			TypeUrl: resource.ClusterType,
+			InitialResourceVersions: map[string]string{
+				"default/kuard/80":   resourceVersion(value),
+				"default/httpbin/80": "stale",
+			},
+		}
+		expect(deltaResponse{
+			version:   "1",
+			resources: []string{"default/nginx/80"},
+			removed:   []string{"default/httpbin/80"},
+		})
+	})
+}
@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+)
+
+// redacted replaces the contents of secrets in a ResourceDump.
+const redacted = "[redacted]"
+
+// ResourceDump serves the contents of the xDS resources as JSON, as they
+// would be sent to an Envoy. The private keys and other confidential
+// contents of secrets are redacted.
+type ResourceDump struct {
+	// Proxies holds the resources served to each proxy, keyed by proxy
+	// name and then type URL. If a single proxy is served its name is "".
+	Proxies map[string]map[string]Resource
+}
+
+// dump is the JSON form of the contents of a resource.
+type dump struct {
+	Proxy       string            `json:"proxy,omitempty"`
+	VersionInfo string            `json:"version_info"`
+	TypeURL     string            `json:"type_url"`
+	Resources   []json.RawMessage `json:"resources"`
+}
+
+// Handler returns a http.Handler which serves the contents of the resource
+// with typeURL. The proxy query parameter selects the proxy when several
+// are served, and the name query parameter, which may be repeated, limits
+// the resources served to those named.
+func (d *ResourceDump) Handler(typeURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxy := r.URL.Query().Get("proxy")
+		if proxy == "" && len(d.Proxies) == 1 {
+			for name := range d.Proxies {
+				proxy = name
+			}
+		}
+		resources, ok := d.Proxies[proxy]
+		if !ok {
+			var names []string
+			for name := range d.Proxies {
+				names = append(names, strconv.Quote(name))
+			}
+			sort.Strings(names)
+			http.Error(w, fmt.Sprintf("unknown proxy %q, expected one of %s", proxy, strings.Join(names, ", ")), http.StatusNotFound)
+			return
+		}
+		res, ok := resources[typeURL]
+		if !ok {
+			http.Error(w, fmt.Sprintf("no resource registered for typeURL %q", typeURL), http.StatusNotFound)
+			return
+		}
+
+		// internally all registration values start at zero so
+		// registering a last that is less than zero returns the
+		// current version immediately.
+		ch := make(chan int, 1)
+		res.Register(ch, -1)
+		last := <-ch
+
+		names := make(map[string]bool)
+		for _, name := range r.URL.Query()["name"] {
+			names[name] = true
+		}
+
+		out := dump{
+			Proxy:       proxy,
+			VersionInfo: strconv.Itoa(last),
+			TypeURL:     typeURL,
+			Resources:   []json.RawMessage{},
+		}
+		m := jsonpb.Marshaler{OrigName: true}
+		for _, msg := range res.Contents() {
+			if len(names) > 0 && !names[resourceName(msg)] {
+				continue
+			}
+			s, err := m.MarshalToString(redact(msg))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			out.Resources = append(out.Resources, json.RawMessage(s))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(&out); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// redact returns msg with the confidential contents of secrets replaced.
+// Other messages are returned unchanged.
+func redact(msg proto.Message) proto.Message {
+	secret, ok := msg.(*envoy_tls_v3.Secret)
+	if !ok {
+		return msg
+	}
+	secret = proto.Clone(secret).(*envoy_tls_v3.Secret)
+	switch t := secret.Type.(type) {
+	case *envoy_tls_v3.Secret_TlsCertificate:
+		redactDataSource(t.TlsCertificate.GetPrivateKey())
+		redactDataSource(t.TlsCertificate.GetPassword())
+	case *envoy_tls_v3.Secret_SessionTicketKeys:
+		for _, key := range t.SessionTicketKeys.GetKeys() {
+			redactDataSource(key)
+		}
+	case *envoy_tls_v3.Secret_GenericSecret:
+		redactDataSource(t.GenericSecret.GetSecret())
+	}
+	return secret
+}
+
+// redactDataSource replaces the contents of an inline data source.
+// Data sources which refer to files are left unchanged.
+func redactDataSource(ds *envoy_core_v3.DataSource) {
+	switch ds.GetSpecifier().(type) {
+	case *envoy_core_v3.DataSource_InlineBytes, *envoy_core_v3.DataSource_InlineString:
+		ds.Specifier = &envoy_core_v3.DataSource_InlineString{InlineString: redacted}
+	}
+}
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package grpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/golang/protobuf/proto"
+)
+
+func TestResourceDump(t *testing.T) {
+	clusters := &notifier{
+		typeURL: resource.ClusterType,
+		last:    3,
+		contents: []proto.Message{
+			&envoy_cluster_v3.Cluster{Name: "default/kuard/80"},
+			&envoy_cluster_v3.Cluster{Name: "default/nginx/80"},
+		},
+	}
+	single := &ResourceDump{Proxies: map[string]map[string]Resource{
+		"": {resource.ClusterType: clusters},
+	}}
+	several := &ResourceDump{Proxies: map[string]map[string]Resource{
+		"gw1": {resource.ClusterType: clusters},
+		"gw2": {},
+	}}
+
+	tests := map[string]struct {
+		dump    *ResourceDump
+		typeURL string
+		query   string
+		code    int
+		want    []string // names of the resources
+		version string
+	}{
+		"clusters": {
+			dump:    single,
+			typeURL: resource.ClusterType,
+			code:    http.StatusOK,
+			want:    []string{"default/kuard/80", "default/nginx/80"},
+			version: "3",
+		},
+		"filtered by name": {
+			dump:    single,
+			typeURL: resource.ClusterType,
+			query:   "?name=default/nginx/80",
+			code:    http.StatusOK,
+			want:    []string{"default/nginx/80"},
+			version: "3",
+		},
+		"named proxy": {
+			dump:    several,
+			typeURL: resource.ClusterType,
+			query:   "?proxy=gw1",
+			code:    http.StatusOK,
+			want:    []string{"default/kuard/80", "default/nginx/80"},
+			version: "3",
+		},
+		"proxy not named": {
+			dump:    several,
+			typeURL: resource.ClusterType,
+			code:    http.StatusNotFound,
+		},
+		"unknown type": {
+			dump:    several,
+			typeURL: resource.ClusterType,
+			query:   "?proxy=gw2",
+			code:    http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tc.dump.Handler(tc.typeURL).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/xds/clusters"+tc.query, nil))
+			if rec.Code != tc.code {
+				t.Fatalf("expected status %d, got %d: %s", tc.code, rec.Code, rec.Body)
+			}
+			if tc.code != http.StatusOK {
+				return
+			}
+			var got struct {
+				VersionInfo string `json:"version_info"`
+				Resources   []struct {
+					Name string `json:"name"`
+				} `json:"resources"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, r := range got.Resources {
+				names = append(names, r.Name)
+			}
+			if got.VersionInfo != tc.version || strings.Join(names, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("expected version %s, resources %v, got: %s", tc.version, tc.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestResourceDumpRedactsSecrets(t *testing.T) {
+	secret := &envoy_tls_v3.Secret{
+		Name: "default/secret/cd1b506996",
+		Type: &envoy_tls_v3.Secret_TlsCertificate{
+			TlsCertificate: &envoy_tls_v3.TlsCertificate{
+				CertificateChain: &envoy_core_v3.DataSource{
+					Specifier: &envoy_core_v3.DataSource_InlineBytes{InlineBytes: []byte("certificate")},
+				},
+				PrivateKey: &envoy_core_v3.DataSource{
+					Specifier: &envoy_core_v3.DataSource_InlineBytes{InlineBytes: []byte("private-key")},
+				},
+			},
+		},
+	}
+	d := &ResourceDump{Proxies: map[string]map[string]Resource{
+		"": {resource.SecretType: &notifier{typeURL: resource.SecretType, contents: []proto.Message{secret}}},
+	}}
+
+	rec := httptest.NewRecorder()
+	d.Handler(resource.SecretType).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/xds/secrets", nil))
+	body := rec.Body.String()
+	if strings.Contains(body, "cHJpdmF0ZS1rZXk=") || !strings.Contains(body, redacted) {
+		t.Fatalf("expected the private key to be redacted, got: %s", body)
+	}
+	// the certificate chain is public.
+	if !strings.Contains(body, "Y2VydGlmaWNhdGU=") {
+		t.Fatalf("expected the certificate chain, got: %s", body)
+	}
+	// the secret served is unchanged.
+	if got := secret.GetTlsCertificate().GetPrivateKey().GetInlineBytes(); string(got) != "private-key" {
+		t.Fatalf("expected the served secret to be unchanged, got: %q", got)
+	}
+}
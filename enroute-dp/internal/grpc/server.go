@@ -12,7 +12,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package grpc provides a gRPC implementation of the Envoy v2 xDS API.
+// Package grpc provides a gRPC implementation of the Envoy v3 xDS API.
 package grpc
 
 import (
@@ -23,10 +23,17 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
-	"github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_service_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	envoy_service_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/service/endpoint/v3"
+	envoy_service_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/service/listener/v3"
 	loadstats "github.com/envoyproxy/go-control-plane/envoy/service/load_stats/v2"
-	rl "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v2"
+	rlv2 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v2"
+	rl "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	envoy_service_route_v3 "github.com/envoyproxy/go-control-plane/envoy/service/route/v3"
+	envoy_service_secret_v3 "github.com/envoyproxy/go-control-plane/envoy/service/secret/v3"
 	"github.com/sirupsen/logrus"
 )
 
@@ -35,8 +42,29 @@ const (
 	grpcMaxConcurrentStreams = 1 << 20
 )
 
-// NewAPI returns a *grpc.Server which responds to the Envoy v2 xDS gRPC API.
-func NewAPI(log logrus.FieldLogger, resources map[string]Resource) *grpc.Server {
+// NewAPI returns a *grpc.Server which responds to the Envoy v3 xDS gRPC API,
+// and if serveV2 is true, the v2 xDS gRPC API.
+// If status is not nil, it records the configs Envoys accept and reject.
+func NewAPI(log logrus.FieldLogger, resources map[string]Resource, status *ConfigStatus, serveV2 bool) *grpc.Server {
+	return newAPI(xdsHandler{
+		FieldLogger: log,
+		resources:   resources,
+		status:      status,
+	}, serveV2)
+}
+
+// NewProxyAPI returns a *grpc.Server like NewAPI which serves several
+// proxies. Each Envoy is served the resources registered under the
+// name of its proxy, as returned by ProxyName.
+func NewProxyAPI(log logrus.FieldLogger, proxies map[string]map[string]Resource, status *ConfigStatus, serveV2 bool) *grpc.Server {
+	return newAPI(xdsHandler{
+		FieldLogger: log,
+		proxies:     proxies,
+		status:      status,
+	}, serveV2)
+}
+
+func newAPI(xh xdsHandler, serveV2 bool) *grpc.Server {
 	opts := []grpc.ServerOption{
 		// By default the Go grpc library defaults to a value of ~100 streams per
 		// connection. This number is likely derived from the HTTP/2 spec:
@@ -47,24 +75,36 @@ func NewAPI(log logrus.FieldLogger, resources map[string]Resource) *grpc.Server
 		grpc.MaxConcurrentStreams(grpcMaxConcurrentStreams),
 	}
 	g := grpc.NewServer(opts...)
-	s := &grpcServer{
-		xdsHandler{
-			FieldLogger: log,
-			resources:   resources,
-		},
-	}
+	s := &grpcServer{xh}
 
 	rls := &ratelimitServer{}
 
-	envoy_api_v2.RegisterClusterDiscoveryServiceServer(g, s)
-	envoy_api_v2.RegisterEndpointDiscoveryServiceServer(g, s)
-	envoy_api_v2.RegisterListenerDiscoveryServiceServer(g, s)
-	envoy_api_v2.RegisterRouteDiscoveryServiceServer(g, s)
-	discovery.RegisterSecretDiscoveryServiceServer(g, s)
-	rl.RegisterRateLimitServiceServer(g, rls)
+	envoy_service_cluster_v3.RegisterClusterDiscoveryServiceServer(g, s)
+	envoy_service_endpoint_v3.RegisterEndpointDiscoveryServiceServer(g, s)
+	envoy_service_listener_v3.RegisterListenerDiscoveryServiceServer(g, s)
+	envoy_service_route_v3.RegisterRouteDiscoveryServiceServer(g, s)
+	envoy_service_secret_v3.RegisterSecretDiscoveryServiceServer(g, s)
+	envoy_discovery_v3.RegisterAggregatedDiscoveryServiceServer(g, s)
+	if serveV2 {
+		v2 := grpcServerV2{s}
+		envoy_api_v2.RegisterClusterDiscoveryServiceServer(g, v2)
+		envoy_api_v2.RegisterEndpointDiscoveryServiceServer(g, v2)
+		envoy_api_v2.RegisterListenerDiscoveryServiceServer(g, v2)
+		envoy_api_v2.RegisterRouteDiscoveryServiceServer(g, v2)
+		discovery.RegisterSecretDiscoveryServiceServer(g, v2)
+		discovery.RegisterAggregatedDiscoveryServiceServer(g, v2)
+	}
+	registerRateLimit(g, rls)
 	return g
 }
 
+// registerRateLimit registers rls on g under the v3 and v2 rate limit
+// service APIs.
+func registerRateLimit(g *grpc.Server, rls *ratelimitServer) {
+	rl.RegisterRateLimitServiceServer(g, rls)
+	rlv2.RegisterRateLimitServiceServer(g, ratelimitServerV2{rls})
+}
+
 func NewAPIRateLimit(log logrus.FieldLogger, c chan string) *grpc.Server {
 	opts := []grpc.ServerOption{
 		// By default the Go grpc library defaults to a value of ~100 streams per
@@ -77,11 +117,11 @@ func NewAPIRateLimit(log logrus.FieldLogger, c chan string) *grpc.Server {
 	}
 	g := grpc.NewServer(opts...)
 	rls := &ratelimitServer{}
-	rl.RegisterRateLimitServiceServer(g, rls)
+	registerRateLimit(g, rls)
 	return g
 }
 
-// grpcServer implements the LDS, RDS, CDS, and EDS, gRPC endpoints.
+// grpcServer implements the v3 LDS, RDS, CDS, EDS, SDS, and ADS gRPC endpoints.
 type grpcServer struct {
 	xdsHandler
 }
@@ -90,43 +130,43 @@ type ratelimitServer struct {
 	rl.RateLimitServiceServer
 }
 
-func (s *grpcServer) FetchClusters(_ context.Context, req *envoy_api_v2.DiscoveryRequest) (*envoy_api_v2.DiscoveryResponse, error) {
+func (s *grpcServer) FetchClusters(_ context.Context, req *envoy_discovery_v3.DiscoveryRequest) (*envoy_discovery_v3.DiscoveryResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "FetchClusters unimplemented")
 }
 
-func (s *grpcServer) FetchEndpoints(_ context.Context, req *envoy_api_v2.DiscoveryRequest) (*envoy_api_v2.DiscoveryResponse, error) {
+func (s *grpcServer) FetchEndpoints(_ context.Context, req *envoy_discovery_v3.DiscoveryRequest) (*envoy_discovery_v3.DiscoveryResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "FetchEndpoints unimplemented")
 }
 
-func (s *grpcServer) DeltaEndpoints(envoy_api_v2.EndpointDiscoveryService_DeltaEndpointsServer) error {
-	return status.Errorf(codes.Unimplemented, "DeltaEndpoints unimplemented")
+func (s *grpcServer) DeltaEndpoints(srv envoy_service_endpoint_v3.EndpointDiscoveryService_DeltaEndpointsServer) error {
+	return s.delta(srv)
 }
 
-func (s *grpcServer) FetchListeners(_ context.Context, req *envoy_api_v2.DiscoveryRequest) (*envoy_api_v2.DiscoveryResponse, error) {
+func (s *grpcServer) FetchListeners(_ context.Context, req *envoy_discovery_v3.DiscoveryRequest) (*envoy_discovery_v3.DiscoveryResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "FetchListeners unimplemented")
 }
 
-func (s *grpcServer) DeltaListeners(envoy_api_v2.ListenerDiscoveryService_DeltaListenersServer) error {
-	return status.Errorf(codes.Unimplemented, "DeltaListeners unimplemented")
+func (s *grpcServer) DeltaListeners(srv envoy_service_listener_v3.ListenerDiscoveryService_DeltaListenersServer) error {
+	return s.delta(srv)
 }
 
-func (s *grpcServer) FetchRoutes(_ context.Context, req *envoy_api_v2.DiscoveryRequest) (*envoy_api_v2.DiscoveryResponse, error) {
+func (s *grpcServer) FetchRoutes(_ context.Context, req *envoy_discovery_v3.DiscoveryRequest) (*envoy_discovery_v3.DiscoveryResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "FetchRoutes unimplemented")
 }
 
-func (s *grpcServer) FetchSecrets(_ context.Context, req *envoy_api_v2.DiscoveryRequest) (*envoy_api_v2.DiscoveryResponse, error) {
+func (s *grpcServer) FetchSecrets(_ context.Context, req *envoy_discovery_v3.DiscoveryRequest) (*envoy_discovery_v3.DiscoveryResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "FetchSecrets unimplemented")
 }
 
-func (s *grpcServer) DeltaSecrets(discovery.SecretDiscoveryService_DeltaSecretsServer) error {
-	return status.Errorf(codes.Unimplemented, "DeltaSecrets unimplemented")
+func (s *grpcServer) DeltaSecrets(srv envoy_service_secret_v3.SecretDiscoveryService_DeltaSecretsServer) error {
+	return s.delta(srv)
 }
 
-func (s *grpcServer) StreamClusters(srv envoy_api_v2.ClusterDiscoveryService_StreamClustersServer) error {
+func (s *grpcServer) StreamClusters(srv envoy_service_cluster_v3.ClusterDiscoveryService_StreamClustersServer) error {
 	return s.stream(srv)
 }
 
-func (s *grpcServer) StreamEndpoints(srv envoy_api_v2.EndpointDiscoveryService_StreamEndpointsServer) error {
+func (s *grpcServer) StreamEndpoints(srv envoy_service_endpoint_v3.EndpointDiscoveryService_StreamEndpointsServer) error {
 	return s.stream(srv)
 }
 
@@ -134,26 +174,34 @@ func (s *grpcServer) StreamLoadStats(srv loadstats.LoadReportingService_StreamLo
 	return status.Errorf(codes.Unimplemented, "StreamLoadStats unimplemented")
 }
 
-func (s *grpcServer) DeltaClusters(envoy_api_v2.ClusterDiscoveryService_DeltaClustersServer) error {
-	return status.Errorf(codes.Unimplemented, "IncrementalClusters unimplemented")
+func (s *grpcServer) DeltaClusters(srv envoy_service_cluster_v3.ClusterDiscoveryService_DeltaClustersServer) error {
+	return s.delta(srv)
 }
 
-func (s *grpcServer) DeltaRoutes(envoy_api_v2.RouteDiscoveryService_DeltaRoutesServer) error {
-	return status.Errorf(codes.Unimplemented, "IncrementalRoutes unimplemented")
+func (s *grpcServer) DeltaRoutes(srv envoy_service_route_v3.RouteDiscoveryService_DeltaRoutesServer) error {
+	return s.delta(srv)
 }
 
-func (s *grpcServer) StreamListeners(srv envoy_api_v2.ListenerDiscoveryService_StreamListenersServer) error {
+func (s *grpcServer) StreamListeners(srv envoy_service_listener_v3.ListenerDiscoveryService_StreamListenersServer) error {
 	return s.stream(srv)
 }
 
-func (s *grpcServer) StreamRoutes(srv envoy_api_v2.RouteDiscoveryService_StreamRoutesServer) error {
+func (s *grpcServer) StreamRoutes(srv envoy_service_route_v3.RouteDiscoveryService_StreamRoutesServer) error {
 	return s.stream(srv)
 }
 
-func (s *grpcServer) StreamSecrets(srv discovery.SecretDiscoveryService_StreamSecretsServer) error {
+func (s *grpcServer) StreamSecrets(srv envoy_service_secret_v3.SecretDiscoveryService_StreamSecretsServer) error {
 	return s.stream(srv)
 }
 
+func (s *grpcServer) StreamAggregatedResources(srv envoy_discovery_v3.AggregatedDiscoveryService_StreamAggregatedResourcesServer) error {
+	return s.ads(srv)
+}
+
+func (s *grpcServer) DeltaAggregatedResources(envoy_discovery_v3.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
+	return status.Errorf(codes.Unimplemented, "DeltaAggregatedResources unimplemented")
+}
+
 func (s *ratelimitServer) getRateLimit(requestsPerUnit uint32, unit rl.RateLimitResponse_RateLimit_Unit) *rl.RateLimitResponse_RateLimit {
 	return &rl.RateLimitResponse_RateLimit{RequestsPerUnit: requestsPerUnit, Unit: unit}
 }
@@ -23,9 +23,19 @@ import (
 	"testing"
 	"time"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
-	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
+	envoy_api_v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_ratelimit_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	envoy_service_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	envoy_service_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/service/endpoint/v3"
+	envoy_service_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/service/listener/v3"
+	rlv2 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v2"
+	rl "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	envoy_service_route_v3 "github.com/envoyproxy/go-control-plane/envoy/service/route/v3"
+	envoy_service_secret_v3 "github.com/envoyproxy/go-control-plane/envoy/service/secret/v3"
+	resourcev2 "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/saarasio/enroute/enroute-dp/internal/contour"
 	"github.com/saarasio/enroute/enroute-dp/internal/metrics"
@@ -63,7 +73,7 @@ func TestGRPC(t *testing.T) {
 				},
 			})
 
-			sds := v2.NewClusterDiscoveryServiceClient(cc)
+			sds := envoy_service_cluster_v3.NewClusterDiscoveryServiceClient(cc)
 			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 			defer cancel()
 			stream, err := sds.StreamClusters(ctx)
@@ -90,7 +100,7 @@ func TestGRPC(t *testing.T) {
 				}},
 			})
 
-			eds := v2.NewEndpointDiscoveryServiceClient(cc)
+			eds := envoy_service_endpoint_v3.NewEndpointDiscoveryServiceClient(cc)
 			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 			defer cancel()
 			stream, err := eds.StreamEndpoints(ctx)
@@ -123,7 +133,7 @@ func TestGRPC(t *testing.T) {
 				},
 			})
 
-			lds := v2.NewListenerDiscoveryServiceClient(cc)
+			lds := envoy_service_listener_v3.NewListenerDiscoveryServiceClient(cc)
 			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 			defer cancel()
 			stream, err := lds.StreamListeners(ctx)
@@ -155,7 +165,7 @@ func TestGRPC(t *testing.T) {
 				},
 			})
 
-			rds := v2.NewRouteDiscoveryServiceClient(cc)
+			rds := envoy_service_route_v3.NewRouteDiscoveryServiceClient(cc)
 			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 			defer cancel()
 			stream, err := rds.StreamRoutes(ctx)
@@ -176,7 +186,7 @@ func TestGRPC(t *testing.T) {
 				},
 			})
 
-			sds := discovery.NewSecretDiscoveryServiceClient(cc)
+			sds := envoy_service_secret_v3.NewSecretDiscoveryServiceClient(cc)
 			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 			defer cancel()
 			stream, err := sds.StreamSecrets(ctx)
@@ -185,6 +195,81 @@ func TestGRPC(t *testing.T) {
 			checkrecv(t, stream)                    // check we receive one notification
 			checktimeout(t, stream)                 // check that the second receive times out
 		},
+		"StreamClustersV2": func(t *testing.T, cc *grpc.ClientConn) {
+			reh.OnAdd(&v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "simple",
+					Namespace: "default",
+				},
+				Spec: v1.ServiceSpec{
+					Selector: map[string]string{
+						"app": "simple",
+					},
+					Ports: []v1.ServicePort{{
+						Protocol:   "TCP",
+						Port:       80,
+						TargetPort: intstr.FromInt(6502),
+					}},
+				},
+			})
+
+			reh.OnAdd(&v1beta1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "simple",
+					Namespace: "default",
+				},
+				Spec: v1beta1.IngressSpec{
+					Backend: &v1beta1.IngressBackend{
+						ServiceName: "simple",
+						ServicePort: intstr.FromInt(80),
+					},
+				},
+			})
+
+			cds := envoy_api_v2.NewClusterDiscoveryServiceClient(cc)
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			stream, err := cds.StreamClusters(ctx)
+			check(t, err)
+			err = stream.Send(&envoy_api_v2.DiscoveryRequest{TypeUrl: resourcev2.ClusterType})
+			check(t, err)
+			resp, err := stream.Recv()
+			check(t, err)
+			// v3 resources are served under their v2 type URL.
+			if resp.TypeUrl != resourcev2.ClusterType {
+				t.Fatalf("expected type URL %q, got %q", resourcev2.ClusterType, resp.TypeUrl)
+			}
+			if len(resp.Resources) != 1 {
+				t.Fatalf("expected 1 cluster, got %d", len(resp.Resources))
+			}
+			var c envoy_api_v2.Cluster
+			check(t, ptypes.UnmarshalAny(resp.Resources[0], &c))
+			if c.Name == "" {
+				t.Fatalf("expected a named cluster, got %v", &c)
+			}
+		},
+		"ShouldRateLimit": func(t *testing.T, cc *grpc.ClientConn) {
+			req := &rl.RateLimitRequest{
+				Domain:      "enroute",
+				Descriptors: []*envoy_ratelimit_v3.RateLimitDescriptor{{}, {}},
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			resp, err := rl.NewRateLimitServiceClient(cc).ShouldRateLimit(ctx, req)
+			check(t, err)
+			if resp.OverallCode != rl.RateLimitResponse_OK || len(resp.Statuses) != 2 {
+				t.Fatalf("expected 2 statuses and OK, got %v", resp)
+			}
+
+			// Envoys configured with the v2 transport are served too.
+			var reqv2 rlv2.RateLimitRequest
+			check(t, convert(req, &reqv2))
+			respv2, err := rlv2.NewRateLimitServiceClient(cc).ShouldRateLimit(ctx, &reqv2)
+			check(t, err)
+			if respv2.OverallCode != rlv2.RateLimitResponse_OK || len(respv2.Statuses) != 2 {
+				t.Fatalf("expected 2 statuses and OK, got %v", respv2)
+			}
+		},
 	}
 
 	log := logrus.New()
@@ -208,7 +293,7 @@ func TestGRPC(t *testing.T) {
 				ch.ListenerCache.TypeURL(): &ch.ListenerCache,
 				ch.SecretCache.TypeURL():   &ch.SecretCache,
 				et.TypeURL():               et,
-			})
+			}, nil, true)
 			l, err := net.Listen("tcp", "127.0.0.1:0")
 			check(t, err)
 			done := make(chan error, 1)
@@ -235,17 +320,17 @@ func check(t *testing.T, err error) {
 }
 
 func sendreq(t *testing.T, stream interface {
-	Send(*v2.DiscoveryRequest) error
+	Send(*envoy_discovery_v3.DiscoveryRequest) error
 }, typeurl string) {
 	t.Helper()
-	err := stream.Send(&v2.DiscoveryRequest{
+	err := stream.Send(&envoy_discovery_v3.DiscoveryRequest{
 		TypeUrl: typeurl,
 	})
 	check(t, err)
 }
 
 func checkrecv(t *testing.T, stream interface {
-	Recv() (*v2.DiscoveryResponse, error)
+	Recv() (*envoy_discovery_v3.DiscoveryResponse, error)
 }) {
 	t.Helper()
 	_, err := stream.Recv()
@@ -253,7 +338,7 @@ func checkrecv(t *testing.T, stream interface {
 }
 
 func checktimeout(t *testing.T, stream interface {
-	Recv() (*v2.DiscoveryResponse, error)
+	Recv() (*envoy_discovery_v3.DiscoveryResponse, error)
 }) {
 	t.Helper()
 	_, err := stream.Recv()
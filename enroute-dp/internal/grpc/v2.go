@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package grpc
+
+import (
+	"context"
+
+	envoy_api_v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	rlv2 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v2"
+	rl "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	resourcev2 "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// v3TypeURLs maps the type URL of each v2 resource to its v3 equivalent.
+var v3TypeURLs = map[string]string{
+	resourcev2.ClusterType:  resource.ClusterType,
+	resourcev2.EndpointType: resource.EndpointType,
+	resourcev2.ListenerType: resource.ListenerType,
+	resourcev2.RouteType:    resource.RouteType,
+	resourcev2.SecretType:   resource.SecretType,
+}
+
+// v2TypeURLs maps the type URL of each v3 resource to its v2 equivalent.
+var v2TypeURLs = func() map[string]string {
+	m := make(map[string]string, len(v3TypeURLs))
+	for v2, v3 := range v3TypeURLs {
+		m[v3] = v2
+	}
+	return m
+}()
+
+// translateTypeURL returns the type URL typeURL maps to in m,
+// or typeURL if it is not mapped.
+func translateTypeURL(m map[string]string, typeURL string) string {
+	if t, ok := m[typeURL]; ok {
+		return t
+	}
+	return typeURL
+}
+
+// convert copies from into to, which must have the same wire format.
+// The v2 and v3 discovery messages, and the resources built by this
+// package, differ only in their type URLs.
+func convert(from, to proto.Message) error {
+	buf, err := proto.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(buf, to)
+}
+
+type v2Stream interface {
+	Context() context.Context
+	Send(*envoy_api_v2.DiscoveryResponse) error
+	Recv() (*envoy_api_v2.DiscoveryRequest, error)
+}
+
+// v2GrpcStream adapts a v2 xDS stream to the v3 grpcStream.
+type v2GrpcStream struct {
+	v2Stream
+}
+
+func (s v2GrpcStream) Recv() (*envoy_discovery_v3.DiscoveryRequest, error) {
+	req, err := s.v2Stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	var v3 envoy_discovery_v3.DiscoveryRequest
+	if err := convert(req, &v3); err != nil {
+		return nil, err
+	}
+	v3.TypeUrl = translateTypeURL(v3TypeURLs, v3.TypeUrl)
+	return &v3, nil
+}
+
+func (s v2GrpcStream) Send(resp *envoy_discovery_v3.DiscoveryResponse) error {
+	var v2 envoy_api_v2.DiscoveryResponse
+	if err := convert(resp, &v2); err != nil {
+		return err
+	}
+	v2.TypeUrl = translateTypeURL(v2TypeURLs, v2.TypeUrl)
+	for _, r := range v2.Resources {
+		r.TypeUrl = translateTypeURL(v2TypeURLs, r.TypeUrl)
+	}
+	return s.v2Stream.Send(&v2)
+}
+
+type v2DeltaStream interface {
+	Context() context.Context
+	Send(*envoy_api_v2.DeltaDiscoveryResponse) error
+	Recv() (*envoy_api_v2.DeltaDiscoveryRequest, error)
+}
+
+// v2GrpcDeltaStream adapts a v2 incremental xDS stream to the v3 deltaStream.
+type v2GrpcDeltaStream struct {
+	v2DeltaStream
+}
+
+func (s v2GrpcDeltaStream) Recv() (*envoy_discovery_v3.DeltaDiscoveryRequest, error) {
+	req, err := s.v2DeltaStream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	var v3 envoy_discovery_v3.DeltaDiscoveryRequest
+	if err := convert(req, &v3); err != nil {
+		return nil, err
+	}
+	v3.TypeUrl = translateTypeURL(v3TypeURLs, v3.TypeUrl)
+	return &v3, nil
+}
+
+func (s v2GrpcDeltaStream) Send(resp *envoy_discovery_v3.DeltaDiscoveryResponse) error {
+	var v2 envoy_api_v2.DeltaDiscoveryResponse
+	if err := convert(resp, &v2); err != nil {
+		return err
+	}
+	v2.TypeUrl = translateTypeURL(v2TypeURLs, v2.TypeUrl)
+	for _, r := range v2.Resources {
+		if r.Resource != nil {
+			r.Resource.TypeUrl = translateTypeURL(v2TypeURLs, r.Resource.TypeUrl)
+		}
+	}
+	return s.v2DeltaStream.Send(&v2)
+}
+
+// grpcServerV2 implements the v2 LDS, RDS, CDS, EDS, SDS, and ADS gRPC
+// endpoints by serving the v3 resources of grpcServer under their v2
+// type URLs. Resources nested within them, such as the typed config of
+// filters, keep their v3 type URLs, which Envoy 1.14 and later accept
+// on either API version.
+type grpcServerV2 struct {
+	*grpcServer
+}
+
+func (s grpcServerV2) FetchClusters(context.Context, *envoy_api_v2.DiscoveryRequest) (*envoy_api_v2.DiscoveryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "FetchClusters unimplemented")
+}
+
+func (s grpcServerV2) FetchEndpoints(context.Context, *envoy_api_v2.DiscoveryRequest) (*envoy_api_v2.DiscoveryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "FetchEndpoints unimplemented")
+}
+
+func (s grpcServerV2) FetchListeners(context.Context, *envoy_api_v2.DiscoveryRequest) (*envoy_api_v2.DiscoveryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "FetchListeners unimplemented")
+}
+
+func (s grpcServerV2) FetchRoutes(context.Context, *envoy_api_v2.DiscoveryRequest) (*envoy_api_v2.DiscoveryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "FetchRoutes unimplemented")
+}
+
+func (s grpcServerV2) FetchSecrets(context.Context, *envoy_api_v2.DiscoveryRequest) (*envoy_api_v2.DiscoveryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "FetchSecrets unimplemented")
+}
+
+func (s grpcServerV2) StreamClusters(srv envoy_api_v2.ClusterDiscoveryService_StreamClustersServer) error {
+	return s.stream(v2GrpcStream{srv})
+}
+
+func (s grpcServerV2) StreamEndpoints(srv envoy_api_v2.EndpointDiscoveryService_StreamEndpointsServer) error {
+	return s.stream(v2GrpcStream{srv})
+}
+
+func (s grpcServerV2) StreamListeners(srv envoy_api_v2.ListenerDiscoveryService_StreamListenersServer) error {
+	return s.stream(v2GrpcStream{srv})
+}
+
+func (s grpcServerV2) StreamRoutes(srv envoy_api_v2.RouteDiscoveryService_StreamRoutesServer) error {
+	return s.stream(v2GrpcStream{srv})
+}
+
+func (s grpcServerV2) StreamSecrets(srv discovery.SecretDiscoveryService_StreamSecretsServer) error {
+	return s.stream(v2GrpcStream{srv})
+}
+
+func (s grpcServerV2) StreamAggregatedResources(srv discovery.AggregatedDiscoveryService_StreamAggregatedResourcesServer) error {
+	return s.ads(v2GrpcStream{srv})
+}
+
+func (s grpcServerV2) DeltaClusters(srv envoy_api_v2.ClusterDiscoveryService_DeltaClustersServer) error {
+	return s.delta(v2GrpcDeltaStream{srv})
+}
+
+func (s grpcServerV2) DeltaEndpoints(srv envoy_api_v2.EndpointDiscoveryService_DeltaEndpointsServer) error {
+	return s.delta(v2GrpcDeltaStream{srv})
+}
+
+func (s grpcServerV2) DeltaListeners(srv envoy_api_v2.ListenerDiscoveryService_DeltaListenersServer) error {
+	return s.delta(v2GrpcDeltaStream{srv})
+}
+
+func (s grpcServerV2) DeltaRoutes(srv envoy_api_v2.RouteDiscoveryService_DeltaRoutesServer) error {
+	return s.delta(v2GrpcDeltaStream{srv})
+}
+
+func (s grpcServerV2) DeltaSecrets(srv discovery.SecretDiscoveryService_DeltaSecretsServer) error {
+	return s.delta(v2GrpcDeltaStream{srv})
+}
+
+func (s grpcServerV2) DeltaAggregatedResources(discovery.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
+	return status.Errorf(codes.Unimplemented, "DeltaAggregatedResources unimplemented")
+}
+
+// ratelimitServerV2 implements the v2 rate limit service for Envoys
+// configured with the v2 transport, by converting to and from the v3
+// messages of ratelimitServer, which have the same wire format.
+type ratelimitServerV2 struct {
+	*ratelimitServer
+}
+
+func (s ratelimitServerV2) ShouldRateLimit(ctx context.Context, req *rlv2.RateLimitRequest) (*rlv2.RateLimitResponse, error) {
+	var reqv3 rl.RateLimitRequest
+	if err := convert(req, &reqv3); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp, err := s.ratelimitServer.ShouldRateLimit(ctx, &reqv3)
+	if err != nil {
+		return nil, err
+	}
+	var respv2 rlv2.RateLimitResponse
+	if err := convert(resp, &respv2); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &respv2, nil
+}
@@ -22,7 +22,9 @@ import (
 	"strconv"
 	"sync/atomic"
 
-	envoy_api_v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/any"
 	"github.com/sirupsen/logrus"
@@ -38,7 +40,13 @@ type Resource interface {
 	Query(names []string) []proto.Message
 
 	// Register registers ch to receive a value when Notify is called.
-	Register(chan int, int)
+	// If resource names are supplied ch is only notified of changes
+	// which may affect them.
+	Register(chan int, int, ...string)
+
+	// Unregister removes ch, so that it is not left registered once
+	// its stream has ended.
+	Unregister(chan int)
 
 	// TypeURL returns the typeURL of messages returned from Values.
 	TypeURL() string
@@ -48,19 +56,46 @@ type Resource interface {
 type xdsHandler struct {
 	logrus.FieldLogger
 	connections counter
-	resources   map[string]Resource // registered resource types
+	resources   map[string]Resource            // registered resource types
+	proxies     map[string]map[string]Resource // resource types registered per proxy name
+	status      *ConfigStatus                  // ACK/NACK status of each stream, may be nil
+}
+
+// ProxyName returns the name of the proxy node belongs to. Envoy
+// instances serving the same proxy share a cluster name, set with
+// --service-cluster, which is preferred over their unique node id.
+func ProxyName(node *envoy_core_v3.Node) string {
+	if c := node.GetCluster(); c != "" {
+		return c
+	}
+	return node.GetId()
+}
+
+// resourcesFor returns the resource types served to node. A node is
+// served the resources registered for its proxy if there are any, and
+// otherwise those registered for every node.
+func (xh *xdsHandler) resourcesFor(node *envoy_core_v3.Node) (map[string]Resource, error) {
+	if resources, ok := xh.proxies[ProxyName(node)]; ok {
+		return resources, nil
+	}
+	if xh.resources == nil && len(xh.proxies) > 0 {
+		return nil, fmt.Errorf("no resources registered for proxy %q", ProxyName(node))
+	}
+	return xh.resources, nil
 }
 
 type grpcStream interface {
 	Context() context.Context
-	Send(*envoy_api_v2.DiscoveryResponse) error
-	Recv() (*envoy_api_v2.DiscoveryRequest, error)
+	Send(*envoy_discovery_v3.DiscoveryResponse) error
+	Recv() (*envoy_discovery_v3.DiscoveryRequest, error)
 }
 
 // stream processes a stream of DiscoveryRequests.
 func (xh *xdsHandler) stream(st grpcStream) (err error) {
 	// bump connection counter and set it as a field on the logger
-	log := xh.WithField("connection", xh.connections.next())
+	connection := xh.connections.next()
+	log := xh.WithField("connection", connection)
+	defer xh.status.closed(connection)
 
 	// set up some nice function exit handling which notifies if the
 	// stream terminated on error or not.
@@ -80,6 +115,21 @@ func (xh *xdsHandler) stream(st grpcStream) (err error) {
 	last := -1
 	ctx := st.Context()
 
+	// the resources served on this stream are chosen by the node
+	// which sent the first request.
+	var resources map[string]Resource
+
+	var sent sentResources
+
+	// the resource registered with, which ch must not stay registered
+	// with once the stream ends.
+	var registered Resource
+	defer func() {
+		if registered != nil {
+			registered.Unregister(ch)
+		}
+	}()
+
 	// now stick in this loop until the client disconnects.
 	for {
 		// first we wait for the request from Envoy, this is part of
@@ -89,29 +139,53 @@ func (xh *xdsHandler) stream(st grpcStream) (err error) {
 			return err
 		}
 
-		// TODO(dfc) issue 1176: handle xDS ACK/NACK
+		if resources == nil {
+			resources, err = xh.resourcesFor(req.Node)
+			if err != nil {
+				return err
+			}
+		}
 
 		// from the request we derive the resource to stream which have
 		// been registered according to the typeURL.
-		r, ok := xh.resources[req.TypeUrl]
+		r, ok := resources[req.TypeUrl]
 		if !ok {
 			return fmt.Errorf("no resource registered for typeURL %q", req.TypeUrl)
 		}
 
+		// record whether Envoy accepted the last response on this stream.
+		xh.status.request(connection, req)
+		if req.ErrorDetail != nil {
+			log.WithField("type_url", req.TypeUrl).WithField("response_nonce", req.ResponseNonce).WithField("error_detail", req.ErrorDetail.Message).Warn("config rejected")
+		}
+
 		// stick some debugging details on the logger, not that we redeclare log in this scope
 		// so the next time around the loop all is forgotten.
 		log := log.WithField("version_info", req.VersionInfo).WithField("resource_names", req.ResourceNames).WithField("type_url", req.TypeUrl).WithField("response_nonce", req.ResponseNonce).WithField("error_detail", req.ErrorDetail)
 
 		log.Info("stream_wait")
 
+		// Envoy requests the resources it needs as clusters and listeners
+		// come and go. Resources newly named are sent now, rather than on
+		// the next change, and those no longer named are forgotten.
+		if sent.names != nil && !equalNames(sent.names, req.ResourceNames) {
+			sent.retain(req.ResourceNames)
+			last--
+		}
+		sent.names = append([]string{}, req.ResourceNames...)
+
 		// now we wait for a notification, if this is the first request received on this
 		// connection last will be less than zero and that will trigger a response immediately.
-		r.Register(ch, last)
-		select {
-		case last = <-ch:
-			// boom, something in the cache has changed.
-			// TODO(dfc) the thing that has changed may not be in the scope of the filter
-			// so we're going to be sending an update that is a no-op. See #426
+		// Notifications for resources which were not requested are not delivered, and
+		// those which leave the requested resources unchanged are not sent.
+		for {
+			registered = r
+			r.Register(ch, last, req.ResourceNames...)
+			select {
+			case last = <-ch:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 
 			var resources []proto.Message
 			switch len(req.ResourceNames) {
@@ -124,12 +198,21 @@ func (xh *xdsHandler) stream(st grpcStream) (err error) {
 				resources = r.Query(req.ResourceNames)
 			}
 
+			resources, changed, err := sent.changes(resources, len(req.ResourceNames) > 0 && partialTypes[r.TypeURL()])
+			if err != nil {
+				return err
+			}
+			if !changed {
+				log.WithField("last", last).Debug("no changes")
+				continue
+			}
+
 			any, err := toAny(r.TypeURL(), resources)
 			if err != nil {
 				return err
 			}
 
-			resp := &envoy_api_v2.DiscoveryResponse{
+			resp := &envoy_discovery_v3.DiscoveryResponse{
 				VersionInfo: strconv.Itoa(last),
 				Resources:   any,
 				TypeUrl:     r.TypeURL(),
@@ -138,9 +221,84 @@ func (xh *xdsHandler) stream(st grpcStream) (err error) {
 			if err := st.Send(resp); err != nil {
 				return err
 			}
+			xh.status.sent(connection, resp.TypeUrl, resp.VersionInfo)
 			log.WithField("count", len(resources)).Info("response")
-		case <-ctx.Done():
-			return ctx.Err()
+			break
+		}
+	}
+}
+
+// partialTypes are the resource types for which a response need only hold
+// the named resources which changed. Envoy keeps the endpoints and route
+// configurations it is not sent, whereas each response of clusters or
+// listeners replaces all of those it holds.
+var partialTypes = map[string]bool{
+	resource.EndpointType: true,
+	resource.RouteType:    true,
+}
+
+// sentResources records the resources sent on a stream.
+type sentResources struct {
+	// names holds the resource names of the last request.
+	names []string
+
+	// versions holds the version of each resource sent, by name.
+	// Resources without a name are recorded by their version.
+	versions map[string]string
+}
+
+// changes returns the resources to send in place of those last sent, and
+// false if there is no need to send them as none have changed. If partial
+// is true, only the resources which changed are returned.
+func (s *sentResources) changes(resources []proto.Message, partial bool) ([]proto.Message, bool, error) {
+	first := s.versions == nil
+	versions := make(map[string]string, len(resources))
+	var changed []proto.Message
+	for _, r := range resources {
+		value, err := marshalDeterministic(r)
+		if err != nil {
+			return nil, false, err
+		}
+		version := resourceVersion(value)
+		name := resourceName(r)
+		if name == "" {
+			name = version
+		}
+		versions[name] = version
+		if s.versions[name] != version {
+			changed = append(changed, r)
+		}
+	}
+	removed := false
+	for name := range s.versions {
+		if _, ok := versions[name]; !ok {
+			removed = true
+			break
+		}
+	}
+	s.versions = versions
+
+	switch {
+	case first:
+		// Envoy waits for the first response before completing its
+		// initialisation, so it is sent even if it is empty.
+		return resources, true, nil
+	case partial:
+		return changed, len(changed) > 0, nil
+	default:
+		return resources, len(changed) > 0 || removed, nil
+	}
+}
+
+// retain forgets the resources sent which are not named.
+func (s *sentResources) retain(names []string) {
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		keep[name] = true
+	}
+	for name := range s.versions {
+		if !keep[name] {
+			delete(s.versions, name)
 		}
 	}
 }
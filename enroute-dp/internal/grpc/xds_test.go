@@ -23,7 +23,9 @@ import (
 	"io/ioutil"
 	"testing"
 
-	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/golang/protobuf/proto"
 	"github.com/sirupsen/logrus"
 )
@@ -40,7 +42,7 @@ func TestXDSHandlerStream(t *testing.T) {
 			xh: xdsHandler{FieldLogger: log},
 			stream: &mockStream{
 				context: context.Background,
-				recv: func() (*v2.DiscoveryRequest, error) {
+				recv: func() (*envoy_discovery_v3.DiscoveryRequest, error) {
 					return nil, io.EOF
 				},
 			},
@@ -50,8 +52,8 @@ func TestXDSHandlerStream(t *testing.T) {
 			xh: xdsHandler{FieldLogger: log},
 			stream: &mockStream{
 				context: context.Background,
-				recv: func() (*v2.DiscoveryRequest, error) {
-					return &v2.DiscoveryRequest{
+				recv: func() (*envoy_discovery_v3.DiscoveryRequest, error) {
+					return &envoy_discovery_v3.DiscoveryRequest{
 						TypeUrl: "com.heptio.potato",
 					}, nil
 				},
@@ -75,8 +77,8 @@ func TestXDSHandlerStream(t *testing.T) {
 			},
 			stream: &mockStream{
 				context: context.Background,
-				recv: func() (*v2.DiscoveryRequest, error) {
-					return &v2.DiscoveryRequest{
+				recv: func() (*envoy_discovery_v3.DiscoveryRequest, error) {
+					return &envoy_discovery_v3.DiscoveryRequest{
 						TypeUrl: "com.heptio.potato",
 					}, nil
 				},
@@ -92,7 +94,7 @@ func TestXDSHandlerStream(t *testing.T) {
 							ch <- i + 1
 						},
 						contents: func() []proto.Message {
-							return []proto.Message{new(v2.ClusterLoadAssignment)}
+							return []proto.Message{new(envoy_endpoint_v3.ClusterLoadAssignment)}
 						},
 						typeurl: func() string { return "com.heptio.potato" },
 					},
@@ -100,12 +102,12 @@ func TestXDSHandlerStream(t *testing.T) {
 			},
 			stream: &mockStream{
 				context: context.Background,
-				recv: func() (*v2.DiscoveryRequest, error) {
-					return &v2.DiscoveryRequest{
+				recv: func() (*envoy_discovery_v3.DiscoveryRequest, error) {
+					return &envoy_discovery_v3.DiscoveryRequest{
 						TypeUrl: "com.heptio.potato",
 					}, nil
 				},
-				send: func(resp *v2.DiscoveryResponse) error {
+				send: func(resp *envoy_discovery_v3.DiscoveryResponse) error {
 					return io.EOF
 				},
 			},
@@ -130,12 +132,12 @@ func TestXDSHandlerStream(t *testing.T) {
 					cancel()
 					return ctx
 				},
-				recv: func() (*v2.DiscoveryRequest, error) {
-					return &v2.DiscoveryRequest{
+				recv: func() (*envoy_discovery_v3.DiscoveryRequest, error) {
+					return &envoy_discovery_v3.DiscoveryRequest{
 						TypeUrl: "com.heptio.potato",
 					}, nil
 				},
-				send: func(resp *v2.DiscoveryResponse) error {
+				send: func(resp *envoy_discovery_v3.DiscoveryResponse) error {
 					return io.EOF
 				},
 			},
@@ -155,25 +157,153 @@ func TestXDSHandlerStream(t *testing.T) {
 
 type mockStream struct {
 	context func() context.Context
-	send    func(*v2.DiscoveryResponse) error
-	recv    func() (*v2.DiscoveryRequest, error)
+	send    func(*envoy_discovery_v3.DiscoveryResponse) error
+	recv    func() (*envoy_discovery_v3.DiscoveryRequest, error)
 }
 
-func (m *mockStream) Context() context.Context              { return m.context() }
-func (m *mockStream) Send(resp *v2.DiscoveryResponse) error { return m.send(resp) }
-func (m *mockStream) Recv() (*v2.DiscoveryRequest, error)   { return m.recv() }
+func (m *mockStream) Context() context.Context                              { return m.context() }
+func (m *mockStream) Send(resp *envoy_discovery_v3.DiscoveryResponse) error { return m.send(resp) }
+func (m *mockStream) Recv() (*envoy_discovery_v3.DiscoveryRequest, error)   { return m.recv() }
 
 type mockResource struct {
-	contents func() []proto.Message
-	query    func([]string) []proto.Message
-	register func(chan int, int)
-	typeurl  func() string
+	contents   func() []proto.Message
+	query      func([]string) []proto.Message
+	register   func(chan int, int)
+	unregister func(chan int)
+	typeurl    func() string
 }
 
-func (m *mockResource) Contents() []proto.Message            { return m.contents() }
-func (m *mockResource) Query(names []string) []proto.Message { return m.query(names) }
-func (m *mockResource) Register(ch chan int, last int)       { m.register(ch, last) }
-func (m *mockResource) TypeURL() string                      { return m.typeurl() }
+func (m *mockResource) Contents() []proto.Message                   { return m.contents() }
+func (m *mockResource) Query(names []string) []proto.Message        { return m.query(names) }
+func (m *mockResource) Register(ch chan int, last int, _ ...string) { m.register(ch, last) }
+func (m *mockResource) TypeURL() string                             { return m.typeurl() }
+
+func (m *mockResource) Unregister(ch chan int) {
+	if m.unregister != nil {
+		m.unregister(ch)
+	}
+}
+
+func TestStreamUnregistersOnExit(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	// each stream is registered for a notification when its context
+	// is canceled, and must not stay registered once it has ended.
+	tests := map[string]func(xh *xdsHandler, ctx context.Context) error{
+		"xds": func(xh *xdsHandler, ctx context.Context) error {
+			return xh.stream(&mockStream{
+				context: func() context.Context { return ctx },
+				recv: func() (*envoy_discovery_v3.DiscoveryRequest, error) {
+					return &envoy_discovery_v3.DiscoveryRequest{TypeUrl: "com.heptio.potato"}, nil
+				},
+			})
+		},
+		"ads": func(xh *xdsHandler, ctx context.Context) error {
+			sent := false
+			return xh.ads(&mockStream{
+				context: func() context.Context { return ctx },
+				recv: func() (*envoy_discovery_v3.DiscoveryRequest, error) {
+					if sent {
+						<-ctx.Done()
+						return nil, io.EOF
+					}
+					sent = true
+					return &envoy_discovery_v3.DiscoveryRequest{TypeUrl: "com.heptio.potato"}, nil
+				},
+			})
+		},
+	}
+
+	for name, fn := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var registered, unregistered chan int
+			xh := xdsHandler{
+				FieldLogger: log,
+				resources: map[string]Resource{
+					"com.heptio.potato": &mockResource{
+						register: func(ch chan int, last int) {
+							registered = ch
+							cancel()
+						},
+						unregister: func(ch chan int) {
+							unregistered = ch
+						},
+						typeurl: func() string { return "com.heptio.potato" },
+					},
+				},
+			}
+
+			if err := fn(&xh, ctx); err != context.Canceled {
+				t.Fatalf("expected: %v, got: %v", context.Canceled, err)
+			}
+			if registered == nil {
+				t.Fatal("stream was not registered")
+			}
+			if unregistered != registered {
+				t.Fatal("stream was not unregistered when it ended")
+			}
+		})
+	}
+}
+
+func TestXDSHandlerResourcesFor(t *testing.T) {
+	gw1 := map[string]Resource{"com.heptio.potato": &mockResource{}}
+	gw2 := map[string]Resource{"com.heptio.potato": &mockResource{}}
+	shared := map[string]Resource{"com.heptio.potato": &mockResource{}}
+
+	tests := map[string]struct {
+		xh      xdsHandler
+		node    *envoy_core_v3.Node
+		want    map[string]Resource
+		wantErr error
+	}{
+		"no proxies": {
+			xh:   xdsHandler{resources: shared},
+			node: &envoy_core_v3.Node{Id: "envoy-1", Cluster: "gw1"},
+			want: shared,
+		},
+		"proxy named by cluster": {
+			xh:   xdsHandler{proxies: map[string]map[string]Resource{"gw1": gw1, "gw2": gw2}},
+			node: &envoy_core_v3.Node{Id: "gw1", Cluster: "gw2"},
+			want: gw2,
+		},
+		"proxy named by id": {
+			xh:   xdsHandler{proxies: map[string]map[string]Resource{"gw1": gw1, "gw2": gw2}},
+			node: &envoy_core_v3.Node{Id: "gw1"},
+			want: gw1,
+		},
+		"unknown proxy": {
+			xh:      xdsHandler{proxies: map[string]map[string]Resource{"gw1": gw1, "gw2": gw2}},
+			node:    &envoy_core_v3.Node{Id: "envoy-1", Cluster: "gw3"},
+			wantErr: fmt.Errorf("no resources registered for proxy %q", "gw3"),
+		},
+		"unknown proxy, shared resources": {
+			xh:   xdsHandler{resources: shared, proxies: map[string]map[string]Resource{"gw1": gw1}},
+			node: &envoy_core_v3.Node{Cluster: "gw3"},
+			want: shared,
+		},
+		"no node": {
+			xh:      xdsHandler{proxies: map[string]map[string]Resource{"gw1": gw1}},
+			wantErr: fmt.Errorf("no resources registered for proxy %q", ""),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.xh.resourcesFor(tc.node)
+			if !equalError(tc.wantErr, err) {
+				t.Fatalf("expected error: %v, got: %v", tc.wantErr, err)
+			}
+			if fmt.Sprintf("%p", tc.want) != fmt.Sprintf("%p", got) {
+				t.Fatalf("expected resources %p, got %p", tc.want, got)
+			}
+		})
+	}
+}
 
 func TestCounterNext(t *testing.T) {
 	var c counter
@@ -32,13 +32,15 @@ type GatewayHostStatus struct {
 }
 
 // SetStatus sets the GatewayHost status field to an Valid or Invalid status
-func (irs *GatewayHostStatus) SetStatus(status, desc string, existing *gatewayhostv1.GatewayHost) error {
-	// Check if update needed by comparing status & desc
-	if existing.CurrentStatus != status || existing.Description != desc {
+// along with any warnings, such as certificates about to expire.
+func (irs *GatewayHostStatus) SetStatus(status, desc string, warnings []string, existing *gatewayhostv1.GatewayHost) error {
+	// Check if update needed by comparing status, desc & warnings
+	if existing.CurrentStatus != status || existing.Description != desc || !equalWarnings(existing.Warnings, warnings) {
 		updated := existing.DeepCopy()
 		updated.Status = gatewayhostv1.Status{
 			CurrentStatus: status,
 			Description:   desc,
+			Warnings:      warnings,
 		}
 		return irs.setStatus(existing, updated)
 	}
@@ -68,3 +70,15 @@ func (irs *GatewayHostStatus) setStatus(existing, updated *gatewayhostv1.Gateway
 	}
 	return err
 }
+
+func equalWarnings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
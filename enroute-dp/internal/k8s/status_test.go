@@ -31,6 +31,7 @@ func TestSetStatus(t *testing.T) {
 	tests := map[string]struct {
 		msg           string
 		desc          string
+		warnings      []string
 		existing      *gatewayhostv1beta1.GatewayHost
 		expectedPatch string
 		expectedVerbs []string
@@ -83,6 +84,40 @@ func TestSetStatus(t *testing.T) {
 			expectedPatch: `{"status":{"currentStatus":"valid","description":"this is a valid IR"}}`,
 			expectedVerbs: []string{"patch"},
 		},
+		"add warnings": {
+			msg:      "valid",
+			desc:     "this is a valid IR",
+			warnings: []string{"TLS certificate in secret default/cert expires at 2020-05-01T00:00:00Z"},
+			existing: &gatewayhostv1beta1.GatewayHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+				},
+				Status: gatewayhostv1beta1.Status{
+					CurrentStatus: "valid",
+					Description:   "this is a valid IR",
+				},
+			},
+			expectedPatch: `{"status":{"warnings":["TLS certificate in secret default/cert expires at 2020-05-01T00:00:00Z"]}}`,
+			expectedVerbs: []string{"patch"},
+		},
+		"clear warnings": {
+			msg:  "valid",
+			desc: "this is a valid IR",
+			existing: &gatewayhostv1beta1.GatewayHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+				},
+				Status: gatewayhostv1beta1.Status{
+					CurrentStatus: "valid",
+					Description:   "this is a valid IR",
+					Warnings:      []string{"TLS certificate in secret default/cert expires at 2020-05-01T00:00:00Z"},
+				},
+			},
+			expectedPatch: `{"status":{"warnings":null}}`,
+			expectedVerbs: []string{"patch"},
+		},
 	}
 
 	for name, tc := range tests {
@@ -101,7 +136,7 @@ func TestSetStatus(t *testing.T) {
 			irs := GatewayHostStatus{
 				Client: client,
 			}
-			if err := irs.SetStatus(tc.msg, tc.desc, tc.existing); err != nil {
+			if err := irs.SetStatus(tc.msg, tc.desc, tc.warnings, tc.existing); err != nil {
 				t.Fatal(err)
 			}
 
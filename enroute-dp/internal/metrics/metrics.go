@@ -20,6 +20,7 @@ package metrics
 import (
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"k8s.io/client-go/kubernetes"
@@ -37,12 +38,24 @@ type Metrics struct {
 	gatewayHostValidGauge      *prometheus.GaugeVec
 	gatewayHostOrphanedGauge   *prometheus.GaugeVec
 	gatewayHostDAGRebuildGauge *prometheus.GaugeVec
+	secretCertExpiryGauge      *prometheus.GaugeVec
+	xdsSentVersionGauge        *prometheus.GaugeVec
+	xdsAckedVersionGauge       *prometheus.GaugeVec
+	xdsRejectedGauge           *prometheus.GaugeVec
 
 	CacheHandlerOnUpdateSummary prometheus.Summary
 	ResourceEventHandlerSummary *prometheus.SummaryVec
+	XDSNackCounter              *prometheus.CounterVec
+	HoldoffCoalescedCounter     *prometheus.CounterVec
+	DAGRebuildSummary           *prometheus.SummaryVec
 
-	// Keep a local cache of metrics for comparison on updates
-	metricCache *GatewayHostMetric
+	// Keep a local cache of metrics for comparison on updates. The
+	// GatewayHost and secret metrics are cached per proxy, as each
+	// proxy updates its own.
+	mu          sync.Mutex
+	metricCache map[string]*GatewayHostMetric
+	secretCache map[string]map[SecretMeta]time.Time
+	xdsCache    *XDSMetric
 }
 
 // GatewayHostMetric stores various metrics for GatewayHost objects
@@ -59,6 +72,23 @@ type Meta struct {
 	VHost, Namespace string
 }
 
+// SecretMeta holds the namespace, name and SNI of a served secret
+type SecretMeta struct {
+	Namespace, Name, SNI string
+}
+
+// XDSMetric stores the config status of the xDS streams
+type XDSMetric struct {
+	Sent     map[XDSMeta]float64
+	Acked    map[XDSMeta]float64
+	Rejected map[XDSMeta]int
+}
+
+// XDSMeta holds the node ID and type URL of an xDS stream
+type XDSMeta struct {
+	NodeID, TypeURL string
+}
+
 const (
 	GatewayHostTotalGauge      = "enroute_gatewayhost_total"
 	GatewayHostRootTotalGauge  = "enroute_gatewayhost_root_total"
@@ -66,6 +96,13 @@ const (
 	GatewayHostValidGauge      = "enroute_gatewayhost_valid_total"
 	GatewayHostOrphanedGauge   = "enroute_gatewayhost_orphaned_total"
 	GatewayHostDAGRebuildGauge = "enroute_gatewayhost_dagrebuild_timestamp"
+	SecretCertExpiryGauge      = "enroute_secret_certificate_expiry_timestamp"
+	XDSSentVersionGauge        = "enroute_xds_sent_version"
+	XDSAckedVersionGauge       = "enroute_xds_acked_version"
+	XDSRejectedGauge           = "enroute_xds_rejected"
+	XDSNackCounter             = "enroute_xds_nack_total"
+	HoldoffCoalescedCounter    = "enroute_holdoff_coalesced_total"
+	DAGRebuildSummary          = "enroute_dag_rebuild_duration_seconds"
 
 	cacheHandlerOnUpdateSummary = "enroute_cachehandler_onupdate_duration_seconds"
 	resourceEventHandlerSummary = "enroute_resourceeventhandler_duration_seconds"
@@ -75,48 +112,99 @@ const (
 // the supplied registry.
 func NewMetrics(registry *prometheus.Registry) *Metrics {
 	m := Metrics{
-		metricCache: &GatewayHostMetric{},
+		metricCache: make(map[string]*GatewayHostMetric),
+		secretCache: make(map[string]map[SecretMeta]time.Time),
+		xdsCache:    &XDSMetric{},
 		gatewayHostTotalGauge: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: GatewayHostTotalGauge,
 				Help: "Total number of GatewayHosts",
 			},
-			[]string{"namespace"},
+			[]string{"namespace", "proxy"},
 		),
 		gatewayHostRootTotalGauge: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: GatewayHostRootTotalGauge,
 				Help: "Total number of root GatewayHosts",
 			},
-			[]string{"namespace"},
+			[]string{"namespace", "proxy"},
 		),
 		gatewayHostInvalidGauge: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: GatewayHostInvalidGauge,
 				Help: "Total number of invalid GatewayHosts",
 			},
-			[]string{"namespace", "vhost"},
+			[]string{"namespace", "vhost", "proxy"},
 		),
 		gatewayHostValidGauge: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: GatewayHostValidGauge,
 				Help: "Total number of valid GatewayHosts",
 			},
-			[]string{"namespace", "vhost"},
+			[]string{"namespace", "vhost", "proxy"},
 		),
 		gatewayHostOrphanedGauge: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: GatewayHostOrphanedGauge,
 				Help: "Total number of orphaned GatewayHosts",
 			},
-			[]string{"namespace"},
+			[]string{"namespace", "proxy"},
 		),
 		gatewayHostDAGRebuildGauge: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: GatewayHostDAGRebuildGauge,
 				Help: "Timestamp of the last DAG rebuild",
 			},
-			[]string{},
+			[]string{"proxy"},
+		),
+		secretCertExpiryGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: SecretCertExpiryGauge,
+				Help: "Expiry timestamp of the TLS certificate served for an SNI",
+			},
+			[]string{"namespace", "name", "sni", "proxy"},
+		),
+		xdsSentVersionGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: XDSSentVersionGauge,
+				Help: "Version of the config last sent to an Envoy",
+			},
+			[]string{"node_id", "type_url"},
+		),
+		xdsAckedVersionGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: XDSAckedVersionGauge,
+				Help: "Version of the config last acknowledged by an Envoy",
+			},
+			[]string{"node_id", "type_url"},
+		),
+		xdsRejectedGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: XDSRejectedGauge,
+				Help: "Set to 1 while the config last sent to an Envoy is rejected",
+			},
+			[]string{"node_id", "type_url"},
+		),
+		XDSNackCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: XDSNackCounter,
+				Help: "Total number of configs rejected by Envoys",
+			},
+			[]string{"node_id", "type_url"},
+		),
+		HoldoffCoalescedCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: HoldoffCoalescedCounter,
+				Help: "Total number of changes coalesced into the DAG rebuild of a later change",
+			},
+			[]string{"kind", "proxy"},
+		),
+		DAGRebuildSummary: prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Name:       DAGRebuildSummary,
+			Help:       "Histogram for the runtime of DAG rebuilds",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		},
+			[]string{"kind", "proxy"},
 		),
 		CacheHandlerOnUpdateSummary: prometheus.NewSummary(prometheus.SummaryOpts{
 			Name:       cacheHandlerOnUpdateSummary,
@@ -144,59 +232,73 @@ func (m *Metrics) register(registry *prometheus.Registry) {
 		m.gatewayHostValidGauge,
 		m.gatewayHostOrphanedGauge,
 		m.gatewayHostDAGRebuildGauge,
+		m.secretCertExpiryGauge,
+		m.xdsSentVersionGauge,
+		m.xdsAckedVersionGauge,
+		m.xdsRejectedGauge,
 		m.CacheHandlerOnUpdateSummary,
 		m.ResourceEventHandlerSummary,
+		m.XDSNackCounter,
+		m.HoldoffCoalescedCounter,
+		m.DAGRebuildSummary,
 	)
 }
 
-// SetDAGLastRebuilt records the last time the DAG was rebuilt.
-func (m *Metrics) SetDAGLastRebuilt(ts time.Time) {
-	m.gatewayHostDAGRebuildGauge.WithLabelValues().Set(float64(ts.Unix()))
+// SetDAGLastRebuilt records the last time the DAG of proxy was rebuilt.
+func (m *Metrics) SetDAGLastRebuilt(proxy string, ts time.Time) {
+	m.gatewayHostDAGRebuildGauge.WithLabelValues(proxy).Set(float64(ts.Unix()))
 }
 
-// SetGatewayHostMetric sets metric values for a set of GatewayHosts
-func (m *Metrics) SetGatewayHostMetric(metrics GatewayHostMetric) {
+// SetGatewayHostMetric sets metric values for the GatewayHosts of proxy
+func (m *Metrics) SetGatewayHostMetric(proxy string, metrics GatewayHostMetric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cache := m.metricCache[proxy]
+	if cache == nil {
+		cache = &GatewayHostMetric{}
+	}
+
 	// Process metrics
 	for meta, value := range metrics.Total {
-		m.gatewayHostTotalGauge.WithLabelValues(meta.Namespace).Set(float64(value))
-		delete(m.metricCache.Total, meta)
+		m.gatewayHostTotalGauge.WithLabelValues(meta.Namespace, proxy).Set(float64(value))
+		delete(cache.Total, meta)
 	}
 	for meta, value := range metrics.Invalid {
-		m.gatewayHostInvalidGauge.WithLabelValues(meta.Namespace, meta.VHost).Set(float64(value))
-		delete(m.metricCache.Invalid, meta)
+		m.gatewayHostInvalidGauge.WithLabelValues(meta.Namespace, meta.VHost, proxy).Set(float64(value))
+		delete(cache.Invalid, meta)
 	}
 	for meta, value := range metrics.Orphaned {
-		m.gatewayHostOrphanedGauge.WithLabelValues(meta.Namespace).Set(float64(value))
-		delete(m.metricCache.Orphaned, meta)
+		m.gatewayHostOrphanedGauge.WithLabelValues(meta.Namespace, proxy).Set(float64(value))
+		delete(cache.Orphaned, meta)
 	}
 	for meta, value := range metrics.Valid {
-		m.gatewayHostValidGauge.WithLabelValues(meta.Namespace, meta.VHost).Set(float64(value))
-		delete(m.metricCache.Valid, meta)
+		m.gatewayHostValidGauge.WithLabelValues(meta.Namespace, meta.VHost, proxy).Set(float64(value))
+		delete(cache.Valid, meta)
 	}
 	for meta, value := range metrics.Root {
-		m.gatewayHostRootTotalGauge.WithLabelValues(meta.Namespace).Set(float64(value))
-		delete(m.metricCache.Root, meta)
+		m.gatewayHostRootTotalGauge.WithLabelValues(meta.Namespace, proxy).Set(float64(value))
+		delete(cache.Root, meta)
 	}
 
 	// All metrics processed, now remove what's left as they are not needed
-	for meta := range m.metricCache.Total {
-		m.gatewayHostTotalGauge.DeleteLabelValues(meta.Namespace)
+	for meta := range cache.Total {
+		m.gatewayHostTotalGauge.DeleteLabelValues(meta.Namespace, proxy)
 	}
-	for meta := range m.metricCache.Invalid {
-		m.gatewayHostInvalidGauge.DeleteLabelValues(meta.Namespace, meta.VHost)
+	for meta := range cache.Invalid {
+		m.gatewayHostInvalidGauge.DeleteLabelValues(meta.Namespace, meta.VHost, proxy)
 	}
-	for meta := range m.metricCache.Orphaned {
-		m.gatewayHostOrphanedGauge.DeleteLabelValues(meta.Namespace)
+	for meta := range cache.Orphaned {
+		m.gatewayHostOrphanedGauge.DeleteLabelValues(meta.Namespace, proxy)
 	}
-	for meta := range m.metricCache.Valid {
-		m.gatewayHostValidGauge.DeleteLabelValues(meta.Namespace, meta.VHost)
+	for meta := range cache.Valid {
+		m.gatewayHostValidGauge.DeleteLabelValues(meta.Namespace, meta.VHost, proxy)
 	}
-	for meta := range m.metricCache.Root {
-		m.gatewayHostRootTotalGauge.DeleteLabelValues(meta.Namespace)
+	for meta := range cache.Root {
+		m.gatewayHostRootTotalGauge.DeleteLabelValues(meta.Namespace, proxy)
 	}
 
-	// copier.Copy(&m.metricCache, metrics)
-	m.metricCache = &GatewayHostMetric{
+	m.metricCache[proxy] = &GatewayHostMetric{
 		Total:    metrics.Total,
 		Invalid:  metrics.Invalid,
 		Valid:    metrics.Valid,
@@ -205,6 +307,61 @@ func (m *Metrics) SetGatewayHostMetric(metrics GatewayHostMetric) {
 	}
 }
 
+// SetSecretMetric sets the certificate expiry of the secrets served by proxy
+func (m *Metrics) SetSecretMetric(proxy string, expiry map[SecretMeta]time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cache := m.secretCache[proxy]
+	for meta, notAfter := range expiry {
+		m.secretCertExpiryGauge.WithLabelValues(meta.Namespace, meta.Name, meta.SNI, proxy).Set(float64(notAfter.Unix()))
+		delete(cache, meta)
+	}
+
+	// remove secrets no longer served
+	for meta := range cache {
+		m.secretCertExpiryGauge.DeleteLabelValues(meta.Namespace, meta.Name, meta.SNI, proxy)
+	}
+
+	m.secretCache[proxy] = expiry
+}
+
+// SetXDSMetric sets the config status of a set of xDS streams
+func (m *Metrics) SetXDSMetric(metrics XDSMetric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for meta, value := range metrics.Sent {
+		m.xdsSentVersionGauge.WithLabelValues(meta.NodeID, meta.TypeURL).Set(value)
+		delete(m.xdsCache.Sent, meta)
+	}
+	for meta, value := range metrics.Acked {
+		m.xdsAckedVersionGauge.WithLabelValues(meta.NodeID, meta.TypeURL).Set(value)
+		delete(m.xdsCache.Acked, meta)
+	}
+	for meta, value := range metrics.Rejected {
+		m.xdsRejectedGauge.WithLabelValues(meta.NodeID, meta.TypeURL).Set(float64(value))
+		delete(m.xdsCache.Rejected, meta)
+	}
+
+	// remove streams no longer connected
+	for meta := range m.xdsCache.Sent {
+		m.xdsSentVersionGauge.DeleteLabelValues(meta.NodeID, meta.TypeURL)
+	}
+	for meta := range m.xdsCache.Acked {
+		m.xdsAckedVersionGauge.DeleteLabelValues(meta.NodeID, meta.TypeURL)
+	}
+	for meta := range m.xdsCache.Rejected {
+		m.xdsRejectedGauge.DeleteLabelValues(meta.NodeID, meta.TypeURL)
+	}
+
+	m.xdsCache = &XDSMetric{
+		Sent:     metrics.Sent,
+		Acked:    metrics.Acked,
+		Rejected: metrics.Rejected,
+	}
+}
+
 // Service serves various metric and health checking endpoints
 type Service struct {
 	httpsvc.Service
@@ -18,6 +18,7 @@ package metrics
 
 import (
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -42,6 +43,10 @@ func TestSetDAGLastRebuilt(t *testing.T) {
 				metric: GatewayHostDAGRebuildGauge,
 				want: []*io_prometheus_client.Metric{
 					{
+						Label: []*io_prometheus_client.LabelPair{{
+							Name:  func() *string { i := "proxy"; return &i }(),
+							Value: func() *string { i := "gw"; return &i }(),
+						}},
 						Gauge: &io_prometheus_client.Gauge{
 							Value: func() *float64 { i := float64(1.258490098e+09); return &i }(),
 						},
@@ -55,7 +60,7 @@ func TestSetDAGLastRebuilt(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			r := prometheus.NewRegistry()
 			m := NewMetrics(r)
-			m.SetDAGLastRebuilt(tc.value)
+			m.SetDAGLastRebuilt("gw", tc.value)
 
 			gatherers := prometheus.Gatherers{
 				r,
@@ -116,6 +121,9 @@ func TestWriteGatewayHostMetric(t *testing.T) {
 						Label: []*io_prometheus_client.LabelPair{{
 							Name:  func() *string { i := "namespace"; return &i }(),
 							Value: func() *string { i := "foons"; return &i }(),
+						}, {
+							Name:  func() *string { i := "proxy"; return &i }(),
+							Value: func() *string { i := "gw"; return &i }(),
 						}},
 						Gauge: &io_prometheus_client.Gauge{
 							Value: func() *float64 { i := float64(3); return &i }(),
@@ -125,6 +133,9 @@ func TestWriteGatewayHostMetric(t *testing.T) {
 						Label: []*io_prometheus_client.LabelPair{{
 							Name:  func() *string { i := "namespace"; return &i }(),
 							Value: func() *string { i := "testns"; return &i }(),
+						}, {
+							Name:  func() *string { i := "proxy"; return &i }(),
+							Value: func() *string { i := "gw"; return &i }(),
 						}},
 						Gauge: &io_prometheus_client.Gauge{
 							Value: func() *float64 { i := float64(6); return &i }(),
@@ -139,6 +150,9 @@ func TestWriteGatewayHostMetric(t *testing.T) {
 						Label: []*io_prometheus_client.LabelPair{{
 							Name:  func() *string { i := "namespace"; return &i }(),
 							Value: func() *string { i := "testns"; return &i }(),
+						}, {
+							Name:  func() *string { i := "proxy"; return &i }(),
+							Value: func() *string { i := "gw"; return &i }(),
 						}},
 						Gauge: &io_prometheus_client.Gauge{
 							Value: func() *float64 { i := float64(1); return &i }(),
@@ -153,6 +167,9 @@ func TestWriteGatewayHostMetric(t *testing.T) {
 						Label: []*io_prometheus_client.LabelPair{{
 							Name:  func() *string { i := "namespace"; return &i }(),
 							Value: func() *string { i := "testns"; return &i }(),
+						}, {
+							Name:  func() *string { i := "proxy"; return &i }(),
+							Value: func() *string { i := "gw"; return &i }(),
 						}, {
 							Name:  func() *string { i := "vhost"; return &i }(),
 							Value: func() *string { i := "foo.com"; return &i }(),
@@ -170,6 +187,9 @@ func TestWriteGatewayHostMetric(t *testing.T) {
 						Label: []*io_prometheus_client.LabelPair{{
 							Name:  func() *string { i := "namespace"; return &i }(),
 							Value: func() *string { i := "testns"; return &i }(),
+						}, {
+							Name:  func() *string { i := "proxy"; return &i }(),
+							Value: func() *string { i := "gw"; return &i }(),
 						}, {
 							Name:  func() *string { i := "vhost"; return &i }(),
 							Value: func() *string { i := "foo.com"; return &i }(),
@@ -187,6 +207,9 @@ func TestWriteGatewayHostMetric(t *testing.T) {
 						Label: []*io_prometheus_client.LabelPair{{
 							Name:  func() *string { i := "namespace"; return &i }(),
 							Value: func() *string { i := "testns"; return &i }(),
+						}, {
+							Name:  func() *string { i := "proxy"; return &i }(),
+							Value: func() *string { i := "gw"; return &i }(),
 						}},
 						Gauge: &io_prometheus_client.Gauge{
 							Value: func() *float64 { i := float64(4); return &i }(),
@@ -201,7 +224,7 @@ func TestWriteGatewayHostMetric(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			r := prometheus.NewRegistry()
 			m := NewMetrics(r)
-			m.SetGatewayHostMetric(tc.irMetrics)
+			m.SetGatewayHostMetric("gw", tc.irMetrics)
 
 			gatherers := prometheus.Gatherers{
 				r,
@@ -260,6 +283,9 @@ func TestRemoveMetric(t *testing.T) {
 				Label: []*io_prometheus_client.LabelPair{{
 					Name:  func() *string { i := "namespace"; return &i }(),
 					Value: func() *string { i := "foons"; return &i }(),
+				}, {
+					Name:  func() *string { i := "proxy"; return &i }(),
+					Value: func() *string { i := "gw"; return &i }(),
 				}},
 				Gauge: &io_prometheus_client.Gauge{
 					Value: func() *float64 { i := float64(3); return &i }(),
@@ -269,6 +295,9 @@ func TestRemoveMetric(t *testing.T) {
 				Label: []*io_prometheus_client.LabelPair{{
 					Name:  func() *string { i := "namespace"; return &i }(),
 					Value: func() *string { i := "testns"; return &i }(),
+				}, {
+					Name:  func() *string { i := "proxy"; return &i }(),
+					Value: func() *string { i := "gw"; return &i }(),
 				}},
 				Gauge: &io_prometheus_client.Gauge{
 					Value: func() *float64 { i := float64(6); return &i }(),
@@ -284,6 +313,9 @@ func TestRemoveMetric(t *testing.T) {
 				Label: []*io_prometheus_client.LabelPair{{
 					Name:  func() *string { i := "namespace"; return &i }(),
 					Value: func() *string { i := "testns"; return &i }(),
+				}, {
+					Name:  func() *string { i := "proxy"; return &i }(),
+					Value: func() *string { i := "gw"; return &i }(),
 				}},
 				Gauge: &io_prometheus_client.Gauge{
 					Value: func() *float64 { i := float64(1); return &i }(),
@@ -299,6 +331,9 @@ func TestRemoveMetric(t *testing.T) {
 				Label: []*io_prometheus_client.LabelPair{{
 					Name:  func() *string { i := "namespace"; return &i }(),
 					Value: func() *string { i := "testns"; return &i }(),
+				}, {
+					Name:  func() *string { i := "proxy"; return &i }(),
+					Value: func() *string { i := "gw"; return &i }(),
 				}, {
 					Name:  func() *string { i := "vhost"; return &i }(),
 					Value: func() *string { i := "foo.com"; return &i }(),
@@ -317,6 +352,9 @@ func TestRemoveMetric(t *testing.T) {
 				Label: []*io_prometheus_client.LabelPair{{
 					Name:  func() *string { i := "namespace"; return &i }(),
 					Value: func() *string { i := "testns"; return &i }(),
+				}, {
+					Name:  func() *string { i := "proxy"; return &i }(),
+					Value: func() *string { i := "gw"; return &i }(),
 				}, {
 					Name:  func() *string { i := "vhost"; return &i }(),
 					Value: func() *string { i := "foo.com"; return &i }(),
@@ -335,6 +373,9 @@ func TestRemoveMetric(t *testing.T) {
 				Label: []*io_prometheus_client.LabelPair{{
 					Name:  func() *string { i := "namespace"; return &i }(),
 					Value: func() *string { i := "testns"; return &i }(),
+				}, {
+					Name:  func() *string { i := "proxy"; return &i }(),
+					Value: func() *string { i := "gw"; return &i }(),
 				}},
 				Gauge: &io_prometheus_client.Gauge{
 					Value: func() *float64 { i := float64(4); return &i }(),
@@ -392,6 +433,9 @@ func TestRemoveMetric(t *testing.T) {
 					Label: []*io_prometheus_client.LabelPair{{
 						Name:  func() *string { i := "namespace"; return &i }(),
 						Value: func() *string { i := "foons"; return &i }(),
+					}, {
+						Name:  func() *string { i := "proxy"; return &i }(),
+						Value: func() *string { i := "gw"; return &i }(),
 					}},
 					Gauge: &io_prometheus_client.Gauge{
 						Value: func() *float64 { i := float64(3); return &i }(),
@@ -401,6 +445,9 @@ func TestRemoveMetric(t *testing.T) {
 					Label: []*io_prometheus_client.LabelPair{{
 						Name:  func() *string { i := "namespace"; return &i }(),
 						Value: func() *string { i := "testns"; return &i }(),
+					}, {
+						Name:  func() *string { i := "proxy"; return &i }(),
+						Value: func() *string { i := "gw"; return &i }(),
 					}},
 					Gauge: &io_prometheus_client.Gauge{
 						Value: func() *float64 { i := float64(6); return &i }(),
@@ -413,6 +460,9 @@ func TestRemoveMetric(t *testing.T) {
 					Label: []*io_prometheus_client.LabelPair{{
 						Name:  func() *string { i := "namespace"; return &i }(),
 						Value: func() *string { i := "testns"; return &i }(),
+					}, {
+						Name:  func() *string { i := "proxy"; return &i }(),
+						Value: func() *string { i := "gw"; return &i }(),
 					}, {
 						Name:  func() *string { i := "vhost"; return &i }(),
 						Value: func() *string { i := "foo.com"; return &i }(),
@@ -427,6 +477,9 @@ func TestRemoveMetric(t *testing.T) {
 					Label: []*io_prometheus_client.LabelPair{{
 						Name:  func() *string { i := "namespace"; return &i }(),
 						Value: func() *string { i := "testns"; return &i }(),
+					}, {
+						Name:  func() *string { i := "proxy"; return &i }(),
+						Value: func() *string { i := "gw"; return &i }(),
 					}, {
 						Name:  func() *string { i := "vhost"; return &i }(),
 						Value: func() *string { i := "foo.com"; return &i }(),
@@ -441,6 +494,9 @@ func TestRemoveMetric(t *testing.T) {
 					Label: []*io_prometheus_client.LabelPair{{
 						Name:  func() *string { i := "namespace"; return &i }(),
 						Value: func() *string { i := "testns"; return &i }(),
+					}, {
+						Name:  func() *string { i := "proxy"; return &i }(),
+						Value: func() *string { i := "gw"; return &i }(),
 					}},
 					Gauge: &io_prometheus_client.Gauge{
 						Value: func() *float64 { i := float64(4); return &i }(),
@@ -487,6 +543,9 @@ func TestRemoveMetric(t *testing.T) {
 					Label: []*io_prometheus_client.LabelPair{{
 						Name:  func() *string { i := "namespace"; return &i }(),
 						Value: func() *string { i := "testns"; return &i }(),
+					}, {
+						Name:  func() *string { i := "proxy"; return &i }(),
+						Value: func() *string { i := "gw"; return &i }(),
 					}},
 					Gauge: &io_prometheus_client.Gauge{
 						Value: func() *float64 { i := float64(6); return &i }(),
@@ -499,6 +558,9 @@ func TestRemoveMetric(t *testing.T) {
 					Label: []*io_prometheus_client.LabelPair{{
 						Name:  func() *string { i := "namespace"; return &i }(),
 						Value: func() *string { i := "testns"; return &i }(),
+					}, {
+						Name:  func() *string { i := "proxy"; return &i }(),
+						Value: func() *string { i := "gw"; return &i }(),
 					}, {
 						Name:  func() *string { i := "vhost"; return &i }(),
 						Value: func() *string { i := "foo.com"; return &i }(),
@@ -513,6 +575,9 @@ func TestRemoveMetric(t *testing.T) {
 					Label: []*io_prometheus_client.LabelPair{{
 						Name:  func() *string { i := "namespace"; return &i }(),
 						Value: func() *string { i := "testns"; return &i }(),
+					}, {
+						Name:  func() *string { i := "proxy"; return &i }(),
+						Value: func() *string { i := "gw"; return &i }(),
 					}, {
 						Name:  func() *string { i := "vhost"; return &i }(),
 						Value: func() *string { i := "foo.com"; return &i }(),
@@ -527,6 +592,9 @@ func TestRemoveMetric(t *testing.T) {
 					Label: []*io_prometheus_client.LabelPair{{
 						Name:  func() *string { i := "namespace"; return &i }(),
 						Value: func() *string { i := "testns"; return &i }(),
+					}, {
+						Name:  func() *string { i := "proxy"; return &i }(),
+						Value: func() *string { i := "gw"; return &i }(),
 					}},
 					Gauge: &io_prometheus_client.Gauge{
 						Value: func() *float64 { i := float64(4); return &i }(),
@@ -573,6 +641,9 @@ func TestRemoveMetric(t *testing.T) {
 					Label: []*io_prometheus_client.LabelPair{{
 						Name:  func() *string { i := "namespace"; return &i }(),
 						Value: func() *string { i := "testns"; return &i }(),
+					}, {
+						Name:  func() *string { i := "proxy"; return &i }(),
+						Value: func() *string { i := "gw"; return &i }(),
 					}},
 					Gauge: &io_prometheus_client.Gauge{
 						Value: func() *float64 { i := float64(6); return &i }(),
@@ -585,6 +656,9 @@ func TestRemoveMetric(t *testing.T) {
 					Label: []*io_prometheus_client.LabelPair{{
 						Name:  func() *string { i := "namespace"; return &i }(),
 						Value: func() *string { i := "testns"; return &i }(),
+					}, {
+						Name:  func() *string { i := "proxy"; return &i }(),
+						Value: func() *string { i := "gw"; return &i }(),
 					}, {
 						Name:  func() *string { i := "vhost"; return &i }(),
 						Value: func() *string { i := "foo.com"; return &i }(),
@@ -599,6 +673,9 @@ func TestRemoveMetric(t *testing.T) {
 					Label: []*io_prometheus_client.LabelPair{{
 						Name:  func() *string { i := "namespace"; return &i }(),
 						Value: func() *string { i := "testns"; return &i }(),
+					}, {
+						Name:  func() *string { i := "proxy"; return &i }(),
+						Value: func() *string { i := "gw"; return &i }(),
 					}, {
 						Name:  func() *string { i := "vhost"; return &i }(),
 						Value: func() *string { i := "foo.com"; return &i }(),
@@ -613,6 +690,9 @@ func TestRemoveMetric(t *testing.T) {
 					Label: []*io_prometheus_client.LabelPair{{
 						Name:  func() *string { i := "namespace"; return &i }(),
 						Value: func() *string { i := "testns"; return &i }(),
+					}, {
+						Name:  func() *string { i := "proxy"; return &i }(),
+						Value: func() *string { i := "gw"; return &i }(),
 					}},
 					Gauge: &io_prometheus_client.Gauge{
 						Value: func() *float64 { i := float64(4); return &i }(),
@@ -626,7 +706,7 @@ func TestRemoveMetric(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			r := prometheus.NewRegistry()
 			m := NewMetrics(r)
-			m.SetGatewayHostMetric(tc.irMetrics)
+			m.SetGatewayHostMetric("gw", tc.irMetrics)
 
 			gatherers := prometheus.Gatherers{
 				r,
@@ -674,7 +754,7 @@ func TestRemoveMetric(t *testing.T) {
 				t.Fatalf("write metric orphaned metric failed, want: %v got: %v", root.want, gotRoot)
 			}
 
-			m.SetGatewayHostMetric(tc.irMetricsUpdated)
+			m.SetGatewayHostMetric("gw", tc.irMetricsUpdated)
 
 			// Now validate that metrics got removed
 			gatherers = prometheus.Gatherers{
@@ -725,3 +805,177 @@ func TestRemoveMetric(t *testing.T) {
 		})
 	}
 }
+
+func TestSetSecretMetric(t *testing.T) {
+	expiry := func(namespace, name, sni string, value float64) *io_prometheus_client.Metric {
+		return &io_prometheus_client.Metric{
+			Label: []*io_prometheus_client.LabelPair{{
+				Name:  func() *string { i := "name"; return &i }(),
+				Value: func() *string { i := name; return &i }(),
+			}, {
+				Name:  func() *string { i := "namespace"; return &i }(),
+				Value: func() *string { i := namespace; return &i }(),
+			}, {
+				Name:  func() *string { i := "proxy"; return &i }(),
+				Value: func() *string { i := "gw"; return &i }(),
+			}, {
+				Name:  func() *string { i := "sni"; return &i }(),
+				Value: func() *string { i := sni; return &i }(),
+			}},
+			Gauge: &io_prometheus_client.Gauge{
+				Value: func() *float64 { i := value; return &i }(),
+			},
+		}
+	}
+	notAfter := time.Date(2029, 6, 12, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		secrets        map[SecretMeta]time.Time
+		secretsUpdated map[SecretMeta]time.Time
+		want           []*io_prometheus_client.Metric
+	}{
+		"simple": {
+			secrets: map[SecretMeta]time.Time{
+				{Namespace: "testns", Name: "secret", SNI: "foo.com"}: notAfter,
+			},
+			want: []*io_prometheus_client.Metric{
+				expiry("testns", "secret", "foo.com", float64(notAfter.Unix())),
+			},
+		},
+		"secret no longer served": {
+			secrets: map[SecretMeta]time.Time{
+				{Namespace: "testns", Name: "secret", SNI: "foo.com"}: notAfter,
+				{Namespace: "testns", Name: "secret", SNI: "bar.com"}: notAfter,
+			},
+			secretsUpdated: map[SecretMeta]time.Time{
+				{Namespace: "testns", Name: "secret", SNI: "bar.com"}: notAfter,
+			},
+			want: []*io_prometheus_client.Metric{
+				expiry("testns", "secret", "bar.com", float64(notAfter.Unix())),
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := prometheus.NewRegistry()
+			m := NewMetrics(r)
+			m.SetSecretMetric("gw", tc.secrets)
+			if tc.secretsUpdated != nil {
+				m.SetSecretMetric("gw", tc.secretsUpdated)
+			}
+
+			gathering, err := r.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []*io_prometheus_client.Metric{}
+			for _, mf := range gathering {
+				if mf.GetName() == SecretCertExpiryGauge {
+					got = mf.Metric
+				}
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("write secret metric failed, want: %v got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSetXDSMetric(t *testing.T) {
+	r := prometheus.NewRegistry()
+	m := NewMetrics(r)
+
+	meta1 := XDSMeta{NodeID: "envoy-1", TypeURL: "type.googleapis.com/envoy.config.cluster.v3.Cluster"}
+	meta2 := XDSMeta{NodeID: "envoy-2", TypeURL: "type.googleapis.com/envoy.config.cluster.v3.Cluster"}
+	m.SetXDSMetric(XDSMetric{
+		Sent:     map[XDSMeta]float64{meta1: 3, meta2: 3},
+		Acked:    map[XDSMeta]float64{meta1: 2, meta2: 3},
+		Rejected: map[XDSMeta]int{meta1: 1, meta2: 0},
+	})
+	// envoy-2 disconnects
+	m.SetXDSMetric(XDSMetric{
+		Sent:     map[XDSMeta]float64{meta1: 3},
+		Acked:    map[XDSMeta]float64{meta1: 2},
+		Rejected: map[XDSMeta]int{meta1: 1},
+	})
+
+	gathering, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]float64{
+		XDSSentVersionGauge:  3,
+		XDSAckedVersionGauge: 2,
+		XDSRejectedGauge:     1,
+	}
+	for _, mf := range gathering {
+		v, ok := want[mf.GetName()]
+		if !ok {
+			continue
+		}
+		if len(mf.Metric) != 1 {
+			t.Fatalf("%s: expected 1 metric, got %v", mf.GetName(), mf.Metric)
+		}
+		if got := mf.Metric[0].GetGauge().GetValue(); got != v {
+			t.Fatalf("%s: expected %v, got %v", mf.GetName(), v, got)
+		}
+		delete(want, mf.GetName())
+	}
+	if len(want) != 0 {
+		t.Fatalf("metrics not found: %v", want)
+	}
+}
+
+func TestMetricsPerProxy(t *testing.T) {
+	r := prometheus.NewRegistry()
+	m := NewMetrics(r)
+
+	// proxies update their metrics concurrently
+	var wg sync.WaitGroup
+	for _, proxy := range []string{"gw1", "gw2"} {
+		wg.Add(1)
+		go func(proxy string) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				m.SetGatewayHostMetric(proxy, GatewayHostMetric{
+					Total: map[Meta]int{{Namespace: "testns"}: i},
+				})
+				m.SetSecretMetric(proxy, map[SecretMeta]time.Time{
+					{Namespace: "testns", Name: "secret", SNI: proxy + ".com"}: time.Now(),
+				})
+			}
+		}(proxy)
+	}
+	wg.Wait()
+
+	// and an update of one proxy leaves the series of the others
+	m.SetGatewayHostMetric("gw1", GatewayHostMetric{})
+	m.SetSecretMetric("gw1", nil)
+
+	gathering, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{GatewayHostTotalGauge: true, SecretCertExpiryGauge: true}
+	for _, mf := range gathering {
+		if !want[mf.GetName()] {
+			continue
+		}
+		if len(mf.Metric) != 1 {
+			t.Fatalf("%s: expected 1 metric, got %v", mf.GetName(), mf.Metric)
+		}
+		for _, l := range mf.Metric[0].Label {
+			if l.GetName() == "proxy" && l.GetValue() != "gw2" {
+				t.Fatalf("%s: expected the metric of gw2, got %v", mf.GetName(), mf.Metric)
+			}
+		}
+		delete(want, mf.GetName())
+	}
+	if len(want) != 0 {
+		t.Fatalf("metrics not found: %v", want)
+	}
+}
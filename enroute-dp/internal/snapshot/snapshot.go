@@ -0,0 +1,273 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+// Package snapshot saves the xDS resources served to Envoy to a file, and
+// loads them back, so that a restarted enroute serves the last good
+// configuration before it hears from its configuration source.
+package snapshot
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/sirupsen/logrus"
+)
+
+// FormatVersion is the version of the snapshot file format written by Save.
+const FormatVersion = 1
+
+// Resource is a cache of xDS resources whose changes can be waited on.
+type Resource interface {
+	Contents() []proto.Message
+	Register(chan int, int, ...string)
+	TypeURL() string
+}
+
+// file is the format of a snapshot on disk.
+type file struct {
+	// Version is the format of the file, FormatVersion.
+	Version int `json:"version"`
+
+	// Created is when the snapshot was taken.
+	Created time.Time `json:"created"`
+
+	// Encrypted is true if the secrets are encrypted.
+	Encrypted bool `json:"encrypted,omitempty"`
+
+	// Resources holds the marshalled resources of each type, keyed by
+	// type URL.
+	Resources map[string][][]byte `json:"resources"`
+}
+
+// Save writes the contents of resources to path. If key is not nil the
+// secrets are encrypted with it, using AES-GCM. The file is replaced
+// atomically, so a reader never sees a partly written snapshot.
+func Save(path string, resources []Resource, key []byte) error {
+	f := file{
+		Version:   FormatVersion,
+		Created:   time.Now().UTC(),
+		Encrypted: key != nil,
+		Resources: make(map[string][][]byte),
+	}
+	for _, r := range resources {
+		for _, msg := range r.Contents() {
+			value, err := proto.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			if key != nil && r.TypeURL() == resource.SecretType {
+				if value, err = seal(key, value); err != nil {
+					return err
+				}
+			}
+			f.Resources[r.TypeURL()] = append(f.Resources[r.TypeURL()], value)
+		}
+	}
+
+	buf, err := json.MarshalIndent(&f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load reads the resources saved to path by Save. Encrypted secrets are
+// decrypted with key.
+func Load(path string, key []byte) ([]proto.Message, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(buf, &f); err != nil {
+		return nil, fmt.Errorf("decoding snapshot %s: %v", path, err)
+	}
+	if f.Version != FormatVersion {
+		return nil, fmt.Errorf("snapshot %s has format version %d, expected %d", path, f.Version, FormatVersion)
+	}
+	if f.Encrypted && key == nil {
+		return nil, fmt.Errorf("snapshot %s has encrypted secrets, but no key was supplied", path)
+	}
+
+	// resources are returned in a stable order.
+	typeURLs := make([]string, 0, len(f.Resources))
+	for typeURL := range f.Resources {
+		typeURLs = append(typeURLs, typeURL)
+	}
+	sort.Strings(typeURLs)
+
+	var msgs []proto.Message
+	for _, typeURL := range typeURLs {
+		for _, value := range f.Resources[typeURL] {
+			if f.Encrypted && typeURL == resource.SecretType {
+				if value, err = open(key, value); err != nil {
+					return nil, fmt.Errorf("decrypting snapshot %s: %v", path, err)
+				}
+			}
+			var msg ptypes.DynamicAny
+			if err := ptypes.UnmarshalAny(&any.Any{TypeUrl: typeURL, Value: value}, &msg); err != nil {
+				return nil, fmt.Errorf("decoding snapshot %s: %v", path, err)
+			}
+			msgs = append(msgs, msg.Message)
+		}
+	}
+	return msgs, nil
+}
+
+// LoadKey reads a base64 encoded 256 bit AES key from path.
+func LoadKey(path string) ([]byte, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(buf)))
+	if err != nil {
+		return nil, fmt.Errorf("decoding key %s: %v", path, err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key %s is %d bytes, expected 32", path, len(key))
+	}
+	return key, nil
+}
+
+// seal encrypts plaintext with key. The nonce is prepended to the result.
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open decrypts ciphertext sealed with key.
+func open(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce := ciphertext[:gcm.NonceSize()]
+	return gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Writer saves a snapshot of its Resources to Path each time they change.
+type Writer struct {
+	// Path of the snapshot file.
+	Path string
+
+	// Key, if set, encrypts the secrets in the snapshot.
+	Key []byte
+
+	// Resources saved in the snapshot.
+	Resources []Resource
+
+	// Delay is how long to wait after a change before saving, so that
+	// the changes a rebuild makes to each resource are saved together.
+	// If zero, it defaults to one second.
+	Delay time.Duration
+
+	logrus.FieldLogger
+}
+
+// Start fulfills the g.Start contract.
+// When stop is closed the writer stops saving snapshots.
+func (w *Writer) Start(stop <-chan struct{}) error {
+	w.Println("started")
+	defer w.Println("stopped")
+
+	delay := w.Delay
+	if delay == 0 {
+		delay = time.Second
+	}
+
+	// each resource is waited on in its own goroutine, as each
+	// has its own sequence of notifications.
+	changed := make(chan struct{}, 1)
+	for _, r := range w.Resources {
+		go func(r Resource) {
+			ch := make(chan int, 1)
+			last := 0
+			for {
+				r.Register(ch, last)
+				select {
+				case last = <-ch:
+					select {
+					case changed <- struct{}{}:
+					default:
+					}
+				case <-stop:
+					return
+				}
+			}
+		}(r)
+	}
+
+	for {
+		select {
+		case <-changed:
+		case <-stop:
+			return nil
+		}
+		select {
+		case <-time.After(delay):
+		case <-stop:
+			return nil
+		}
+		// drain the changes made while waiting, they are part of this snapshot.
+		select {
+		case <-changed:
+		default:
+		}
+		if err := Save(w.Path, w.Resources, w.Key); err != nil {
+			w.WithError(err).WithField("path", w.Path).Error("failed to save snapshot")
+			continue
+		}
+		w.WithField("path", w.Path).Debug("saved snapshot")
+	}
+}
@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+)
+
+// cache is a Resource whose contents are replaced by the test.
+type cache struct {
+	typeURL string
+
+	mu       sync.Mutex
+	contents []proto.Message
+	last     int
+	waiters  []chan int
+}
+
+func (c *cache) Contents() []proto.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.contents
+}
+
+func (c *cache) Register(ch chan int, last int, _ ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if last < c.last {
+		ch <- c.last
+		return
+	}
+	c.waiters = append(c.waiters, ch)
+}
+
+func (c *cache) TypeURL() string { return c.typeURL }
+
+func (c *cache) update(contents ...proto.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.contents = contents
+	c.last++
+	for _, ch := range c.waiters {
+		ch <- c.last
+	}
+	c.waiters = nil
+}
+
+var (
+	cluster = &envoy_cluster_v3.Cluster{Name: "default/kuard/80"}
+	secret  = &envoy_tls_v3.Secret{
+		Name: "default/secret/cd1b506996",
+		Type: &envoy_tls_v3.Secret_TlsCertificate{
+			TlsCertificate: &envoy_tls_v3.TlsCertificate{
+				PrivateKey: &envoy_core_v3.DataSource{
+					Specifier: &envoy_core_v3.DataSource_InlineBytes{
+						InlineBytes: []byte("private-key-material"),
+					},
+				},
+			},
+		},
+	}
+)
+
+func resources() []Resource {
+	return []Resource{
+		&cache{typeURL: resource.ClusterType, contents: []proto.Message{cluster}},
+		&cache{typeURL: resource.SecretType, contents: []proto.Message{secret}},
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+
+	tests := map[string]struct {
+		saveKey, loadKey []byte
+		wantErr          string
+	}{
+		"plain": {},
+		"encrypted": {
+			saveKey: key,
+			loadKey: key,
+		},
+		"encrypted, no key": {
+			saveKey: key,
+			wantErr: "no key was supplied",
+		},
+		"encrypted, wrong key": {
+			saveKey: key,
+			loadKey: bytes.Repeat([]byte{8}, 32),
+			wantErr: "decrypting snapshot",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "snapshot")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "enroute.json")
+
+			if err := Save(path, resources(), tc.saveKey); err != nil {
+				t.Fatal(err)
+			}
+			buf, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var f file
+			if err := json.Unmarshal(buf, &f); err != nil {
+				t.Fatal(err)
+			}
+			plain := bytes.Contains(f.Resources[resource.SecretType][0], []byte("private-key-material"))
+			if plain != (tc.saveKey == nil) {
+				t.Fatalf("expected secret encrypted: %v, got plaintext: %v", tc.saveKey != nil, plain)
+			}
+
+			got, err := Load(path, tc.loadKey)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []proto.Message{cluster, secret}
+			if diff := cmp.Diff(want, got, cmp.Comparer(proto.Equal)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestLoadFormatVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "enroute.json")
+	if err := ioutil.WriteFile(path, []byte(`{"version": 2, "resources": {}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Load(path, nil)
+	if err == nil || !strings.Contains(err.Error(), "format version 2") {
+		t.Fatalf("expected a format version error, got: %v", err)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	clusters := &cache{typeURL: resource.ClusterType}
+	w := &Writer{
+		Path:        filepath.Join(dir, "enroute.json"),
+		Resources:   []Resource{clusters},
+		Delay:       time.Millisecond,
+		FieldLogger: log,
+	}
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- w.Start(stop) }()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	// nothing is saved until the resources change.
+	time.Sleep(20 * time.Millisecond)
+	if _, err := os.Stat(w.Path); !os.IsNotExist(err) {
+		t.Fatalf("expected no snapshot, got: %v", err)
+	}
+
+	clusters.update(cluster)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := Load(w.Path, nil)
+		if err == nil && len(got) == 1 && proto.Equal(got[0], cluster) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("snapshot not saved: %v, %v", got, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
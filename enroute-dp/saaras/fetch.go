@@ -10,38 +10,68 @@ import (
 	"time"
 )
 
-const oneSecond time.Duration = 1
+// cloudPollIntervalSeconds is how often the configuration is fetched
+// while it cannot be watched
 const cloudPollIntervalSeconds int = 10
 
 type Service struct {
 	logrus.FieldLogger
 }
 
+// WatchCloudGatewayHost fetches the configuration of the proxy
+// scc.ProxyName whenever its version streamed by enroute-cp changes. When
+// the stream cannot be watched, like with an enroute-cp which does not
+// stream versions, the configuration is polled until it can again.
+// fetched, if set, is called once the configuration is first fetched.
 func WatchCloudGatewayHost(g *workgroup.Group,
 	log logrus.FieldLogger,
 	reh *contour.ResourceEventHandler,
 	et *contour.EndpointsTranslator,
 	pct *contour.GlobalConfigTranslator,
-	scc *SaarasCloudCache) {
+	scc *SaarasCloudCache,
+	fetched func()) {
 
 	g.Add(func(stop <-chan struct{}) error {
 		log.Println("started")
 		defer log.Println("stopped")
 
-		count := 0
+		url := enrouteCPURL(WatchPath + scc.ProxyName)
+		polling := false
+		fetch := func() {
+			log.Infoln("Fetch-and-Apply configuration from cloud")
+			if err := FetchGatewayHost(reh, et, pct, scc, log); err == nil && fetched != nil {
+				fetched()
+				fetched = nil
+			}
+		}
 
 		for {
-			time.Sleep(oneSecond * 1000 * time.Millisecond)
-			count = count + 1
+			version := ""
+			err := WatchVersion(url, stop, func(v string) {
+				if polling {
+					log.Infoln("Watching configuration on enroute-cp")
+					polling = false
+				}
+				if v != version {
+					version = v
+					fetch()
+				}
+			}, log)
+			if err == nil {
+				return nil
+			}
 
-			// Poll for GatewayHost every cloudPollIntervalSeconds seconds
-			if (count % cloudPollIntervalSeconds) == 0 {
-				log.Infoln("Fetch-and-Apply configuration from cloud")
-				FetchGatewayHost(reh, et, pct, scc, log)
+			if !polling {
+				log.Warnf("Cannot watch configuration on enroute-cp [%v], polling every %d seconds\n", err, cloudPollIntervalSeconds)
+				polling = true
 			}
+			select {
+			case <-stop:
+				return nil
+			case <-time.After(time.Duration(cloudPollIntervalSeconds) * time.Second):
+			}
+			fetch()
 		}
-
-		return nil
 	})
 }
 
@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"net/http"
 	"os"
 )
 
@@ -15,23 +16,38 @@ var ENROUTE_CP_SERVER_IP string
 var ENROUTE_CP_SERVER_PORT string
 var ENROUTE_CP_PROTO string
 
-// Used by enroute-dp
-func FetchConfig(query string, buf *bytes.Buffer, args map[string]string, log logrus.FieldLogger) error {
+// ENROUTE_CP_TOKEN, if set, is the key enroute-dp authenticates to
+// enroute-cp with, its WEBAPP_SECRET or an API token
+var ENROUTE_CP_TOKEN string
 
-	var SAARAS_GRAPHQL_SERVER_URL2 string
+// setAuthorization sets the key of h to ENROUTE_CP_TOKEN, if set
+func setAuthorization(h http.Header) {
+	if ENROUTE_CP_TOKEN != "" {
+		h.Set("Authorization", "Bearer "+ENROUTE_CP_TOKEN)
+	}
+}
 
+// enrouteCPURL returns the url of path on enroute-cp
+func enrouteCPURL(path string) string {
 	if ENROUTE_CP_PROTO == "HTTP" || ENROUTE_CP_PROTO == "http" {
-		SAARAS_GRAPHQL_SERVER_URL2 = "http://" + ENROUTE_CP_SERVER_IP + ":" + ENROUTE_CP_SERVER_PORT + "/v1/graphql"
+		return "http://" + ENROUTE_CP_SERVER_IP + ":" + ENROUTE_CP_SERVER_PORT + path
 	} else if ENROUTE_CP_PROTO == "HTTPS" || ENROUTE_CP_PROTO == "https" {
-		SAARAS_GRAPHQL_SERVER_URL2 = "https://" + ENROUTE_CP_SERVER_IP + ":" + ENROUTE_CP_SERVER_PORT + "/v1/graphql"
-	} else {
-		fmt.Printf("Please provide a valid value for enroute-cp-proto. Allowed values are HTTP or HTTPS\n")
-		os.Exit(1)
+		return "https://" + ENROUTE_CP_SERVER_IP + ":" + ENROUTE_CP_SERVER_PORT + path
 	}
+	fmt.Printf("Please provide a valid value for enroute-cp-proto. Allowed values are HTTP or HTTPS\n")
+	os.Exit(1)
+	return ""
+}
+
+// Used by enroute-dp
+func FetchConfig(query string, buf *bytes.Buffer, args map[string]string, log logrus.FieldLogger) error {
+
+	SAARAS_GRAPHQL_SERVER_URL2 := enrouteCPURL("/v1/graphql")
 
 	client := NewClient(SAARAS_GRAPHQL_SERVER_URL2)
 	client.Log = func(s string) { log.Debugf("%s", s) }
 	req := NewRequest(query)
+	setAuthorization(req.Header)
 
 	for k, v := range args {
 		req.Var(k, v)
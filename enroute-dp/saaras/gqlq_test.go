@@ -0,0 +1,52 @@
+package saaras
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/saarasio/enroute/enroute-dp/internal/assert"
+	"github.com/sirupsen/logrus"
+)
+
+func TestEnrouteCPToken(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+		if r.URL.Path == WatchPath+"gw" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			return
+		}
+		fmt.Fprint(w, `{"data": {}}`)
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(ip, port, proto, token string) {
+		ENROUTE_CP_SERVER_IP, ENROUTE_CP_SERVER_PORT, ENROUTE_CP_PROTO, ENROUTE_CP_TOKEN = ip, port, proto, token
+	}(ENROUTE_CP_SERVER_IP, ENROUTE_CP_SERVER_PORT, ENROUTE_CP_PROTO, ENROUTE_CP_TOKEN)
+	ENROUTE_CP_SERVER_IP, ENROUTE_CP_SERVER_PORT, _ = net.SplitHostPort(u.Host)
+	ENROUTE_CP_PROTO = "http"
+
+	fetch := func() {
+		var buf bytes.Buffer
+		assert.Equal(t, nil, FetchConfig(QGatewayHost, &buf, map[string]string{"proxy_name": "gw"}, logrus.New()))
+		WatchVersion(enrouteCPURL(WatchPath+"gw"), make(chan struct{}), func(string) {}, logrus.New())
+	}
+
+	// Requests are not authenticated without a token
+	fetch()
+	assert.Equal(t, []string{"", ""}, got)
+
+	got = nil
+	ENROUTE_CP_TOKEN = "token"
+	fetch()
+	assert.Equal(t, []string{"Bearer token", "Bearer token"}, got)
+}
@@ -87,6 +87,7 @@ query get_services_by_proxy($proxy_name: String!) {
           secret_key
           secret_sni
           secret_cert
+          secret_tls_config
           create_ts
           update_ts
           artifacts {
@@ -214,9 +215,11 @@ type SaarasSecret struct {
 	Secret_key  string
 	Secret_cert string
 	Secret_sni  string
-	Artifacts   []SaarasArtifact
-	Create_ts   string
-	Update_ts   string
+	// TLS parameters of the virtual host using this secret, see saarasconfig.TLSConfig
+	Secret_tls_config string
+	Artifacts         []SaarasArtifact
+	Create_ts         string
+	Update_ts         string
 }
 
 type SaarasSecrets struct {
@@ -370,8 +373,15 @@ func getIrSecretName2(sir *SaarasGatewayHostService) string {
 func getIrTLS(sir *SaarasGatewayHostService) *v1beta1.TLS {
 	secret_name := getIrSecretName2(sir)
 	if len(secret_name) > 0 {
+		// The TLS config is validated by enroute-cp when it is saved and
+		// again when the DAG is built, a decode error leaves the defaults.
+		tc, _ := cfg.UnmarshalTLSConfig(sir.Service.Service_secrets[0].Secret.Secret_tls_config)
 		return &v1beta1.TLS{
-			SecretName: secret_name,
+			SecretName:             secret_name,
+			MinimumProtocolVersion: tc.MinimumProtocolVersion,
+			MaximumProtocolVersion: tc.MaximumProtocolVersion,
+			CipherSuites:           tc.CipherSuites,
+			ECDHCurves:             tc.ECDHCurves,
 		}
 	} else {
 		return nil
@@ -442,7 +452,7 @@ func saaras_routecondition_to_v1b1_ir_routecondition(r SaarasRoute2) []v1beta1.C
 	return conds
 }
 
-func Saaras_ir__to__v1b1_ir2(sir *SaarasGatewayHostService) *v1beta1.GatewayHost {
+func Saaras_ir__to__v1b1_ir2(sir *SaarasGatewayHostService, proxy string) *v1beta1.GatewayHost {
 	routes := make([]v1beta1.Route, 0)
 	for _, oneRoute := range sir.Service.Routes {
 		routes = append(routes, v1beta1.Route{
@@ -462,12 +472,14 @@ func Saaras_ir__to__v1b1_ir2(sir *SaarasGatewayHostService) *v1beta1.GatewayHost
 			//},
 			Services: saaras_route_to_v1b1_service_slice2(sir, oneRoute),
 			Filters:  saaras_ir_route_filter__to__v1b1_route_filter(oneRoute),
+			// ACME HTTP-01 challenges must not be redirected to https
+			PermitInsecure: oneRoute.Route_prefix == cfg.ACME_HTTP01_CHALLENGE_PREFIX,
 		})
 	}
 	return &v1beta1.GatewayHost{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      sir.Service.Service_name,
-			Namespace: ENROUTE_NAME,
+			Namespace: proxy,
 		},
 		Spec: v1beta1.GatewayHostSpec{
 			VirtualHost: &v1beta1.VirtualHost{
@@ -481,12 +493,12 @@ func Saaras_ir__to__v1b1_ir2(sir *SaarasGatewayHostService) *v1beta1.GatewayHost
 	}
 }
 
-func saaras_ir_slice__to__v1b1_ir_map(s *[]SaarasGatewayHostService, log logrus.FieldLogger) *map[string]*v1beta1.GatewayHost {
+func saaras_ir_slice__to__v1b1_ir_map(s *[]SaarasGatewayHostService, proxy string, log logrus.FieldLogger) *map[string]*v1beta1.GatewayHost {
 	var m map[string]*v1beta1.GatewayHost
 	m = make(map[string]*v1beta1.GatewayHost)
 
 	for _, oneSaarasIRService := range *s {
-		onev1b1ir := Saaras_ir__to__v1b1_ir2(&oneSaarasIRService)
+		onev1b1ir := Saaras_ir__to__v1b1_ir2(&oneSaarasIRService, proxy)
 		//spew.Dump(onev1b1ir)
 		//m[strconv.FormatInt(oneSaarasIRService.Service.Service_id, 10)] = onev1b1ir
 		m[onev1b1ir.Spec.VirtualHost.Fqdn] = onev1b1ir
@@ -24,9 +24,12 @@ import (
 	"sync"
 )
 
-var ENROUTE_NAME string
-
+// SaarasCloudCache holds the configuration of a proxy fetched from
+// enroute-cp, and generates events for its changes.
 type SaarasCloudCache struct {
+	// ProxyName is the name of the proxy whose configuration is fetched.
+	ProxyName string
+
 	mu sync.RWMutex
 
 	sdbpg      map[string]*cfg.SaarasProxyGroupConfig
@@ -132,7 +135,7 @@ func (sac *SaarasCloudCache) update__v1b1_service__cache(
 }
 
 func saaras_ir_slice__to__v1b1_routefilter_map(
-	s *[]SaarasGatewayHostService, log logrus.FieldLogger) *map[string]*v1beta1.RouteFilter {
+	s *[]SaarasGatewayHostService, proxy string, log logrus.FieldLogger) *map[string]*v1beta1.RouteFilter {
 	rf := make(map[string]*v1beta1.RouteFilter, 0)
 	for _, oneSaarasIRService := range *s {
 		for _, oneRoute := range oneSaarasIRService.Service.Routes {
@@ -141,7 +144,7 @@ func saaras_ir_slice__to__v1b1_routefilter_map(
 				one_routefilter := &v1beta1.RouteFilter{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      oneRF.Filter.Filter_name,
-						Namespace: ENROUTE_NAME,
+						Namespace: proxy,
 					},
 					Spec: v1beta1.RouteFilterSpec{
 						Name: oneRF.Filter.Filter_name,
@@ -160,7 +163,7 @@ func saaras_ir_slice__to__v1b1_routefilter_map(
 	return &rf
 }
 
-func saaras_ir_slice__to__v1b1__pc_map(s *[]SaarasGatewayHostService,
+func saaras_ir_slice__to__v1b1__pc_map(s *[]SaarasGatewayHostService, proxy string,
 	log logrus.FieldLogger) *map[string]*v1beta1.GlobalConfig {
 
 	pc := make(map[string]*v1beta1.GlobalConfig, 0)
@@ -170,7 +173,7 @@ func saaras_ir_slice__to__v1b1__pc_map(s *[]SaarasGatewayHostService,
 			one_pcf := &v1beta1.GlobalConfig{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      onePC.Globalconfig.GlobalconfigName,
-					Namespace: ENROUTE_NAME,
+					Namespace: proxy,
 				},
 				Spec: v1beta1.GlobalConfigSpec{
 					Name:   "proxy_config_name",
@@ -187,7 +190,7 @@ func saaras_ir_slice__to__v1b1__pc_map(s *[]SaarasGatewayHostService,
 }
 
 func saaras_ir_slice__to__v1b1_httpfilter_map(
-	s *[]SaarasGatewayHostService, log logrus.FieldLogger) *map[string]*v1beta1.HttpFilter {
+	s *[]SaarasGatewayHostService, proxy string, log logrus.FieldLogger) *map[string]*v1beta1.HttpFilter {
 	vf := make(map[string]*v1beta1.HttpFilter, 0)
 	for _, oneSaarasIRService := range *s {
 		for _, oneServiceFilter := range oneSaarasIRService.Service.Service_filters {
@@ -195,7 +198,7 @@ func saaras_ir_slice__to__v1b1_httpfilter_map(
 			one_vhfilter := &v1beta1.HttpFilter{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      oneServiceFilter.Filter.Filter_name,
-					Namespace: ENROUTE_NAME,
+					Namespace: proxy,
 				},
 				Spec: v1beta1.HttpFilterSpec{
 					Name: oneServiceFilter.Filter.Filter_name,
@@ -214,7 +217,7 @@ func saaras_ir_slice__to__v1b1_httpfilter_map(
 }
 
 func saaras_ir_slice__to__v1b1_service_map(
-	s *[]SaarasGatewayHostService, log logrus.FieldLogger) *map[string]*v1.Service {
+	s *[]SaarasGatewayHostService, proxy string, log logrus.FieldLogger) *map[string]*v1.Service {
 	svc := make(map[string]*v1.Service, 0)
 	for _, oneSaarasIRService := range *s {
 		for _, oneRoute := range oneSaarasIRService.Service.Routes {
@@ -227,7 +230,7 @@ func saaras_ir_slice__to__v1b1_service_map(
 				one_service := &v1.Service{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      oneService.Upstream.Upstream_name,
-						Namespace: ENROUTE_NAME,
+						Namespace: proxy,
 					},
 					Spec: v1.ServiceSpec{
 						Ports: sp,
@@ -259,7 +262,7 @@ func saaras_ir_slice__to__v1b1_service_map(
 	return &svc
 }
 
-func saaras_upstream__to__v1_ep(mss *SaarasMicroService2) *v1.Endpoints {
+func saaras_upstream__to__v1_ep(mss *SaarasMicroService2, proxy string) *v1.Endpoints {
 	ep_subsets := make([]v1.EndpointSubset, 0)
 	ep_subsets_addresses := make([]v1.EndpointAddress, 0)
 	ep_subsets_ports := make([]v1.EndpointPort, 0)
@@ -318,19 +321,19 @@ func saaras_upstream__to__v1_ep(mss *SaarasMicroService2) *v1.Endpoints {
 	return &v1.Endpoints{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      mss.Upstream.Upstream_name,
-			Namespace: ENROUTE_NAME,
+			Namespace: proxy,
 		},
 		Subsets: ep_subsets,
 	}
 }
 
 func saaras_ir_slice__to__v1b1_endpoint_map(
-	s *[]SaarasGatewayHostService, log logrus.FieldLogger) *map[string]*v1.Endpoints {
+	s *[]SaarasGatewayHostService, proxy string, log logrus.FieldLogger) *map[string]*v1.Endpoints {
 	eps := make(map[string]*v1.Endpoints, 0)
 	for _, oneSaarasIRService := range *s {
 		for _, oneRoute := range oneSaarasIRService.Service.Routes {
 			for _, oneService := range oneRoute.Route_upstreams {
-				v1_ep := saaras_upstream__to__v1_ep(&oneService)
+				v1_ep := saaras_upstream__to__v1_ep(&oneService, proxy)
 				eps[v1_ep.ObjectMeta.Namespace+v1_ep.ObjectMeta.Name] = v1_ep
 			}
 		}
@@ -503,7 +506,7 @@ func (sac *SaarasCloudCache) update__v1__rf_cache(v1b1_rf_map *map[string]*v1bet
 }
 
 func (sac *SaarasCloudCache) update__v1b1__pc(v1b1_pc_map *map[string]*v1beta1.GlobalConfig,
-	pct *contour.GlobalConfigTranslator, log logrus.FieldLogger) {
+	reh *contour.ResourceEventHandler, pct *contour.GlobalConfigTranslator, log logrus.FieldLogger) {
 
 	for _, cloud_pc := range *v1b1_pc_map {
 		if cached_pc, ok := sac.pc[cloud_pc.ObjectMeta.Namespace+cloud_pc.ObjectMeta.Name+cloud_pc.Spec.Type]; ok {
@@ -511,6 +514,7 @@ func (sac *SaarasCloudCache) update__v1b1__pc(v1b1_pc_map *map[string]*v1beta1.G
 			} else {
 				sac.pc[cloud_pc.ObjectMeta.Namespace+cloud_pc.ObjectMeta.Name+cloud_pc.Spec.Type] = cloud_pc
 				pct.OnUpdate(cached_pc, cloud_pc)
+				reh.OnUpdate(cached_pc, cloud_pc)
 			}
 		} else {
 			if sac.pc == nil {
@@ -518,6 +522,7 @@ func (sac *SaarasCloudCache) update__v1b1__pc(v1b1_pc_map *map[string]*v1beta1.G
 			}
 			sac.pc[cloud_pc.ObjectMeta.Namespace+cloud_pc.ObjectMeta.Name+cloud_pc.Spec.Type] = cloud_pc
 			pct.OnAdd(cloud_pc)
+			reh.OnAdd(cloud_pc)
 		}
 	}
 
@@ -526,6 +531,7 @@ func (sac *SaarasCloudCache) update__v1b1__pc(v1b1_pc_map *map[string]*v1beta1.G
 			if _, ok := (*v1b1_pc_map)[cached_pc_id]; !ok {
 				delete(sac.pc, cached_pc_id)
 				pct.OnDelete(cached_pc)
+				reh.OnDelete(cached_pc)
 			}
 		}
 	}
@@ -595,18 +601,18 @@ func (sac *SaarasCloudCache) update__v1__secret_cache(v1_secret_map *map[string]
 	}
 }
 
-func v1_secret(saaras_secret *SaarasSecret) *v1.Secret {
+func v1_secret(saaras_secret *SaarasSecret, proxy string) *v1.Secret {
 
 	var v1secret v1.Secret
 	//				v1_service := &v1.Secret{
 	//					ObjectMeta: metav1.ObjectMeta{
 	//						Name:      saaras_secret.Secret_name,
-	//						Namespace: ENROUTE_NAME,
+	//						Namespace: proxy,
 	//					},
 	//				}
 
 	v1secret.ObjectMeta.Name = saaras_secret.Secret_name
-	v1secret.ObjectMeta.Namespace = ENROUTE_NAME
+	v1secret.ObjectMeta.Namespace = proxy
 
 	//TODO: This needs to be captured in the DB
 	v1secret.Type = v1.SecretTypeTLS
@@ -632,11 +638,11 @@ func v1_secret(saaras_secret *SaarasSecret) *v1.Secret {
 	return &v1secret
 }
 
-func saaras_ir_slice__to__v1_secret(s *[]SaarasGatewayHostService, log logrus.FieldLogger) *map[string]*v1.Secret {
+func saaras_ir_slice__to__v1_secret(s *[]SaarasGatewayHostService, proxy string, log logrus.FieldLogger) *map[string]*v1.Secret {
 	secrets := make(map[string]*v1.Secret, 0)
 	for _, oneSaarasIRService := range *s {
 		for _, oneSecret := range oneSaarasIRService.Service.Service_secrets {
-			secrets[ENROUTE_NAME+oneSecret.Secret.Secret_name] = v1_secret(&oneSecret.Secret)
+			secrets[proxy+oneSecret.Secret.Secret_name] = v1_secret(&oneSecret.Secret, proxy)
 		}
 	}
 	return &secrets
@@ -649,26 +655,26 @@ func (sac *SaarasCloudCache) OnFetch(obj interface{}, reh *contour.ResourceEvent
 	case []SaarasGatewayHostService:
 		log.Infof("-- SaarasCloudCache.OnFetch() --\n")
 
-		v1b1_ir_map := saaras_ir_slice__to__v1b1_ir_map(&obj, log)
+		v1b1_ir_map := saaras_ir_slice__to__v1b1_ir_map(&obj, sac.ProxyName, log)
 		sac.update__v1b1_ir__cache(v1b1_ir_map, reh, log)
 
-		v1b1_service_map := saaras_ir_slice__to__v1b1_service_map(&obj, log)
+		v1b1_service_map := saaras_ir_slice__to__v1b1_service_map(&obj, sac.ProxyName, log)
 		sac.update__v1b1_service__cache(v1b1_service_map, reh, log)
 
-		v1b1_endpoint_map := saaras_ir_slice__to__v1b1_endpoint_map(&obj, log)
+		v1b1_endpoint_map := saaras_ir_slice__to__v1b1_endpoint_map(&obj, sac.ProxyName, log)
 		sac.update__v1b1__endpoint_cache(v1b1_endpoint_map, et, log)
 
-		v1_secret_map := saaras_ir_slice__to__v1_secret(&obj, log)
+		v1_secret_map := saaras_ir_slice__to__v1_secret(&obj, sac.ProxyName, log)
 		sac.update__v1__secret_cache(v1_secret_map, reh, log)
 
-		v1b1_rf_map := saaras_ir_slice__to__v1b1_routefilter_map(&obj, log)
+		v1b1_rf_map := saaras_ir_slice__to__v1b1_routefilter_map(&obj, sac.ProxyName, log)
 		sac.update__v1__rf_cache(v1b1_rf_map, reh, log)
 
-		v1b1_vf_map := saaras_ir_slice__to__v1b1_httpfilter_map(&obj, log)
+		v1b1_vf_map := saaras_ir_slice__to__v1b1_httpfilter_map(&obj, sac.ProxyName, log)
 		sac.update__v1__vf_cache(v1b1_vf_map, reh, log)
 
-		v1b1_pc_map := saaras_ir_slice__to__v1b1__pc_map(&obj, log)
-		sac.update__v1b1__pc(v1b1_pc_map, pct, log)
+		v1b1_pc_map := saaras_ir_slice__to__v1b1__pc_map(&obj, sac.ProxyName, log)
+		sac.update__v1b1__pc(v1b1_pc_map, reh, pct, log)
 		break
 
 	case []cfg.SaarasProxyGroupConfig:
@@ -686,26 +692,30 @@ func (sac *SaarasCloudCache) OnFetch(obj interface{}, reh *contour.ResourceEvent
 	}
 }
 
-func FetchGatewayHost(reh *contour.ResourceEventHandler, et *contour.EndpointsTranslator, pct *contour.GlobalConfigTranslator, scc *SaarasCloudCache, log logrus.FieldLogger) {
+// FetchGatewayHost fetches the configuration of the proxy scc.ProxyName
+// and applies it, unless it cannot be fetched in full.
+func FetchGatewayHost(reh *contour.ResourceEventHandler, et *contour.EndpointsTranslator, pct *contour.GlobalConfigTranslator, scc *SaarasCloudCache, log logrus.FieldLogger) error {
 	var buf bytes.Buffer
 	var args map[string]string
 	args = make(map[string]string)
 
-	args["proxy_name"] = ENROUTE_NAME
+	args["proxy_name"] = scc.ProxyName
 
 	// Fetch Application
 	if err := FetchConfig(QGatewayHost, &buf, args, log); err != nil {
 		log.Errorf("Error when running http request [%v]\n", err)
 		// If we failed reaching the route, an empty GatewayHost is received.
 		// Bail here or it'll clear the cache
-		return
+		return err
 	}
 
 	var gr DataPayloadSaarasApp2
 	if err := json.NewDecoder(&buf).Decode(&gr); err != nil {
-		errors.Wrap(err, "decoding response")
 		log.Errorf("Error when decoding json [%v]\n", err)
+		// Bail here too, what was decoded may only be part of it
+		return errors.Wrap(err, "decoding response")
 	}
 	//spew.Dump(gr)
 	scc.OnFetch(gr.Data.Saaras_db_proxy_service, reh, et, pct, log)
+	return nil
 }
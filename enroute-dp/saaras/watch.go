@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package saaras
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// WatchPath is where enroute-cp streams the version of the configuration
+// of a proxy as server-sent events, an event being sent whenever the
+// version changes.
+const WatchPath = "/v1/watch/"
+
+// watchIdleTimeout is how long a watch stream may stay silent before it
+// is considered dropped. enroute-cp keeps streams alive every 30 seconds.
+var watchIdleTimeout = 75 * time.Second
+
+// WatchVersion reads the versions streamed from url, calling onVersion
+// with each. It returns when the stream drops, stays silent for longer
+// than watchIdleTimeout, or stop is closed, in which case it returns nil.
+func WatchVersion(url string, stop <-chan struct{}, onVersion func(version string), log logrus.FieldLogger) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idle := time.AfterFunc(watchIdleTimeout, cancel)
+	defer idle.Stop()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	setAuthorization(req.Header)
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "watching configuration")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("watching configuration: %s", resp.Status)
+	}
+	log.Debugf("Watching configuration at %s\n", url)
+
+	var event, data string
+	lines := bufio.NewScanner(resp.Body)
+	for lines.Scan() {
+		idle.Reset(watchIdleTimeout)
+
+		l := lines.Text()
+		switch {
+		case strings.HasPrefix(l, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(l, "event:"))
+		case strings.HasPrefix(l, "data:"):
+			data += strings.TrimSpace(strings.TrimPrefix(l, "data:"))
+		case l == "":
+			// An empty line ends an event
+			if event == "version" && data != "" {
+				var v struct {
+					Version string `json:"version"`
+				}
+				if err := json.Unmarshal([]byte(data), &v); err != nil {
+					return errors.Wrap(err, "decoding version")
+				}
+				onVersion(v.Version)
+			}
+			event, data = "", ""
+		}
+	}
+
+	select {
+	case <-stop:
+		return nil
+	default:
+	}
+	if err := lines.Err(); err != nil {
+		return errors.Wrap(err, "watching configuration")
+	}
+	return errors.New("watching configuration: stream closed")
+}
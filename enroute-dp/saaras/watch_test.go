@@ -0,0 +1,46 @@
+package saaras
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/saarasio/enroute/enroute-dp/internal/assert"
+	"github.com/sirupsen/logrus"
+)
+
+func TestWatchVersion(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: version\ndata: {\"proxy_name\":\"gw\",\"version\":\"a\"}\n\n")
+		fmt.Fprint(w, ": keepalive\n\n")
+		fmt.Fprint(w, "event: other\ndata: {\"version\":\"x\"}\n\n")
+		fmt.Fprint(w, "event: version\ndata: {\"proxy_name\":\"gw\",\"version\":\"b\"}\n\n")
+	}))
+	defer srv.Close()
+
+	var got []string
+	err := WatchVersion(srv.URL, make(chan struct{}), func(v string) { got = append(got, v) }, logrus.New())
+	assert.Equal(t, []string{"a", "b"}, got)
+	assert.Equal(t, "watching configuration: stream closed", fmt.Sprint(err))
+
+	// A silent stream is dropped
+	defer func(d time.Duration) { watchIdleTimeout = d }(watchIdleTimeout)
+	watchIdleTimeout = 100 * time.Millisecond
+	silent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer silent.Close()
+	err = WatchVersion(silent.URL, make(chan struct{}), func(string) {}, logrus.New())
+	assert.Equal(t, true, err != nil)
+
+	// Closing stop ends the watch without an error
+	stop := make(chan struct{})
+	time.AfterFunc(50*time.Millisecond, func() { close(stop) })
+	watchIdleTimeout = time.Minute
+	err = WatchVersion(silent.URL, stop, func(string) {}, logrus.New())
+	assert.Equal(t, nil, err)
+}
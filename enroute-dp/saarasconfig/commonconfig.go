@@ -18,6 +18,10 @@ const FILTER_TYPE_HTTP_RATELIMIT string = "http_filter_ratelimit"
 const FILTER_TYPE_RT_RATELIMIT string = "route_filter_ratelimit"
 
 const PROXY_CONFIG_RATELIMIT string = "globalconfig_ratelimit"
+const PROXY_CONFIG_TLS string = "globalconfig_tls"
+
+// ACME HTTP-01 challenges are served under this path prefix, over plain HTTP
+const ACME_HTTP01_CHALLENGE_PREFIX string = "/.well-known/acme-challenge/"
 
 const JAEGER_TRACING_CLUSTER string = "jaeger-trace"
 const EDS_CONFIG_CLUSTER string = "contour"
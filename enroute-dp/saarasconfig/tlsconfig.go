@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright(c) 2018-2020 Saaras Inc.
+
+package saarasconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TLSConfig holds the TLS parameters a virtual host negotiates with
+// downstream clients. It is used as the config of a PROXY_CONFIG_TLS
+// global config and as the TLS config of a standalone service secret.
+type TLSConfig struct {
+	// Minimum TLS version, one of 1.1, 1.2 or 1.3
+	MinimumProtocolVersion string `json:"minimum_protocol_version,omitempty"`
+	// Maximum TLS version, one of 1.1, 1.2 or 1.3
+	MaximumProtocolVersion string `json:"maximum_protocol_version,omitempty"`
+	// Cipher suites offered for TLS 1.2 and below
+	CipherSuites []string `json:"cipher_suites,omitempty"`
+	// ECDH curves offered during the handshake
+	ECDHCurves []string `json:"ecdh_curves,omitempty"`
+}
+
+// SupportedTLSProtocolVersions lists the TLS versions a virtual host can
+// be pinned to. TLS 1.0 is never enabled.
+var SupportedTLSProtocolVersions = []string{"1.1", "1.2", "1.3"}
+
+// SupportedCipherSuites lists the cipher suites envoy (BoringSSL) accepts
+// in a DownstreamTlsContext. Equal preference groups of the form
+// "[A|B]" are accepted if every member is supported.
+var SupportedCipherSuites = []string{
+	"ECDHE-ECDSA-AES128-GCM-SHA256",
+	"ECDHE-RSA-AES128-GCM-SHA256",
+	"ECDHE-ECDSA-CHACHA20-POLY1305",
+	"ECDHE-RSA-CHACHA20-POLY1305",
+	"ECDHE-ECDSA-AES128-SHA",
+	"ECDHE-RSA-AES128-SHA",
+	"AES128-GCM-SHA256",
+	"AES128-SHA",
+	"ECDHE-ECDSA-AES256-GCM-SHA384",
+	"ECDHE-RSA-AES256-GCM-SHA384",
+	"ECDHE-ECDSA-AES256-SHA",
+	"ECDHE-RSA-AES256-SHA",
+	"AES256-GCM-SHA384",
+	"AES256-SHA",
+}
+
+// SupportedECDHCurves lists the ECDH curves envoy (BoringSSL) accepts.
+var SupportedECDHCurves = []string{
+	"X25519",
+	"P-256",
+	"P-384",
+	"P-521",
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, h := range haystack {
+		if h == needle {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidCipherSuite returns true if envoy supports the cipher suite,
+// or every cipher suite of an equal preference group.
+func ValidCipherSuite(cipher string) bool {
+	if strings.HasPrefix(cipher, "[") && strings.HasSuffix(cipher, "]") {
+		for _, c := range strings.Split(strings.Trim(cipher, "[]"), "|") {
+			if !contains(SupportedCipherSuites, c) {
+				return false
+			}
+		}
+		return true
+	}
+	return contains(SupportedCipherSuites, cipher)
+}
+
+// ValidECDHCurve returns true if envoy supports the ECDH curve.
+func ValidECDHCurve(curve string) bool {
+	return contains(SupportedECDHCurves, curve)
+}
+
+// ValidTLSProtocolVersion returns true if version is empty or
+// one of SupportedTLSProtocolVersions.
+func ValidTLSProtocolVersion(version string) bool {
+	return version == "" || contains(SupportedTLSProtocolVersions, version)
+}
+
+// Validate checks the TLSConfig against the versions, cipher suites
+// and curves envoy supports.
+func (t *TLSConfig) Validate() error {
+	if !ValidTLSProtocolVersion(t.MinimumProtocolVersion) {
+		return fmt.Errorf("invalid minimum TLS protocol version %q", t.MinimumProtocolVersion)
+	}
+	if !ValidTLSProtocolVersion(t.MaximumProtocolVersion) {
+		return fmt.Errorf("invalid maximum TLS protocol version %q", t.MaximumProtocolVersion)
+	}
+	// versions are of the form 1.x, so they compare lexically
+	if t.MinimumProtocolVersion != "" && t.MaximumProtocolVersion != "" &&
+		t.MinimumProtocolVersion > t.MaximumProtocolVersion {
+		return fmt.Errorf("minimum TLS protocol version %q is greater than maximum %q",
+			t.MinimumProtocolVersion, t.MaximumProtocolVersion)
+	}
+	for _, c := range t.CipherSuites {
+		if !ValidCipherSuite(c) {
+			return fmt.Errorf("unsupported cipher suite %q", c)
+		}
+	}
+	for _, c := range t.ECDHCurves {
+		if !ValidECDHCurve(c) {
+			return fmt.Errorf("unsupported ECDH curve %q", c)
+		}
+	}
+	return nil
+}
+
+// UnmarshalTLSConfig decodes and validates a TLSConfig. An empty
+// config decodes to the zero TLSConfig.
+func UnmarshalTLSConfig(config string) (TLSConfig, error) {
+	var tc TLSConfig
+
+	if strings.TrimSpace(config) == "" {
+		return tc, nil
+	}
+
+	if err := json.NewDecoder(strings.NewReader(config)).Decode(&tc); err != nil {
+		return tc, errors.Wrap(err, "decoding tls config")
+	}
+
+	return tc, tc.Validate()
+}
@@ -0,0 +1,72 @@
+package saarasconfig
+
+import (
+	"testing"
+
+	"github.com/saarasio/enroute/enroute-dp/internal/assert"
+)
+
+func TestTLSConfigUnmarshal(t *testing.T) {
+	tests := map[string]struct {
+		config  string
+		want    TLSConfig
+		wantErr bool
+	}{
+		"empty config": {
+			config: ``,
+			want:   TLSConfig{},
+		},
+		"versions, ciphers and curves": {
+			config: `
+            {
+                "minimum_protocol_version" : "1.2",
+                "maximum_protocol_version" : "1.3",
+                "cipher_suites" : [ "[ECDHE-ECDSA-AES128-GCM-SHA256|ECDHE-ECDSA-CHACHA20-POLY1305]", "ECDHE-RSA-AES256-GCM-SHA384" ],
+                "ecdh_curves" : [ "X25519", "P-256" ]
+            }
+            `,
+			want: TLSConfig{
+				MinimumProtocolVersion: "1.2",
+				MaximumProtocolVersion: "1.3",
+				CipherSuites: []string{
+					"[ECDHE-ECDSA-AES128-GCM-SHA256|ECDHE-ECDSA-CHACHA20-POLY1305]",
+					"ECDHE-RSA-AES256-GCM-SHA384",
+				},
+				ECDHCurves: []string{"X25519", "P-256"},
+			},
+		},
+		"unsupported cipher": {
+			config:  `{ "cipher_suites" : [ "DES-CBC3-SHA" ] }`,
+			want:    TLSConfig{CipherSuites: []string{"DES-CBC3-SHA"}},
+			wantErr: true,
+		},
+		"unsupported cipher in group": {
+			config:  `{ "cipher_suites" : [ "[AES128-SHA|RC4-SHA]" ] }`,
+			want:    TLSConfig{CipherSuites: []string{"[AES128-SHA|RC4-SHA]"}},
+			wantErr: true,
+		},
+		"unsupported curve": {
+			config:  `{ "ecdh_curves" : [ "secp256k1" ] }`,
+			want:    TLSConfig{ECDHCurves: []string{"secp256k1"}},
+			wantErr: true,
+		},
+		"invalid version": {
+			config:  `{ "maximum_protocol_version" : "1.4" }`,
+			want:    TLSConfig{MaximumProtocolVersion: "1.4"},
+			wantErr: true,
+		},
+		"minimum greater than maximum": {
+			config:  `{ "minimum_protocol_version" : "1.3", "maximum_protocol_version" : "1.2" }`,
+			want:    TLSConfig{MinimumProtocolVersion: "1.3", MaximumProtocolVersion: "1.2"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := UnmarshalTLSConfig(tc.config)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
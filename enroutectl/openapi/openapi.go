@@ -582,12 +582,12 @@ func SanitizeGatewayHost(gh *v1beta1.GatewayHost) {
 	gh.TypeMeta.Kind = "GatewayHost"
 }
 
-func SaarasServiceToGatewayHost(saarassvc config.Service) *v1beta1.GatewayHost {
+func SaarasServiceToGatewayHost(saarassvc config.Service, proxy string) *v1beta1.GatewayHost {
 
 	// Convert config.Service to saaras.SaarasGatewayHostService
 	// Call Saaras_ir__to__v1b1_ir2() to convert saaras.SaarasGatewayHostService to v1beta1.GatewayHost
 	sghs := EnrouteCtlServiceToSaarasGatewayHost(&saarassvc)
-	v1b1gh := saaras.Saaras_ir__to__v1b1_ir2(sghs)
+	v1b1gh := saaras.Saaras_ir__to__v1b1_ir2(sghs, proxy)
 	SanitizeGatewayHost(v1b1gh)
 	return v1b1gh
 }
@@ -596,7 +596,7 @@ func ProxyConfigToGatewayHosts(proxy config.SaarasDbProxy) *v1beta1.GatewayHostL
 	items := make([]v1beta1.GatewayHost, 0)
 
 	for _, oneSvc := range proxy.ProxyServices {
-		gwHost := SaarasServiceToGatewayHost(oneSvc.Service)
+		gwHost := SaarasServiceToGatewayHost(oneSvc.Service, proxy.ProxyName)
 		items = append(items, *gwHost)
 	}
 	return &v1beta1.GatewayHostList{
@@ -0,0 +1,10 @@
+CREATE TABLE saaras_db.revision (
+    revision_id bigserial NOT NULL,
+    proxy_name text NOT NULL,
+    author text,
+    config jsonb,
+    diff text,
+    create_ts timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT revision_pkey PRIMARY KEY (revision_id)
+);
+CREATE INDEX revision_proxy_name_idx ON saaras_db.revision (proxy_name, revision_id);
@@ -0,0 +1,12 @@
+CREATE TABLE saaras_db.token (
+    token_id bigserial NOT NULL,
+    token_name text NOT NULL,
+    token_hash text NOT NULL,
+    token_role text NOT NULL,
+    token_proxies jsonb,
+    token_services jsonb,
+    create_ts timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT token_pkey PRIMARY KEY (token_id),
+    CONSTRAINT token_token_name_key UNIQUE (token_name),
+    CONSTRAINT token_token_hash_key UNIQUE (token_hash)
+);
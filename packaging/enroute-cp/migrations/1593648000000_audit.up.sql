@@ -0,0 +1,16 @@
+CREATE TABLE saaras_db.audit (
+    audit_id bigserial NOT NULL,
+    token_name text,
+    client_ip text,
+    method text NOT NULL,
+    endpoint text NOT NULL,
+    path text NOT NULL,
+    objects jsonb,
+    before jsonb,
+    after jsonb,
+    code integer NOT NULL,
+    create_ts timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT audit_pkey PRIMARY KEY (audit_id)
+);
+CREATE INDEX audit_create_ts_idx ON saaras_db.audit (create_ts);
+CREATE INDEX audit_objects_idx ON saaras_db.audit USING gin (objects);
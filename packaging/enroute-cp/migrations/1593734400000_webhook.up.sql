@@ -0,0 +1,10 @@
+CREATE TABLE saaras_db.webhook (
+    webhook_id bigserial NOT NULL,
+    webhook_name text NOT NULL,
+    webhook_url text NOT NULL,
+    webhook_secret text NOT NULL,
+    webhook_events jsonb,
+    create_ts timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT webhook_pkey PRIMARY KEY (webhook_id),
+    CONSTRAINT webhook_webhook_name_key UNIQUE (webhook_name)
+);
@@ -0,0 +1 @@
+ALTER TABLE saaras_db.secret ADD COLUMN secret_acme_service character varying;
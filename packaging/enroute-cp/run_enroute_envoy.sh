@@ -32,6 +32,12 @@ wait_for_port 6379
 
 /bin/enroute bootstrap --xds-address 127.0.0.1 --xds-port 8001 /supervisord/config.json
 
-/bin/enroute serve --xds-port=8001 --xds-address=127.0.0.1 --enroute-cp-ip localhost --enroute-cp-port 8888 --enroute-cp-proto http --enroute-name gw --enable-ratelimit &
+# enroute-cp requires a token when WEBAPP_SECRET is set, passed in the
+# environment to keep it off the command line
+if [ -z "${ENROUTE_CP_TOKEN}" ] && [ -n "${WEBAPP_SECRET}" ]; then
+    export ENROUTE_CP_TOKEN="$WEBAPP_SECRET"
+fi
+
+/bin/enroute serve --xds-port=8001 --xds-address=127.0.0.1 --enroute-cp-ip localhost --enroute-cp-port 1323 --enroute-cp-proto http --enroute-name gw --enable-ratelimit &
 
 /bin/envoy -c /supervisord/config.json --service-node "service-node-enroute-gw" --service-cluster "gw" --log-level error